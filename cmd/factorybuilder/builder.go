@@ -3,10 +3,12 @@ package main
 import (
 	"bytes"
 	"embed"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
 )
@@ -22,12 +24,28 @@ const (
 	Exporter  ComponentType = "exporter"
 )
 
+// Compiler is the compiler the wasm module is built with.
+type Compiler string
+
+const (
+	// GoCompiler builds with the go toolchain, through wasibuilder.
+	GoCompiler Compiler = "go"
+	// TinyGoCompiler builds with the wasip1 target of TinyGo, which produces
+	// much smaller modules but only supports a subset of the guest APIs.
+	TinyGoCompiler Compiler = "tinygo"
+)
+
 type Builder struct {
 	WorkDir       string
 	ComponentType ComponentType
 	Package       string
 	PackageName   string
 	Output        string
+	Compiler      Compiler
+	// Tags and LDFlags are passed to the build as -tags and -ldflags,
+	// unless empty.
+	Tags    string
+	LDFlags string
 }
 
 func (b *Builder) Prepare() error {
@@ -36,17 +54,22 @@ func (b *Builder) Prepare() error {
 		return fmt.Errorf("failed to create workdir %s: %w", workDir, err)
 	}
 
-	err = b.exec("go", "mod", "init", b.PackageName)
+	err = runIn(b.WorkDir, "go", "mod", "init", b.PackageName)
 	if err != nil {
 		return fmt.Errorf("failed to init go module: %w", err)
 	}
 
-	err = b.exec("go", "get", b.Package)
+	err = runIn(b.WorkDir, "go", "get", b.Package)
 	if err != nil {
 		return fmt.Errorf("failed to get package %s: %w", b.Package, err)
 	}
 
-	err = b.exec("go", "mod", "edit", "-tool=github.com/otelwasm/wasibuilder")
+	err = b.resolveComponentType()
+	if err != nil {
+		return err
+	}
+
+	err = runIn(b.WorkDir, "go", "mod", "edit", "-tool=github.com/otelwasm/wasibuilder")
 	if err != nil {
 		return fmt.Errorf("failed to add wasibuilder as tool: %w", err)
 	}
@@ -54,14 +77,14 @@ func (b *Builder) Prepare() error {
 	dst := filepath.Join(b.WorkDir, "main.go")
 	tmplName := strings.ToLower(string(b.ComponentType)) + ".gotmpl"
 
-	err = b.writeTemplate(dst, tmplName, map[string]any{
+	err = writeTemplate(dst, tmplName, map[string]any{
 		"UpstreamPackage": b.Package,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to write template %s: %w", tmplName, err)
 	}
 
-	err = b.exec("go", "mod", "tidy")
+	err = runIn(b.WorkDir, "go", "mod", "tidy")
 	if err != nil {
 		return fmt.Errorf("failed to tidy go module: %w", err)
 	}
@@ -69,13 +92,67 @@ func (b *Builder) Prepare() error {
 	return nil
 }
 
+// ErrComponentTypeMismatch is returned when the component type doesn't match
+// the type of the factory of the package.
+var ErrComponentTypeMismatch = errors.New("component type mismatch")
+
+// factoryPattern matches the signature of NewFactory in the documentation of
+// the package, capturing the package of the factory type.
+var factoryPattern = regexp.MustCompile(`func NewFactory\(\) (\w+)\.Factory`)
+
+// goDoc returns the documentation of symbol in pkg, as printed by go doc in
+// dir. It is replaced in tests.
+var goDoc = func(dir, pkg, symbol string) ([]byte, error) {
+	cmd := exec.Command("go", "doc", pkg, symbol)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// resolveComponentType checks the component type against the type of the
+// factory returned by NewFactory of the package, or sets it if it isn't
+// known. The guest SDK exports the functions of every component type, so
+// the built module can't tell.
+func (b *Builder) resolveComponentType() error {
+	doc, err := goDoc(b.WorkDir, b.Package, "NewFactory")
+	if err != nil {
+		return fmt.Errorf("failed to read the factory of package %s: %w", b.Package, err)
+	}
+	match := factoryPattern.FindSubmatch(doc)
+	if match == nil {
+		return fmt.Errorf("package %s has no NewFactory function returning a component factory", b.Package)
+	}
+	factoryType := ComponentType(match[1])
+	switch factoryType {
+	case Receiver, Processor, Exporter:
+	default:
+		return fmt.Errorf("package %s builds a %s, which isn't supported", b.Package, factoryType)
+	}
+
+	if b.ComponentType == "" {
+		b.ComponentType = factoryType
+		return nil
+	}
+	if b.ComponentType != factoryType {
+		return fmt.Errorf("package %s builds a %s, not a %s: %w", b.Package, factoryType, b.ComponentType, ErrComponentTypeMismatch)
+	}
+	return nil
+}
+
 func (b *Builder) Build() error {
 	output, err := filepath.Abs(b.Output)
 	if err != nil {
 		return fmt.Errorf("failed to get absolute path of output file %s: %w", b.Output, err)
 	}
 
-	err = b.exec("go", "tool", "wasibuilder", "go", "build", "-buildmode=c-shared", "-o", output, ".")
+	if b.Compiler == TinyGoCompiler {
+		if _, err := exec.LookPath("tinygo"); err != nil {
+			return fmt.Errorf("tinygo is required by -compiler %s, install it or build with -compiler %s: %w", TinyGoCompiler, GoCompiler, err)
+		}
+	}
+
+	command := b.buildCommand(output)
+	err = runIn(b.WorkDir, command[0], command[1:]...)
 	if err != nil {
 		return fmt.Errorf("failed to build package %s: %w", b.Package, err)
 	}
@@ -83,6 +160,26 @@ func (b *Builder) Build() error {
 	return nil
 }
 
+// buildCommand returns the command building the package in the workdir into
+// output.
+func (b *Builder) buildCommand(output string) []string {
+	var command []string
+	switch b.Compiler {
+	case TinyGoCompiler:
+		command = []string{"tinygo", "build", "-target=wasip1"}
+	default:
+		command = []string{"go", "tool", "wasibuilder", "go", "build"}
+	}
+	command = append(command, "-buildmode=c-shared")
+	if b.Tags != "" {
+		command = append(command, "-tags", b.Tags)
+	}
+	if b.LDFlags != "" {
+		command = append(command, "-ldflags", b.LDFlags)
+	}
+	return append(command, "-o", output, ".")
+}
+
 func (b *Builder) Clean() error {
 	err := os.RemoveAll(b.WorkDir)
 	if err != nil {
@@ -92,16 +189,17 @@ func (b *Builder) Clean() error {
 	return nil
 }
 
-func (b *Builder) exec(command string, args ...string) error {
+// runIn runs the command in dir, with the output of factorybuilder.
+func runIn(dir, command string, args ...string) error {
 	cmd := exec.Command(command, args...)
-	cmd.Dir = b.WorkDir
+	cmd.Dir = dir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	return cmd.Run()
 }
 
-func (b *Builder) writeTemplate(dst, templateName string, data interface{}) error {
+func writeTemplate(dst, templateName string, data interface{}) error {
 	templatePath := filepath.Join("templates", templateName)
 
 	tmpl := template.Must(template.ParseFS(templates, templatePath))
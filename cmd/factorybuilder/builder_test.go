@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBuildCommand(t *testing.T) {
+	tests := []struct {
+		compiler Compiler
+		want     []string
+	}{
+		{compiler: "", want: []string{"go", "tool", "wasibuilder", "go", "build", "-buildmode=c-shared", "-o", "out.wasm", "."}},
+		{compiler: GoCompiler, want: []string{"go", "tool", "wasibuilder", "go", "build", "-buildmode=c-shared", "-o", "out.wasm", "."}},
+		{compiler: TinyGoCompiler, want: []string{"tinygo", "build", "-target=wasip1", "-buildmode=c-shared", "-o", "out.wasm", "."}},
+	}
+	for _, tt := range tests {
+		b := &Builder{Compiler: tt.compiler}
+		if got := b.buildCommand("out.wasm"); !slices.Equal(got, tt.want) {
+			t.Errorf("compiler %q: expected %v, got %v", tt.compiler, tt.want, got)
+		}
+	}
+}
+
+func TestBuildCommandFlags(t *testing.T) {
+	for _, compiler := range []Compiler{GoCompiler, TinyGoCompiler} {
+		b := &Builder{Compiler: compiler, Tags: "wasip1,nosocket", LDFlags: "-s -w"}
+		got := b.buildCommand("out.wasm")
+		for _, flag := range [][]string{{"-tags", "wasip1,nosocket"}, {"-ldflags", "-s -w"}} {
+			i := slices.Index(got, flag[0])
+			if i < 0 || i+1 >= len(got) || got[i+1] != flag[1] {
+				t.Errorf("compiler %s: expected %s %q in %v", compiler, flag[0], flag[1], got)
+			}
+		}
+		// The flags must precede the package.
+		if got[len(got)-1] != "." {
+			t.Errorf("compiler %s: expected the package last in %v", compiler, got)
+		}
+	}
+}
+
+func TestBuildNopExampleWithTinyGo(t *testing.T) {
+	if _, err := exec.LookPath("tinygo"); err != nil {
+		t.Skip("tinygo is not installed")
+	}
+
+	b := &Builder{
+		WorkDir:  filepath.Join("..", "..", "examples", "processor", "nop"),
+		Package:  "nop",
+		Output:   filepath.Join(t.TempDir(), "nop.wasm"),
+		Compiler: TinyGoCompiler,
+	}
+	if err := b.Build(); err != nil {
+		t.Fatalf("failed to build: %v", err)
+	}
+	module, err := os.ReadFile(b.Output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if !bytes.HasPrefix(module, []byte("\x00asm")) {
+		t.Error("expected a wasm module")
+	}
+}
+
+func TestBuildWithoutTinyGo(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	b := &Builder{WorkDir: t.TempDir(), Output: "out.wasm", Compiler: TinyGoCompiler}
+	if err := b.Build(); err == nil || !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("expected an error for the missing tinygo, got %v", err)
+	}
+}
+
+func TestResolveComponentType(t *testing.T) {
+	orig := goDoc
+	t.Cleanup(func() { goDoc = orig })
+
+	tests := []struct {
+		name    string
+		doc     string
+		typ     ComponentType
+		want    ComponentType
+		wantErr error
+	}{
+		{name: "detected", doc: "func NewFactory() processor.Factory", want: Processor},
+		{name: "matching", doc: "func NewFactory() exporter.Factory", typ: Exporter, want: Exporter},
+		{name: "mismatched", doc: "func NewFactory() exporter.Factory", typ: Processor, wantErr: ErrComponentTypeMismatch},
+		{name: "unsupported", doc: "func NewFactory() connector.Factory"},
+		{name: "no factory", doc: "doc: no symbol NewFactory in package"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goDoc = func(dir, pkg, symbol string) ([]byte, error) {
+				return []byte("package upstream // import \"example.com/upstream\"\n\n" + tt.doc + "\n"), nil
+			}
+			b := &Builder{Package: "example.com/upstream", ComponentType: tt.typ}
+			err := b.resolveComponentType()
+			switch {
+			case tt.want == "" && err == nil:
+				t.Errorf("expected an error, got the type %s", b.ComponentType)
+			case tt.want != "" && err != nil:
+				t.Errorf("expected the type %s, got %v", tt.want, err)
+			case tt.want != "" && b.ComponentType != tt.want:
+				t.Errorf("expected the type %s, got %s", tt.want, b.ComponentType)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
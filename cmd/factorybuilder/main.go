@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/exec"
 	"strings"
 )
 
@@ -13,39 +15,36 @@ var (
 	componentType ComponentType
 	workDir       string
 	remain        bool
+	compiler      Compiler
+	tags          string
+	ldflags       string
 )
 
 func init() {
 	flag.StringVar(&output, "o", "", "output file (default: {package}.wasm)")
-	flag.StringVar((*string)(&componentType), "type", "", "component type: receiver, processor, exporter (default: detect from package)")
+	flag.StringVar((*string)(&componentType), "type", "", "component type: receiver, processor, exporter (default: detect from the factory of the package)")
 	flag.StringVar(&workDir, "workdir", "", "working directory (default: ./{package})")
 	flag.BoolVar(&remain, "remain", false, "keep the working directory after build")
+	flag.StringVar((*string)(&compiler), "compiler", string(GoCompiler), "compiler: go, tinygo")
+	flag.StringVar(&tags, "tags", "", "comma-separated build tags passed to the build")
+	flag.StringVar(&ldflags, "ldflags", "", "linker flags passed to the build, e.g. \"-s -w\" to strip debug info")
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s {package}\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s {package}\n       %s validate -type {type} {module}\n", os.Args[0], os.Args[0])
 		flag.PrintDefaults()
 	}
-
-	flag.Parse()
-}
-
-func detectComponentType(packagePath string) ComponentType {
-	switch {
-	case strings.Contains(packagePath, "receiver"):
-		return Receiver
-	case strings.Contains(packagePath, "processor"):
-		return Processor
-	case strings.Contains(packagePath, "exporter"):
-		return Exporter
-	default:
-		return ""
-	}
 }
 
 func main() {
+	// Parsed in main rather than init, as the test binary has flags of its
+	// own.
+	flag.Parse()
 	if flag.NArg() == 0 {
 		flag.Usage()
 		return
 	}
+	if flag.Arg(0) == "validate" {
+		os.Exit(validate(flag.Args()[1:]))
+	}
 	packagePath := flag.Arg(0)
 	split := strings.Split(packagePath, "/")
 	packageName := split[len(split)-1]
@@ -54,24 +53,26 @@ func main() {
 		output = packageName + ".wasm"
 	}
 
-	if componentType == "" {
-		componentType = detectComponentType(packagePath)
-	}
-	if componentType == "" {
-	}
+	// The builder detects the type from the factory of the package, or
+	// checks the given one against it.
 	switch componentType {
-	case Receiver, Processor, Exporter:
+	case Receiver, Processor, Exporter, "":
 		// OK
-	case "":
-		slog.Error("Could not detect component type from package path", "packagePath", packagePath)
-		slog.Info("Please specify the component type using -type flag")
-		os.Exit(1)
 	default:
 		slog.Error("Invalid component type", "componentType", componentType)
 		slog.Info("Valid component types are: receiver, processor, exporter")
 		os.Exit(1)
 	}
 
+	switch compiler {
+	case GoCompiler, TinyGoCompiler:
+		// OK
+	default:
+		slog.Error("Invalid compiler", "compiler", compiler)
+		slog.Info("Valid compilers are: go, tinygo")
+		os.Exit(1)
+	}
+
 	if workDir == "" {
 		workDir = packageName
 	}
@@ -82,6 +83,9 @@ func main() {
 		Package:       packagePath,
 		PackageName:   packageName,
 		Output:        output,
+		Compiler:      compiler,
+		Tags:          tags,
+		LDFlags:       ldflags,
 	}
 
 	exitCode := 0
@@ -117,3 +121,42 @@ func main() {
 
 	slog.Info("Build completed successfully", "output", output)
 }
+
+// validate runs the validate subcommand, returning the exit code.
+func validate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	validator := &Validator{}
+	flags.StringVar((*string)(&validator.ComponentType), "type", "", "component type: receiver, processor, exporter, connector")
+	flags.StringVar(&validator.WorkDir, "workdir", "validate", "working directory")
+	flags.StringVar(&validator.WasmPluginDir, "wasmplugin", "", "directory of a local wasmplugin module to validate with (default: the published module)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s validate -type {type} {module}\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 || validator.ComponentType == "" {
+		flags.Usage()
+		return 1
+	}
+	validator.Module = flags.Arg(0)
+
+	defer func() {
+		if err := validator.Clean(); err != nil {
+			slog.Warn("Failed to clean up", "error", err)
+		}
+	}()
+
+	if err := validator.Prepare(); err != nil {
+		slog.Error("Failed to prepare validation", "error", err)
+		return 1
+	}
+	if err := validator.Validate(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		slog.Error("Failed to validate module", "error", err)
+		return 1
+	}
+	return 0
+}
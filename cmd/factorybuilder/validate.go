@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// wasmPluginModule is the module validating the wasm modules.
+const wasmPluginModule = "github.com/otelwasm/otelwasm/wasmplugin"
+
+// Validator checks a wasm module against the host, by running a program
+// calling wasmplugin.ValidateModule from the working directory. The program
+// is built there, like the guests, so factorybuilder itself doesn't depend
+// on the host.
+type Validator struct {
+	WorkDir       string
+	ComponentType ComponentType
+	// Module is the path of the wasm module to validate.
+	Module string
+	// WasmPluginDir is the directory of a local copy of wasmplugin to build
+	// the program with, instead of the published module.
+	WasmPluginDir string
+}
+
+func (v *Validator) Prepare() error {
+	err := os.MkdirAll(v.WorkDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create workdir %s: %w", v.WorkDir, err)
+	}
+
+	err = runIn(v.WorkDir, "go", "mod", "init", "validate")
+	if err != nil {
+		return fmt.Errorf("failed to init go module: %w", err)
+	}
+
+	if v.WasmPluginDir != "" {
+		dir, err := filepath.Abs(v.WasmPluginDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path of %s: %w", v.WasmPluginDir, err)
+		}
+		err = runIn(v.WorkDir, "go", "mod", "edit", "-require="+wasmPluginModule+"@v0.0.0", "-replace="+wasmPluginModule+"="+dir)
+		if err != nil {
+			return fmt.Errorf("failed to replace %s: %w", wasmPluginModule, err)
+		}
+		// The checksums of the local copy pin its dependencies.
+		sums, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+		if err != nil {
+			return fmt.Errorf("failed to read go.sum of %s: %w", dir, err)
+		}
+		err = os.WriteFile(filepath.Join(v.WorkDir, "go.sum"), sums, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to write go.sum: %w", err)
+		}
+	} else {
+		err = runIn(v.WorkDir, "go", "get", wasmPluginModule)
+		if err != nil {
+			return fmt.Errorf("failed to get package %s: %w", wasmPluginModule, err)
+		}
+	}
+
+	err = writeTemplate(filepath.Join(v.WorkDir, "main.go"), "validate.gotmpl", nil)
+	if err != nil {
+		return fmt.Errorf("failed to write template validate.gotmpl: %w", err)
+	}
+
+	err = runIn(v.WorkDir, "go", "mod", "tidy")
+	if err != nil {
+		return fmt.Errorf("failed to tidy go module: %w", err)
+	}
+
+	return nil
+}
+
+// Validate runs the validation. An *exec.ExitError with the exit code 2 is
+// returned if the module is invalid.
+func (v *Validator) Validate() error {
+	module, err := filepath.Abs(v.Module)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path of module %s: %w", v.Module, err)
+	}
+	program, err := filepath.Abs(filepath.Join(v.WorkDir, "validate"))
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path of workdir %s: %w", v.WorkDir, err)
+	}
+
+	// Built rather than run with go run, which doesn't keep the exit code.
+	err = runIn(v.WorkDir, "go", "build", "-o", program, ".")
+	if err != nil {
+		return fmt.Errorf("failed to build validation program: %w", err)
+	}
+	return runIn(v.WorkDir, program, "-type", string(v.ComponentType), module)
+}
+
+func (v *Validator) Clean() error {
+	err := os.RemoveAll(v.WorkDir)
+	if err != nil {
+		return fmt.Errorf("failed to remove workdir %s: %w", v.WorkDir, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the validation program")
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.wasm")
+	if err := os.WriteFile(invalid, []byte("not wasm"), 0o600); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+
+	v := &Validator{
+		WorkDir:       filepath.Join(t.TempDir(), "validate"),
+		ComponentType: Processor,
+		WasmPluginDir: filepath.Join("..", "..", "wasmplugin"),
+	}
+	if err := v.Prepare(); err != nil {
+		t.Fatalf("failed to prepare: %v", err)
+	}
+
+	v.Module = filepath.Join("..", "..", "examples", "processor", "nop", "main.wasm")
+	if err := v.Validate(); err != nil {
+		t.Errorf("expected the nop processor to be valid, got %v", err)
+	}
+
+	v.Module = invalid
+	var exitErr *exec.ExitError
+	if err := v.Validate(); !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Errorf("expected the exit code 2 for an invalid module, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/plugin" // register tracestometricsconnector
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func init() {
+	plugin.Set(&SpanCountConnector{})
+}
+func main() {}
+
+var _ api.TracesToMetricsConnector = (*SpanCountConnector)(nil)
+
+// SpanCountConnector counts the spans of each resource, as a delta sum.
+type SpanCountConnector struct{}
+
+// ConnectTracesToMetrics implements api.TracesToMetricsConnector.
+func (c *SpanCountConnector) ConnectTracesToMetrics(traces ptrace.Traces) (pmetric.Metrics, *api.Status) {
+	now := pcommon.NewTimestampFromTime(time.Now())
+	metrics := pmetric.NewMetrics()
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		rs := traces.ResourceSpans().At(i)
+		var count int64
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			count += int64(rs.ScopeSpans().At(j).Spans().Len())
+		}
+		if count == 0 {
+			continue
+		}
+
+		rm := metrics.ResourceMetrics().AppendEmpty()
+		rs.Resource().CopyTo(rm.Resource())
+		sm := rm.ScopeMetrics().AppendEmpty()
+		sm.Scope().SetName("spancount")
+		m := sm.Metrics().AppendEmpty()
+		m.SetName("span.count")
+		m.SetUnit("{span}")
+		sum := m.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		dp := sum.DataPoints().AppendEmpty()
+		dp.SetTimestamp(now)
+		dp.SetIntValue(count)
+	}
+	return metrics, nil
+}
@@ -33,10 +33,14 @@ func init() {
 		api.MetricsExporter
 		api.LogsExporter
 		api.TracesExporter
+		api.Shutdowner
+		api.Reconfigurer
 	}{
 		connector.Metrics(),
 		connector.Logs(),
 		connector.Traces(),
+		connector,
+		connector,
 	})
 }
 func main() {}
@@ -11,21 +11,9 @@ import (
 	"go.uber.org/zap"
 )
 
-// TODO: Fix the bug when we use sending queue on exporter.
-// Currently, the exporter is not working properly with sending queue because of the architecture of the wasm plugin.
-//
-// Here's the example of the configuration:
-//
-// ```yaml
-// exporters:
-//   wasm/otlphttpexporter:
-//     path: "/path/to/main.wasm"
-//     plugin_config:
-//       sending_queue:
-//         enabled: false
-// ```
-//
-// You MUST disable the sending queue when you use the otlphttpexporter built as a wasm module.
+// The sending queue is supported: the exporter connector makes it wait for
+// the export of each request before the push returns, as the queue can't be
+// drained once the guest call returns.
 // For more details, see https://github.com/otelwasm/otelwasm/issues/60
 
 func init() {
@@ -50,10 +38,14 @@ func init() {
 		api.MetricsExporter
 		api.LogsExporter
 		api.TracesExporter
+		api.Shutdowner
+		api.Reconfigurer
 	}{
 		connector.Metrics(),
 		connector.Logs(),
 		connector.Traces(),
+		connector,
+		connector,
 	})
 }
 
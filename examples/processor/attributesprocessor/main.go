@@ -36,10 +36,12 @@ func init() {
 		api.MetricsProcessor
 		api.LogsProcessor
 		api.TracesProcessor
+		api.Reconfigurer
 	}{
 		connector.Metrics(),
 		connector.Logs(),
 		connector.Traces(),
+		connector,
 	})
 }
 
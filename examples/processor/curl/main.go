@@ -2,10 +2,9 @@ package main
 
 import (
 	"io"
-	"net/http"
-	"time"
 
 	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/http"
 	"github.com/otelwasm/otelwasm/guest/plugin" // register tracesprocessor
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
@@ -23,16 +22,15 @@ var (
 	_ api.LogsProcessor    = (*CurlProcessor)(nil)
 )
 
-var httpClient = &http.Client{
-	Timeout: 60 * time.Second,
-}
-
+// CurlProcessor sends a request to example.com for every batch. The request
+// is sent by the host, so example.com must be in the http.allowed_hosts of
+// the plugin.
 type CurlProcessor struct{}
 
 // ProcessTraces implements api.TracesProcessor.
 func (n *CurlProcessor) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *api.Status) {
-	// Make a GET request to example.com using http.Client
-	resp, err := httpClient.Get("http://example.com")
+	// Make a GET request to example.com through the host
+	resp, err := http.Get("http://example.com")
 	if err != nil {
 		return traces, api.StatusError(err.Error())
 	}
@@ -54,8 +52,8 @@ func (n *CurlProcessor) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *api
 
 // ProcessMetrics implements api.MetricsProcessor.
 func (n *CurlProcessor) ProcessMetrics(metrics pmetric.Metrics) (pmetric.Metrics, *api.Status) {
-	// Make a GET request to example.com using http.Client
-	resp, err := httpClient.Get("http://example.com")
+	// Make a GET request to example.com through the host
+	resp, err := http.Get("http://example.com")
 	if err != nil {
 		return metrics, api.StatusError(err.Error())
 	}
@@ -77,8 +75,8 @@ func (n *CurlProcessor) ProcessMetrics(metrics pmetric.Metrics) (pmetric.Metrics
 
 // ProcessLogs implements api.LogsProcessor.
 func (n *CurlProcessor) ProcessLogs(logs plog.Logs) (plog.Logs, *api.Status) {
-	// Make a GET request to example.com using http.Client
-	resp, err := httpClient.Get("http://example.com")
+	// Make a GET request to example.com through the host
+	resp, err := http.Get("http://example.com")
 	if err != nil {
 		return logs, api.StatusError(err.Error())
 	}
@@ -2,36 +2,50 @@ package main
 
 import (
 	"github.com/otelwasm/otelwasm/guest/api"
-	"github.com/otelwasm/otelwasm/guest/plugin" // register tracesprocessor
+	"github.com/otelwasm/otelwasm/guest/imports"
+	"github.com/otelwasm/otelwasm/guest/profilesprocessor" // register the processors of every signal
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
 func init() {
-	plugin.Set(&NopProcessor{})
+	profilesprocessor.Set(&NopProcessor{})
 }
 func main() {}
 
 var (
-	_ api.TracesProcessor  = (*NopProcessor)(nil)
-	_ api.MetricsProcessor = (*NopProcessor)(nil)
-	_ api.LogsProcessor    = (*NopProcessor)(nil)
+	_ api.TracesProcessor   = (*NopProcessor)(nil)
+	_ api.MetricsProcessor  = (*NopProcessor)(nil)
+	_ api.LogsProcessor     = (*NopProcessor)(nil)
+	_ api.ProfilesProcessor = (*NopProcessor)(nil)
 )
 
+// NopProcessor passes the telemetry on as is. It reports the telemetry
+// unchanged, so it isn't serialized back to the host.
 type NopProcessor struct{}
 
 // ProcessTraces implements api.TracesProcessor.
 func (n *NopProcessor) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *api.Status) {
-	return traces, nil
+	imports.SetResultUnchanged()
+	return ptrace.Traces{}, nil
 }
 
 // ProcessMetrics implements api.MetricsProcessor.
 func (n *NopProcessor) ProcessMetrics(metrics pmetric.Metrics) (pmetric.Metrics, *api.Status) {
-	return metrics, nil
+	imports.SetResultUnchanged()
+	return pmetric.Metrics{}, nil
 }
 
 // ProcessLogs implements api.LogsProcessor.
 func (n *NopProcessor) ProcessLogs(logs plog.Logs) (plog.Logs, *api.Status) {
-	return logs, nil
+	imports.SetResultUnchanged()
+	return plog.Logs{}, nil
+}
+
+// ProcessProfiles implements api.ProfilesProcessor.
+func (n *NopProcessor) ProcessProfiles(profiles pprofile.Profiles) (pprofile.Profiles, *api.Status) {
+	imports.SetResultUnchanged()
+	return pprofile.Profiles{}, nil
 }
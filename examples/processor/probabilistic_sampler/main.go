@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/filter"
+	"github.com/otelwasm/otelwasm/guest/imports"
+	"github.com/otelwasm/otelwasm/guest/plugin" // register tracesprocessor
+	"github.com/otelwasm/otelwasm/guest/rand"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func init() {
+	plugin.Set(&ProbabilisticSampler{})
+}
+func main() {}
+
+var _ api.TracesProcessor = (*ProbabilisticSampler)(nil)
+
+// ProbabilisticSampler keeps a percentage of the traces. The decision is
+// made from the hash of the trace ID, so all the spans of a trace are kept
+// or dropped together, across batches and collectors. Spans without a trace
+// ID are sampled at random with the random numbers of the host.
+type ProbabilisticSampler struct {
+	config *Config
+	// configVersion is the version of the plugin config config was decoded
+	// from.
+	configVersion uint32
+}
+
+type Config struct {
+	// SamplingPercentage is the percentage of the traces kept, from 0 to
+	// 100.
+	SamplingPercentage float64 `json:"sampling_percentage"`
+}
+
+func (c *Config) Validate() error {
+	if c.SamplingPercentage < 0 || c.SamplingPercentage > 100 || math.IsNaN(c.SamplingPercentage) {
+		return fmt.Errorf("sampling_percentage must be between 0 and 100, got %v", c.SamplingPercentage)
+	}
+	return nil
+}
+
+// loadConfig decodes the plugin config, unless it was already decoded and
+// the host didn't update it since.
+func (p *ProbabilisticSampler) loadConfig() (*Config, error) {
+	version := imports.GetConfigVersion()
+	if p.config != nil && version == p.configVersion {
+		return p.config, nil
+	}
+	config := &Config{}
+	if err := imports.GetConfig(config); err != nil {
+		return nil, fmt.Errorf("failed to decode the config: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	p.config, p.configVersion = config, version
+	return config, nil
+}
+
+// ProcessTraces implements api.TracesProcessor.
+func (p *ProbabilisticSampler) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *api.Status) {
+	config, err := p.loadConfig()
+	if err != nil {
+		return ptrace.Traces{}, api.StatusError(err.Error())
+	}
+
+	// The hashes are compared to the threshold scaled to the 64-bit range.
+	ratio := config.SamplingPercentage / 100
+	threshold := uint64(ratio * math.MaxUint64)
+	return filter.Spans(traces, func(span ptrace.Span) bool {
+		if ratio >= 1 {
+			return true
+		}
+		traceID := span.TraceID()
+		if traceID.IsEmpty() {
+			return rand.Float64() < ratio
+		}
+		return hashTraceID(traceID) < threshold
+	}), api.StatusSuccess()
+}
+
+// hashTraceID spreads the trace IDs evenly over the 64-bit range, in case
+// their bits aren't random.
+func hashTraceID(traceID [16]byte) uint64 {
+	h := fnv.New64a()
+	h.Write(traceID[:])
+	return h.Sum64()
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/crypto"
+	"github.com/otelwasm/otelwasm/guest/imports"
+	"github.com/otelwasm/otelwasm/guest/plugin" // register processors
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func init() {
+	plugin.Set(&RedactProcessor{})
+}
+func main() {}
+
+var (
+	_ api.TracesProcessor  = (*RedactProcessor)(nil)
+	_ api.MetricsProcessor = (*RedactProcessor)(nil)
+	_ api.LogsProcessor    = (*RedactProcessor)(nil)
+)
+
+// RedactProcessor masks or hashes the values of the attributes whose keys
+// match the configured patterns, on the resources, scopes, spans, span
+// events, metric data points and log records. The attributes nested in map
+// and slice values are redacted too. The telemetry is redacted in place, and
+// passed on without copying it.
+type RedactProcessor struct {
+	// cfg is the config decoded from the plugin config of version
+	// cfgVersion.
+	cfg        *Config
+	cfgVersion uint32
+}
+
+const (
+	actionMask = "mask"
+	actionHash = "hash"
+)
+
+type Config struct {
+	// Patterns are the regular expressions matched against the attribute
+	// keys.
+	Patterns []string `json:"patterns"`
+	// Action is "mask", replacing the values with Mask, or "hash",
+	// replacing them with their salted SHA-256 digest in hex, keyed by the
+	// hash.salt option of the plugin. The default is "mask".
+	Action string `json:"action"`
+	// Mask replaces the masked values. The default is "****".
+	Mask string `json:"mask"`
+
+	patterns []*regexp.Regexp
+}
+
+func (c *Config) Validate() error {
+	if len(c.Patterns) == 0 {
+		return errors.New("patterns is required")
+	}
+	c.patterns = c.patterns[:0]
+	for i, pattern := range c.Patterns {
+		if pattern == "" {
+			return fmt.Errorf("patterns[%d] can't be empty", i)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("patterns[%d]: %w", i, err)
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	switch c.Action {
+	case "":
+		c.Action = actionMask
+	case actionMask, actionHash:
+	default:
+		return fmt.Errorf("action must be %q or %q, got %q", actionMask, actionHash, c.Action)
+	}
+	if c.Mask == "" {
+		c.Mask = "****"
+	}
+	return nil
+}
+
+// config returns the config, decoded again once the host updates it.
+func (p *RedactProcessor) config() (*Config, *api.Status) {
+	version := imports.GetConfigVersion()
+	if p.cfg != nil && version == p.cfgVersion {
+		return p.cfg, nil
+	}
+	cfg := &Config{}
+	if err := imports.GetConfig(cfg); err != nil {
+		return nil, api.StatusError(fmt.Sprintf("failed to get config: %v", err))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, api.StatusError(err.Error())
+	}
+	p.cfg, p.cfgVersion = cfg, version
+	return cfg, nil
+}
+
+// ProcessTraces implements api.TracesProcessor.
+func (p *RedactProcessor) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *api.Status) {
+	cfg, status := p.config()
+	if status != nil {
+		return ptrace.Traces{}, status
+	}
+	r := redactor{cfg: cfg}
+	rSpans := traces.ResourceSpans()
+	for i := 0; i < rSpans.Len(); i++ {
+		r.redact(rSpans.At(i).Resource().Attributes())
+		scopeSpans := rSpans.At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			r.redact(scopeSpans.At(j).Scope().Attributes())
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				r.redact(span.Attributes())
+				events := span.Events()
+				for l := 0; l < events.Len(); l++ {
+					r.redact(events.At(l).Attributes())
+				}
+			}
+		}
+	}
+	if r.err != nil {
+		return ptrace.Traces{}, api.StatusPermanent(r.err.Error())
+	}
+	return traces, api.StatusSuccess()
+}
+
+// ProcessMetrics implements api.MetricsProcessor.
+func (p *RedactProcessor) ProcessMetrics(metrics pmetric.Metrics) (pmetric.Metrics, *api.Status) {
+	cfg, status := p.config()
+	if status != nil {
+		return pmetric.Metrics{}, status
+	}
+	r := redactor{cfg: cfg}
+	rMetrics := metrics.ResourceMetrics()
+	for i := 0; i < rMetrics.Len(); i++ {
+		r.redact(rMetrics.At(i).Resource().Attributes())
+		scopeMetrics := rMetrics.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			r.redact(scopeMetrics.At(j).Scope().Attributes())
+			ms := scopeMetrics.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				r.redactMetric(ms.At(k))
+			}
+		}
+	}
+	if r.err != nil {
+		return pmetric.Metrics{}, api.StatusPermanent(r.err.Error())
+	}
+	return metrics, api.StatusSuccess()
+}
+
+// ProcessLogs implements api.LogsProcessor.
+func (p *RedactProcessor) ProcessLogs(logs plog.Logs) (plog.Logs, *api.Status) {
+	cfg, status := p.config()
+	if status != nil {
+		return plog.Logs{}, status
+	}
+	r := redactor{cfg: cfg}
+	rLogs := logs.ResourceLogs()
+	for i := 0; i < rLogs.Len(); i++ {
+		r.redact(rLogs.At(i).Resource().Attributes())
+		scopeLogs := rLogs.At(i).ScopeLogs()
+		for j := 0; j < scopeLogs.Len(); j++ {
+			r.redact(scopeLogs.At(j).Scope().Attributes())
+			records := scopeLogs.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				r.redact(records.At(k).Attributes())
+			}
+		}
+	}
+	if r.err != nil {
+		return plog.Logs{}, api.StatusPermanent(r.err.Error())
+	}
+	return logs, api.StatusSuccess()
+}
+
+// redactor redacts the attributes of a batch, recording the first error,
+// e.g. a missing salt, so the batch isn't passed on half redacted.
+type redactor struct {
+	cfg *Config
+	err error
+}
+
+func (r *redactor) redactMetric(m pmetric.Metric) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := m.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			r.redact(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeSum:
+		dps := m.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			r.redact(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		dps := m.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			r.redact(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := m.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			r.redact(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeSummary:
+		dps := m.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			r.redact(dps.At(i).Attributes())
+		}
+	}
+}
+
+// redact redacts the values of the keys of attrs matching the patterns, and
+// the attributes nested in the values of the other keys.
+func (r *redactor) redact(attrs pcommon.Map) {
+	attrs.Range(func(key string, v pcommon.Value) bool {
+		if r.matches(key) {
+			r.replace(v)
+		} else {
+			r.redactNested(v)
+		}
+		return r.err == nil
+	})
+}
+
+func (r *redactor) redactNested(v pcommon.Value) {
+	switch v.Type() {
+	case pcommon.ValueTypeMap:
+		r.redact(v.Map())
+	case pcommon.ValueTypeSlice:
+		s := v.Slice()
+		for i := 0; i < s.Len() && r.err == nil; i++ {
+			r.redactNested(s.At(i))
+		}
+	}
+}
+
+func (r *redactor) matches(key string) bool {
+	for _, re := range r.cfg.patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// replace replaces v, whatever its type, with the mask or the digest of its
+// string representation, e.g. the JSON of a map.
+func (r *redactor) replace(v pcommon.Value) {
+	if r.cfg.Action == actionMask {
+		v.SetStr(r.cfg.Mask)
+		return
+	}
+	digest, err := crypto.SaltedSHA256Hex(v.AsString())
+	if err != nil {
+		r.err = fmt.Errorf("failed to hash attribute value: %w", err)
+		return
+	}
+	v.SetStr(digest)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/crypto"
+	"github.com/otelwasm/otelwasm/guest/plugin" // register tracesprocessor
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func init() {
+	plugin.Set(&RedactEmailProcessor{})
+}
+func main() {}
+
+var _ api.TracesProcessor = (*RedactEmailProcessor)(nil)
+
+// RedactEmailProcessor replaces the span attribute values holding an email
+// address with their salted SHA-256 digest, in hex, so the spans of a user
+// can still be correlated without exposing the address. The salt is the
+// hash.salt option of the plugin.
+type RedactEmailProcessor struct{}
+
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// ProcessTraces implements api.TracesProcessor.
+func (p *RedactEmailProcessor) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *api.Status) {
+	rSpans := traces.ResourceSpans()
+	for i := 0; i < rSpans.Len(); i++ {
+		scopeSpans := rSpans.At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if err := redact(spans.At(k).Attributes()); err != nil {
+					return ptrace.Traces{}, api.StatusPermanent(fmt.Sprintf("failed to hash email: %v", err))
+				}
+			}
+		}
+	}
+	return traces, api.StatusSuccess()
+}
+
+// redact replaces the email addresses of attrs with their salted digest.
+func redact(attrs pcommon.Map) error {
+	var err error
+	attrs.Range(func(_ string, v pcommon.Value) bool {
+		if v.Type() != pcommon.ValueTypeStr || !emailPattern.MatchString(v.Str()) {
+			return true
+		}
+		var digest string
+		if digest, err = crypto.SaltedSHA256Hex(v.Str()); err != nil {
+			return false
+		}
+		v.SetStr(digest)
+		return true
+	})
+	return err
+}
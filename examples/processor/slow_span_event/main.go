@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/imports"
+	"github.com/otelwasm/otelwasm/guest/plugin" // register tracesprocessor
+	"github.com/otelwasm/otelwasm/guest/spanevent"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func init() {
+	plugin.Set(&SlowSpanProcessor{})
+}
+func main() {}
+
+var _ api.TracesProcessor = (*SlowSpanProcessor)(nil)
+
+// SlowSpanEventName is the name of the event recorded on the slow spans.
+const SlowSpanEventName = "otelwasm.slow_span"
+
+// SlowSpanProcessor records an event on the spans lasting longer than the
+// configured threshold, so the spans are flagged without being dropped. The
+// traces are modified in place.
+type SlowSpanProcessor struct {
+	// cfg is the config decoded from the plugin config of version
+	// cfgVersion.
+	cfg        *Config
+	cfgVersion uint32
+}
+
+type Config struct {
+	// Threshold is the duration past which a span is slow, e.g. "500ms".
+	Threshold string `json:"threshold"`
+
+	threshold time.Duration
+}
+
+func (c *Config) Validate() error {
+	if c.Threshold == "" {
+		return fmt.Errorf("threshold is required")
+	}
+	d, err := time.ParseDuration(c.Threshold)
+	if err != nil {
+		return fmt.Errorf("threshold: %w", err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("threshold must be positive")
+	}
+	c.threshold = d
+	return nil
+}
+
+// config returns the config, decoded again once the host updates it.
+func (p *SlowSpanProcessor) config() (*Config, *api.Status) {
+	version := imports.GetConfigVersion()
+	if p.cfg != nil && version == p.cfgVersion {
+		return p.cfg, nil
+	}
+	cfg := &Config{}
+	if err := imports.GetConfig(cfg); err != nil {
+		return nil, api.StatusError(fmt.Sprintf("failed to get config: %v", err))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, api.StatusError(err.Error())
+	}
+	p.cfg, p.cfgVersion = cfg, version
+	return cfg, nil
+}
+
+// ProcessTraces implements api.TracesProcessor.
+func (p *SlowSpanProcessor) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *api.Status) {
+	cfg, status := p.config()
+	if status != nil {
+		return ptrace.Traces{}, status
+	}
+	rSpans := traces.ResourceSpans()
+	for i := 0; i < rSpans.Len(); i++ {
+		scopeSpans := rSpans.At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				duration := span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime())
+				if duration <= cfg.threshold {
+					continue
+				}
+				// The event is timestamped with the end of the span, when it
+				// was known to be slow.
+				if _, err := spanevent.Add(span, spanevent.Event{
+					Name:      SlowSpanEventName,
+					Timestamp: span.EndTimestamp().AsTime(),
+					Attributes: map[string]any{
+						"duration_ms":  duration.Milliseconds(),
+						"threshold_ms": cfg.threshold.Milliseconds(),
+					},
+				}); err != nil {
+					return ptrace.Traces{}, api.StatusError(err.Error())
+				}
+			}
+		}
+	}
+	return traces, api.StatusSuccess()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/hostfile"
+	"github.com/otelwasm/otelwasm/guest/imports"
+	"github.com/otelwasm/otelwasm/guest/logging"
+	"github.com/otelwasm/otelwasm/guest/plugin" // register logsreceiver
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func init() {
+	plugin.Set(&FileTail{})
+}
+func main() {}
+
+var _ api.LogsReceiver = (*FileTail)(nil)
+
+// readSize is the size of the reads of the file.
+const readSize = 64 << 10
+
+// maxLineSize is the size past which a line without its end is emitted as
+// is, so a file without line ends doesn't grow the guest memory.
+const maxLineSize = 1 << 20
+
+// FileTail emits a log record for each line appended to a host file,
+// like the filelog receiver. The file is read through the host, which must
+// allow it with the files.allowed_paths option. The file is read from the
+// start again once it is truncated, e.g. by a copytruncate rotation.
+type FileTail struct{}
+
+type Config struct {
+	// Path is the absolute path of the file.
+	Path string `json:"path"`
+	// PollInterval is the interval between the reads of the file. The
+	// default is 200ms.
+	PollInterval string `json:"poll_interval"`
+	// StartAt is where the file is read from at start, "end", the default,
+	// to only emit the lines appended since, or "beginning".
+	StartAt string `json:"start_at"`
+}
+
+// tailer reads the lines appended to a file.
+type tailer struct {
+	path   string
+	offset int64
+	// pending is the end of the file past the last line end.
+	pending []byte
+	buf     []byte
+}
+
+// StartLogs implements api.LogsReceiver.
+func (f *FileTail) StartLogs(ctx context.Context) {
+	logger := logging.NewLogger()
+	var config Config
+	if err := imports.GetConfig(&config); err != nil {
+		logger.Error("Failed to decode the config", zap.Error(err))
+		return
+	}
+	interval := 200 * time.Millisecond
+	if config.PollInterval != "" {
+		d, err := time.ParseDuration(config.PollInterval)
+		if err != nil || d <= 0 {
+			logger.Error("Invalid poll_interval", zap.String("poll_interval", config.PollInterval))
+			return
+		}
+		interval = d
+	}
+
+	t := &tailer{path: config.Path, buf: make([]byte, readSize)}
+	switch config.StartAt {
+	case "", "end":
+		// The file may not exist yet, and is then read from the start.
+		if info, err := hostfile.Stat(t.path); err == nil {
+			t.offset = info.Size
+		}
+	case "beginning":
+	default:
+		logger.Error("Invalid start_at, must be beginning or end", zap.String("start_at", config.StartAt))
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		lines, err := t.poll()
+		if err != nil {
+			logger.Warn("Failed to read the file", zap.Error(err))
+		}
+		if len(lines) > 0 {
+			imports.SetResultLogs(t.logs(lines))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll returns the lines appended to the file since the last poll.
+func (t *tailer) poll() ([][]byte, error) {
+	info, err := hostfile.Stat(t.path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size < t.offset {
+		// The file was truncated.
+		t.offset, t.pending = 0, nil
+	}
+
+	var lines [][]byte
+	for t.offset < info.Size {
+		n, err := hostfile.ReadAt(t.path, t.buf, t.offset)
+		if err != nil && err != io.EOF {
+			return lines, err
+		}
+		if n == 0 {
+			break
+		}
+		t.offset += int64(n)
+		data := append(t.pending, t.buf[:n]...)
+		for {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			lines = append(lines, bytes.TrimSuffix(data[:i], []byte("\r")))
+			data = data[i+1:]
+		}
+		if len(data) > maxLineSize {
+			lines, data = append(lines, data), nil
+		}
+		t.pending = bytes.Clone(data)
+	}
+	return lines, nil
+}
+
+// logs returns a log record for each line, as the filelog receiver does.
+func (t *tailer) logs(lines [][]byte) plog.Logs {
+	ld := plog.NewLogs()
+	scopeLogs := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	records := scopeLogs.LogRecords()
+	records.EnsureCapacity(len(lines))
+	now := pcommon.NewTimestampFromTime(time.Now())
+	for _, line := range lines {
+		record := records.AppendEmpty()
+		record.SetObservedTimestamp(now)
+		record.Body().SetStr(string(line))
+		record.Attributes().PutStr("log.file.path", t.path)
+	}
+	return ld
+}
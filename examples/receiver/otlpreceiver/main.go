@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+
 	"github.com/otelwasm/otelwasm/guest/api"
 	"github.com/otelwasm/otelwasm/guest/factoryconnector"
 	"github.com/otelwasm/otelwasm/guest/plugin" // register receivers
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/receiver"
 	"go.opentelemetry.io/collector/receiver/otlpreceiver"
 	"go.uber.org/zap"
@@ -13,15 +17,58 @@ import (
 
 // TODO: Fix the bug when using the gRPC endpoint.
 // Currently, the gRPC endpoint is not working properly due to the panic while handling the incoming request.
+// Until then, gRPC is disabled by default and the receiver fails to start if it is configured.
 // For more details, see https://github.com/otelwasm/otelwasm/issues/59
 
+var errGRPCUnsupported = errors.New("protocols::grpc is not supported by the wasm otlpreceiver, use protocols::http (see https://github.com/otelwasm/otelwasm/issues/59)")
+
+// httpOnlyFactory is the otlpreceiver factory, with gRPC disabled by default
+// and rejected if configured.
+type httpOnlyFactory struct {
+	receiver.Factory
+}
+
+func (f httpOnlyFactory) CreateDefaultConfig() component.Config {
+	cfg := f.Factory.CreateDefaultConfig().(*otlpreceiver.Config)
+	cfg.GRPC = nil
+	return cfg
+}
+
+func (f httpOnlyFactory) CreateTraces(ctx context.Context, set receiver.Settings, cfg component.Config, next consumer.Traces) (receiver.Traces, error) {
+	if err := checkProtocols(cfg); err != nil {
+		return nil, err
+	}
+	return f.Factory.CreateTraces(ctx, set, cfg, next)
+}
+
+func (f httpOnlyFactory) CreateMetrics(ctx context.Context, set receiver.Settings, cfg component.Config, next consumer.Metrics) (receiver.Metrics, error) {
+	if err := checkProtocols(cfg); err != nil {
+		return nil, err
+	}
+	return f.Factory.CreateMetrics(ctx, set, cfg, next)
+}
+
+func (f httpOnlyFactory) CreateLogs(ctx context.Context, set receiver.Settings, cfg component.Config, next consumer.Logs) (receiver.Logs, error) {
+	if err := checkProtocols(cfg); err != nil {
+		return nil, err
+	}
+	return f.Factory.CreateLogs(ctx, set, cfg, next)
+}
+
+func checkProtocols(cfg component.Config) error {
+	if cfg.(*otlpreceiver.Config).GRPC != nil {
+		return errGRPCUnsupported
+	}
+	return nil
+}
+
 func init() {
 	logger, err := zap.NewProduction()
 	if err != nil {
 		panic(err)
 	}
 
-	factory := otlpreceiver.NewFactory()
+	factory := httpOnlyFactory{otlpreceiver.NewFactory()}
 	telemetrySettings := componenttest.NewNopTelemetrySettings()
 	telemetrySettings.Logger = logger
 
@@ -8,12 +8,24 @@ type Status struct {
 
 type StatusCode int32
 
-// These are predefined codes used in a Status.
+// These are predefined codes used in a Status. Hosts predating a code
+// handle it as StatusCodeError.
 const (
 	// Completed without errors.
 	StatusCodeSuccess StatusCode = iota
 	// Exited with unexpected errors.
 	StatusCodeError
+	// Rejected the input as invalid, which the collector doesn't retry.
+	StatusCodeInvalidArgument
+	// Failed in a way retrying wouldn't fix, e.g. the destination of an
+	// exporter refused the data. The collector doesn't retry it.
+	StatusCodePermanent
+	// Failed in a way retrying may fix, e.g. the destination of an exporter
+	// was unreachable.
+	StatusCodeRetryable
+	// Refused to slow the collector down, e.g. the destination of an
+	// exporter is rate limited. Retried like StatusCodeRetryable.
+	StatusCodeThrottled
 )
 
 func StatusSuccess() *Status {
@@ -23,3 +35,19 @@ func StatusSuccess() *Status {
 func StatusError(reason string) *Status {
 	return &Status{Code: StatusCodeError, Reason: reason}
 }
+
+// StatusPermanent returns the status of a failure retrying wouldn't fix.
+func StatusPermanent(reason string) *Status {
+	return &Status{Code: StatusCodePermanent, Reason: reason}
+}
+
+// StatusRetryable returns the status of a failure retrying may fix.
+func StatusRetryable(reason string) *Status {
+	return &Status{Code: StatusCodeRetryable, Reason: reason}
+}
+
+// StatusThrottled returns the status of a refusal to slow the collector
+// down.
+func StatusThrottled(reason string) *Status {
+	return &Status{Code: StatusCodeThrottled, Reason: reason}
+}
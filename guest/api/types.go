@@ -5,6 +5,7 @@ import (
 
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
@@ -28,24 +29,43 @@ type MetricsReceiver interface {
 	StartMetrics(ctx context.Context)
 }
 
+// TracesProcessor processes the traces of a pipeline. Returning the zero
+// ptrace.Traces{} sets no result: the host passes on the traces it passed to
+// the call, without them being serialized back, as a processor leaving them
+// unchanged would; changes made to them in place are lost. Returning empty
+// traces, such as ptrace.NewTraces(), drops them all.
 type TracesProcessor interface {
 	Plugin
 
 	ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *Status)
 }
 
+// MetricsProcessor processes the metrics of a pipeline. Like for
+// TracesProcessor, returning the zero pmetric.Metrics{} passes the metrics on
+// unchanged.
 type MetricsProcessor interface {
 	Plugin
 
 	ProcessMetrics(metrics pmetric.Metrics) (pmetric.Metrics, *Status)
 }
 
+// LogsProcessor processes the logs of a pipeline. Like for TracesProcessor,
+// returning the zero plog.Logs{} passes the logs on unchanged.
 type LogsProcessor interface {
 	Plugin
 
 	ProcessLogs(logs plog.Logs) (plog.Logs, *Status)
 }
 
+// ProfilesProcessor processes the profiles of a pipeline. Like for
+// TracesProcessor, returning the zero pprofile.Profiles{} passes the profiles
+// on unchanged. It is registered with profilesprocessor.Set, not plugin.Set.
+type ProfilesProcessor interface {
+	Plugin
+
+	ProcessProfiles(profiles pprofile.Profiles) (pprofile.Profiles, *Status)
+}
+
 type TracesExporter interface {
 	Plugin
 
@@ -63,3 +83,61 @@ type LogsExporter interface {
 
 	PushLogs(logs plog.Logs) *Status
 }
+
+// TracesToMetricsConnector derives metrics from the traces of a pipeline, to
+// feed a metrics pipeline.
+type TracesToMetricsConnector interface {
+	Plugin
+
+	ConnectTracesToMetrics(traces ptrace.Traces) (pmetric.Metrics, *Status)
+}
+
+// Starter is implemented by plugins setting up resources once, such as a
+// connection pool, when the host starts their component, rather than lazily
+// on their first call. The host calls Start at most once.
+type Starter interface {
+	Plugin
+
+	Start(ctx context.Context) *Status
+}
+
+// Shutdowner is implemented by plugins buffering data or holding resources,
+// so they can flush or release them when the host shuts them down. The host
+// calls Shutdown at most once, with imports.GetShutdownRequested reporting
+// the shutdown as requested.
+type Shutdowner interface {
+	Plugin
+
+	Shutdown(ctx context.Context) *Status
+}
+
+// Reconfigurer is implemented by plugins caching their config, so they can
+// read it again when the host updates it.
+type Reconfigurer interface {
+	Plugin
+
+	Reconfigure(ctx context.Context) *Status
+}
+
+// Capabilities are the capabilities a plugin declares to the host.
+type Capabilities struct {
+	// MutatesData is set if the plugin mutates the data it's passed. The host
+	// assumes it is unless the plugin declares otherwise.
+	MutatesData bool
+
+	// TracesJSON is set if the plugin prefers the traces passed in OTLP JSON
+	// rather than protobuf, e.g. to read the guest memory dumps during
+	// development. The host decides, see the traces_encoding option, and
+	// the SDK decodes either encoding. The SDK writes the traces in the
+	// encoding of the last traces read, in the preferred one until then.
+	TracesJSON bool
+}
+
+// CapabilitiesDeclarer is implemented by plugins declaring their
+// capabilities, e.g. so the host doesn't clone data for processors that
+// don't mutate it.
+type CapabilitiesDeclarer interface {
+	Plugin
+
+	Capabilities() Capabilities
+}
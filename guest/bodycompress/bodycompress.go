@@ -0,0 +1,127 @@
+// Package bodycompress gzips large log record bodies, so bandwidth-sensitive
+// exporters send less data.
+//
+// A compressed body is replaced by the gzipped bytes of its string or bytes
+// value, and the record is marked with the EncodingAttribute. Bodies of other
+// types are left untouched.
+package bodycompress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// EncodingAttribute is the log record attribute marking a compressed
+	// body.
+	EncodingAttribute = "log.body.encoding"
+	// EncodingGzip is the value of EncodingAttribute for gzipped bodies.
+	EncodingGzip = "gzip"
+
+	// DefaultThreshold is the body size compressed if none is configured, in
+	// bytes.
+	DefaultThreshold = 1024
+)
+
+// Config is the configuration of the compression.
+type Config struct {
+	// Threshold is the size above which bodies are compressed, in bytes.
+	// Defaults to DefaultThreshold.
+	Threshold int `json:"threshold"`
+	// Level is the gzip compression level, from 1 to 9. Defaults to
+	// gzip.DefaultCompression.
+	Level int `json:"level"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative")
+	}
+	if c.Level != 0 && (c.Level < gzip.BestSpeed || c.Level > gzip.BestCompression) {
+		return fmt.Errorf("invalid compression level %d", c.Level)
+	}
+	return nil
+}
+
+// Logs compresses the bodies of ld over the threshold and reports whether ld
+// was mutated.
+func Logs(ld plog.Logs, cfg Config) (mutated bool, err error) {
+	if err := cfg.Validate(); err != nil {
+		return false, err
+	}
+	threshold := cfg.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+	level := cfg.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		sls := rls.At(i).ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			lrs := sls.At(j).LogRecords()
+			for k := 0; k < lrs.Len(); k++ {
+				lr := lrs.At(k)
+				if _, ok := lr.Attributes().Get(EncodingAttribute); ok {
+					continue
+				}
+				body := bodyBytes(lr.Body())
+				if len(body) <= threshold {
+					continue
+				}
+
+				buf.Reset()
+				w, err := gzip.NewWriterLevel(&buf, level)
+				if err != nil {
+					return mutated, err
+				}
+				if _, err := w.Write(body); err != nil {
+					return mutated, fmt.Errorf("failed to compress body: %w", err)
+				}
+				if err := w.Close(); err != nil {
+					return mutated, fmt.Errorf("failed to compress body: %w", err)
+				}
+				lr.Body().SetEmptyBytes().FromRaw(buf.Bytes())
+				lr.Attributes().PutStr(EncodingAttribute, EncodingGzip)
+				mutated = true
+			}
+		}
+	}
+	return mutated, nil
+}
+
+// bodyBytes returns the bytes of a string or bytes body, or nil for bodies of
+// other types.
+func bodyBytes(body pcommon.Value) []byte {
+	switch body.Type() {
+	case pcommon.ValueTypeStr:
+		return []byte(body.Str())
+	case pcommon.ValueTypeBytes:
+		return body.Bytes().AsRaw()
+	}
+	return nil
+}
+
+// Decompress returns the uncompressed body of a record compressed by Logs.
+// Bodies of records which weren't compressed are returned as they are.
+func Decompress(lr plog.LogRecord) ([]byte, error) {
+	if v, ok := lr.Attributes().Get(EncodingAttribute); !ok || v.Str() != EncodingGzip {
+		return bodyBytes(lr.Body()), nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(lr.Body().Bytes().AsRaw()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress body: %w", err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
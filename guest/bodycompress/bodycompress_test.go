@@ -0,0 +1,88 @@
+package bodycompress
+
+import (
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// newLogs returns logs with a record per body.
+func newLogs(bodies ...string) plog.Logs {
+	ld := plog.NewLogs()
+	lrs := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for _, body := range bodies {
+		lrs.AppendEmpty().Body().SetStr(body)
+	}
+	return ld
+}
+
+func TestLogs(t *testing.T) {
+	large := strings.Repeat("request failed: connection reset by peer\n", 64)
+	ld := newLogs("small", large)
+
+	mutated, err := Logs(ld, Config{})
+	if err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	if !mutated {
+		t.Error("expected logs to be mutated")
+	}
+
+	lrs := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	small := lrs.At(0)
+	if small.Body().Str() != "small" || small.Attributes().Len() != 0 {
+		t.Errorf("expected the small body to be untouched, got %v %v", small.Body().AsRaw(), small.Attributes().AsRaw())
+	}
+
+	compressed := lrs.At(1)
+	if v, _ := compressed.Attributes().Get(EncodingAttribute); v.Str() != EncodingGzip {
+		t.Errorf("expected the large body to be marked as gzipped, got %v", compressed.Attributes().AsRaw())
+	}
+	if got := compressed.Body().Bytes().Len(); got >= len(large) {
+		t.Errorf("expected the compressed body to be smaller than %d bytes, got %d", len(large), got)
+	}
+	body, err := Decompress(compressed)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+	if string(body) != large {
+		t.Error("expected the decompressed body to match the original")
+	}
+
+	// Compressed bodies aren't compressed again.
+	if mutated, _ := Logs(ld, Config{}); mutated {
+		t.Error("expected compressed logs not to be mutated again")
+	}
+}
+
+func TestLogsThreshold(t *testing.T) {
+	ld := newLogs("0123456789")
+	if mutated, _ := Logs(ld, Config{Threshold: 10}); mutated {
+		t.Error("expected a body at the threshold not to be compressed")
+	}
+	if mutated, _ := Logs(ld, Config{Threshold: 9}); !mutated {
+		t.Error("expected a body over the threshold to be compressed")
+	}
+}
+
+func TestLogsSkipsStructuredBodies(t *testing.T) {
+	ld := plog.NewLogs()
+	lr := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	lr.Body().SetEmptyMap().PutStr("message", strings.Repeat("x", 2*DefaultThreshold))
+
+	if mutated, _ := Logs(ld, Config{}); mutated {
+		t.Error("expected a map body not to be compressed")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"negative threshold": {Threshold: -1},
+		"invalid level":      {Level: 10},
+	} {
+		if _, err := Logs(newLogs("body"), cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
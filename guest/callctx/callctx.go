@@ -0,0 +1,103 @@
+// Package callctx exposes the deadline of the current guest call, and the
+// shutdown of the pipeline, as a context.Context, so guests can abort
+// expensive work, e.g. processors, whose functions take no context.
+//
+// The host serves the deadline with the getDeadlineUnixNano host function,
+// so guests using the package don't run on hosts predating it.
+package callctx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// ErrShutdown is the error of the contexts done because the host requested
+// the shutdown of the guest. It matches context.Canceled.
+var ErrShutdown = fmt.Errorf("shutdown requested: %w", context.Canceled)
+
+// deadlineUnixNano, shutdownRequested and now read from the host, replaced
+// in tests.
+var (
+	deadlineUnixNano  = imports.DeadlineUnixNano
+	shutdownRequested = imports.GetShutdownRequested
+	now               = func() time.Time {
+		wall, _ := imports.HostNow()
+		return time.Unix(0, wall)
+	}
+)
+
+// Deadline returns the deadline of the current call, set by the context of
+// the call on the host or the execution timeout of the plugin, whichever is
+// earlier. ok is false if the call has no deadline.
+func Deadline() (deadline time.Time, ok bool) {
+	ns := deadlineUnixNano()
+	if ns == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
+
+// Context returns a context done once the deadline of the current call
+// passes, or the host requests the shutdown of the guest. It must only be
+// used during the call it was returned in.
+//
+// The deadline is checked against the host clock, as the WASI clock of the
+// guest may drift. Done is only closed while the guest yields, e.g. waits in
+// a select, so busy loops should poll Err instead.
+func Context() context.Context {
+	deadline, _ := Deadline()
+	return &callContext{deadline: deadline, done: make(chan struct{})}
+}
+
+type callContext struct {
+	deadline time.Time
+
+	mu    sync.Mutex
+	done  chan struct{}
+	timer *time.Timer
+	err   error
+}
+
+func (c *callContext) Deadline() (time.Time, bool) {
+	return c.deadline, !c.deadline.IsZero()
+}
+
+func (c *callContext) Done() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil && c.timer == nil && !c.deadline.IsZero() {
+		c.timer = time.AfterFunc(c.deadline.Sub(now()), func() { c.Err() })
+	}
+	return c.done
+}
+
+// Err checks the deadline and the shutdown on every call, so it is up to
+// date even if the timer closing Done didn't get to run.
+func (c *callContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	switch {
+	case shutdownRequested():
+		c.err = ErrShutdown
+	case !c.deadline.IsZero() && !now().Before(c.deadline):
+		c.err = context.DeadlineExceeded
+	default:
+		return nil
+	}
+	close(c.done)
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	return c.err
+}
+
+func (c *callContext) Value(key any) any {
+	return nil
+}
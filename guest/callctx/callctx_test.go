@@ -0,0 +1,106 @@
+package callctx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeHost makes the host report the given deadline, zero for none, and the
+// given time.
+func fakeHost(t *testing.T, deadline time.Time, current *time.Time, shutdown *bool) {
+	t.Helper()
+	prevDeadline, prevShutdown, prevNow := deadlineUnixNano, shutdownRequested, now
+	t.Cleanup(func() { deadlineUnixNano, shutdownRequested, now = prevDeadline, prevShutdown, prevNow })
+	deadlineUnixNano = func() int64 {
+		if deadline.IsZero() {
+			return 0
+		}
+		return deadline.UnixNano()
+	}
+	shutdownRequested = func() bool { return *shutdown }
+	now = func() time.Time { return *current }
+}
+
+func TestDeadline(t *testing.T) {
+	current, shutdown := time.Unix(1700000000, 0), false
+	fakeHost(t, time.Time{}, &current, &shutdown)
+	if _, ok := Deadline(); ok {
+		t.Error("expected no deadline")
+	}
+
+	deadline := current.Add(time.Second)
+	fakeHost(t, deadline, &current, &shutdown)
+	got, ok := Deadline()
+	if !ok || !got.Equal(deadline) {
+		t.Errorf("expected deadline %v, got %v (ok %t)", deadline, got, ok)
+	}
+}
+
+func TestContextDeadlineExceeded(t *testing.T) {
+	current, shutdown := time.Unix(1700000000, 0), false
+	deadline := current.Add(time.Second)
+	fakeHost(t, deadline, &current, &shutdown)
+
+	ctx := Context()
+	if got, ok := ctx.Deadline(); !ok || !got.Equal(deadline) {
+		t.Errorf("expected deadline %v, got %v (ok %t)", deadline, got, ok)
+	}
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("expected no error before the deadline, got %v", err)
+	}
+
+	current = deadline
+	if err := ctx.Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("expected Done to be closed once the deadline passed")
+	}
+}
+
+func TestContextShutdown(t *testing.T) {
+	current, shutdown := time.Unix(1700000000, 0), false
+	fakeHost(t, time.Time{}, &current, &shutdown)
+
+	ctx := Context()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline")
+	}
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("expected no error before the shutdown, got %v", err)
+	}
+
+	shutdown = true
+	err := ctx.Err()
+	if !errors.Is(err, ErrShutdown) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrShutdown matching context.Canceled, got %v", err)
+	}
+	// The error sticks once the context is done.
+	shutdown = false
+	if err := ctx.Err(); !errors.Is(err, ErrShutdown) {
+		t.Errorf("expected the error to stick, got %v", err)
+	}
+}
+
+func TestContextDoneTimer(t *testing.T) {
+	// Err is never called before the deadline, so only the timer closes
+	// Done.
+	current, shutdown := time.Now(), false
+	fakeHost(t, current.Add(10*time.Millisecond), &current, &shutdown)
+	now = time.Now
+
+	ctx := Context()
+	done := ctx.Done()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Done to be closed at the deadline")
+	}
+	if err := ctx.Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
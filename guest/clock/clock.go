@@ -0,0 +1,31 @@
+// Package clock reads the host clock, so guests get the same time under the
+// interpreter and the compiler, where the WASI clock of the guest runtime
+// may drift or be frozen.
+//
+// Use Now instead of time.Now for the timestamps set on telemetry, e.g. span
+// start and end timestamps.
+package clock
+
+import (
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// Now returns the current time of the host.
+func Now() time.Time {
+	wall, _ := imports.HostNow()
+	return time.Unix(0, wall)
+}
+
+// Monotonic returns the reading of the monotonic clock of the host. Only the
+// difference between two readings is meaningful, e.g. to measure durations.
+func Monotonic() time.Duration {
+	_, monotonic := imports.HostNow()
+	return time.Duration(monotonic)
+}
+
+// Since returns the time elapsed since the monotonic reading start.
+func Since(start time.Duration) time.Duration {
+	return Monotonic() - start
+}
@@ -0,0 +1,211 @@
+// Package completeness measures how complete the traces seen by a guest are,
+// i.e. the share of spans whose parent span was seen as well, so operators
+// notice spans lost upstream or traces split across collectors.
+//
+// A span is an orphan if it has a parent span ID and the parent isn't in the
+// batch. With a window configured, the IDs of the spans seen are remembered
+// in the state store for the window, so a parent received in an earlier
+// batch isn't counted as missing. Parents received in a later batch can't be
+// accounted for.
+package completeness
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/clock"
+	"github.com/otelwasm/otelwasm/guest/metrics"
+	"github.com/otelwasm/otelwasm/guest/state"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// DefaultMetric is the name of the metric emitted if none is configured.
+	DefaultMetric = "trace_completeness.spans"
+	// DefaultStateKey is the state key of the spans seen if none is
+	// configured.
+	DefaultStateKey = "completeness.seen"
+)
+
+// entrySize is the size of a span seen in the state store: its span ID then
+// the unix nanoseconds it was seen at.
+const entrySize = 16
+
+// The guest functions, replaced in tests.
+var (
+	stateGet = state.Get
+	stateSet = state.Set
+	now      = clock.Now
+	addInt64 = metrics.AddInt64
+)
+
+// Config is the configuration of the checker.
+type Config struct {
+	// WindowMs is the time, in milliseconds, the spans seen are remembered to
+	// resolve the parents of the spans of later batches. Zero only resolves
+	// parents within the batch.
+	WindowMs int64 `json:"window_ms"`
+	// StateKey is the state key the spans seen are kept under. Defaults to
+	// DefaultStateKey.
+	StateKey string `json:"state_key"`
+	// Metric is the name of the counter of the spans checked, with a
+	// "complete" boolean attribute. Defaults to DefaultMetric.
+	Metric string `json:"metric"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.WindowMs < 0 {
+		return fmt.Errorf("window_ms must not be negative")
+	}
+	return nil
+}
+
+// Window returns the window as a duration.
+func (c *Config) Window() time.Duration {
+	return time.Duration(c.WindowMs) * time.Millisecond
+}
+
+// Result is the completeness of a batch.
+type Result struct {
+	// Spans is the number of spans checked.
+	Spans int
+	// Orphans is the number of spans whose parent is missing.
+	Orphans int
+}
+
+// Ratio returns the share of the spans whose parent isn't missing, 1 if no
+// span was checked.
+func (r Result) Ratio() float64 {
+	if r.Spans == 0 {
+		return 1
+	}
+	return float64(r.Spans-r.Orphans) / float64(r.Spans)
+}
+
+// Traces returns the completeness of td on its own.
+func Traces(td ptrace.Traces) Result {
+	return check(td, nil)
+}
+
+// check returns the completeness of td, resolving the parents missing from
+// td in seen.
+func check(td ptrace.Traces, seen map[pcommon.SpanID]int64) Result {
+	batch := make(map[pcommon.SpanID]struct{}, td.SpanCount())
+	forEachSpan(td, func(span ptrace.Span) {
+		batch[span.SpanID()] = struct{}{}
+	})
+
+	var r Result
+	forEachSpan(td, func(span ptrace.Span) {
+		r.Spans++
+		parent := span.ParentSpanID()
+		if parent.IsEmpty() {
+			return
+		}
+		if _, ok := batch[parent]; ok {
+			return
+		}
+		if _, ok := seen[parent]; ok {
+			return
+		}
+		r.Orphans++
+	})
+	return r
+}
+
+func forEachSpan(td ptrace.Traces, f func(ptrace.Span)) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				f(spans.At(k))
+			}
+		}
+	}
+}
+
+// Checker checks the completeness of batches across the configured window,
+// and emits the completeness metric.
+//
+// The spans seen are shared with the other calls of the guest through the
+// state store. Concurrent checks may lose the spans of each other, as the
+// last write of the store wins, which only overcounts orphans.
+type Checker struct {
+	window   time.Duration
+	stateKey string
+	metric   string
+}
+
+// New returns a checker of the given configuration.
+func New(cfg Config) (*Checker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.StateKey == "" {
+		cfg.StateKey = DefaultStateKey
+	}
+	if cfg.Metric == "" {
+		cfg.Metric = DefaultMetric
+	}
+	return &Checker{window: cfg.Window(), stateKey: cfg.StateKey, metric: cfg.Metric}, nil
+}
+
+// Check returns the completeness of td, emits it in the metric, and
+// remembers the spans of td for the window. The result is valid even if an
+// error is returned, e.g. state.ErrLimitExceeded when the spans seen don't
+// fit the store.
+func (c *Checker) Check(td ptrace.Traces) (Result, error) {
+	if c.window == 0 {
+		r := check(td, nil)
+		return r, c.emit(r)
+	}
+
+	at := now()
+	seen := c.load(at)
+	r := check(td, seen)
+	if err := c.emit(r); err != nil {
+		return r, err
+	}
+
+	forEachSpan(td, func(span ptrace.Span) {
+		seen[span.SpanID()] = at.UnixNano()
+	})
+	return r, c.store(seen)
+}
+
+func (c *Checker) emit(r Result) error {
+	if err := addInt64(c.metric, int64(r.Spans-r.Orphans), map[string]any{"complete": true}); err != nil {
+		return err
+	}
+	return addInt64(c.metric, int64(r.Orphans), map[string]any{"complete": false})
+}
+
+// load returns the spans seen within the window before at, with the unix
+// nanoseconds they were seen at.
+func (c *Checker) load(at time.Time) map[pcommon.SpanID]int64 {
+	seen := make(map[pcommon.SpanID]int64)
+	value, _ := stateGet(c.stateKey)
+	expired := at.Add(-c.window).UnixNano()
+	for ; len(value) >= entrySize; value = value[entrySize:] {
+		seenAt := int64(binary.LittleEndian.Uint64(value[8:entrySize]))
+		if seenAt <= expired {
+			continue
+		}
+		seen[pcommon.SpanID(value[:8])] = seenAt
+	}
+	return seen
+}
+
+func (c *Checker) store(seen map[pcommon.SpanID]int64) error {
+	value := make([]byte, 0, len(seen)*entrySize)
+	for id, seenAt := range seen {
+		value = append(value, id[:]...)
+		value = binary.LittleEndian.AppendUint64(value, uint64(seenAt))
+	}
+	return stateSet(c.stateKey, value)
+}
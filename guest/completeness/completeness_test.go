@@ -0,0 +1,139 @@
+package completeness
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// newTraces returns traces with a span per ID, each mapped to the ID of its
+// parent, zero for roots.
+func newTraces(spans map[byte]byte) ptrace.Traces {
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for id, parent := range spans {
+		span := ss.AppendEmpty()
+		span.SetTraceID(pcommon.TraceID{1})
+		span.SetSpanID(pcommon.SpanID{id})
+		if parent != 0 {
+			span.SetParentSpanID(pcommon.SpanID{parent})
+		}
+	}
+	return td
+}
+
+// fakeGuest replaces the state store, the clock and the metrics with fakes,
+// returning the clock and the sums of the metric per "complete" attribute.
+func fakeGuest(t *testing.T) (*time.Time, map[bool]int64) {
+	entries := make(map[string][]byte)
+	at := time.Unix(1000, 0)
+	sums := make(map[bool]int64)
+
+	origGet, origSet, origNow, origAdd := stateGet, stateSet, now, addInt64
+	t.Cleanup(func() { stateGet, stateSet, now, addInt64 = origGet, origSet, origNow, origAdd })
+
+	stateGet = func(key string) ([]byte, bool) {
+		value, ok := entries[key]
+		return value, ok
+	}
+	stateSet = func(key string, value []byte) error {
+		entries[key] = value
+		return nil
+	}
+	now = func() time.Time { return at }
+	addInt64 = func(name string, value int64, attrs map[string]any) error {
+		if name != DefaultMetric {
+			t.Errorf("expected the metric %q, got %q", DefaultMetric, name)
+		}
+		sums[attrs["complete"].(bool)] += value
+		return nil
+	}
+	return &at, sums
+}
+
+func TestTraces(t *testing.T) {
+	tests := []struct {
+		name    string
+		spans   map[byte]byte
+		orphans int
+	}{
+		{name: "complete", spans: map[byte]byte{1: 0, 2: 1, 3: 1, 4: 3}},
+		{name: "missing root", spans: map[byte]byte{2: 1, 3: 1, 4: 3}, orphans: 2},
+		{name: "missing intermediate", spans: map[byte]byte{1: 0, 2: 1, 4: 3}, orphans: 1},
+		{name: "empty", spans: map[byte]byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Traces(newTraces(tt.spans))
+			if r.Spans != len(tt.spans) || r.Orphans != tt.orphans {
+				t.Errorf("expected %d spans and %d orphans, got %+v", len(tt.spans), tt.orphans, r)
+			}
+		})
+	}
+
+	if got := (Result{Spans: 4, Orphans: 1}).Ratio(); got != 0.75 {
+		t.Errorf("expected a ratio of 0.75, got %v", got)
+	}
+}
+
+func TestCheckerWithoutWindow(t *testing.T) {
+	_, sums := fakeGuest(t)
+	c, err := New(Config{})
+	if err != nil {
+		t.Fatalf("failed to create checker: %v", err)
+	}
+
+	if _, err := c.Check(newTraces(map[byte]byte{1: 0, 2: 1})); err != nil {
+		t.Fatalf("failed to check: %v", err)
+	}
+	// The parent of the first batch is forgotten without a window.
+	r, err := c.Check(newTraces(map[byte]byte{3: 1}))
+	if err != nil {
+		t.Fatalf("failed to check: %v", err)
+	}
+	if r.Orphans != 1 {
+		t.Errorf("expected 1 orphan, got %+v", r)
+	}
+	if sums[true] != 2 || sums[false] != 1 {
+		t.Errorf("expected 2 complete and 1 orphan spans in the metric, got %v", sums)
+	}
+}
+
+func TestCheckerWindow(t *testing.T) {
+	at, sums := fakeGuest(t)
+	c, err := New(Config{WindowMs: 1000})
+	if err != nil {
+		t.Fatalf("failed to create checker: %v", err)
+	}
+
+	check := func(spans map[byte]byte, orphans int) {
+		t.Helper()
+		r, err := c.Check(newTraces(spans))
+		if err != nil {
+			t.Fatalf("failed to check: %v", err)
+		}
+		if r.Orphans != orphans {
+			t.Errorf("expected %d orphans, got %+v", orphans, r)
+		}
+	}
+
+	check(map[byte]byte{1: 0}, 0)
+	*at = at.Add(500 * time.Millisecond)
+	// The root was seen within the window.
+	check(map[byte]byte{2: 1}, 0)
+	*at = at.Add(700 * time.Millisecond)
+	// The root expired, but the span of the second batch is still known.
+	check(map[byte]byte{3: 1, 4: 2}, 1)
+
+	if sums[true] != 3 || sums[false] != 1 {
+		t.Errorf("expected 3 complete and 1 orphan spans in the metric, got %v", sums)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	if _, err := New(Config{WindowMs: -1}); err == nil {
+		t.Error("expected an error for a negative window")
+	}
+}
@@ -0,0 +1,57 @@
+// Package crypto hashes data through the host, which is faster than hashing
+// in the guest, and keeps the hashing code out of the module, e.g. to
+// fingerprint spans or pseudonymize personal data.
+//
+// Salted digests are keyed by the hash.salt option of the plugin, so the
+// salt isn't baked into the module. The host serves the hashes with the
+// hashSHA256 and hashFNV64 host functions, so guests using the package
+// don't run on hosts predating them.
+package crypto
+
+import (
+	"encoding/hex"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// hashSHA256 and hashFNV64 hash through the host, replaced in tests.
+var (
+	hashSHA256 = imports.HashSHA256
+	hashFNV64  = imports.HashFNV64
+)
+
+// ErrNoSalt is the error of the salted digests when the plugin has no
+// hash.salt. It matches fs.ErrPermission.
+var ErrNoSalt = imports.ErrNotAllowed
+
+// SHA256 returns the SHA-256 digest of b.
+func SHA256(b []byte) [32]byte {
+	digest, err := hashSHA256(b, false)
+	if err != nil {
+		// Unsalted digests only fail if b is outside the guest memory.
+		panic(err)
+	}
+	return digest
+}
+
+// SaltedSHA256 returns the HMAC-SHA256 of b keyed by the hash.salt option
+// of the plugin. It fails with ErrNoSalt if the plugin has no salt.
+func SaltedSHA256(b []byte) ([32]byte, error) {
+	return hashSHA256(b, true)
+}
+
+// SaltedSHA256Hex returns the digest of SaltedSHA256 in hex, e.g. to
+// replace an attribute value.
+func SaltedSHA256Hex(s string) (string, error) {
+	digest, err := SaltedSHA256([]byte(s))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// FNV64 returns the 64-bit FNV-1a hash of b. It isn't a cryptographic hash,
+// but is cheaper for fingerprints, e.g. of span names.
+func FNV64(b []byte) uint64 {
+	return hashFNV64(b)
+}
@@ -0,0 +1,54 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// fakeHost hashes with the standard library, salting with salt, if any.
+func fakeHost(t *testing.T, salt string) {
+	t.Helper()
+	prev := hashSHA256
+	t.Cleanup(func() { hashSHA256 = prev })
+	hashSHA256 = func(b []byte, salted bool) ([32]byte, error) {
+		if !salted {
+			return sha256.Sum256(b), nil
+		}
+		if salt == "" {
+			return [32]byte{}, imports.ErrNotAllowed
+		}
+		return sha256.Sum256(append([]byte(salt), b...)), nil
+	}
+}
+
+func TestSHA256(t *testing.T) {
+	fakeHost(t, "")
+	if got, want := SHA256([]byte("span")), sha256.Sum256([]byte("span")); got != want {
+		t.Errorf("expected %x, got %x", want, got)
+	}
+}
+
+func TestSaltedSHA256Hex(t *testing.T) {
+	fakeHost(t, "pepper")
+	got, err := SaltedSHA256Hex("alice@example.com")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	want := sha256.Sum256([]byte("pepperalice@example.com"))
+	if got != hex.EncodeToString(want[:]) {
+		t.Errorf("expected %x, got %s", want, got)
+	}
+}
+
+func TestSaltedSHA256NoSalt(t *testing.T) {
+	fakeHost(t, "")
+	_, err := SaltedSHA256([]byte("alice@example.com"))
+	if !errors.Is(err, ErrNoSalt) || !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected ErrNoSalt, got %v", err)
+	}
+}
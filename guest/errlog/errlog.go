@@ -0,0 +1,92 @@
+// Package errlog turns the errors and panics recovered by guests into log
+// records, so failures of risky code, e.g. parsing untrusted payloads, are
+// reported with the telemetry instead of being lost.
+//
+// Records have the error severity, the error message as body, and the
+// exception attributes of the OpenTelemetry semantic conventions.
+package errlog
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/otelwasm/otelwasm/guest/clock"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// ExceptionTypeAttribute is the attribute holding the Go type of the
+	// error, or of the panic value.
+	ExceptionTypeAttribute = "exception.type"
+	// ExceptionMessageAttribute is the attribute holding the error message.
+	ExceptionMessageAttribute = "exception.message"
+	// ExceptionStacktraceAttribute is the attribute holding the stack trace,
+	// if any.
+	ExceptionStacktraceAttribute = "exception.stacktrace"
+)
+
+// PanicError is the error of a recovered panic.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Record fills lr with err and its stack trace. The stack trace of a
+// PanicError is used if stack is nil.
+func Record(lr plog.LogRecord, err error, stack []byte) {
+	lr.SetTimestamp(pcommon.NewTimestampFromTime(clock.Now()))
+	lr.SetSeverityNumber(plog.SeverityNumberError)
+	lr.SetSeverityText(plog.SeverityNumberError.String())
+	lr.Body().SetStr(err.Error())
+
+	exceptionType := fmt.Sprintf("%T", err)
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		exceptionType = fmt.Sprintf("%T", panicErr.Value)
+		if stack == nil {
+			stack = panicErr.Stack
+		}
+	}
+	attrs := lr.Attributes()
+	attrs.PutStr(ExceptionTypeAttribute, exceptionType)
+	attrs.PutStr(ExceptionMessageAttribute, err.Error())
+	if len(stack) > 0 {
+		attrs.PutStr(ExceptionStacktraceAttribute, string(stack))
+	}
+}
+
+// Append appends a record of err and its stack trace to ld, in a resource
+// and scope of their own, and returns it.
+func Append(ld plog.Logs, err error, stack []byte) plog.LogRecord {
+	lr := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	Record(lr, err, stack)
+	return lr
+}
+
+// Capture calls fn, recovering any panic as a *PanicError, and appends a
+// record of the error to ld if fn fails. The error is returned.
+func Capture(ld plog.Logs, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+		if err != nil {
+			Append(ld, err, nil)
+		}
+	}()
+	return fn()
+}
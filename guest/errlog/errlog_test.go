@@ -0,0 +1,82 @@
+package errlog
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestCaptureError(t *testing.T) {
+	ld := plog.NewLogs()
+	want := &fs.PathError{Op: "open", Path: "payload.json", Err: fs.ErrNotExist}
+
+	err := Capture(ld, func() error { return want })
+	if err != want {
+		t.Fatalf("expected the error of fn, got %v", err)
+	}
+	if ld.LogRecordCount() != 1 {
+		t.Fatalf("expected 1 record, got %d", ld.LogRecordCount())
+	}
+	lr := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	if lr.SeverityNumber() != plog.SeverityNumberError {
+		t.Errorf("expected severity error, got %v", lr.SeverityNumber())
+	}
+	if lr.Body().Str() != want.Error() {
+		t.Errorf("expected body %q, got %q", want.Error(), lr.Body().Str())
+	}
+	attrs := lr.Attributes().AsRaw()
+	if attrs[ExceptionTypeAttribute] != "*fs.PathError" || attrs[ExceptionMessageAttribute] != want.Error() {
+		t.Errorf("unexpected exception attributes %v", attrs)
+	}
+	if _, ok := attrs[ExceptionStacktraceAttribute]; ok {
+		t.Error("expected no stack trace for a returned error")
+	}
+}
+
+func TestCapturePanic(t *testing.T) {
+	ld := plog.NewLogs()
+
+	err := Capture(ld, func() error {
+		var attrs map[string]string
+		attrs["key"] = "value"
+		return nil
+	})
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected a PanicError, got %v", err)
+	}
+	lr := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	if !strings.Contains(lr.Body().Str(), "assignment to entry in nil map") {
+		t.Errorf("expected the panic message as body, got %q", lr.Body().Str())
+	}
+	attrs := lr.Attributes().AsRaw()
+	if typ, _ := attrs[ExceptionTypeAttribute].(string); !strings.HasPrefix(typ, "runtime.") {
+		t.Errorf("expected a runtime exception type, got %v", attrs[ExceptionTypeAttribute])
+	}
+	stack, _ := attrs[ExceptionStacktraceAttribute].(string)
+	if !strings.Contains(stack, "TestCapturePanic") {
+		t.Errorf("expected the stack trace of the panic, got %q", stack)
+	}
+}
+
+func TestCaptureSuccess(t *testing.T) {
+	ld := plog.NewLogs()
+	if err := Capture(ld, func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ld.LogRecordCount() != 0 {
+		t.Errorf("expected no record, got %d", ld.LogRecordCount())
+	}
+}
+
+func TestRecordWithStack(t *testing.T) {
+	lr := plog.NewLogRecord()
+	Record(lr, errors.New("invalid payload"), []byte("goroutine 1 [running]:"))
+
+	if v, _ := lr.Attributes().Get(ExceptionStacktraceAttribute); v.Str() != "goroutine 1 [running]:" {
+		t.Errorf("expected the given stack trace, got %q", v.Str())
+	}
+}
@@ -34,3 +34,12 @@ func ConsumeTraces(ctx context.Context, ld ptrace.Traces) error {
 	runtime.KeepAlive(ld) // until ptr is no longer needed.
 	return nil
 }
+
+var _ consumer.ConsumeTracesFunc = EmitTraces
+
+// EmitTraces passes the traces to the next consumer of the receiver as a
+// batch of their own, instead of overwriting the result like ConsumeTraces,
+// so receivers can produce any number of batches.
+func EmitTraces(ctx context.Context, ld ptrace.Traces) error {
+	return imports.EmitTraces(ld)
+}
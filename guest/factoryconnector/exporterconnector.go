@@ -2,12 +2,14 @@ package factoryconnector
 
 import (
 	"context"
+	"errors"
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/otelwasm/otelwasm/guest/api"
 	"github.com/otelwasm/otelwasm/guest/imports"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
@@ -19,6 +21,14 @@ type ExporterConnector struct {
 	factory  exporter.Factory
 	cfg      component.Config
 	settings exporter.Settings
+
+	// started are the exporters to shut down, in start order.
+	started []component.Component
+	// generation is incremented by Reconfigure, so the exporters created
+	// from the previous config are created again.
+	generation int
+	// cfgVersion is the version of the config cfg was decoded from.
+	cfgVersion uint32
 }
 
 func NewExporterConnector(
@@ -43,11 +53,58 @@ func (e *ExporterConnector) Traces() api.TracesExporter {
 	return &tracesExporter{ExporterConnector: e}
 }
 
+// Shutdown shuts the started exporters down, which flushes the data they
+// queue or buffer.
+func (e *ExporterConnector) Shutdown(ctx context.Context) *api.Status {
+	var errs error
+	for _, exp := range e.started {
+		if err := exp.Shutdown(ctx); err != nil {
+			e.settings.Logger.Error("failed to shutdown exporter", zap.Error(err))
+			errs = errors.Join(errs, err)
+		}
+	}
+	e.started = nil
+	if errs != nil {
+		return api.StatusError(errs.Error())
+	}
+	return api.StatusSuccess()
+}
+
+// Reconfigure shuts the started exporters down, and creates them again from
+// the updated config on their next push.
+func (e *ExporterConnector) Reconfigure(ctx context.Context) *api.Status {
+	status := e.Shutdown(ctx)
+	e.cfg = nil
+	e.generation++
+	return status
+}
+
+// syncQueueConfig makes the sending queue of exporters built with
+// exporterhelper wait for the export of each request. The guest only runs
+// while the host calls it, so the queue consumers wouldn't get to drain the
+// queue once the push returns.
+var syncQueueConfig = map[string]any{
+	"sending_queue": map[string]any{
+		"wait_for_result": true,
+	},
+}
+
+// reloadUpdatedConfig reconfigures the connector if the host updated the
+// config since it was decoded, in case the guest doesn't export reconfigure.
+func (e *ExporterConnector) reloadUpdatedConfig() {
+	if e.cfg == nil || imports.GetConfigVersion() == e.cfgVersion {
+		return
+	}
+	e.settings.Logger.Info("plugin config updated, recreating exporters")
+	e.Reconfigure(context.Background())
+}
+
 func (e *ExporterConnector) initConfig() {
 	if e.cfg != nil {
 		return
 	}
 	logger := e.settings.Logger
+	e.cfgVersion = imports.GetConfigVersion()
 
 	var config any
 	err := imports.GetConfig(&config)
@@ -57,6 +114,10 @@ func (e *ExporterConnector) initConfig() {
 
 	e.cfg = e.factory.CreateDefaultConfig()
 
+	// Decoded before the user config, which may still override it.
+	if err := mapstructure.Decode(syncQueueConfig, &e.cfg); err != nil {
+		logger.Fatal("failed to decode config", zap.Error(err))
+	}
 	if err := mapstructure.Decode(config, &e.cfg); err != nil {
 		logger.Fatal("failed to decode config", zap.Error(err))
 	}
@@ -66,10 +127,14 @@ func (e *ExporterConnector) initConfig() {
 type metricsExporter struct {
 	*ExporterConnector
 	metricsExporter exporter.Metrics
+	// createdAt is the generation of the connector the exporter was created
+	// at.
+	createdAt int
 }
 
 func (e *metricsExporter) PushMetrics(metrics pmetric.Metrics) *api.Status {
-	if e.metricsExporter == nil {
+	e.reloadUpdatedConfig()
+	if e.metricsExporter == nil || e.createdAt != e.generation {
 		e.initConfig()
 		logger := e.settings.Logger
 
@@ -85,12 +150,14 @@ func (e *metricsExporter) PushMetrics(metrics pmetric.Metrics) *api.Status {
 			logger.Error("failed to start metrics exporter", zap.Error(err))
 			return api.StatusError(err.Error())
 		}
+		e.started = append(e.started, e.metricsExporter)
+		e.createdAt = e.generation
 	}
 
 	err := e.metricsExporter.ConsumeMetrics(context.Background(), metrics)
 	if err != nil {
 		e.settings.Logger.Error("failed to export metrics", zap.Error(err))
-		return api.StatusError(err.Error())
+		return exportStatus(err)
 	}
 
 	return api.StatusSuccess()
@@ -99,10 +166,14 @@ func (e *metricsExporter) PushMetrics(metrics pmetric.Metrics) *api.Status {
 type logsExporter struct {
 	*ExporterConnector
 	logsExporter exporter.Logs
+	// createdAt is the generation of the connector the exporter was created
+	// at.
+	createdAt int
 }
 
 func (e *logsExporter) PushLogs(logs plog.Logs) *api.Status {
-	if e.logsExporter == nil {
+	e.reloadUpdatedConfig()
+	if e.logsExporter == nil || e.createdAt != e.generation {
 		e.initConfig()
 		logger := e.settings.Logger
 
@@ -118,12 +189,14 @@ func (e *logsExporter) PushLogs(logs plog.Logs) *api.Status {
 			logger.Error("failed to start logs exporter", zap.Error(err))
 			return api.StatusError(err.Error())
 		}
+		e.started = append(e.started, e.logsExporter)
+		e.createdAt = e.generation
 	}
 
 	err := e.logsExporter.ConsumeLogs(context.Background(), logs)
 	if err != nil {
 		e.settings.Logger.Error("failed to export logs", zap.Error(err))
-		return api.StatusError(err.Error())
+		return exportStatus(err)
 	}
 
 	return api.StatusSuccess()
@@ -132,10 +205,14 @@ func (e *logsExporter) PushLogs(logs plog.Logs) *api.Status {
 type tracesExporter struct {
 	*ExporterConnector
 	tracesExporter exporter.Traces
+	// createdAt is the generation of the connector the exporter was created
+	// at.
+	createdAt int
 }
 
 func (e *tracesExporter) PushTraces(traces ptrace.Traces) *api.Status {
-	if e.tracesExporter == nil {
+	e.reloadUpdatedConfig()
+	if e.tracesExporter == nil || e.createdAt != e.generation {
 		e.initConfig()
 		logger := e.settings.Logger
 
@@ -151,13 +228,24 @@ func (e *tracesExporter) PushTraces(traces ptrace.Traces) *api.Status {
 			logger.Error("failed to start traces exporter", zap.Error(err))
 			return api.StatusError(err.Error())
 		}
+		e.started = append(e.started, e.tracesExporter)
+		e.createdAt = e.generation
 	}
 
 	err := e.tracesExporter.ConsumeTraces(context.Background(), traces)
 	if err != nil {
 		e.settings.Logger.Error("failed to export traces", zap.Error(err))
-		return api.StatusError(err.Error())
+		return exportStatus(err)
 	}
 
 	return api.StatusSuccess()
 }
+
+// exportStatus returns the status of err, the error of the wrapped exporter,
+// telling the host not to retry the permanent errors.
+func exportStatus(err error) *api.Status {
+	if consumererror.IsPermanent(err) {
+		return api.StatusPermanent(err.Error())
+	}
+	return api.StatusError(err.Error())
+}
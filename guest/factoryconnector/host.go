@@ -0,0 +1,80 @@
+package factoryconnector
+
+import (
+	"context"
+	"sort"
+
+	"github.com/otelwasm/otelwasm/guest/imports"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/extensionauth"
+	"go.uber.org/zap"
+)
+
+// extensionHost is the component.Host of the guest receivers. It exposes the
+// collector extensions of the host as server authenticators, so receivers
+// configured with an auth extension authenticate their requests through the
+// host. Other kinds of extensions, e.g. storage, aren't supported: their
+// authentication fails.
+type extensionHost struct {
+	logger *zap.Logger
+}
+
+func (h extensionHost) GetExtensions() map[component.ID]component.Component {
+	ids, err := imports.Extensions()
+	if err != nil {
+		h.logger.Error("failed to get extensions", zap.Error(err))
+		return nil
+	}
+	extensions := make(map[component.ID]component.Component, len(ids))
+	for _, id := range ids {
+		var extID component.ID
+		if err := extID.UnmarshalText([]byte(id)); err != nil {
+			h.logger.Error("invalid extension ID", zap.String("id", id), zap.Error(err))
+			continue
+		}
+		extensions[extID] = authExtension{id: id}
+	}
+	return extensions
+}
+
+// authExtension is an extension of the host, authenticating requests with it.
+type authExtension struct {
+	component.StartFunc
+	component.ShutdownFunc
+	id string
+}
+
+var _ extensionauth.Server = authExtension{}
+
+// Authenticate authenticates the request headers with the host extension and
+// adds the auth data returned by the host to the client info of ctx. The
+// client metadata is replaced by the metadata returned by the host, if any.
+func (e authExtension) Authenticate(ctx context.Context, headers map[string][]string) (context.Context, error) {
+	res, err := imports.Authenticate(e.id, headers)
+	if err != nil {
+		return ctx, err
+	}
+	info := client.FromContext(ctx)
+	info.Auth = authData(res.Auth)
+	if len(res.Metadata) > 0 {
+		info.Metadata = client.NewMetadata(res.Metadata)
+	}
+	return client.NewContext(ctx, info), nil
+}
+
+// authData is the client.AuthData of a client authenticated by the host.
+type authData map[string]any
+
+func (d authData) GetAttribute(name string) any {
+	return d[name]
+}
+
+func (d authData) GetAttributeNames() []string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,6 +2,7 @@ package factoryconnector
 
 import (
 	"context"
+	"errors"
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/otelwasm/otelwasm/guest/api"
@@ -20,6 +21,14 @@ type ProcessorConnector struct {
 	factory  processor.Factory
 	cfg      component.Config
 	settings processor.Settings
+
+	// started are the processors to shut down, in start order.
+	started []component.Component
+	// generation is incremented by Reconfigure, so the processors created
+	// from the previous config are created again.
+	generation int
+	// cfgVersion is the version of the config cfg was decoded from.
+	cfgVersion uint32
 }
 
 func NewProcessorConnector(
@@ -44,11 +53,42 @@ func (p *ProcessorConnector) Traces() api.TracesProcessor {
 	return &tracesProcessor{ProcessorConnector: p}
 }
 
+// Reconfigure shuts the started processors down, and creates them again
+// from the updated config on their next call. The data the processors flush
+// on shutdown is dropped, as the host passes no batch to reconfigure.
+func (p *ProcessorConnector) Reconfigure(ctx context.Context) *api.Status {
+	var errs error
+	for _, proc := range p.started {
+		if err := proc.Shutdown(ctx); err != nil {
+			p.settings.Logger.Error("failed to shutdown processor", zap.Error(err))
+			errs = errors.Join(errs, err)
+		}
+	}
+	p.started = nil
+	p.cfg = nil
+	p.generation++
+	if errs != nil {
+		return api.StatusError(errs.Error())
+	}
+	return api.StatusSuccess()
+}
+
+// reloadUpdatedConfig reconfigures the connector if the host updated the
+// config since it was decoded, in case the guest doesn't export reconfigure.
+func (p *ProcessorConnector) reloadUpdatedConfig() {
+	if p.cfg == nil || imports.GetConfigVersion() == p.cfgVersion {
+		return
+	}
+	p.settings.Logger.Info("plugin config updated, recreating processors")
+	p.Reconfigure(context.Background())
+}
+
 func (p *ProcessorConnector) initConfig() {
 	if p.cfg != nil {
 		return
 	}
 	logger := p.settings.Logger
+	p.cfgVersion = imports.GetConfigVersion()
 
 	var config any
 	err := imports.GetConfig(&config)
@@ -68,16 +108,22 @@ type metricsProcessor struct {
 	*ProcessorConnector
 	metricsProcessor processor.Metrics
 	nextConsumer     consumer.Metrics
+	// createdAt is the generation of the connector the processor was
+	// created at.
+	createdAt int
+	// consumed is set once the processor passes on the metrics of the call.
+	consumed bool
 }
 
 func (p *metricsProcessor) ProcessMetrics(metrics pmetric.Metrics) (pmetric.Metrics, *api.Status) {
-	if p.metricsProcessor == nil {
+	p.reloadUpdatedConfig()
+	if p.metricsProcessor == nil || p.createdAt != p.generation {
 		p.initConfig()
 		logger := p.settings.Logger
 
 		// Create a consumer that will capture the processed results
 		var err error
-		p.nextConsumer, err = consumer.NewMetrics(ConsumeMetrics, consumer.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+		p.nextConsumer, err = consumer.NewMetrics(p.consume, consumer.WithCapabilities(consumer.Capabilities{MutatesData: true}))
 		if err != nil {
 			logger.Error("failed to create metrics consumer", zap.Error(err))
 			return metrics, api.StatusError(err.Error())
@@ -96,33 +142,52 @@ func (p *metricsProcessor) ProcessMetrics(metrics pmetric.Metrics) (pmetric.Metr
 			logger.Error("failed to start metrics processor", zap.Error(err))
 			return metrics, api.StatusError(err.Error())
 		}
+		p.started = append(p.started, p.metricsProcessor)
+		p.createdAt = p.generation
 	}
 
 	// Process the metrics
+	p.consumed = false
 	err := p.metricsProcessor.ConsumeMetrics(context.Background(), metrics)
 	if err != nil {
 		p.settings.Logger.Error("failed to process metrics", zap.Error(err))
 		return metrics, api.StatusError(err.Error())
 	}
+	if !p.consumed {
+		// The processor dropped the metrics, which the host would pass on if
+		// no result was set.
+		return pmetric.NewMetrics(), api.StatusSuccess()
+	}
 
-	// Return empty metrics to indicate that the result was already written to memory
+	// Return no result, as it was already written to memory
 	return pmetric.Metrics{}, api.StatusSuccess()
 }
 
+func (p *metricsProcessor) consume(ctx context.Context, metrics pmetric.Metrics) error {
+	p.consumed = true
+	return ConsumeMetrics(ctx, metrics)
+}
+
 type logsProcessor struct {
 	*ProcessorConnector
 	logsProcessor processor.Logs
 	nextConsumer  consumer.Logs
+	// createdAt is the generation of the connector the processor was
+	// created at.
+	createdAt int
+	// consumed is set once the processor passes on the logs of the call.
+	consumed bool
 }
 
 func (p *logsProcessor) ProcessLogs(logs plog.Logs) (plog.Logs, *api.Status) {
-	if p.logsProcessor == nil {
+	p.reloadUpdatedConfig()
+	if p.logsProcessor == nil || p.createdAt != p.generation {
 		p.initConfig()
 		logger := p.settings.Logger
 
 		// Create a consumer that will capture the processed results
 		var err error
-		p.nextConsumer, err = consumer.NewLogs(ConsumeLogs, consumer.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+		p.nextConsumer, err = consumer.NewLogs(p.consume, consumer.WithCapabilities(consumer.Capabilities{MutatesData: true}))
 		if err != nil {
 			logger.Error("failed to create logs consumer", zap.Error(err))
 			return logs, api.StatusError(err.Error())
@@ -141,33 +206,52 @@ func (p *logsProcessor) ProcessLogs(logs plog.Logs) (plog.Logs, *api.Status) {
 			logger.Error("failed to start logs processor", zap.Error(err))
 			return logs, api.StatusError(err.Error())
 		}
+		p.started = append(p.started, p.logsProcessor)
+		p.createdAt = p.generation
 	}
 
 	// Process the logs
+	p.consumed = false
 	err := p.logsProcessor.ConsumeLogs(context.Background(), logs)
 	if err != nil {
 		p.settings.Logger.Error("failed to process logs", zap.Error(err))
 		return logs, api.StatusError(err.Error())
 	}
+	if !p.consumed {
+		// The processor dropped the logs, which the host would pass on if
+		// no result was set.
+		return plog.NewLogs(), api.StatusSuccess()
+	}
 
-	// Return empty logs to indicate that the result was already written to memory
+	// Return no result, as it was already written to memory
 	return plog.Logs{}, api.StatusSuccess()
 }
 
+func (p *logsProcessor) consume(ctx context.Context, logs plog.Logs) error {
+	p.consumed = true
+	return ConsumeLogs(ctx, logs)
+}
+
 type tracesProcessor struct {
 	*ProcessorConnector
 	tracesProcessor processor.Traces
 	nextConsumer    consumer.Traces
+	// createdAt is the generation of the connector the processor was
+	// created at.
+	createdAt int
+	// consumed is set once the processor passes on the traces of the call.
+	consumed bool
 }
 
 func (p *tracesProcessor) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *api.Status) {
-	if p.tracesProcessor == nil {
+	p.reloadUpdatedConfig()
+	if p.tracesProcessor == nil || p.createdAt != p.generation {
 		p.initConfig()
 		logger := p.settings.Logger
 
 		// Create a consumer that will capture the processed results
 		var err error
-		p.nextConsumer, err = consumer.NewTraces(ConsumeTraces, consumer.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+		p.nextConsumer, err = consumer.NewTraces(p.consume, consumer.WithCapabilities(consumer.Capabilities{MutatesData: true}))
 		if err != nil {
 			logger.Error("failed to create traces consumer", zap.Error(err))
 			return traces, api.StatusError(err.Error())
@@ -186,15 +270,28 @@ func (p *tracesProcessor) ProcessTraces(traces ptrace.Traces) (ptrace.Traces, *a
 			logger.Error("failed to start traces processor", zap.Error(err))
 			return traces, api.StatusError(err.Error())
 		}
+		p.started = append(p.started, p.tracesProcessor)
+		p.createdAt = p.generation
 	}
 
 	// Process the traces
+	p.consumed = false
 	err := p.tracesProcessor.ConsumeTraces(context.Background(), traces)
 	if err != nil {
 		p.settings.Logger.Error("failed to process traces", zap.Error(err))
 		return traces, api.StatusError(err.Error())
 	}
+	if !p.consumed {
+		// The processor dropped the traces, which the host would pass on if
+		// no result was set.
+		return ptrace.NewTraces(), api.StatusSuccess()
+	}
 
-	// Return empty traces to indicate that the result was already written to memory
+	// Return no result, as it was already written to memory
 	return ptrace.Traces{}, api.StatusSuccess()
 }
+
+func (p *tracesProcessor) consume(ctx context.Context, traces ptrace.Traces) error {
+	p.consumed = true
+	return ConsumeTraces(ctx, traces)
+}
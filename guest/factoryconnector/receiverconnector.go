@@ -7,7 +7,6 @@ import (
 	"github.com/otelwasm/otelwasm/guest/api"
 	"github.com/otelwasm/otelwasm/guest/imports"
 	"go.opentelemetry.io/collector/component"
-	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/receiver"
 	"go.uber.org/zap"
@@ -79,7 +78,7 @@ func (n *metricsReceiver) StartMetrics(ctx context.Context) {
 		logger.Fatal("failed to create metrics receiver", zap.Error(err))
 	}
 
-	err = metrics.Start(ctx, componenttest.NewNopHost())
+	err = metrics.Start(ctx, extensionHost{logger: logger})
 	if err != nil {
 		logger.Fatal("failed to start metrics receiver", zap.Error(err))
 	}
@@ -109,7 +108,7 @@ func (n *logsReceiver) StartLogs(ctx context.Context) {
 		logger.Fatal("failed to create logs receiver", zap.Error(err))
 	}
 
-	err = logs.Start(ctx, componenttest.NewNopHost())
+	err = logs.Start(ctx, extensionHost{logger: logger})
 	if err != nil {
 		logger.Fatal("failed to start logs receiver", zap.Error(err))
 	}
@@ -129,7 +128,7 @@ func (n *tracesReceiver) StartTraces(ctx context.Context) {
 	n.initConfig()
 	logger := n.settings.Logger
 
-	tracesConsumer, err := consumer.NewTraces(ConsumeTraces, consumer.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+	tracesConsumer, err := consumer.NewTraces(EmitTraces, consumer.WithCapabilities(consumer.Capabilities{MutatesData: true}))
 	if err != nil {
 		logger.Fatal("failed to create traces consumer", zap.Error(err))
 	}
@@ -139,7 +138,7 @@ func (n *tracesReceiver) StartTraces(ctx context.Context) {
 		logger.Fatal("failed to create traces receiver", zap.Error(err))
 	}
 
-	err = traces.Start(ctx, componenttest.NewNopHost())
+	err = traces.Start(ctx, extensionHost{logger: logger})
 	if err != nil {
 		logger.Fatal("failed to start traces receiver", zap.Error(err))
 	}
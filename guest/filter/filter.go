@@ -0,0 +1,63 @@
+// Package filter keeps the spans, metrics and log records matching a
+// predicate, removing the scopes and resources left empty, so guests don't
+// hand-roll the nested loops over resources and scopes.
+//
+// The telemetry is filtered in place and returned, e.g.
+//
+//	return filter.Spans(traces, func(span ptrace.Span) bool {
+//		return span.Status().Code() == ptrace.StatusCodeError
+//	}), api.StatusSuccess()
+//
+// Resources and scopes already empty are removed too.
+package filter
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Spans removes the spans of td keep returns false for, then the scopes and
+// resources left without spans, and returns td.
+func Spans(td ptrace.Traces, keep func(ptrace.Span) bool) ptrace.Traces {
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				return !keep(span)
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return td
+}
+
+// Metrics removes the metrics of md keep returns false for, then the scopes
+// and resources left without metrics, and returns md.
+func Metrics(md pmetric.Metrics, keep func(pmetric.Metric) bool) pmetric.Metrics {
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(m pmetric.Metric) bool {
+				return !keep(m)
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return md
+}
+
+// Logs removes the log records of ld keep returns false for, then the scopes
+// and resources left without records, and returns ld.
+func Logs(ld plog.Logs, keep func(plog.LogRecord) bool) plog.Logs {
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				return !keep(lr)
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return ld
+}
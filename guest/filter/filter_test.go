@@ -0,0 +1,136 @@
+package filter
+
+import (
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// newTraces returns traces with a resource per group of scopes, each scope
+// holding spans of the given names.
+func newTraces(resources ...[][]string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	for _, scopes := range resources {
+		rs := td.ResourceSpans().AppendEmpty()
+		for _, names := range scopes {
+			spans := rs.ScopeSpans().AppendEmpty().Spans()
+			for _, name := range names {
+				spans.AppendEmpty().SetName(name)
+			}
+		}
+	}
+	return td
+}
+
+// shape returns the span names of td by resource and scope.
+func shape(td ptrace.Traces) [][]string {
+	var res [][]string
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		sss := td.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			names := []string{}
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				names = append(names, spans.At(k).Name())
+			}
+			res = append(res, names)
+		}
+	}
+	return res
+}
+
+func TestSpans(t *testing.T) {
+	keepA := func(span ptrace.Span) bool { return span.Name() == "a" }
+	tests := []struct {
+		name          string
+		td            ptrace.Traces
+		wantScopes    [][]string
+		wantResources int
+	}{
+		{
+			name:          "some spans of a scope",
+			td:            newTraces([][]string{{"a", "b", "a"}}),
+			wantScopes:    [][]string{{"a", "a"}},
+			wantResources: 1,
+		},
+		{
+			name:          "all spans of a scope",
+			td:            newTraces([][]string{{"b"}, {"a"}}),
+			wantScopes:    [][]string{{"a"}},
+			wantResources: 1,
+		},
+		{
+			name:          "all spans of a resource",
+			td:            newTraces([][]string{{"b"}, {"b", "b"}}, [][]string{{"a"}}),
+			wantScopes:    [][]string{{"a"}},
+			wantResources: 1,
+		},
+		{
+			name: "all spans",
+			td:   newTraces([][]string{{"b"}}, [][]string{{"b"}}),
+		},
+		{
+			name:          "empty scopes and resources",
+			td:            newTraces([][]string{{}}, nil, [][]string{{"a"}}),
+			wantScopes:    [][]string{{"a"}},
+			wantResources: 1,
+		},
+		{
+			name: "no spans",
+			td:   ptrace.NewTraces(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := Spans(tt.td, keepA)
+			if got := shape(td); !slices.EqualFunc(got, tt.wantScopes, slices.Equal) {
+				t.Errorf("expected the scopes %v, got %v", tt.wantScopes, got)
+			}
+			if got := td.ResourceSpans().Len(); got != tt.wantResources {
+				t.Errorf("expected %d resources, got %d", tt.wantResources, got)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	md := pmetric.NewMetrics()
+	for _, names := range [][]string{{"keep", "drop"}, {"drop"}} {
+		metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+		for _, name := range names {
+			metrics.AppendEmpty().SetName(name)
+		}
+	}
+
+	md = Metrics(md, func(m pmetric.Metric) bool { return m.Name() == "keep" })
+	if md.ResourceMetrics().Len() != 1 || md.MetricCount() != 1 {
+		t.Fatalf("expected a resource with a metric, got %d resources with %d metrics", md.ResourceMetrics().Len(), md.MetricCount())
+	}
+	if got := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Name(); got != "keep" {
+		t.Errorf("expected the kept metric, got %q", got)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	ld := plog.NewLogs()
+	sls := ld.ResourceLogs().AppendEmpty().ScopeLogs()
+	for _, severity := range [][]plog.SeverityNumber{{plog.SeverityNumberDebug}, {plog.SeverityNumberError, plog.SeverityNumberInfo}} {
+		records := sls.AppendEmpty().LogRecords()
+		for _, s := range severity {
+			records.AppendEmpty().SetSeverityNumber(s)
+		}
+	}
+
+	ld = Logs(ld, func(lr plog.LogRecord) bool { return lr.SeverityNumber() >= plog.SeverityNumberError })
+	if ld.ResourceLogs().At(0).ScopeLogs().Len() != 1 || ld.LogRecordCount() != 1 {
+		t.Fatalf("expected a scope with a record, got %d scopes with %d records", ld.ResourceLogs().At(0).ScopeLogs().Len(), ld.LogRecordCount())
+	}
+
+	ld = Logs(ld, func(plog.LogRecord) bool { return false })
+	if ld.ResourceLogs().Len() != 0 {
+		t.Errorf("expected no resource once all records are removed, got %d", ld.ResourceLogs().Len())
+	}
+}
@@ -4,11 +4,15 @@ go 1.24.2
 
 require (
 	github.com/go-viper/mapstructure/v2 v2.2.1
+	go.opentelemetry.io/collector/client v1.31.0
 	go.opentelemetry.io/collector/component v1.31.0
 	go.opentelemetry.io/collector/component/componenttest v0.125.0
 	go.opentelemetry.io/collector/consumer v1.31.0
+	go.opentelemetry.io/collector/consumer/consumererror v0.125.0
 	go.opentelemetry.io/collector/exporter v0.125.0
+	go.opentelemetry.io/collector/extension/extensionauth v1.31.0
 	go.opentelemetry.io/collector/pdata v1.31.0
+	go.opentelemetry.io/collector/pdata/pprofile v0.125.0
 	go.opentelemetry.io/collector/processor v1.31.0
 	go.opentelemetry.io/collector/receiver v1.31.0
 	go.uber.org/zap v1.27.0
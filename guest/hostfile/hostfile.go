@@ -0,0 +1,51 @@
+// Package hostfile reads host files through the host, within the paths
+// allowed by the files.allowed_paths option of the plugin, as guests have no
+// WASI access to the host filesystem. Files outside of them fail with an
+// error matching fs.ErrPermission.
+//
+// The host serves the reads with the hostReadFile and hostStatFile host
+// functions, so guests using the package don't run on hosts predating them.
+package hostfile
+
+import (
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// readFile and statFile read from the host, replaced in tests.
+var (
+	readFile = imports.ReadFile
+	statFile = imports.StatFile
+)
+
+// FileInfo describes a host file.
+type FileInfo struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Stat returns the FileInfo of the host file at path, which must be
+// absolute.
+func Stat(path string) (FileInfo, error) {
+	size, modTime, err := statFile(path)
+	if err != nil {
+		return FileInfo{}, &fs.PathError{Op: "stat", Path: path, Err: err}
+	}
+	return FileInfo{Size: size, ModTime: time.Unix(0, modTime)}, nil
+}
+
+// ReadAt reads len(p) bytes of the host file at path from off, with the
+// semantics of io.ReaderAt: io.EOF is returned if fewer bytes are read.
+func ReadAt(path string, p []byte, off int64) (int, error) {
+	n, err := readFile(path, off, p)
+	if err != nil {
+		return n, &fs.PathError{Op: "read", Path: path, Err: err}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
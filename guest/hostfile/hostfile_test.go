@@ -0,0 +1,72 @@
+package hostfile
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// fakeHost serves the reads from files, by path, refusing the others.
+func fakeHost(t *testing.T, files map[string]string) {
+	t.Helper()
+	prevRead, prevStat := readFile, statFile
+	t.Cleanup(func() { readFile, statFile = prevRead, prevStat })
+	readFile = func(path string, offset int64, buf []byte) (int, error) {
+		content, ok := files[path]
+		if !ok {
+			return 0, imports.ErrNotAllowed
+		}
+		if offset >= int64(len(content)) {
+			return 0, nil
+		}
+		return copy(buf, content[offset:]), nil
+	}
+	statFile = func(path string) (int64, int64, error) {
+		content, ok := files[path]
+		if !ok {
+			return 0, 0, imports.ErrNotAllowed
+		}
+		return int64(len(content)), 42, nil
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	fakeHost(t, map[string]string{"/var/log/app.log": "first\nsecond\n"})
+
+	buf := make([]byte, 6)
+	if n, err := ReadAt("/var/log/app.log", buf, 0); err != nil || string(buf[:n]) != "first\n" {
+		t.Errorf("expected the first line, got %q, %v", buf[:n], err)
+	}
+	buf = make([]byte, 16)
+	if n, err := ReadAt("/var/log/app.log", buf, 6); err != io.EOF || string(buf[:n]) != "second\n" {
+		t.Errorf("expected the second line and io.EOF, got %q, %v", buf[:n], err)
+	}
+}
+
+func TestStat(t *testing.T) {
+	fakeHost(t, map[string]string{"/var/log/app.log": "first\n"})
+
+	info, err := Stat("/var/log/app.log")
+	if err != nil {
+		t.Fatalf("failed to stat: %v", err)
+	}
+	if info.Size != 6 || info.ModTime.UnixNano() != 42 {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestNotAllowed(t *testing.T) {
+	fakeHost(t, nil)
+
+	_, err := Stat("/etc/passwd")
+	var pathErr *fs.PathError
+	if !errors.Is(err, fs.ErrPermission) || !errors.As(err, &pathErr) || pathErr.Path != "/etc/passwd" {
+		t.Errorf("expected a permission error on the path, got %v", err)
+	}
+	if _, err := ReadAt("/etc/passwd", make([]byte, 1), 0); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected a permission error, got %v", err)
+	}
+}
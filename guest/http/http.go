@@ -0,0 +1,99 @@
+// Package http sends HTTP requests through the host, which performs them
+// within the destinations allowed by the http.allowed_hosts option of the
+// plugin, so guests need no socket support and the collector controls their
+// egress. Redirects are followed by the host, within the same destinations.
+//
+// The host serves the requests with the httpDo host function, so guests
+// using the package don't run on hosts predating it.
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// httpDo sends a request through the host, replaced in tests.
+var httpDo = imports.HTTPDo
+
+// request is the request passed to the host, in JSON.
+type request struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   []byte              `json:"body,omitempty"`
+}
+
+// response is the response of the host, in JSON. Error is set if the host
+// refused or failed to send the request.
+type response struct {
+	StatusCode int                 `json:"status_code"`
+	Header     map[string][]string `json:"header"`
+	Body       []byte              `json:"body"`
+	Error      string              `json:"error"`
+}
+
+// Transport is the http.RoundTripper sending the requests through the host.
+// The host bounds the requests with the http.timeout option, and the
+// responses with http.max_response_size, regardless of the context of the
+// request.
+type Transport struct{}
+
+var _ http.RoundTripper = Transport{}
+
+// RoundTrip implements http.RoundTripper.
+func (Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := request{Method: req.Method, URL: req.URL.String(), Header: req.Header}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	rawReq, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp response
+	if err := json.Unmarshal(httpDo(rawReq), &resp); err != nil {
+		return nil, fmt.Errorf("invalid response from host: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(resp.Header),
+		Body:          io.NopCloser(bytes.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
+
+// DefaultClient is the http.Client sending the requests through the host.
+var DefaultClient = &http.Client{Transport: Transport{}}
+
+// Get issues a GET to url with DefaultClient.
+func Get(url string) (*http.Response, error) {
+	return DefaultClient.Get(url)
+}
+
+// Post issues a POST to url with DefaultClient.
+func Post(url, contentType string, body io.Reader) (*http.Response, error) {
+	return DefaultClient.Post(url, contentType, body)
+}
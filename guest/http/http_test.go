@@ -0,0 +1,63 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeHost answers the requests sent through the host with resp, and
+// returns the last request sent.
+func fakeHost(t *testing.T, resp response) *request {
+	t.Helper()
+	prev := httpDo
+	t.Cleanup(func() { httpDo = prev })
+	var last request
+	httpDo = func(req []byte) []byte {
+		if err := json.Unmarshal(req, &last); err != nil {
+			t.Fatalf("failed to decode request %s: %v", req, err)
+		}
+		b, _ := json.Marshal(resp)
+		return b
+	}
+	return &last
+}
+
+func TestPost(t *testing.T) {
+	req := fakeHost(t, response{
+		StatusCode: http.StatusAccepted,
+		Header:     map[string][]string{"Content-Type": {"text/plain"}},
+		Body:       []byte("accepted"),
+	})
+
+	resp, err := Post("http://example.com/ingest", "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if req.Method != http.MethodPost || req.URL != "http://example.com/ingest" || string(req.Body) != `{"a":1}` {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if got := req.Header["Content-Type"]; len(got) != 1 || got[0] != "application/json" {
+		t.Errorf("expected the content type to be sent, got %v", got)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusAccepted || resp.Status != "202 Accepted" || string(body) != "accepted" {
+		t.Errorf("unexpected response %d %q %q", resp.StatusCode, resp.Status, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("expected the response header, got %q", got)
+	}
+}
+
+func TestGetRefused(t *testing.T) {
+	fakeHost(t, response{Error: "destination not in http.allowed_hosts: example.com:80"})
+
+	_, err := Get("http://example.com")
+	if err == nil || !strings.Contains(err.Error(), "not in http.allowed_hosts") {
+		t.Errorf("expected the error of the host, got %v", err)
+	}
+}
@@ -1,26 +1,46 @@
 package imports
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime"
 
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
 	"github.com/otelwasm/otelwasm/guest/internal/mem"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
+// GetConfig decodes the plugin config into v. An error is returned if the
+// host failed to pass the config.
 func GetConfig(v any) error {
-	rawMsg := mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
-		return getPluginConfig(ptr, limit)
-	})
+	rawMsg, err := imports.PluginConfig()
+	if err != nil {
+		return err
+	}
 	return json.Unmarshal(rawMsg, v)
 }
 
+// GetConfigVersion returns the version of the config GetConfig reads. The
+// host increments it when the config is updated, so a guest caching the
+// decoded config calls GetConfig again once the version changes. The
+// version doesn't change during a call.
+func GetConfigVersion() uint32 {
+	return getPluginConfigVersion()
+}
+
+// GetShutdownRequested reports whether the host requested the guest to shut
+// down, as it does for the call of api.Shutdowner.
+func GetShutdownRequested() bool {
+	return getShutdownRequested() != 0
+}
+
 func SetResultTraces(traces ptrace.Traces) {
-	marshaler := ptrace.ProtoMarshaler{}
-	rawMsg, err := marshaler.MarshalTraces(traces)
+	rawMsg, err := imports.MarshalResultTraces(traces)
 	if err != nil {
 		fmt.Println(err)
 		panic(err)
@@ -30,6 +50,40 @@ func SetResultTraces(traces ptrace.Traces) {
 	runtime.KeepAlive(rawMsg) // until ptr is no longer needed
 }
 
+// AppendResultTraces appends traces to the result batches of the processor,
+// each passed downstream on its own. The result set by SetResultTraces is
+// ignored once a batch is appended.
+func AppendResultTraces(traces ptrace.Traces) {
+	rawMsg, err := imports.MarshalResultTraces(traces)
+	if err != nil {
+		panic(err)
+	}
+	ptr, size := mem.BytesToPtr(rawMsg)
+	appendResultTraces(ptr, size)
+	runtime.KeepAlive(rawMsg) // until ptr is no longer needed
+}
+
+// ErrEmitFailed is returned by EmitTraces if the next consumer of the
+// receiver refused the batch.
+var ErrEmitFailed = errors.New("next consumer refused the emitted data")
+
+// EmitTraces passes traces to the next consumer of the receiver immediately,
+// unlike SetResultTraces which overwrites the result of the call, so a
+// long-running receiver can emit any number of batches.
+func EmitTraces(traces ptrace.Traces) error {
+	rawMsg, err := imports.MarshalResultTraces(traces)
+	if err != nil {
+		return err
+	}
+	ptr, size := mem.BytesToPtr(rawMsg)
+	status := emitTraces(ptr, size)
+	runtime.KeepAlive(rawMsg) // until ptr is no longer needed
+	if status != 0 {
+		return ErrEmitFailed
+	}
+	return nil
+}
+
 func SetResultMetrics(metrics pmetric.Metrics) {
 	marshaler := pmetric.ProtoMarshaler{}
 	rawMsg, err := marshaler.MarshalMetrics(metrics)
@@ -47,7 +101,162 @@ func SetResultLogs(logs plog.Logs) {
 	if err != nil {
 		panic(err)
 	}
+	if rawMsg, err = imports.EncodeResultLogs(rawMsg); err != nil {
+		panic(err)
+	}
 	ptr, size := mem.BytesToPtr(rawMsg)
 	setResultLogs(ptr, size)
 	runtime.KeepAlive(rawMsg) // until ptr is no longer needed
 }
+
+func SetResultProfiles(profiles pprofile.Profiles) {
+	rawMsg, err := (&pprofile.ProtoMarshaler{}).MarshalProfiles(profiles)
+	if err != nil {
+		panic(err)
+	}
+	ptr, size := mem.BytesToPtr(rawMsg)
+	setResultProfiles(ptr, size)
+	runtime.KeepAlive(rawMsg) // until ptr is no longer needed
+}
+
+// SetResultUnchanged reports that the processor left the telemetry passed to
+// it as is, so the host passes on its own copy instead of decoding a result,
+// which saves serializing the telemetry back. The result set by the call, if
+// any, is ignored.
+//
+// Hosts predating the function refuse to load the guests calling it.
+func SetResultUnchanged() {
+	setResultUnchanged()
+}
+
+// GetBagValue returns the value stored under key in the bag shared with the
+// other guests handling the same batch, or an empty string if there is none.
+func GetBagValue(key string) string {
+	keyPtr, keyLen := mem.StringToPtr(key)
+	value := mem.GetString(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		return getBagValue(keyPtr, keyLen, ptr, limit)
+	})
+	runtime.KeepAlive(key) // until keyPtr is no longer needed
+	return value
+}
+
+// SetBagValue stores value under key in the bag shared with the other guests
+// handling the same batch, so downstream guests can read it.
+func SetBagValue(key, value string) {
+	keyPtr, keyLen := mem.StringToPtr(key)
+	valuePtr, valueLen := mem.StringToPtr(value)
+	setBagValue(keyPtr, keyLen, valuePtr, valueLen)
+	runtime.KeepAlive(key) // until keyPtr is no longer needed
+	runtime.KeepAlive(value)
+}
+
+// envNotFound is returned by getEnv if the variable isn't exposed to the
+// guest.
+const envNotFound = ^uint32(0)
+
+// GetEnv returns the value of the environment variable name. Only the
+// variables allowlisted in the plugin configuration are exposed, so the
+// boolean is false for any other variable.
+func GetEnv(name string) (string, bool) {
+	namePtr, nameLen := mem.StringToPtr(name)
+	found := true
+	value := mem.GetString(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		len = getEnv(namePtr, nameLen, ptr, limit)
+		if len == envNotFound {
+			found = false
+			return 0
+		}
+		return len
+	})
+	runtime.KeepAlive(name) // until namePtr is no longer needed
+	return value, found
+}
+
+// configFileNotFound is returned by getConfigFile if no file is configured
+// under the name.
+const configFileNotFound = ^uint32(0)
+
+// ConfigFile returns the content of the file configured under name in the
+// plugin_config_files of the component. The boolean is false if no file is
+// configured under name.
+func ConfigFile(name string) ([]byte, bool) {
+	namePtr, nameLen := mem.StringToPtr(name)
+	found := true
+	content := mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		len = getConfigFile(namePtr, nameLen, ptr, limit)
+		if len == configFileNotFound {
+			found = false
+			return 0
+		}
+		return len
+	})
+	runtime.KeepAlive(name) // until namePtr is no longer needed
+	if !found {
+		return nil, false
+	}
+	// The content is read in a buffer reused by the next reads.
+	return bytes.Clone(content), true
+}
+
+// Extensions returns the IDs of the collector extensions available to the
+// guest.
+func Extensions() ([]string, error) {
+	rawMsg := mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		return getExtensions(ptr, limit)
+	})
+	if len(rawMsg) == 0 {
+		return nil, nil
+	}
+	var ids []string
+	if err := json.Unmarshal(rawMsg, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ClientInfo is the information of a client authenticated by the host.
+type ClientInfo struct {
+	// Auth maps the names of the auth data attributes to their value. Only
+	// the attributes representable in JSON are passed by the host.
+	Auth map[string]any `json:"auth"`
+
+	// Metadata is the client metadata set by the authenticator.
+	Metadata map[string][]string `json:"metadata"`
+}
+
+// Authenticate authenticates the request headers with the server
+// authenticator extension of the given ID.
+func Authenticate(id string, headers map[string][]string) (ClientInfo, error) {
+	rawHeaders, err := json.Marshal(headers)
+	if err != nil {
+		return ClientInfo{}, err
+	}
+	idPtr, idLen := mem.StringToPtr(id)
+	headersPtr, headersLen := mem.BytesToPtr(rawHeaders)
+	rawMsg := mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		return authenticate(idPtr, idLen, headersPtr, headersLen, ptr, limit)
+	})
+	runtime.KeepAlive(id) // until idPtr is no longer needed
+	runtime.KeepAlive(rawHeaders)
+
+	var result struct {
+		ClientInfo
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rawMsg, &result); err != nil {
+		return ClientInfo{}, err
+	}
+	if result.Error != "" {
+		return ClientInfo{}, errors.New(result.Error)
+	}
+	return result.ClientInfo, nil
+}
+
+// TraceParent returns the W3C traceparent of the span the host started
+// around the current call, so the spans of the guest can be its children.
+// It is empty unless the host traces the guest calls.
+func TraceParent() string {
+	return mem.GetString(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		return getTraceParent(ptr, limit)
+	})
+}
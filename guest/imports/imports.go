@@ -2,14 +2,50 @@
 
 package imports
 
-//go:wasmimport opentelemetry.io/wasm getPluginConfig
-func getPluginConfig(ptr, size uint32) (len uint32)
+//go:wasmimport opentelemetry.io/wasm getPluginConfigVersion
+func getPluginConfigVersion() uint32
+
+//go:wasmimport opentelemetry.io/wasm getShutdownRequested
+func getShutdownRequested() uint32
 
 //go:wasmimport opentelemetry.io/wasm setResultTraces
 func setResultTraces(ptr, size uint32)
 
+//go:wasmimport opentelemetry.io/wasm appendResultTraces
+func appendResultTraces(ptr, size uint32)
+
+//go:wasmimport opentelemetry.io/wasm emitTraces
+func emitTraces(ptr, size uint32) (status uint32)
+
 //go:wasmimport opentelemetry.io/wasm setResultMetrics
 func setResultMetrics(ptr, size uint32)
 
 //go:wasmimport opentelemetry.io/wasm setResultLogs
 func setResultLogs(ptr, size uint32)
+
+//go:wasmimport opentelemetry.io/wasm setResultProfiles
+func setResultProfiles(ptr, size uint32)
+
+//go:wasmimport opentelemetry.io/wasm setResultUnchanged
+func setResultUnchanged()
+
+//go:wasmimport opentelemetry.io/wasm getBagValue
+func getBagValue(keyPtr, keySize, ptr, size uint32) (len uint32)
+
+//go:wasmimport opentelemetry.io/wasm setBagValue
+func setBagValue(keyPtr, keySize, valuePtr, valueSize uint32)
+
+//go:wasmimport opentelemetry.io/wasm getEnv
+func getEnv(namePtr, nameSize, ptr, size uint32) (len uint32)
+
+//go:wasmimport opentelemetry.io/wasm getConfigFile
+func getConfigFile(namePtr, nameSize, ptr, size uint32) (len uint32)
+
+//go:wasmimport opentelemetry.io/wasm getExtensions
+func getExtensions(ptr, size uint32) (len uint32)
+
+//go:wasmimport opentelemetry.io/wasm authenticate
+func authenticate(idPtr, idSize, headersPtr, headersSize, ptr, size uint32) (len uint32)
+
+//go:wasmimport opentelemetry.io/wasm getTraceParent
+func getTraceParent(ptr, size uint32) (len uint32)
@@ -4,10 +4,34 @@ package imports
 
 // This file is used to stub out the imports for running tests.
 
-func getPluginConfig(ptr, size uint32) (len uint32) { return }
+func getPluginConfigVersion() uint32 { return 0 }
+
+func getShutdownRequested() uint32 { return 0 }
 
 func setResultTraces(ptr, size uint32) { return }
 
+func appendResultTraces(ptr, size uint32) { return }
+
+func emitTraces(ptr, size uint32) (status uint32) { return }
+
 func setResultMetrics(ptr, size uint32) { return }
 
 func setResultLogs(ptr, size uint32) { return }
+
+func setResultProfiles(ptr, size uint32) { return }
+
+func setResultUnchanged() { return }
+
+func getBagValue(keyPtr, keySize, ptr, size uint32) (len uint32) { return }
+
+func setBagValue(keyPtr, keySize, valuePtr, valueSize uint32) { return }
+
+func getEnv(namePtr, nameSize, ptr, size uint32) (len uint32) { return envNotFound }
+
+func getConfigFile(namePtr, nameSize, ptr, size uint32) (len uint32) { return configFileNotFound }
+
+func getExtensions(ptr, size uint32) (len uint32) { return }
+
+func authenticate(idPtr, idSize, headersPtr, headersSize, ptr, size uint32) (len uint32) { return }
+
+func getTraceParent(ptr, size uint32) (len uint32) { return }
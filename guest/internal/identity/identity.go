@@ -0,0 +1,60 @@
+// Package identity computes stable keys identifying attribute sets, so
+// records carrying equal attributes can be grouped regardless of the
+// insertion order of their attributes.
+package identity
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// MapKey returns a key that is equal for two maps if and only if they hold
+// the same attributes.
+func MapKey(m pcommon.Map) string {
+	var sb strings.Builder
+	writeMap(&sb, m)
+	return sb.String()
+}
+
+func writeMap(sb *strings.Builder, m pcommon.Map) {
+	keys := make([]string, 0, m.Len())
+	m.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Strings(keys)
+
+	sb.WriteByte('{')
+	for _, k := range keys {
+		v, _ := m.Get(k)
+		sb.WriteString(strconv.Quote(k))
+		sb.WriteByte(':')
+		writeValue(sb, v)
+		sb.WriteByte(',')
+	}
+	sb.WriteByte('}')
+}
+
+func writeValue(sb *strings.Builder, v pcommon.Value) {
+	// The type is part of the key so that e.g. the string "1" and the int 1
+	// are told apart.
+	sb.WriteString(v.Type().String())
+	sb.WriteByte(':')
+	switch v.Type() {
+	case pcommon.ValueTypeMap:
+		writeMap(sb, v.Map())
+	case pcommon.ValueTypeSlice:
+		sb.WriteByte('[')
+		s := v.Slice()
+		for i := 0; i < s.Len(); i++ {
+			writeValue(sb, s.At(i))
+			sb.WriteByte(',')
+		}
+		sb.WriteByte(']')
+	default:
+		sb.WriteString(strconv.Quote(v.AsString()))
+	}
+}
@@ -0,0 +1,49 @@
+package imports
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// gzipMagic starts every gzip stream, while serialized logs never start with
+// it.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// logsCompressed is set if the host passed the last logs read compressed, so
+// the result logs are passed back compressed too.
+var logsCompressed bool
+
+// decompressLogs returns the serialized logs passed by the host,
+// decompressed if the host compressed them.
+func decompressLogs(b []byte) ([]byte, error) {
+	logsCompressed = bytes.HasPrefix(b, gzipMagic)
+	if !logsCompressed {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// EncodeResultLogs returns the serialized result logs as the host reads
+// them: compressed if the host compressed the logs passed to the guest.
+func EncodeResultLogs(b []byte) ([]byte, error) {
+	if !logsCompressed {
+		return b, nil
+	}
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,38 @@
+package imports
+
+import (
+	"bytes"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// tracesJSON is set if the host passed the last traces read in OTLP JSON, so
+// the result traces are passed back in JSON too. It is the preference of the
+// plugin until traces are read, see SetTracesJSON.
+var tracesJSON bool
+
+// SetTracesJSON sets whether the plugin prefers OTLP JSON traces, which the
+// host passes by default. The traces written before any is read, e.g. by a
+// receiver, are in that encoding.
+func SetTracesJSON(json bool) {
+	tracesJSON = json
+}
+
+// unmarshalTraces decodes the traces passed by the host, in OTLP JSON if the
+// plugin prefers it, OTLP protobuf otherwise.
+func unmarshalTraces(b []byte) (ptrace.Traces, error) {
+	tracesJSON = bytes.HasPrefix(b, []byte("{"))
+	if tracesJSON {
+		return (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(b)
+	}
+	return (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(b)
+}
+
+// MarshalResultTraces serializes the result traces in the encoding of the
+// traces passed by the host. The host reads either encoding.
+func MarshalResultTraces(traces ptrace.Traces) ([]byte, error) {
+	if tracesJSON {
+		return (&ptrace.JSONMarshaler{}).MarshalTraces(traces)
+	}
+	return (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+}
@@ -0,0 +1,41 @@
+package imports
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestTracesEncodingRoundTrip(t *testing.T) {
+	t.Cleanup(func() { tracesJSON = false })
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("GET /cart")
+
+	for name, marshal := range map[string]func(ptrace.Traces) ([]byte, error){
+		"proto": (&ptrace.ProtoMarshaler{}).MarshalTraces,
+		"json":  (&ptrace.JSONMarshaler{}).MarshalTraces,
+	} {
+		t.Run(name, func(t *testing.T) {
+			passed, err := marshal(traces)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := unmarshalTraces(passed)
+			if err != nil {
+				t.Fatalf("failed to decode the traces passed by the host: %v", err)
+			}
+			if got.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name() != "GET /cart" {
+				t.Error("expected the span to be decoded")
+			}
+
+			// The result is encoded as the traces passed.
+			result, err := MarshalResultTraces(got)
+			if err != nil {
+				t.Fatalf("failed to encode the result: %v", err)
+			}
+			if string(result) != string(passed) {
+				t.Errorf("expected the result in the %s encoding, got %q", name, result)
+			}
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package imports
 
 import (
+	"bytes"
+	"encoding/binary"
 	"runtime"
 
 	"github.com/otelwasm/otelwasm/guest/api"
 	"github.com/otelwasm/otelwasm/guest/internal/mem"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
@@ -34,11 +37,10 @@ func setStatusReason(reason string) {
 }
 
 func CurrentTraces() ptrace.Traces {
-	rawMsg := mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
-		return currentTraces(ptr, limit)
-	})
-	unmarshaler := ptrace.ProtoUnmarshaler{}
-	traces, err := unmarshaler.UnmarshalTraces(rawMsg)
+	// Traces are read frame by frame, so large batches are marshaled once by
+	// the host.
+	rawMsg := mem.GetChunked(currentTracesChunk)
+	traces, err := unmarshalTraces(rawMsg)
 	if err != nil {
 		panic(err)
 	}
@@ -61,6 +63,10 @@ func CurrentLogs() plog.Logs {
 	rawMsg := mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
 		return currentLogs(ptr, limit)
 	})
+	rawMsg, err := decompressLogs(rawMsg)
+	if err != nil {
+		panic(err)
+	}
 	unmarshaler := plog.ProtoUnmarshaler{}
 	logs, err := unmarshaler.UnmarshalLogs(rawMsg)
 	if err != nil {
@@ -69,6 +75,181 @@ func CurrentLogs() plog.Logs {
 	return logs
 }
 
+func CurrentProfiles() pprofile.Profiles {
+	rawMsg := mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		return currentProfiles(ptr, limit)
+	})
+	profiles, err := (&pprofile.ProtoUnmarshaler{}).UnmarshalProfiles(rawMsg)
+	if err != nil {
+		panic(err)
+	}
+	return profiles
+}
+
 func GetShutdownRequested() bool {
 	return getShutdownRequested() != 0
 }
+
+// DeadlineUnixNano returns the deadline of the current call in nanoseconds
+// since the Unix epoch, or 0 if the call has no deadline.
+func DeadlineUnixNano() int64 {
+	return getDeadlineUnixNano()
+}
+
+// HostNow returns the wall clock and monotonic clock readings of the host,
+// in nanoseconds.
+func HostNow() (wall, monotonic int64) {
+	var buf [16]byte
+	ptr, _ := mem.BytesToPtr(buf[:])
+	hostNow(ptr)
+	runtime.KeepAlive(buf) // until ptr is no longer needed
+	return int64(binary.LittleEndian.Uint64(buf[:8])), int64(binary.LittleEndian.Uint64(buf[8:]))
+}
+
+// LogMessage logs msg with the logger of the host. fields is a JSON object,
+// or empty if the message has no fields.
+func LogMessage(level int32, msg string, fields []byte) {
+	msgPtr, msgLen := mem.StringToPtr(msg)
+	var fieldsPtr, fieldsLen uint32
+	if len(fields) > 0 {
+		fieldsPtr, fieldsLen = mem.BytesToPtr(fields)
+	}
+	logMessage(level, msgPtr, msgLen, fieldsPtr, fieldsLen)
+	runtime.KeepAlive(msg) // until msgPtr is no longer needed
+	runtime.KeepAlive(fields)
+}
+
+// LogLevel returns the minimum zap level enabled by the logger of the host.
+func LogLevel() int32 {
+	return getLogLevel()
+}
+
+// RecordMetric records value in the metric name of the given kind of the
+// host. attrs is a JSON object, or empty if the value has no attributes.
+func RecordMetric(name string, kind uint32, value int64, attrs []byte) {
+	namePtr, nameLen := mem.StringToPtr(name)
+	var attrsPtr, attrsLen uint32
+	if len(attrs) > 0 {
+		attrsPtr, attrsLen = mem.BytesToPtr(attrs)
+	}
+	recordMetric(namePtr, nameLen, kind, value, attrsPtr, attrsLen)
+	runtime.KeepAlive(name) // until namePtr is no longer needed
+	runtime.KeepAlive(attrs)
+}
+
+// KVNotFound is returned by kvGet if the key isn't set.
+const KVNotFound = ^uint32(0)
+
+// KVGet returns the value of key in the state of the guest. The boolean is
+// false if key isn't set.
+func KVGet(key string) ([]byte, bool) {
+	keyPtr, keyLen := mem.StringToPtr(key)
+	found := true
+	value := mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		len = kvGet(keyPtr, keyLen, ptr, limit)
+		if len == KVNotFound {
+			found = false
+			return 0
+		}
+		return len
+	})
+	runtime.KeepAlive(key) // until keyPtr is no longer needed
+	if !found {
+		return nil, false
+	}
+	// The value is read in a buffer reused by the next reads.
+	return bytes.Clone(value), true
+}
+
+// KVSet sets the value of key in the state of the guest, or deletes key if
+// value is empty, and returns the status of the host.
+func KVSet(key string, value []byte) uint32 {
+	keyPtr, keyLen := mem.StringToPtr(key)
+	var valuePtr, valueLen uint32
+	if len(value) > 0 {
+		valuePtr, valueLen = mem.BytesToPtr(value)
+	}
+	status := kvSet(keyPtr, keyLen, valuePtr, valueLen)
+	runtime.KeepAlive(key) // until keyPtr is no longer needed
+	runtime.KeepAlive(value)
+	return status
+}
+
+// ComponentInfo returns the JSON description of the component running the
+// guest, with its ID and the resource attributes of the collector.
+func ComponentInfo() []byte {
+	return mem.GetBytes(func(ptr uint32, limit mem.BufLimit) (len uint32) {
+		return getComponentInfo(ptr, limit)
+	})
+}
+
+// RandUint64 returns a random number drawn by the host.
+func RandUint64() uint64 {
+	return randUint64()
+}
+
+// HTTPDo sends the request, in JSON, through the host and returns the
+// response, in JSON. The response is read frame by frame, so the host sends
+// the request once however large the response.
+func HTTPDo(req []byte) []byte {
+	ptr, size := mem.BytesToPtr(req)
+	httpDo(ptr, size)
+	runtime.KeepAlive(req) // until ptr is no longer needed
+	return mem.GetChunked(httpResponseChunk)
+}
+
+// ReadFile reads the host file at path from offset into buf, and returns the
+// number of bytes read, fewer than len(buf) only at the end of the file.
+func ReadFile(path string, offset int64, buf []byte) (int, error) {
+	pathPtr, pathLen := mem.StringToPtr(path)
+	ptr, limit := mem.BytesToPtr(buf)
+	var n uint32
+	status := withStatus(func(statusPtr uint32) {
+		n = hostReadFile(pathPtr, pathLen, offset, ptr, limit, statusPtr)
+	})
+	runtime.KeepAlive(path) // until pathPtr is no longer needed
+	runtime.KeepAlive(buf)
+	return int(n), status.Err()
+}
+
+// StatFile returns the size of the host file at path, and its modification
+// time in nanoseconds since the epoch.
+func StatFile(path string) (size, modTime int64, err error) {
+	pathPtr, pathLen := mem.StringToPtr(path)
+	var stat [16]byte
+	statPtr, _ := mem.BytesToPtr(stat[:])
+	status := withStatus(func(statusPtr uint32) {
+		hostStatFile(pathPtr, pathLen, statPtr, statusPtr)
+	})
+	runtime.KeepAlive(path) // until pathPtr is no longer needed
+	runtime.KeepAlive(stat)
+	if err := status.Err(); err != nil {
+		return 0, 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(stat[:8])), int64(binary.LittleEndian.Uint64(stat[8:])), nil
+}
+
+// HashSHA256 returns the SHA-256 digest of b computed by the host, salted
+// with the salt of the plugin config of the host if salted is set. Salted
+// digests fail with ErrNotAllowed if the host has no salt.
+func HashSHA256(b []byte, salted bool) ([32]byte, error) {
+	var digest [32]byte
+	ptr, size := mem.BytesToPtr(b)
+	digestPtr, _ := mem.BytesToPtr(digest[:])
+	var saltedFlag uint32
+	if salted {
+		saltedFlag = 1
+	}
+	status := Status(hashSHA256(ptr, size, saltedFlag, digestPtr))
+	runtime.KeepAlive(b) // until ptr is no longer needed
+	runtime.KeepAlive(digest)
+	return digest, status.Err()
+}
+
+// HashFNV64 returns the 64-bit FNV-1a hash of b computed by the host.
+func HashFNV64(b []byte) uint64 {
+	ptr, size := mem.BytesToPtr(b)
+	h := hashFNV64(ptr, size)
+	runtime.KeepAlive(b) // until ptr is no longer needed
+	return h
+}
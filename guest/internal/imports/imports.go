@@ -4,8 +4,8 @@ package imports
 
 import "github.com/otelwasm/otelwasm/guest/internal/mem"
 
-//go:wasmimport opentelemetry.io/wasm currentTraces
-func currentTraces(ptr uint32, limit mem.BufLimit) (len uint32)
+//go:wasmimport opentelemetry.io/wasm currentTracesChunk
+func currentTracesChunk(offset, ptr uint32, limit mem.BufLimit) (size uint32)
 
 //go:wasmimport opentelemetry.io/wasm currentMetrics
 func currentMetrics(ptr uint32, limit mem.BufLimit) (len uint32)
@@ -13,8 +13,59 @@ func currentMetrics(ptr uint32, limit mem.BufLimit) (len uint32)
 //go:wasmimport opentelemetry.io/wasm currentLogs
 func currentLogs(ptr uint32, limit mem.BufLimit) (len uint32)
 
+//go:wasmimport opentelemetry.io/wasm currentProfiles
+func currentProfiles(ptr uint32, limit mem.BufLimit) (len uint32)
+
 //go:wasmimport opentelemetry.io/wasm setResultStatusReason
 func setResultStatusReason(ptr, size uint32)
 
 //go:wasmimport opentelemetry.io/wasm getShutdownRequested
 func getShutdownRequested() uint32
+
+//go:wasmimport opentelemetry.io/wasm getDeadlineUnixNano
+func getDeadlineUnixNano() int64
+
+//go:wasmimport opentelemetry.io/wasm hostNow
+func hostNow(ptr uint32)
+
+//go:wasmimport opentelemetry.io/wasm logMessage
+func logMessage(level int32, msgPtr, msgSize, fieldsPtr, fieldsSize uint32)
+
+//go:wasmimport opentelemetry.io/wasm getLogLevel
+func getLogLevel() int32
+
+//go:wasmimport opentelemetry.io/wasm recordMetric
+func recordMetric(namePtr, nameSize, kind uint32, value int64, attrsPtr, attrsSize uint32)
+
+//go:wasmimport opentelemetry.io/wasm kvGet
+func kvGet(keyPtr, keySize, ptr uint32, limit mem.BufLimit) (len uint32)
+
+//go:wasmimport opentelemetry.io/wasm kvSet
+func kvSet(keyPtr, keySize, valuePtr, valueSize uint32) (status uint32)
+
+//go:wasmimport opentelemetry.io/wasm getComponentInfo
+func getComponentInfo(ptr uint32, limit mem.BufLimit) (len uint32)
+
+//go:wasmimport opentelemetry.io/wasm randUint64
+func randUint64() uint64
+
+//go:wasmimport opentelemetry.io/wasm httpDo
+func httpDo(reqPtr, reqSize uint32) (size uint32)
+
+//go:wasmimport opentelemetry.io/wasm httpResponseChunk
+func httpResponseChunk(offset, ptr uint32, limit mem.BufLimit) (size uint32)
+
+//go:wasmimport opentelemetry.io/wasm getPluginConfigStatus
+func getPluginConfigStatus(ptr uint32, limit mem.BufLimit, statusPtr uint32) (len uint32)
+
+//go:wasmimport opentelemetry.io/wasm hostReadFile
+func hostReadFile(pathPtr, pathSize uint32, offset int64, ptr uint32, limit mem.BufLimit, statusPtr uint32) (n uint32)
+
+//go:wasmimport opentelemetry.io/wasm hostStatFile
+func hostStatFile(pathPtr, pathSize, statPtr, statusPtr uint32)
+
+//go:wasmimport opentelemetry.io/wasm hashSHA256
+func hashSHA256(ptr, size, salted, digestPtr uint32) (status uint32)
+
+//go:wasmimport opentelemetry.io/wasm hashFNV64
+func hashFNV64(ptr, size uint32) uint64
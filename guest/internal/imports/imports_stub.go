@@ -6,12 +6,48 @@ import "github.com/otelwasm/otelwasm/guest/internal/mem"
 
 // This file is used to stub out the imports for running tests.
 
-func currentTraces(ptr uint32, limit mem.BufLimit) (len uint32) { return }
+func currentTracesChunk(offset, ptr uint32, limit mem.BufLimit) (size uint32) { return }
 
 func currentMetrics(ptr uint32, limit mem.BufLimit) (len uint32) { return }
 
 func currentLogs(ptr uint32, limit mem.BufLimit) (len uint32) { return }
 
+func currentProfiles(ptr uint32, limit mem.BufLimit) (len uint32) { return }
+
 func setResultStatusReason(ptr, size uint32) { return }
 
 func getShutdownRequested() uint32 { return 0 }
+
+func getDeadlineUnixNano() int64 { return 0 }
+
+func hostNow(ptr uint32) { return }
+
+func logMessage(level int32, msgPtr, msgSize, fieldsPtr, fieldsSize uint32) { return }
+
+func getLogLevel() int32 { return 0 }
+
+func recordMetric(namePtr, nameSize, kind uint32, value int64, attrsPtr, attrsSize uint32) { return }
+
+func kvGet(keyPtr, keySize, ptr uint32, limit mem.BufLimit) (len uint32) { return KVNotFound }
+
+func kvSet(keyPtr, keySize, valuePtr, valueSize uint32) (status uint32) { return }
+
+func getComponentInfo(ptr uint32, limit mem.BufLimit) (len uint32) { return }
+
+func randUint64() uint64 { return 0 }
+
+func httpDo(reqPtr, reqSize uint32) (size uint32) { return }
+
+func httpResponseChunk(offset, ptr uint32, limit mem.BufLimit) (size uint32) { return }
+
+func getPluginConfigStatus(ptr uint32, limit mem.BufLimit, statusPtr uint32) (len uint32) { return }
+
+func hostReadFile(pathPtr, pathSize uint32, offset int64, ptr uint32, limit mem.BufLimit, statusPtr uint32) (n uint32) {
+	return
+}
+
+func hostStatFile(pathPtr, pathSize, statPtr, statusPtr uint32) { return }
+
+func hashSHA256(ptr, size, salted, digestPtr uint32) (status uint32) { return }
+
+func hashFNV64(ptr, size uint32) uint64 { return 0 }
@@ -0,0 +1,108 @@
+package imports
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+
+	"github.com/otelwasm/otelwasm/guest/internal/mem"
+)
+
+// Status is the status the host functions reporting their failures write to
+// the status pointer the guest passes. The values mirror the host.
+type Status uint32
+
+const (
+	StatusOK Status = iota
+	// StatusBufferTooSmall means the value didn't fit the buffer and wasn't
+	// written. The host returns the size of the value.
+	StatusBufferTooSmall
+	// StatusOutOfMemory means the guest passed a buffer outside its memory.
+	StatusOutOfMemory
+	// StatusNotAllowed means the configuration of the plugin doesn't allow
+	// the operation.
+	StatusNotAllowed
+	// StatusNotFound means the resource doesn't exist.
+	StatusNotFound
+	// StatusIOError means the operation failed on the host side.
+	StatusIOError
+)
+
+var (
+	// ErrBufferTooSmall is the error of StatusBufferTooSmall.
+	ErrBufferTooSmall = errors.New("buffer too small")
+	// ErrOutOfMemory is the error of StatusOutOfMemory.
+	ErrOutOfMemory = errors.New("buffer out of guest memory")
+	// ErrNotAllowed is the error of StatusNotAllowed, matching
+	// fs.ErrPermission.
+	ErrNotAllowed = fmt.Errorf("not allowed by the plugin configuration: %w", fs.ErrPermission)
+	// ErrNotFound is the error of StatusNotFound, matching fs.ErrNotExist.
+	ErrNotFound = fmt.Errorf("host: %w", fs.ErrNotExist)
+	// ErrIO is the error of StatusIOError.
+	ErrIO = errors.New("host I/O error")
+)
+
+// Err returns the error of the status, nil for StatusOK.
+func (s Status) Err() error {
+	switch s {
+	case StatusOK:
+		return nil
+	case StatusBufferTooSmall:
+		return ErrBufferTooSmall
+	case StatusOutOfMemory:
+		return ErrOutOfMemory
+	case StatusNotAllowed:
+		return ErrNotAllowed
+	case StatusNotFound:
+		return ErrNotFound
+	case StatusIOError:
+		return ErrIO
+	default:
+		return fmt.Errorf("unknown host status %d", s)
+	}
+}
+
+// withStatus calls fn with the pointer of the status the host writes, and
+// returns it.
+func withStatus(fn func(statusPtr uint32)) Status {
+	var buf [4]byte
+	ptr, _ := mem.BytesToPtr(buf[:])
+	fn(ptr)
+	runtime.KeepAlive(buf) // until ptr is no longer needed
+	return Status(binary.LittleEndian.Uint32(buf[:]))
+}
+
+// readPluginConfig reads the plugin config into buf and returns its size
+// with the status of the host. It is a variable so tests can fake the host.
+var readPluginConfig = func(buf []byte) (size uint32, status Status) {
+	ptr, limit := mem.BytesToPtr(buf)
+	status = withStatus(func(statusPtr uint32) {
+		size = getPluginConfigStatus(ptr, limit, statusPtr)
+	})
+	runtime.KeepAlive(buf) // until ptr is no longer needed
+	return size, status
+}
+
+// pluginConfigBuf is the buffer the plugin config is first read into, grown
+// to the size the host reports when too small.
+var pluginConfigBuf = make([]byte, 512)
+
+// PluginConfig returns the plugin config in JSON.
+func PluginConfig() ([]byte, error) {
+	for {
+		size, status := readPluginConfig(pluginConfigBuf)
+		switch status {
+		case StatusOK:
+			return pluginConfigBuf[:size], nil
+		case StatusBufferTooSmall:
+			if size <= uint32(len(pluginConfigBuf)) {
+				return nil, fmt.Errorf("getPluginConfig: %w for %d bytes", ErrBufferTooSmall, size)
+			}
+			pluginConfigBuf = make([]byte, size)
+		default:
+			return nil, fmt.Errorf("getPluginConfig: %w", status.Err())
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package imports
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakePluginConfig makes the host pass config, with the given status once
+// it fits the buffer, and returns the number of reads.
+func fakePluginConfig(t *testing.T, config string, status Status) *int {
+	t.Helper()
+	prevRead, prevBuf := readPluginConfig, pluginConfigBuf
+	t.Cleanup(func() { readPluginConfig, pluginConfigBuf = prevRead, prevBuf })
+	pluginConfigBuf = make([]byte, 4)
+	var reads int
+	readPluginConfig = func(buf []byte) (uint32, Status) {
+		reads++
+		if len(config) > len(buf) {
+			return uint32(len(config)), StatusBufferTooSmall
+		}
+		copy(buf, config)
+		return uint32(len(config)), status
+	}
+	return &reads
+}
+
+func TestPluginConfig(t *testing.T) {
+	reads := fakePluginConfig(t, `{"key":"value"}`, StatusOK)
+
+	config, err := PluginConfig()
+	if err != nil {
+		t.Fatalf("failed to read the config: %v", err)
+	}
+	if string(config) != `{"key":"value"}` {
+		t.Errorf("unexpected config %s", config)
+	}
+	if *reads != 2 {
+		t.Errorf("expected the config to be read again in a large enough buffer, got %d reads", *reads)
+	}
+}
+
+func TestPluginConfigOutOfMemory(t *testing.T) {
+	fakePluginConfig(t, `{}`, StatusOutOfMemory)
+
+	if _, err := PluginConfig(); !errors.Is(err, ErrOutOfMemory) {
+		t.Errorf("expected %v, got %v", ErrOutOfMemory, err)
+	}
+}
+
+func TestPluginConfigBufferTooSmall(t *testing.T) {
+	// The host reporting a buffer too small for a value that fits it must
+	// not be read forever.
+	fakePluginConfig(t, `{}`, StatusBufferTooSmall)
+
+	if _, err := PluginConfig(); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("expected %v, got %v", ErrBufferTooSmall, err)
+	}
+}
+
+func TestStatusErr(t *testing.T) {
+	if err := StatusOK.Err(); err != nil {
+		t.Errorf("expected no error for StatusOK, got %v", err)
+	}
+	if err := Status(42).Err(); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+}
@@ -49,9 +49,10 @@ func StringToPtr(s string) (uint32, uint32) {
 // BytesToPtr returns a pointer and size pair for the given byte slice in a way
 // compatible with WebAssembly numeric types.
 // The returned pointer aliases the slice hence it must be kept alive until ptr
-// is no longer needed.
+// is no longer needed. An empty slice, e.g. the encoding of empty telemetry,
+// has a zero size.
 func BytesToPtr(b []byte) (uint32, uint32) {
-	ptr := unsafe.Pointer(&b[0])
+	ptr := unsafe.Pointer(unsafe.SliceData(b))
 	return uint32(uintptr(ptr)), uint32(len(b))
 }
 
@@ -104,6 +105,29 @@ func GetBytes(fn func(ptr uint32, limit BufLimit) (len uint32)) []byte {
 	return readBuf[:size]
 }
 
+// chunkLimit is the maximum size of the frames read by GetChunked.
+const chunkLimit = uint32(64 << 10)
+
+// GetChunked reads a value the host writes frame by frame. fn writes the
+// frame of the value starting at offset, up to limit bytes, and returns the
+// size of the whole value. Values fitting in the read buffer are read with a
+// single call.
+func GetChunked(fn func(offset, ptr uint32, limit BufLimit) (size uint32)) []byte {
+	size := fn(0, uint32(readBufPtr), readBufLimit)
+	if size <= readBufLimit {
+		return readBuf[:size]
+	}
+
+	out := make([]byte, size)
+	copy(out, readBuf)
+	for offset := readBufLimit; offset < size; offset += chunkLimit {
+		frame := out[offset:min(offset+chunkLimit, size)]
+		ptr, limit := BytesToPtr(frame)
+		fn(offset, ptr, limit)
+	}
+	return out
+}
+
 func GetString(fn func(ptr uint32, limit BufLimit) (len uint32)) string {
 	size := fn(uint32(readBufPtr), readBufLimit)
 	if size == 0 {
@@ -27,6 +27,15 @@ import (
 
 var current api.Plugin
 
+// TelemetryTypeProfiles is the flag of the profiles signal in the result of
+// getSupportedTelemetry.
+const TelemetryTypeProfiles uint32 = 1 << 3
+
+// SupportedTelemetry are the flags of the telemetry types registered by the
+// opt-in packages, e.g. profilesprocessor, which the plugin package doesn't
+// import. They are added to those of plugin.Set.
+var SupportedTelemetry uint32
+
 // MustSet sets the plugin once
 func MustSet(plugin api.Plugin) {
 	if !set(plugin) {
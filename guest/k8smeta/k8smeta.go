@@ -0,0 +1,196 @@
+// Package k8smeta enriches telemetry with the Kubernetes metadata of the pods
+// it comes from, out of a metadata map provided by the config rather than
+// the Kubernetes API, which guests can't reach.
+//
+// The pod of a resource is identified by its k8s.pod.uid attribute, or its
+// k8s.namespace.name and k8s.pod.name attributes, and its metadata is set as
+// resource attributes. Records of resources whose pod isn't identified are
+// identified by their own attributes, and their metadata is set on them, as
+// records of a resource may come from different pods.
+package k8smeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/otelwasm/otelwasm/guest/imports"
+	"github.com/otelwasm/otelwasm/guest/internal/datapoints"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// The attributes identifying a pod.
+const (
+	PodUIDAttribute        = "k8s.pod.uid"
+	PodNameAttribute       = "k8s.pod.name"
+	NamespaceNameAttribute = "k8s.namespace.name"
+)
+
+// configFile reads a plugin config file, replaced in tests.
+var configFile = imports.ConfigFile
+
+// Metadata are the attributes of a pod, each named k8s.*, e.g.
+// k8s.deployment.name or k8s.node.name.
+type Metadata map[string]string
+
+// Config is the configuration of the enrichment.
+type Config struct {
+	// Pods maps the pods, by UID or by "<namespace>/<name>", to their
+	// metadata.
+	Pods map[string]Metadata `json:"pods"`
+	// File is the name of a plugin config file holding the pods in the
+	// format of Pods, merged over them. It lets the metadata be updated
+	// without changing the plugin config, e.g. from a mounted ConfigMap.
+	File string `json:"file"`
+	// Override replaces the attributes the telemetry already has. They are
+	// kept by default.
+	Override bool `json:"override"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	for pod, metadata := range c.Pods {
+		if err := metadata.validate(); err != nil {
+			return fmt.Errorf("pod %s: %w", pod, err)
+		}
+	}
+	return nil
+}
+
+func (m Metadata) validate() error {
+	for k := range m {
+		if !strings.HasPrefix(k, "k8s.") {
+			return fmt.Errorf("attribute %s must be named k8s.*", k)
+		}
+	}
+	return nil
+}
+
+// Enricher sets the metadata of the pods on the telemetry.
+type Enricher struct {
+	pods     map[string]Metadata
+	override bool
+}
+
+// New returns an enricher of the given configuration, reading the metadata
+// of the configured file.
+func New(cfg Config) (*Enricher, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	pods := make(map[string]Metadata, len(cfg.Pods))
+	for pod, metadata := range cfg.Pods {
+		pods[pod] = metadata
+	}
+	if cfg.File != "" {
+		content, ok := configFile(cfg.File)
+		if !ok {
+			return nil, fmt.Errorf("config file %s not found", cfg.File)
+		}
+		var filePods map[string]Metadata
+		if err := json.Unmarshal(content, &filePods); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", cfg.File, err)
+		}
+		for pod, metadata := range filePods {
+			if err := metadata.validate(); err != nil {
+				return nil, fmt.Errorf("config file %s: pod %s: %w", cfg.File, pod, err)
+			}
+			pods[pod] = metadata
+		}
+	}
+	return &Enricher{pods: pods, override: cfg.Override}, nil
+}
+
+// lookup returns the metadata of the pod identified by attrs.
+func (e *Enricher) lookup(attrs pcommon.Map) (Metadata, bool) {
+	if uid, ok := attrs.Get(PodUIDAttribute); ok {
+		if metadata, ok := e.pods[uid.AsString()]; ok {
+			return metadata, true
+		}
+	}
+	namespace, ok := attrs.Get(NamespaceNameAttribute)
+	if !ok {
+		return nil, false
+	}
+	name, ok := attrs.Get(PodNameAttribute)
+	if !ok {
+		return nil, false
+	}
+	metadata, ok := e.pods[namespace.AsString()+"/"+name.AsString()]
+	return metadata, ok
+}
+
+// enrich sets the metadata of the pod identified by attrs on them, and
+// reports whether the pod is known.
+func (e *Enricher) enrich(attrs pcommon.Map) bool {
+	metadata, ok := e.lookup(attrs)
+	if !ok {
+		return false
+	}
+	for k, v := range metadata {
+		if _, exists := attrs.Get(k); exists && !e.override {
+			continue
+		}
+		attrs.PutStr(k, v)
+	}
+	return true
+}
+
+// Traces sets the metadata of the pods on td.
+func (e *Enricher) Traces(td ptrace.Traces) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		if e.enrich(rs.Resource().Attributes()) {
+			continue
+		}
+		sss := rs.ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				e.enrich(spans.At(k).Attributes())
+			}
+		}
+	}
+}
+
+// Metrics sets the metadata of the pods on md.
+func (e *Enricher) Metrics(md pmetric.Metrics) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		if e.enrich(rm.Resource().Attributes()) {
+			continue
+		}
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				datapoints.RangeAttributes(metrics.At(k), func(attrs pcommon.Map) {
+					e.enrich(attrs)
+				})
+			}
+		}
+	}
+}
+
+// Logs sets the metadata of the pods on ld.
+func (e *Enricher) Logs(ld plog.Logs) {
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		if e.enrich(rl.Resource().Attributes()) {
+			continue
+		}
+		sls := rl.ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			records := sls.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				e.enrich(records.At(k).Attributes())
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package k8smeta
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+var testConfig = Config{
+	Pods: map[string]Metadata{
+		"shop/frontend-1": {"k8s.deployment.name": "frontend", "k8s.node.name": "node-a"},
+		"0f5e":            {"k8s.deployment.name": "backend", "k8s.node.name": "node-b"},
+	},
+}
+
+func newEnricher(t *testing.T, cfg Config) *Enricher {
+	t.Helper()
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create enricher: %v", err)
+	}
+	return e
+}
+
+func assertAttributes(t *testing.T, attrs pcommon.Map, want map[string]any) {
+	t.Helper()
+	for k, v := range want {
+		got, ok := attrs.Get(k)
+		if v == nil {
+			if ok {
+				t.Errorf("expected no %s, got %s", k, got.AsString())
+			}
+			continue
+		}
+		if !ok || got.AsString() != v {
+			t.Errorf("expected %s=%v, got %v", k, v, attrs.AsRaw())
+		}
+	}
+}
+
+func TestTraces(t *testing.T) {
+	e := newEnricher(t, testConfig)
+
+	td := ptrace.NewTraces()
+	byName := td.ResourceSpans().AppendEmpty()
+	byName.Resource().Attributes().PutStr(NamespaceNameAttribute, "shop")
+	byName.Resource().Attributes().PutStr(PodNameAttribute, "frontend-1")
+	byName.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	byUID := td.ResourceSpans().AppendEmpty()
+	byUID.Resource().Attributes().PutStr(PodUIDAttribute, "0f5e")
+	unknown := td.ResourceSpans().AppendEmpty()
+	unknown.Resource().Attributes().PutStr(NamespaceNameAttribute, "shop")
+	unknown.Resource().Attributes().PutStr(PodNameAttribute, "unknown")
+	e.Traces(td)
+
+	assertAttributes(t, byName.Resource().Attributes(), map[string]any{"k8s.deployment.name": "frontend", "k8s.node.name": "node-a"})
+	assertAttributes(t, byName.ScopeSpans().At(0).Spans().At(0).Attributes(), map[string]any{"k8s.deployment.name": nil})
+	assertAttributes(t, byUID.Resource().Attributes(), map[string]any{"k8s.deployment.name": "backend", "k8s.node.name": "node-b"})
+	assertAttributes(t, unknown.Resource().Attributes(), map[string]any{"k8s.deployment.name": nil})
+}
+
+func TestRecordIdentifiers(t *testing.T) {
+	e := newEnricher(t, testConfig)
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+	first := records.AppendEmpty()
+	first.Attributes().PutStr(PodUIDAttribute, "0f5e")
+	second := records.AppendEmpty()
+	second.Attributes().PutStr(NamespaceNameAttribute, "shop")
+	second.Attributes().PutStr(PodNameAttribute, "frontend-1")
+	e.Logs(ld)
+
+	assertAttributes(t, rl.Resource().Attributes(), map[string]any{"k8s.deployment.name": nil})
+	assertAttributes(t, first.Attributes(), map[string]any{"k8s.deployment.name": "backend"})
+	assertAttributes(t, second.Attributes(), map[string]any{"k8s.deployment.name": "frontend"})
+
+	md := pmetric.NewMetrics()
+	dp := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.Attributes().PutStr(PodUIDAttribute, "0f5e")
+	e.Metrics(md)
+	assertAttributes(t, dp.Attributes(), map[string]any{"k8s.node.name": "node-b"})
+}
+
+func TestOverride(t *testing.T) {
+	for _, override := range []bool{false, true} {
+		cfg := testConfig
+		cfg.Override = override
+		e := newEnricher(t, cfg)
+
+		td := ptrace.NewTraces()
+		attrs := td.ResourceSpans().AppendEmpty().Resource().Attributes()
+		attrs.PutStr(PodUIDAttribute, "0f5e")
+		attrs.PutStr("k8s.node.name", "node-c")
+		e.Traces(td)
+
+		want := "node-c"
+		if override {
+			want = "node-b"
+		}
+		assertAttributes(t, attrs, map[string]any{"k8s.node.name": want, "k8s.deployment.name": "backend"})
+	}
+}
+
+func TestFile(t *testing.T) {
+	orig := configFile
+	t.Cleanup(func() { configFile = orig })
+	configFile = func(name string) ([]byte, bool) {
+		if name != "pods.json" {
+			return nil, false
+		}
+		return []byte(`{"shop/frontend-1": {"k8s.deployment.name": "frontend-v2"}}`), true
+	}
+
+	cfg := testConfig
+	cfg.File = "pods.json"
+	e := newEnricher(t, cfg)
+
+	td := ptrace.NewTraces()
+	attrs := td.ResourceSpans().AppendEmpty().Resource().Attributes()
+	attrs.PutStr(NamespaceNameAttribute, "shop")
+	attrs.PutStr(PodNameAttribute, "frontend-1")
+	e.Traces(td)
+	// The file replaces the metadata of the pods it lists.
+	assertAttributes(t, attrs, map[string]any{"k8s.deployment.name": "frontend-v2", "k8s.node.name": nil})
+
+	cfg.File = "missing.json"
+	if _, err := New(cfg); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	cfg := Config{Pods: map[string]Metadata{"shop/frontend-1": {"deployment": "frontend"}}}
+	if _, err := New(cfg); err == nil {
+		t.Error("expected an error for an attribute not named k8s.*")
+	}
+}
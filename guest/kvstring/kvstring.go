@@ -0,0 +1,120 @@
+// Package kvstring converts attributes to and from a canonical string form,
+// for backends storing flat strings, e.g. as labels or log lines.
+//
+// The form is the key=value pairs of the attributes sorted by key and
+// separated by commas, e.g. "host=a,service=frontend". Backslashes, commas,
+// equal signs and line feeds of keys and values are escaped with a
+// backslash, line feeds as \n, so the form fits on a line. Values are
+// formatted by pcommon.Value.AsString: the attributes parsed back are all
+// strings.
+package kvstring
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// ErrInvalid is returned when parsing a string not in the canonical form.
+var ErrInvalid = errors.New("invalid key=value string")
+
+const (
+	pairSeparator  = ','
+	valueSeparator = '='
+	escape         = '\\'
+)
+
+// Format returns the canonical string form of attrs.
+func Format(attrs pcommon.Map) string {
+	keys := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	slices.Sort(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(pairSeparator)
+		}
+		v, _ := attrs.Get(k)
+		writeEscaped(&b, k)
+		b.WriteByte(valueSeparator)
+		writeEscaped(&b, v.AsString())
+	}
+	return b.String()
+}
+
+func writeEscaped(b *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case escape, pairSeparator, valueSeparator:
+			b.WriteByte(escape)
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// Parse parses the canonical string form s into string attributes. The
+// empty string parses into no attributes.
+func Parse(s string) (pcommon.Map, error) {
+	attrs := pcommon.NewMap()
+	if s == "" {
+		return attrs, nil
+	}
+
+	var key, token strings.Builder
+	inValue := false
+	end := func() error {
+		if !inValue {
+			return fmt.Errorf("%w: pair %q has no value", ErrInvalid, token.String())
+		}
+		attrs.PutStr(key.String(), token.String())
+		key.Reset()
+		token.Reset()
+		inValue = false
+		return nil
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case escape:
+			i++
+			if i == len(s) {
+				return pcommon.Map{}, fmt.Errorf("%w: trailing escape", ErrInvalid)
+			}
+			switch e := s[i]; e {
+			case escape, pairSeparator, valueSeparator:
+				token.WriteByte(e)
+			case 'n':
+				token.WriteByte('\n')
+			default:
+				return pcommon.Map{}, fmt.Errorf("%w: unknown escape \\%c", ErrInvalid, e)
+			}
+		case valueSeparator:
+			if inValue {
+				return pcommon.Map{}, fmt.Errorf("%w: unescaped %c in the value of %q", ErrInvalid, c, key.String())
+			}
+			key.WriteString(token.String())
+			token.Reset()
+			inValue = true
+		case pairSeparator:
+			if err := end(); err != nil {
+				return pcommon.Map{}, err
+			}
+		default:
+			token.WriteByte(c)
+		}
+	}
+	if err := end(); err != nil {
+		return pcommon.Map{}, err
+	}
+	return attrs, nil
+}
@@ -0,0 +1,66 @@
+package kvstring
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestFormat(t *testing.T) {
+	attrs := pcommon.NewMap()
+	attrs.PutStr("service", "frontend")
+	attrs.PutInt("code", 200)
+	attrs.PutBool("ok", true)
+	attrs.PutStr("a=b", "x,y\\z\nw")
+
+	want := `a\=b=x\,y\\z\nw,code=200,ok=true,service=frontend`
+	if got := Format(attrs); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []map[string]any{
+		{},
+		{"service": "frontend"},
+		{"service": "frontend", "host": "a"},
+		{"": ""},
+		{"key": ""},
+		{"a,b": "c=d", "e\\": "\\f", "g": "line\nbreak", "h": `\n`},
+		{"url": "http://example.com/?a=1,b=2", "empty": ""},
+	}
+	for _, raw := range tests {
+		attrs := pcommon.NewMap()
+		if err := attrs.FromRaw(raw); err != nil {
+			t.Fatalf("failed to build attributes: %v", err)
+		}
+		s := Format(attrs)
+		parsed, err := Parse(s)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", s, err)
+		}
+		if got := parsed.AsRaw(); !reflect.DeepEqual(got, raw) {
+			t.Errorf("expected %v after a round trip through %q, got %v", raw, s, got)
+		}
+		if again := Format(parsed); again != s {
+			t.Errorf("expected the form %q to be stable, got %q", s, again)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"novalue",
+		"a=1,novalue",
+		"a=1=2",
+		"a=1\\",
+		"a=\\x",
+		"a=1,",
+	} {
+		if _, err := Parse(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %v for %q, got %v", ErrInvalid, s, err)
+		}
+	}
+}
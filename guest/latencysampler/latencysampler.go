@@ -0,0 +1,100 @@
+// Package latencysampler samples traces based on their latency, a tail-based
+// heuristic applied to each batch: slow traces are always kept while fast
+// ones are sampled at a configured rate.
+//
+// A trace is slow if any of its spans in the batch lasts at least the
+// threshold. The sampling decision of fast traces is derived from their trace
+// ID, so it is consistent across batches and collectors.
+package latencysampler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Config is the configuration of the sampler.
+type Config struct {
+	// ThresholdMs is the span duration, in milliseconds, from which a trace is
+	// considered slow.
+	ThresholdMs int64 `json:"threshold_ms"`
+	// SamplingRate is the ratio, between 0 and 1, of fast traces to keep.
+	SamplingRate float64 `json:"sampling_rate"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.ThresholdMs < 0 {
+		return fmt.Errorf("threshold_ms must not be negative")
+	}
+	if c.SamplingRate < 0 || c.SamplingRate > 1 {
+		return fmt.Errorf("sampling_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// Threshold returns the threshold as a duration.
+func (c *Config) Threshold() time.Duration {
+	return time.Duration(c.ThresholdMs) * time.Millisecond
+}
+
+// Traces drops the spans of the fast traces of td not sampled, and reports
+// whether td was mutated.
+func Traces(td ptrace.Traces, cfg Config) (mutated bool) {
+	slow := slowTraces(td, cfg.Threshold())
+	keep := func(span ptrace.Span) bool {
+		return slow[span.TraceID()] || Sampled(span.TraceID(), cfg.SamplingRate)
+	}
+
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				if keep(span) {
+					return false
+				}
+				mutated = true
+				return true
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return mutated
+}
+
+// slowTraces returns the IDs of the traces having a span lasting at least
+// threshold.
+func slowTraces(td ptrace.Traces, threshold time.Duration) map[pcommon.TraceID]bool {
+	slow := make(map[pcommon.TraceID]bool)
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				if span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime()) >= threshold {
+					slow[span.TraceID()] = true
+				}
+			}
+		}
+	}
+	return slow
+}
+
+// Sampled reports whether the trace of the given ID is sampled at rate. The
+// decision is based on the last 8 bytes of the ID, which are random for W3C
+// trace context IDs.
+func Sampled(id pcommon.TraceID, rate float64) bool {
+	switch {
+	case rate <= 0:
+		return false
+	case rate >= 1:
+		return true
+	}
+	return binary.BigEndian.Uint64(id[8:]) < uint64(rate*math.MaxUint64)
+}
@@ -0,0 +1,105 @@
+package latencysampler
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func traceID(r *rand.Rand) pcommon.TraceID {
+	var id pcommon.TraceID
+	binary.BigEndian.PutUint64(id[:8], r.Uint64())
+	binary.BigEndian.PutUint64(id[8:], r.Uint64())
+	return id
+}
+
+// appendTrace appends a trace made of a root span and a child span lasting
+// the given duration.
+func appendTrace(spans ptrace.SpanSlice, id pcommon.TraceID, child time.Duration) {
+	start := time.Unix(0, 0)
+	for _, d := range []time.Duration{time.Millisecond, child} {
+		span := spans.AppendEmpty()
+		span.SetTraceID(id)
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(d)))
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "valid", cfg: Config{ThresholdMs: 100, SamplingRate: 0.1}},
+		{name: "negative threshold", cfg: Config{ThresholdMs: -1}, wantErr: true},
+		{name: "rate above one", cfg: Config{SamplingRate: 1.5}, wantErr: true},
+		{name: "negative rate", cfg: Config{SamplingRate: -0.5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSlowTracesAlwaysKept(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for i := 0; i < 100; i++ {
+		appendTrace(spans, traceID(r), time.Second)
+	}
+
+	if Traces(td, Config{ThresholdMs: 500, SamplingRate: 0}) {
+		t.Error("expected traces not to be mutated")
+	}
+	// Both spans of each slow trace are kept, including the fast root span.
+	if td.SpanCount() != 200 {
+		t.Errorf("expected 200 spans, got %d", td.SpanCount())
+	}
+}
+
+func TestFastTracesSampled(t *testing.T) {
+	const traces = 10000
+	for _, rate := range []float64{0, 0.1, 0.5, 1} {
+		r := rand.New(rand.NewPCG(1, 2))
+		td := ptrace.NewTraces()
+		spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+		for i := 0; i < traces; i++ {
+			appendTrace(spans, traceID(r), 10*time.Millisecond)
+		}
+
+		Traces(td, Config{ThresholdMs: 500, SamplingRate: rate})
+
+		kept := float64(td.SpanCount()) / 2
+		if math.Abs(kept/traces-rate) > 0.02 {
+			t.Errorf("rate %v: expected about %v traces kept, got %v", rate, rate*traces, kept)
+		}
+		if td.SpanCount()%2 != 0 {
+			t.Errorf("rate %v: expected traces to be kept or dropped as a whole", rate)
+		}
+		if rate == 0 && td.ResourceSpans().Len() != 0 {
+			t.Errorf("expected empty resource spans to be removed")
+		}
+	}
+}
+
+func TestSampledConsistent(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	for i := 0; i < 1000; i++ {
+		id := traceID(r)
+		// A trace sampled at some rate is sampled at any higher rate.
+		if Sampled(id, 0.2) && !Sampled(id, 0.3) {
+			t.Fatalf("trace %v sampled at 0.2 but not at 0.3", id)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package logging logs the messages of guests with the logger of the host,
+// so they end up in the collector logs with the other components.
+//
+// The minimum level of the host logger is read when the core is created, and
+// messages below it are dropped by the guest, before their fields are
+// encoded and passed to the host.
+//
+// Fields are typed, e.g. zap.Int("spans", n), and integers reach the host as
+// integers, so there is no need to format them in the guest.
+package logging
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// loggerField is the field holding the name of a named logger.
+const loggerField = "logger"
+
+// NewLogger returns a logger logging with the logger of the host, e.g. for
+// the telemetry settings of the wrapped component.
+func NewLogger(opts ...zap.Option) *zap.Logger {
+	return zap.New(NewCore(), opts...)
+}
+
+// NewCore returns a core logging with the logger of the host.
+func NewCore() zapcore.Core {
+	return newCore(zapcore.Level(imports.LogLevel()), imports.LogMessage)
+}
+
+// core passes the entries at or above level to write, with their fields
+// encoded as a JSON object.
+type core struct {
+	level  zapcore.Level
+	fields []zapcore.Field
+	write  func(level int32, msg string, fields []byte)
+}
+
+func newCore(level zapcore.Level, write func(level int32, msg string, fields []byte)) *core {
+	return &core{level: level, write: write}
+}
+
+func (c *core) Enabled(level zapcore.Level) bool {
+	return level >= c.level
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{
+		level:  c.level,
+		fields: append(slices.Clip(c.fields), fields...),
+		write:  c.write,
+	}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	if ent.LoggerName != "" {
+		enc.AddString(loggerField, ent.LoggerName)
+	}
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	var b []byte
+	if len(enc.Fields) > 0 {
+		var err error
+		if b, err = json.Marshal(enc.Fields); err != nil {
+			return err
+		}
+	}
+	c.write(int32(ent.Level), ent.Message, b)
+	return nil
+}
+
+func (c *core) Sync() error {
+	return nil
+}
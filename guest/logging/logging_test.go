@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// message is a message passed to the host.
+type message struct {
+	level  zapcore.Level
+	msg    string
+	fields string
+}
+
+// newTestLogger returns a logger for a host logging at level, and the
+// messages it passed to the host.
+func newTestLogger(level zapcore.Level) (*zap.Logger, *[]message) {
+	var messages []message
+	logger := zap.New(newCore(level, func(level int32, msg string, fields []byte) {
+		messages = append(messages, message{level: zapcore.Level(level), msg: msg, fields: string(fields)})
+	}))
+	return logger, &messages
+}
+
+func TestDropsBelowHostLevel(t *testing.T) {
+	logger, messages := newTestLogger(zapcore.InfoLevel)
+
+	logger.Debug("dropped", zap.String("user", "alice"))
+	logger.Info("kept")
+	logger.Warn("kept", zap.Int("attempt", 2))
+
+	want := []message{
+		{level: zapcore.InfoLevel, msg: "kept"},
+		{level: zapcore.WarnLevel, msg: "kept", fields: `{"attempt":2}`},
+	}
+	if len(*messages) != len(want) {
+		t.Fatalf("expected %d messages, got %d: %v", len(want), len(*messages), *messages)
+	}
+	for i, m := range want {
+		if (*messages)[i] != m {
+			t.Errorf("message %d: expected %v, got %v", i, m, (*messages)[i])
+		}
+	}
+}
+
+func TestHostLogsNothing(t *testing.T) {
+	logger, messages := newTestLogger(zapcore.InvalidLevel)
+
+	logger.Error("dropped")
+	if len(*messages) != 0 {
+		t.Errorf("expected no message, got %v", *messages)
+	}
+}
+
+func TestFields(t *testing.T) {
+	logger, messages := newTestLogger(zapcore.DebugLevel)
+
+	logger.Named("webhook").With(zap.String("tenant", "acme")).Debug("request", zap.Bool("retried", true))
+	want := `{"logger":"webhook","retried":true,"tenant":"acme"}`
+	if len(*messages) != 1 || (*messages)[0].fields != want {
+		t.Errorf("expected fields %s, got %v", want, *messages)
+	}
+}
+
+func TestIntegerFields(t *testing.T) {
+	logger, messages := newTestLogger(zapcore.DebugLevel)
+	logger.Info("batch", zap.Int("spans", 12), zap.Int64("trace_count", 9007199254740993), zap.Ints("sizes", []int{1, 2}))
+	want := `{"sizes":[1,2],"spans":12,"trace_count":9007199254740993}`
+	if len(*messages) != 1 || (*messages)[0].fields != want {
+		t.Errorf("expected fields %s, got %v", want, *messages)
+	}
+}
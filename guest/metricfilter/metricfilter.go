@@ -0,0 +1,142 @@
+// Package metricfilter drops metrics by name, keeping the metrics matching an
+// allowlist and dropping those matching a denylist. Scopes and resources
+// left without metrics are removed.
+//
+// Patterns are globs, where "*" matches any sequence of characters and "?"
+// any single character, or regular expressions. Either way a pattern must
+// match the whole name.
+package metricfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// MatchType is the syntax of the patterns.
+type MatchType string
+
+const (
+	// MatchTypeGlob matches names against glob patterns.
+	MatchTypeGlob MatchType = "glob"
+	// MatchTypeRegexp matches names against regular expressions.
+	MatchTypeRegexp MatchType = "regexp"
+)
+
+// Config is the configuration of the filter.
+type Config struct {
+	// MatchType is the syntax of the patterns. Defaults to MatchTypeGlob.
+	MatchType MatchType `json:"match_type"`
+	// Include is the allowlist. Metrics not matching any of its patterns are
+	// dropped. All metrics are allowed if empty.
+	Include []string `json:"include"`
+	// Exclude is the denylist. Metrics matching any of its patterns are
+	// dropped, even if allowed by Include.
+	Exclude []string `json:"exclude"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	_, err := New(*c)
+	return err
+}
+
+// Filter is a compiled Config.
+type Filter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// New compiles the patterns of cfg.
+func New(cfg Config) (*Filter, error) {
+	var compile func(string) (*regexp.Regexp, error)
+	switch cfg.MatchType {
+	case "", MatchTypeGlob:
+		compile = compileGlob
+	case MatchTypeRegexp:
+		compile = func(pattern string) (*regexp.Regexp, error) {
+			return regexp.Compile("^(?:" + pattern + ")$")
+		}
+	default:
+		return nil, fmt.Errorf("invalid match_type: %s", cfg.MatchType)
+	}
+
+	f := &Filter{}
+	var err error
+	if f.include, err = compileAll(cfg.Include, compile); err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	if f.exclude, err = compileAll(cfg.Exclude, compile); err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+	return f, nil
+}
+
+func compileAll(patterns []string, compile func(string) (*regexp.Regexp, error)) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// compileGlob compiles a glob pattern into a regular expression matching the
+// whole name.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func matchAny(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Keep reports whether the metric name passes the filter.
+func (f *Filter) Keep(name string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	return !matchAny(f.exclude, name)
+}
+
+// Metrics drops the metrics of md not passing the filter, along with the
+// scopes and resources left empty, and reports whether md was mutated.
+func (f *Filter) Metrics(md pmetric.Metrics) (mutated bool) {
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(m pmetric.Metric) bool {
+				if f.Keep(m.Name()) {
+					return false
+				}
+				mutated = true
+				return true
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return mutated
+}
@@ -0,0 +1,158 @@
+package metricfilter
+
+import (
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// newMetrics returns metrics with a resource per group of names, each with
+// a single scope.
+func newMetrics(groups ...[]string) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	for _, names := range groups {
+		metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+		for _, name := range names {
+			m := metrics.AppendEmpty()
+			m.SetName(name)
+			m.SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(1)
+		}
+	}
+	return md
+}
+
+func names(md pmetric.Metrics) []string {
+	var res []string
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		sms := md.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				res = append(res, ms.At(k).Name())
+			}
+		}
+	}
+	return res
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "empty", cfg: Config{}},
+		{name: "glob", cfg: Config{Include: []string{"http.*"}, Exclude: []string{"http.server.?"}}},
+		{name: "regexp", cfg: Config{MatchType: MatchTypeRegexp, Include: []string{`http\..+`}}},
+		{name: "invalid regexp", cfg: Config{MatchType: MatchTypeRegexp, Exclude: []string{"("}}, wantErr: true},
+		{name: "invalid match type", cfg: Config{MatchType: "strict"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	all := []string{"http.server.duration", "http.client.duration", "process.cpu.time", "runtime.go.gc"}
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		want        []string
+		wantMutated bool
+	}{
+		{
+			name: "no lists",
+			want: all,
+		},
+		{
+			name:        "allow glob",
+			cfg:         Config{Include: []string{"http.*"}},
+			want:        []string{"http.server.duration", "http.client.duration"},
+			wantMutated: true,
+		},
+		{
+			name:        "deny glob",
+			cfg:         Config{Exclude: []string{"*.duration"}},
+			want:        []string{"process.cpu.time", "runtime.go.gc"},
+			wantMutated: true,
+		},
+		{
+			name:        "deny overrides allow",
+			cfg:         Config{Include: []string{"http.*"}, Exclude: []string{"http.client.*"}},
+			want:        []string{"http.server.duration"},
+			wantMutated: true,
+		},
+		{
+			name:        "glob matches whole names",
+			cfg:         Config{Include: []string{"http"}},
+			wantMutated: true,
+		},
+		{
+			name:        "allow regexp",
+			cfg:         Config{MatchType: MatchTypeRegexp, Include: []string{`(process|runtime)\..*`}},
+			want:        []string{"process.cpu.time", "runtime.go.gc"},
+			wantMutated: true,
+		},
+		{
+			name:        "deny regexp",
+			cfg:         Config{MatchType: MatchTypeRegexp, Exclude: []string{`.*\.(time|gc)`}},
+			want:        []string{"http.server.duration", "http.client.duration"},
+			wantMutated: true,
+		},
+		{
+			name: "nothing matched by denylist",
+			cfg:  Config{Exclude: []string{"db.*"}},
+			want: all,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("failed to create filter: %v", err)
+			}
+			md := newMetrics(all)
+			if mutated := f.Metrics(md); mutated != tt.wantMutated {
+				t.Errorf("expected mutated %v, got %v", tt.wantMutated, mutated)
+			}
+			if got := names(md); !slices.Equal(got, tt.want) {
+				t.Errorf("expected metrics %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMetricsRemovesEmptiedParents(t *testing.T) {
+	f, err := New(Config{Exclude: []string{"runtime.*"}})
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+	md := newMetrics(
+		[]string{"http.server.duration", "runtime.go.gc"},
+		[]string{"runtime.go.goroutines", "runtime.go.mem.heap"},
+	)
+	// A second scope left empty under the first resource.
+	scope := md.ResourceMetrics().At(0).ScopeMetrics().AppendEmpty()
+	scope.Metrics().AppendEmpty().SetName("runtime.uptime")
+
+	if !f.Metrics(md) {
+		t.Error("expected md to be mutated")
+	}
+	if got := md.ResourceMetrics().Len(); got != 1 {
+		t.Fatalf("expected the emptied resource to be removed, got %d resources", got)
+	}
+	if got := md.ResourceMetrics().At(0).ScopeMetrics().Len(); got != 1 {
+		t.Errorf("expected the emptied scope to be removed, got %d scopes", got)
+	}
+	if got := names(md); !slices.Equal(got, []string{"http.server.duration"}) {
+		t.Errorf("expected the allowed metric to be kept, got %v", got)
+	}
+}
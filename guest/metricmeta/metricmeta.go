@@ -0,0 +1,52 @@
+// Package metricmeta applies metric metadata, descriptions and units, from
+// the guest configuration to the metrics a guest emits.
+package metricmeta
+
+import "go.opentelemetry.io/collector/pdata/pmetric"
+
+// Metadata is the metadata of a metric. Empty fields leave the metric
+// untouched.
+type Metadata struct {
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+}
+
+// Config maps metric names to their metadata.
+type Config map[string]Metadata
+
+// Apply sets the metadata configured for each metric of md and reports
+// whether md was mutated.
+func Apply(md pmetric.Metrics, cfg Config) (mutated bool) {
+	if len(cfg) == 0 {
+		return false
+	}
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				if applyMetric(ms.At(k), cfg) {
+					mutated = true
+				}
+			}
+		}
+	}
+	return mutated
+}
+
+func applyMetric(m pmetric.Metric, cfg Config) (mutated bool) {
+	meta, ok := cfg[m.Name()]
+	if !ok {
+		return false
+	}
+	if meta.Description != "" && meta.Description != m.Description() {
+		m.SetDescription(meta.Description)
+		mutated = true
+	}
+	if meta.Unit != "" && meta.Unit != m.Unit() {
+		m.SetUnit(meta.Unit)
+		mutated = true
+	}
+	return mutated
+}
@@ -0,0 +1,60 @@
+package metricmeta
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func newMetrics(names ...string) (pmetric.Metrics, pmetric.MetricSlice) {
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	for _, name := range names {
+		ms.AppendEmpty().SetName(name)
+	}
+	return md, ms
+}
+
+func TestApply(t *testing.T) {
+	var cfg Config
+	raw := `{
+		"http.requests": {"description": "Number of HTTP requests.", "unit": "{request}"},
+		"cpu.usage": {"unit": "%"}
+	}`
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatalf("failed to unmarshal config: %v", err)
+	}
+
+	md, ms := newMetrics("http.requests", "cpu.usage", "memory.usage")
+	ms.At(1).SetDescription("CPU usage.")
+
+	if !Apply(md, cfg) {
+		t.Error("expected metrics to be mutated")
+	}
+
+	want := []Metadata{
+		{Description: "Number of HTTP requests.", Unit: "{request}"},
+		// Fields not configured are left untouched.
+		{Description: "CPU usage.", Unit: "%"},
+		{},
+	}
+	for i, w := range want {
+		m := ms.At(i)
+		if m.Description() != w.Description || m.Unit() != w.Unit {
+			t.Errorf("%s: expected %+v, got {Description:%s Unit:%s}", m.Name(), w, m.Description(), m.Unit())
+		}
+	}
+}
+
+func TestApplyUnchanged(t *testing.T) {
+	md, ms := newMetrics("http.requests", "memory.usage")
+	ms.At(0).SetUnit("{request}")
+
+	if Apply(md, Config{"http.requests": {Unit: "{request}"}}) {
+		t.Error("expected metrics not to be mutated when metadata already match")
+	}
+	if Apply(md, nil) {
+		t.Error("expected metrics not to be mutated without config")
+	}
+}
@@ -0,0 +1,42 @@
+// Package metrics records metrics of the guest in the telemetry of the
+// collector, e.g. the number of spans a processor dropped, so operators
+// monitor the guest like any other component.
+//
+// The values are aggregated by the host, with the meter of the component.
+// Attributes are strings, numbers or booleans.
+package metrics
+
+import (
+	"encoding/json"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// The kinds of metrics, as passed to the host.
+const (
+	kindCounter uint32 = iota
+	kindGauge
+)
+
+// AddInt64 adds value to the counter name. Counters are monotonic, so value
+// must not be negative.
+func AddInt64(name string, value int64, attrs map[string]any) error {
+	return record(name, kindCounter, value, attrs)
+}
+
+// SetInt64 sets the gauge name to value.
+func SetInt64(name string, value int64, attrs map[string]any) error {
+	return record(name, kindGauge, value, attrs)
+}
+
+func record(name string, kind uint32, value int64, attrs map[string]any) error {
+	var rawAttrs []byte
+	if len(attrs) > 0 {
+		var err error
+		if rawAttrs, err = json.Marshal(attrs); err != nil {
+			return err
+		}
+	}
+	imports.RecordMetric(name, kind, value, rawAttrs)
+	return nil
+}
@@ -26,8 +26,8 @@ var _ func() uint32 = _processMetrics
 func _processMetrics() uint32 {
 	metrics := imports.CurrentMetrics()
 	result, status := metricsprocessor.ProcessMetrics(metrics)
-	// If the result is not empty, set it in the host.
-	// In case of empty result, the result should be written inside the guest call.
+	// The zero result sets none, either because it was set during the call
+	// or to have the host pass its metrics on unchanged.
 	if result != (pmetric.Metrics{}) {
 		pubimports.SetResultMetrics(result)
 	}
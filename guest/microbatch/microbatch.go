@@ -0,0 +1,153 @@
+// Package microbatch buffers the telemetry of many small incoming requests,
+// e.g. webhook calls, and emits it as one batch to reduce the overhead of the
+// next consumer.
+//
+// A batch is flushed once it holds MaxSize records, or once MaxDelay elapsed
+// since its first record was added, as measured by the host clock. Guests
+// call Tick periodically so idle batches are flushed as well.
+package microbatch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/clock"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// DefaultMaxSize is the batch size used if none is configured.
+	DefaultMaxSize = 512
+	// DefaultMaxDelay is the batch delay used if none is configured.
+	DefaultMaxDelay = time.Second
+)
+
+// Config is the configuration of the batching.
+type Config struct {
+	// MaxSize is the number of records, i.e. log records, spans or metric
+	// data points, flushing the batch. Defaults to DefaultMaxSize.
+	MaxSize int `json:"max_size"`
+	// MaxDelay is the time records may wait in the batch. Defaults to
+	// DefaultMaxDelay.
+	MaxDelay time.Duration `json:"max_delay"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.MaxSize < 0 {
+		return fmt.Errorf("max_size must not be negative")
+	}
+	if c.MaxDelay < 0 {
+		return fmt.Errorf("max_delay must not be negative")
+	}
+	return nil
+}
+
+// Batcher buffers telemetry of type T and passes it to its flush function in
+// batches. It is safe for concurrent use.
+type Batcher[T any] struct {
+	maxSize  int
+	maxDelay time.Duration
+	newBatch func() T
+	count    func(T) int
+	moveTo   func(src, dst T)
+	flush    func(T)
+	// now reads the monotonic clock.
+	now func() time.Duration
+
+	mu    sync.Mutex
+	batch T
+	size  int
+	start time.Duration
+}
+
+func newBatcher[T any](cfg Config, newBatch func() T, count func(T) int, moveTo func(src, dst T), flush func(T)) (*Batcher[T], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = DefaultMaxSize
+	}
+	if cfg.MaxDelay == 0 {
+		cfg.MaxDelay = DefaultMaxDelay
+	}
+	return &Batcher[T]{
+		maxSize:  cfg.MaxSize,
+		maxDelay: cfg.MaxDelay,
+		newBatch: newBatch,
+		count:    count,
+		moveTo:   moveTo,
+		flush:    flush,
+		now:      clock.Monotonic,
+		batch:    newBatch(),
+	}, nil
+}
+
+// NewLogs returns a batcher of logs, counting log records.
+func NewLogs(cfg Config, flush func(plog.Logs)) (*Batcher[plog.Logs], error) {
+	return newBatcher(cfg, plog.NewLogs, plog.Logs.LogRecordCount, func(src, dst plog.Logs) {
+		src.ResourceLogs().MoveAndAppendTo(dst.ResourceLogs())
+	}, flush)
+}
+
+// NewTraces returns a batcher of traces, counting spans.
+func NewTraces(cfg Config, flush func(ptrace.Traces)) (*Batcher[ptrace.Traces], error) {
+	return newBatcher(cfg, ptrace.NewTraces, ptrace.Traces.SpanCount, func(src, dst ptrace.Traces) {
+		src.ResourceSpans().MoveAndAppendTo(dst.ResourceSpans())
+	}, flush)
+}
+
+// NewMetrics returns a batcher of metrics, counting data points.
+func NewMetrics(cfg Config, flush func(pmetric.Metrics)) (*Batcher[pmetric.Metrics], error) {
+	return newBatcher(cfg, pmetric.NewMetrics, pmetric.Metrics.DataPointCount, func(src, dst pmetric.Metrics) {
+		src.ResourceMetrics().MoveAndAppendTo(dst.ResourceMetrics())
+	}, flush)
+}
+
+// Add moves the records of data to the batch, and flushes the batch if it is
+// full or its delay elapsed. data must not be used afterwards.
+func (b *Batcher[T]) Add(data T) {
+	n := b.count(data)
+	if n == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size == 0 {
+		b.start = b.now()
+	}
+	b.moveTo(data, b.batch)
+	b.size += n
+	if b.size >= b.maxSize || b.now()-b.start >= b.maxDelay {
+		b.flushLocked()
+	}
+}
+
+// Tick flushes the batch if its delay elapsed.
+func (b *Batcher[T]) Tick() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.size > 0 && b.now()-b.start >= b.maxDelay {
+		b.flushLocked()
+	}
+}
+
+// Flush flushes the batch if it isn't empty, e.g. on shutdown.
+func (b *Batcher[T]) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.size > 0 {
+		b.flushLocked()
+	}
+}
+
+func (b *Batcher[T]) flushLocked() {
+	batch := b.batch
+	b.batch = b.newBatch()
+	b.size = 0
+	b.flush(batch)
+}
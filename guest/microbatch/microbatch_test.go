@@ -0,0 +1,110 @@
+package microbatch
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// newLogs returns logs with n log records.
+func newLogs(n int) plog.Logs {
+	ld := plog.NewLogs()
+	lrs := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for range n {
+		lrs.AppendEmpty()
+	}
+	return ld
+}
+
+// newTestBatcher returns a batcher of logs reading the fake monotonic clock
+// now, and the record counts of the flushed batches.
+func newTestBatcher(t *testing.T, cfg Config, now *time.Duration) (*Batcher[plog.Logs], *[]int) {
+	t.Helper()
+	var flushed []int
+	b, err := NewLogs(cfg, func(ld plog.Logs) {
+		flushed = append(flushed, ld.LogRecordCount())
+	})
+	if err != nil {
+		t.Fatalf("failed to create batcher: %v", err)
+	}
+	b.now = func() time.Duration { return *now }
+	return b, &flushed
+}
+
+func TestFlushBySize(t *testing.T) {
+	var now time.Duration
+	b, flushed := newTestBatcher(t, Config{MaxSize: 5, MaxDelay: time.Hour}, &now)
+
+	b.Add(newLogs(2))
+	b.Add(newLogs(2))
+	if len(*flushed) != 0 {
+		t.Fatalf("expected no flush under the size, got %v", *flushed)
+	}
+	b.Add(newLogs(3))
+	b.Add(newLogs(1))
+	if len(*flushed) != 1 || (*flushed)[0] != 7 {
+		t.Fatalf("expected a batch of 7 records, got %v", *flushed)
+	}
+
+	b.Flush()
+	if len(*flushed) != 2 || (*flushed)[1] != 1 {
+		t.Errorf("expected the remaining record to be flushed, got %v", *flushed)
+	}
+}
+
+func TestFlushByTime(t *testing.T) {
+	now := 10 * time.Second
+	b, flushed := newTestBatcher(t, Config{MaxSize: 100, MaxDelay: time.Second}, &now)
+
+	b.Add(newLogs(1))
+	now += 500 * time.Millisecond
+	b.Add(newLogs(1))
+	b.Tick()
+	if len(*flushed) != 0 {
+		t.Fatalf("expected no flush before the delay, got %v", *flushed)
+	}
+
+	now += 500 * time.Millisecond
+	b.Tick()
+	if len(*flushed) != 1 || (*flushed)[0] != 2 {
+		t.Fatalf("expected a batch of 2 records, got %v", *flushed)
+	}
+
+	// The delay of the next batch starts with its first record.
+	now += 10 * time.Second
+	b.Add(newLogs(1))
+	b.Tick()
+	if len(*flushed) != 1 {
+		t.Errorf("expected the new batch not to be flushed, got %v", *flushed)
+	}
+	now += time.Second
+	b.Add(newLogs(1))
+	if len(*flushed) != 2 || (*flushed)[1] != 2 {
+		t.Errorf("expected adding past the delay to flush, got %v", *flushed)
+	}
+}
+
+func TestEmptyBatchIsNotFlushed(t *testing.T) {
+	var now time.Duration
+	b, flushed := newTestBatcher(t, Config{}, &now)
+
+	b.Add(plog.NewLogs())
+	now += time.Hour
+	b.Tick()
+	b.Flush()
+	if len(*flushed) != 0 {
+		t.Errorf("expected no flush, got %v", *flushed)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"negative size":  {MaxSize: -1},
+		"negative delay": {MaxDelay: -time.Second},
+	} {
+		if _, err := NewLogs(cfg, func(plog.Logs) {}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+// Package openmetrics converts OpenMetrics exposition text into metrics, so
+// receiver guests scraping OpenMetrics endpoints can emit OTLP.
+//
+// Besides the samples, the OpenMetrics specific features are converted:
+// units declared with "# UNIT" become metric units, "_created" samples become
+// start timestamps, and exemplars of counters and histogram buckets become
+// exemplars of the data points, with the "trace_id" and "span_id" labels
+// becoming their trace and span IDs.
+//
+// Counters become cumulative monotonic sums, gauges, unknown, info and
+// stateset metrics become gauges, histograms become cumulative histograms and
+// summaries become summaries. Gauge histograms are not supported.
+package openmetrics
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otelwasm/otelwasm/guest/internal/identity"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// Metric types declared by "# TYPE" lines.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+	TypeSummary   = "summary"
+	TypeInfo      = "info"
+	TypeStateSet  = "stateset"
+	TypeUnknown   = "unknown"
+)
+
+// Parse converts OpenMetrics exposition text into metrics. Samples without
+// timestamp are timestamped with now. Parsing stops at the "# EOF" line.
+func Parse(text string, now pcommon.Timestamp) (pmetric.Metrics, error) {
+	p := &parser{now: now}
+	for i, line := range strings.Split(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if line == "# EOF" {
+			break
+		}
+		if err := p.parseLine(line); err != nil {
+			return pmetric.Metrics{}, fmt.Errorf("openmetrics: line %d: %w", i+1, err)
+		}
+	}
+	return p.metrics(), nil
+}
+
+type label struct {
+	name, value string
+}
+
+type exemplar struct {
+	labels    []label
+	value     float64
+	timestamp pcommon.Timestamp
+}
+
+type bucket struct {
+	le       float64
+	count    float64
+	exemplar *exemplar
+}
+
+type quantile struct {
+	quantile, value float64
+}
+
+// point accumulates the samples of a data point, which share the labels of
+// the point except "le" and "quantile".
+type point struct {
+	labels    []label
+	timestamp pcommon.Timestamp
+	created   pcommon.Timestamp
+	value     float64
+	count     float64
+	sum       float64
+	buckets   []bucket
+	quantiles []quantile
+	exemplars []exemplar
+}
+
+type family struct {
+	name, typ, unit, help string
+	points                []*point
+	index                 map[string]*point
+}
+
+type parser struct {
+	now      pcommon.Timestamp
+	families []*family
+	current  *family
+}
+
+func (p *parser) family(name string) *family {
+	if p.current == nil || p.current.name != name {
+		p.current = &family{name: name, typ: TypeUnknown, index: make(map[string]*point)}
+		p.families = append(p.families, p.current)
+	}
+	return p.current
+}
+
+func (p *parser) parseLine(line string) error {
+	if strings.HasPrefix(line, "# ") {
+		return p.parseMetadata(line[2:])
+	}
+	return p.parseSample(line)
+}
+
+func (p *parser) parseMetadata(line string) error {
+	keyword, rest, _ := strings.Cut(line, " ")
+	name, value, _ := strings.Cut(rest, " ")
+	switch keyword {
+	case "TYPE":
+		switch value {
+		case TypeCounter, TypeGauge, TypeHistogram, TypeSummary, TypeInfo, TypeStateSet, TypeUnknown:
+		default:
+			return fmt.Errorf("unsupported metric type: %s", value)
+		}
+		p.family(name).typ = value
+	case "UNIT":
+		p.family(name).unit = value
+	case "HELP":
+		p.family(name).help = unescape(value)
+	default:
+		// Other comments are ignored.
+	}
+	return nil
+}
+
+// suffixes returns the sample name suffixes of the given metric type.
+func suffixes(typ string) []string {
+	switch typ {
+	case TypeCounter:
+		return []string{"_total", "_created"}
+	case TypeHistogram:
+		return []string{"_bucket", "_count", "_sum", "_created"}
+	case TypeSummary:
+		return []string{"", "_count", "_sum", "_created"}
+	case TypeInfo:
+		return []string{"_info"}
+	default:
+		return []string{""}
+	}
+}
+
+func (p *parser) parseSample(line string) error {
+	sample, rawExemplar, hasExemplar := strings.Cut(line, " # ")
+
+	nameEnd := strings.IndexAny(sample, "{ ")
+	if nameEnd <= 0 {
+		return fmt.Errorf("invalid sample: %s", line)
+	}
+	name, rest := sample[:nameEnd], sample[nameEnd:]
+
+	var labels []label
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		if labels, rest, err = parseLabels(rest); err != nil {
+			return err
+		}
+	}
+
+	value, timestamp, err := parseValueAndTimestamp(rest)
+	if err != nil {
+		return err
+	}
+	rawValue := strings.Fields(rest)[0]
+	// Attach the sample to the current family if its name matches one of the
+	// family sample names, or to a new unknown family otherwise.
+	f := p.current
+	suffix, ok := "", false
+	if f != nil {
+		for _, s := range suffixes(f.typ) {
+			if name == f.name+s {
+				suffix, ok = s, true
+				break
+			}
+		}
+	}
+	if !ok {
+		f = p.family(name)
+	}
+
+	var le, q string
+	pointLabels := slices.DeleteFunc(slices.Clone(labels), func(l label) bool {
+		switch {
+		case f.typ == TypeHistogram && l.name == "le":
+			le = l.value
+			return true
+		case f.typ == TypeSummary && l.name == "quantile":
+			q = l.value
+			return true
+		}
+		return false
+	})
+	pt := f.point(pointLabels, timestamp)
+
+	var ex *exemplar
+	if hasExemplar {
+		if ex, err = parseExemplar(rawExemplar); err != nil {
+			return err
+		}
+	}
+
+	switch suffix {
+	case "_created":
+		if pt.created, err = parseTimestamp(rawValue); err != nil {
+			return fmt.Errorf("invalid created timestamp: %q", rawValue)
+		}
+	case "_count":
+		pt.count = value
+	case "_sum":
+		pt.sum = value
+	case "_bucket":
+		bound, err := parseFloat(le)
+		if err != nil {
+			return fmt.Errorf("invalid le label: %q", le)
+		}
+		pt.buckets = append(pt.buckets, bucket{le: bound, count: value, exemplar: ex})
+		return nil
+	default:
+		if f.typ == TypeSummary {
+			quant, err := parseFloat(q)
+			if err != nil {
+				return fmt.Errorf("invalid quantile label: %q", q)
+			}
+			pt.quantiles = append(pt.quantiles, quantile{quantile: quant, value: value})
+			return nil
+		}
+		pt.value = value
+	}
+	if ex != nil {
+		pt.exemplars = append(pt.exemplars, *ex)
+	}
+	return nil
+}
+
+func (f *family) point(labels []label, timestamp pcommon.Timestamp) *point {
+	attrs := pcommon.NewMap()
+	for _, l := range labels {
+		attrs.PutStr(l.name, l.value)
+	}
+	key := identity.MapKey(attrs)
+	pt, ok := f.index[key]
+	if !ok {
+		pt = &point{labels: labels}
+		f.index[key] = pt
+		f.points = append(f.points, pt)
+	}
+	if timestamp != 0 {
+		pt.timestamp = timestamp
+	}
+	return pt
+}
+
+// parseLabels parses the "{...}" label set at the start of s and returns the
+// rest of s.
+func parseLabels(s string) ([]label, string, error) {
+	var labels []label
+	s = s[1:]
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok || !strings.HasPrefix(rest, `"`) {
+			return nil, "", fmt.Errorf("invalid label set: %s", s)
+		}
+		value, rest, err := parseQuoted(rest)
+		if err != nil {
+			return nil, "", err
+		}
+		labels = append(labels, label{name: strings.TrimSpace(name), value: value})
+		s = rest
+	}
+}
+
+// parseQuoted parses the quoted string at the start of s and returns the
+// rest of s.
+func parseQuoted(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i++; i < len(s) && s[i] == 'n' {
+				b.WriteByte('\n')
+			} else if i < len(s) {
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated label value: %s", s)
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\"`, `"`).Replace(s)
+}
+
+func parseValueAndTimestamp(s string) (float64, pcommon.Timestamp, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, 0, fmt.Errorf("invalid sample value: %q", s)
+	}
+	value, err := parseFloat(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid sample value: %q", fields[0])
+	}
+	if len(fields) == 1 {
+		return value, 0, nil
+	}
+	ts, err := parseTimestamp(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid timestamp: %q", fields[1])
+	}
+	return value, ts, nil
+}
+
+// parseTimestamp parses a timestamp in seconds. Decimal timestamps are parsed
+// exactly, down to the nanosecond.
+func parseTimestamp(s string) (pcommon.Timestamp, error) {
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		return pcommon.Timestamp(f * 1e9), err
+	}
+	sec, frac, _ := strings.Cut(s, ".")
+	secs, err := strconv.ParseUint(sec, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if len(frac) > 9 {
+		frac = frac[:9]
+	}
+	var nanos uint64
+	if frac != "" {
+		if nanos, err = strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	return pcommon.Timestamp(secs*1e9 + nanos), nil
+}
+
+func parseExemplar(s string) (*exemplar, error) {
+	if !strings.HasPrefix(s, "{") {
+		return nil, fmt.Errorf("invalid exemplar: %s", s)
+	}
+	labels, rest, err := parseLabels(s)
+	if err != nil {
+		return nil, err
+	}
+	value, ts, err := parseValueAndTimestamp(rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exemplar: %w", err)
+	}
+	return &exemplar{labels: labels, value: value, timestamp: ts}, nil
+}
+
+func parseFloat(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func (p *parser) metrics() pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	for _, f := range p.families {
+		if len(f.points) == 0 {
+			continue
+		}
+		for _, pt := range f.points {
+			if pt.timestamp == 0 {
+				pt.timestamp = p.now
+			}
+		}
+		m := ms.AppendEmpty()
+		m.SetName(f.name)
+		m.SetUnit(f.unit)
+		m.SetDescription(f.help)
+		switch f.typ {
+		case TypeCounter:
+			sum := m.SetEmptySum()
+			sum.SetIsMonotonic(true)
+			sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			for _, pt := range f.points {
+				dp := sum.DataPoints().AppendEmpty()
+				pt.fill(dp)
+				dp.SetDoubleValue(pt.value)
+				for _, ex := range pt.exemplars {
+					ex.appendTo(dp.Exemplars())
+				}
+			}
+		case TypeHistogram:
+			h := m.SetEmptyHistogram()
+			h.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			for _, pt := range f.points {
+				pt.appendHistogram(h.DataPoints())
+			}
+		case TypeSummary:
+			s := m.SetEmptySummary()
+			for _, pt := range f.points {
+				dp := s.DataPoints().AppendEmpty()
+				pt.fill(dp)
+				dp.SetCount(uint64(pt.count))
+				dp.SetSum(pt.sum)
+				for _, q := range pt.quantiles {
+					qv := dp.QuantileValues().AppendEmpty()
+					qv.SetQuantile(q.quantile)
+					qv.SetValue(q.value)
+				}
+			}
+		default:
+			g := m.SetEmptyGauge()
+			for _, pt := range f.points {
+				dp := g.DataPoints().AppendEmpty()
+				pt.fill(dp)
+				dp.SetDoubleValue(pt.value)
+			}
+		}
+	}
+	return md
+}
+
+// fill sets the attributes and timestamps of dp from pt.
+func (pt *point) fill(dp interface {
+	Attributes() pcommon.Map
+	SetTimestamp(pcommon.Timestamp)
+	SetStartTimestamp(pcommon.Timestamp)
+},
+) {
+	for _, l := range pt.labels {
+		dp.Attributes().PutStr(l.name, l.value)
+	}
+	dp.SetTimestamp(pt.timestamp)
+	dp.SetStartTimestamp(pt.created)
+}
+
+func (pt *point) appendHistogram(dps pmetric.HistogramDataPointSlice) {
+	dp := dps.AppendEmpty()
+	pt.fill(dp)
+	dp.SetCount(uint64(pt.count))
+	dp.SetSum(pt.sum)
+
+	// OpenMetrics buckets are cumulative while OTLP ones aren't, and the
+	// +Inf bucket is implicit in OTLP.
+	sort.Slice(pt.buckets, func(i, j int) bool { return pt.buckets[i].le < pt.buckets[j].le })
+	var previous float64
+	for _, b := range pt.buckets {
+		if !math.IsInf(b.le, 1) {
+			dp.ExplicitBounds().Append(b.le)
+		}
+		dp.BucketCounts().Append(uint64(b.count - previous))
+		previous = b.count
+		if b.exemplar != nil {
+			b.exemplar.appendTo(dp.Exemplars())
+		}
+	}
+}
+
+func (ex *exemplar) appendTo(exemplars pmetric.ExemplarSlice) {
+	e := exemplars.AppendEmpty()
+	e.SetDoubleValue(ex.value)
+	e.SetTimestamp(ex.timestamp)
+	for _, l := range ex.labels {
+		switch l.name {
+		case "trace_id":
+			var id pcommon.TraceID
+			if b, err := hex.DecodeString(l.value); err == nil && len(b) == len(id) {
+				copy(id[:], b)
+				e.SetTraceID(id)
+				continue
+			}
+		case "span_id":
+			var id pcommon.SpanID
+			if b, err := hex.DecodeString(l.value); err == nil && len(b) == len(id) {
+				copy(id[:], b)
+				e.SetSpanID(id)
+				continue
+			}
+		}
+		e.FilteredAttributes().PutStr(l.name, l.value)
+	}
+}
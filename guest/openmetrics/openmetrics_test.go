@@ -0,0 +1,177 @@
+package openmetrics
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const now = pcommon.Timestamp(42)
+
+const sample = `# TYPE http_requests counter
+# UNIT http_requests requests
+# HELP http_requests Number of \"HTTP\" requests.
+http_requests_total{code="200"} 1027 1520879607.789 # {trace_id="0102030405060708090a0b0c0d0e0f10",span_id="0102030405060708"} 1 1520879607.5
+http_requests_created{code="200"} 1520870000
+http_requests_total{code="500"} 3
+# TYPE temperature gauge
+# UNIT temperature celsius
+temperature{room="kitchen"} 21.5
+# TYPE latency histogram
+# UNIT latency seconds
+latency_bucket{le="0.1"} 8 # {user="alice"} 0.05
+latency_bucket{le="1"} 10
+latency_bucket{le="+Inf"} 11
+latency_count 11
+latency_sum 4.5
+latency_created 1520870000.5
+# TYPE rpc_duration summary
+rpc_duration{quantile="0.5"} 0.2
+rpc_duration{quantile="0.99"} 0.9
+rpc_duration_count 20
+rpc_duration_sum 5
+untyped_thing 7
+# EOF
+ignored_after_eof 1
+`
+
+func parse(t *testing.T) map[string]pmetric.Metric {
+	t.Helper()
+	md, err := Parse(sample, now)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	metrics := make(map[string]pmetric.Metric)
+	ms := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < ms.Len(); i++ {
+		metrics[ms.At(i).Name()] = ms.At(i)
+	}
+	if len(metrics) != 5 {
+		t.Errorf("expected 5 metrics, got %d", len(metrics))
+	}
+	return metrics
+}
+
+func TestCounter(t *testing.T) {
+	m := parse(t)["http_requests"]
+	if m.Type() != pmetric.MetricTypeSum || !m.Sum().IsMonotonic() {
+		t.Fatalf("expected monotonic sum, got %v", m.Type())
+	}
+	if m.Unit() != "requests" {
+		t.Errorf("expected unit requests, got %q", m.Unit())
+	}
+	if m.Description() != `Number of "HTTP" requests.` {
+		t.Errorf("unexpected description %q", m.Description())
+	}
+
+	dps := m.Sum().DataPoints()
+	if dps.Len() != 2 {
+		t.Fatalf("expected 2 data points, got %d", dps.Len())
+	}
+	dp := dps.At(0)
+	if dp.DoubleValue() != 1027 {
+		t.Errorf("expected value 1027, got %v", dp.DoubleValue())
+	}
+	if dp.Timestamp() != pcommon.Timestamp(1520879607_789000000) {
+		t.Errorf("unexpected timestamp %v", dp.Timestamp())
+	}
+	if dp.StartTimestamp() != pcommon.Timestamp(1520870000_000000000) {
+		t.Errorf("expected created timestamp as start timestamp, got %v", dp.StartTimestamp())
+	}
+
+	if dp.Exemplars().Len() != 1 {
+		t.Fatalf("expected 1 exemplar, got %d", dp.Exemplars().Len())
+	}
+	ex := dp.Exemplars().At(0)
+	wantTraceID := pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	if ex.TraceID() != wantTraceID || ex.SpanID() != (pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("unexpected exemplar trace and span IDs: %v %v", ex.TraceID(), ex.SpanID())
+	}
+	if ex.DoubleValue() != 1 || ex.Timestamp() != pcommon.Timestamp(1520879607_500000000) {
+		t.Errorf("unexpected exemplar value and timestamp: %v %v", ex.DoubleValue(), ex.Timestamp())
+	}
+	if ex.FilteredAttributes().Len() != 0 {
+		t.Errorf("expected no filtered attributes, got %v", ex.FilteredAttributes().AsRaw())
+	}
+
+	if got := dps.At(1).Timestamp(); got != now {
+		t.Errorf("expected sample without timestamp to use now, got %v", got)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	metrics := parse(t)
+	for name, unit := range map[string]string{"temperature": "celsius", "untyped_thing": ""} {
+		m := metrics[name]
+		if m.Type() != pmetric.MetricTypeGauge {
+			t.Errorf("%s: expected gauge, got %v", name, m.Type())
+			continue
+		}
+		if m.Unit() != unit {
+			t.Errorf("%s: expected unit %q, got %q", name, unit, m.Unit())
+		}
+	}
+	if v, _ := metrics["temperature"].Gauge().DataPoints().At(0).Attributes().Get("room"); v.Str() != "kitchen" {
+		t.Errorf("expected room attribute kitchen, got %q", v.Str())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	m := parse(t)["latency"]
+	if m.Type() != pmetric.MetricTypeHistogram {
+		t.Fatalf("expected histogram, got %v", m.Type())
+	}
+	dp := m.Histogram().DataPoints().At(0)
+	if dp.Count() != 11 || dp.Sum() != 4.5 {
+		t.Errorf("expected count 11 and sum 4.5, got %d and %v", dp.Count(), dp.Sum())
+	}
+	if got := dp.ExplicitBounds().AsRaw(); len(got) != 2 || got[0] != 0.1 || got[1] != 1 {
+		t.Errorf("expected bounds [0.1 1], got %v", got)
+	}
+	if got := dp.BucketCounts().AsRaw(); len(got) != 3 || got[0] != 8 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("expected bucket counts [8 2 1], got %v", got)
+	}
+	if dp.StartTimestamp() != pcommon.Timestamp(1520870000_500000000) {
+		t.Errorf("expected created timestamp as start timestamp, got %v", dp.StartTimestamp())
+	}
+	if dp.Attributes().Len() != 0 {
+		t.Errorf("expected le label not to be an attribute, got %v", dp.Attributes().AsRaw())
+	}
+
+	if dp.Exemplars().Len() != 1 {
+		t.Fatalf("expected 1 exemplar, got %d", dp.Exemplars().Len())
+	}
+	ex := dp.Exemplars().At(0)
+	if v, _ := ex.FilteredAttributes().Get("user"); v.Str() != "alice" || ex.DoubleValue() != 0.05 {
+		t.Errorf("unexpected exemplar %v %v", ex.FilteredAttributes().AsRaw(), ex.DoubleValue())
+	}
+}
+
+func TestSummary(t *testing.T) {
+	m := parse(t)["rpc_duration"]
+	if m.Type() != pmetric.MetricTypeSummary {
+		t.Fatalf("expected summary, got %v", m.Type())
+	}
+	dp := m.Summary().DataPoints().At(0)
+	if dp.Count() != 20 || dp.Sum() != 5 {
+		t.Errorf("expected count 20 and sum 5, got %d and %v", dp.Count(), dp.Sum())
+	}
+	if dp.QuantileValues().Len() != 2 || dp.QuantileValues().At(1).Quantile() != 0.99 || dp.QuantileValues().At(1).Value() != 0.9 {
+		t.Errorf("unexpected quantiles")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"# TYPE foo gaugehistogram\n",
+		"foo{bar=\"baz} 1\n",
+		"foo one\n",
+		"foo 1 2 3\n",
+		"foo_total 1 # 1\n",
+	} {
+		if _, err := Parse(text, now); err == nil {
+			t.Errorf("expected an error parsing %q", text)
+		}
+	}
+}
@@ -1,5 +1,13 @@
 package plugin
 
+import (
+	"context"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+	internalplugin "github.com/otelwasm/otelwasm/guest/internal/plugin"
+)
+
 type TelemetryType uint32
 
 const (
@@ -15,5 +23,86 @@ var _ func() uint32 = _getSupportedTelemetry
 
 //go:wasmexport getSupportedTelemetry
 func _getSupportedTelemetry() uint32 {
-	return uint32(supportedTelemetry)
+	return uint32(supportedTelemetry) | internalplugin.SupportedTelemetry
+}
+
+// abiVersion is the version of the host ABI the SDK is built against. The
+// host refuses guests of versions it doesn't support, rather than failing
+// to link them.
+const abiVersion uint32 = 4
+
+var _ func() uint32 = _abiVersion
+
+//go:wasmexport otelwasm_abi_version
+func _abiVersion() uint32 {
+	return abiVersion
+}
+
+// capabilityMutatesData is the flag of api.Capabilities.MutatesData in the
+// result of getCapabilities.
+const capabilityMutatesData uint32 = 1 << 0
+
+// capabilityCompressedLogs tells the host the SDK decompresses the logs it
+// compresses, whatever the plugin.
+const capabilityCompressedLogs uint32 = 1 << 1
+
+// capabilityTracesJSON is the flag of api.Capabilities.TracesJSON in the
+// result of getCapabilities.
+const capabilityTracesJSON uint32 = 1 << 2
+
+// capabilities are the capabilities declared by the plugin.
+var capabilities = api.Capabilities{MutatesData: true}
+
+var _ func() uint32 = _getCapabilities
+
+//go:wasmexport getCapabilities
+func _getCapabilities() uint32 {
+	flags := capabilityCompressedLogs
+	if capabilities.MutatesData {
+		flags |= capabilityMutatesData
+	}
+	if capabilities.TracesJSON {
+		flags |= capabilityTracesJSON
+	}
+	return flags
+}
+
+// starter is the plugin setting up its resources on start, if any.
+var starter api.Starter
+
+var _ func() uint32 = _start
+
+//go:wasmexport start
+func _start() uint32 {
+	if starter == nil {
+		return imports.StatusToCode(nil)
+	}
+	return imports.StatusToCode(starter.Start(context.Background()))
+}
+
+// shutdowner is the plugin flushing its buffered data on shutdown, if any.
+var shutdowner api.Shutdowner
+
+var _ func() uint32 = _shutdown
+
+//go:wasmexport shutdown
+func _shutdown() uint32 {
+	if shutdowner == nil {
+		return imports.StatusToCode(nil)
+	}
+	return imports.StatusToCode(shutdowner.Shutdown(context.Background()))
+}
+
+// reconfigurer is the plugin reading its config again once the host updates
+// it, if any.
+var reconfigurer api.Reconfigurer
+
+var _ func() uint32 = _reconfigure
+
+//go:wasmexport reconfigure
+func _reconfigure() uint32 {
+	if reconfigurer == nil {
+		return imports.StatusToCode(nil)
+	}
+	return imports.StatusToCode(reconfigurer.Reconfigure(context.Background()))
 }
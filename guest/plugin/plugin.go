@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"github.com/otelwasm/otelwasm/guest/api"
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
 	"github.com/otelwasm/otelwasm/guest/logsexporter"
 	"github.com/otelwasm/otelwasm/guest/logsprocessor"
 	"github.com/otelwasm/otelwasm/guest/logsreceiver"
@@ -11,6 +12,7 @@ import (
 	"github.com/otelwasm/otelwasm/guest/tracesexporter"
 	"github.com/otelwasm/otelwasm/guest/tracesprocessor"
 	"github.com/otelwasm/otelwasm/guest/tracesreceiver"
+	"github.com/otelwasm/otelwasm/guest/tracestometricsconnector"
 )
 
 func Set(plugin api.Plugin) {
@@ -50,6 +52,23 @@ func Set(plugin api.Plugin) {
 		tracesreceiver.SetPlugin(plugin)
 		supportedTelemetry |= telemetryTypeTraces
 	}
+	if plugin, ok := plugin.(api.TracesToMetricsConnector); ok {
+		tracestometricsconnector.SetPlugin(plugin)
+		supportedTelemetry |= telemetryTypeTraces
+	}
+	if plugin, ok := plugin.(api.CapabilitiesDeclarer); ok {
+		capabilities = plugin.Capabilities()
+		imports.SetTracesJSON(capabilities.TracesJSON)
+	}
+	if plugin, ok := plugin.(api.Starter); ok {
+		starter = plugin
+	}
+	if plugin, ok := plugin.(api.Shutdowner); ok {
+		shutdowner = plugin
+	}
+	if plugin, ok := plugin.(api.Reconfigurer); ok {
+		reconfigurer = plugin
+	}
 
 	// TODO: panic of return error
 }
@@ -0,0 +1,51 @@
+// Package profilesprocessor registers the profiles processors. It is opt-in,
+// as plugin.Set doesn't import it: the guests not processing profiles don't
+// import the profiles host functions, which hosts predating them lack.
+package profilesprocessor
+
+import (
+	"runtime"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	pubimports "github.com/otelwasm/otelwasm/guest/imports"
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+	internalplugin "github.com/otelwasm/otelwasm/guest/internal/plugin"
+	"github.com/otelwasm/otelwasm/guest/plugin"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+var profilesprocessor api.ProfilesProcessor
+
+func SetPlugin(pp api.ProfilesProcessor) {
+	if pp == nil {
+		panic("nil ProfilesProcessor")
+	}
+	profilesprocessor = pp
+	internalplugin.MustSet(pp)
+}
+
+// Set registers the plugin like plugin.Set, and as the profiles processor if
+// it implements api.ProfilesProcessor. Guests processing profiles call it
+// instead of plugin.Set.
+func Set(p api.Plugin) {
+	plugin.Set(p)
+	if p, ok := p.(api.ProfilesProcessor); ok {
+		SetPlugin(p)
+		internalplugin.SupportedTelemetry |= internalplugin.TelemetryTypeProfiles
+	}
+}
+
+var _ func() uint32 = _processProfiles
+
+//go:wasmexport processProfiles
+func _processProfiles() uint32 {
+	profiles := imports.CurrentProfiles()
+	result, status := profilesprocessor.ProcessProfiles(profiles)
+	// The zero result sets none, to have the host pass its profiles on
+	// unchanged.
+	if result != (pprofile.Profiles{}) {
+		pubimports.SetResultProfiles(result)
+	}
+	runtime.KeepAlive(result) // until ptr is no longer needed
+	return imports.StatusToCode(status)
+}
@@ -0,0 +1,36 @@
+// Package rand draws random numbers from the host, e.g. for sampling
+// decisions. The host draws them from a cryptographically secure source by
+// default, or from a pseudo-random generator seeded with the rand_seed
+// option, so the decisions are the same in tests and replays, which the
+// WASI random source of the guest runtime can't guarantee.
+//
+// The host serves them with the randUint64 host function, so guests using
+// the package don't run on hosts predating it.
+package rand
+
+import (
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// randUint64 draws a number from the host, replaced in tests.
+var randUint64 = imports.RandUint64
+
+// Uint64 returns a random 64-bit number.
+func Uint64() uint64 {
+	return randUint64()
+}
+
+// Float64 returns a random number in [0.0, 1.0).
+func Float64() float64 {
+	// The 53 most significant bits fill the mantissa, as in math/rand/v2.
+	return float64(randUint64()>>11) / (1 << 53)
+}
+
+// Source is the math/rand/v2 source of the numbers drawn from the host, e.g.
+// for rand.New(Source{}).IntN(n).
+type Source struct{}
+
+// Uint64 implements rand.Source.
+func (Source) Uint64() uint64 {
+	return randUint64()
+}
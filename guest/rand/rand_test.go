@@ -0,0 +1,49 @@
+package rand
+
+import (
+	"math"
+	mathrand "math/rand/v2"
+	"testing"
+)
+
+func fakeHost(t *testing.T, numbers ...uint64) {
+	t.Helper()
+	prev := randUint64
+	t.Cleanup(func() { randUint64 = prev })
+	randUint64 = func() uint64 {
+		n := numbers[0]
+		numbers = numbers[1:]
+		return n
+	}
+}
+
+func TestUint64(t *testing.T) {
+	fakeHost(t, 42, 7)
+	if got := Uint64(); got != 42 {
+		t.Errorf("expected 42, got %d", got)
+	}
+	if got := (Source{}).Uint64(); got != 7 {
+		t.Errorf("expected 7, got %d", got)
+	}
+}
+
+func TestFloat64(t *testing.T) {
+	fakeHost(t, 0, math.MaxUint64, 1<<63)
+	for _, want := range []float64{0, 1 - 1.0/(1<<53), 0.5} {
+		got := Float64()
+		if got != want {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if got < 0 || got >= 1 {
+			t.Errorf("expected a number in [0, 1), got %v", got)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	fakeHost(t, 3<<60, 3<<60, 3<<60)
+	var _ mathrand.Source = Source{}
+	if got := mathrand.New(Source{}).IntN(4); got < 0 || got >= 4 {
+		t.Errorf("expected a number in [0, 4), got %d", got)
+	}
+}
@@ -0,0 +1,105 @@
+// Package require enforces mandatory resource attributes, so guests can rely
+// on e.g. service.name being set downstream.
+//
+// Resources missing a required attribute get its default value, or are
+// dropped along with their records.
+package require
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Action is what happens to a resource missing a required attribute.
+type Action string
+
+const (
+	// ActionDefault sets the attribute to its default value.
+	ActionDefault Action = "default"
+	// ActionDrop removes the resource and its records.
+	ActionDrop Action = "drop"
+)
+
+// Attribute is a required resource attribute.
+type Attribute struct {
+	// Key is the key of the attribute.
+	Key string `json:"key"`
+	// Default is the value set by ActionDefault.
+	Default string `json:"default"`
+	// Action is applied to resources missing the attribute. Defaults to
+	// ActionDefault.
+	Action Action `json:"action"`
+}
+
+// Config is the configuration of the required attributes.
+type Config struct {
+	Attributes []Attribute `json:"attributes"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	for _, a := range c.Attributes {
+		if a.Key == "" {
+			return fmt.Errorf("attribute key is required")
+		}
+		switch a.Action {
+		case "", ActionDefault, ActionDrop:
+		default:
+			return fmt.Errorf("invalid action for attribute %s: %s", a.Key, a.Action)
+		}
+	}
+	return nil
+}
+
+// enforce applies the configuration to the attributes of a resource and
+// reports whether the resource must be removed and whether it was mutated.
+func (c *Config) enforce(resource pcommon.Map) (remove, mutated bool) {
+	for _, a := range c.Attributes {
+		if _, ok := resource.Get(a.Key); ok {
+			continue
+		}
+		if a.Action == ActionDrop {
+			return true, true
+		}
+		resource.PutStr(a.Key, a.Default)
+		mutated = true
+	}
+	return false, mutated
+}
+
+// Traces enforces the required attributes on the resources of td and reports
+// whether td was mutated.
+func Traces(td ptrace.Traces, cfg Config) (mutated bool) {
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		remove, m := cfg.enforce(rs.Resource().Attributes())
+		mutated = mutated || m
+		return remove
+	})
+	return mutated
+}
+
+// Metrics enforces the required attributes on the resources of md and
+// reports whether md was mutated.
+func Metrics(md pmetric.Metrics, cfg Config) (mutated bool) {
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		remove, m := cfg.enforce(rm.Resource().Attributes())
+		mutated = mutated || m
+		return remove
+	})
+	return mutated
+}
+
+// Logs enforces the required attributes on the resources of ld and reports
+// whether ld was mutated.
+func Logs(ld plog.Logs, cfg Config) (mutated bool) {
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		remove, m := cfg.enforce(rl.Resource().Attributes())
+		mutated = mutated || m
+		return remove
+	})
+	return mutated
+}
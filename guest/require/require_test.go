@@ -0,0 +1,102 @@
+package require
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// newTraces returns traces with one resource per service. An empty service
+// means the resource doesn't carry service.name.
+func newTraces(services ...string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	for _, service := range services {
+		rs := td.ResourceSpans().AppendEmpty()
+		if service != "" {
+			rs.Resource().Attributes().PutStr("service.name", service)
+		}
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	}
+	return td
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "valid", cfg: Config{Attributes: []Attribute{{Key: "service.name", Action: ActionDrop}}}},
+		{name: "missing key", cfg: Config{Attributes: []Attribute{{Default: "unknown"}}}, wantErr: true},
+		{name: "invalid action", cfg: Config{Attributes: []Attribute{{Key: "service.name", Action: "flag"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPresent(t *testing.T) {
+	td := newTraces("checkout")
+	cfg := Config{Attributes: []Attribute{{Key: "service.name", Default: "unknown"}}}
+
+	if Traces(td, cfg) {
+		t.Error("expected traces not to be mutated")
+	}
+	if v, _ := td.ResourceSpans().At(0).Resource().Attributes().Get("service.name"); v.Str() != "checkout" {
+		t.Errorf("expected service.name to be kept, got %q", v.Str())
+	}
+}
+
+func TestMissingWithDefault(t *testing.T) {
+	td := newTraces("checkout", "")
+	cfg := Config{Attributes: []Attribute{
+		{Key: "service.name", Default: "unknown"},
+		{Key: "deployment.environment", Default: "production", Action: ActionDefault},
+	}}
+
+	if !Traces(td, cfg) {
+		t.Error("expected traces to be mutated")
+	}
+	want := []map[string]any{
+		{"service.name": "checkout", "deployment.environment": "production"},
+		{"service.name": "unknown", "deployment.environment": "production"},
+	}
+	for i, attrs := range want {
+		got := td.ResourceSpans().At(i).Resource().Attributes().AsRaw()
+		for k, v := range attrs {
+			if got[k] != v {
+				t.Errorf("resource %d: expected %s=%v, got %v", i, k, v, got[k])
+			}
+		}
+	}
+}
+
+func TestMissingDrop(t *testing.T) {
+	cfg := Config{Attributes: []Attribute{{Key: "service.name", Action: ActionDrop}}}
+
+	td := newTraces("checkout", "", "cart")
+	if !Traces(td, cfg) {
+		t.Error("expected traces to be mutated")
+	}
+	if td.ResourceSpans().Len() != 2 {
+		t.Errorf("expected 2 resources left, got %d", td.ResourceSpans().Len())
+	}
+
+	md := pmetric.NewMetrics()
+	md.ResourceMetrics().AppendEmpty()
+	if !Metrics(md, cfg) || md.ResourceMetrics().Len() != 0 {
+		t.Errorf("expected the resource metrics to be dropped, got %d", md.ResourceMetrics().Len())
+	}
+
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().Resource().Attributes().PutStr("service.name", "checkout")
+	if Logs(ld, cfg) || ld.ResourceLogs().Len() != 1 {
+		t.Errorf("expected the resource logs to be kept, got %d", ld.ResourceLogs().Len())
+	}
+}
@@ -0,0 +1,174 @@
+// Package resourcebuild builds structured telemetry out of flat records, as
+// ingested by receivers of formats without a notion of resource. The
+// attributes of a record named by the resource keys are moved to its
+// resource, and records with equal resource attributes are grouped under the
+// same resource.
+package resourcebuild
+
+import (
+	"github.com/otelwasm/otelwasm/guest/internal/identity"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// splitter splits flat attributes into the resource and record ones.
+type splitter struct {
+	keys map[string]struct{}
+	// index maps the identity of the resource attributes to the position of
+	// the resource in the built telemetry.
+	index map[string]int
+}
+
+func newSplitter(keys []string) splitter {
+	s := splitter{keys: make(map[string]struct{}, len(keys)), index: make(map[string]int)}
+	for _, k := range keys {
+		s.keys[k] = struct{}{}
+	}
+	return s
+}
+
+// split copies the attributes of flat named by the resource keys to
+// resource, and the others to record. flat isn't modified.
+func (s splitter) split(flat, resource, record pcommon.Map) {
+	flat.Range(func(k string, v pcommon.Value) bool {
+		if _, ok := s.keys[k]; ok {
+			v.CopyTo(resource.PutEmpty(k))
+		} else {
+			v.CopyTo(record.PutEmpty(k))
+		}
+		return true
+	})
+}
+
+// group returns the position of the resource of the given attributes, and
+// whether the resource must be appended at that position.
+func (s splitter) group(resource pcommon.Map) (i int, added bool) {
+	key := identity.MapKey(resource)
+	if i, ok := s.index[key]; ok {
+		return i, false
+	}
+	i = len(s.index)
+	s.index[key] = i
+	return i, true
+}
+
+// Traces builds traces out of flat spans.
+type Traces struct {
+	splitter
+	td ptrace.Traces
+}
+
+// NewTraces returns a builder moving the attributes named by keys to the
+// resource of the spans.
+func NewTraces(keys ...string) *Traces {
+	return &Traces{splitter: newSplitter(keys), td: ptrace.NewTraces()}
+}
+
+// AppendSpan appends an empty span to the resource identified by the
+// resource attributes of attrs, and sets the other attributes on the span.
+func (b *Traces) AppendSpan(attrs pcommon.Map) ptrace.Span {
+	resource := pcommon.NewMap()
+	span := ptrace.NewSpan()
+	b.split(attrs, resource, span.Attributes())
+
+	i, added := b.group(resource)
+	if added {
+		rs := b.td.ResourceSpans().AppendEmpty()
+		resource.MoveTo(rs.Resource().Attributes())
+		rs.ScopeSpans().AppendEmpty()
+	}
+	dest := b.td.ResourceSpans().At(i).ScopeSpans().At(0).Spans().AppendEmpty()
+	span.MoveTo(dest)
+	return dest
+}
+
+// Traces returns the traces built so far, resources in the order they were
+// first seen.
+func (b *Traces) Traces() ptrace.Traces {
+	return b.td
+}
+
+// Logs builds logs out of flat log records.
+type Logs struct {
+	splitter
+	ld plog.Logs
+}
+
+// NewLogs returns a builder moving the attributes named by keys to the
+// resource of the log records.
+func NewLogs(keys ...string) *Logs {
+	return &Logs{splitter: newSplitter(keys), ld: plog.NewLogs()}
+}
+
+// AppendLogRecord appends an empty log record to the resource identified by
+// the resource attributes of attrs, and sets the other attributes on the
+// record.
+func (b *Logs) AppendLogRecord(attrs pcommon.Map) plog.LogRecord {
+	resource := pcommon.NewMap()
+	lr := plog.NewLogRecord()
+	b.split(attrs, resource, lr.Attributes())
+
+	i, added := b.group(resource)
+	if added {
+		rl := b.ld.ResourceLogs().AppendEmpty()
+		resource.MoveTo(rl.Resource().Attributes())
+		rl.ScopeLogs().AppendEmpty()
+	}
+	dest := b.ld.ResourceLogs().At(i).ScopeLogs().At(0).LogRecords().AppendEmpty()
+	lr.MoveTo(dest)
+	return dest
+}
+
+// Logs returns the logs built so far, resources in the order they were
+// first seen.
+func (b *Logs) Logs() plog.Logs {
+	return b.ld
+}
+
+// Metrics builds metrics out of flat data points.
+type Metrics struct {
+	splitter
+	md pmetric.Metrics
+	// metrics maps the metric names to their position, per resource.
+	metrics []map[string]int
+}
+
+// NewMetrics returns a builder moving the attributes named by keys to the
+// resource of the metrics.
+func NewMetrics(keys ...string) *Metrics {
+	return &Metrics{splitter: newSplitter(keys), md: pmetric.NewMetrics()}
+}
+
+// Metric returns the metric called name of the resource identified by the
+// resource attributes of attrs, appended empty the first time, and the other
+// attributes of attrs, to set on the data point of the record. The caller
+// sets the type of the metric when it's empty.
+func (b *Metrics) Metric(attrs pcommon.Map, name string) (pmetric.Metric, pcommon.Map) {
+	resource := pcommon.NewMap()
+	record := pcommon.NewMap()
+	b.split(attrs, resource, record)
+
+	i, added := b.group(resource)
+	if added {
+		rm := b.md.ResourceMetrics().AppendEmpty()
+		resource.MoveTo(rm.Resource().Attributes())
+		rm.ScopeMetrics().AppendEmpty()
+		b.metrics = append(b.metrics, make(map[string]int))
+	}
+	metrics := b.md.ResourceMetrics().At(i).ScopeMetrics().At(0).Metrics()
+	j, ok := b.metrics[i][name]
+	if !ok {
+		j = metrics.Len()
+		b.metrics[i][name] = j
+		metrics.AppendEmpty().SetName(name)
+	}
+	return metrics.At(j), record
+}
+
+// Metrics returns the metrics built so far, resources in the order they were
+// first seen.
+func (b *Metrics) Metrics() pmetric.Metrics {
+	return b.md
+}
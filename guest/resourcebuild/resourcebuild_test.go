@@ -0,0 +1,127 @@
+package resourcebuild
+
+import (
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// flat returns the attributes of a flat record of the given service and host,
+// with a record attribute.
+func flat(service, host string, status int64) pcommon.Map {
+	m := pcommon.NewMap()
+	m.PutStr("service.name", service)
+	if host != "" {
+		m.PutStr("host.name", host)
+	}
+	m.PutInt("http.status_code", status)
+	return m
+}
+
+func TestTraces(t *testing.T) {
+	b := NewTraces("service.name", "host.name")
+	for i, attrs := range []pcommon.Map{
+		flat("frontend", "a", 200),
+		flat("backend", "a", 500),
+		flat("frontend", "a", 404),
+		// A resource key missing makes another resource.
+		flat("frontend", "", 200),
+	} {
+		span := b.AppendSpan(attrs)
+		span.SetName(string(rune('0' + i)))
+
+		if _, ok := attrs.Get("service.name"); !ok {
+			t.Fatalf("expected the flat attributes to be left as is")
+		}
+	}
+
+	td := b.Traces()
+	want := []struct {
+		resource map[string]any
+		spans    []string
+	}{
+		{resource: map[string]any{"service.name": "frontend", "host.name": "a"}, spans: []string{"0", "2"}},
+		{resource: map[string]any{"service.name": "backend", "host.name": "a"}, spans: []string{"1"}},
+		{resource: map[string]any{"service.name": "frontend"}, spans: []string{"3"}},
+	}
+	if td.ResourceSpans().Len() != len(want) {
+		t.Fatalf("expected %d resources, got %d", len(want), td.ResourceSpans().Len())
+	}
+	for i, w := range want {
+		rs := td.ResourceSpans().At(i)
+		if got := rs.Resource().Attributes().AsRaw(); !reflect.DeepEqual(got, w.resource) {
+			t.Errorf("resource %d: expected %v, got %v", i, w.resource, got)
+		}
+		spans := rs.ScopeSpans().At(0).Spans()
+		var names []string
+		for j := 0; j < spans.Len(); j++ {
+			names = append(names, spans.At(j).Name())
+			if got := spans.At(j).Attributes().AsRaw(); len(got) != 1 || got["http.status_code"] == nil {
+				t.Errorf("resource %d: expected only the record attribute on the span, got %v", i, got)
+			}
+		}
+		if !reflect.DeepEqual(names, w.spans) {
+			t.Errorf("resource %d: expected spans %v, got %v", i, w.spans, names)
+		}
+	}
+}
+
+func TestLogs(t *testing.T) {
+	b := NewLogs("service.name")
+	b.AppendLogRecord(flat("frontend", "a", 200)).Body().SetStr("first")
+	b.AppendLogRecord(flat("backend", "a", 500)).Body().SetStr("second")
+	b.AppendLogRecord(flat("frontend", "b", 200)).Body().SetStr("third")
+
+	ld := b.Logs()
+	if ld.ResourceLogs().Len() != 2 {
+		t.Fatalf("expected 2 resources, got %d", ld.ResourceLogs().Len())
+	}
+	records := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	if records.Len() != 2 || records.At(1).Body().Str() != "third" {
+		t.Fatalf("expected the frontend records to be grouped, got %d records", records.Len())
+	}
+	// host.name isn't a resource key, so it stays on the record.
+	if host, _ := records.At(1).Attributes().Get("host.name"); host.Str() != "b" {
+		t.Errorf("expected host.name on the record, got %v", records.At(1).Attributes().AsRaw())
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	b := NewMetrics("service.name")
+	for _, attrs := range []pcommon.Map{
+		flat("frontend", "a", 200),
+		flat("frontend", "b", 500),
+		flat("backend", "a", 200),
+	} {
+		m, record := b.Metric(attrs, "requests")
+		if m.Type() == pmetric.MetricTypeEmpty {
+			m.SetEmptySum().SetIsMonotonic(true)
+		}
+		dp := m.Sum().DataPoints().AppendEmpty()
+		dp.SetIntValue(1)
+		record.CopyTo(dp.Attributes())
+	}
+	m, _ := b.Metric(flat("frontend", "a", 200), "latency")
+	m.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(0.5)
+
+	md := b.Metrics()
+	if md.ResourceMetrics().Len() != 2 {
+		t.Fatalf("expected 2 resources, got %d", md.ResourceMetrics().Len())
+	}
+	metrics := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	if metrics.Len() != 2 {
+		t.Fatalf("expected the frontend requests and latency metrics, got %d metrics", metrics.Len())
+	}
+	if got := metrics.At(0).Sum().DataPoints().Len(); got != 2 {
+		t.Errorf("expected 2 frontend requests data points, got %d", got)
+	}
+	want := map[string]any{"host.name": "b", "http.status_code": int64(500)}
+	if got := metrics.At(0).Sum().DataPoints().At(1).Attributes().AsRaw(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected data point attributes %v, got %v", want, got)
+	}
+	if got := md.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().Len(); got != 1 {
+		t.Errorf("expected 1 backend requests data point, got %d", got)
+	}
+}
@@ -0,0 +1,101 @@
+// Package rollup computes per-resource span and error counts from traces, so
+// guests can emit service level rollups for dashboards.
+package rollup
+
+import (
+	"github.com/otelwasm/otelwasm/guest/internal/identity"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// SpanCountMetric is the name of the metric counting the spans of a resource.
+	SpanCountMetric = "otelwasm.rollup.span.count"
+	// ErrorCountMetric is the name of the metric counting the spans of a
+	// resource whose status is error.
+	ErrorCountMetric = "otelwasm.rollup.error.count"
+)
+
+// Config is the configuration of the rollup.
+type Config struct {
+	// DropTraces makes Apply emit the rollup metrics instead of the traces.
+	// By default, the traces are kept alongside the metrics.
+	DropTraces bool `json:"drop_traces"`
+}
+
+// Summary holds the counts of a single resource.
+type Summary struct {
+	Resource   pcommon.Resource
+	SpanCount  int64
+	ErrorCount int64
+}
+
+// Summarize counts the spans and the error spans of each distinct resource
+// in traces. Resource spans sharing the same resource attributes are merged
+// into a single summary. Summaries are ordered by first appearance.
+func Summarize(traces ptrace.Traces) []Summary {
+	var summaries []Summary
+	index := make(map[string]int)
+
+	rss := traces.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		key := identity.MapKey(rs.Resource().Attributes())
+		idx, ok := index[key]
+		if !ok {
+			idx = len(summaries)
+			index[key] = idx
+			summaries = append(summaries, Summary{Resource: rs.Resource()})
+		}
+
+		sss := rs.ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			summaries[idx].SpanCount += int64(spans.Len())
+			for k := 0; k < spans.Len(); k++ {
+				if spans.At(k).Status().Code() == ptrace.StatusCodeError {
+					summaries[idx].ErrorCount++
+				}
+			}
+		}
+	}
+	return summaries
+}
+
+// Metrics converts summaries into delta sums, one resource metrics per
+// summary, timestamped with now.
+func Metrics(summaries []Summary, now pcommon.Timestamp) pmetric.Metrics {
+	metrics := pmetric.NewMetrics()
+	for _, s := range summaries {
+		rm := metrics.ResourceMetrics().AppendEmpty()
+		s.Resource.CopyTo(rm.Resource())
+		ms := rm.ScopeMetrics().AppendEmpty().Metrics()
+		appendSum(ms, SpanCountMetric, "Number of spans.", s.SpanCount, now)
+		appendSum(ms, ErrorCountMetric, "Number of spans with an error status.", s.ErrorCount, now)
+	}
+	return metrics
+}
+
+func appendSum(ms pmetric.MetricSlice, name, description string, value int64, now pcommon.Timestamp) {
+	m := ms.AppendEmpty()
+	m.SetName(name)
+	m.SetDescription(description)
+	m.SetUnit("{span}")
+	sum := m.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	dp := sum.DataPoints().AppendEmpty()
+	dp.SetTimestamp(now)
+	dp.SetIntValue(value)
+}
+
+// Apply computes the rollup metrics of traces. The returned traces are the
+// input traces, or empty traces if cfg.DropTraces is set.
+func Apply(traces ptrace.Traces, cfg Config, now pcommon.Timestamp) (ptrace.Traces, pmetric.Metrics) {
+	metrics := Metrics(Summarize(traces), now)
+	if cfg.DropTraces {
+		return ptrace.NewTraces(), metrics
+	}
+	return traces, metrics
+}
@@ -0,0 +1,101 @@
+package rollup
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func appendResourceSpans(td ptrace.Traces, service string, ok, failed int) {
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", service)
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	for i := 0; i < ok; i++ {
+		spans.AppendEmpty().Status().SetCode(ptrace.StatusCodeOk)
+	}
+	for i := 0; i < failed; i++ {
+		spans.AppendEmpty().Status().SetCode(ptrace.StatusCodeError)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	td := ptrace.NewTraces()
+	appendResourceSpans(td, "frontend", 3, 1)
+	appendResourceSpans(td, "backend", 2, 0)
+	// Same resource as the first one, so it must be merged into it.
+	appendResourceSpans(td, "frontend", 1, 2)
+
+	summaries := Summarize(td)
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(summaries))
+	}
+
+	want := []struct {
+		service        string
+		spans, errored int64
+	}{
+		{"frontend", 7, 3},
+		{"backend", 2, 0},
+	}
+	for i, w := range want {
+		s := summaries[i]
+		if v, _ := s.Resource.Attributes().Get("service.name"); v.Str() != w.service {
+			t.Errorf("summary %d: expected service %q, got %q", i, w.service, v.Str())
+		}
+		if s.SpanCount != w.spans {
+			t.Errorf("summary %d: expected %d spans, got %d", i, w.spans, s.SpanCount)
+		}
+		if s.ErrorCount != w.errored {
+			t.Errorf("summary %d: expected %d errors, got %d", i, w.errored, s.ErrorCount)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	td := ptrace.NewTraces()
+	appendResourceSpans(td, "frontend", 1, 1)
+	now := pcommon.Timestamp(42)
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantSpans int
+	}{
+		{name: "alongside traces", cfg: Config{}, wantSpans: 2},
+		{name: "instead of traces", cfg: Config{DropTraces: true}, wantSpans: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces, metrics := Apply(td, tt.cfg, now)
+			if traces.SpanCount() != tt.wantSpans {
+				t.Errorf("expected %d spans, got %d", tt.wantSpans, traces.SpanCount())
+			}
+
+			if metrics.ResourceMetrics().Len() != 1 {
+				t.Fatalf("expected 1 resource metrics, got %d", metrics.ResourceMetrics().Len())
+			}
+			ms := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			values := map[string]int64{}
+			for i := 0; i < ms.Len(); i++ {
+				m := ms.At(i)
+				if m.Sum().AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+					t.Errorf("%s: expected delta temporality", m.Name())
+				}
+				dp := m.Sum().DataPoints().At(0)
+				if dp.Timestamp() != now {
+					t.Errorf("%s: expected timestamp %v, got %v", m.Name(), now, dp.Timestamp())
+				}
+				values[m.Name()] = dp.IntValue()
+			}
+			if values[SpanCountMetric] != 2 {
+				t.Errorf("expected span count 2, got %d", values[SpanCountMetric])
+			}
+			if values[ErrorCountMetric] != 1 {
+				t.Errorf("expected error count 1, got %d", values[ErrorCountMetric])
+			}
+		})
+	}
+}
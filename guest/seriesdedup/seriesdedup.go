@@ -0,0 +1,185 @@
+// Package seriesdedup merges duplicate metric series, so aggregation guests
+// emit a single data point per series.
+//
+// A series is identified by its metric name and its data point attribute set,
+// within a scope. The data points of duplicate delta sums and delta
+// histograms with the same bucket bounds are summed. For every other type,
+// the data point with the latest timestamp wins, the last one in the batch on
+// ties.
+package seriesdedup
+
+import (
+	"slices"
+
+	"github.com/otelwasm/otelwasm/guest/internal/identity"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// Metrics merges the duplicate series of md and reports whether md was
+// mutated.
+func Metrics(md pmetric.Metrics) (mutated bool) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			if dedupMetrics(sms.At(j).Metrics()) {
+				mutated = true
+			}
+		}
+	}
+	return mutated
+}
+
+// dedupMetrics merges the metrics sharing the same name and type into the
+// first of them, then merges their duplicate data points.
+func dedupMetrics(ms pmetric.MetricSlice) (mutated bool) {
+	index := make(map[string]pmetric.Metric)
+	ms.RemoveIf(func(m pmetric.Metric) bool {
+		key := m.Type().String() + "|" + m.Name()
+		kept, ok := index[key]
+		if !ok {
+			index[key] = m
+			return false
+		}
+		moveDataPoints(m, kept)
+		mutated = true
+		return true
+	})
+
+	for i := 0; i < ms.Len(); i++ {
+		if dedupDataPoints(ms.At(i)) {
+			mutated = true
+		}
+	}
+	return mutated
+}
+
+func moveDataPoints(src, dest pmetric.Metric) {
+	switch src.Type() {
+	case pmetric.MetricTypeGauge:
+		src.Gauge().DataPoints().MoveAndAppendTo(dest.Gauge().DataPoints())
+	case pmetric.MetricTypeSum:
+		src.Sum().DataPoints().MoveAndAppendTo(dest.Sum().DataPoints())
+	case pmetric.MetricTypeHistogram:
+		src.Histogram().DataPoints().MoveAndAppendTo(dest.Histogram().DataPoints())
+	case pmetric.MetricTypeExponentialHistogram:
+		src.ExponentialHistogram().DataPoints().MoveAndAppendTo(dest.ExponentialHistogram().DataPoints())
+	case pmetric.MetricTypeSummary:
+		src.Summary().DataPoints().MoveAndAppendTo(dest.Summary().DataPoints())
+	}
+}
+
+func dedupDataPoints(m pmetric.Metric) bool {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		return dedup(m.Gauge().DataPoints(), lastWrite[pmetric.NumberDataPoint])
+	case pmetric.MetricTypeSum:
+		if m.Sum().AggregationTemporality() == pmetric.AggregationTemporalityDelta {
+			return dedup(m.Sum().DataPoints(), addNumber)
+		}
+		return dedup(m.Sum().DataPoints(), lastWrite[pmetric.NumberDataPoint])
+	case pmetric.MetricTypeHistogram:
+		if m.Histogram().AggregationTemporality() == pmetric.AggregationTemporalityDelta {
+			return dedup(m.Histogram().DataPoints(), addHistogram)
+		}
+		return dedup(m.Histogram().DataPoints(), lastWrite[pmetric.HistogramDataPoint])
+	case pmetric.MetricTypeExponentialHistogram:
+		return dedup(m.ExponentialHistogram().DataPoints(), lastWrite[pmetric.ExponentialHistogramDataPoint])
+	case pmetric.MetricTypeSummary:
+		return dedup(m.Summary().DataPoints(), lastWrite[pmetric.SummaryDataPoint])
+	}
+	return false
+}
+
+type dataPoint[T any] interface {
+	Attributes() pcommon.Map
+	StartTimestamp() pcommon.Timestamp
+	Timestamp() pcommon.Timestamp
+	CopyTo(dest T)
+}
+
+type dataPointSlice[T any] interface {
+	RemoveIf(f func(T) bool)
+}
+
+// dedup merges the data points of dps sharing the same attribute set into
+// the first of them.
+func dedup[T dataPoint[T]](dps dataPointSlice[T], merge func(kept, dp T)) (mutated bool) {
+	index := make(map[string]T)
+	dps.RemoveIf(func(dp T) bool {
+		key := identity.MapKey(dp.Attributes())
+		kept, ok := index[key]
+		if !ok {
+			index[key] = dp
+			return false
+		}
+		merge(kept, dp)
+		mutated = true
+		return true
+	})
+	return mutated
+}
+
+func lastWrite[T dataPoint[T]](kept, dp T) {
+	if dp.Timestamp() >= kept.Timestamp() {
+		dp.CopyTo(kept)
+	}
+}
+
+// mergeTimestamps widens the time range of kept to include the one of dp.
+func mergeTimestamps[T interface {
+	dataPoint[T]
+	SetStartTimestamp(pcommon.Timestamp)
+	SetTimestamp(pcommon.Timestamp)
+}](kept, dp T,
+) {
+	if dp.StartTimestamp() < kept.StartTimestamp() {
+		kept.SetStartTimestamp(dp.StartTimestamp())
+	}
+	if dp.Timestamp() > kept.Timestamp() {
+		kept.SetTimestamp(dp.Timestamp())
+	}
+}
+
+func addNumber(kept, dp pmetric.NumberDataPoint) {
+	mergeTimestamps(kept, dp)
+	if kept.ValueType() == pmetric.NumberDataPointValueTypeInt && dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		kept.SetIntValue(kept.IntValue() + dp.IntValue())
+		return
+	}
+	kept.SetDoubleValue(numberValue(kept) + numberValue(dp))
+}
+
+func numberValue(dp pmetric.NumberDataPoint) float64 {
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		return float64(dp.IntValue())
+	}
+	return dp.DoubleValue()
+}
+
+func addHistogram(kept, dp pmetric.HistogramDataPoint) {
+	if !slices.Equal(kept.ExplicitBounds().AsRaw(), dp.ExplicitBounds().AsRaw()) {
+		lastWrite(kept, dp)
+		return
+	}
+	mergeTimestamps(kept, dp)
+
+	kept.SetCount(kept.Count() + dp.Count())
+	if kept.HasSum() && dp.HasSum() {
+		kept.SetSum(kept.Sum() + dp.Sum())
+	} else {
+		kept.RemoveSum()
+	}
+	if dp.HasMin() && (!kept.HasMin() || dp.Min() < kept.Min()) {
+		kept.SetMin(dp.Min())
+	}
+	if dp.HasMax() && (!kept.HasMax() || dp.Max() > kept.Max()) {
+		kept.SetMax(dp.Max())
+	}
+
+	counts := kept.BucketCounts()
+	for i := 0; i < counts.Len() && i < dp.BucketCounts().Len(); i++ {
+		counts.SetAt(i, counts.At(i)+dp.BucketCounts().At(i))
+	}
+}
@@ -0,0 +1,134 @@
+package seriesdedup
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func newMetrics() (pmetric.Metrics, pmetric.MetricSlice) {
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	return md, ms
+}
+
+func appendSum(ms pmetric.MetricSlice, name string, temporality pmetric.AggregationTemporality) pmetric.NumberDataPointSlice {
+	m := ms.AppendEmpty()
+	m.SetName(name)
+	sum := m.SetEmptySum()
+	sum.SetAggregationTemporality(temporality)
+	return sum.DataPoints()
+}
+
+func appendNumber(dps pmetric.NumberDataPointSlice, host string, ts pcommon.Timestamp, v int64) {
+	dp := dps.AppendEmpty()
+	dp.Attributes().PutStr("host", host)
+	dp.SetTimestamp(ts)
+	dp.SetIntValue(v)
+}
+
+func TestDistinctSeries(t *testing.T) {
+	md, ms := newMetrics()
+	dps := appendSum(ms, "requests", pmetric.AggregationTemporalityDelta)
+	appendNumber(dps, "a", 1, 1)
+	appendNumber(dps, "b", 1, 2)
+	appendNumber(appendSum(ms, "errors", pmetric.AggregationTemporalityDelta), "a", 1, 3)
+
+	if Metrics(md) {
+		t.Error("expected metrics not to be mutated")
+	}
+	if md.MetricCount() != 2 || md.DataPointCount() != 3 {
+		t.Errorf("expected 2 metrics and 3 data points, got %d and %d", md.MetricCount(), md.DataPointCount())
+	}
+}
+
+func TestDeltaSumSummed(t *testing.T) {
+	md, ms := newMetrics()
+	dps := appendSum(ms, "requests", pmetric.AggregationTemporalityDelta)
+	appendNumber(dps, "a", 1, 1)
+	appendNumber(dps, "b", 1, 10)
+	// Same series split across two metrics.
+	appendNumber(appendSum(ms, "requests", pmetric.AggregationTemporalityDelta), "a", 2, 2)
+
+	if !Metrics(md) {
+		t.Error("expected metrics to be mutated")
+	}
+	if md.MetricCount() != 1 || md.DataPointCount() != 2 {
+		t.Fatalf("expected 1 metric and 2 data points, got %d and %d", md.MetricCount(), md.DataPointCount())
+	}
+	dp := ms.At(0).Sum().DataPoints().At(0)
+	if dp.IntValue() != 3 {
+		t.Errorf("expected summed value 3, got %d", dp.IntValue())
+	}
+	if dp.Timestamp() != 2 {
+		t.Errorf("expected latest timestamp 2, got %d", dp.Timestamp())
+	}
+}
+
+func TestDeltaSumMixedValueTypes(t *testing.T) {
+	md, ms := newMetrics()
+	dps := appendSum(ms, "requests", pmetric.AggregationTemporalityDelta)
+	appendNumber(dps, "a", 1, 1)
+	dp := dps.AppendEmpty()
+	dp.Attributes().PutStr("host", "a")
+	dp.SetDoubleValue(0.5)
+
+	Metrics(md)
+	if got := dps.At(0).DoubleValue(); got != 1.5 {
+		t.Errorf("expected summed value 1.5, got %v", got)
+	}
+}
+
+func TestLastWrite(t *testing.T) {
+	md, ms := newMetrics()
+	dps := appendSum(ms, "total", pmetric.AggregationTemporalityCumulative)
+	appendNumber(dps, "a", 5, 50)
+	appendNumber(dps, "a", 3, 30)
+	appendNumber(dps, "a", 5, 55)
+
+	if !Metrics(md) {
+		t.Error("expected metrics to be mutated")
+	}
+	if dps.Len() != 1 {
+		t.Fatalf("expected 1 data point, got %d", dps.Len())
+	}
+	if dps.At(0).IntValue() != 55 {
+		t.Errorf("expected latest value 55, got %d", dps.At(0).IntValue())
+	}
+}
+
+func TestDeltaHistogramSummed(t *testing.T) {
+	md, ms := newMetrics()
+	m := ms.AppendEmpty()
+	m.SetName("latency")
+	h := m.SetEmptyHistogram()
+	h.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	for _, v := range []float64{1, 20} {
+		dp := h.DataPoints().AppendEmpty()
+		dp.ExplicitBounds().FromRaw([]float64{10})
+		dp.SetCount(1)
+		dp.SetSum(v)
+		dp.SetMin(v)
+		dp.SetMax(v)
+		if v < 10 {
+			dp.BucketCounts().FromRaw([]uint64{1, 0})
+		} else {
+			dp.BucketCounts().FromRaw([]uint64{0, 1})
+		}
+	}
+
+	if !Metrics(md) {
+		t.Error("expected metrics to be mutated")
+	}
+	if h.DataPoints().Len() != 1 {
+		t.Fatalf("expected 1 data point, got %d", h.DataPoints().Len())
+	}
+	dp := h.DataPoints().At(0)
+	if dp.Count() != 2 || dp.Sum() != 21 || dp.Min() != 1 || dp.Max() != 20 {
+		t.Errorf("unexpected merged histogram: count %d, sum %v, min %v, max %v", dp.Count(), dp.Sum(), dp.Min(), dp.Max())
+	}
+	if got := dp.BucketCounts().AsRaw(); got[0] != 1 || got[1] != 1 {
+		t.Errorf("expected bucket counts [1 1], got %v", got)
+	}
+}
@@ -0,0 +1,80 @@
+// Package spanevent records events on spans, e.g. the decisions of a
+// processor such as sampling a span out or flagging it as slow, and the
+// exceptions met while processing them.
+//
+// Events are appended to the span in place, so the span must be a part of
+// the traces returned by the guest, not a copy of it. The attributes are
+// converted before the event is appended, so a span is never left with a
+// half-built event.
+package spanevent
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/clock"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// ExceptionEventName is the name of the events recorded by
+	// RecordException, as of the OpenTelemetry semantic conventions.
+	ExceptionEventName = "exception"
+
+	// ExceptionTypeKey and ExceptionMessageKey are the attributes of the
+	// exception events holding the type and the message of the error.
+	ExceptionTypeKey    = "exception.type"
+	ExceptionMessageKey = "exception.message"
+)
+
+// now returns the timestamp of the events without one, replaced in tests.
+var now = clock.Now
+
+// Event is an event to record on a span.
+type Event struct {
+	// Name is the name of the event, e.g. "dropped due to rate limit".
+	Name string
+
+	// Timestamp is the time of the event. The host time is used if zero.
+	Timestamp time.Time
+
+	// Attributes are the attributes of the event. The values are of the
+	// types accepted by pcommon.Value.FromRaw, e.g. string, int64, float64,
+	// bool, []byte, []any and map[string]any.
+	Attributes map[string]any
+}
+
+// Add appends the event to the events of span and returns it.
+func Add(span ptrace.Span, event Event) (ptrace.SpanEvent, error) {
+	if event.Name == "" {
+		return ptrace.SpanEvent{}, errors.New("spanevent: the event name is required")
+	}
+	attrs := pcommon.NewMap()
+	if err := attrs.FromRaw(event.Attributes); err != nil {
+		return ptrace.SpanEvent{}, fmt.Errorf("spanevent: %s: %w", event.Name, err)
+	}
+	ts := event.Timestamp
+	if ts.IsZero() {
+		ts = now()
+	}
+
+	e := span.Events().AppendEmpty()
+	e.SetName(event.Name)
+	e.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	attrs.MoveTo(e.Attributes())
+	return e, nil
+}
+
+// RecordException appends an exception event describing err to the events
+// of span, with attrs as additional attributes, and returns it. The status of
+// the span is left as is.
+func RecordException(span ptrace.Span, err error, attrs map[string]any) (ptrace.SpanEvent, error) {
+	all := make(map[string]any, len(attrs)+2)
+	maps.Copy(all, attrs)
+	all[ExceptionTypeKey] = fmt.Sprintf("%T", err)
+	all[ExceptionMessageKey] = err.Error()
+	return Add(span, Event{Name: ExceptionEventName, Attributes: all})
+}
@@ -0,0 +1,119 @@
+package spanevent
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func fakeNow(t *testing.T, current time.Time) {
+	t.Helper()
+	prev := now
+	t.Cleanup(func() { now = prev })
+	now = func() time.Time { return current }
+}
+
+// testSpan returns a span nested in traces, as passed to the processors.
+func testSpan() (ptrace.Traces, ptrace.Span) {
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName("GET /users")
+	return traces, span
+}
+
+func TestAdd(t *testing.T) {
+	current := time.Unix(1700000000, 0)
+	fakeNow(t, current)
+	traces, span := testSpan()
+
+	if _, err := Add(span, Event{Name: "dropped due to rate limit", Attributes: map[string]any{
+		"limit":  int64(100),
+		"tenant": "acme",
+		"rules":  []any{"per_tenant"},
+	}}); err != nil {
+		t.Fatalf("failed to add the event: %v", err)
+	}
+	at := current.Add(-time.Second)
+	if _, err := Add(span, Event{Name: "sampled", Timestamp: at}); err != nil {
+		t.Fatalf("failed to add the event: %v", err)
+	}
+
+	// The events are read back from the traces, not from span.
+	events := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Events()
+	if events.Len() != 2 {
+		t.Fatalf("expected 2 events, got %d", events.Len())
+	}
+	e := events.At(0)
+	if e.Name() != "dropped due to rate limit" || !e.Timestamp().AsTime().Equal(current) {
+		t.Errorf("expected the event to be timestamped with the host time, got %s at %v", e.Name(), e.Timestamp().AsTime())
+	}
+	want := map[string]any{"limit": int64(100), "tenant": "acme", "rules": []any{"per_tenant"}}
+	if got := e.Attributes().AsRaw(); !equalRaw(got, want) {
+		t.Errorf("expected the attributes %v, got %v", want, got)
+	}
+	if e := events.At(1); e.Name() != "sampled" || !e.Timestamp().AsTime().Equal(at) || e.Attributes().Len() != 0 {
+		t.Errorf("expected the sampled event at %v without attributes, got %s at %v with %v", at, e.Name(), e.Timestamp().AsTime(), e.Attributes().AsRaw())
+	}
+}
+
+func TestAddInvalid(t *testing.T) {
+	_, span := testSpan()
+	tests := []struct {
+		name  string
+		event Event
+	}{
+		{name: "no name", event: Event{}},
+		{name: "unsupported attribute", event: Event{Name: "decision", Attributes: map[string]any{"at": time.Now()}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Add(span, tt.event); err == nil {
+				t.Error("expected the event to be refused")
+			}
+			if span.Events().Len() != 0 {
+				t.Errorf("expected no event to be appended, got %d", span.Events().Len())
+			}
+		})
+	}
+}
+
+func TestRecordException(t *testing.T) {
+	fakeNow(t, time.Unix(1700000000, 0))
+	_, span := testSpan()
+	span.Status().SetCode(ptrace.StatusCodeOk)
+
+	err := &fs.PathError{Op: "open", Path: "rules.json", Err: fs.ErrNotExist}
+	e, recordErr := RecordException(span, err, map[string]any{"rule": "per_tenant"})
+	if recordErr != nil {
+		t.Fatalf("failed to record the exception: %v", recordErr)
+	}
+	if e.Name() != ExceptionEventName {
+		t.Errorf("expected an %s event, got %s", ExceptionEventName, e.Name())
+	}
+	want := map[string]any{
+		ExceptionTypeKey:    "*fs.PathError",
+		ExceptionMessageKey: "open rules.json: file does not exist",
+		"rule":              "per_tenant",
+	}
+	if got := e.Attributes().AsRaw(); !equalRaw(got, want) {
+		t.Errorf("expected the attributes %v, got %v", want, got)
+	}
+	if span.Status().Code() != ptrace.StatusCodeOk {
+		t.Errorf("expected the span status to be left as is, got %v", span.Status().Code())
+	}
+}
+
+// equalRaw reports whether the raw attributes got and want are equal.
+func equalRaw(got, want map[string]any) bool {
+	a, b := pcommon.NewMap(), pcommon.NewMap()
+	if err := a.FromRaw(got); err != nil {
+		return false
+	}
+	if err := b.FromRaw(want); err != nil {
+		return false
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,252 @@
+// Package spanmetrics converts spans to call count and duration metrics,
+// with either delta or cumulative aggregation temporality.
+//
+// A series is identified by the resource of the span, its name, kind and
+// status code, and the configured span attribute dimensions. Delta metrics
+// only cover the spans of the converted batch. Cumulative metrics accumulate
+// the spans of every batch since the series first appeared, in a Store
+// persisting across guest calls.
+package spanmetrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/otelwasm/otelwasm/guest/internal/identity"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// CallsMetric is the name of the metric counting the spans of a series.
+	CallsMetric = "traces.span.metrics.calls"
+	// DurationMetric is the name of the histogram of the span durations of a
+	// series, in milliseconds.
+	DurationMetric = "traces.span.metrics.duration"
+
+	// TemporalityDelta makes each conversion report the spans of its batch.
+	TemporalityDelta = "delta"
+	// TemporalityCumulative makes each conversion report the spans of every
+	// batch since the series first appeared.
+	TemporalityCumulative = "cumulative"
+)
+
+// DefaultBuckets are the duration histogram bounds used if none are
+// configured, in milliseconds.
+var DefaultBuckets = []float64{2, 4, 6, 8, 10, 50, 100, 200, 400, 800, 1000, 1400, 2000, 5000, 10000, 15000}
+
+// Config is the configuration of the conversion.
+type Config struct {
+	// Temporality is the aggregation temporality of the metrics, delta or
+	// cumulative. Defaults to delta.
+	Temporality string `json:"temporality"`
+	// Dimensions are the span attributes added to the series attributes.
+	// Spans without a dimension attribute are grouped under an empty value.
+	Dimensions []string `json:"dimensions"`
+	// Buckets are the duration histogram bounds, in milliseconds. Defaults to
+	// DefaultBuckets.
+	Buckets []float64 `json:"buckets"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	switch c.Temporality {
+	case "", TemporalityDelta, TemporalityCumulative:
+	default:
+		return fmt.Errorf("unsupported temporality %q", c.Temporality)
+	}
+	if !sort.Float64sAreSorted(c.Buckets) {
+		return fmt.Errorf("buckets must be sorted")
+	}
+	return nil
+}
+
+// Store is a key-value store persisting the cumulative state across guest
+// calls.
+type Store interface {
+	// Get returns the value stored under key.
+	Get(key string) ([]byte, bool)
+	// Set stores value under key.
+	Set(key string, value []byte)
+}
+
+// MemoryStore is a Store in the guest memory. Its state lasts as long as the
+// guest module instance.
+type MemoryStore map[string][]byte
+
+// Get implements Store.
+func (s MemoryStore) Get(key string) ([]byte, bool) {
+	v, ok := s[key]
+	return v, ok
+}
+
+// Set implements Store.
+func (s MemoryStore) Set(key string, value []byte) {
+	s[key] = value
+}
+
+// series is the aggregated state of a series.
+type series struct {
+	Start        pcommon.Timestamp `json:"start"`
+	Count        uint64            `json:"count"`
+	Sum          float64           `json:"sum"`
+	BucketCounts []uint64          `json:"bucket_counts"`
+
+	resource   pcommon.Resource
+	attributes pcommon.Map
+}
+
+func (s *series) add(span ptrace.Span, bounds []float64) {
+	if s.Count == 0 || span.StartTimestamp() < s.Start {
+		s.Start = span.StartTimestamp()
+	}
+	ms := float64(span.EndTimestamp()-span.StartTimestamp()) / float64(time.Millisecond)
+	s.Count++
+	s.Sum += ms
+	s.BucketCounts[sort.SearchFloat64s(bounds, ms)]++
+}
+
+// merge adds the state of prev to s, keeping the start of prev.
+func (s *series) merge(prev *series) {
+	s.Start = prev.Start
+	s.Count += prev.Count
+	s.Sum += prev.Sum
+	for i := range s.BucketCounts {
+		if i < len(prev.BucketCounts) {
+			s.BucketCounts[i] += prev.BucketCounts[i]
+		}
+	}
+}
+
+// Convert converts the spans of td to metrics timestamped with now. store is
+// only used, and required, with the cumulative temporality.
+func Convert(td ptrace.Traces, cfg Config, store Store, now pcommon.Timestamp) (pmetric.Metrics, error) {
+	if err := cfg.Validate(); err != nil {
+		return pmetric.Metrics{}, err
+	}
+	cumulative := cfg.Temporality == TemporalityCumulative
+	if cumulative && store == nil {
+		return pmetric.Metrics{}, fmt.Errorf("cumulative temporality requires a store")
+	}
+	bounds := cfg.Buckets
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+
+	var keys []string
+	index := make(map[string]*series)
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		resourceKey := identity.MapKey(rs.Resource().Attributes())
+		sss := rs.ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				attrs := seriesAttributes(span, cfg.Dimensions)
+				key := resourceKey + identity.MapKey(attrs)
+				s, ok := index[key]
+				if !ok {
+					s = &series{
+						BucketCounts: make([]uint64, len(bounds)+1),
+						resource:     rs.Resource(),
+						attributes:   attrs,
+					}
+					index[key] = s
+					keys = append(keys, key)
+				}
+				s.add(span, bounds)
+			}
+		}
+	}
+
+	if cumulative {
+		for _, key := range keys {
+			s := index[key]
+			if raw, ok := store.Get(key); ok {
+				var prev series
+				if err := json.Unmarshal(raw, &prev); err != nil {
+					return pmetric.Metrics{}, fmt.Errorf("failed to decode the state of a series: %w", err)
+				}
+				s.merge(&prev)
+			}
+			raw, err := json.Marshal(s)
+			if err != nil {
+				return pmetric.Metrics{}, fmt.Errorf("failed to encode the state of a series: %w", err)
+			}
+			store.Set(key, raw)
+		}
+	}
+
+	temporality := pmetric.AggregationTemporalityDelta
+	if cumulative {
+		temporality = pmetric.AggregationTemporalityCumulative
+	}
+	return toMetrics(keys, index, bounds, temporality, now), nil
+}
+
+// seriesAttributes returns the attributes identifying the series of span.
+func seriesAttributes(span ptrace.Span, dimensions []string) pcommon.Map {
+	attrs := pcommon.NewMap()
+	attrs.PutStr("span.name", span.Name())
+	attrs.PutStr("span.kind", span.Kind().String())
+	attrs.PutStr("status.code", span.Status().Code().String())
+	for _, d := range dimensions {
+		if v, ok := span.Attributes().Get(d); ok {
+			v.CopyTo(attrs.PutEmpty(d))
+		} else {
+			attrs.PutStr(d, "")
+		}
+	}
+	return attrs
+}
+
+func toMetrics(keys []string, index map[string]*series, bounds []float64, temporality pmetric.AggregationTemporality, now pcommon.Timestamp) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	// Series of the same resource share a resource metrics.
+	resources := make(map[string]pmetric.MetricSlice)
+	for _, key := range keys {
+		s := index[key]
+		resourceKey := identity.MapKey(s.resource.Attributes())
+		ms, ok := resources[resourceKey]
+		if !ok {
+			rm := md.ResourceMetrics().AppendEmpty()
+			s.resource.CopyTo(rm.Resource())
+			ms = rm.ScopeMetrics().AppendEmpty().Metrics()
+
+			calls := ms.AppendEmpty()
+			calls.SetName(CallsMetric)
+			calls.SetUnit("{call}")
+			sum := calls.SetEmptySum()
+			sum.SetIsMonotonic(true)
+			sum.SetAggregationTemporality(temporality)
+
+			duration := ms.AppendEmpty()
+			duration.SetName(DurationMetric)
+			duration.SetUnit("ms")
+			duration.SetEmptyHistogram().SetAggregationTemporality(temporality)
+
+			resources[resourceKey] = ms
+		}
+
+		dp := ms.At(0).Sum().DataPoints().AppendEmpty()
+		s.attributes.CopyTo(dp.Attributes())
+		dp.SetStartTimestamp(s.Start)
+		dp.SetTimestamp(now)
+		dp.SetIntValue(int64(s.Count))
+
+		hdp := ms.At(1).Histogram().DataPoints().AppendEmpty()
+		s.attributes.CopyTo(hdp.Attributes())
+		hdp.SetStartTimestamp(s.Start)
+		hdp.SetTimestamp(now)
+		hdp.SetCount(s.Count)
+		hdp.SetSum(s.Sum)
+		hdp.ExplicitBounds().FromRaw(bounds)
+		hdp.BucketCounts().FromRaw(s.BucketCounts)
+	}
+	return md
+}
@@ -0,0 +1,135 @@
+package spanmetrics
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const now = pcommon.Timestamp(1000 * time.Second)
+
+// newTraces returns traces of the checkout service with a span lasting each
+// of durations.
+func newTraces(start pcommon.Timestamp, durations ...time.Duration) ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	for _, d := range durations {
+		span := spans.AppendEmpty()
+		span.SetName("GET /cart")
+		span.SetKind(ptrace.SpanKindServer)
+		span.Attributes().PutStr("http.method", "GET")
+		span.SetStartTimestamp(start)
+		span.SetEndTimestamp(start + pcommon.Timestamp(d))
+	}
+	return td
+}
+
+func convert(t *testing.T, td ptrace.Traces, cfg Config, store Store) (pmetric.NumberDataPoint, pmetric.HistogramDataPoint) {
+	t.Helper()
+	md, err := Convert(td, cfg, store, now)
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+	ms := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	if ms.Len() != 2 || ms.At(0).Name() != CallsMetric || ms.At(1).Name() != DurationMetric {
+		t.Fatalf("expected calls and duration metrics, got %d metrics", ms.Len())
+	}
+	if ms.At(0).Sum().DataPoints().Len() != 1 || ms.At(1).Histogram().DataPoints().Len() != 1 {
+		t.Fatal("expected a single series")
+	}
+	return ms.At(0).Sum().DataPoints().At(0), ms.At(1).Histogram().DataPoints().At(0)
+}
+
+func TestDelta(t *testing.T) {
+	cfg := Config{Buckets: []float64{10, 100}}
+	store := MemoryStore{}
+
+	calls, duration := convert(t, newTraces(10, 5*time.Millisecond, 50*time.Millisecond), cfg, store)
+	if calls.IntValue() != 2 || duration.Count() != 2 || duration.Sum() != 55 {
+		t.Errorf("expected 2 calls lasting 55ms, got %d calls, count %d, sum %v", calls.IntValue(), duration.Count(), duration.Sum())
+	}
+	if got := duration.BucketCounts().AsRaw(); got[0] != 1 || got[1] != 1 || got[2] != 0 {
+		t.Errorf("expected bucket counts [1 1 0], got %v", got)
+	}
+	if calls.StartTimestamp() != 10 || calls.Timestamp() != now {
+		t.Errorf("unexpected timestamps %v and %v", calls.StartTimestamp(), calls.Timestamp())
+	}
+
+	// The second batch only reports its own spans.
+	calls, duration = convert(t, newTraces(20, 500*time.Millisecond), cfg, store)
+	if calls.IntValue() != 1 || duration.Sum() != 500 {
+		t.Errorf("expected 1 call lasting 500ms, got %d calls lasting %v", calls.IntValue(), duration.Sum())
+	}
+	if got := duration.BucketCounts().AsRaw(); got[2] != 1 {
+		t.Errorf("expected bucket counts [0 0 1], got %v", got)
+	}
+	if len(store) != 0 {
+		t.Errorf("expected delta not to use the store, got %d entries", len(store))
+	}
+}
+
+func TestCumulative(t *testing.T) {
+	cfg := Config{Temporality: TemporalityCumulative, Buckets: []float64{10, 100}}
+	store := MemoryStore{}
+
+	convert(t, newTraces(10, 5*time.Millisecond, 50*time.Millisecond), cfg, store)
+	calls, duration := convert(t, newTraces(20, 500*time.Millisecond), cfg, store)
+
+	if calls.IntValue() != 3 || duration.Count() != 3 || duration.Sum() != 555 {
+		t.Errorf("expected 3 calls lasting 555ms, got %d calls, count %d, sum %v", calls.IntValue(), duration.Count(), duration.Sum())
+	}
+	if got := duration.BucketCounts().AsRaw(); got[0] != 1 || got[1] != 1 || got[2] != 1 {
+		t.Errorf("expected bucket counts [1 1 1], got %v", got)
+	}
+	if calls.StartTimestamp() != 10 || duration.StartTimestamp() != 10 {
+		t.Errorf("expected the start of the first batch, got %v and %v", calls.StartTimestamp(), duration.StartTimestamp())
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	td := newTraces(10, time.Millisecond)
+	span := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().AppendEmpty()
+	span.SetName("GET /cart")
+	span.SetKind(ptrace.SpanKindServer)
+	span.Attributes().PutStr("http.method", "POST")
+
+	md, err := Convert(td, Config{Dimensions: []string{"http.method", "missing"}}, nil, now)
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+	dps := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints()
+	if dps.Len() != 2 {
+		t.Fatalf("expected 2 series, got %d", dps.Len())
+	}
+	want := map[string]any{
+		"span.name":   "GET /cart",
+		"span.kind":   "Server",
+		"status.code": "Unset",
+		"http.method": "GET",
+		"missing":     "",
+	}
+	got := dps.At(0).Attributes().AsRaw()
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("expected attribute %s=%v, got %v", k, v, got[k])
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	td := newTraces(10, time.Millisecond)
+	for name, cfg := range map[string]Config{
+		"unknown temporality": {Temporality: "gauge"},
+		"unsorted buckets":    {Buckets: []float64{10, 1}},
+		"missing store":       {Temporality: TemporalityCumulative},
+	} {
+		if _, err := Convert(td, cfg, nil, now); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
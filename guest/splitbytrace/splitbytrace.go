@@ -0,0 +1,76 @@
+// Package splitbytrace partitions trace batches so each trace becomes a batch
+// of its own, e.g. to fan traces out to downstream components keyed by trace
+// ID.
+//
+// Emit passes the batches downstream as separate results of the processor.
+// The processor should then return the zero ptrace.Traces, as the host
+// ignores its result once batches are appended.
+package splitbytrace
+
+import (
+	"github.com/otelwasm/otelwasm/guest/imports"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Split returns a batch per trace of td, in the order the traces first appear.
+// The spans keep their resource and scope.
+func Split(td ptrace.Traces) []ptrace.Traces {
+	var batches []ptrace.Traces
+	index := make(map[pcommon.TraceID]int)
+
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			// The scope spans of each batch for the current scope, created
+			// with the first span of the trace in the scope.
+			scopes := make(map[pcommon.TraceID]ptrace.ScopeSpans)
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+				id := span.TraceID()
+				dest, ok := scopes[id]
+				if !ok {
+					n, ok := index[id]
+					if !ok {
+						n = len(batches)
+						index[id] = n
+						batches = append(batches, ptrace.NewTraces())
+					}
+					dest = resourceSpans(batches[n], rs).ScopeSpans().AppendEmpty()
+					ss.Scope().CopyTo(dest.Scope())
+					dest.SetSchemaUrl(ss.SchemaUrl())
+					scopes[id] = dest
+				}
+				span.CopyTo(dest.Spans().AppendEmpty())
+			}
+		}
+	}
+	return batches
+}
+
+// resourceSpans returns the resource spans of batch for rs, appending a copy
+// of its resource unless it is the last resource of batch already.
+func resourceSpans(batch ptrace.Traces, rs ptrace.ResourceSpans) ptrace.ResourceSpans {
+	if n := batch.ResourceSpans().Len(); n > 0 {
+		last := batch.ResourceSpans().At(n - 1)
+		if last.SchemaUrl() == rs.SchemaUrl() && last.Resource().Attributes().Equal(rs.Resource().Attributes()) {
+			return last
+		}
+	}
+	dest := batch.ResourceSpans().AppendEmpty()
+	rs.Resource().CopyTo(dest.Resource())
+	dest.SetSchemaUrl(rs.SchemaUrl())
+	return dest
+}
+
+// Emit splits td by trace and appends each batch to the results of the
+// processor, so the host passes them downstream on their own. It returns the
+// number of batches.
+func Emit(td ptrace.Traces) int {
+	batches := Split(td)
+	for _, batch := range batches {
+		imports.AppendResultTraces(batch)
+	}
+	return len(batches)
+}
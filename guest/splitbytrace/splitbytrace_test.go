@@ -0,0 +1,83 @@
+package splitbytrace
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func traceID(b byte) pcommon.TraceID {
+	return pcommon.TraceID{15: b}
+}
+
+// appendSpans appends a resource named service and a span per trace ID to
+// td.
+func appendSpans(td ptrace.Traces, service string, ids ...byte) {
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", service)
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName("scope")
+	for _, id := range ids {
+		span := ss.Spans().AppendEmpty()
+		span.SetTraceID(traceID(id))
+		span.SetName(service)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	td := ptrace.NewTraces()
+	appendSpans(td, "frontend", 1, 2, 1)
+	appendSpans(td, "backend", 2, 3)
+
+	batches := Split(td)
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(batches))
+	}
+
+	wantSpans := map[pcommon.TraceID]int{traceID(1): 2, traceID(2): 2, traceID(3): 1}
+	for i, batch := range batches {
+		ids := make(map[pcommon.TraceID]int)
+		for j := 0; j < batch.ResourceSpans().Len(); j++ {
+			rs := batch.ResourceSpans().At(j)
+			if _, ok := rs.Resource().Attributes().Get("service.name"); !ok {
+				t.Errorf("batch %d: expected the resource to be kept", i)
+			}
+			for k := 0; k < rs.ScopeSpans().Len(); k++ {
+				ss := rs.ScopeSpans().At(k)
+				if ss.Scope().Name() != "scope" {
+					t.Errorf("batch %d: expected the scope to be kept", i)
+				}
+				for l := 0; l < ss.Spans().Len(); l++ {
+					ids[ss.Spans().At(l).TraceID()]++
+				}
+			}
+		}
+		if len(ids) != 1 {
+			t.Fatalf("batch %d: expected a single trace, got %v", i, ids)
+		}
+		for id, n := range ids {
+			if n != wantSpans[id] {
+				t.Errorf("batch %d: expected %d spans of trace %v, got %d", i, wantSpans[id], id, n)
+			}
+			delete(wantSpans, id)
+		}
+	}
+	if len(wantSpans) != 0 {
+		t.Errorf("expected every trace to have a batch, missing %v", wantSpans)
+	}
+
+	// Trace 2 spans both resources.
+	if got := batches[1].ResourceSpans().Len(); got != 2 {
+		t.Errorf("expected the batch of trace 2 to hold 2 resources, got %d", got)
+	}
+	if got := batches[0].ResourceSpans().Len(); got != 1 {
+		t.Errorf("expected the batch of trace 1 to hold 1 resource, got %d", got)
+	}
+}
+
+func TestSplitEmpty(t *testing.T) {
+	if batches := Split(ptrace.NewTraces()); len(batches) != 0 {
+		t.Errorf("expected no batch, got %d", len(batches))
+	}
+}
@@ -0,0 +1,71 @@
+// Package state keeps guest state across calls in a key-value store of the
+// host, e.g. the cursor of a receiver. Unlike globals, the state is
+// observable by the host and, if the component configures a state path,
+// survives restarts.
+//
+// The store is shared by the concurrent calls of the guest: each operation
+// is atomic, and the last write of a key wins. The host limits the number of
+// keys and the size of the values.
+package state
+
+import (
+	"errors"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+var (
+	// ErrLimitExceeded is returned when setting a value too large, or a new
+	// key in a full store.
+	ErrLimitExceeded = errors.New("state limit exceeded")
+	// ErrNotPersisted is returned when the host failed to persist the store.
+	// The value is set regardless, but won't survive a restart.
+	ErrNotPersisted = errors.New("state not persisted")
+)
+
+// The status codes returned by the host on set.
+const (
+	statusOK uint32 = iota
+	statusLimitExceeded
+	statusPersistFailed
+)
+
+// The host functions, replaced in tests.
+var (
+	kvGet = imports.KVGet
+	kvSet = imports.KVSet
+)
+
+// Get returns the value of key. The boolean is false if key isn't set.
+func Get(key string) ([]byte, bool) {
+	return kvGet(key)
+}
+
+// GetString returns the value of key as a string. The boolean is false if
+// key isn't set.
+func GetString(key string) (string, bool) {
+	value, ok := Get(key)
+	return string(value), ok
+}
+
+// Set sets the value of key. An empty value deletes key.
+func Set(key string, value []byte) error {
+	switch kvSet(key, value) {
+	case statusOK:
+		return nil
+	case statusLimitExceeded:
+		return ErrLimitExceeded
+	default:
+		return ErrNotPersisted
+	}
+}
+
+// SetString sets the value of key to a string. An empty value deletes key.
+func SetString(key, value string) error {
+	return Set(key, []byte(value))
+}
+
+// Delete deletes key.
+func Delete(key string) error {
+	return Set(key, nil)
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeHost replaces the host functions with an in-memory store, limiting the
+// values to maxValueSize bytes.
+func fakeHost(t *testing.T, maxValueSize int) map[string][]byte {
+	entries := make(map[string][]byte)
+	origGet, origSet := kvGet, kvSet
+	t.Cleanup(func() { kvGet, kvSet = origGet, origSet })
+
+	kvGet = func(key string) ([]byte, bool) {
+		value, ok := entries[key]
+		return value, ok
+	}
+	kvSet = func(key string, value []byte) uint32 {
+		if len(value) == 0 {
+			delete(entries, key)
+			return statusOK
+		}
+		if len(value) > maxValueSize {
+			return statusLimitExceeded
+		}
+		entries[key] = value
+		return statusOK
+	}
+	return entries
+}
+
+func TestSetGet(t *testing.T) {
+	fakeHost(t, 64)
+
+	if _, ok := GetString("cursor"); ok {
+		t.Fatal("expected the key to be unset")
+	}
+	if err := SetString("cursor", "logs/2024/01/object-42.json"); err != nil {
+		t.Fatalf("failed to set the key: %v", err)
+	}
+	if value, ok := GetString("cursor"); !ok || value != "logs/2024/01/object-42.json" {
+		t.Errorf("expected the value set, got %q (%v)", value, ok)
+	}
+
+	if err := Delete("cursor"); err != nil {
+		t.Fatalf("failed to delete the key: %v", err)
+	}
+	if _, ok := Get("cursor"); ok {
+		t.Error("expected the key to be deleted")
+	}
+}
+
+func TestSetLimitExceeded(t *testing.T) {
+	fakeHost(t, 4)
+
+	if err := SetString("cursor", "too large"); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+func TestSetNotPersisted(t *testing.T) {
+	fakeHost(t, 64)
+	kvSet = func(string, []byte) uint32 { return statusPersistFailed }
+
+	if err := SetString("cursor", "value"); !errors.Is(err, ErrNotPersisted) {
+		t.Errorf("expected ErrNotPersisted, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Package statsd converts metrics between the StatsD line protocol and OTLP,
+// so guests can bridge StatsD sources and sinks.
+//
+// Each line has the form "<name>:<value>|<type>[|@<sample rate>][|#<tags>]".
+// Counters ("c") become delta monotonic sums whose value is scaled by the
+// sample rate, gauges ("g") become gauges and timers ("ms") become histograms
+// without buckets whose count reflects the sample rate.
+package statsd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// StatsD metric types.
+const (
+	TypeCounter = "c"
+	TypeGauge   = "g"
+	TypeTimer   = "ms"
+)
+
+// TimerUnit is the unit of the histograms converted from timers.
+const TimerUnit = "ms"
+
+// Parse converts newline separated StatsD lines into metrics. Empty lines are
+// ignored. Lines sharing the same name and type are converted into data
+// points of the same metric.
+func Parse(data string, now pcommon.Timestamp) (pmetric.Metrics, error) {
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	index := make(map[string]pmetric.Metric)
+
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := parseLine(line, now, ms, index); err != nil {
+			return pmetric.Metrics{}, fmt.Errorf("statsd: line %d: %w", i+1, err)
+		}
+	}
+	return md, nil
+}
+
+func parseLine(line string, now pcommon.Timestamp, ms pmetric.MetricSlice, index map[string]pmetric.Metric) error {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("missing metric name")
+	}
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 {
+		return fmt.Errorf("missing metric type")
+	}
+	rawValue, typ := fields[0], fields[1]
+
+	rate := 1.0
+	attrs := pcommon.NewMap()
+	for _, field := range fields[2:] {
+		switch {
+		case strings.HasPrefix(field, "@"):
+			r, err := strconv.ParseFloat(field[1:], 64)
+			if err != nil || r <= 0 || r > 1 {
+				return fmt.Errorf("invalid sample rate: %s", field)
+			}
+			rate = r
+		case strings.HasPrefix(field, "#"):
+			for _, tag := range strings.Split(field[1:], ",") {
+				k, v, _ := strings.Cut(tag, ":")
+				attrs.PutStr(k, v)
+			}
+		default:
+			return fmt.Errorf("invalid field: %s", field)
+		}
+	}
+
+	value, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil {
+		return fmt.Errorf("invalid value: %s", rawValue)
+	}
+
+	key := typ + "|" + name
+	m, ok := index[key]
+	if !ok {
+		m = ms.AppendEmpty()
+		m.SetName(name)
+		index[key] = m
+	}
+
+	switch typ {
+	case TypeCounter:
+		if !ok {
+			sum := m.SetEmptySum()
+			sum.SetIsMonotonic(true)
+			sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		}
+		dp := m.Sum().DataPoints().AppendEmpty()
+		scaled := value / rate
+		if i, err := strconv.ParseInt(rawValue, 10, 64); err == nil && rate == 1 {
+			dp.SetIntValue(i)
+		} else {
+			dp.SetDoubleValue(scaled)
+		}
+		dp.SetTimestamp(now)
+		attrs.MoveTo(dp.Attributes())
+	case TypeGauge:
+		if strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-") {
+			return fmt.Errorf("relative gauges are not supported: %s", rawValue)
+		}
+		if !ok {
+			m.SetEmptyGauge()
+		}
+		dp := m.Gauge().DataPoints().AppendEmpty()
+		dp.SetDoubleValue(value)
+		dp.SetTimestamp(now)
+		attrs.MoveTo(dp.Attributes())
+	case TypeTimer:
+		if !ok {
+			m.SetUnit(TimerUnit)
+			m.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		}
+		count := uint64(math.Round(1 / rate))
+		dp := m.Histogram().DataPoints().AppendEmpty()
+		dp.SetCount(count)
+		dp.SetSum(value * float64(count))
+		dp.SetMin(value)
+		dp.SetMax(value)
+		dp.SetTimestamp(now)
+		attrs.MoveTo(dp.Attributes())
+	default:
+		return fmt.Errorf("unsupported metric type: %s", typ)
+	}
+	return nil
+}
+
+// Format converts metrics into newline terminated StatsD lines, one per data
+// point. Sums become counters, except cumulative sums that become gauges as
+// their values are absolute. Histograms become timers whose value is the mean
+// and whose sample rate reflects the count. Other metric types are skipped.
+func Format(md pmetric.Metrics) string {
+	var b strings.Builder
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				formatMetric(&b, ms.At(k))
+			}
+		}
+	}
+	return b.String()
+}
+
+func formatMetric(b *strings.Builder, m pmetric.Metric) {
+	switch m.Type() {
+	case pmetric.MetricTypeSum:
+		typ := TypeCounter
+		if m.Sum().AggregationTemporality() == pmetric.AggregationTemporalityCumulative {
+			typ = TypeGauge
+		}
+		dps := m.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			writeLine(b, m.Name(), numberValue(dps.At(i)), typ, 1, dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeGauge:
+		dps := m.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			writeLine(b, m.Name(), numberValue(dps.At(i)), TypeGauge, 1, dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		dps := m.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			if dp.Count() == 0 {
+				continue
+			}
+			mean := dp.Sum() / float64(dp.Count())
+			writeLine(b, m.Name(), formatFloat(mean), TypeTimer, 1/float64(dp.Count()), dp.Attributes())
+		}
+	}
+}
+
+func numberValue(dp pmetric.NumberDataPoint) string {
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		return strconv.FormatInt(dp.IntValue(), 10)
+	}
+	return formatFloat(dp.DoubleValue())
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func writeLine(b *strings.Builder, name, value, typ string, rate float64, attrs pcommon.Map) {
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(typ)
+	if rate < 1 {
+		b.WriteString("|@")
+		b.WriteString(formatFloat(rate))
+	}
+	if attrs.Len() > 0 {
+		b.WriteString("|#")
+		first := true
+		attrs.Range(func(k string, v pcommon.Value) bool {
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			b.WriteString(k)
+			if s := v.AsString(); s != "" {
+				b.WriteByte(':')
+				b.WriteString(s)
+			}
+			return true
+		})
+	}
+	b.WriteByte('\n')
+}
@@ -0,0 +1,163 @@
+package statsd
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const now = pcommon.Timestamp(42)
+
+func parseMetric(t *testing.T, line string) pmetric.Metric {
+	t.Helper()
+	md, err := Parse(line, now)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", line, err)
+	}
+	if md.MetricCount() != 1 {
+		t.Fatalf("expected 1 metric, got %d", md.MetricCount())
+	}
+	return md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+}
+
+func TestParseCounter(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		wantInt    int64
+		wantDouble float64
+	}{
+		{name: "int", line: "requests:3|c", wantInt: 3},
+		{name: "double", line: "requests:1.5|c", wantDouble: 1.5},
+		{name: "sampled", line: "requests:3|c|@0.5", wantDouble: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := parseMetric(t, tt.line)
+			if m.Type() != pmetric.MetricTypeSum {
+				t.Fatalf("expected sum, got %v", m.Type())
+			}
+			if !m.Sum().IsMonotonic() || m.Sum().AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+				t.Error("expected delta monotonic sum")
+			}
+			dp := m.Sum().DataPoints().At(0)
+			if dp.Timestamp() != now {
+				t.Errorf("expected timestamp %v, got %v", now, dp.Timestamp())
+			}
+			if dp.IntValue() != tt.wantInt || dp.DoubleValue() != tt.wantDouble {
+				t.Errorf("expected %d/%v, got %d/%v", tt.wantInt, tt.wantDouble, dp.IntValue(), dp.DoubleValue())
+			}
+		})
+	}
+}
+
+func TestParseGauge(t *testing.T) {
+	m := parseMetric(t, "temperature:21.5|g|#room:kitchen,heated")
+	if m.Type() != pmetric.MetricTypeGauge {
+		t.Fatalf("expected gauge, got %v", m.Type())
+	}
+	dp := m.Gauge().DataPoints().At(0)
+	if dp.DoubleValue() != 21.5 {
+		t.Errorf("expected 21.5, got %v", dp.DoubleValue())
+	}
+	want := map[string]any{"room": "kitchen", "heated": ""}
+	if got := dp.Attributes().AsRaw(); len(got) != len(want) || got["room"] != want["room"] || got["heated"] != want["heated"] {
+		t.Errorf("expected attributes %v, got %v", want, got)
+	}
+}
+
+func TestParseTimer(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantCount uint64
+		wantSum   float64
+	}{
+		{name: "unsampled", line: "latency:320|ms", wantCount: 1, wantSum: 320},
+		{name: "sampled", line: "latency:320|ms|@0.1", wantCount: 10, wantSum: 3200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := parseMetric(t, tt.line)
+			if m.Type() != pmetric.MetricTypeHistogram {
+				t.Fatalf("expected histogram, got %v", m.Type())
+			}
+			if m.Unit() != TimerUnit {
+				t.Errorf("expected unit %s, got %s", TimerUnit, m.Unit())
+			}
+			dp := m.Histogram().DataPoints().At(0)
+			if dp.Count() != tt.wantCount || dp.Sum() != tt.wantSum {
+				t.Errorf("expected count %d and sum %v, got %d and %v", tt.wantCount, tt.wantSum, dp.Count(), dp.Sum())
+			}
+			if dp.Min() != 320 || dp.Max() != 320 {
+				t.Errorf("expected min and max 320, got %v and %v", dp.Min(), dp.Max())
+			}
+		})
+	}
+}
+
+func TestParseGroupsLines(t *testing.T) {
+	md, err := Parse("requests:1|c\nrequests:2|c|#code:500\n\nrequests:5|g\n", now)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if md.MetricCount() != 2 {
+		t.Errorf("expected 2 metrics, got %d", md.MetricCount())
+	}
+	if md.DataPointCount() != 3 {
+		t.Errorf("expected 3 data points, got %d", md.DataPointCount())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, line := range []string{
+		"requests",
+		":1|c",
+		"requests:1",
+		"requests:one|c",
+		"requests:1|s",
+		"requests:1|c|@2",
+		"requests:1|c|x",
+		"temperature:+1|g",
+	} {
+		if _, err := Parse(line, now); err == nil {
+			t.Errorf("expected an error parsing %q", line)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	lines := "requests:3|c|#code:200\n" +
+		"ratio:0.25|c|@0.5\n" +
+		"temperature:21.5|g|#room:kitchen,heated\n" +
+		"latency:320|ms|@0.1\n"
+	md, err := Parse(lines, now)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	// The sampled counter was scaled when parsed, so it is formatted unsampled.
+	want := "requests:3|c|#code:200\n" +
+		"ratio:0.5|c\n" +
+		"temperature:21.5|g|#room:kitchen,heated\n" +
+		"latency:320|ms|@0.1\n"
+	if got := Format(md); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestFormatCumulativeSum(t *testing.T) {
+	md := pmetric.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("total")
+	sum := m.SetEmptySum()
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	sum.DataPoints().AppendEmpty().SetIntValue(7)
+
+	if got, want := Format(md), "total:7|g\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
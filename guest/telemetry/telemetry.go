@@ -0,0 +1,52 @@
+// Package telemetry reads the component running the guest and the resource
+// of the collector, e.g. to tag the data the guest exports with the service
+// name and version of the collector.
+//
+// The host serves them with the getComponentInfo host function, so guests
+// using the package don't run on hosts predating it.
+package telemetry
+
+import (
+	"encoding/json"
+
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+)
+
+// componentInfo reads the description of the component, replaced in tests.
+var componentInfo = imports.ComponentInfo
+
+type info struct {
+	ComponentID        string         `json:"component_id"`
+	ResourceAttributes map[string]any `json:"resource_attributes"`
+}
+
+func read() info {
+	var i info
+	// The host always serves valid JSON, a decoding failure leaves i empty.
+	_ = json.Unmarshal(componentInfo(), &i)
+	return i
+}
+
+// GetComponentID returns the ID of the component running the guest, e.g.
+// "wasm/traces", or an empty string if the host doesn't tell.
+func GetComponentID() string {
+	return read().ComponentID
+}
+
+// GetResourceAttributes returns the attributes of the resource of the
+// collector, with the values of pcommon.Map.AsRaw decoded from JSON, so
+// numbers are float64.
+func GetResourceAttributes() map[string]any {
+	attrs := read().ResourceAttributes
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+	return attrs
+}
+
+// GetServiceName returns the service.name resource attribute of the
+// collector, or an empty string if unset.
+func GetServiceName() string {
+	name, _ := read().ResourceAttributes["service.name"].(string)
+	return name
+}
@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func fakeHost(t *testing.T, info string) {
+	orig := componentInfo
+	t.Cleanup(func() { componentInfo = orig })
+	componentInfo = func() []byte { return []byte(info) }
+}
+
+func TestComponentInfo(t *testing.T) {
+	fakeHost(t, `{"component_id":"wasm/traces","resource_attributes":{"service.name":"otelcol","service.version":"1.2.3","host.cpus":8}}`)
+
+	if got := GetComponentID(); got != "wasm/traces" {
+		t.Errorf("expected the component ID wasm/traces, got %q", got)
+	}
+	if got := GetServiceName(); got != "otelcol" {
+		t.Errorf("expected the service name otelcol, got %q", got)
+	}
+	want := map[string]any{"service.name": "otelcol", "service.version": "1.2.3", "host.cpus": float64(8)}
+	if got := GetResourceAttributes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestComponentInfoEmpty(t *testing.T) {
+	fakeHost(t, "")
+
+	if id, name := GetComponentID(), GetServiceName(); id != "" || name != "" {
+		t.Errorf("expected no component ID nor service name, got %q and %q", id, name)
+	}
+	if got := GetResourceAttributes(); got == nil || len(got) != 0 {
+		t.Errorf("expected no attribute, got %v", got)
+	}
+}
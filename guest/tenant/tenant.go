@@ -0,0 +1,160 @@
+// Package tenant enforces per-tenant isolation in multi-tenant guests by
+// checking that every record carries the tenant attribute of the configured
+// tenant, and dropping or flagging the records that don't.
+package tenant
+
+import (
+	"fmt"
+
+	"github.com/otelwasm/otelwasm/guest/internal/datapoints"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Action is what happens to a record violating the tenant isolation.
+type Action string
+
+const (
+	// ActionDrop removes violating records. Scopes and resources left empty
+	// are removed too.
+	ActionDrop Action = "drop"
+	// ActionFlag keeps violating records and sets the flag attribute on them.
+	ActionFlag Action = "flag"
+)
+
+// DefaultFlagAttribute is the attribute set on flagged records if
+// Config.FlagAttribute is empty.
+const DefaultFlagAttribute = "otelwasm.tenant.violation"
+
+// Violation describes why a record violates the tenant isolation. It is the
+// value of the flag attribute of flagged records.
+type Violation string
+
+const (
+	// ViolationMissing means the record doesn't carry the tenant attribute.
+	ViolationMissing Violation = "missing"
+	// ViolationMismatch means the record belongs to another tenant.
+	ViolationMismatch Violation = "mismatch"
+)
+
+// Config is the configuration of the tenant isolation.
+type Config struct {
+	// Attribute is the attribute holding the tenant of a record. It is looked
+	// up in the record attributes first, then in the resource attributes.
+	Attribute string `json:"attribute"`
+	// Tenant is the tenant records must belong to.
+	Tenant string `json:"tenant"`
+	// Action is applied to violating records. Defaults to ActionDrop.
+	Action Action `json:"action"`
+	// FlagAttribute is the attribute set on flagged records.
+	// Defaults to DefaultFlagAttribute.
+	FlagAttribute string `json:"flag_attribute"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.Attribute == "" {
+		return fmt.Errorf("attribute is required")
+	}
+	if c.Tenant == "" {
+		return fmt.Errorf("tenant is required")
+	}
+	switch c.Action {
+	case "", ActionDrop, ActionFlag:
+	default:
+		return fmt.Errorf("invalid action: %s", c.Action)
+	}
+	return nil
+}
+
+func (c *Config) check(resource, record pcommon.Map) Violation {
+	v, ok := record.Get(c.Attribute)
+	if !ok {
+		v, ok = resource.Get(c.Attribute)
+	}
+	switch {
+	case !ok:
+		return ViolationMissing
+	case v.AsString() != c.Tenant:
+		return ViolationMismatch
+	default:
+		return ""
+	}
+}
+
+// enforce applies the action to a single record and reports whether the
+// record must be removed and whether it was mutated.
+func (c *Config) enforce(resource, record pcommon.Map) (remove, mutated bool) {
+	violation := c.check(resource, record)
+	if violation == "" {
+		return false, false
+	}
+	if c.Action == ActionFlag {
+		flag := c.FlagAttribute
+		if flag == "" {
+			flag = DefaultFlagAttribute
+		}
+		record.PutStr(flag, string(violation))
+		return false, true
+	}
+	return true, true
+}
+
+// Traces enforces the tenant isolation on every span of td and reports
+// whether td was mutated.
+func Traces(td ptrace.Traces, cfg Config) (mutated bool) {
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		resource := rs.Resource().Attributes()
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				remove, m := cfg.enforce(resource, span.Attributes())
+				mutated = mutated || m
+				return remove
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return mutated
+}
+
+// Metrics enforces the tenant isolation on every data point of md and
+// reports whether md was mutated.
+func Metrics(md pmetric.Metrics, cfg Config) (mutated bool) {
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		resource := rm.Resource().Attributes()
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(m pmetric.Metric) bool {
+				datapoints.RemoveIf(m, func(attrs pcommon.Map) bool {
+					remove, dm := cfg.enforce(resource, attrs)
+					mutated = mutated || dm
+					return remove
+				})
+				return datapoints.Len(m) == 0
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return mutated
+}
+
+// Logs enforces the tenant isolation on every log record of ld and reports
+// whether ld was mutated.
+func Logs(ld plog.Logs, cfg Config) (mutated bool) {
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		resource := rl.Resource().Attributes()
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				remove, m := cfg.enforce(resource, lr.Attributes())
+				mutated = mutated || m
+				return remove
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return mutated
+}
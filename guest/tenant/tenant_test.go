@@ -0,0 +1,170 @@
+package tenant
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const tenantKey = "tenant.id"
+
+// newTraces returns traces with one span per tenant. An empty tenant means
+// the span doesn't carry the tenant attribute.
+func newTraces(tenants ...string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for _, tenant := range tenants {
+		span := spans.AppendEmpty()
+		span.SetName(tenant)
+		if tenant != "" {
+			span.Attributes().PutStr(tenantKey, tenant)
+		}
+	}
+	return td
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "valid", cfg: Config{Attribute: tenantKey, Tenant: "acme"}},
+		{name: "flag", cfg: Config{Attribute: tenantKey, Tenant: "acme", Action: ActionFlag}},
+		{name: "missing attribute", cfg: Config{Tenant: "acme"}, wantErr: true},
+		{name: "missing tenant", cfg: Config{Attribute: tenantKey}, wantErr: true},
+		{name: "invalid action", cfg: Config{Attribute: tenantKey, Tenant: "acme", Action: "keep"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTracesDrop(t *testing.T) {
+	cfg := Config{Attribute: tenantKey, Tenant: "acme"}
+
+	tests := []struct {
+		name        string
+		tenants     []string
+		wantSpans   int
+		wantMutated bool
+	}{
+		{name: "present", tenants: []string{"acme", "acme"}, wantSpans: 2},
+		{name: "missing", tenants: []string{"acme", ""}, wantSpans: 1, wantMutated: true},
+		{name: "mismatched", tenants: []string{"initech", "acme"}, wantSpans: 1, wantMutated: true},
+		{name: "all violating", tenants: []string{"initech", ""}, wantSpans: 0, wantMutated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := newTraces(tt.tenants...)
+			if mutated := Traces(td, cfg); mutated != tt.wantMutated {
+				t.Errorf("expected mutated %v, got %v", tt.wantMutated, mutated)
+			}
+			if td.SpanCount() != tt.wantSpans {
+				t.Errorf("expected %d spans, got %d", tt.wantSpans, td.SpanCount())
+			}
+			if tt.wantSpans == 0 && td.ResourceSpans().Len() != 0 {
+				t.Errorf("expected empty resource spans to be removed, got %d", td.ResourceSpans().Len())
+			}
+		})
+	}
+}
+
+func TestTracesFlag(t *testing.T) {
+	cfg := Config{Attribute: tenantKey, Tenant: "acme", Action: ActionFlag}
+	td := newTraces("acme", "", "initech")
+
+	if !Traces(td, cfg) {
+		t.Error("expected traces to be mutated")
+	}
+
+	spans := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	if spans.Len() != 3 {
+		t.Fatalf("expected 3 spans, got %d", spans.Len())
+	}
+	want := []string{"", string(ViolationMissing), string(ViolationMismatch)}
+	for i, w := range want {
+		v, ok := spans.At(i).Attributes().Get(DefaultFlagAttribute)
+		if w == "" {
+			if ok {
+				t.Errorf("span %d: expected no flag, got %q", i, v.Str())
+			}
+			continue
+		}
+		if v.Str() != w {
+			t.Errorf("span %d: expected flag %q, got %q", i, w, v.Str())
+		}
+	}
+}
+
+func TestResourceAttribute(t *testing.T) {
+	cfg := Config{Attribute: tenantKey, Tenant: "acme"}
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr(tenantKey, "acme")
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty()
+	// The record attribute takes precedence over the resource one.
+	records.AppendEmpty().Attributes().PutStr(tenantKey, "initech")
+
+	if !Logs(ld, cfg) {
+		t.Error("expected logs to be mutated")
+	}
+	if ld.LogRecordCount() != 1 {
+		t.Errorf("expected 1 log record, got %d", ld.LogRecordCount())
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	cfg := Config{Attribute: tenantKey, Tenant: "acme", FlagAttribute: "violation"}
+
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	dps := ms.AppendEmpty().SetEmptyGauge().DataPoints()
+	dps.AppendEmpty().Attributes().PutStr(tenantKey, "acme")
+	dps.AppendEmpty().Attributes().PutStr(tenantKey, "initech")
+	ms.AppendEmpty().SetEmptySum().DataPoints().AppendEmpty()
+
+	t.Run("drop", func(t *testing.T) {
+		md := pmetric.NewMetrics()
+		ms.CopyTo(md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics())
+		if !Metrics(md, cfg) {
+			t.Error("expected metrics to be mutated")
+		}
+		if md.DataPointCount() != 1 {
+			t.Errorf("expected 1 data point, got %d", md.DataPointCount())
+		}
+		// The sum lost its only data point, so it must be removed.
+		if md.MetricCount() != 1 {
+			t.Errorf("expected 1 metric, got %d", md.MetricCount())
+		}
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		cfg := cfg
+		cfg.Action = ActionFlag
+		if !Metrics(md, cfg) {
+			t.Error("expected metrics to be mutated")
+		}
+		if md.DataPointCount() != 3 {
+			t.Errorf("expected 3 data points, got %d", md.DataPointCount())
+		}
+		v, _ := ms.At(0).Gauge().DataPoints().At(1).Attributes().Get("violation")
+		if v.Str() != string(ViolationMismatch) {
+			t.Errorf("expected flag %q, got %q", ViolationMismatch, v.Str())
+		}
+		v, _ = ms.At(1).Sum().DataPoints().At(0).Attributes().Get("violation")
+		if v.Str() != string(ViolationMissing) {
+			t.Errorf("expected flag %q, got %q", ViolationMissing, v.Str())
+		}
+	})
+}
@@ -0,0 +1,116 @@
+// Package timefmt converts between textual timestamps and pcommon
+// timestamps, for guests parsing logs or payloads of receivers.
+//
+// Layouts are Go time layouts, e.g. time.RFC3339, or one of the Unix epoch
+// layouts parsing integer counts since the epoch.
+package timefmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	// LayoutUnix parses seconds since the Unix epoch, with an optional
+	// fraction, e.g. "1700000000.25".
+	LayoutUnix = "unix"
+	// LayoutUnixMilli parses milliseconds since the Unix epoch.
+	LayoutUnixMilli = "unix_ms"
+	// LayoutUnixMicro parses microseconds since the Unix epoch.
+	LayoutUnixMicro = "unix_us"
+	// LayoutUnixNano parses nanoseconds since the Unix epoch.
+	LayoutUnixNano = "unix_ns"
+)
+
+// DefaultLayouts are the layouts tried if none are configured.
+var DefaultLayouts = []string{time.RFC3339Nano, LayoutUnix}
+
+// unitLayouts maps the integer Unix epoch layouts to their unit.
+var unitLayouts = map[string]time.Duration{
+	LayoutUnixMilli: time.Millisecond,
+	LayoutUnixMicro: time.Microsecond,
+	LayoutUnixNano:  time.Nanosecond,
+}
+
+// Config is the configuration of the parsing.
+type Config struct {
+	// Layouts are tried in order until one parses the timestamp. Defaults to
+	// DefaultLayouts.
+	Layouts []string `json:"layouts"`
+}
+
+// Parse parses value with the first matching layout of the configuration.
+func (c Config) Parse(value string) (pcommon.Timestamp, error) {
+	layouts := c.Layouts
+	if len(layouts) == 0 {
+		layouts = DefaultLayouts
+	}
+	for _, layout := range layouts {
+		if ts, err := ParseLayout(value, layout); err == nil {
+			return ts, nil
+		}
+	}
+	return 0, fmt.Errorf("timestamp %q matches none of the layouts %q", value, layouts)
+}
+
+// ParseLayout parses value with layout. Timestamps before the Unix epoch
+// can't be represented and are an error.
+func ParseLayout(value, layout string) (pcommon.Timestamp, error) {
+	if layout == LayoutUnix {
+		return parseUnix(value)
+	}
+	if unit, ok := unitLayouts[layout]; ok {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s timestamp %q", layout, value)
+		}
+		return pcommon.Timestamp(n * uint64(unit)), nil
+	}
+
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return 0, err
+	}
+	if t.Before(time.Unix(0, 0)) {
+		return 0, fmt.Errorf("timestamp %q is before the Unix epoch", value)
+	}
+	return pcommon.NewTimestampFromTime(t), nil
+}
+
+// parseUnix parses seconds since the Unix epoch. The fraction is parsed as
+// decimal digits, rather than a float, so nanoseconds aren't rounded.
+func parseUnix(value string) (pcommon.Timestamp, error) {
+	secs, frac, _ := strings.Cut(value, ".")
+	s, err := strconv.ParseUint(secs, 10, 64)
+	if err != nil || len(frac) > 9 {
+		return 0, fmt.Errorf("invalid %s timestamp %q", LayoutUnix, value)
+	}
+	var ns uint64
+	if frac != "" {
+		ns, err = strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s timestamp %q", LayoutUnix, value)
+		}
+	}
+	return pcommon.Timestamp(s*uint64(time.Second) + ns), nil
+}
+
+// Format formats ts with layout. Times are formatted in UTC.
+func Format(ts pcommon.Timestamp, layout string) string {
+	if layout == LayoutUnix {
+		secs, ns := uint64(ts)/uint64(time.Second), uint64(ts)%uint64(time.Second)
+		if ns == 0 {
+			return strconv.FormatUint(secs, 10)
+		}
+		frac := strings.TrimRight(fmt.Sprintf("%09d", ns), "0")
+		return strconv.FormatUint(secs, 10) + "." + frac
+	}
+	if unit, ok := unitLayouts[layout]; ok {
+		return strconv.FormatUint(uint64(ts)/uint64(unit), 10)
+	}
+	return ts.AsTime().UTC().Format(layout)
+}
@@ -0,0 +1,89 @@
+package timefmt
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// ts is 2023-11-14T22:13:20.25Z.
+var ts = pcommon.NewTimestampFromTime(time.Unix(1700000000, 250000000))
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		layout  string
+		want    pcommon.Timestamp
+		wantErr bool
+	}{
+		{name: "rfc3339", value: "2023-11-14T22:13:20.25Z", layout: time.RFC3339, want: ts},
+		{name: "rfc3339 offset", value: "2023-11-14T23:13:20.25+01:00", layout: time.RFC3339Nano, want: ts},
+		{name: "unix", value: "1700000000.25", layout: LayoutUnix, want: ts},
+		{name: "unix seconds", value: "1700000000", layout: LayoutUnix, want: pcommon.Timestamp(1700000000 * time.Second)},
+		{name: "unix millis", value: "1700000000250", layout: LayoutUnixMilli, want: ts},
+		{name: "unix micros", value: "1700000000250000", layout: LayoutUnixMicro, want: ts},
+		{name: "unix nanos", value: "1700000000250000000", layout: LayoutUnixNano, want: ts},
+		{name: "custom", value: "14/Nov/2023:22:13:20.25 +0000", layout: "02/Jan/2006:15:04:05 -0700", want: ts},
+		{name: "negative unix", value: "-1", layout: LayoutUnix, wantErr: true},
+		{name: "unix too precise", value: "1700000000.0000000001", layout: LayoutUnix, wantErr: true},
+		{name: "before epoch", value: "1969-12-31T23:59:59Z", layout: time.RFC3339, wantErr: true},
+		{name: "mismatch", value: "yesterday", layout: time.RFC3339, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLayout(tt.value, tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLayout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLayout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigParse(t *testing.T) {
+	for _, value := range []string{"2023-11-14T22:13:20.25Z", "1700000000.25"} {
+		got, err := Config{}.Parse(value)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", value, err)
+		}
+		if got != ts {
+			t.Errorf("Parse(%q) = %v, want %v", value, got, ts)
+		}
+	}
+
+	cfg := Config{Layouts: []string{time.DateTime, LayoutUnixMilli}}
+	if got, err := cfg.Parse("1700000000250"); err != nil || got != ts {
+		t.Errorf("Parse() = %v, %v, want %v", got, err, ts)
+	}
+	if _, err := cfg.Parse("2023-11-14T22:13:20Z"); err == nil {
+		t.Error("expected a value matching none of the layouts to fail")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{layout: time.RFC3339Nano, want: "2023-11-14T22:13:20.25Z"},
+		{layout: LayoutUnix, want: "1700000000.25"},
+		{layout: LayoutUnixMilli, want: "1700000000250"},
+		{layout: LayoutUnixNano, want: "1700000000250000000"},
+		{layout: "02/Jan/2006:15:04:05.00 -0700", want: "14/Nov/2023:22:13:20.25 +0000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			got := Format(ts, tt.layout)
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+			if back, err := ParseLayout(got, tt.layout); err != nil || back != ts {
+				t.Errorf("ParseLayout(Format()) = %v, %v, want %v", back, err, ts)
+			}
+		})
+	}
+}
@@ -26,8 +26,8 @@ var _ func() uint32 = _processTraces
 func _processTraces() uint32 {
 	traces := imports.CurrentTraces()
 	result, status := tracesprocessor.ProcessTraces(traces)
-	// If the result is not empty, set it in the host.
-	// In case of empty result, the result should be written inside the guest call.
+	// The zero result sets none, either because it was set during the call
+	// or to have the host pass its traces on unchanged.
 	if result != (ptrace.Traces{}) {
 		pubimports.SetResultTraces(result)
 	}
@@ -0,0 +1,35 @@
+package tracestometricsconnector
+
+import (
+	"runtime"
+
+	"github.com/otelwasm/otelwasm/guest/api"
+	pubimports "github.com/otelwasm/otelwasm/guest/imports"
+	"github.com/otelwasm/otelwasm/guest/internal/imports"
+	"github.com/otelwasm/otelwasm/guest/internal/plugin"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+var tracestometricsconnector api.TracesToMetricsConnector
+
+func SetPlugin(c api.TracesToMetricsConnector) {
+	if c == nil {
+		panic("nil TracesToMetricsConnector")
+	}
+	tracestometricsconnector = c
+	plugin.MustSet(c)
+}
+
+var _ func() uint32 = _connectTracesToMetrics
+
+//go:wasmexport connectTracesToMetrics
+func _connectTracesToMetrics() uint32 {
+	traces := imports.CurrentTraces()
+	result, status := tracestometricsconnector.ConnectTracesToMetrics(traces)
+	// The host passes nothing downstream if the result is empty.
+	if result != (pmetric.Metrics{}) {
+		pubimports.SetResultMetrics(result)
+	}
+	runtime.KeepAlive(result) // until ptr is no longer needed
+	return imports.StatusToCode(status)
+}
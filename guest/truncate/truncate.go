@@ -0,0 +1,171 @@
+// Package truncate enforces a maximum length on attribute string values, so
+// a runaway value, e.g. a dumped request body, doesn't blow up the size of
+// the telemetry.
+//
+// A truncated value ends with the configured marker, and its original
+// length is recorded in a sibling attribute named after the key with the
+// OriginalLengthSuffix. Attributes of all levels are truncated: resources,
+// scopes, spans, span events and links, metric data points and log records.
+// Only top-level string values are truncated, not those nested in slices or
+// maps.
+package truncate
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/otelwasm/otelwasm/guest/internal/datapoints"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// DefaultMarker is the marker appended to truncated values if none is
+	// configured.
+	DefaultMarker = "..."
+
+	// OriginalLengthSuffix is appended to the key of a truncated attribute
+	// to name the attribute holding its original length, in bytes.
+	OriginalLengthSuffix = ".original_length"
+)
+
+// Config is the configuration of the truncation.
+type Config struct {
+	// MaxLength is the maximum length of the values, in bytes, marker
+	// included.
+	MaxLength int `json:"max_length"`
+	// Marker is appended to the truncated values. Defaults to DefaultMarker.
+	Marker string `json:"marker"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.MaxLength <= 0 {
+		return fmt.Errorf("max_length must be positive")
+	}
+	return nil
+}
+
+// truncator truncates the attributes of a validated configuration.
+type truncator struct {
+	maxLength int
+	marker    string
+}
+
+func newTruncator(cfg Config) (*truncator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	marker := cfg.Marker
+	if marker == "" {
+		marker = DefaultMarker
+	}
+	// The marker is dropped if it doesn't leave room for any of the value.
+	if len(marker) >= cfg.MaxLength {
+		marker = ""
+	}
+	return &truncator{maxLength: cfg.MaxLength, marker: marker}, nil
+}
+
+// attributes truncates the values of attrs and reports whether attrs was
+// mutated.
+func (t *truncator) attributes(attrs pcommon.Map) (mutated bool) {
+	// The sibling attributes can't be added while ranging over attrs.
+	var keys []string
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		if v.Type() == pcommon.ValueTypeStr && len(v.Str()) > t.maxLength {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	for _, k := range keys {
+		v, _ := attrs.Get(k)
+		s := v.Str()
+		v.SetStr(cut(s, t.maxLength-len(t.marker)) + t.marker)
+		attrs.PutInt(k+OriginalLengthSuffix, int64(len(s)))
+	}
+	return len(keys) > 0
+}
+
+// cut returns the longest prefix of s of at most n bytes, not splitting a
+// UTF-8 encoded rune.
+func cut(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Traces truncates the attributes of td and reports whether td was mutated.
+func Traces(td ptrace.Traces, cfg Config) (mutated bool, err error) {
+	t, err := newTruncator(cfg)
+	if err != nil {
+		return false, err
+	}
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		mutated = t.attributes(rs.Resource().Attributes()) || mutated
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			mutated = t.attributes(ss.Scope().Attributes()) || mutated
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+				mutated = t.attributes(span.Attributes()) || mutated
+				for l := 0; l < span.Events().Len(); l++ {
+					mutated = t.attributes(span.Events().At(l).Attributes()) || mutated
+				}
+				for l := 0; l < span.Links().Len(); l++ {
+					mutated = t.attributes(span.Links().At(l).Attributes()) || mutated
+				}
+			}
+		}
+	}
+	return mutated, nil
+}
+
+// Metrics truncates the attributes of md and reports whether md was mutated.
+func Metrics(md pmetric.Metrics, cfg Config) (mutated bool, err error) {
+	t, err := newTruncator(cfg)
+	if err != nil {
+		return false, err
+	}
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		mutated = t.attributes(rm.Resource().Attributes()) || mutated
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			mutated = t.attributes(sm.Scope().Attributes()) || mutated
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				datapoints.RangeAttributes(sm.Metrics().At(k), func(attrs pcommon.Map) {
+					mutated = t.attributes(attrs) || mutated
+				})
+			}
+		}
+	}
+	return mutated, nil
+}
+
+// Logs truncates the attributes of ld and reports whether ld was mutated.
+func Logs(ld plog.Logs, cfg Config) (mutated bool, err error) {
+	t, err := newTruncator(cfg)
+	if err != nil {
+		return false, err
+	}
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		mutated = t.attributes(rl.Resource().Attributes()) || mutated
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			mutated = t.attributes(sl.Scope().Attributes()) || mutated
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				mutated = t.attributes(sl.LogRecords().At(k).Attributes()) || mutated
+			}
+		}
+	}
+	return mutated, nil
+}
@@ -0,0 +1,111 @@
+package truncate
+
+import (
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestConfigValidate(t *testing.T) {
+	if err := (&Config{MaxLength: 16}).Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+	if err := (&Config{}).Validate(); err == nil {
+		t.Error("expected missing max_length to fail")
+	}
+}
+
+func TestTraces(t *testing.T) {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.Attributes().PutStr("http.request.body", strings.Repeat("a", 32))
+	span.Attributes().PutInt("http.response.status_code", 200)
+	span.Events().AppendEmpty().Attributes().PutStr("exception.stacktrace", strings.Repeat("b", 20))
+
+	mutated, err := Traces(td, Config{MaxLength: 16})
+	if err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if !mutated {
+		t.Error("expected traces to be mutated")
+	}
+
+	want := map[string]any{
+		"http.request.body":                 strings.Repeat("a", 13) + "...",
+		"http.request.body.original_length": int64(32),
+		"http.response.status_code":         int64(200),
+	}
+	assertAttributes(t, span.Attributes(), want)
+	assertAttributes(t, span.Events().At(0).Attributes(), map[string]any{
+		"exception.stacktrace":                 strings.Repeat("b", 13) + "...",
+		"exception.stacktrace.original_length": int64(20),
+	})
+	assertAttributes(t, rs.Resource().Attributes(), map[string]any{"service.name": "checkout"})
+}
+
+func TestBelowLimit(t *testing.T) {
+	md := pmetric.NewMetrics()
+	dp := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptySum().DataPoints().AppendEmpty()
+	dp.Attributes().PutStr("url.path", strings.Repeat("p", 16))
+
+	mutated, err := Metrics(md, Config{MaxLength: 16})
+	if err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if mutated {
+		t.Error("expected metrics not to be mutated")
+	}
+	assertAttributes(t, dp.Attributes(), map[string]any{"url.path": strings.Repeat("p", 16)})
+}
+
+func TestLogs(t *testing.T) {
+	ld := plog.NewLogs()
+	lr := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	// "é" is 2 bytes long, so the value can't be cut at 5 bytes.
+	lr.Attributes().PutStr("user.name", "ééééé")
+
+	mutated, err := Logs(ld, Config{MaxLength: 6, Marker: "~"})
+	if err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if !mutated {
+		t.Error("expected logs to be mutated")
+	}
+	assertAttributes(t, lr.Attributes(), map[string]any{
+		"user.name":                 "éé~",
+		"user.name.original_length": int64(10),
+	})
+}
+
+func TestMarkerLongerThanLimit(t *testing.T) {
+	ld := plog.NewLogs()
+	lr := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	lr.Attributes().PutStr("user.id", "12345")
+
+	if _, err := Logs(ld, Config{MaxLength: 2}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if v, _ := lr.Attributes().Get("user.id"); v.Str() != "12" {
+		t.Errorf("expected the value to be cut without marker, got %q", v.Str())
+	}
+}
+
+func assertAttributes(t *testing.T, attrs pcommon.Map, want map[string]any) {
+	t.Helper()
+	got := attrs.AsRaw()
+	if len(got) != len(want) {
+		t.Errorf("expected attributes %v, got %v", want, got)
+		return
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, got[k])
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package wasmconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+const connectTracesToMetricsFunctionName = "connectTracesToMetrics"
+
+// tracesToMetricsConnector passes the traces of a pipeline to the guest, and
+// the metrics the guest derives from them to the next consumer of a metrics
+// pipeline.
+type tracesToMetricsConnector struct {
+	plugin       *wasmplugin.WasmPlugin
+	nextConsumer consumer.Metrics
+}
+
+var _ connector.Traces = (*tracesToMetricsConnector)(nil)
+
+// newTracesToMetricsConnector creates a new traces to metrics connector
+// using WebAssembly
+func newTracesToMetricsConnector(ctx context.Context, cfg *Config, set connector.Settings, nextConsumer consumer.Metrics) (*tracesToMetricsConnector, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	requiredFunctions := []string{connectTracesToMetricsFunctionName}
+
+	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions,
+		wasmplugin.WithMeterProvider(set.MeterProvider),
+		wasmplugin.WithTracerProvider(set.TracerProvider),
+		wasmplugin.WithLogger(set.Logger),
+		wasmplugin.WithComponent(set.ID.String(), set.Resource))
+	if err != nil {
+		return nil, err
+	}
+
+	// The guest consumes traces.
+	if supported, err := plugin.IsTracesSupported(ctx); err != nil {
+		return nil, fmt.Errorf("failed to check traces support status: %w", err)
+	} else if !supported {
+		return nil, pipeline.ErrSignalNotSupported
+	}
+
+	return &tracesToMetricsConnector{
+		plugin:       plugin,
+		nextConsumer: nextConsumer,
+	}, nil
+}
+
+func (c *tracesToMetricsConnector) Capabilities() consumer.Capabilities {
+	return connectorCapabilities
+}
+
+func (c *tracesToMetricsConnector) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	stack := wasmplugin.AcquireStack()
+	defer wasmplugin.ReleaseStack(stack)
+	stack.CurrentTraces = td
+	stack.Bag = wasmplugin.BagFromContext(ctx)
+
+	res, err := c.plugin.ProcessFunctionCall(ctx, connectTracesToMetricsFunctionName, stack)
+	if err != nil {
+		return err
+	}
+
+	if err := c.plugin.CheckStatus(ctx, connectTracesToMetricsFunctionName, res, stack); err != nil {
+		return fmt.Errorf("wasm: error connecting traces to metrics: %w", err)
+	}
+
+	// The guest derived nothing from the traces.
+	if stack.ResultMetrics == (pmetric.Metrics{}) || stack.ResultMetrics.ResourceMetrics().Len() == 0 {
+		return nil
+	}
+	return c.nextConsumer.ConsumeMetrics(ctx, stack.ResultMetrics)
+}
+
+// Start reports the status of the connector, for the health check
+// extension: starting, then OK if the guest is ready and started.
+func (c *tracesToMetricsConnector) Start(ctx context.Context, host component.Host) error {
+	componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusStarting))
+	if !c.plugin.Ready() {
+		componentstatus.ReportStatus(host, componentstatus.NewPermanentErrorEvent(wasmplugin.ErrGuestNotReady))
+		return nil
+	}
+	if err := c.plugin.StartGuest(ctx); err != nil {
+		return fmt.Errorf("wasm: error starting guest: %w", err)
+	}
+	componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusOK))
+	return nil
+}
+
+func (c *tracesToMetricsConnector) Shutdown(ctx context.Context) error {
+	// The guest is shut down first, so it can flush the data it buffers
+	// while the module is still open. The runtime is closed regardless.
+	var guestErr error
+	if err := c.plugin.ShutdownGuest(ctx); err != nil {
+		guestErr = fmt.Errorf("wasm: error shutting down guest: %w", err)
+	}
+	return errors.Join(guestErr, c.plugin.Shutdown(ctx))
+}
@@ -0,0 +1,139 @@
+package wasmconnector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/connector/connectortest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// spanCountGuest returns a guest whose connectTracesToMetrics sets the given
+// metrics as the result, whatever the traces.
+func spanCountGuest(t *testing.T, telemetry int32, metrics pmetric.Metrics) string {
+	t.Helper()
+	metricsBytes, err := (&pmetric.ProtoMarshaler{}).MarshalMetrics(metrics)
+	if err != nil {
+		t.Fatalf("failed to marshal metrics: %v", err)
+	}
+	mod := wasmtest.NewGuest(telemetry).
+		Import(wasmtest.HostModule, "setResultMetrics", []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: metricsBytes}}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  connectTracesToMetricsFunctionName,
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(int32(len(metricsBytes))), mod.Call("setResultMetrics"),
+			wasmtest.I32Const(0),
+		),
+	})
+	return mod.Write(t)
+}
+
+func TestCreateDefaultConfig(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	if cfg == nil {
+		t.Fatal("failed to create default config")
+	}
+
+	if err := componenttest.CheckConfigStruct(cfg); err != nil {
+		t.Errorf("config failed structure validation: %v", err)
+	}
+
+	if _, ok := cfg.(*Config); !ok {
+		t.Error("config is not the correct type")
+	}
+}
+
+func TestCreateTracesToMetrics(t *testing.T) {
+	metrics := pmetric.NewMetrics()
+	metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetName("span.count")
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Path = spanCountGuest(t, 4, metrics)
+	ctx := t.Context()
+
+	sink := new(consumertest.MetricsSink)
+	c, err := factory.CreateTracesToMetrics(ctx, connectortest.NewNopSettings(typeStr), cfg, sink)
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+	host := &statusHost{Host: componenttest.NewNopHost()}
+	if err := c.Start(ctx, host); err != nil {
+		t.Fatalf("failed to start connector: %v", err)
+	}
+	if len(host.events) != 2 || host.events[1].Status() != componentstatus.StatusOK {
+		t.Errorf("expected the started connector to report the OK status, got %v", host.events)
+	}
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	if err := c.ConsumeTraces(ctx, traces); err != nil {
+		t.Fatalf("failed to consume traces: %v", err)
+	}
+
+	if err := c.Shutdown(ctx); err != nil {
+		t.Errorf("failed to shutdown connector: %v", err)
+	}
+
+	got := sink.AllMetrics()
+	if len(got) != 1 || got[0].MetricCount() != 1 {
+		t.Fatalf("expected the derived metric to be passed downstream, got %d batches", len(got))
+	}
+	if name := got[0].ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Name(); name != "span.count" {
+		t.Errorf("expected the span.count metric, got %s", name)
+	}
+}
+
+func TestCreateTracesToMetricsEmptyResult(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Path = spanCountGuest(t, 4, pmetric.NewMetrics())
+	ctx := t.Context()
+
+	sink := new(consumertest.MetricsSink)
+	c, err := factory.CreateTracesToMetrics(ctx, connectortest.NewNopSettings(typeStr), cfg, sink)
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+	defer c.Shutdown(ctx)
+
+	if err := c.ConsumeTraces(ctx, ptrace.NewTraces()); err != nil {
+		t.Fatalf("failed to consume traces: %v", err)
+	}
+	if len(sink.AllMetrics()) != 0 {
+		t.Errorf("expected no metrics passed downstream, got %d batches", len(sink.AllMetrics()))
+	}
+}
+
+func TestCreateTracesToMetricsUnsupported(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	// The guest declares metrics support only.
+	cfg.Path = spanCountGuest(t, 1, pmetric.NewMetrics())
+
+	_, err := factory.CreateTracesToMetrics(t.Context(), connectortest.NewNopSettings(typeStr), cfg, consumertest.NewNop())
+	if !errors.Is(err, pipeline.ErrSignalNotSupported) {
+		t.Errorf("expected %v, got %v", pipeline.ErrSignalNotSupported, err)
+	}
+}
+
+// statusHost is a host recording the status reported by the components.
+type statusHost struct {
+	component.Host
+	events []*componentstatus.Event
+}
+
+func (h *statusHost) Report(event *componentstatus.Event) {
+	h.events = append(h.events, event)
+}
@@ -0,0 +1,39 @@
+package wasmconnector
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/consumer"
+)
+
+var (
+	typeStr                                = component.MustNewType("wasm")
+	connectorCapabilities                  = consumer.Capabilities{MutatesData: false}
+	_                     component.Config = (*Config)(nil)
+)
+
+func createDefaultConfig() component.Config {
+	cfg := &Config{}
+	cfg.RuntimeConfig.Default()
+	return cfg
+}
+
+// NewFactory creates a factory for wasmconnector.
+func NewFactory() connector.Factory {
+	return connector.NewFactory(
+		typeStr,
+		createDefaultConfig,
+		connector.WithTracesToMetrics(createTracesToMetrics, component.StabilityLevelAlpha),
+	)
+}
+
+func createTracesToMetrics(
+	ctx context.Context,
+	set connector.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (connector.Traces, error) {
+	return newTracesToMetricsConnector(ctx, cfg.(*Config), set, nextConsumer)
+}
@@ -1,11 +1,36 @@
 package wasmexporter
 
-import "github.com/otelwasm/otelwasm/wasmplugin"
+import (
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+)
 
 type Config struct {
 	wasmplugin.Config `mapstructure:",squash"`
+
+	// BackOffConfig is the configuration of the retries of the pushes failed
+	// with a retryable or throttled status, or a trap. The pushes failed
+	// with a permanent or invalid argument status aren't retried. Retries are
+	// disabled by default.
+	BackOffConfig configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+
+	// QueueConfig is the configuration of the queue the data is pushed to
+	// the guest from, and of the batching of the queued data. The host
+	// queues, batches and retries, so the guest only pushes each batch
+	// synchronously. Queueing is disabled by default.
+	QueueConfig exporterhelper.QueueBatchConfig `mapstructure:"sending_queue"`
 }
 
 func (cfg *Config) Validate() error {
-	return cfg.Config.Validate()
+	if err := cfg.Config.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.BackOffConfig.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.QueueConfig.Validate(); err != nil {
+		return err
+	}
+	return cfg.QueueConfig.Batch.Validate()
 }
@@ -2,9 +2,15 @@ package wasmexporter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
@@ -17,12 +23,32 @@ const (
 	pushLogsFunctionName    = "pushLogs"
 )
 
+// pushError returns err, the error of a push, as the collector error of the
+// status code returned by the guest, so the retry sender and the upstream
+// components retry the data only if it may succeed. Failures other than
+// error statuses, e.g. traps, are passed on as is, which are retried.
+func pushError(err error) error {
+	var guestErr *wasmplugin.GuestError
+	if !errors.As(err, &guestErr) || guestErr.Err != nil {
+		return err
+	}
+	switch guestErr.Status {
+	case wasmplugin.StatusCodePermanent, wasmplugin.StatusCodeInvalidArgument:
+		return consumererror.NewPermanent(err)
+	case wasmplugin.StatusCodeThrottled:
+		// The retry sender waits for its backoff, the guest setting no delay.
+		return exporterhelper.NewThrottleRetry(err, 0)
+	default:
+		return err
+	}
+}
+
 type wasmExporter struct {
 	plugin *wasmplugin.WasmPlugin
 }
 
 // newWasmTracesExporter creates a new traces exporter using WebAssembly
-func newWasmTracesExporter(ctx context.Context, cfg *Config) (*wasmExporter, error) {
+func newWasmTracesExporter(ctx context.Context, cfg *Config, set exporter.Settings) (*wasmExporter, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -31,7 +57,11 @@ func newWasmTracesExporter(ctx context.Context, cfg *Config) (*wasmExporter, err
 	requiredFunctions := []string{pushTracesFunctionName}
 
 	// Initialize the WASM plugin
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
+	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions,
+		wasmplugin.WithMeterProvider(set.MeterProvider),
+		wasmplugin.WithTracerProvider(set.TracerProvider),
+		wasmplugin.WithLogger(set.Logger),
+		wasmplugin.WithComponent(set.ID.String(), set.Resource))
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +79,7 @@ func newWasmTracesExporter(ctx context.Context, cfg *Config) (*wasmExporter, err
 }
 
 // newWasmMetricsExporter creates a new metrics exporter using WebAssembly
-func newWasmMetricsExporter(ctx context.Context, cfg *Config) (*wasmExporter, error) {
+func newWasmMetricsExporter(ctx context.Context, cfg *Config, set exporter.Settings) (*wasmExporter, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -58,7 +88,11 @@ func newWasmMetricsExporter(ctx context.Context, cfg *Config) (*wasmExporter, er
 	requiredFunctions := []string{pushMetricsFunctionName}
 
 	// Initialize the WASM plugin
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
+	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions,
+		wasmplugin.WithMeterProvider(set.MeterProvider),
+		wasmplugin.WithTracerProvider(set.TracerProvider),
+		wasmplugin.WithLogger(set.Logger),
+		wasmplugin.WithComponent(set.ID.String(), set.Resource))
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +110,7 @@ func newWasmMetricsExporter(ctx context.Context, cfg *Config) (*wasmExporter, er
 }
 
 // newWasmLogsExporter creates a new logs exporter using WebAssembly
-func newWasmLogsExporter(ctx context.Context, cfg *Config) (*wasmExporter, error) {
+func newWasmLogsExporter(ctx context.Context, cfg *Config, set exporter.Settings) (*wasmExporter, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -85,7 +119,11 @@ func newWasmLogsExporter(ctx context.Context, cfg *Config) (*wasmExporter, error
 	requiredFunctions := []string{pushLogsFunctionName}
 
 	// Initialize the WASM plugin
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
+	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions,
+		wasmplugin.WithMeterProvider(set.MeterProvider),
+		wasmplugin.WithTracerProvider(set.TracerProvider),
+		wasmplugin.WithLogger(set.Logger),
+		wasmplugin.WithComponent(set.ID.String(), set.Resource))
 	if err != nil {
 		return nil, err
 	}
@@ -106,19 +144,18 @@ func (wp *wasmExporter) pushTraces(
 	ctx context.Context,
 	td ptrace.Traces,
 ) error {
-	stack := &wasmplugin.Stack{
-		CurrentTraces:    td,
-		PluginConfigJSON: wp.plugin.PluginConfigJSON,
-	}
+	stack := wasmplugin.AcquireStack()
+	defer wasmplugin.ReleaseStack(stack)
+	stack.CurrentTraces = td
+	stack.Bag = wasmplugin.BagFromContext(ctx)
 
 	res, err := wp.plugin.ProcessFunctionCall(ctx, pushTracesFunctionName, stack)
 	if err != nil {
 		return err
 	}
 
-	statusCode := wasmplugin.StatusCode(res[0])
-	if statusCode != 0 {
-		return fmt.Errorf("wasm: error pushing traces: %s: %s", statusCode.String(), stack.StatusReason)
+	if err := wp.plugin.CheckStatus(ctx, pushTracesFunctionName, res, stack); err != nil {
+		return pushError(fmt.Errorf("wasm: error pushing traces: %w", err))
 	}
 
 	return nil
@@ -128,19 +165,18 @@ func (wp *wasmExporter) pushMetrics(
 	ctx context.Context,
 	md pmetric.Metrics,
 ) error {
-	stack := &wasmplugin.Stack{
-		CurrentMetrics:   md,
-		PluginConfigJSON: wp.plugin.PluginConfigJSON,
-	}
+	stack := wasmplugin.AcquireStack()
+	defer wasmplugin.ReleaseStack(stack)
+	stack.CurrentMetrics = md
+	stack.Bag = wasmplugin.BagFromContext(ctx)
 
 	res, err := wp.plugin.ProcessFunctionCall(ctx, pushMetricsFunctionName, stack)
 	if err != nil {
 		return err
 	}
 
-	statusCode := wasmplugin.StatusCode(res[0])
-	if statusCode != 0 {
-		return fmt.Errorf("wasm: error pushing metrics: %s: %s", statusCode.String(), stack.StatusReason)
+	if err := wp.plugin.CheckStatus(ctx, pushMetricsFunctionName, res, stack); err != nil {
+		return pushError(fmt.Errorf("wasm: error pushing metrics: %w", err))
 	}
 
 	return nil
@@ -150,24 +186,44 @@ func (wp *wasmExporter) pushLogs(
 	ctx context.Context,
 	ld plog.Logs,
 ) error {
-	stack := &wasmplugin.Stack{
-		CurrentLogs:      ld,
-		PluginConfigJSON: wp.plugin.PluginConfigJSON,
-	}
+	stack := wasmplugin.AcquireStack()
+	defer wasmplugin.ReleaseStack(stack)
+	stack.CurrentLogs = ld
+	stack.Bag = wasmplugin.BagFromContext(ctx)
 
 	res, err := wp.plugin.ProcessFunctionCall(ctx, pushLogsFunctionName, stack)
 	if err != nil {
 		return err
 	}
 
-	statusCode := wasmplugin.StatusCode(res[0])
-	if statusCode != 0 {
-		return fmt.Errorf("wasm: error pushing logs: %s: %s", statusCode.String(), stack.StatusReason)
+	if err := wp.plugin.CheckStatus(ctx, pushLogsFunctionName, res, stack); err != nil {
+		return pushError(fmt.Errorf("wasm: error pushing logs: %w", err))
 	}
 
 	return nil
 }
 
+// start reports the status of the exporter, for the health check extension:
+// starting, then OK if the guest is ready and started.
+func (wp *wasmExporter) start(ctx context.Context, host component.Host) error {
+	componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusStarting))
+	if !wp.plugin.Ready() {
+		componentstatus.ReportStatus(host, componentstatus.NewPermanentErrorEvent(wasmplugin.ErrGuestNotReady))
+		return nil
+	}
+	if err := wp.plugin.StartGuest(ctx); err != nil {
+		return fmt.Errorf("wasm: error starting guest: %w", err)
+	}
+	componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusOK))
+	return nil
+}
+
 func (wp *wasmExporter) shutdown(ctx context.Context) error {
-	return wp.plugin.Shutdown(ctx)
+	// The guest is shut down first, so it can flush the data it buffers
+	// while the module is still open. The runtime is closed regardless.
+	var guestErr error
+	if err := wp.plugin.ShutdownGuest(ctx); err != nil {
+		guestErr = fmt.Errorf("wasm: error shutting down guest: %w", err)
+	}
+	return errors.Join(guestErr, wp.plugin.Shutdown(ctx))
 }
@@ -0,0 +1,172 @@
+//go:build docker
+// +build docker
+
+package wasmexporter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func runInContainer(ctx context.Context, t *testing.T, container *testcontainers.DockerContainer, commands ...string) string {
+	t.Helper()
+
+	execCode, reader, err := container.Exec(ctx, commands)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	var out bytes.Buffer
+	io.Copy(io.MultiWriter(os.Stderr, &out), reader)
+	if execCode != 0 {
+		t.Fatalf("failed to execute command")
+	}
+	return out.String()
+}
+
+// startMinio starts a MinIO container with a testbucket bucket, and sets the
+// credentials of the guest S3 exporter.
+func startMinio(ctx context.Context, t *testing.T) *testcontainers.DockerContainer {
+	t.Helper()
+
+	container, err := testcontainers.Run(ctx,
+		"quay.io/minio/minio:RELEASE.2025-05-24T17-08-30Z",
+		testcontainers.WithExposedPorts("9000:9000/tcp", "9001:9001/tcp"),
+		testcontainers.WithEnv(map[string]string{
+			"MINIO_ROOT_USER":     "minio",
+			"MINIO_ROOT_PASSWORD": "minio123",
+			"MINIO_ACCESS_KEY":    "minio",
+			"MINIO_SECRET_KEY":    "minio123",
+		}),
+		testcontainers.WithCmd(
+			"server", "/data",
+			"--address", ":9000",
+			"--console-address", ":9001",
+		),
+		testcontainers.WithWaitStrategy(wait.ForAll(
+			wait.ForListeningPort("9000/tcp"),
+			wait.ForListeningPort("9001/tcp"),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("failed to start container: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio123")
+
+	runInContainer(ctx, t, container,
+		"mc",
+		"config", "host", "add", "myminio",
+		"http://127.0.0.1:9000",
+		"minio",
+		"minio123",
+	)
+
+	runInContainer(ctx, t, container,
+		"mc",
+		"mb",
+		"myminio/testbucket",
+	)
+	return container
+}
+
+// newS3Config returns the config of the guest S3 exporter, uploading the
+// traces to the bucket of startMinio through a sending queue.
+func newS3Config() *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/awss3exporter/main.wasm"
+	cfg.EnvAllowlist = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
+	cfg.PluginConfig = map[string]any{
+		"marshaler": "otlp_json",
+		// The queue is enabled, as in the default config of the exporter.
+		"sending_queue": map[string]any{
+			"enabled": true,
+		},
+		"s3uploader": map[string]any{
+			"region":              "us-east-1",
+			"s3_bucket":           "testbucket",
+			"s3_prefix":           "traces",
+			"endpoint":            "http://127.0.0.1:9000",
+			"disable_ssl":         true,
+			"s3_force_path_style": true,
+		},
+	}
+	return cfg
+}
+
+func TestS3ExporterFlushesOnShutdown(t *testing.T) {
+	ctx := t.Context()
+	container := startMinio(ctx, t)
+
+	wasmExp, err := newWasmTracesExporter(ctx, newS3Config(), exportertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm exporter: %v", err)
+	}
+
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName("test-span")
+	if err := wasmExp.pushTraces(ctx, traces); err != nil {
+		t.Fatalf("failed to push traces: %v", err)
+	}
+
+	if err := wasmExp.shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown exporter: %v", err)
+	}
+
+	objects := runInContainer(ctx, t, container,
+		"mc",
+		"ls",
+		"--recursive",
+		"myminio/testbucket/traces",
+	)
+	if objects == "" {
+		t.Fatal("expected the buffered traces to be flushed to the bucket on shutdown")
+	}
+}
+
+func TestS3ExporterWithSendingQueue(t *testing.T) {
+	ctx := t.Context()
+	container := startMinio(ctx, t)
+
+	wasmExp, err := newWasmTracesExporter(ctx, newS3Config(), exportertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm exporter: %v", err)
+	}
+	t.Cleanup(func() { wasmExp.shutdown(context.Background()) })
+
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName("test-span")
+	if err := wasmExp.pushTraces(ctx, traces); err != nil {
+		t.Fatalf("failed to push traces: %v", err)
+	}
+
+	// The queued traces are uploaded before the push returns, rather than
+	// left in the queue of the guest until it shuts down.
+	objects := runInContainer(ctx, t, container,
+		"mc",
+		"ls",
+		"--recursive",
+		"myminio/testbucket/traces",
+	)
+	if objects == "" {
+		t.Fatal("expected the queued traces to reach the bucket before the push returned")
+	}
+}
@@ -2,9 +2,23 @@
 package wasmexporter
 
 import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
 	"go.opentelemetry.io/collector/exporter/exportertest"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
@@ -55,6 +69,58 @@ func TestCreateTracesExporter(t *testing.T) {
 	}
 }
 
+// statusHost is a host recording the status reported by the components.
+type statusHost struct {
+	component.Host
+	events []*componentstatus.Event
+}
+
+func (h *statusHost) Report(event *componentstatus.Event) {
+	h.events = append(h.events, event)
+}
+
+func TestExporterStartStatus(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Path = "testdata/nop/main.wasm"
+	ctx := t.Context()
+
+	t.Run("ready", func(t *testing.T) {
+		te, err := factory.CreateTraces(ctx, exportertest.NewNopSettings(typeStr), cfg)
+		if err != nil {
+			t.Fatalf("failed to create traces exporter: %v", err)
+		}
+		host := &statusHost{Host: componenttest.NewNopHost()}
+		if err := te.Start(ctx, host); err != nil {
+			t.Fatalf("failed to start exporter: %v", err)
+		}
+		if err := te.Shutdown(ctx); err != nil {
+			t.Fatalf("failed to shutdown exporter: %v", err)
+		}
+		if len(host.events) != 2 || host.events[0].Status() != componentstatus.StatusStarting || host.events[1].Status() != componentstatus.StatusOK {
+			t.Errorf("expected the starting then OK status, got %v", host.events)
+		}
+	})
+
+	t.Run("guest closed", func(t *testing.T) {
+		wp, err := newWasmTracesExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm exporter: %v", err)
+		}
+		if err := wp.plugin.Shutdown(ctx); err != nil {
+			t.Fatalf("failed to shut the guest down: %v", err)
+		}
+		host := &statusHost{Host: componenttest.NewNopHost()}
+		if err := wp.start(ctx, host); err != nil {
+			t.Fatalf("failed to start exporter: %v", err)
+		}
+		if len(host.events) != 2 || host.events[1].Status() != componentstatus.StatusPermanentError ||
+			!errors.Is(host.events[1].Err(), wasmplugin.ErrGuestNotReady) {
+			t.Errorf("expected a permanent error status, got %v", host.events)
+		}
+	})
+}
+
 func TestCreateMetricsExporter(t *testing.T) {
 	// Test that the exporter can be created with the default config
 	factory := NewFactory()
@@ -111,7 +177,7 @@ func TestExportTracesWithNopExporter(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/nop/main.wasm"
 	ctx := t.Context()
-	wasmExp, err := newWasmTracesExporter(ctx, cfg)
+	wasmExp, err := newWasmTracesExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm traces exporter: %v", err)
 	}
@@ -138,7 +204,7 @@ func TestExportMetricsWithNopExporter(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/nop/main.wasm"
 	ctx := t.Context()
-	wasmExp, err := newWasmMetricsExporter(ctx, cfg)
+	wasmExp, err := newWasmMetricsExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm metrics exporter: %v", err)
 	}
@@ -164,7 +230,7 @@ func TestExportLogsWithNopExporter(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/nop/main.wasm"
 	ctx := t.Context()
-	wasmExp, err := newWasmLogsExporter(ctx, cfg)
+	wasmExp, err := newWasmLogsExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm logs exporter: %v", err)
 	}
@@ -190,7 +256,7 @@ func TestExportTracesWithStdoutExporter(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/stdout/main.wasm"
 	ctx := t.Context()
-	wasmExp, err := newWasmTracesExporter(ctx, cfg)
+	wasmExp, err := newWasmTracesExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm traces exporter: %v", err)
 	}
@@ -217,7 +283,7 @@ func TestExportMetricsWithStdoutExporter(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/stdout/main.wasm"
 	ctx := t.Context()
-	wasmExp, err := newWasmMetricsExporter(ctx, cfg)
+	wasmExp, err := newWasmMetricsExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm metrics exporter: %v", err)
 	}
@@ -243,7 +309,7 @@ func TestExportLogsWithStdoutExporter(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/stdout/main.wasm"
 	ctx := t.Context()
-	wasmExp, err := newWasmLogsExporter(ctx, cfg)
+	wasmExp, err := newWasmLogsExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm logs exporter: %v", err)
 	}
@@ -278,7 +344,7 @@ func TestShutdown(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/nop/main.wasm"
 	ctx := t.Context()
-	wasmExp, err := newWasmTracesExporter(ctx, cfg)
+	wasmExp, err := newWasmTracesExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm exporter: %v", err)
 	}
@@ -289,3 +355,226 @@ func TestShutdown(t *testing.T) {
 		t.Fatalf("failed to shutdown exporter: %v", err)
 	}
 }
+
+func TestStartCallsGuestStart(t *testing.T) {
+	// The guest start fails with "started" as the status reason, so the test
+	// can tell it was called.
+	mod := wasmtest.NewGuest(4).
+		Import(wasmtest.HostModule, "setResultStatusReason", []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte("started")}}
+	mod.Functions = append(mod.Functions,
+		wasmtest.Function{
+			Export:  pushTracesFunctionName,
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    wasmtest.I32Const(0),
+		},
+		wasmtest.Function{
+			Export:  "start",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(7), mod.Call("setResultStatusReason"),
+				wasmtest.I32Const(1),
+			),
+		},
+	)
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	wasmExp, err := newWasmTracesExporter(t.Context(), cfg, exportertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm exporter: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := wasmExp.shutdown(t.Context()); err != nil {
+			t.Errorf("failed to shutdown exporter: %v", err)
+		}
+	})
+
+	var guestErr *wasmplugin.GuestError
+	if err := wasmExp.start(t.Context(), componenttest.NewNopHost()); !errors.As(err, &guestErr) || guestErr.StatusReason != "started" {
+		t.Fatalf("expected the guest start error, got %v", err)
+	}
+	if err := wasmExp.start(t.Context(), componenttest.NewNopHost()); err != nil {
+		t.Errorf("expected the guest to be started once, got %v", err)
+	}
+}
+
+func TestShutdownCallsGuestShutdown(t *testing.T) {
+	// The guest shutdown fails with "flushed" as the status reason, so the
+	// test can tell it was called.
+	mod := wasmtest.NewGuest(4).
+		Import(wasmtest.HostModule, "setResultStatusReason", []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte("flushed")}}
+	mod.Functions = append(mod.Functions,
+		wasmtest.Function{
+			Export:  pushTracesFunctionName,
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    wasmtest.I32Const(0),
+		},
+		wasmtest.Function{
+			Export:  "shutdown",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(7), mod.Call("setResultStatusReason"),
+				wasmtest.I32Const(1),
+			),
+		},
+	)
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	wasmExp, err := newWasmTracesExporter(t.Context(), cfg, exportertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm exporter: %v", err)
+	}
+	if err := wasmExp.start(t.Context(), componenttest.NewNopHost()); err != nil {
+		t.Fatalf("failed to start wasm exporter: %v", err)
+	}
+
+	err = wasmExp.shutdown(t.Context())
+	var guestErr *wasmplugin.GuestError
+	if !errors.As(err, &guestErr) || guestErr.StatusReason != "flushed" {
+		t.Fatalf("expected the guest shutdown error, got %v", err)
+	}
+}
+
+func TestPushErrorStatus(t *testing.T) {
+	tests := []struct {
+		status        wasmplugin.StatusCode
+		wantPermanent bool
+		wantThrottled bool
+	}{
+		{status: wasmplugin.StatusCodeError},
+		{status: wasmplugin.StatusCodeInvalidArgument, wantPermanent: true},
+		{status: wasmplugin.StatusCodePermanent, wantPermanent: true},
+		{status: wasmplugin.StatusCodeRetryable},
+		{status: wasmplugin.StatusCodeThrottled, wantThrottled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status.String(), func(t *testing.T) {
+			mod := wasmtest.NewGuest(4, wasmtest.Function{
+				Export:  pushTracesFunctionName,
+				Results: []api.ValueType{api.ValueTypeI32},
+				Body:    wasmtest.I32Const(int32(tt.status)),
+			})
+			cfg := createDefaultConfig().(*Config)
+			cfg.Path = mod.Write(t)
+			ctx := t.Context()
+			wp, err := newWasmTracesExporter(ctx, cfg, exportertest.NewNopSettings(typeStr))
+			if err != nil {
+				t.Fatalf("failed to create wasm exporter: %v", err)
+			}
+			t.Cleanup(func() { wp.shutdown(ctx) })
+
+			err = wp.pushTraces(ctx, ptrace.NewTraces())
+			var guestErr *wasmplugin.GuestError
+			if !errors.As(err, &guestErr) || guestErr.Status != tt.status {
+				t.Fatalf("expected the guest error of status %s, got %v", tt.status, err)
+			}
+			if got := consumererror.IsPermanent(err); got != tt.wantPermanent {
+				t.Errorf("expected permanent %v, got %v", tt.wantPermanent, got)
+			}
+			if got := strings.HasPrefix(err.Error(), "Throttle ("); got != tt.wantThrottled {
+				t.Errorf("expected throttled %v, got %v", tt.wantThrottled, err)
+			}
+		})
+	}
+}
+
+// countingGuest returns a guest whose pushTraces counts its calls at the
+// offset 0 of its memory, and fails the first failures calls with a
+// retryable status.
+func countingGuest(failures int32) *wasmtest.Module {
+	return wasmtest.NewGuest(4, wasmtest.Function{
+		Export:  pushTracesFunctionName,
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0),
+			wasmtest.I32Const(0), wasmtest.I32Load(0), wasmtest.I32Const(1), wasmtest.I32Add,
+			wasmtest.I32Store(0),
+			wasmtest.I32Const(0), wasmtest.I32Load(0), wasmtest.I32Const(failures+1), wasmtest.I32LtU,
+			wasmtest.If(),
+			wasmtest.I32Const(int32(wasmplugin.StatusCodeRetryable)), wasmtest.Return,
+			wasmtest.End,
+			wasmtest.I32Const(0),
+		),
+	})
+}
+
+// newCountingExporter returns the traces exporter of cfg wrapping the
+// pushes of a counting guest, and a function returning the count of spans
+// of each push, failed ones included.
+func newCountingExporter(t *testing.T, cfg *Config, failures int32) (exporter.Traces, func() []int) {
+	t.Helper()
+	cfg.Path = countingGuest(failures).Write(t)
+	ctx := t.Context()
+	set := exportertest.NewNopSettings(typeStr)
+	wp, err := newWasmTracesExporter(ctx, cfg, set)
+	if err != nil {
+		t.Fatalf("failed to create wasm exporter: %v", err)
+	}
+	var mu sync.Mutex
+	var pushes []int
+	push := func(ctx context.Context, td ptrace.Traces) error {
+		mu.Lock()
+		pushes = append(pushes, td.SpanCount())
+		mu.Unlock()
+		return wp.pushTraces(ctx, td)
+	}
+	exp, err := exporterhelper.NewTraces(ctx, set, cfg, push, wp.options(cfg)...)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	if err := exp.Start(ctx, componenttest.NewNopHost()); err != nil {
+		t.Fatalf("failed to start exporter: %v", err)
+	}
+	t.Cleanup(func() { exp.Shutdown(context.Background()) })
+	return exp, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(pushes)
+	}
+}
+
+// oneSpan returns traces of a single span.
+func oneSpan() ptrace.Traces {
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	return traces
+}
+
+func TestRetryTransientError(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.BackOffConfig.Enabled = true
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	cfg.BackOffConfig.MaxInterval = time.Millisecond
+	exp, pushes := newCountingExporter(t, cfg, 2)
+
+	if err := exp.ConsumeTraces(t.Context(), oneSpan()); err != nil {
+		t.Fatalf("expected the push to be retried until it succeeds, got %v", err)
+	}
+	if got := pushes(); !slices.Equal(got, []int{1, 1, 1}) {
+		t.Errorf("expected the span to be pushed 3 times, got %v", got)
+	}
+}
+
+func TestQueueBatchesPushes(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.QueueConfig.Enabled = true
+	cfg.QueueConfig.Sizer = exporterhelper.RequestSizerTypeItems
+	cfg.QueueConfig.Batch = &exporterhelper.BatchConfig{FlushTimeout: time.Minute, MinSize: 3}
+	exp, pushes := newCountingExporter(t, cfg, 0)
+
+	for range 3 {
+		if err := exp.ConsumeTraces(t.Context(), oneSpan()); err != nil {
+			t.Fatalf("failed to queue the traces: %v", err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pushes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := pushes(); !slices.Equal(got, []int{3}) {
+		t.Errorf("expected the 3 spans to be pushed in a single batch, got %v", got)
+	}
+}
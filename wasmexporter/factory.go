@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configretry"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
@@ -16,7 +17,10 @@ var (
 )
 
 func createDefaultConfig() component.Config {
-	cfg := &Config{}
+	cfg := &Config{BackOffConfig: configretry.NewDefaultBackOffConfig()}
+	cfg.BackOffConfig.Enabled = false
+	cfg.QueueConfig = exporterhelper.NewDefaultQueueConfig()
+	cfg.QueueConfig.Enabled = false
 	cfg.RuntimeConfig.Default()
 	return cfg
 }
@@ -37,14 +41,13 @@ func createTraces(
 	set exporter.Settings,
 	cfg component.Config,
 ) (exporter.Traces, error) {
-	wasmExporter, err := newWasmTracesExporter(ctx, cfg.(*Config))
+	wasmExporter, err := newWasmTracesExporter(ctx, cfg.(*Config), set)
 	if err != nil {
 		return nil, err
 	}
 	return exporterhelper.NewTraces(ctx, set, cfg,
 		wasmExporter.pushTraces,
-		exporterhelper.WithCapabilities(exporterCapabilities),
-		exporterhelper.WithShutdown(wasmExporter.shutdown),
+		wasmExporter.options(cfg.(*Config))...,
 	)
 }
 
@@ -53,14 +56,13 @@ func createMetrics(
 	set exporter.Settings,
 	cfg component.Config,
 ) (exporter.Metrics, error) {
-	wasmExporter, err := newWasmMetricsExporter(ctx, cfg.(*Config))
+	wasmExporter, err := newWasmMetricsExporter(ctx, cfg.(*Config), set)
 	if err != nil {
 		return nil, err
 	}
 	return exporterhelper.NewMetrics(ctx, set, cfg,
 		wasmExporter.pushMetrics,
-		exporterhelper.WithCapabilities(exporterCapabilities),
-		exporterhelper.WithShutdown(wasmExporter.shutdown),
+		wasmExporter.options(cfg.(*Config))...,
 	)
 }
 
@@ -69,13 +71,24 @@ func createLogs(
 	set exporter.Settings,
 	cfg component.Config,
 ) (exporter.Logs, error) {
-	wasmExporter, err := newWasmLogsExporter(ctx, cfg.(*Config))
+	wasmExporter, err := newWasmLogsExporter(ctx, cfg.(*Config), set)
 	if err != nil {
 		return nil, err
 	}
 	return exporterhelper.NewLogs(ctx, set, cfg,
 		wasmExporter.pushLogs,
-		exporterhelper.WithCapabilities(exporterCapabilities),
-		exporterhelper.WithShutdown(wasmExporter.shutdown),
+		wasmExporter.options(cfg.(*Config))...,
 	)
 }
+
+// options returns the options of the exporter helper wrapping the pushes of
+// the guest.
+func (e *wasmExporter) options(cfg *Config) []exporterhelper.Option {
+	return []exporterhelper.Option{
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithRetry(cfg.BackOffConfig),
+		exporterhelper.WithQueue(cfg.QueueConfig),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(e.shutdown),
+	}
+}
@@ -0,0 +1,77 @@
+package wasmplugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+const (
+	// ABIVersion is the version of the ABI between the host and the guests,
+	// i.e. the host functions and the guest functions with their semantics.
+	// It is bumped when a guest built against the new ABI can't run on an
+	// older host, or conversely.
+	//
+	// Version 2 added host functions the guest SDK imports, e.g. kvGet,
+	// recordMetric and emitTraces, so its guests don't link on hosts of
+	// version 1. Version 3 added getPluginConfigVersion, which the guest SDK
+	// polls to decode the plugin config again once it is updated. Version 4
+	// added getPluginConfigStatus, which the guest SDK reads the plugin
+	// config with, so it tells a buffer too small from a failure.
+	//
+	// Host functions only imported by the guest packages using them, e.g.
+	// getComponentInfo or currentProfiles, don't bump the version: older
+	// hosts refuse the guests importing them with checkHostImports.
+	ABIVersion = 4
+
+	// MinABIVersion is the oldest ABI version of the guests the host runs.
+	// The host functions of version 1 are unchanged in the later versions.
+	MinABIVersion = 1
+)
+
+// hostABIVersions is the range of the guest ABI versions the host runs. It
+// is a variable so tests can emulate older hosts.
+var hostABIVersions = struct{ min, max uint32 }{MinABIVersion, ABIVersion}
+
+// checkABIVersion returns the ABI version the guest declares through the
+// otelwasm_abi_version export, or ErrABIVersionUnsupported if it's out of the
+// supported range. Guests predating the declaration are assumed to be
+// compatible, and zero is returned for them.
+func checkABIVersion(ctx context.Context, mod api.Module) (uint32, error) {
+	fn := mod.ExportedFunction(abiVersion)
+	if fn == nil {
+		return 0, nil
+	}
+	res, err := fn.Call(createContextWithStack(ctx, &Stack{}))
+	if err != nil {
+		return 0, fmt.Errorf("wasm: failed to get ABI version: %w", err)
+	}
+	if len(res) != 1 {
+		return 0, fmt.Errorf("wasm: %s must return the ABI version: %w", abiVersion, ErrABIVersionUnsupported)
+	}
+	version := uint32(res[0])
+	if version < hostABIVersions.min || version > hostABIVersions.max {
+		return 0, fmt.Errorf("wasm: guest ABI version %d, host supports %d to %d: %w",
+			version, hostABIVersions.min, hostABIVersions.max, ErrABIVersionUnsupported)
+	}
+	return version, nil
+}
+
+// checkHostImports returns ErrABIVersionUnsupported if the guest imports a
+// host function the host doesn't export, which means the guest was built
+// against a newer ABI. Without it, the guest fails to instantiate with a
+// link error.
+func checkHostImports(guest wazero.CompiledModule, host api.Module) error {
+	exported := host.ExportedFunctionDefinitions()
+	for _, fn := range guest.ImportedFunctions() {
+		module, name, _ := fn.Import()
+		if _, ok := exported[name]; module != otelWasm || ok {
+			continue
+		}
+		return fmt.Errorf("wasm: guest imports host function %s, unknown to host ABI version %d, the guest was likely built with a newer SDK: %w",
+			name, hostABIVersions.max, ErrABIVersionUnsupported)
+	}
+	return nil
+}
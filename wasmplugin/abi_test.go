@@ -0,0 +1,86 @@
+package wasmplugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestABIVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int32
+		wantErr bool
+	}{
+		{name: "supported", version: ABIVersion},
+		{name: "previous supported", version: MinABIVersion},
+		{name: "too old", version: MinABIVersion - 1, wantErr: true},
+		{name: "too new", version: ABIVersion + 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := wasmtest.NewGuest(int32(telemetryTypeTraces), wasmtest.Function{
+				Export:  abiVersion,
+				Results: []api.ValueType{api.ValueTypeI32},
+				Body:    wasmtest.Instructions(wasmtest.I32Const(tt.version)),
+			})
+			cfg := Config{Path: mod.Write(t)}
+			cfg.Default()
+
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, nil)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("failed to create plugin: %v", err)
+				}
+				plugin.Shutdown(t.Context())
+				return
+			}
+			if !errors.Is(err, ErrABIVersionUnsupported) {
+				t.Fatalf("expected %v, got %v", ErrABIVersionUnsupported, err)
+			}
+			// Both versions are reported, so the upgrade path is clear.
+			for _, want := range []string{
+				fmt.Sprintf("guest ABI version %d", tt.version),
+				fmt.Sprintf("host supports %d to %d", MinABIVersion, ABIVersion),
+			} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected the error to contain %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestABIVersionNewerGuest(t *testing.T) {
+	// The host only supports the first ABI version, as hosts released before
+	// the version 2 guests.
+	orig := hostABIVersions
+	hostABIVersions.min, hostABIVersions.max = 1, 1
+	t.Cleanup(func() { hostABIVersions = orig })
+
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32(abiVersion, 2))
+	cfg := Config{Path: mod.Write(t)}
+	cfg.Default()
+
+	_, err := NewWasmPlugin(t.Context(), &cfg, nil)
+	if !errors.Is(err, ErrABIVersionUnsupported) || !strings.Contains(err.Error(), "guest ABI version 2, host supports 1 to 1") {
+		t.Errorf("expected %v for the version 2 guest, got %v", ErrABIVersionUnsupported, err)
+	}
+}
+
+func TestABIVersionUnknownHostFunction(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, "futureHostFunction", nil, nil)
+	cfg := Config{Path: mod.Write(t)}
+	cfg.Default()
+
+	_, err := NewWasmPlugin(t.Context(), &cfg, nil)
+	if !errors.Is(err, ErrABIVersionUnsupported) || !strings.Contains(err.Error(), "futureHostFunction") {
+		t.Errorf("expected %v naming the unknown function, got %v", ErrABIVersionUnsupported, err)
+	}
+}
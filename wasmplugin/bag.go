@@ -0,0 +1,51 @@
+package wasmplugin
+
+import (
+	"context"
+	"sync"
+)
+
+// Bag is a key-value store shared by the guests handling the same batch.
+// It travels along the batch in the collector context, so an upstream guest
+// can pass hints to a downstream one.
+type Bag struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// Get returns the value stored under key.
+func (b *Bag) Get(key string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	v, ok := b.values[key]
+	return v, ok
+}
+
+// Set stores value under key.
+func (b *Bag) Set(key, value string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.values == nil {
+		b.values = make(map[string]string)
+	}
+	b.values[key] = value
+}
+
+// bagKey is the key used to store the bag in the context
+type bagKey struct{}
+
+// ContextWithBag returns a context carrying a bag. ctx is returned as is if
+// it already carries one, so that every stage of a pipeline shares the bag of
+// the first one.
+func ContextWithBag(ctx context.Context) context.Context {
+	if BagFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, bagKey{}, &Bag{})
+}
+
+// BagFromContext returns the bag carried by ctx, or nil if there is none.
+func BagFromContext(ctx context.Context) *Bag {
+	bag, _ := ctx.Value(bagKey{}).(*Bag)
+	return bag
+}
@@ -0,0 +1,235 @@
+package wasmplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CallLogConfig is the configuration of the recording of the guest calls to
+// a file of JSON lines, for the offline analysis of throughput issues. Each
+// line records a call with its function, the sizes of the telemetry passed
+// to and from the guest, its duration and its status code or error.
+type CallLogConfig struct {
+	// Path is the file the calls are appended to. Calls aren't recorded if
+	// empty. The plugins configured with the same path share the file.
+	Path string `mapstructure:"path,omitempty"`
+
+	// MaxSizeBytes is the size past which the file is rotated.
+	// The default is 100MiB.
+	MaxSizeBytes int64 `mapstructure:"max_size_bytes,omitempty"`
+
+	// MaxBackups is the number of rotated files kept, named after Path with
+	// the suffixes .1, the most recent, to .MaxBackups. Zero keeps none, the
+	// file is truncated once full.
+	MaxBackups int `mapstructure:"max_backups,omitempty"`
+}
+
+func (cfg *CallLogConfig) Validate() error {
+	if cfg.MaxSizeBytes < 0 {
+		return fmt.Errorf("call_log.max_size_bytes must not be negative")
+	}
+	if cfg.MaxBackups < 0 {
+		return fmt.Errorf("call_log.max_backups must not be negative")
+	}
+	return nil
+}
+
+// Default sets the default values for the call log configuration
+// if they are not set.
+func (cfg *CallLogConfig) Default() {
+	if cfg.MaxSizeBytes == 0 {
+		cfg.MaxSizeBytes = DefaultCallLogConfig.MaxSizeBytes
+	}
+}
+
+// DefaultCallLogConfig is the default configuration for the recording of the
+// guest calls.
+var DefaultCallLogConfig = CallLogConfig{
+	MaxSizeBytes: 100 << 20,
+}
+
+// callRecord is a line of the call log.
+type callRecord struct {
+	Time       time.Time `json:"time"`
+	Function   string    `json:"function"`
+	InputSize  uint32    `json:"input_size"`
+	OutputSize uint32    `json:"output_size"`
+	DurationNs int64     `json:"duration_ns"`
+	// StatusCode is the status code returned by the guest, unset if the call
+	// failed.
+	StatusCode *uint32 `json:"status_code,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// callLogs are the open call logs by path, shared by the plugins.
+var callLogs = struct {
+	sync.Mutex
+	byPath map[string]*callLog
+}{byPath: make(map[string]*callLog)}
+
+// callLog appends call records to a file, rotating it once full.
+type callLog struct {
+	cfg    CallLogConfig
+	logger *zap.Logger
+	// refs is the number of plugins using the log, guarded by callLogs.
+	refs int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	failed bool
+}
+
+// openCallLog returns the call log of cfg, opening its file unless another
+// plugin did, or nil if calls aren't recorded. The log must be released.
+func openCallLog(cfg CallLogConfig, logger *zap.Logger) (*callLog, error) {
+	if cfg.Path == "" {
+		return nil, nil
+	}
+	cfg.Default()
+
+	callLogs.Lock()
+	defer callLogs.Unlock()
+	if l, ok := callLogs.byPath[cfg.Path]; ok {
+		l.refs++
+		return l, nil
+	}
+	l := &callLog{cfg: cfg, logger: logger, refs: 1}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	callLogs.byPath[cfg.Path] = l
+	return l, nil
+}
+
+func (l *callLog) open() error {
+	file, err := os.OpenFile(l.cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("wasm: error opening call log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("wasm: error opening call log: %w", err)
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// release closes the file once no plugin uses the log.
+func (l *callLog) release() error {
+	if l == nil {
+		return nil
+	}
+	callLogs.Lock()
+	defer callLogs.Unlock()
+	if l.refs--; l.refs > 0 {
+		return nil
+	}
+	delete(callLogs.byPath, l.cfg.Path)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// logCall calls call, then appends its record to the log. The call isn't
+// failed if the record can't be written, the first failure is logged.
+func (l *callLog) logCall(ctx context.Context, functionName string, stack *Stack, call func(context.Context) ([]uint64, error)) ([]uint64, error) {
+	start := time.Now()
+	res, err := call(ctx)
+	record := callRecord{
+		Time:       start,
+		Function:   functionName,
+		InputSize:  stack.inputSize,
+		OutputSize: stack.outputSize,
+		DurationNs: time.Since(start).Nanoseconds(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	} else if len(res) > 0 {
+		status := uint32(res[0])
+		record.StatusCode = &status
+	}
+
+	if werr := l.write(record); werr != nil && l.logger != nil {
+		l.logger.Warn("Failed to record guest call, the next failures aren't logged", zap.String("path", l.cfg.Path), zap.Error(werr))
+	}
+	return res, err
+}
+
+// write appends record to the file, rotating it first if it would exceed
+// the maximum size. Only the first failure is returned.
+func (l *callLog) write(record callRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return l.fail(err)
+	}
+	line = append(line, '\n')
+	if l.file == nil {
+		// The file couldn't be reopened by the last rotation.
+		if err := l.open(); err != nil {
+			return l.fail(err)
+		}
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.cfg.MaxSizeBytes {
+		if err := l.rotate(); err != nil {
+			return l.fail(err)
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return l.fail(err)
+	}
+	return nil
+}
+
+// fail returns err if it is the first failure of the log, nil otherwise.
+// Called with mu held.
+func (l *callLog) fail(err error) error {
+	if l.failed {
+		return nil
+	}
+	l.failed = true
+	return err
+}
+
+// rotate renames the file to its first backup, shifting the older backups
+// and dropping the oldest, then opens a new file.
+func (l *callLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	path := l.cfg.Path
+	if l.cfg.MaxBackups == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return l.open()
+	}
+	for i := l.cfg.MaxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return err
+	}
+	return l.open()
+}
@@ -0,0 +1,135 @@
+package wasmplugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+)
+
+// readCallRecords returns the records of the call log at path.
+func readCallRecords(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open call log: %v", err)
+	}
+	defer file.Close()
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("failed to decode record %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestCallLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("processTraces", 1))
+	plugin := newTestPlugin(t, mod, Config{CallLog: CallLogConfig{Path: path}}, "processTraces")
+
+	for range 3 {
+		if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{}); err != nil {
+			t.Fatalf("failed to call the guest: %v", err)
+		}
+	}
+
+	// The built-in functions probed on creation aren't recorded.
+	records := readCallRecords(t, path)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d: %v", len(records), records)
+	}
+	for _, record := range records {
+		for _, field := range []string{"time", "function", "input_size", "output_size", "duration_ns", "status_code"} {
+			if _, ok := record[field]; !ok {
+				t.Errorf("expected the field %s in %v", field, record)
+			}
+		}
+		if record["function"] != "processTraces" || record["status_code"] != float64(1) {
+			t.Errorf("expected processTraces with the status code 1, got %v", record)
+		}
+		if _, ok := record["error"]; ok {
+			t.Errorf("expected no error in %v", record)
+		}
+	}
+}
+
+func TestCallLogFailedCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), wasmtest.Function{
+		Export: "processTraces",
+		Body:   wasmtest.Unreachable,
+	})
+	plugin := newTestPlugin(t, mod, Config{CallLog: CallLogConfig{Path: path}}, "processTraces")
+
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{}); err == nil {
+		t.Fatal("expected the call to fail")
+	}
+	records := readCallRecords(t, path)
+	if len(records) != 1 || records[0]["error"] == nil || records[0]["status_code"] != nil {
+		t.Errorf("expected a record with an error and no status code, got %v", records)
+	}
+}
+
+func TestCallLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("processTraces", 0))
+	// Every record exceeds the maximum size, so each call rotates the file.
+	cfg := Config{CallLog: CallLogConfig{Path: path, MaxSizeBytes: 1, MaxBackups: 2}}
+	plugin := newTestPlugin(t, mod, cfg, "processTraces")
+
+	for range 4 {
+		if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{}); err != nil {
+			t.Fatalf("failed to call the guest: %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if records := readCallRecords(t, name); len(records) != 1 {
+			t.Errorf("expected a record in %s, got %d", name, len(records))
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected no more than 2 backups, got %v", err)
+	}
+}
+
+func TestCallLogShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	cfg := Config{CallLog: CallLogConfig{Path: path}}
+	first := newTestPlugin(t, wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("processTraces", 0)), cfg, "processTraces")
+	second := newTestPlugin(t, wasmtest.NewGuest(int32(telemetryTypeLogs), returnsI32("processLogs", 0)), cfg, "processLogs")
+	if first.callLog != second.callLog {
+		t.Fatal("expected the plugins to share the call log")
+	}
+
+	if _, err := first.ProcessFunctionCall(t.Context(), "processTraces", &Stack{}); err != nil {
+		t.Fatalf("failed to call the guest: %v", err)
+	}
+	if err := first.Shutdown(t.Context()); err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+	// The log stays open for the remaining plugin.
+	if _, err := second.ProcessFunctionCall(t.Context(), "processLogs", &Stack{}); err != nil {
+		t.Fatalf("failed to call the guest: %v", err)
+	}
+	if records := readCallRecords(t, path); len(records) != 2 {
+		t.Errorf("expected 2 records, got %v", records)
+	}
+}
+
+func TestCallLogConfigValidate(t *testing.T) {
+	for _, cfg := range []CallLogConfig{{MaxSizeBytes: -1}, {MaxBackups: -1}} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
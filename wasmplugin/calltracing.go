@@ -0,0 +1,59 @@
+package wasmplugin
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Guest call span attributes
+	callFunctionAttribute   = "wasm.function"
+	callInputSizeAttribute  = "wasm.call.input.size"
+	callOutputSizeAttribute = "wasm.call.output.size"
+	callStatusCodeAttribute = "wasm.call.status_code"
+
+	// traceParentHeader is the W3C trace context header passed to the guest
+	traceParentHeader = "traceparent"
+)
+
+// traceCall calls call in a span named after the guest function, recording
+// the size of the telemetry read and written by the guest and the status
+// code it returned.
+func (p *WasmPlugin) traceCall(ctx context.Context, functionName string, stack *Stack, call func(context.Context) ([]uint64, error)) ([]uint64, error) {
+	ctx, span := p.tracer.Start(ctx, functionName, trace.WithAttributes(
+		attribute.String(callFunctionAttribute, functionName),
+	))
+	defer span.End()
+
+	res, err := call(ctx)
+	span.SetAttributes(
+		attribute.Int64(callInputSizeAttribute, int64(stack.inputSize)),
+		attribute.Int64(callOutputSizeAttribute, int64(stack.outputSize)),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return res, err
+	}
+	if len(res) > 0 {
+		span.SetAttributes(attribute.Int64(callStatusCodeAttribute, int64(uint32(res[0]))))
+	}
+	return res, nil
+}
+
+// getTraceParentFn writes the W3C traceparent of the span of the call to the
+// guest memory, so the spans of the guest can be its children. Nothing is
+// written if the call has no span.
+func getTraceParentFn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf := uint32(stack[0])
+	bufLimit := uint32(stack[1])
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	stack[0] = uint64(writeResult(mod, []byte(carrier.Get(traceParentHeader)), buf, bufLimit))
+}
@@ -0,0 +1,95 @@
+package wasmplugin
+
+import (
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// tracingGuest returns a guest whose processTraces reads the current traces,
+// sets empty result traces, and echoes the trace parent as the status
+// reason.
+func tracingGuest() *wasmtest.Module {
+	const bufOffset = 1024
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, currentTraces, []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, setResultTraces, []api.ValueType{i32, i32}, nil).
+		Import(wasmtest.HostModule, getTraceParent, []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{i32, i32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{i32},
+		Locals:  []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(bufOffset), wasmtest.I32Const(1024), mod.Call(currentTraces), wasmtest.Drop,
+			wasmtest.I32Const(0), wasmtest.I32Const(0), mod.Call(setResultTraces),
+			wasmtest.I32Const(bufOffset), wasmtest.I32Const(1024), mod.Call(getTraceParent),
+			wasmtest.LocalSet(0),
+			wasmtest.I32Const(bufOffset), wasmtest.LocalGet(0), mod.Call(setResultStatusReason),
+			wasmtest.I32Const(0),
+		),
+	})
+	return mod
+}
+
+func TestTraceGuestCalls(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		exporter := tracetest.NewInMemoryExporter()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		cfg := Config{Path: tracingGuest().Write(t), TraceGuestCalls: enabled}
+		cfg.Default()
+		plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"}, WithTracerProvider(tp))
+		if err != nil {
+			t.Fatalf("failed to create plugin: %v", err)
+		}
+		defer plugin.Shutdown(t.Context())
+
+		traces := ptrace.NewTraces()
+		traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("checkout")
+		inputSize := (&ptrace.ProtoMarshaler{}).TracesSize(traces)
+		stack := &Stack{CurrentTraces: traces}
+		if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+			t.Fatalf("failed to call processTraces: %v", err)
+		}
+
+		spans := exporter.GetSpans()
+		if !enabled {
+			if len(spans) != 0 || stack.StatusReason != "" {
+				t.Errorf("expected no span, got %d spans and trace parent %q", len(spans), stack.StatusReason)
+			}
+			continue
+		}
+		// The spans of the built-in function calls aren't recorded.
+		if len(spans) != 1 {
+			t.Fatalf("expected 1 span, got %d", len(spans))
+		}
+		span := spans[0]
+		if span.Name != "processTraces" {
+			t.Errorf("expected span processTraces, got %s", span.Name)
+		}
+		want := map[attribute.Key]int64{
+			callInputSizeAttribute:  int64(inputSize),
+			callOutputSizeAttribute: 0,
+			callStatusCodeAttribute: 0,
+		}
+		for _, attr := range span.Attributes {
+			if v, ok := want[attr.Key]; ok && attr.Value.AsInt64() != v {
+				t.Errorf("expected %s=%d, got %d", attr.Key, v, attr.Value.AsInt64())
+			}
+			delete(want, attr.Key)
+		}
+		if len(want) != 0 {
+			t.Errorf("missing attributes %v", want)
+		}
+		wantParent := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"
+		if stack.StatusReason != wantParent {
+			t.Errorf("expected trace parent %s, got %s", wantParent, stack.StatusReason)
+		}
+	}
+}
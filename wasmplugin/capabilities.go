@@ -0,0 +1,55 @@
+package wasmplugin
+
+import (
+	"context"
+	"fmt"
+)
+
+// Capabilities is the set of capabilities declared by the guest through its
+// optional getCapabilities function.
+type Capabilities uint32
+
+const (
+	// CapabilityMutatesData means the guest mutates the data it's passed, so
+	// the collector must clone shared data before passing it.
+	CapabilityMutatesData Capabilities = 1 << iota
+
+	// CapabilityCompressedLogs means the guest reads and writes the logs
+	// compressed with gzip if the host compresses them, see
+	// Config.LogsCompression.
+	CapabilityCompressedLogs
+
+	// CapabilityTracesJSON means the guest reads the traces in OTLP JSON,
+	// and prefers them so, see Config.TracesEncoding. The guest still reads
+	// OTLP protobuf if the host is configured to pass it.
+	CapabilityTracesJSON
+)
+
+// DefaultCapabilities are the capabilities of guests not exporting
+// getCapabilities.
+const DefaultCapabilities = CapabilityMutatesData
+
+// MutatesData reports whether c includes CapabilityMutatesData.
+func (c Capabilities) MutatesData() bool {
+	return c&CapabilityMutatesData != 0
+}
+
+// readCapabilities returns the capabilities declared by the guest.
+func (p *WasmPlugin) readCapabilities(ctx context.Context) (Capabilities, error) {
+	if _, ok := p.ExportedFunctions[getCapabilities]; !ok {
+		return DefaultCapabilities, nil
+	}
+	res, err := p.ProcessFunctionCall(ctx, getCapabilities, &Stack{})
+	if err != nil {
+		return 0, fmt.Errorf("wasm: failed to get capabilities: %w", err)
+	}
+	if len(res) != 1 {
+		return 0, fmt.Errorf("wasm: %s must return the capabilities: %w", getCapabilities, ErrInvalidModule)
+	}
+	return Capabilities(res[0]), nil
+}
+
+// Capabilities returns the capabilities declared by the guest.
+func (p *WasmPlugin) Capabilities() Capabilities {
+	return p.capabilities
+}
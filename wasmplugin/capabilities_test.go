@@ -0,0 +1,47 @@
+package wasmplugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+)
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name        string
+		declaration []wasmtest.Function
+		want        Capabilities
+	}{
+		{name: "undeclared", want: DefaultCapabilities},
+		{name: "declared none", declaration: []wasmtest.Function{returnsI32("getCapabilities", 0)}, want: 0},
+		{name: "declared mutates data", declaration: []wasmtest.Function{returnsI32("getCapabilities", 1)}, want: CapabilityMutatesData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := wasmtest.NewGuest(int32(telemetryTypeTraces), tt.declaration...)
+			plugin := newTestPlugin(t, mod, Config{})
+
+			if got := plugin.Capabilities(); got != tt.want {
+				t.Errorf("expected capabilities %b, got %b", tt.want, got)
+			}
+			if plugin.Capabilities().MutatesData() != tt.want.MutatesData() {
+				t.Errorf("expected MutatesData() = %v", tt.want.MutatesData())
+			}
+		})
+	}
+}
+
+func TestCapabilitiesWithoutResult(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), wasmtest.Function{Export: "getCapabilities", Body: wasmtest.Nop})
+	cfg := Config{Path: mod.Write(t)}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, nil)
+	if err == nil {
+		plugin.Shutdown(t.Context())
+	}
+	if !errors.Is(err, ErrInvalidModule) {
+		t.Errorf("expected ErrInvalidModule, got %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package wasmplugin
+
+import "time"
+
+// Clock returns the wall clock and monotonic clock readings served to the
+// guest by the hostNow host function, in nanoseconds. The wall clock reading
+// is relative to the Unix epoch, the monotonic one to an arbitrary origin.
+type Clock func() (wall, monotonic int64)
+
+// clockOrigin is the origin of the monotonic readings of SystemClock.
+var clockOrigin = time.Now()
+
+// SystemClock is the Clock reading the host clock.
+func SystemClock() (wall, monotonic int64) {
+	now := time.Now()
+	return now.UnixNano(), int64(now.Sub(clockOrigin))
+}
@@ -0,0 +1,53 @@
+package wasmplugin
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestHostNow(t *testing.T) {
+	// processTraces reads the clock into memory, then echoes the readings
+	// as the status reason.
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, hostNow, []api.ValueType{api.ValueTypeI32}, nil).
+		Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), mod.Call(hostNow),
+			wasmtest.I32Const(0), wasmtest.I32Const(16), mod.Call(setResultStatusReason),
+			wasmtest.I32Const(0),
+		),
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+
+	stack := &Stack{
+		Clock: func() (wall, monotonic int64) { return 1700000000_000000001, 42 },
+	}
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+		t.Fatalf("failed to call processTraces: %v", err)
+	}
+
+	readings := []byte(stack.StatusReason)
+	if len(readings) != 16 {
+		t.Fatalf("expected 16 bytes of readings, got %d", len(readings))
+	}
+	if wall := int64(binary.LittleEndian.Uint64(readings)); wall != 1700000000_000000001 {
+		t.Errorf("expected the fake wall clock, got %d", wall)
+	}
+	if monotonic := int64(binary.LittleEndian.Uint64(readings[8:])); monotonic != 42 {
+		t.Errorf("expected the fake monotonic clock, got %d", monotonic)
+	}
+}
+
+func TestSystemClock(t *testing.T) {
+	_, before := SystemClock()
+	_, after := SystemClock()
+	if after < before {
+		t.Errorf("expected monotonic readings not to go backwards, got %d then %d", before, after)
+	}
+}
@@ -0,0 +1,43 @@
+package wasmplugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// componentInfo describes the component running the guest, served to the
+// guest as JSON by getComponentInfo.
+type componentInfo struct {
+	// ID is the ID of the component, e.g. "wasm/traces".
+	ID string `json:"component_id"`
+
+	// ResourceAttributes are the attributes of the resource of the collector,
+	// e.g. service.name and service.version.
+	ResourceAttributes map[string]any `json:"resource_attributes"`
+}
+
+// WithComponent sets the ID of the component running the guest and the
+// resource of the collector, which the guest reads with getComponentInfo.
+// The guest reads an empty ID and no attribute by default.
+func WithComponent(id string, resource pcommon.Resource) Option {
+	return func(o *options) {
+		o.component = componentInfo{ID: id, ResourceAttributes: resource.Attributes().AsRaw()}
+	}
+}
+
+// newGetComponentInfoFn returns the host function writing info to the guest
+// memory as JSON.
+func newGetComponentInfoFn(info componentInfo) api.GoModuleFunc {
+	if info.ResourceAttributes == nil {
+		info.ResourceAttributes = map[string]any{}
+	}
+	b, _ := json.Marshal(info)
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		buf := uint32(stack[0])
+		bufLimit := uint32(stack[1])
+		stack[0] = uint64(writeResult(mod, b, buf, bufLimit))
+	}
+}
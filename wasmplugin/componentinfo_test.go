@@ -0,0 +1,68 @@
+package wasmplugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// readComponentInfo returns the component info read by a guest created with
+// opts.
+func readComponentInfo(t *testing.T, opts ...Option) componentInfo {
+	t.Helper()
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, getComponentInfo, []api.ValueType{i32, i32}, []api.ValueType{i32})
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{i32},
+		Body:    wasmtest.Instructions(wasmtest.I32Const(0), wasmtest.I32Const(1024), mod.Call(getComponentInfo)),
+	})
+	cfg := Config{Path: mod.Write(t)}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"}, opts...)
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	t.Cleanup(func() { plugin.Shutdown(t.Context()) })
+
+	res, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+	if err != nil {
+		t.Fatalf("failed to call the guest: %v", err)
+	}
+	b, ok := plugin.Module.Memory().Read(0, uint32(res[0]))
+	if !ok {
+		t.Fatalf("failed to read %d bytes of guest memory", res[0])
+	}
+	var info componentInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		t.Fatalf("failed to decode %s: %v", b, err)
+	}
+	return info
+}
+
+func TestGetComponentInfo(t *testing.T) {
+	resource := pcommon.NewResource()
+	resource.Attributes().PutStr("service.name", "otelcol")
+	resource.Attributes().PutStr("service.version", "1.2.3")
+	resource.Attributes().PutInt("host.cpus", 8)
+
+	info := readComponentInfo(t, WithComponent("wasm/traces", resource))
+	want := componentInfo{ID: "wasm/traces", ResourceAttributes: resource.Attributes().AsRaw()}
+	// JSON numbers are read as float64.
+	want.ResourceAttributes["host.cpus"] = float64(8)
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("expected %+v, got %+v", want, info)
+	}
+}
+
+func TestGetComponentInfoUnset(t *testing.T) {
+	info := readComponentInfo(t)
+	if info.ID != "" || info.ResourceAttributes == nil || len(info.ResourceAttributes) != 0 {
+		t.Errorf("expected an empty ID and no attribute, got %+v", info)
+	}
+}
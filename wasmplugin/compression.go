@@ -0,0 +1,100 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// LogsCompression is the compression of the serialized logs passed between
+// the host and the guest.
+type LogsCompression string
+
+const (
+	// LogsCompressionNone passes the logs uncompressed. This is the default.
+	LogsCompressionNone LogsCompression = "none"
+
+	// LogsCompressionGzip compresses the logs with gzip at its best speed,
+	// trading CPU for smaller copies through the guest memory. The guests
+	// not declaring CapabilityCompressedLogs, built with an older SDK, are
+	// passed uncompressed logs regardless. Decompressing in the guest costs
+	// more than the copies it saves in BenchmarkLogsCompressionWasmInterpreter
+	// of wasmprocessor, at any batch size, so measure it with the plugin
+	// before enabling it.
+	LogsCompressionGzip LogsCompression = "gzip"
+)
+
+func (c LogsCompression) validate() error {
+	switch c {
+	case "", LogsCompressionNone, LogsCompressionGzip:
+		return nil
+	default:
+		return fmt.Errorf("invalid logs_compression: %s", c)
+	}
+}
+
+// gzipMagic starts every gzip stream. Serialized logs never start with it,
+// as 0x1f isn't a valid protobuf tag, so compressed results are told from
+// uncompressed ones.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gzipWriters are the writers compressing the logs passed to the guests.
+var gzipWriters = sync.Pool{
+	New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return w
+	},
+}
+
+// compressLogs returns b compressed with gzip.
+func compressLogs(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(b) / 4)
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressLogs returns b decompressed if it is a gzip stream, as is
+// otherwise.
+func decompressLogs(b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, gzipMagic) {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// currentLogsBytes returns CurrentLogs serialized, compressed if the guest
+// is passed compressed logs. The result is cached so a guest retrying with a
+// larger buffer doesn't marshal them again.
+func (s *Stack) currentLogsBytes() ([]byte, error) {
+	if s.currentLogsProto != nil && s.currentLogsProtoOf == s.CurrentLogs {
+		return s.currentLogsProto, nil
+	}
+	b, err := (&plog.ProtoMarshaler{}).MarshalLogs(s.CurrentLogs)
+	if err != nil {
+		return nil, err
+	}
+	if s.compressLogs {
+		if b, err = compressLogs(b); err != nil {
+			return nil, err
+		}
+	}
+	s.currentLogsProto, s.currentLogsProtoOf = b, s.CurrentLogs
+	return b, nil
+}
@@ -0,0 +1,112 @@
+package wasmplugin
+
+import (
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func testLogs(n int) plog.Logs {
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for range n {
+		records.AppendEmpty().Body().SetStr("the same message, compressing well")
+	}
+	return logs
+}
+
+// logsGuest returns a guest declaring caps, exporting "logs", which returns
+// the size of the current logs, and "result", which sets the result logs to
+// result.
+func logsGuest(caps int32, result []byte) *wasmtest.Module {
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeLogs), returnsI32("getCapabilities", caps)).
+		Import(wasmtest.HostModule, currentLogs, []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, setResultLogs, []api.ValueType{i32, i32}, nil)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: result}}
+	mod.Functions = append(mod.Functions,
+		wasmtest.Function{
+			Export:  "logs",
+			Results: []api.ValueType{i32},
+			// A zero limit, so the host only returns the size.
+			Body: wasmtest.Instructions(wasmtest.I32Const(0), wasmtest.I32Const(0), mod.Call(currentLogs)),
+		},
+		wasmtest.Function{
+			Export: "result",
+			Body:   wasmtest.Instructions(wasmtest.I32Const(0), wasmtest.I32Const(int32(len(result))), mod.Call(setResultLogs)),
+		},
+	)
+	return mod
+}
+
+func TestLogsCompressionNegotiation(t *testing.T) {
+	logs := testLogs(100)
+	plain, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := compressLogs(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		compression LogsCompression
+		caps        int32
+		want        int
+	}{
+		{name: "default", caps: int32(CapabilityCompressedLogs), want: len(plain)},
+		{name: "none", compression: LogsCompressionNone, caps: int32(CapabilityCompressedLogs), want: len(plain)},
+		{name: "gzip", compression: LogsCompressionGzip, caps: int32(CapabilityCompressedLogs), want: len(compressed)},
+		{name: "gzip to an older guest", compression: LogsCompressionGzip, want: len(plain)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, logsGuest(tt.caps, nil), Config{LogsCompression: tt.compression}, "logs")
+
+			res, err := plugin.ProcessFunctionCall(t.Context(), "logs", &Stack{CurrentLogs: logs})
+			if err != nil {
+				t.Fatalf("failed to call the guest: %v", err)
+			}
+			if got := int(res[0]); got != tt.want {
+				t.Errorf("expected the guest to be passed %d bytes of logs, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLogsCompressionResult(t *testing.T) {
+	logs := testLogs(10)
+	plain, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := compressLogs(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod := logsGuest(int32(CapabilityCompressedLogs), compressed)
+	plugin := newTestPlugin(t, mod, Config{LogsCompression: LogsCompressionGzip}, "result")
+	stack := &Stack{CurrentLogs: plog.NewLogs()}
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "result", stack); err != nil {
+		t.Fatalf("failed to call the guest: %v", err)
+	}
+	if got := stack.ResultLogs.LogRecordCount(); got != 10 {
+		t.Errorf("expected the compressed result of 10 records to be read, got %d", got)
+	}
+}
+
+func TestLogsCompressionValidate(t *testing.T) {
+	for _, c := range []LogsCompression{"", LogsCompressionNone, LogsCompressionGzip} {
+		if err := c.validate(); err != nil {
+			t.Errorf("expected %q to be valid, got %v", c, err)
+		}
+	}
+	if err := LogsCompression("zstd").validate(); err == nil {
+		t.Error("expected an unknown compression to be refused")
+	}
+}
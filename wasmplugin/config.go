@@ -1,6 +1,9 @@
 package wasmplugin
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // PluginConfig is a generic configuration type that can be passed to WASM modules
 type PluginConfig map[string]interface{}
@@ -31,15 +34,27 @@ type RuntimeConfig struct {
 	// Mode is the runtime mode for the WASM plugin.
 	// The default is "interpreter".
 	Mode RuntimeMode `mapstructure:"mode,omitempty"`
+
+	// Fallbacks are the runtime modes tried in order if the guest fails to
+	// compile or instantiate with Mode, e.g. to migrate modules with quirks
+	// from a mode to another.
+	Fallbacks []RuntimeMode `mapstructure:"fallbacks,omitempty"`
 }
 
 func (cfg *RuntimeConfig) Validate() error {
-	if cfg.Mode != RuntimeModeInterpreter && cfg.Mode != RuntimeModeCompiled {
-		return fmt.Errorf("invalid runtime mode: %s", cfg.Mode)
+	for _, mode := range cfg.modes() {
+		if mode != RuntimeModeInterpreter && mode != RuntimeModeCompiled {
+			return fmt.Errorf("invalid runtime mode: %s", mode)
+		}
 	}
 	return nil
 }
 
+// modes returns the runtime modes in the order they are tried.
+func (cfg *RuntimeConfig) modes() []RuntimeMode {
+	return append([]RuntimeMode{cfg.Mode}, cfg.Fallbacks...)
+}
+
 // Default sets the default values for the runtime configuration
 // if they are not set.
 func (cfg *RuntimeConfig) Default() {
@@ -53,16 +68,153 @@ var DefaultRuntimeConfig = RuntimeConfig{
 	Mode: RuntimeModeInterpreter,
 }
 
+// ABIConfig is the configuration of how the host resolves the functions
+// exported by the guest.
+type ABIConfig struct {
+	// FunctionPrefixes is the ordered list of prefixes probed when resolving
+	// guest function exports such as "processTraces". The host uses the first
+	// prefix under which the function is exported, which lets modules built
+	// against several ABI generations coexist. An empty string stands for the
+	// unprefixed name.
+	// The default is to resolve unprefixed names only.
+	FunctionPrefixes []string `mapstructure:"function_prefixes,omitempty"`
+}
+
 // Config defines the common configuration for WASM components
 type Config struct {
-	// Path to the WASM module file
+	// Path to the WASM module file, which may be gzipped, e.g. a .wasm.gz
+	// file.
 	Path string `mapstructure:"path"`
 
 	// PluginConfig is the configuration to be passed to the WASM module
 	PluginConfig PluginConfig `mapstructure:"plugin_config"`
 
+	// PluginConfigFiles maps names to the paths of files passed to the
+	// module as raw bytes, through the getConfigFile host function, e.g. for
+	// binary or large configuration such as databases. The files are read
+	// once, when the module is instantiated.
+	PluginConfigFiles map[string]string `mapstructure:"plugin_config_files,omitempty"`
+
 	// Runtime is the configuration of WASM plugin runtime.
 	RuntimeConfig RuntimeConfig `mapstructure:"runtime"`
+
+	// CompiledCacheDir is the directory the compiled module is persisted to
+	// and reused from on subsequent starts, skipping the compilation, e.g.
+	// across container restarts with a mounted volume. Only the compiled
+	// runtime mode compiles the module. The compilation isn't persisted if
+	// empty.
+	CompiledCacheDir string `mapstructure:"compiled_cache_dir,omitempty"`
+
+	// ABI is the configuration of the host/guest function naming.
+	ABI ABIConfig `mapstructure:"abi"`
+
+	// SlowCallThreshold is the duration past which a guest function call is
+	// reported as slow, by adding an event to the span found in the context
+	// of the call. Zero disables the report.
+	SlowCallThreshold time.Duration `mapstructure:"slow_call_threshold,omitempty"`
+
+	// ExecutionTimeout is the maximum duration of a guest function call. A
+	// guest exceeding it is closed, as its state can't be trusted anymore,
+	// and fails every subsequent call. Zero disables the timeout.
+	ExecutionTimeout time.Duration `mapstructure:"execution_timeout,omitempty"`
+
+	// Quota is the configuration of the resources the guest may consume.
+	Quota QuotaConfig `mapstructure:"quota"`
+
+	// MaxMemoryPages is the maximum number of 64KiB memory pages of the
+	// guest. The guest isn't instantiated if its initial memory exceeds it,
+	// and its memory doesn't grow past it: the guest call failing for the
+	// lack of memory returns ErrMemoryLimitExceeded. Zero sets no limit but
+	// the global one.
+	MaxMemoryPages uint32 `mapstructure:"max_memory_pages,omitempty"`
+
+	// GlobalMemoryLimitPages is the maximum number of 64KiB memory pages of
+	// all the guests of the collector together. Guests aren't instantiated,
+	// and their memory doesn't grow, past it. The limit is process-wide, so
+	// the lowest value configured by the components applies. Zero sets no
+	// limit.
+	GlobalMemoryLimitPages uint32 `mapstructure:"global_memory_limit_pages,omitempty"`
+
+	// State is the configuration of the key-value store the guest keeps its
+	// state in across calls and, if persisted, restarts.
+	State StateConfig `mapstructure:"state"`
+
+	// ErrorLog is the configuration of the logging of the guest failures.
+	ErrorLog ErrorLogConfig `mapstructure:"error_log"`
+
+	// EnvAllowlist is the list of the host environment variables exposed to
+	// the guest, through WASI and the getEnv host function. Other variables
+	// are hidden from the guest.
+	EnvAllowlist []string `mapstructure:"env_allowlist,omitempty"`
+
+	// EnvPassthrough exposes the whole host environment to the guest,
+	// ignoring EnvAllowlist, as guests were exposed before the allowlist.
+	//
+	// Deprecated: list the variables the guest reads in EnvAllowlist
+	// instead. The option will be removed in a future release.
+	EnvPassthrough bool `mapstructure:"env_passthrough,omitempty"`
+
+	// ExpectedDigest is the digest the module file must match to be loaded,
+	// of the form "sha256:<hex>". The digest of a gzipped module is the one
+	// of the decompressed module. The module isn't verified if empty.
+	ExpectedDigest string `mapstructure:"expected_digest,omitempty"`
+
+	// TraceHostCalls logs every host function call of the guest, with its
+	// parameters and results, at the debug level. It is meant for debugging
+	// guests as it slows every host call down.
+	TraceHostCalls bool `mapstructure:"trace_host_calls,omitempty"`
+
+	// RandSeed seeds the pseudo-random generator the guest draws numbers from
+	// with randUint64, so its decisions, e.g. sampling ones, are reproducible
+	// in tests and replays. Zero, the default, draws the numbers from a
+	// cryptographically secure source instead.
+	RandSeed uint64 `mapstructure:"rand_seed,omitempty"`
+
+	// LogsCompression is the compression of the logs passed to and from the
+	// guest, "none" or "gzip", which trades CPU for smaller copies through the
+	// guest memory, e.g. for large batches of verbose logs. The default is
+	// "none".
+	LogsCompression LogsCompression `mapstructure:"logs_compression,omitempty"`
+
+	// TracesEncoding is the encoding of the traces passed to the guest,
+	// "proto" or "json". It is left to the guest by default: JSON if it
+	// declares CapabilityTracesJSON, protobuf otherwise. The traces set by
+	// the guest are decoded in the same encoding.
+	TracesEncoding TracesEncoding `mapstructure:"traces_encoding,omitempty"`
+
+	// HTTP is the configuration of the HTTP requests the guest sends through
+	// the host. The guest can't send any by default.
+	HTTP HTTPConfig `mapstructure:"http"`
+
+	// Files is the configuration of the host files the guest reads through
+	// the host. The guest can't read any by default.
+	Files FilesConfig `mapstructure:"files"`
+
+	// Hash is the configuration of the hashes the guest computes through
+	// the host.
+	Hash HashConfig `mapstructure:"hash"`
+
+	// StdioPassthrough writes what the guest writes to its standard output
+	// and error to the collector's as is. By default, each line is logged
+	// with the logger of the component at the debug level, with the source
+	// field set to guest-stdout or guest-stderr, so the container logs stay
+	// structured.
+	StdioPassthrough bool `mapstructure:"stdio_passthrough,omitempty"`
+
+	// TraceGuestCalls starts a span around each guest function call, with
+	// the tracer of the component, recording the size of the telemetry
+	// passed to and from the guest. The guest may read the span context to
+	// start child spans. It is disabled by default to save the overhead in
+	// hot paths.
+	TraceGuestCalls bool `mapstructure:"trace_guest_calls,omitempty"`
+
+	// CallLog is the configuration of the recording of the guest calls to a
+	// file. Calls aren't recorded by default.
+	CallLog CallLogConfig `mapstructure:"call_log"`
+
+	// TestingFaultInjection fails guest calls on purpose. FOR TESTING ONLY,
+	// never enable it in production.
+	TestingFaultInjection FaultInjectionConfig `mapstructure:"testing_fault_injection"`
 }
 
 // Validate validates the configuration
@@ -74,6 +226,60 @@ func (cfg *Config) Validate() error {
 	if cfg.Path == "" {
 		return fmt.Errorf("path is required")
 	}
+
+	if err := validateConfigFiles(cfg.PluginConfigFiles); err != nil {
+		return err
+	}
+
+	if cfg.SlowCallThreshold < 0 {
+		return fmt.Errorf("slow_call_threshold must not be negative")
+	}
+
+	if cfg.ExecutionTimeout < 0 {
+		return fmt.Errorf("execution_timeout must not be negative")
+	}
+
+	if err := cfg.Quota.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.State.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.ErrorLog.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.LogsCompression.validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.TracesEncoding.validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.HTTP.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Files.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.CallLog.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.TestingFaultInjection.Validate(); err != nil {
+		return err
+	}
+
+	if cfg.ExpectedDigest != "" {
+		if _, err := parseDigest(cfg.ExpectedDigest); err != nil {
+			return fmt.Errorf("expected_digest: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -81,4 +287,9 @@ func (cfg *Config) Validate() error {
 // if they are not set.
 func (cfg *Config) Default() {
 	cfg.RuntimeConfig.Default()
+	cfg.Quota.Default()
+	cfg.State.Default()
+	cfg.ErrorLog.Default()
+	cfg.HTTP.Default()
+	cfg.CallLog.Default()
 }
@@ -2,6 +2,7 @@ package wasmplugin
 
 import (
 	"testing"
+	"time"
 )
 
 func TestRuntimeConfigValidate(t *testing.T) {
@@ -31,6 +32,22 @@ func TestRuntimeConfigValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid fallbacks",
+			config: RuntimeConfig{
+				Mode:      RuntimeModeCompiled,
+				Fallbacks: []RuntimeMode{RuntimeModeInterpreter},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid fallback",
+			config: RuntimeConfig{
+				Mode:      RuntimeModeCompiled,
+				Fallbacks: []RuntimeMode{"wasmtime"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -87,6 +104,15 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "relative allowed file path",
+			config: Config{
+				Path:          "test.wasm",
+				RuntimeConfig: RuntimeConfig{Mode: RuntimeModeInterpreter},
+				Files:         FilesConfig{AllowedPaths: []string{"logs"}},
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing path",
 			config: Config{
@@ -119,6 +145,116 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "negative slow call threshold",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				SlowCallThreshold: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative execution timeout",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				ExecutionTimeout: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid fault injection rate",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				TestingFaultInjection: FaultInjectionConfig{Rate: 1.5},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid fault injection call",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				TestingFaultInjection: FaultInjectionConfig{Calls: []int64{0}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative error log interval",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				ErrorLog: ErrorLogConfig{Interval: -time.Second},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative state limit",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				State: StateConfig{MaxEntries: -1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing plugin config file",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				PluginConfigFiles: map[string]string{"geoip": "testdata/missing.db"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "plugin config file directory",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				PluginConfigFiles: map[string]string{"geoip": "."},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid plugin config file",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				PluginConfigFiles: map[string]string{"config": "config.go"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid expected digest",
+			config: Config{
+				Path: "test.wasm",
+				RuntimeConfig: RuntimeConfig{
+					Mode: RuntimeModeInterpreter,
+				},
+				ExpectedDigest: "md5:d41d8cd98f00b204e9800998ecf8427e",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
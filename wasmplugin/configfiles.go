@@ -0,0 +1,75 @@
+package wasmplugin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// configFileNotFound is returned by getConfigFile if no file is configured
+// under the name.
+const configFileNotFound = math.MaxUint32
+
+// validateConfigFiles checks that the files of plugin_config_files exist and
+// are regular files.
+func validateConfigFiles(files map[string]string) error {
+	for name, path := range files {
+		if name == "" {
+			return fmt.Errorf("plugin_config_files: file name must not be empty")
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("plugin_config_files: %s: %w", name, err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("plugin_config_files: %s: %s is not a regular file", name, path)
+		}
+	}
+	return nil
+}
+
+// readConfigFiles reads the files of plugin_config_files, by name.
+func readConfigFiles(files map[string]string) (map[string][]byte, error) {
+	contents := make(map[string][]byte, len(files))
+	for name, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("wasm: error reading config file %s: %w", name, err)
+		}
+		contents[name] = content
+	}
+	return contents, nil
+}
+
+// newGetConfigFileFn returns the getConfigFile host function exposing the
+// contents of the config files.
+func newGetConfigFileFn(files map[string][]byte) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		name := uint32(stack[0])
+		nameLen := uint32(stack[1])
+		buf := uint32(stack[2])
+		bufLimit := uint32(stack[3])
+
+		nameBytes, ok := mod.Memory().Read(name, nameLen)
+		if !ok {
+			paramsFromContext(ctx).recordHostError(getConfigFile, errOutOfMemory)
+			stack[0] = configFileNotFound
+			return
+		}
+
+		content, ok := files[string(nameBytes)]
+		if !ok {
+			stack[0] = configFileNotFound
+			return
+		}
+		// The length is returned even if the content doesn't fit, so the
+		// guest can retry with a large enough buffer.
+		if uint32(len(content)) <= bufLimit {
+			mod.Memory().Write(buf, content)
+		}
+		stack[0] = uint64(len(content))
+	}
+}
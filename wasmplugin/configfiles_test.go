@@ -0,0 +1,75 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestGetConfigFile(t *testing.T) {
+	// A binary file, which would need encoding to pass through JSON.
+	content := []byte{0x00, 0xff, 0x10, 'g', 'e', 'o', 0x00}
+	path := filepath.Join(t.TempDir(), "geoip.db")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// processTraces reads the file named in the data segment into a buffer of
+	// bufLimit bytes, echoes the buffer as the status reason and returns the
+	// result of getConfigFile.
+	const bufOffset = 1024
+	newGuest := func(file string, bufLimit int32) *wasmtest.Module {
+		mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+			Import(wasmtest.HostModule, getConfigFile, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
+			Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+		mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte(file)}}
+		mod.Functions = append(mod.Functions, wasmtest.Function{
+			Export:  "processTraces",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Locals:  []api.ValueType{api.ValueTypeI32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(int32(len(file))),
+				wasmtest.I32Const(bufOffset), wasmtest.I32Const(bufLimit), mod.Call(getConfigFile),
+				wasmtest.LocalSet(0),
+				wasmtest.I32Const(bufOffset), wasmtest.I32Const(bufLimit), mod.Call(setResultStatusReason),
+				wasmtest.LocalGet(0),
+			),
+		})
+		return mod
+	}
+
+	tests := []struct {
+		name       string
+		file       string
+		bufLimit   int32
+		wantStatus uint32
+		wantBuf    []byte
+	}{
+		{name: "configured", file: "geoip", bufLimit: int32(len(content)), wantStatus: uint32(len(content)), wantBuf: content},
+		{name: "buffer too small", file: "geoip", bufLimit: 4, wantStatus: uint32(len(content)), wantBuf: make([]byte, 4)},
+		{name: "not configured", file: "asn", bufLimit: 4, wantStatus: configFileNotFound, wantBuf: make([]byte, 4)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{PluginConfigFiles: map[string]string{"geoip": path}}
+			plugin := newTestPlugin(t, newGuest(tt.file, tt.bufLimit), cfg, "processTraces")
+
+			stack := &Stack{}
+			res, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack)
+			if err != nil {
+				t.Fatalf("failed to call processTraces: %v", err)
+			}
+			if got := uint32(res[0]); got != tt.wantStatus {
+				t.Errorf("expected getConfigFile to return %d, got %d", tt.wantStatus, got)
+			}
+			if got := []byte(stack.StatusReason); !bytes.Equal(got, tt.wantBuf) {
+				t.Errorf("expected buffer %v, got %v", tt.wantBuf, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package wasmplugin
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// newDeadlineGuest returns a guest whose processTraces echoes the deadline
+// read with getDeadlineUnixNano as the status reason.
+func newDeadlineGuest() *wasmtest.Module {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, getDeadlineUnixNano, nil, []api.ValueType{api.ValueTypeI64}).
+		Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), mod.Call(getDeadlineUnixNano), wasmtest.I64Store(0),
+			wasmtest.I32Const(0), wasmtest.I32Const(8), mod.Call(setResultStatusReason),
+			wasmtest.I32Const(0),
+		),
+	})
+	return mod
+}
+
+func TestGetDeadlineUnixNano(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).Truncate(time.Nanosecond)
+
+	tests := []struct {
+		name             string
+		deadline         time.Time
+		executionTimeout time.Duration
+		// want is the expected deadline, or zero for none.
+		want func() time.Time
+	}{
+		{
+			name: "no deadline",
+			want: func() time.Time { return time.Time{} },
+		},
+		{
+			name:     "context deadline",
+			deadline: deadline,
+			want:     func() time.Time { return deadline },
+		},
+		{
+			name:             "execution timeout before the context deadline",
+			deadline:         deadline,
+			executionTimeout: time.Minute,
+			want:             func() time.Time { return time.Now().Add(time.Minute) },
+		},
+		{
+			name:             "context deadline before the execution timeout",
+			deadline:         deadline,
+			executionTimeout: 2 * time.Hour,
+			want:             func() time.Time { return deadline },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, newDeadlineGuest(), Config{ExecutionTimeout: tt.executionTimeout}, "processTraces")
+
+			ctx := t.Context()
+			if !tt.deadline.IsZero() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, tt.deadline)
+				defer cancel()
+			}
+			stack := &Stack{}
+			if _, err := plugin.ProcessFunctionCall(ctx, "processTraces", stack); err != nil {
+				t.Fatalf("failed to call processTraces: %v", err)
+			}
+			want := tt.want()
+
+			reason := []byte(stack.StatusReason)
+			if len(reason) != 8 {
+				t.Fatalf("expected 8 bytes of deadline, got %d", len(reason))
+			}
+			got := int64(binary.LittleEndian.Uint64(reason))
+			if want.IsZero() {
+				if got != 0 {
+					t.Errorf("expected no deadline, got %v", time.Unix(0, got))
+				}
+				return
+			}
+			// The execution timeout starts when the call does, so it is only
+			// checked to be within a second of the expected one.
+			if diff := want.Sub(time.Unix(0, got)).Abs(); diff > time.Second {
+				t.Errorf("expected deadline %v, got %v", want, time.Unix(0, got))
+			}
+		})
+	}
+}
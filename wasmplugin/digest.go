@@ -0,0 +1,46 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// digestPrefix is the algorithm prefix of the supported digests
+const digestPrefix = "sha256:"
+
+// parseDigest parses a digest of the form "sha256:<hex>".
+func parseDigest(digest string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(digest, digestPrefix)
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest %q: must start with %q", digest, digestPrefix)
+	}
+	sum, err := hex.DecodeString(encoded)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("invalid sha256 digest %q", digest)
+	}
+	return sum, nil
+}
+
+// moduleDigest returns the digest of module, in the form of the expected
+// digests.
+func moduleDigest(module []byte) string {
+	sum := sha256.Sum256(module)
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// verifyDigest returns ErrDigestMismatch unless module matches the expected
+// digest.
+func verifyDigest(module []byte, expected string) error {
+	want, err := parseDigest(expected)
+	if err != nil {
+		return err
+	}
+	got := sha256.Sum256(module)
+	if !bytes.Equal(got[:], want) {
+		return fmt.Errorf("%w: expected %s, got %s%x", ErrDigestMismatch, expected, digestPrefix, got)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package wasmplugin
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+)
+
+func TestExpectedDigest(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces))
+	path := mod.Write(t)
+	module, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read module: %v", err)
+	}
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(module))
+
+	tests := []struct {
+		name     string
+		digest   string
+		mismatch bool
+	}{
+		{name: "matching", digest: digest},
+		{name: "mismatching", digest: fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("other"))), mismatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Path: path, ExpectedDigest: tt.digest}
+			cfg.Default()
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, nil)
+			if tt.mismatch {
+				if !errors.Is(err, ErrDigestMismatch) {
+					t.Fatalf("expected %v, got %v", ErrDigestMismatch, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			if err := plugin.Shutdown(t.Context()); err != nil {
+				t.Errorf("failed to shutdown plugin: %v", err)
+			}
+		})
+	}
+}
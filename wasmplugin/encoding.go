@@ -0,0 +1,65 @@
+package wasmplugin
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// TracesEncoding is the encoding of the serialized traces passed between the
+// host and the guest.
+type TracesEncoding string
+
+const (
+	// TracesEncodingProto passes the traces in OTLP protobuf, which every
+	// guest reads.
+	TracesEncodingProto TracesEncoding = "proto"
+
+	// TracesEncodingJSON passes the traces in OTLP JSON, e.g. for guests
+	// written in languages with better JSON than protobuf support, or to
+	// read the guest memory dumps during development. The guest must
+	// declare CapabilityTracesJSON, as it costs more to marshal.
+	TracesEncodingJSON TracesEncoding = "json"
+)
+
+func (e TracesEncoding) validate() error {
+	switch e {
+	case "", TracesEncodingProto, TracesEncodingJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid traces_encoding: %s", e)
+	}
+}
+
+// tracesJSON returns whether the traces are passed in JSON to a guest
+// declaring capabilities: if the encoding is set to JSON, or left to the
+// guest preferring it.
+func (e TracesEncoding) tracesJSON(capabilities Capabilities) (bool, error) {
+	declared := capabilities&CapabilityTracesJSON != 0
+	switch e {
+	case TracesEncodingJSON:
+		if !declared {
+			return false, fmt.Errorf("wasm: traces_encoding %s: guest doesn't declare reading JSON traces", e)
+		}
+		return true, nil
+	case TracesEncodingProto:
+		return false, nil
+	default:
+		return declared, nil
+	}
+}
+
+// marshalTracesJSON serializes the traces read by guests passed JSON traces.
+var marshalTracesJSON = (&ptrace.JSONMarshaler{}).MarshalTraces
+
+// unmarshalTraces decodes the traces written by the guest, in OTLP JSON if
+// json is set, OTLP protobuf otherwise. The guest writes them in the
+// encoding agreed for the plugin, the bytes can't tell: OTLP protobuf starts
+// with the tag of resource_spans, '\n', followed by a length which may be
+// '{'.
+func unmarshalTraces(b []byte, json bool) (ptrace.Traces, error) {
+	if json {
+		return (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(b)
+	}
+	return (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(b)
+}
@@ -0,0 +1,165 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func testTraces() ptrace.Traces {
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName("GET /cart")
+	span.Attributes().PutInt("http.status_code", 200)
+	return traces
+}
+
+// echoTracesGuest returns a guest declaring caps, whose processTraces sets
+// the current traces as result, as it reads them, and echoes their first
+// byte as the status reason.
+func echoTracesGuest(caps int32) *wasmtest.Module {
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("getCapabilities", caps)).
+		Import(wasmtest.HostModule, currentTraces, []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, setResultTraces, []api.ValueType{i32, i32}, nil).
+		Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{i32, i32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(0), wasmtest.I32Const(1<<16), mod.Call(currentTraces), mod.Call(setResultTraces),
+			wasmtest.I32Const(0), wasmtest.I32Const(1), mod.Call(setResultStatusReason),
+			wasmtest.I32Const(0),
+		),
+	})
+	return mod
+}
+
+func TestTracesEncodingRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  TracesEncoding
+		caps      int32
+		wantJSON  bool
+		wantError bool
+	}{
+		{name: "default", wantJSON: false},
+		{name: "default to a guest preferring JSON", caps: int32(CapabilityTracesJSON), wantJSON: true},
+		{name: "proto", encoding: TracesEncodingProto, caps: int32(CapabilityTracesJSON), wantJSON: false},
+		{name: "json", encoding: TracesEncodingJSON, caps: int32(CapabilityTracesJSON), wantJSON: true},
+		{name: "json to a guest not reading it", encoding: TracesEncodingJSON, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Path: echoTracesGuest(tt.caps).Write(t), TracesEncoding: tt.encoding}
+			cfg.Default()
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"})
+			if tt.wantError {
+				if err == nil {
+					plugin.Shutdown(t.Context())
+					t.Fatal("expected JSON traces to be refused to the guest not declaring them")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			t.Cleanup(func() { plugin.Shutdown(t.Context()) })
+
+			traces := testTraces()
+			stack := &Stack{CurrentTraces: traces}
+			if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+				t.Fatalf("failed to call the guest: %v", err)
+			}
+			if stack.HostError != nil {
+				t.Fatalf("failed to decode the result: %v", stack.HostError)
+			}
+			if gotJSON := stack.StatusReason == "{"; gotJSON != tt.wantJSON {
+				t.Errorf("expected JSON traces %v, got first byte %q", tt.wantJSON, stack.StatusReason)
+			}
+
+			want, _ := (&ptrace.JSONMarshaler{}).MarshalTraces(traces)
+			got, _ := (&ptrace.JSONMarshaler{}).MarshalTraces(stack.ResultTraces)
+			if string(got) != string(want) {
+				t.Errorf("expected the traces to round trip, got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTraces(t *testing.T) {
+	traces := testTraces()
+	proto, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json, err := (&ptrace.JSONMarshaler{}).MarshalTraces(traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tt := range map[string]struct {
+		b    []byte
+		json bool
+	}{
+		"proto":         {b: proto},
+		"json":          {b: json, json: true},
+		"indented json": {b: append([]byte("\n  "), json...), json: true},
+	} {
+		got, err := unmarshalTraces(tt.b, tt.json)
+		if err != nil {
+			t.Errorf("%s: failed to decode: %v", name, err)
+			continue
+		}
+		if got.SpanCount() != 1 || got.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name() != "GET /cart" {
+			t.Errorf("%s: expected the span to be decoded", name)
+		}
+	}
+}
+
+// TestTracesEncodingProtoLookingLikeJSON checks the protobuf traces whose
+// first ResourceSpans is 123 bytes long, so starting with "\n{", are decoded
+// as protobuf.
+func TestTracesEncodingProtoLookingLikeJSON(t *testing.T) {
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName(strings.Repeat("a", 105))
+	proto, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(proto, []byte("\n{")) {
+		t.Fatalf("expected the traces to start with \"\\n{\", got %q", proto[:2])
+	}
+
+	cfg := Config{Path: echoTracesGuest(0).Write(t)}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"})
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	t.Cleanup(func() { plugin.Shutdown(t.Context()) })
+
+	stack := &Stack{CurrentTraces: traces}
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+		t.Fatalf("failed to call the guest: %v", err)
+	}
+	if stack.HostError != nil {
+		t.Fatalf("failed to decode the result: %v", stack.HostError)
+	}
+	if stack.ResultTraces.SpanCount() != 1 {
+		t.Errorf("expected the span to be decoded, got %d spans", stack.ResultTraces.SpanCount())
+	}
+}
+
+func TestTracesEncodingValidate(t *testing.T) {
+	cfg := Config{Path: "module.wasm", TracesEncoding: "xml"}
+	cfg.Default()
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an unknown traces encoding to be refused")
+	}
+}
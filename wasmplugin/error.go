@@ -1,5 +1,165 @@
 package wasmplugin
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var ErrRequiredFunctionNotExported = errors.New("required function not exported")
+
+// errOutOfMemory is recorded when the guest passes a host function a buffer
+// outside its memory.
+var errOutOfMemory = errors.New("buffer out of guest memory")
+
+// errEmitUnsupported is recorded when the guest emits data to a component
+// which doesn't consume emitted data, e.g. a processor.
+var errEmitUnsupported = errors.New("emitting data isn't supported by the component")
+
+// ErrInvalidModule is returned when the guest module fails to compile, e.g.
+// because it isn't WebAssembly, or doesn't export its memory.
+var ErrInvalidModule = errors.New("invalid guest module")
+
+// ErrABIVersionUnsupported is returned when the guest was built against a
+// version of the host ABI the host doesn't support.
+var ErrABIVersionUnsupported = errors.New("guest ABI version unsupported")
+
+// ErrQuotaExceeded is returned when calling a guest that exceeded its quota
+// in the current window.
+var ErrQuotaExceeded = errors.New("guest quota exceeded")
+
+// ErrGuestDisabled is returned when calling a guest disabled for exceeding
+// its quota.
+var ErrGuestDisabled = errors.New("guest disabled")
+
+// ErrExecutionTimeout is returned when a guest call exceeds the execution
+// timeout.
+var ErrExecutionTimeout = errors.New("guest exceeded execution timeout")
+
+// ErrDigestMismatch is returned when loading a module not matching the
+// configured digest.
+var ErrDigestMismatch = errors.New("module digest mismatch")
+
+// ErrGlobalMemoryLimitExceeded is returned when a guest can't be
+// instantiated, or fails as its memory can't grow, because the memory of all
+// the guests would exceed the global memory limit.
+var ErrGlobalMemoryLimitExceeded = errors.New("global guest memory limit exceeded")
+
+// ErrMemoryLimitExceeded is returned when a guest can't be instantiated, or
+// fails as its memory can't grow, because its memory would exceed the limit
+// of Config.MaxMemoryPages.
+var ErrMemoryLimitExceeded = errors.New("guest memory limit exceeded")
+
+// ErrGuestNotReady is reported by the components whose guest can't serve
+// calls, e.g. because it exited.
+var ErrGuestNotReady = errors.New("guest not ready")
+
+// ErrInjectedFault is returned by the guest calls failed by the fault
+// injection.
+var ErrInjectedFault = errors.New("injected fault")
+
+// ErrorReason is the category of a guest failure.
+type ErrorReason string
+
+const (
+	// ErrorReasonStatus means the guest returned an error status code.
+	ErrorReasonStatus ErrorReason = "status"
+
+	// ErrorReasonInvalidArgument means the guest rejected its input with the
+	// INVALID_ARGUMENT status code.
+	ErrorReasonInvalidArgument ErrorReason = "invalid_argument"
+
+	// ErrorReasonTrap means the guest call aborted, e.g. because the guest
+	// panicked or exited.
+	ErrorReasonTrap ErrorReason = "trap"
+
+	// ErrorReasonQuota means the guest wasn't called because it exceeded its
+	// quota.
+	ErrorReasonQuota ErrorReason = "quota"
+
+	// ErrorReasonHostCall means the guest called a host function with
+	// invalid arguments, see Stack.HostError.
+	ErrorReasonHostCall ErrorReason = "host_call"
+
+	// ErrorReasonExit means the guest exited, e.g. by calling os.Exit, with
+	// GuestError.ExitCode. The guest is closed, failing the subsequent calls.
+	ErrorReasonExit ErrorReason = "exit"
+
+	// ErrorReasonTimeout means the guest call exceeded the execution timeout.
+	// The guest is closed, failing the subsequent calls.
+	ErrorReasonTimeout ErrorReason = "timeout"
+
+	// ErrorReasonInjected means the guest wasn't called because the fault
+	// injection failed the call, see FaultInjectionConfig.
+	ErrorReasonInjected ErrorReason = "injected"
+)
+
+// GuestError is the error of a failed guest function call.
+type GuestError struct {
+	// Function is the name of the called guest function.
+	Function string
+
+	// Reason is the category of the failure.
+	Reason ErrorReason
+
+	// Status is the status code returned by the guest. Only set if the guest
+	// returned.
+	Status StatusCode
+
+	// StatusReason is the reason reported by the guest along with Status.
+	StatusReason string
+
+	// ExitCode is the WASI exit code of the guest. Only set if Reason is
+	// ErrorReasonExit.
+	ExitCode uint32
+
+	// Panic is the message of the panic the guest wrote to its standard
+	// output or error before it failed, e.g. "runtime error: index out of
+	// range [3] with length 3". Only set if the output is logged, see
+	// Config.StdioPassthrough.
+	Panic string
+
+	// StackTrace is the wasm stack trace of the trap, innermost frame first,
+	// e.g. "main.processTraces(i32) i32". Only set if Reason is
+	// ErrorReasonTrap.
+	StackTrace []string
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *GuestError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %s", e.Status, e.StatusReason)
+	}
+	if e.Reason == ErrorReasonExit {
+		if e.Panic != "" {
+			return fmt.Sprintf("wasm: %s: guest exited with code %d: panic: %s", e.Function, e.ExitCode, e.Panic)
+		}
+		return fmt.Sprintf("wasm: %s: guest exited with code %d", e.Function, e.ExitCode)
+	}
+	if e.Panic != "" {
+		return fmt.Sprintf("wasm: %s: panic: %s: %v", e.Function, e.Panic, e.Err)
+	}
+	return fmt.Sprintf("wasm: %s: %v", e.Function, e.Err)
+}
+
+func (e *GuestError) Unwrap() error {
+	return e.Err
+}
+
+// wasmStackTraceHeader separates the message of a trap returned by the
+// runtime from its wasm stack trace.
+const wasmStackTraceHeader = "\nwasm stack trace:\n\t"
+
+// wasmStackTrace returns the lines of the wasm stack trace of err, or nil if
+// err has none.
+func wasmStackTrace(err error) []string {
+	_, trace, ok := strings.Cut(err.Error(), wasmStackTraceHeader)
+	if !ok {
+		return nil
+	}
+	// The Go stack trace of a host function panic follows a blank line.
+	trace, _, _ = strings.Cut(trace, "\n\n")
+	return strings.Split(trace, "\n\t")
+}
@@ -0,0 +1,133 @@
+package wasmplugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrorLogConfig is the configuration of the logging of the guest failures.
+// The first occurrence of an error is logged, then identical errors are
+// collapsed into summaries counting them, so a guest failing on every batch
+// doesn't flood the logs.
+type ErrorLogConfig struct {
+	// Every logs a summary every given number of occurrences of an error.
+	// Zero disables the count based summaries.
+	Every int64 `mapstructure:"every,omitempty"`
+
+	// Interval is the minimum duration between the summaries of an error.
+	// Summaries are logged by the next occurrence of the error past the
+	// interval, so an error no longer occurring isn't summarized. Zero
+	// disables the time based summaries.
+	// The default is one minute, unless Every is set.
+	Interval time.Duration `mapstructure:"interval,omitempty"`
+}
+
+func (cfg *ErrorLogConfig) Validate() error {
+	if cfg.Every < 0 {
+		return fmt.Errorf("error_log.every must not be negative")
+	}
+	if cfg.Interval < 0 {
+		return fmt.Errorf("error_log.interval must not be negative")
+	}
+	return nil
+}
+
+// Default sets the default values for the error log configuration
+// if they are not set.
+func (cfg *ErrorLogConfig) Default() {
+	if cfg.Every == 0 && cfg.Interval == 0 {
+		cfg.Interval = DefaultErrorLogConfig.Interval
+	}
+}
+
+// DefaultErrorLogConfig is the default configuration for the logging of the
+// guest failures.
+var DefaultErrorLogConfig = ErrorLogConfig{
+	Interval: time.Minute,
+}
+
+// maxErrorLogEntries bounds the number of distinct errors tracked, in case
+// the guest fails with unique messages. The tracked errors are forgotten
+// once reached.
+const maxErrorLogEntries = 256
+
+// errorLogEntry tracks the occurrences of an error.
+type errorLogEntry struct {
+	// suppressed is the number of occurrences since the last log.
+	suppressed int64
+	total      int64
+	lastLogged time.Time
+}
+
+// errorLogger logs guest failures, collapsing identical errors.
+type errorLogger struct {
+	cfg    ErrorLogConfig
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*errorLogEntry
+}
+
+// newErrorLogger returns the error logger of cfg, or nil if logger is nil.
+func newErrorLogger(cfg ErrorLogConfig, logger *zap.Logger) *errorLogger {
+	if logger == nil {
+		return nil
+	}
+	cfg.Default()
+	return &errorLogger{cfg: cfg, logger: logger, now: time.Now, entries: make(map[string]*errorLogEntry)}
+}
+
+// log logs err unless an identical error was logged recently.
+func (l *errorLogger) log(err *GuestError) {
+	if l == nil {
+		return
+	}
+	key := err.Function + "\x00" + err.Error()
+	now := l.now()
+
+	l.mu.Lock()
+	entry, ok := l.entries[key]
+	if !ok {
+		if len(l.entries) >= maxErrorLogEntries {
+			clear(l.entries)
+		}
+		entry = &errorLogEntry{lastLogged: now}
+		l.entries[key] = entry
+	}
+	entry.total++
+	if ok {
+		entry.suppressed++
+		due := (l.cfg.Every > 0 && entry.suppressed >= l.cfg.Every) ||
+			(l.cfg.Interval > 0 && now.Sub(entry.lastLogged) >= l.cfg.Interval)
+		if !due {
+			l.mu.Unlock()
+			return
+		}
+	}
+	suppressed, total := entry.suppressed, entry.total
+	entry.suppressed = 0
+	entry.lastLogged = now
+	l.mu.Unlock()
+
+	fields := []zap.Field{
+		zap.String("function", err.Function),
+		zap.String("reason", string(err.Reason)),
+		zap.Error(err),
+	}
+	if err.Panic != "" {
+		fields = append(fields, zap.String("panic", err.Panic))
+	}
+	if len(err.StackTrace) > 0 {
+		fields = append(fields, zap.Strings("wasm_stack_trace", err.StackTrace))
+	}
+	if !ok {
+		l.logger.Error("Guest call failed", fields...)
+		return
+	}
+	fields = append(fields, zap.Int64("occurrences", suppressed), zap.Int64("total", total))
+	l.logger.Error("Guest call failed repeatedly", fields...)
+}
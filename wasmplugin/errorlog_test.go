@@ -0,0 +1,101 @@
+package wasmplugin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestErrorLoggerCollapsesRepeatedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ErrorLogConfig
+		// step is the time between two occurrences.
+		step time.Duration
+		// wantOccurrences are the occurrences counted by the summaries.
+		wantOccurrences []int64
+	}{
+		{name: "every", cfg: ErrorLogConfig{Every: 4}, step: time.Second, wantOccurrences: []int64{4, 4}},
+		{name: "interval", cfg: ErrorLogConfig{Interval: 10 * time.Second}, step: 3 * time.Second, wantOccurrences: []int64{4, 4}},
+		{name: "default", step: 20 * time.Second, wantOccurrences: []int64{3, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.ErrorLevel)
+			l := newErrorLogger(tt.cfg, zap.New(core))
+			now := time.Unix(0, 0)
+			l.now = func() time.Time { return now }
+
+			err := &GuestError{Function: "processTraces", Reason: ErrorReasonTrap, Err: errors.New("unreachable")}
+			for range 9 {
+				l.log(err)
+				now = now.Add(tt.step)
+			}
+
+			entries := logs.All()
+			if len(entries) != 1+len(tt.wantOccurrences) {
+				t.Fatalf("expected the first occurrence and %d summaries, got %d logs", len(tt.wantOccurrences), len(entries))
+			}
+			if entries[0].Message != "Guest call failed" {
+				t.Errorf("expected the first occurrence to be logged, got %q", entries[0].Message)
+			}
+			total := int64(1)
+			for i, want := range tt.wantOccurrences {
+				fields := entries[i+1].ContextMap()
+				total += want
+				if fields["occurrences"] != want || fields["total"] != total {
+					t.Errorf("summary %d: expected %d occurrences and %d in total, got %v", i, want, total, fields)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorLoggerDistinctErrors(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	l := newErrorLogger(ErrorLogConfig{}, zap.New(core))
+
+	l.log(&GuestError{Function: "processTraces", Reason: ErrorReasonTrap, Err: errors.New("unreachable")})
+	l.log(&GuestError{Function: "processTraces", Reason: ErrorReasonTrap, Err: errors.New("out of bounds")})
+	l.log(&GuestError{Function: "processLogs", Reason: ErrorReasonTrap, Err: errors.New("unreachable")})
+	l.log(&GuestError{Function: "processTraces", Reason: ErrorReasonTrap, Err: errors.New("unreachable")})
+
+	if got := logs.FilterMessage("Guest call failed").Len(); got != 3 {
+		t.Errorf("expected the first occurrence of each error to be logged, got %d logs", got)
+	}
+	if got := logs.Len(); got != 3 {
+		t.Errorf("expected the repeated error to be suppressed, got %d logs", got)
+	}
+}
+
+func TestGuestErrorsLogged(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body:    wasmtest.Unreachable,
+	})
+	core, logs := observer.New(zapcore.ErrorLevel)
+	cfg := Config{Path: mod.Write(t), ErrorLog: ErrorLogConfig{Every: 5}}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"}, WithLogger(zap.New(core)))
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	t.Cleanup(func() { plugin.Shutdown(t.Context()) })
+
+	for range 10 {
+		if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{}); err == nil {
+			t.Fatal("expected the guest to trap")
+		}
+	}
+	if got := logs.Len(); got != 2 {
+		t.Errorf("expected the first failure and a summary to be logged, got %d logs", got)
+	}
+}
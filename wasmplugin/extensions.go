@@ -0,0 +1,115 @@
+package wasmplugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Extensions gives guests access to the collector extensions, e.g. so guest
+// receivers can authenticate their requests with the configured auth
+// extension. Components implement it on top of their component.Host.
+type Extensions interface {
+	// IDs returns the IDs of the available extensions.
+	IDs() []string
+
+	// Authenticate authenticates the request headers with the server
+	// authenticator extension of the given ID, and returns the information
+	// of the authenticated client.
+	Authenticate(ctx context.Context, id string, headers map[string][]string) (ClientInfo, error)
+}
+
+// ClientInfo is the part of the collector client.Info passed from the host to
+// the guest after an authentication. Auth holds the attributes of the
+// client.AuthData, so only attributes representable in JSON cross the
+// boundary. The address of the client isn't passed as the guest knows it
+// already.
+type ClientInfo struct {
+	// Auth maps the names of the auth data attributes to their value.
+	Auth map[string]any `json:"auth,omitempty"`
+
+	// Metadata is the client metadata set by the authenticator.
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+// authResult is the result of authenticate, written in JSON to the guest
+// memory. Error is set if the authentication failed.
+type authResult struct {
+	ClientInfo
+	Error string `json:"error,omitempty"`
+}
+
+// errNoExtensions is the authentication error of guests called without
+// Extensions.
+var errNoExtensions = errors.New("no extension available")
+
+// getExtensionsFn writes the IDs of the available extensions to the guest
+// memory as a JSON array.
+func getExtensionsFn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf := uint32(stack[0])
+	bufLimit := uint32(stack[1])
+
+	ids := []string{}
+	if extensions := paramsFromContext(ctx).Extensions; extensions != nil {
+		ids = extensions.IDs()
+	}
+	b, _ := json.Marshal(ids)
+	stack[0] = uint64(writeResult(mod, b, buf, bufLimit))
+}
+
+// authenticateFn authenticates the headers passed by the guest in JSON with
+// the extension of the given ID, and writes the authResult to the guest
+// memory.
+func authenticateFn(ctx context.Context, mod api.Module, stack []uint64) {
+	id := uint32(stack[0])
+	idLen := uint32(stack[1])
+	headers := uint32(stack[2])
+	headersLen := uint32(stack[3])
+	buf := uint32(stack[4])
+	bufLimit := uint32(stack[5])
+
+	params := paramsFromContext(ctx)
+	idBytes, ok := mod.Memory().Read(id, idLen)
+	if !ok {
+		params.recordHostError(authenticate, errOutOfMemory)
+		stack[0] = 0
+		return
+	}
+	headersBytes, ok := mod.Memory().Read(headers, headersLen)
+	if !ok {
+		params.recordHostError(authenticate, errOutOfMemory)
+		stack[0] = 0
+		return
+	}
+
+	var result authResult
+	var sources map[string][]string
+	if err := json.Unmarshal(headersBytes, &sources); err != nil {
+		result.Error = fmt.Sprintf("invalid headers: %v", err)
+	} else if params.Extensions == nil {
+		result.Error = errNoExtensions.Error()
+	} else if info, err := params.Extensions.Authenticate(ctx, string(idBytes), sources); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.ClientInfo = info
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		b, _ = json.Marshal(authResult{Error: fmt.Sprintf("invalid client info: %v", err)})
+	}
+	stack[0] = uint64(writeResult(mod, b, buf, bufLimit))
+}
+
+// writeResult writes b to the guest memory if it fits in the buffer. The
+// length is returned even if it doesn't fit, so the guest can retry with a
+// large enough buffer.
+func writeResult(mod api.Module, b []byte, buf, bufLimit uint32) uint32 {
+	if uint32(len(b)) <= bufLimit {
+		mod.Memory().Write(buf, b)
+	}
+	return uint32(len(b))
+}
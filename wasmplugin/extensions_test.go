@@ -0,0 +1,146 @@
+package wasmplugin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// fakeExtensions authenticates requests whose authorization header is
+// "Bearer secret" with the basicauth/server extension.
+type fakeExtensions struct{}
+
+func (fakeExtensions) IDs() []string {
+	return []string{"basicauth/server", "file_storage"}
+}
+
+func (fakeExtensions) Authenticate(_ context.Context, id string, headers map[string][]string) (ClientInfo, error) {
+	if id != "basicauth/server" {
+		return ClientInfo{}, errors.New("not a server authenticator")
+	}
+	if v := headers["authorization"]; len(v) != 1 || v[0] != "Bearer secret" {
+		return ClientInfo{}, errors.New("unauthenticated")
+	}
+	return ClientInfo{Auth: map[string]any{"subject": "alice"}}, nil
+}
+
+// extensionsGuest returns a guest whose processTraces calls the given host
+// function with the data segments as arguments, echoing its result as the
+// status reason.
+func extensionsGuest(function string, args ...string) *wasmtest.Module {
+	const bufOffset = 1024
+	params := make([]api.ValueType, 2*len(args)+2)
+	for i := range params {
+		params[i] = api.ValueTypeI32
+	}
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, function, params, []api.ValueType{api.ValueTypeI32}).
+		Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+
+	var body [][]byte
+	offset := 0
+	for _, arg := range args {
+		mod.Data = append(mod.Data, wasmtest.Data{Offset: uint32(offset), Bytes: []byte(arg)})
+		body = append(body, wasmtest.I32Const(int32(offset)), wasmtest.I32Const(int32(len(arg))))
+		offset += len(arg)
+	}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Locals:  []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(append(body,
+			wasmtest.I32Const(bufOffset), wasmtest.I32Const(1024), mod.Call(function),
+			wasmtest.LocalSet(0),
+			wasmtest.I32Const(bufOffset), wasmtest.LocalGet(0), mod.Call(setResultStatusReason),
+			wasmtest.I32Const(0),
+		)...),
+	})
+	return mod
+}
+
+func TestGetExtensions(t *testing.T) {
+	plugin := newTestPlugin(t, extensionsGuest(getExtensions), Config{}, "processTraces")
+
+	for _, tt := range []struct {
+		name       string
+		extensions Extensions
+		want       string
+	}{
+		{name: "none", want: `[]`},
+		{name: "available", extensions: fakeExtensions{}, want: `["basicauth/server","file_storage"]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stack := &Stack{Extensions: tt.extensions}
+			if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+				t.Fatalf("failed to call processTraces: %v", err)
+			}
+			if stack.StatusReason != tt.want {
+				t.Errorf("expected extensions %s, got %s", tt.want, stack.StatusReason)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		headers    string
+		extensions Extensions
+		// want is a prefix of the expected result.
+		want string
+	}{
+		{
+			name:       "authenticated",
+			id:         "basicauth/server",
+			headers:    `{"authorization":["Bearer secret"]}`,
+			extensions: fakeExtensions{},
+			want:       `{"auth":{"subject":"alice"}}`,
+		},
+		{
+			name:       "unauthenticated",
+			id:         "basicauth/server",
+			headers:    `{"authorization":["Bearer guess"]}`,
+			extensions: fakeExtensions{},
+			want:       `{"error":"unauthenticated"}`,
+		},
+		{
+			name:       "not an authenticator",
+			id:         "file_storage",
+			headers:    `{}`,
+			extensions: fakeExtensions{},
+			want:       `{"error":"not a server authenticator"}`,
+		},
+		{
+			name:    "no extensions",
+			id:      "basicauth/server",
+			headers: `{}`,
+			want:    `{"error":"no extension available"}`,
+		},
+		{
+			name:       "invalid headers",
+			id:         "basicauth/server",
+			headers:    `{"authorization":"Bearer secret"}`,
+			extensions: fakeExtensions{},
+			want:       `{"error":"invalid headers: `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, extensionsGuest(authenticate, tt.id, tt.headers), Config{}, "processTraces")
+
+			stack := &Stack{Extensions: tt.extensions}
+			if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+				t.Fatalf("failed to call processTraces: %v", err)
+			}
+			if !strings.HasPrefix(stack.StatusReason, tt.want) {
+				t.Errorf("expected result %s, got %s", tt.want, stack.StatusReason)
+			}
+		})
+	}
+}
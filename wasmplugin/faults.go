@@ -0,0 +1,68 @@
+package wasmplugin
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// FaultInjectionConfig is the configuration of the failures injected in the
+// guest calls, to test how the collector copes with failing guests without
+// building a guest that fails.
+//
+// FOR TESTING ONLY: injected failures drop data like real guest failures.
+// Faults are disabled unless Rate or Calls is set.
+type FaultInjectionConfig struct {
+	// Rate is the probability of a guest call failing, from 0 to 1.
+	Rate float64 `mapstructure:"rate,omitempty"`
+
+	// Calls are the numbers of the guest calls failing, starting at 1, e.g.
+	// [3] fails the third call.
+	Calls []int64 `mapstructure:"calls,omitempty"`
+}
+
+func (cfg *FaultInjectionConfig) Validate() error {
+	if cfg.Rate < 0 || cfg.Rate > 1 {
+		return fmt.Errorf("invalid fault injection rate: %v", cfg.Rate)
+	}
+	for _, call := range cfg.Calls {
+		if call < 1 {
+			return fmt.Errorf("invalid fault injection call number: %d", call)
+		}
+	}
+	return nil
+}
+
+// faultInjector fails the guest calls selected by its configuration.
+type faultInjector struct {
+	cfg    FaultInjectionConfig
+	random func() float64
+
+	mu    sync.Mutex
+	calls int64
+}
+
+// newFaultInjector returns the fault injector of the given configuration, or
+// nil if it doesn't inject any fault.
+func newFaultInjector(cfg FaultInjectionConfig) *faultInjector {
+	if cfg.Rate == 0 && len(cfg.Calls) == 0 {
+		return nil
+	}
+	return &faultInjector{cfg: cfg, random: rand.Float64}
+}
+
+// inject returns ErrInjectedFault if the current call must fail.
+func (f *faultInjector) inject() error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if slices.Contains(f.cfg.Calls, f.calls) || f.random() < f.cfg.Rate {
+		return ErrInjectedFault
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package wasmplugin
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestFaultInjectionRate(t *testing.T) {
+	const calls = 10000
+	for _, rate := range []float64{0, 0.1, 0.5, 1} {
+		f := &faultInjector{cfg: FaultInjectionConfig{Rate: rate}, random: rand.New(rand.NewPCG(1, 2)).Float64}
+		failed := 0
+		for range calls {
+			if f.inject() != nil {
+				failed++
+			}
+		}
+		// The seeded generator is deterministic, the tolerance only allows
+		// changing the seed.
+		if got := float64(failed) / calls; got < rate-0.02 || got > rate+0.02 {
+			t.Errorf("rate %v: expected %v of the calls to fail, got %v", rate, rate, got)
+		}
+	}
+}
+
+func TestFaultInjectionCalls(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body:    wasmtest.I32Const(0),
+	})
+	plugin := newTestPlugin(t, mod, Config{
+		TestingFaultInjection: FaultInjectionConfig{Calls: []int64{2, 4}},
+	}, "processTraces")
+
+	var failed []int
+	for call := 1; call <= 5; call++ {
+		_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+		if err == nil {
+			continue
+		}
+		var guestErr *GuestError
+		if !errors.As(err, &guestErr) || guestErr.Reason != ErrorReasonInjected || !errors.Is(err, ErrInjectedFault) {
+			t.Fatalf("call %d: expected an injected fault, got %v", call, err)
+		}
+		failed = append(failed, call)
+	}
+	if len(failed) != 2 || failed[0] != 2 || failed[1] != 4 {
+		t.Errorf("expected calls [2 4] to fail, got %v", failed)
+	}
+}
@@ -0,0 +1,171 @@
+package wasmplugin
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// FilesConfig is the configuration of the host files the guest reads
+// through the hostReadFile and hostStatFile host functions, e.g. with the
+// guest/hostfile package, as the guests have no WASI access to the host
+// filesystem.
+type FilesConfig struct {
+	// AllowedPaths are the absolute paths of the files the guest may read,
+	// either files or directories, allowing the files beneath them. The
+	// files are opened within the directories, so symbolic links and ".."
+	// don't escape them. No file is allowed if empty.
+	AllowedPaths []string `mapstructure:"allowed_paths,omitempty"`
+}
+
+func (cfg *FilesConfig) Validate() error {
+	for _, path := range cfg.AllowedPaths {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("files.allowed_paths: %q must be absolute", path)
+		}
+	}
+	return nil
+}
+
+// errFileNotAllowed is the error of the files missing from
+// FilesConfig.AllowedPaths.
+var errFileNotAllowed = errors.New("path not in files.allowed_paths")
+
+// fileReader opens the files a guest reads.
+type fileReader struct {
+	allowed []string
+}
+
+// newFileReader returns the reader of the files of a guest configured with
+// cfg.
+func newFileReader(cfg FilesConfig) *fileReader {
+	r := &fileReader{}
+	for _, path := range cfg.AllowedPaths {
+		r.allowed = append(r.allowed, filepath.Clean(path))
+	}
+	return r
+}
+
+// open opens path, or returns errFileNotAllowed unless it is allowed.
+func (r *fileReader) open(path string) (*os.File, error) {
+	if !filepath.IsAbs(path) {
+		return nil, errFileNotAllowed
+	}
+	path = filepath.Clean(path)
+	for _, allowed := range r.allowed {
+		if path == allowed {
+			return os.Open(path)
+		}
+		rel, err := filepath.Rel(allowed, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		// The file is opened within the allowed directory, so a symbolic
+		// link beneath it doesn't lead the guest out of it.
+		return os.OpenInRoot(allowed, rel)
+	}
+	return nil, errFileNotAllowed
+}
+
+// fileStatus returns the hostStatus of err, the error of a file operation.
+func fileStatus(err error) hostStatus {
+	switch {
+	case err == nil:
+		return hostStatusOK
+	case errors.Is(err, errFileNotAllowed):
+		return hostStatusNotAllowed
+	case errors.Is(err, fs.ErrNotExist):
+		return hostStatusNotFound
+	default:
+		return hostStatusIOError
+	}
+}
+
+// newHostReadFileFn returns the hostReadFile host function, reading up to
+// buf_limit bytes of the file at path from offset, and returning the number
+// of bytes read. Fewer bytes are read only at the end of the file.
+func newHostReadFileFn(files *fileReader) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		path := uint32(stack[0])
+		pathLen := uint32(stack[1])
+		offset := int64(stack[2])
+		buf := uint32(stack[3])
+		bufLimit := uint32(stack[4])
+		statusPtr := uint32(stack[5])
+
+		n, status := readFile(mod, files, path, pathLen, offset, buf, bufLimit)
+		writeHostStatus(ctx, mod, hostReadFile, statusPtr, status)
+		stack[0] = uint64(n)
+	}
+}
+
+func readFile(mod api.Module, files *fileReader, path, pathLen uint32, offset int64, buf, bufLimit uint32) (uint32, hostStatus) {
+	pathBytes, ok := mod.Memory().Read(path, pathLen)
+	if !ok {
+		return 0, hostStatusOutOfMemory
+	}
+	if offset < 0 {
+		return 0, hostStatusIOError
+	}
+	// The file is read straight into the guest memory.
+	dst, ok := mod.Memory().Read(buf, bufLimit)
+	if !ok {
+		return 0, hostStatusOutOfMemory
+	}
+
+	f, err := files.open(string(pathBytes))
+	if err != nil {
+		return 0, fileStatus(err)
+	}
+	defer f.Close()
+	n, err := f.ReadAt(dst, offset)
+	if err == io.EOF {
+		err = nil
+	}
+	return uint32(n), fileStatus(err)
+}
+
+// newHostStatFileFn returns the hostStatFile host function, writing the size
+// of the file at path, then its modification time in nanoseconds since the
+// epoch, as little-endian 64-bit integers at stat_ptr.
+func newHostStatFileFn(files *fileReader) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		path := uint32(stack[0])
+		pathLen := uint32(stack[1])
+		statPtr := uint32(stack[2])
+		statusPtr := uint32(stack[3])
+
+		writeHostStatus(ctx, mod, hostStatFile, statusPtr, statFile(mod, files, path, pathLen, statPtr))
+	}
+}
+
+func statFile(mod api.Module, files *fileReader, path, pathLen, statPtr uint32) hostStatus {
+	pathBytes, ok := mod.Memory().Read(path, pathLen)
+	if !ok {
+		return hostStatusOutOfMemory
+	}
+	f, err := files.open(string(pathBytes))
+	if err != nil {
+		return fileStatus(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fileStatus(err)
+	}
+
+	var stat [16]byte
+	binary.LittleEndian.PutUint64(stat[:8], uint64(info.Size()))
+	binary.LittleEndian.PutUint64(stat[8:], uint64(info.ModTime().UnixNano()))
+	if !mod.Memory().Write(statPtr, stat[:]) {
+		return hostStatusOutOfMemory
+	}
+	return hostStatusOK
+}
@@ -0,0 +1,151 @@
+package wasmplugin
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestFileReaderOpen(t *testing.T) {
+	dir := t.TempDir()
+	allowedDir := filepath.Join(dir, "allowed")
+	if err := os.Mkdir(allowedDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(allowedDir, "app.log"), filepath.Join(dir, "secret"), filepath.Join(dir, "file.log")} {
+		if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret"), filepath.Join(allowedDir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	files := newFileReader(FilesConfig{AllowedPaths: []string{allowedDir, filepath.Join(dir, "file.log")}})
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+		// escape is set for the paths os.Root refuses, with an unexported
+		// error.
+		escape bool
+	}{
+		{name: "beneath directory", path: filepath.Join(allowedDir, "app.log")},
+		{name: "allowed file", path: filepath.Join(dir, "file.log")},
+		{name: "missing", path: filepath.Join(allowedDir, "missing.log"), wantErr: os.ErrNotExist},
+		{name: "outside", path: filepath.Join(dir, "secret"), wantErr: errFileNotAllowed},
+		{name: "dot dot", path: allowedDir + "/../secret", wantErr: errFileNotAllowed},
+		{name: "relative", path: "allowed/app.log", wantErr: errFileNotAllowed},
+		{name: "symbolic link out of directory", path: filepath.Join(allowedDir, "escape"), escape: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := files.open(tt.path)
+			if err == nil {
+				f.Close()
+			}
+			if tt.escape {
+				if err == nil {
+					t.Errorf("expected %s not to be opened", tt.path)
+				}
+				return
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("expected %s to be opened, got %v", tt.path, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHostReadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("first line\nsecond line\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	i32, i64 := api.ValueTypeI32, api.ValueTypeI64
+
+	// The guest stats the file at path, and reads it from offset into 256,
+	// with the status at 0, the stat at 8 and the path at 64.
+	guest := func(path string, offset int64) *wasmtest.Module {
+		mod := wasmtest.NewGuest(int32(telemetryTypeLogs)).
+			Import(wasmtest.HostModule, hostReadFile, []api.ValueType{i32, i32, i64, i32, i32, i32}, []api.ValueType{i32}).
+			Import(wasmtest.HostModule, hostStatFile, []api.ValueType{i32, i32, i32, i32}, nil)
+		mod.Data = []wasmtest.Data{{Offset: 64, Bytes: []byte(path)}}
+		mod.Functions = append(mod.Functions,
+			wasmtest.Function{
+				Export:  "read",
+				Results: []api.ValueType{i32, i32},
+				Body: wasmtest.Instructions(
+					wasmtest.I32Const(64), wasmtest.I32Const(int32(len(path))), wasmtest.I64Const(offset),
+					wasmtest.I32Const(256), wasmtest.I32Const(256), wasmtest.I32Const(0), mod.Call(hostReadFile),
+					wasmtest.I32Const(0), wasmtest.I32Load(0),
+				),
+			},
+			wasmtest.Function{
+				Export:  "stat",
+				Results: []api.ValueType{i32},
+				Body: wasmtest.Instructions(
+					wasmtest.I32Const(64), wasmtest.I32Const(int32(len(path))), wasmtest.I32Const(8), wasmtest.I32Const(0), mod.Call(hostStatFile),
+					wasmtest.I32Const(0), wasmtest.I32Load(0),
+				),
+			},
+		)
+		return mod
+	}
+	cfg := Config{Files: FilesConfig{AllowedPaths: []string{dir}}}
+
+	t.Run("read", func(t *testing.T) {
+		plugin := newTestPlugin(t, guest(path, 11), cfg, "read", "stat")
+		res, err := plugin.ProcessFunctionCall(t.Context(), "read", &Stack{})
+		if err != nil {
+			t.Fatalf("failed to call the guest: %v", err)
+		}
+		if hostStatus(res[1]) != hostStatusOK {
+			t.Fatalf("expected the file to be read, got status %d", res[1])
+		}
+		got, _ := plugin.Module.Memory().Read(256, uint32(res[0]))
+		if string(got) != "second line\n" {
+			t.Errorf("expected the file to be read from the offset, got %q", got)
+		}
+
+		res, err = plugin.ProcessFunctionCall(t.Context(), "stat", &Stack{})
+		if err != nil {
+			t.Fatalf("failed to call the guest: %v", err)
+		}
+		stat, _ := plugin.Module.Memory().Read(8, 16)
+		if hostStatus(res[0]) != hostStatusOK || binary.LittleEndian.Uint64(stat) != 23 {
+			t.Errorf("expected the size of the file, got status %d and size %d", res[0], binary.LittleEndian.Uint64(stat))
+		}
+	})
+
+	for _, tt := range []struct {
+		name string
+		path string
+		want hostStatus
+	}{
+		{name: "not allowed", path: "/etc/passwd", want: hostStatusNotAllowed},
+		{name: "not found", path: filepath.Join(dir, "missing.log"), want: hostStatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, guest(tt.path, 0), cfg, "read", "stat")
+			for _, fn := range []string{"read", "stat"} {
+				res, err := plugin.ProcessFunctionCall(t.Context(), fn, &Stack{})
+				if err != nil {
+					t.Fatalf("expected the status to be reported to the guest, got %v", err)
+				}
+				if got := hostStatus(res[len(res)-1]); got != tt.want {
+					t.Errorf("expected %s to report status %d, got %d", fn, tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+// guestScopeName is the instrumentation scope of the metrics recorded by the
+// guest, so they aren't mistaken for the plugin metrics.
+const guestScopeName = scopeName + "/guest"
+
+// metricKind is the kind of a metric recorded by the guest.
+type metricKind uint32
+
+const (
+	// metricKindCounter adds the values to a monotonic sum.
+	metricKindCounter metricKind = iota
+	// metricKindGauge records the last value.
+	metricKindGauge
+)
+
+// String returns the name of the kind.
+func (k metricKind) String() string {
+	switch k {
+	case metricKindCounter:
+		return "counter"
+	case metricKindGauge:
+		return "gauge"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", uint32(k))
+	}
+}
+
+var (
+	// errNegativeIncrement is recorded when the guest decrements a counter.
+	errNegativeIncrement = errors.New("counter increment must not be negative")
+	// errUnknownMetricKind is recorded when the guest passes an invalid
+	// metricKind.
+	errUnknownMetricKind = errors.New("unknown metric kind")
+)
+
+// guestMetrics records the metrics of the guest with the meter of the
+// component. Instruments are created the first time the guest records them.
+type guestMetrics struct {
+	meter metric.Meter
+
+	mu       sync.Mutex
+	counters map[string]metric.Int64Counter
+	gauges   map[string]metric.Int64Gauge
+}
+
+func newGuestMetrics(mp metric.MeterProvider) *guestMetrics {
+	if mp == nil {
+		mp = noop.NewMeterProvider()
+	}
+	return &guestMetrics{
+		meter:    mp.Meter(guestScopeName),
+		counters: make(map[string]metric.Int64Counter),
+		gauges:   make(map[string]metric.Int64Gauge),
+	}
+}
+
+// record records value in the metric name of the given kind. A name is bound
+// to the kind it was first recorded with.
+func (g *guestMetrics) record(ctx context.Context, name string, kind metricKind, value int64, attrs []attribute.KeyValue) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	opt := metric.WithAttributes(attrs...)
+	switch kind {
+	case metricKindCounter:
+		if value < 0 {
+			return errNegativeIncrement
+		}
+		if _, ok := g.gauges[name]; ok {
+			return fmt.Errorf("metric %q is a %s", name, metricKindGauge)
+		}
+		counter, ok := g.counters[name]
+		if !ok {
+			var err error
+			if counter, err = g.meter.Int64Counter(name); err != nil {
+				return err
+			}
+			g.counters[name] = counter
+		}
+		counter.Add(ctx, value, opt)
+	case metricKindGauge:
+		if _, ok := g.counters[name]; ok {
+			return fmt.Errorf("metric %q is a %s", name, metricKindCounter)
+		}
+		gauge, ok := g.gauges[name]
+		if !ok {
+			var err error
+			if gauge, err = g.meter.Int64Gauge(name); err != nil {
+				return err
+			}
+			g.gauges[name] = gauge
+		}
+		gauge.Record(ctx, value, opt)
+	default:
+		return errUnknownMetricKind
+	}
+	return nil
+}
+
+// GuestMetricsConsumer consumes the metrics recorded by the guest during a
+// call, e.g. to feed them to an internal pipeline of the collector.
+type GuestMetricsConsumer func(context.Context, pmetric.Metrics) error
+
+// WithGuestMetricsConsumer forwards the metrics recorded by the guest to c,
+// in addition to the meter provider. The metrics recorded during a guest
+// call are forwarded as a single batch once the call returns, counters as
+// delta sums. Metrics aren't forwarded by default.
+func WithGuestMetricsConsumer(c GuestMetricsConsumer) Option {
+	return func(o *options) {
+		o.guestMetricsConsumer = c
+	}
+}
+
+// guestMetricsForwarder forwards the metrics recorded by the guest to a
+// GuestMetricsConsumer.
+type guestMetricsForwarder struct {
+	consumer GuestMetricsConsumer
+	logger   *zap.Logger
+}
+
+// newGuestMetricsForwarder returns nil if no consumer is configured.
+func newGuestMetricsForwarder(o *options) *guestMetricsForwarder {
+	if o.guestMetricsConsumer == nil {
+		return nil
+	}
+	return &guestMetricsForwarder{consumer: o.guestMetricsConsumer, logger: o.logger}
+}
+
+// forward passes the metrics recorded in stack by the last guest call to the
+// consumer and drops them from stack. The call doesn't fail if the consumer
+// does, the error is logged instead.
+func (f *guestMetricsForwarder) forward(ctx context.Context, stack *Stack) {
+	if f == nil || stack.guestMetrics == (pmetric.Metrics{}) {
+		return
+	}
+	md := stack.guestMetrics
+	stack.guestMetrics = pmetric.Metrics{}
+	if err := f.consumer(ctx, md); err != nil && f.logger != nil {
+		f.logger.Warn("Failed to forward guest metrics", zap.Int("metrics", md.MetricCount()), zap.Error(err))
+	}
+}
+
+// appendGuestMetric appends a data point of value to the metrics recorded by
+// the guest during the call.
+func (s *Stack) appendGuestMetric(name string, kind metricKind, value int64, attrs []attribute.KeyValue) {
+	if s.guestMetrics == (pmetric.Metrics{}) {
+		s.guestMetrics = pmetric.NewMetrics()
+		s.guestMetrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Scope().SetName(guestScopeName)
+	}
+	clock := s.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	wall, _ := clock()
+
+	m := s.guestMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().AppendEmpty()
+	m.SetName(name)
+	var dp pmetric.NumberDataPoint
+	if kind == metricKindCounter {
+		sum := m.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		dp = sum.DataPoints().AppendEmpty()
+	} else {
+		dp = m.SetEmptyGauge().DataPoints().AppendEmpty()
+	}
+	dp.SetIntValue(value)
+	dp.SetTimestamp(pcommon.Timestamp(wall))
+	for _, kv := range attrs {
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			dp.Attributes().PutBool(string(kv.Key), kv.Value.AsBool())
+		case attribute.INT64:
+			dp.Attributes().PutInt(string(kv.Key), kv.Value.AsInt64())
+		case attribute.FLOAT64:
+			dp.Attributes().PutDouble(string(kv.Key), kv.Value.AsFloat64())
+		default:
+			dp.Attributes().PutStr(string(kv.Key), kv.Value.Emit())
+		}
+	}
+}
+
+// newRecordMetricFn returns the recordMetric host function, recording a
+// value of the guest in metrics. The attributes are passed as a JSON object
+// of string, number or boolean values. The value is also appended to the
+// metrics forwarded after the call if forward is set.
+func newRecordMetricFn(metrics *guestMetrics, forward bool) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		name := uint32(stack[0])
+		nameLen := uint32(stack[1])
+		kind := metricKind(uint32(stack[2]))
+		value := int64(stack[3])
+		attrs := uint32(stack[4])
+		attrsLen := uint32(stack[5])
+
+		params := paramsFromContext(ctx)
+		nameBytes, ok := mod.Memory().Read(name, nameLen)
+		if !ok {
+			params.recordHostError(recordMetric, errOutOfMemory)
+			return
+		}
+		attrsBytes, ok := mod.Memory().Read(attrs, attrsLen)
+		if !ok {
+			params.recordHostError(recordMetric, errOutOfMemory)
+			return
+		}
+
+		kvs, err := decodeAttributes(attrsBytes)
+		if err != nil {
+			params.recordHostError(recordMetric, err)
+			return
+		}
+		if err := metrics.record(ctx, string(nameBytes), kind, value, kvs); err != nil {
+			params.recordHostError(recordMetric, err)
+			return
+		}
+		if forward {
+			params.appendGuestMetric(string(nameBytes), kind, value, kvs)
+		}
+	}
+}
+
+// decodeAttributes decodes a JSON object into attributes. Integral numbers
+// are decoded as int64 attributes, other numbers as float64 ones.
+func decodeAttributes(b []byte) ([]attribute.KeyValue, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid attributes: %w", err)
+	}
+
+	kvs := make([]attribute.KeyValue, 0, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(key, v))
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				kvs = append(kvs, attribute.Int64(key, i))
+			} else if f, err := v.Float64(); err == nil {
+				kvs = append(kvs, attribute.Float64(key, f))
+			} else {
+				return nil, fmt.Errorf("invalid attribute %q: %w", key, err)
+			}
+		default:
+			return nil, fmt.Errorf("invalid attribute %q: unsupported type %T", key, value)
+		}
+	}
+	return kvs, nil
+}
@@ -0,0 +1,210 @@
+package wasmplugin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// metricGuest returns a guest whose processTraces records value in the
+// metric name of the given kind, with the given JSON attributes.
+func metricGuest(name string, kind metricKind, value int64, attrs string) *wasmtest.Module {
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, recordMetric, []api.ValueType{i32, i32, i32, api.ValueTypeI64, i32, i32}, nil)
+	mod.Data = []wasmtest.Data{
+		{Offset: 0, Bytes: []byte(name)},
+		{Offset: 64, Bytes: []byte(attrs)},
+	}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(int32(len(name))),
+			wasmtest.I32Const(int32(kind)), wasmtest.I64Const(value),
+			wasmtest.I32Const(64), wasmtest.I32Const(int32(len(attrs))),
+			mod.Call(recordMetric),
+			wasmtest.I32Const(0),
+		),
+	})
+	return mod
+}
+
+// guestMetric returns the data of the guest metric name.
+func guestMetric(t *testing.T, reader sdkmetric.Reader, name string) metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		if sm.Scope.Name != guestScopeName {
+			continue
+		}
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return nil
+}
+
+func TestRecordMetric(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  metricKind
+		calls int
+		// want is the recorded value after all calls.
+		want int64
+	}{
+		{name: "counter", kind: metricKindCounter, calls: 3, want: 6},
+		{name: "gauge", kind: metricKindGauge, calls: 3, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := metricGuest("spans_dropped", tt.kind, 2, `{"reason":"sampled","shard":3,"retried":false}`)
+			cfg := Config{Path: mod.Write(t)}
+			cfg.Default()
+			reader := sdkmetric.NewManualReader()
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"},
+				WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			defer plugin.Shutdown(t.Context())
+
+			for range tt.calls {
+				if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{}); err != nil {
+					t.Fatalf("failed to call processTraces: %v", err)
+				}
+			}
+
+			var dps []metricdata.DataPoint[int64]
+			switch data := guestMetric(t, reader, "spans_dropped").(type) {
+			case metricdata.Sum[int64]:
+				dps = data.DataPoints
+			case metricdata.Gauge[int64]:
+				dps = data.DataPoints
+			}
+			if len(dps) != 1 || dps[0].Value != tt.want {
+				t.Fatalf("expected a data point with value %d, got %v", tt.want, dps)
+			}
+			want := attribute.NewSet(
+				attribute.String("reason", "sampled"),
+				attribute.Int64("shard", 3),
+				attribute.Bool("retried", false),
+			)
+			if !dps[0].Attributes.Equals(&want) {
+				t.Errorf("expected attributes %v, got %v", want.ToSlice(), dps[0].Attributes.ToSlice())
+			}
+		})
+	}
+}
+
+func TestRecordMetricErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    metricKind
+		value   int64
+		attrs   string
+		wantErr string
+	}{
+		{name: "negative increment", kind: metricKindCounter, value: -1, wantErr: errNegativeIncrement.Error()},
+		{name: "unknown kind", kind: 7, value: 1, wantErr: errUnknownMetricKind.Error()},
+		{name: "invalid attributes", kind: metricKindCounter, value: 1, attrs: `{"tags":["a"]}`, wantErr: "invalid attribute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, metricGuest("spans_dropped", tt.kind, tt.value, tt.attrs), Config{}, "processTraces")
+
+			_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGuestMetricsConsumer(t *testing.T) {
+	tests := []struct {
+		name string
+		kind metricKind
+		want pmetric.MetricType
+	}{
+		{name: "counter", kind: metricKindCounter, want: pmetric.MetricTypeSum},
+		{name: "gauge", kind: metricKindGauge, want: pmetric.MetricTypeGauge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches []pmetric.Metrics
+			consumer := func(_ context.Context, md pmetric.Metrics) error {
+				batches = append(batches, md)
+				return errors.New("pipeline is full")
+			}
+			mod := metricGuest("spans_dropped", tt.kind, 2, `{"reason":"sampled","shard":3}`)
+			cfg := Config{Path: mod.Write(t)}
+			cfg.Default()
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"}, WithGuestMetricsConsumer(consumer))
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			defer plugin.Shutdown(t.Context())
+
+			// The stack is reused, so each call must forward its own batch.
+			stack := &Stack{Clock: func() (int64, int64) { return 42, 0 }}
+			for range 2 {
+				// The failure of the consumer doesn't fail the call.
+				if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+					t.Fatalf("failed to call processTraces: %v", err)
+				}
+			}
+
+			if len(batches) != 2 {
+				t.Fatalf("expected a batch per call, got %d batches", len(batches))
+			}
+			for _, md := range batches {
+				if md.MetricCount() != 1 {
+					t.Fatalf("expected 1 metric, got %d", md.MetricCount())
+				}
+				sm := md.ResourceMetrics().At(0).ScopeMetrics().At(0)
+				if sm.Scope().Name() != guestScopeName {
+					t.Errorf("expected scope %s, got %s", guestScopeName, sm.Scope().Name())
+				}
+				m := sm.Metrics().At(0)
+				if m.Name() != "spans_dropped" || m.Type() != tt.want {
+					t.Fatalf("expected a %s spans_dropped metric, got a %s %s metric", tt.want, m.Type(), m.Name())
+				}
+				var dp pmetric.NumberDataPoint
+				if m.Type() == pmetric.MetricTypeSum {
+					if m.Sum().AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+						t.Errorf("expected a delta sum, got %s", m.Sum().AggregationTemporality())
+					}
+					dp = m.Sum().DataPoints().At(0)
+				} else {
+					dp = m.Gauge().DataPoints().At(0)
+				}
+				if dp.IntValue() != 2 || dp.Timestamp() != 42 {
+					t.Errorf("expected the value 2 at 42, got %d at %d", dp.IntValue(), dp.Timestamp())
+				}
+				want := map[string]any{"reason": "sampled", "shard": int64(3)}
+				if got := dp.Attributes().AsRaw(); !reflect.DeepEqual(got, want) {
+					t.Errorf("expected attributes %v, got %v", want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/stealthrocket/wasi-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// guestStdout and guestStderr are the WASI file descriptors of the
+	// standard output and error of the guest.
+	guestStdout wasi.FD = 1
+	guestStderr wasi.FD = 2
+
+	// maxGuestOutputLine is the length past which the output of the guest is
+	// logged without waiting for the end of the line.
+	maxGuestOutputLine = 64 << 10
+)
+
+// guestPanicPrefix starts the line the Go and TinyGo runtimes write when the
+// guest panics.
+var guestPanicPrefix = []byte("panic: ")
+
+// guestPanic records the last panic message the guest wrote, to attach it to
+// the error of the failing call.
+type guestPanic struct {
+	mu  sync.Mutex
+	msg string
+}
+
+func (g *guestPanic) observe(line []byte) {
+	if !bytes.HasPrefix(line, guestPanicPrefix) {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.msg = string(line[len(guestPanicPrefix):])
+}
+
+// take returns the recorded message, if any, and forgets it.
+func (g *guestPanic) take() string {
+	if g == nil {
+		return ""
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	msg := g.msg
+	g.msg = ""
+	return msg
+}
+
+// guestOutput logs the lines the guest writes to its standard output or
+// error at the debug level, with the stream as the source field.
+type guestOutput struct {
+	logger *zap.Logger
+	source string
+	panic  *guestPanic
+
+	mu sync.Mutex
+	// buf is the end of the output not terminated by a newline yet.
+	buf []byte
+}
+
+func (o *guestOutput) write(p []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	rest := append(o.buf, p...)
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		o.log(rest[:i])
+		rest = rest[i+1:]
+	}
+	if len(rest) >= maxGuestOutputLine {
+		o.log(rest)
+		rest = nil
+	}
+	o.buf = append(o.buf[:0], rest...)
+}
+
+// flush logs the output not terminated by a newline, if any.
+func (o *guestOutput) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.buf) > 0 {
+		o.log(o.buf)
+		o.buf = o.buf[:0]
+	}
+}
+
+func (o *guestOutput) log(line []byte) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if o.panic != nil {
+		o.panic.observe(line)
+	}
+	if ce := o.logger.Check(zap.DebugLevel, ""); ce != nil {
+		ce.Message = string(line)
+		ce.Write(zap.String("source", o.source))
+	}
+}
+
+// guestOutputSystem is the WASI system of the guest, logging what the guest
+// writes to its standard output and error instead of writing it to the
+// collector's.
+type guestOutputSystem struct {
+	wasi.System
+
+	mu      sync.Mutex
+	outputs map[wasi.FD]*guestOutput
+}
+
+// logGuestOutput returns the wrapper of the WASI system of the guest logging
+// its standard output and error with logger, recording the panics of the
+// guest to lastPanic.
+func logGuestOutput(logger *zap.Logger, lastPanic *guestPanic) func(wasi.System) wasi.System {
+	return func(system wasi.System) wasi.System {
+		return &guestOutputSystem{
+			System: system,
+			outputs: map[wasi.FD]*guestOutput{
+				guestStdout: {logger: logger, source: "guest-stdout", panic: lastPanic},
+				guestStderr: {logger: logger, source: "guest-stderr", panic: lastPanic},
+			},
+		}
+	}
+}
+
+func (s *guestOutputSystem) output(fd wasi.FD) *guestOutput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outputs[fd]
+}
+
+// release stops logging the output of fd, as the guest closed or replaced
+// it.
+func (s *guestOutputSystem) release(fd wasi.FD) {
+	s.mu.Lock()
+	out := s.outputs[fd]
+	delete(s.outputs, fd)
+	s.mu.Unlock()
+	if out != nil {
+		out.flush()
+	}
+}
+
+func (s *guestOutputSystem) FDWrite(ctx context.Context, fd wasi.FD, iovecs []wasi.IOVec) (wasi.Size, wasi.Errno) {
+	out := s.output(fd)
+	if out == nil {
+		return s.System.FDWrite(ctx, fd, iovecs)
+	}
+	var size wasi.Size
+	for _, iovec := range iovecs {
+		out.write(iovec)
+		size += wasi.Size(len(iovec))
+	}
+	return size, wasi.ESUCCESS
+}
+
+func (s *guestOutputSystem) FDClose(ctx context.Context, fd wasi.FD) wasi.Errno {
+	s.release(fd)
+	return s.System.FDClose(ctx, fd)
+}
+
+func (s *guestOutputSystem) FDRenumber(ctx context.Context, from, to wasi.FD) wasi.Errno {
+	s.release(to)
+	return s.System.FDRenumber(ctx, from, to)
+}
+
+func (s *guestOutputSystem) Close(ctx context.Context) error {
+	s.mu.Lock()
+	for _, out := range s.outputs {
+		out.flush()
+	}
+	s.mu.Unlock()
+	return s.System.Close(ctx)
+}
@@ -0,0 +1,170 @@
+package wasmplugin
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// printingGuest returns a guest whose print function writes output to its
+// standard output through WASI, in two writes split at the given offset.
+func printingGuest(output string, split int) *wasmtest.Module {
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import("wasi_snapshot_preview1", "fd_write", []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32})
+	// The iovecs of the writes are at offsets 0 and 8, the output at 64.
+	iovecs := make([]byte, 16)
+	binary.LittleEndian.PutUint32(iovecs[0:], 64)
+	binary.LittleEndian.PutUint32(iovecs[4:], uint32(split))
+	binary.LittleEndian.PutUint32(iovecs[8:], uint32(64+split))
+	binary.LittleEndian.PutUint32(iovecs[12:], uint32(len(output)-split))
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: iovecs}, {Offset: 64, Bytes: []byte(output)}}
+	write := func(iovec int32) []byte {
+		return wasmtest.Instructions(
+			wasmtest.I32Const(1), wasmtest.I32Const(iovec), wasmtest.I32Const(1), wasmtest.I32Const(32),
+			mod.Call("fd_write"), wasmtest.Drop,
+		)
+	}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "print",
+		Results: []api.ValueType{i32},
+		Body:    wasmtest.Instructions(write(0), write(8), wasmtest.I32Const(0)),
+	})
+	return mod
+}
+
+func TestGuestOutputLogged(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	cfg := Config{Path: printingGuest("hello\r\nwor", 3).Write(t)}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"print"}, WithLogger(zap.New(core)))
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+
+	for range 2 {
+		if _, err := plugin.ProcessFunctionCall(t.Context(), "print", &Stack{}); err != nil {
+			t.Fatalf("failed to call print: %v", err)
+		}
+	}
+	// The last line isn't terminated, it is logged once the guest is shut
+	// down.
+	if err := plugin.Shutdown(t.Context()); err != nil {
+		t.Fatalf("failed to shutdown plugin: %v", err)
+	}
+
+	var lines []string
+	for _, entry := range logs.All() {
+		if entry.Level != zapcore.DebugLevel || entry.ContextMap()["source"] != "guest-stdout" {
+			t.Errorf("expected a debug entry from guest-stdout, got %v", entry)
+		}
+		lines = append(lines, entry.Message)
+	}
+	if want := []string{"hello", "worhello", "wor"}; !slices.Equal(lines, want) {
+		t.Errorf("expected the lines %q, got %q", want, lines)
+	}
+}
+
+func TestGuestOutputPassthrough(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	cfg := Config{Path: printingGuest("passthrough\n", 0).Write(t), StdioPassthrough: true}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"print"}, WithLogger(zap.New(core)))
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	defer plugin.Shutdown(t.Context())
+
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "print", &Stack{}); err != nil {
+		t.Fatalf("failed to call print: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected the output not to be logged, got %v", logs.All())
+	}
+}
+
+// panickingGuest returns a guest whose processTraces function writes a panic
+// message to its standard error, as the Go runtime does, then fails with
+// fail.
+func panickingGuest(fail func(mod *wasmtest.Module) []byte) *wasmtest.Module {
+	i32 := api.ValueTypeI32
+	output := "panic: index out of range\n\ngoroutine 1 [running]:\n"
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import("wasi_snapshot_preview1", "fd_write", []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}).
+		Import("wasi_snapshot_preview1", "proc_exit", []api.ValueType{i32}, nil)
+	iovec := make([]byte, 8)
+	binary.LittleEndian.PutUint32(iovec[0:], 64)
+	binary.LittleEndian.PutUint32(iovec[4:], uint32(len(output)))
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: iovec}, {Offset: 64, Bytes: []byte(output)}}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(int32(guestStderr)), wasmtest.I32Const(0), wasmtest.I32Const(1), wasmtest.I32Const(32),
+			mod.Call("fd_write"), wasmtest.Drop,
+			fail(mod),
+		),
+	})
+	return mod
+}
+
+func TestGuestPanic(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    func(mod *wasmtest.Module) []byte
+		reason  ErrorReason
+		wantErr string
+	}{
+		{
+			name:    "trap",
+			fail:    func(*wasmtest.Module) []byte { return wasmtest.Unreachable },
+			reason:  ErrorReasonTrap,
+			wantErr: "wasm: processTraces: panic: index out of range: wasm error: unreachable",
+		},
+		{
+			name: "exit",
+			fail: func(mod *wasmtest.Module) []byte {
+				return wasmtest.Instructions(wasmtest.I32Const(2), mod.Call("proc_exit"), wasmtest.I32Const(0))
+			},
+			reason:  ErrorReasonExit,
+			wantErr: "wasm: processTraces: guest exited with code 2: panic: index out of range",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			cfg := Config{Path: panickingGuest(tt.fail).Write(t)}
+			cfg.Default()
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"}, WithLogger(zap.New(core)))
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			defer plugin.Shutdown(t.Context())
+
+			_, err = plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+			var guestErr *GuestError
+			if !errors.As(err, &guestErr) || guestErr.Reason != tt.reason {
+				t.Fatalf("expected a %s guest error, got %v", tt.reason, err)
+			}
+			if guestErr.Panic != "index out of range" {
+				t.Errorf("expected the panic message to be captured, got %q", guestErr.Panic)
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("expected the error to start with %q, got %q", tt.wantErr, err.Error())
+			}
+
+			failures := logs.FilterMessage("Guest call failed").All()
+			if len(failures) != 1 || failures[0].ContextMap()["panic"] != "index out of range" {
+				t.Errorf("expected the failure to be logged with the panic, got %v", failures)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package wasmplugin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"hash/fnv"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// HashConfig is the configuration of the hashes the guest computes through
+// the hashSHA256 and hashFNV64 host functions, e.g. with the guest/crypto
+// package.
+type HashConfig struct {
+	// Salt keys the salted SHA-256 digests requested by the guest, e.g. to
+	// pseudonymize personal data, so the salt is kept out of the module.
+	// The guest can't request salted digests if empty.
+	Salt string `mapstructure:"salt,omitempty"`
+}
+
+// newHashSHA256Fn returns the hashSHA256 host function, writing the SHA-256
+// digest of the guest buffer to the 32 bytes at digest. Salted digests are
+// the HMAC-SHA256 of the buffer keyed by the salt of cfg. It returns the
+// status of the call: hostStatusNotAllowed if a salted digest is requested
+// with no salt configured, hostStatusOutOfMemory if a buffer is outside the
+// guest memory.
+func newHashSHA256Fn(cfg HashConfig) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		buf, bufLen, salted, digest := uint32(stack[0]), uint32(stack[1]), uint32(stack[2]) != 0, uint32(stack[3])
+
+		b, ok := mod.Memory().Read(buf, bufLen)
+		if !ok {
+			stack[0] = uint64(hostStatusOutOfMemory)
+			return
+		}
+		var sum []byte
+		switch {
+		case !salted:
+			s := sha256.Sum256(b)
+			sum = s[:]
+		case cfg.Salt == "":
+			stack[0] = uint64(hostStatusNotAllowed)
+			return
+		default:
+			mac := hmac.New(sha256.New, []byte(cfg.Salt))
+			mac.Write(b)
+			sum = mac.Sum(nil)
+		}
+		if !mod.Memory().Write(digest, sum) {
+			stack[0] = uint64(hostStatusOutOfMemory)
+			return
+		}
+		stack[0] = uint64(hostStatusOK)
+	}
+}
+
+// hashFNV64Fn returns the 64-bit FNV-1a hash of the guest buffer, e.g. to
+// fingerprint spans, where a cryptographic digest isn't needed.
+func hashFNV64Fn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf, bufLen := uint32(stack[0]), uint32(stack[1])
+
+	b, ok := mod.Memory().Read(buf, bufLen)
+	if !ok {
+		paramsFromContext(ctx).recordHostError(hashFNV64, errOutOfMemory)
+		stack[0] = 0
+		return
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	stack[0] = h.Sum64()
+}
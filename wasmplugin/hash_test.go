@@ -0,0 +1,89 @@
+package wasmplugin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"hash/fnv"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestHashSHA256(t *testing.T) {
+	input := []byte("alice@example.com")
+	i32 := api.ValueTypeI32
+	sum := sha256.Sum256(input)
+	mac := hmac.New(sha256.New, []byte("pepper"))
+	mac.Write(input)
+
+	tests := []struct {
+		name       string
+		salt       string
+		salted     int32
+		buf        int32
+		want       hostStatus
+		wantDigest []byte
+	}{
+		{name: "unsalted", want: hostStatusOK, wantDigest: sum[:]},
+		{name: "unsalted ignores the salt", salt: "pepper", want: hostStatusOK, wantDigest: sum[:]},
+		{name: "salted", salt: "pepper", salted: 1, want: hostStatusOK, wantDigest: mac.Sum(nil)},
+		{name: "salted without salt", salted: 1, want: hostStatusNotAllowed},
+		{name: "buffer out of memory", buf: 1 << 20, want: hostStatusOutOfMemory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The guest hashes the input into memory at 64, and echoes the
+			// digest as the status reason.
+			mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+				Import(wasmtest.HostModule, hashSHA256, []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}).
+				Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{i32, i32}, nil)
+			mod.Data = []wasmtest.Data{{Offset: 0, Bytes: input}}
+			mod.Functions = append(mod.Functions, wasmtest.Function{
+				Export:  "hash",
+				Results: []api.ValueType{i32},
+				Body: wasmtest.Instructions(
+					wasmtest.I32Const(tt.buf), wasmtest.I32Const(int32(len(input))), wasmtest.I32Const(tt.salted), wasmtest.I32Const(64), mod.Call(hashSHA256),
+					wasmtest.I32Const(64), wasmtest.I32Const(32), mod.Call(setResultStatusReason),
+				),
+			})
+			plugin := newTestPlugin(t, mod, Config{Hash: HashConfig{Salt: tt.salt}}, "hash")
+
+			stack := &Stack{}
+			res, err := plugin.ProcessFunctionCall(t.Context(), "hash", stack)
+			if err != nil {
+				t.Fatalf("expected the status to be reported to the guest, got %v", err)
+			}
+			if got := hostStatus(res[0]); got != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, got)
+			}
+			if tt.wantDigest != nil && stack.StatusReason != string(tt.wantDigest) {
+				t.Errorf("expected digest %x, got %x", tt.wantDigest, stack.StatusReason)
+			}
+		})
+	}
+}
+
+func TestHashFNV64(t *testing.T) {
+	input := []byte("trace-id/span-name")
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, hashFNV64, []api.ValueType{i32, i32}, []api.ValueType{api.ValueTypeI64})
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: input}}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "hash",
+		Results: []api.ValueType{api.ValueTypeI64},
+		Body:    wasmtest.Instructions(wasmtest.I32Const(0), wasmtest.I32Const(int32(len(input))), mod.Call(hashFNV64)),
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "hash")
+
+	res, err := plugin.ProcessFunctionCall(t.Context(), "hash", &Stack{})
+	if err != nil {
+		t.Fatalf("failed to call hash: %v", err)
+	}
+	h := fnv.New64a()
+	h.Write(input)
+	if want := h.Sum64(); res[0] != want {
+		t.Errorf("expected hash %x, got %x", want, res[0])
+	}
+}
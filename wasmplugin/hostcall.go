@@ -0,0 +1,42 @@
+package wasmplugin
+
+import (
+	"context"
+	"slices"
+
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+)
+
+// hostCallTracer logs every host function call of the guest, to debug how
+// the guest interacts with the host. A nil tracer logs nothing.
+type hostCallTracer struct {
+	logger *zap.Logger
+}
+
+// newHostCallTracer returns a tracer if tracing is enabled and logger
+// records debug entries, nil otherwise.
+func newHostCallTracer(enabled bool, logger *zap.Logger) *hostCallTracer {
+	if !enabled || logger == nil || !logger.Core().Enabled(zap.DebugLevel) {
+		return nil
+	}
+	return &hostCallTracer{logger: logger}
+}
+
+// wrap returns fn logging its calls. params and results are the number of
+// parameters and results of fn.
+func (t *hostCallTracer) wrap(name string, fn api.GoModuleFunc, params, results int) api.GoModuleFunc {
+	if t == nil {
+		return fn
+	}
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		// Results overwrite the parameters on the stack.
+		in := slices.Clone(stack[:params])
+		fn(ctx, mod, stack)
+		t.logger.Debug("host function call",
+			zap.String("function", name),
+			zap.Uint64s("params", in),
+			zap.Uint64s("results", stack[:results]),
+		)
+	}
+}
@@ -0,0 +1,69 @@
+package wasmplugin
+
+import (
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTraceHostCalls(t *testing.T) {
+	tests := []struct {
+		name        string
+		trace       bool
+		level       zapcore.Level
+		wantEntries int
+	}{
+		{name: "enabled", trace: true, level: zapcore.DebugLevel, wantEntries: 1},
+		{name: "disabled", trace: false, level: zapcore.DebugLevel},
+		{name: "enabled above debug level", trace: true, level: zapcore.InfoLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// processTraces sets the 6 bytes long status reason.
+			mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+				Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+			mod.Data = []wasmtest.Data{{Offset: 16, Bytes: []byte("reason")}}
+			mod.Functions = append(mod.Functions, wasmtest.Function{
+				Export:  "processTraces",
+				Results: []api.ValueType{api.ValueTypeI32},
+				Body: wasmtest.Instructions(
+					wasmtest.I32Const(16), wasmtest.I32Const(6), mod.Call(setResultStatusReason),
+					wasmtest.I32Const(0),
+				),
+			})
+
+			core, logs := observer.New(tt.level)
+			cfg := Config{Path: mod.Write(t), TraceHostCalls: tt.trace}
+			cfg.Default()
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"}, WithLogger(zap.New(core)))
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			defer plugin.Shutdown(t.Context())
+
+			if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{}); err != nil {
+				t.Fatalf("failed to call processTraces: %v", err)
+			}
+
+			entries := logs.FilterMessage("host function call").AllUntimed()
+			if len(entries) != tt.wantEntries {
+				t.Fatalf("expected %d host function call entries, got %d", tt.wantEntries, len(entries))
+			}
+			if tt.wantEntries == 0 {
+				return
+			}
+			fields := entries[0].ContextMap()
+			if fields["function"] != setResultStatusReason {
+				t.Errorf("expected function %s, got %v", setResultStatusReason, fields["function"])
+			}
+			if params, _ := fields["params"].([]any); len(params) != 2 || params[1] != uint64(6) {
+				t.Errorf("expected params [16 6], got %v", fields["params"])
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package wasmplugin
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// hostStatus is the status the host functions reporting their failures to
+// the guest write to the status pointer the guest passes, so the guest
+// reacts to them rather than the call failing once the guest returns. The
+// guest SDK mirrors the values.
+type hostStatus uint32
+
+const (
+	hostStatusOK hostStatus = iota
+	// hostStatusBufferTooSmall means the value didn't fit the buffer and
+	// wasn't written. The size of the value is returned so the guest retries
+	// with a large enough buffer.
+	hostStatusBufferTooSmall
+	// hostStatusOutOfMemory means the guest passed a buffer outside its
+	// memory.
+	hostStatusOutOfMemory
+	// hostStatusNotAllowed means the configuration of the plugin doesn't
+	// allow the operation, e.g. reading a file missing from
+	// files.allowed_paths.
+	hostStatusNotAllowed
+	// hostStatusNotFound means the resource, e.g. the file, doesn't exist.
+	hostStatusNotFound
+	// hostStatusIOError means the operation failed on the host side.
+	hostStatusIOError
+)
+
+// writeHostStatus writes status at ptr. The failure is recorded as the host
+// error of function if ptr is outside the guest memory, as the guest can't
+// be told then.
+func writeHostStatus(ctx context.Context, mod api.Module, function string, ptr uint32, status hostStatus) {
+	if !mod.Memory().WriteUint32Le(ptr, uint32(status)) {
+		paramsFromContext(ctx).recordHostError(function, errOutOfMemory)
+	}
+}
+
+// writeBytesWithStatus writes bytes to memory if they fit within the limit,
+// like writeBytesIfUnderLimit, and returns the status of the write.
+func writeBytesWithStatus(memory api.Memory, bytes []byte, buf, bufLimit uint32) hostStatus {
+	if uint32(len(bytes)) > bufLimit {
+		return hostStatusBufferTooSmall
+	}
+	if !memory.Write(buf, bytes) {
+		return hostStatusOutOfMemory
+	}
+	return hostStatusOK
+}
@@ -0,0 +1,59 @@
+package wasmplugin
+
+import (
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestGetPluginConfigStatus(t *testing.T) {
+	configJSON := `{"key":"value"}`
+	i32 := api.ValueTypeI32
+
+	tests := []struct {
+		name                  string
+		buf, limit, statusPtr int32
+		want                  hostStatus
+		wantErr               bool
+	}{
+		{name: "ok", buf: 64, limit: 64, want: hostStatusOK},
+		{name: "buffer too small", buf: 64, limit: int32(len(configJSON)) - 1, want: hostStatusBufferTooSmall},
+		{name: "buffer out of memory", buf: 1 << 20, limit: 64, want: hostStatusOutOfMemory},
+		{name: "status out of memory", buf: 64, limit: 64, statusPtr: 1 << 20, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The guest reads the config and returns the size and status
+			// written at statusPtr.
+			mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+				Import(wasmtest.HostModule, getPluginConfigStatus, []api.ValueType{i32, i32, i32}, []api.ValueType{i32})
+			mod.Functions = append(mod.Functions, wasmtest.Function{
+				Export:  "config",
+				Results: []api.ValueType{i32, i32},
+				Body: wasmtest.Instructions(
+					wasmtest.I32Const(tt.buf), wasmtest.I32Const(tt.limit), wasmtest.I32Const(tt.statusPtr), mod.Call(getPluginConfigStatus),
+					wasmtest.I32Const(tt.statusPtr%(1<<16)), wasmtest.I32Load(0),
+				),
+			})
+			plugin := newTestPlugin(t, mod, Config{}, "config")
+
+			res, err := plugin.ProcessFunctionCall(t.Context(), "config", &Stack{PluginConfigJSON: []byte(configJSON)})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected a status pointer outside the guest memory to fail the call")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the status to be reported to the guest, got %v", err)
+			}
+			if got := int(res[0]); got != len(configJSON) {
+				t.Errorf("expected the size %d, got %d", len(configJSON), got)
+			}
+			if got := hostStatus(res[1]); got != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// HTTPConfig is the configuration of the HTTP requests the guest sends
+// through the httpDo host function, e.g. with the guest/http package. The
+// host performs the requests, so it controls which destinations the guests
+// reach, and the requests follow the proxy settings of the collector, read
+// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type HTTPConfig struct {
+	// AllowedHosts are the destinations the guest may send requests to,
+	// either a host, allowing any port, or a host:port. Requests to other
+	// destinations, including the redirects of allowed ones, are refused.
+	// No request is allowed if empty.
+	AllowedHosts []string `mapstructure:"allowed_hosts,omitempty"`
+
+	// Timeout is the maximum duration of a request, including the reading
+	// of its response. The default is 10s.
+	Timeout time.Duration `mapstructure:"timeout,omitempty"`
+
+	// MaxResponseSize is the size past which the body of a response is
+	// refused, in bytes. The default is 4MiB.
+	MaxResponseSize int64 `mapstructure:"max_response_size,omitempty"`
+}
+
+func (cfg *HTTPConfig) Validate() error {
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("http.timeout must not be negative")
+	}
+	if cfg.MaxResponseSize < 0 {
+		return fmt.Errorf("http.max_response_size must not be negative")
+	}
+	for _, host := range cfg.AllowedHosts {
+		if _, err := parseAllowedHost(host); err != nil {
+			return fmt.Errorf("http.allowed_hosts: %w", err)
+		}
+	}
+	return nil
+}
+
+// Default sets the default values for the HTTP configuration
+// if they are not set.
+func (cfg *HTTPConfig) Default() {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultHTTPConfig.Timeout
+	}
+	if cfg.MaxResponseSize == 0 {
+		cfg.MaxResponseSize = DefaultHTTPConfig.MaxResponseSize
+	}
+}
+
+// DefaultHTTPConfig is the default configuration for the guest HTTP
+// requests.
+var DefaultHTTPConfig = HTTPConfig{
+	Timeout:         10 * time.Second,
+	MaxResponseSize: 4 << 20,
+}
+
+// parseAllowedHost returns the normalized form of an entry of
+// HTTPConfig.AllowedHosts: the lowercase host, joined with the port if any.
+func parseAllowedHost(entry string) (string, error) {
+	if strings.Contains(entry, "/") {
+		return "", fmt.Errorf("%q must be a host or host:port, without scheme nor path", entry)
+	}
+	host, port, err := net.SplitHostPort(entry)
+	if err != nil {
+		// The entry has no port, IPv6 addresses may be bracketed or not.
+		host, port = strings.TrimSuffix(strings.TrimPrefix(entry, "["), "]"), ""
+	} else if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return "", fmt.Errorf("%q has an invalid port", entry)
+	}
+	if host == "" {
+		return "", fmt.Errorf("%q has no host", entry)
+	}
+	host = strings.ToLower(host)
+	if port == "" {
+		return host, nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// errHTTPNotAllowed is the error of the requests to a destination missing
+// from HTTPConfig.AllowedHosts.
+var errHTTPNotAllowed = errors.New("destination not in http.allowed_hosts")
+
+// httpRequest is the request the guest passes to httpDo, in JSON.
+type httpRequest struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   []byte              `json:"body,omitempty"`
+}
+
+// httpResponse is the result of httpDo the guest reads with
+// httpResponseChunk, in JSON. Error is set if the request was refused or
+// failed, and the other fields are unset then.
+type httpResponse struct {
+	StatusCode int                 `json:"status_code,omitempty"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       []byte              `json:"body,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// httpClient performs the requests of a guest.
+type httpClient struct {
+	cfg     HTTPConfig
+	allowed map[string]bool
+	client  *http.Client
+}
+
+// newHTTPClient returns the client performing the requests of a guest
+// configured with cfg.
+func newHTTPClient(cfg HTTPConfig) *httpClient {
+	cfg.Default()
+	c := &httpClient{cfg: cfg, allowed: make(map[string]bool)}
+	for _, entry := range cfg.AllowedHosts {
+		// The entries were validated with the config.
+		host, _ := parseAllowedHost(entry)
+		c.allowed[host] = true
+	}
+	c.client = &http.Client{
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return c.allow(req.URL)
+		},
+	}
+	return c
+}
+
+// allow returns errHTTPNotAllowed unless the destination of u is allowed.
+func (c *httpClient) allow(u *url.URL) error {
+	var port string
+	switch u.Scheme {
+	case "http":
+		port = "80"
+	case "https":
+		port = "443"
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if p := u.Port(); p != "" {
+		port = p
+	}
+	host := strings.ToLower(u.Hostname())
+	if c.allowed[host] || c.allowed[net.JoinHostPort(host, port)] {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errHTTPNotAllowed, net.JoinHostPort(host, port))
+}
+
+// do performs req, bounded by the timeout of the config.
+func (c *httpClient) do(ctx context.Context, req httpRequest) httpResponse {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return httpResponse{Error: err.Error()}
+	}
+	if err := c.allow(request.URL); err != nil {
+		return httpResponse{Error: err.Error()}
+	}
+	request.Header = req.Header
+	if request.Header == nil {
+		request.Header = make(http.Header)
+	}
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		// The guest client reports the method and URL already.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return httpResponse{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.cfg.MaxResponseSize+1))
+	if err != nil {
+		return httpResponse{Error: err.Error()}
+	}
+	if int64(len(body)) > c.cfg.MaxResponseSize {
+		return httpResponse{Error: fmt.Sprintf("response body exceeds http.max_response_size of %d bytes", c.cfg.MaxResponseSize)}
+	}
+	return httpResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+}
+
+// newHTTPDoFn returns the httpDo host function, performing the request the
+// guest passes in JSON with client. The size of the httpResponse is
+// returned, and the guest reads it with httpResponseChunk, so large
+// responses don't send the request again.
+func newHTTPDoFn(client *httpClient) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		req := uint32(stack[0])
+		reqLen := uint32(stack[1])
+
+		params := paramsFromContext(ctx)
+		reqBytes, ok := mod.Memory().Read(req, reqLen)
+		if !ok {
+			params.recordHostError(httpDo, errOutOfMemory)
+			params.httpResponse = nil
+			stack[0] = 0
+			return
+		}
+
+		var request httpRequest
+		var result httpResponse
+		if err := json.Unmarshal(reqBytes, &request); err != nil {
+			result.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			result = client.do(ctx, request)
+		}
+		b, err := json.Marshal(result)
+		if err != nil {
+			b, _ = json.Marshal(httpResponse{Error: fmt.Sprintf("invalid response: %v", err)})
+		}
+		params.httpResponse = b
+		stack[0] = uint64(len(b))
+	}
+}
+
+// httpResponseChunkFn writes the frame of the response of the last httpDo
+// starting at offset, up to bufLimit bytes, and returns the size of the
+// whole response.
+func httpResponseChunkFn(ctx context.Context, mod api.Module, stack []uint64) {
+	offset := uint32(stack[0])
+	buf := uint32(stack[1])
+	bufLimit := uint32(stack[2])
+
+	params := paramsFromContext(ctx)
+	response := params.httpResponse
+	if offset < uint32(len(response)) {
+		chunk := response[offset:]
+		if uint32(len(chunk)) > bufLimit {
+			chunk = chunk[:bufLimit]
+		}
+		if !mod.Memory().Write(buf, chunk) {
+			params.recordHostError(httpResponseChunk, errOutOfMemory)
+		}
+	}
+	stack[0] = uint64(len(response))
+}
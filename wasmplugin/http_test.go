@@ -0,0 +1,199 @@
+package wasmplugin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// newHTTPTestServer returns a server echoing the method, the X-Test header
+// and the body of the requests, redirecting /redirect to location, and
+// answering /large with a body of 1KiB.
+func newHTTPTestServer(t *testing.T, location string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, location, http.StatusFound)
+		case "/large":
+			w.Write([]byte(strings.Repeat("a", 1024)))
+		default:
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Test", r.Header.Get("X-Test"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(r.Method + " " + string(body)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPClient(t *testing.T) {
+	other := newHTTPTestServer(t, "")
+	server := newHTTPTestServer(t, other.URL)
+	serverURL, _ := url.Parse(server.URL)
+
+	tests := []struct {
+		name       string
+		allowed    []string
+		req        httpRequest
+		wantStatus int
+		wantBody   string
+		wantErr    string
+	}{
+		{
+			name:       "allowed host",
+			allowed:    []string{serverURL.Hostname()},
+			req:        httpRequest{Method: http.MethodPost, URL: server.URL, Header: map[string][]string{"X-Test": {"1"}}, Body: []byte("hello")},
+			wantStatus: http.StatusCreated,
+			wantBody:   "POST hello",
+		},
+		{
+			name:       "allowed port",
+			allowed:    []string{serverURL.Host},
+			req:        httpRequest{Method: http.MethodGet, URL: server.URL},
+			wantStatus: http.StatusCreated,
+			wantBody:   "GET ",
+		},
+		{
+			name:    "other port",
+			allowed: []string{serverURL.Hostname() + ":1"},
+			req:     httpRequest{Method: http.MethodGet, URL: server.URL},
+			wantErr: errHTTPNotAllowed.Error(),
+		},
+		{
+			name:    "nothing allowed",
+			req:     httpRequest{Method: http.MethodGet, URL: server.URL},
+			wantErr: errHTTPNotAllowed.Error(),
+		},
+		{
+			name:    "redirect to other port",
+			allowed: []string{serverURL.Host},
+			req:     httpRequest{Method: http.MethodGet, URL: server.URL + "/redirect"},
+			wantErr: errHTTPNotAllowed.Error(),
+		},
+		{
+			name:    "response too large",
+			allowed: []string{serverURL.Host},
+			req:     httpRequest{Method: http.MethodGet, URL: server.URL + "/large"},
+			wantErr: "exceeds http.max_response_size",
+		},
+		{
+			name:    "unsupported scheme",
+			allowed: []string{serverURL.Host},
+			req:     httpRequest{Method: http.MethodGet, URL: "file:///etc/passwd"},
+			wantErr: "unsupported scheme",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newHTTPClient(HTTPConfig{AllowedHosts: tt.allowed, MaxResponseSize: 512})
+			resp := client.do(t.Context(), tt.req)
+			if tt.wantErr != "" {
+				if !strings.Contains(resp.Error, tt.wantErr) {
+					t.Errorf("expected error %q, got %+v", tt.wantErr, resp)
+				}
+				return
+			}
+			if resp.Error != "" || resp.StatusCode != tt.wantStatus || string(resp.Body) != tt.wantBody {
+				t.Errorf("expected status %d and body %q, got %+v", tt.wantStatus, tt.wantBody, resp)
+			}
+		})
+	}
+}
+
+func TestHTTPConfigValidate(t *testing.T) {
+	for _, host := range []string{"example.com", "example.com:8080", "127.0.0.1", "[::1]:80", "::1"} {
+		cfg := HTTPConfig{AllowedHosts: []string{host}}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("expected %q to be valid, got %v", host, err)
+		}
+	}
+	for _, host := range []string{"", "http://example.com", "example.com/path", "example.com:http", "example.com:0", ":80"} {
+		cfg := HTTPConfig{AllowedHosts: []string{host}}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected %q to be invalid", host)
+		}
+	}
+}
+
+func TestHTTPDo(t *testing.T) {
+	server := newHTTPTestServer(t, "")
+	serverURL, _ := url.Parse(server.URL)
+	req, _ := json.Marshal(httpRequest{Method: http.MethodPut, URL: server.URL, Body: []byte("from guest")})
+
+	// processTraces sends the request, then reads the response in frames of
+	// 8 bytes to 4096, and returns its size.
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, httpDo, []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, httpResponseChunk, []api.ValueType{i32, i32, i32}, []api.ValueType{i32})
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: req}}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{i32},
+		// size, offset
+		Locals: []api.ValueType{i32, i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(int32(len(req))), mod.Call(httpDo), wasmtest.LocalSet(0),
+			wasmtest.Block(), wasmtest.Loop(),
+			wasmtest.LocalGet(1), wasmtest.LocalGet(0), wasmtest.I32LtU, wasmtest.I32Eqz, wasmtest.BrIf(1),
+			wasmtest.LocalGet(1),
+			wasmtest.LocalGet(1), wasmtest.I32Const(4096), wasmtest.I32Add,
+			wasmtest.I32Const(8),
+			mod.Call(httpResponseChunk), wasmtest.Drop,
+			wasmtest.LocalGet(1), wasmtest.I32Const(8), wasmtest.I32Add, wasmtest.LocalSet(1),
+			wasmtest.Br(0),
+			wasmtest.End, wasmtest.End,
+			wasmtest.LocalGet(0),
+		),
+	})
+	plugin := newTestPlugin(t, mod, Config{HTTP: HTTPConfig{AllowedHosts: []string{serverURL.Host}}}, "processTraces")
+
+	res, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+	if err != nil {
+		t.Fatalf("failed to call the guest: %v", err)
+	}
+	b, ok := plugin.Module.Memory().Read(4096, uint32(res[0]))
+	if !ok {
+		t.Fatalf("failed to read %d bytes of guest memory", res[0])
+	}
+	var resp httpResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		t.Fatalf("failed to decode %s: %v", b, err)
+	}
+	if resp.StatusCode != http.StatusCreated || string(resp.Body) != "PUT from guest" {
+		t.Errorf("expected the response of the server, got %+v", resp)
+	}
+}
+
+func TestHTTPDoInvalidRequest(t *testing.T) {
+	stack := &Stack{}
+	ctx := createContextWithStack(t.Context(), stack)
+	mod := newTestPlugin(t, wasmtest.NewGuest(int32(telemetryTypeTraces)), Config{}).Module
+	if !mod.Memory().Write(0, []byte("{")) {
+		t.Fatal("failed to write the request")
+	}
+
+	fnStack := []uint64{0, 1}
+	newHTTPDoFn(newHTTPClient(HTTPConfig{}))(ctx, mod, fnStack)
+	var resp httpResponse
+	if err := json.Unmarshal(stack.httpResponse, &resp); err != nil || uint64(len(stack.httpResponse)) != fnStack[0] {
+		t.Fatalf("expected the size of the response to be returned, got %d for %s", fnStack[0], stack.httpResponse)
+	}
+	if !strings.HasPrefix(resp.Error, "invalid request") {
+		t.Errorf("expected the request to be refused, got %+v", resp)
+	}
+
+	if newHTTPDoFn(newHTTPClient(HTTPConfig{}))(ctx, mod, []uint64{0, 1 << 30}); !errors.Is(stack.HostError, errOutOfMemory) {
+		t.Errorf("expected an out of memory host error, got %v", stack.HostError)
+	}
+}
@@ -0,0 +1,91 @@
+package wasmplugin
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PluginInfo describes a loaded plugin, for operational tooling.
+type PluginInfo struct {
+	// Path is the path of the module.
+	Path string `json:"path"`
+	// Digest is the sha256 digest of the module, in the form of
+	// Config.ExpectedDigest.
+	Digest string `json:"digest"`
+	// Signals are the telemetry signals the guest supports: traces, metrics,
+	// logs and profiles.
+	Signals []string `json:"signals"`
+	// ABIVersion is the ABI version the guest declares, zero if it predates
+	// the declaration.
+	ABIVersion uint32 `json:"abi_version"`
+	// RuntimeMode is the mode of the runtime the guest was instantiated in.
+	RuntimeMode RuntimeMode `json:"runtime_mode"`
+	// Ready reports whether the guest is able to serve calls, see
+	// WasmPlugin.Ready.
+	Ready bool `json:"ready"`
+}
+
+// signals returns the names of the telemetry signals of t.
+func (t telemetryType) signals() []string {
+	var signals []string
+	for _, s := range []struct {
+		typ  telemetryType
+		name string
+	}{
+		{telemetryTypeTraces, "traces"},
+		{telemetryTypeMetrics, "metrics"},
+		{telemetryTypeLogs, "logs"},
+		{telemetryTypeProfiles, "profiles"},
+	} {
+		if t&s.typ != 0 {
+			signals = append(signals, s.name)
+		}
+	}
+	return signals
+}
+
+// Info describes the plugin.
+func (p *WasmPlugin) Info() PluginInfo {
+	info := p.info
+	info.Signals = slices.Clone(info.Signals)
+	info.Ready = p.Ready()
+	return info
+}
+
+// pluginRegistry is the set of the plugins of the process which aren't shut
+// down.
+type pluginRegistry struct {
+	mu      sync.Mutex
+	plugins map[*WasmPlugin]struct{}
+}
+
+var loadedPlugins = &pluginRegistry{plugins: make(map[*WasmPlugin]struct{})}
+
+func (r *pluginRegistry) add(p *WasmPlugin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.plugins[p] = struct{}{}
+}
+
+func (r *pluginRegistry) remove(p *WasmPlugin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.plugins, p)
+}
+
+// LoadedPlugins describes the plugins of the process which aren't shut down,
+// across the components of the collector, sorted by path.
+func LoadedPlugins() []PluginInfo {
+	loadedPlugins.mu.Lock()
+	infos := make([]PluginInfo, 0, len(loadedPlugins.plugins))
+	for p := range loadedPlugins.plugins {
+		infos = append(infos, p.Info())
+	}
+	loadedPlugins.mu.Unlock()
+
+	slices.SortStableFunc(infos, func(a, b PluginInfo) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return infos
+}
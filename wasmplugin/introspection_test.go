@@ -0,0 +1,63 @@
+package wasmplugin
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+)
+
+func TestLoadedPlugins(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces|telemetryTypeLogs|telemetryTypeProfiles), returnsI32(abiVersion, ABIVersion))
+	cfg := Config{Path: mod.Write(t)}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, nil)
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+
+	module, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		t.Fatalf("failed to read module: %v", err)
+	}
+	want := PluginInfo{
+		Path:        cfg.Path,
+		Digest:      fmt.Sprintf("sha256:%x", sha256.Sum256(module)),
+		Signals:     []string{"traces", "logs", "profiles"},
+		ABIVersion:  ABIVersion,
+		RuntimeMode: RuntimeModeInterpreter,
+		Ready:       true,
+	}
+	loaded := func() (PluginInfo, bool) {
+		i := slices.IndexFunc(LoadedPlugins(), func(info PluginInfo) bool { return info.Path == cfg.Path })
+		if i < 0 {
+			return PluginInfo{}, false
+		}
+		return LoadedPlugins()[i], true
+	}
+	info, ok := loaded()
+	if !ok {
+		t.Fatal("expected the plugin to be listed")
+	}
+	if info.Path != want.Path || info.Digest != want.Digest || !slices.Equal(info.Signals, want.Signals) ||
+		info.ABIVersion != want.ABIVersion || info.RuntimeMode != want.RuntimeMode || info.Ready != want.Ready {
+		t.Errorf("expected %+v, got %+v", want, info)
+	}
+
+	if err := plugin.Shutdown(t.Context()); err != nil {
+		t.Fatalf("failed to shutdown plugin: %v", err)
+	}
+	if _, ok := loaded(); ok {
+		t.Error("expected the shut down plugin not to be listed")
+	}
+}
+
+func TestPluginInfoUndeclaredABIVersion(t *testing.T) {
+	plugin := newTestPlugin(t, wasmtest.NewGuest(int32(telemetryTypeMetrics)), Config{})
+	if info := plugin.Info(); info.ABIVersion != 0 || !slices.Equal(info.Signals, []string{"metrics"}) {
+		t.Errorf("expected no ABI version and the metrics signal, got %+v", info)
+	}
+}
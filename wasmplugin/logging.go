@@ -0,0 +1,118 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newLogMessageFn returns the logMessage host function, logging the messages
+// of the guest with logger. The fields of a message are passed as a JSON
+// object.
+func newLogMessageFn(logger *zap.Logger) api.GoModuleFunc {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		level := zapcore.Level(int32(stack[0]))
+		msg := uint32(stack[1])
+		msgLen := uint32(stack[2])
+		fields := uint32(stack[3])
+		fieldsLen := uint32(stack[4])
+
+		// Guests can't panic or exit the collector by logging.
+		level = min(level, zapcore.ErrorLevel)
+		ce := logger.Check(level, "")
+		if ce == nil {
+			return
+		}
+
+		msgBytes, ok := mod.Memory().Read(msg, msgLen)
+		if !ok {
+			recordLogError(ctx, errOutOfMemory)
+			return
+		}
+		fieldsBytes, ok := mod.Memory().Read(fields, fieldsLen)
+		if !ok {
+			recordLogError(ctx, errOutOfMemory)
+			return
+		}
+
+		ce.Message = string(msgBytes)
+		zapFields, err := decodeLogFields(fieldsBytes)
+		if err != nil {
+			recordLogError(ctx, err)
+			return
+		}
+		ce.Write(zapFields...)
+	}
+}
+
+// decodeLogFields decodes the JSON object of the fields of a message. The
+// integers are decoded as such, rather than as floats, so they render as
+// the guest logged them, e.g. IDs past 2^53.
+func decodeLogFields(b []byte) ([]zap.Field, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	fields := make([]zap.Field, 0, len(raw))
+	for key, value := range raw {
+		fields = append(fields, zap.Any(key, logFieldValue(value)))
+	}
+	return fields, nil
+}
+
+// logFieldValue returns v with its JSON numbers, nested ones included,
+// converted to int64 or, if they aren't integers, float64.
+func logFieldValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = logFieldValue(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = logFieldValue(e)
+		}
+	}
+	return v
+}
+
+// recordLogError records err raised by logMessage. Guests may log while they
+// are instantiated, outside of any function call, in which case there is no
+// stack to record the error in.
+func recordLogError(ctx context.Context, err error) {
+	if stack, ok := ctx.Value(stackKey{}).(*Stack); ok {
+		stack.recordHostError(logMessage, err)
+	}
+}
+
+// newGetLogLevelFn returns the getLogLevel host function, returning the
+// minimum level enabled by logger, so the guest drops the messages below it
+// without passing them to the host. zapcore.InvalidLevel is returned if
+// logger logs nothing.
+func newGetLogLevelFn(logger *zap.Logger) api.GoModuleFunc {
+	level := zapcore.InvalidLevel
+	if logger != nil {
+		level = zapcore.LevelOf(logger.Core())
+	}
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		stack[0] = api.EncodeI32(int32(level))
+	}
+}
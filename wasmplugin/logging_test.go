@@ -0,0 +1,99 @@
+package wasmplugin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// loggingGuest returns a guest whose processTraces logs a message at the
+// debug, info and fatal levels, and returns the result of getLogLevel.
+func loggingGuest() *wasmtest.Module {
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, logMessage, []api.ValueType{i32, i32, i32, i32, i32}, nil).
+		Import(wasmtest.HostModule, getLogLevel, nil, []api.ValueType{i32})
+	fields := `{"user":"alice","spans":3,"trace_count":9007199254740993,"ratio":0.5,"sizes":[1,2]}`
+	mod.Data = []wasmtest.Data{
+		{Offset: 0, Bytes: []byte("debug")},
+		{Offset: 16, Bytes: []byte("info")},
+		{Offset: 32, Bytes: []byte("fatal")},
+		{Offset: 48, Bytes: []byte(fields)},
+	}
+	log := func(level zapcore.Level, msg, msgLen, fieldsLen int32) []byte {
+		return wasmtest.Instructions(
+			wasmtest.I32Const(int32(level)), wasmtest.I32Const(msg), wasmtest.I32Const(msgLen),
+			wasmtest.I32Const(48), wasmtest.I32Const(fieldsLen), mod.Call(logMessage),
+		)
+	}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			log(zapcore.DebugLevel, 0, 5, 0),
+			log(zapcore.InfoLevel, 16, 4, int32(len(fields))),
+			log(zapcore.FatalLevel, 32, 5, 0),
+			mod.Call(getLogLevel),
+		),
+	})
+	return mod
+}
+
+func TestLogMessage(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	cfg := Config{Path: loggingGuest().Write(t)}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"}, WithLogger(zap.New(core)))
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	defer plugin.Shutdown(t.Context())
+
+	res, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+	if err != nil {
+		t.Fatalf("failed to call processTraces: %v", err)
+	}
+	if level := zapcore.Level(int32(res[0])); level != zapcore.InfoLevel {
+		t.Errorf("expected log level %v, got %v", zapcore.InfoLevel, level)
+	}
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Message != "info" || entries[0].ContextMap()["user"] != "alice" {
+		t.Errorf("expected info entry with user alice, got %q %v", entries[0].Message, entries[0].ContextMap())
+	}
+	// The integers aren't rounded through floats.
+	want := map[string]any{
+		"user":        "alice",
+		"spans":       int64(3),
+		"trace_count": int64(9007199254740993),
+		"ratio":       0.5,
+		"sizes":       []any{int64(1), int64(2)},
+	}
+	if got := entries[0].ContextMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected fields %v, got %v", want, got)
+	}
+	// The fatal message of the guest doesn't exit the collector.
+	if entries[1].Message != "fatal" || entries[1].Level != zapcore.ErrorLevel {
+		t.Errorf("expected fatal message logged at error level, got %q at %v", entries[1].Message, entries[1].Level)
+	}
+}
+
+func TestGetLogLevelWithoutLogger(t *testing.T) {
+	plugin := newTestPlugin(t, loggingGuest(), Config{}, "processTraces")
+
+	res, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+	if err != nil {
+		t.Fatalf("failed to call processTraces: %v", err)
+	}
+	if level := zapcore.Level(int32(res[0])); level != zapcore.InvalidLevel {
+		t.Errorf("expected log level %v, got %v", zapcore.InvalidLevel, level)
+	}
+}
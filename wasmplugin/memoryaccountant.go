@@ -0,0 +1,162 @@
+package wasmplugin
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// MemoryAccountant sums the memory of the guests of the plugins it is
+// passed to, and refuses to instantiate guests or to grow their memory past
+// its limit. It is safe for concurrent use.
+type MemoryAccountant struct {
+	mu sync.Mutex
+	// limit and used are in bytes. A zero limit means no limit.
+	limit uint64
+	used  uint64
+}
+
+// NewMemoryAccountant returns an accountant limiting the memory of the guests
+// to limitPages 64KiB pages in total, zero for no limit.
+func NewMemoryAccountant(limitPages uint32) *MemoryAccountant {
+	return &MemoryAccountant{limit: uint64(limitPages) * wasmPageSize}
+}
+
+// defaultMemoryAccountant is the accountant shared by the plugins of the
+// process unless WithMemoryAccountant is passed. It has no limit until a
+// plugin configures Config.GlobalMemoryLimitPages.
+var defaultMemoryAccountant = NewMemoryAccountant(0)
+
+// WithMemoryAccountant sets the accountant of the memory of the guest. An
+// accountant shared by all plugins is used by default.
+func WithMemoryAccountant(a *MemoryAccountant) Option {
+	return func(o *options) {
+		o.memoryAccountant = a
+	}
+}
+
+// UsedPages returns the number of pages of the memory of the guests.
+func (a *MemoryAccountant) UsedPages() uint32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return uint32(a.used / wasmPageSize)
+}
+
+// lowerLimit lowers the limit to limitPages, unless it's already lower.
+func (a *MemoryAccountant) lowerLimit(limitPages uint32) {
+	limit := uint64(limitPages) * wasmPageSize
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.limit == 0 || limit < a.limit {
+		a.limit = limit
+	}
+}
+
+func (a *MemoryAccountant) reserve(size uint64) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.limit != 0 && a.used+size > a.limit {
+		return false
+	}
+	a.used += size
+	return true
+}
+
+func (a *MemoryAccountant) release(size uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.used -= size
+}
+
+// newMemory reserves the initial pages of the memory of a guest, limited to
+// maxPages, zero for no limit. ErrMemoryLimitExceeded or
+// ErrGlobalMemoryLimitExceeded is returned if they don't fit. The memory
+// must be freed if the guest fails to instantiate.
+func (a *MemoryAccountant) newMemory(initialPages, maxPages uint32) (*accountedMemory, error) {
+	if maxPages > 0 && initialPages > maxPages {
+		return nil, fmt.Errorf("wasm: %d initial memory pages over %d: %w", initialPages, maxPages, ErrMemoryLimitExceeded)
+	}
+	size := uint64(initialPages) * wasmPageSize
+	if !a.reserve(size) {
+		return nil, fmt.Errorf("wasm: %d initial memory pages: %w", initialPages, ErrGlobalMemoryLimitExceeded)
+	}
+	return &accountedMemory{accountant: a, accounted: size, limit: uint64(maxPages) * wasmPageSize}, nil
+}
+
+// accountedMemory is the memory of a guest, accounted in its accountant. It
+// is the wazero allocator of the memory, and the memory it allocates.
+type accountedMemory struct {
+	accountant *MemoryAccountant
+	buf        []byte
+
+	// accounted is the size accounted for the memory, reserved before the
+	// memory is allocated. Accesses are serialized by wazero.
+	accounted uint64
+	freed     bool
+
+	// limit is the size the memory of the guest can't grow past, in bytes,
+	// regardless of the accountant. A zero limit means no limit.
+	limit uint64
+
+	// refused is the limit the memory failed to grow past, until the
+	// failure is reported.
+	refused atomic.Pointer[error]
+}
+
+// Allocate implements experimental.MemoryAllocator.
+func (m *accountedMemory) Allocate(cap, _ uint64) experimental.LinearMemory {
+	m.buf = make([]byte, 0, cap)
+	return m
+}
+
+// Reallocate implements experimental.LinearMemory. It returns nil if the
+// memory doesn't fit the limits, which fails memory.grow in the guest.
+//
+// The limit of the guest is enforced here rather than with wazero's
+// RuntimeConfig.WithMemoryLimitPages, which fails memory.grow before the
+// allocator is asked, so the failure couldn't be told from other traps.
+func (m *accountedMemory) Reallocate(size uint64) []byte {
+	if m.limit != 0 && size > m.limit {
+		m.refused.Store(&ErrMemoryLimitExceeded)
+		return nil
+	}
+	if size > m.accounted {
+		if !m.accountant.reserve(size - m.accounted) {
+			m.refused.Store(&ErrGlobalMemoryLimitExceeded)
+			return nil
+		}
+		m.accounted = size
+	}
+	if size <= uint64(cap(m.buf)) {
+		m.buf = m.buf[:size]
+		return m.buf
+	}
+	buf := make([]byte, size)
+	copy(buf, m.buf)
+	m.buf = buf
+	return m.buf
+}
+
+// Free implements experimental.LinearMemory.
+func (m *accountedMemory) Free() {
+	if m.freed {
+		return
+	}
+	m.freed = true
+	m.accountant.release(m.accounted)
+	m.buf = nil
+}
+
+// takeRefused returns the error of the limit the memory failed to grow past
+// since the last call, if any.
+func (m *accountedMemory) takeRefused() error {
+	if m == nil {
+		return nil
+	}
+	if err := m.refused.Swap(nil); err != nil {
+		return *err
+	}
+	return nil
+}
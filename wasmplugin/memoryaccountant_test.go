@@ -0,0 +1,136 @@
+package wasmplugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// growGuest returns a guest of 2 initial memory pages, whose grow function
+// grows its memory by a page and traps if it can't.
+func growGuest() *wasmtest.Module {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces))
+	mod.MemoryPages = 2
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "grow",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(1), wasmtest.MemoryGrow,
+			wasmtest.I32Const(-1), wasmtest.I32Eq,
+			wasmtest.If(), wasmtest.Unreachable, wasmtest.End,
+			wasmtest.I32Const(0),
+		),
+	})
+	return mod
+}
+
+func newAccountedPlugin(t *testing.T, path string, accountant *MemoryAccountant) (*WasmPlugin, error) {
+	t.Helper()
+	cfg := Config{Path: path}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"grow"}, WithMemoryAccountant(accountant))
+	if err == nil {
+		t.Cleanup(func() { plugin.Shutdown(t.Context()) })
+	}
+	return plugin, err
+}
+
+func TestMemoryAccountantInstantiation(t *testing.T) {
+	path := growGuest().Write(t)
+	accountant := NewMemoryAccountant(5)
+
+	first, err := newAccountedPlugin(t, path, accountant)
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	if _, err := newAccountedPlugin(t, path, accountant); err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	if used := accountant.UsedPages(); used != 4 {
+		t.Errorf("expected 4 used pages, got %d", used)
+	}
+
+	// The third guest doesn't fit the remaining page.
+	if _, err := newAccountedPlugin(t, path, accountant); !errors.Is(err, ErrGlobalMemoryLimitExceeded) {
+		t.Fatalf("expected %v, got %v", ErrGlobalMemoryLimitExceeded, err)
+	}
+	if used := accountant.UsedPages(); used != 4 {
+		t.Errorf("expected the refused guest not to be accounted, got %d used pages", used)
+	}
+
+	// Shutting a guest down releases its memory.
+	if err := first.Shutdown(t.Context()); err != nil {
+		t.Fatalf("failed to shutdown plugin: %v", err)
+	}
+	if _, err := newAccountedPlugin(t, path, accountant); err != nil {
+		t.Errorf("expected the guest to fit once another is shut down, got %v", err)
+	}
+}
+
+func TestMemoryAccountantGrowth(t *testing.T) {
+	accountant := NewMemoryAccountant(3)
+	plugin, err := newAccountedPlugin(t, growGuest().Write(t), accountant)
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "grow", &Stack{}); err != nil {
+		t.Fatalf("expected the memory to grow within the limit, got %v", err)
+	}
+	_, err = plugin.ProcessFunctionCall(t.Context(), "grow", &Stack{})
+	if !errors.Is(err, ErrGlobalMemoryLimitExceeded) {
+		t.Errorf("expected %v, got %v", ErrGlobalMemoryLimitExceeded, err)
+	}
+	if used := accountant.UsedPages(); used != 3 {
+		t.Errorf("expected 3 used pages, got %d", used)
+	}
+}
+
+func TestGlobalMemoryLimitPagesConfig(t *testing.T) {
+	accountant := NewMemoryAccountant(0)
+	cfg := Config{Path: growGuest().Write(t), GlobalMemoryLimitPages: 1}
+	cfg.Default()
+
+	_, err := NewWasmPlugin(t.Context(), &cfg, nil, WithMemoryAccountant(accountant))
+	if !errors.Is(err, ErrGlobalMemoryLimitExceeded) {
+		t.Errorf("expected %v, got %v", ErrGlobalMemoryLimitExceeded, err)
+	}
+
+	// A higher limit doesn't raise the limit set by another component.
+	cfg.GlobalMemoryLimitPages = 10
+	if _, err := NewWasmPlugin(t.Context(), &cfg, nil, WithMemoryAccountant(accountant)); !errors.Is(err, ErrGlobalMemoryLimitExceeded) {
+		t.Errorf("expected the lowest limit to apply, got %v", err)
+	}
+}
+
+func TestMaxMemoryPages(t *testing.T) {
+	accountant := NewMemoryAccountant(0)
+	cfg := Config{Path: growGuest().Write(t), MaxMemoryPages: 1}
+	cfg.Default()
+	if _, err := NewWasmPlugin(t.Context(), &cfg, nil, WithMemoryAccountant(accountant)); !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Fatalf("expected %v for initial pages over the limit, got %v", ErrMemoryLimitExceeded, err)
+	}
+
+	cfg.MaxMemoryPages = 3
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"grow"}, WithMemoryAccountant(accountant))
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	t.Cleanup(func() { plugin.Shutdown(t.Context()) })
+
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "grow", &Stack{}); err != nil {
+		t.Fatalf("expected the memory to grow within the limit, got %v", err)
+	}
+	for range 2 {
+		_, err = plugin.ProcessFunctionCall(t.Context(), "grow", &Stack{})
+		var guestErr *GuestError
+		if !errors.As(err, &guestErr) || !errors.Is(err, ErrMemoryLimitExceeded) || errors.Is(err, ErrGlobalMemoryLimitExceeded) {
+			t.Errorf("expected a guest error with %v, got %v", ErrMemoryLimitExceeded, err)
+		}
+	}
+	if used := accountant.UsedPages(); used != 3 {
+		t.Errorf("expected 3 used pages, got %d", used)
+	}
+}
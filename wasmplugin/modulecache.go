@@ -0,0 +1,125 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// CompiledModuleCache shares the compilation of module files across plugins,
+// e.g. the traces, metrics and logs components loading the same file. Each
+// plugin still gets a runtime and a module instance of its own.
+//
+// Entries are keyed by the digest of the module, runtime mode and compiled
+// cache directory, so a modified file is compiled again whatever its
+// modification time. An entry is closed once the last plugin loaded from it
+// is shut down. It is safe for concurrent use.
+type CompiledModuleCache struct {
+	mu      sync.Mutex
+	entries map[moduleCacheKey]*cachedModule
+}
+
+type moduleCacheKey struct {
+	digest   [sha256.Size]byte
+	mode     RuntimeMode
+	cacheDir string
+}
+
+// cachedModule is the compilation cache shared by the runtimes a module is
+// loaded in.
+type cachedModule struct {
+	cache       *CompiledModuleCache
+	key         moduleCacheKey
+	compilation wazero.CompilationCache
+	// refs is the number of plugins loaded from the entry, guarded by
+	// cache.mu.
+	refs int
+}
+
+// NewCompiledModuleCache returns an empty cache.
+func NewCompiledModuleCache() *CompiledModuleCache {
+	return &CompiledModuleCache{entries: make(map[moduleCacheKey]*cachedModule)}
+}
+
+// defaultCompiledModuleCache is the cache used by plugins unless
+// WithCompiledModuleCache is passed.
+var defaultCompiledModuleCache = NewCompiledModuleCache()
+
+// WithCompiledModuleCache sets the cache the module is loaded through. A cache
+// shared by all plugins is used by default.
+func WithCompiledModuleCache(c *CompiledModuleCache) Option {
+	return func(o *options) {
+		o.moduleCache = c
+	}
+}
+
+// load reads the module at path and returns it with the cache entry of its
+// compilation, which must be released once the runtime it's loaded in is
+// closed. The compilation of the module is persisted in cacheDir, unless
+// empty, see Config.CompiledCacheDir.
+func (c *CompiledModuleCache) load(path string, mode RuntimeMode, cacheDir string) ([]byte, *cachedModule, error) {
+	bytes, err := readModule(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := moduleCacheKey{digest: sha256.Sum256(bytes), mode: mode, cacheDir: cacheDir}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m, ok := c.entries[key]; ok {
+		m.refs++
+		return bytes, m, nil
+	}
+
+	compilation := wazero.NewCompilationCache()
+	if cacheDir != "" {
+		// wazero keys the compiled modules of the directory by their hash
+		// and the wazero version, so the directory is shared safely by
+		// modules and upgrades.
+		if compilation, err = wazero.NewCompilationCacheWithDir(cacheDir); err != nil {
+			return nil, nil, fmt.Errorf("wasm: error opening compiled cache dir: %w", err)
+		}
+	}
+	m := &cachedModule{cache: c, key: key, compilation: compilation, refs: 1}
+	c.entries[key] = m
+	return bytes, m, nil
+}
+
+// release drops a reference to the entry, closing it with the last one. The
+// runtime loaded from the entry must be closed first.
+func (m *cachedModule) release(ctx context.Context) error {
+	c := m.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m.refs--; m.refs > 0 {
+		return nil
+	}
+	delete(c.entries, m.key)
+	return m.compilation.Close(ctx)
+}
+
+// readModule reads the module file at path, decompressing it if it is
+// gzipped, e.g. a .wasm.gz file shipped to save space in images. Modules
+// start with the wasm magic number, so they aren't mistaken for gzip
+// streams.
+func readModule(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(b, gzipMagic) {
+		return b, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err == nil {
+		b, err = io.ReadAll(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("wasm: error decompressing module %s: %w: %w", path, ErrInvalidModule, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,218 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+)
+
+func TestCompiledModuleCache(t *testing.T) {
+	path := wasmtest.NewGuest(int32(telemetryTypeTraces)).Write(t)
+	cache := NewCompiledModuleCache()
+	ctx := t.Context()
+
+	_, first, err := cache.load(path, RuntimeModeCompiled, "")
+	if err != nil {
+		t.Fatalf("failed to load module: %v", err)
+	}
+	if _, again, _ := cache.load(path, RuntimeModeCompiled, ""); again != first || first.refs != 2 {
+		t.Error("expected the unchanged module to be cached")
+	}
+	_, interpreted, _ := cache.load(path, RuntimeModeInterpreter, "")
+	if interpreted == first {
+		t.Error("expected runtime modes to be cached apart")
+	}
+	interpreted.release(ctx)
+
+	// The module is modified within the resolution of its modification
+	// time.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat module: %v", err)
+	}
+	changed := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("getCapabilities", 0)).Bytes()
+	if err := os.WriteFile(path, changed, 0o600); err != nil {
+		t.Fatalf("failed to rewrite module: %v", err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("failed to reset modification time: %v", err)
+	}
+	bytes, second, err := cache.load(path, RuntimeModeCompiled, "")
+	if err != nil {
+		t.Fatalf("failed to load module: %v", err)
+	}
+	if second == first || !slices.Equal(bytes, changed) {
+		t.Error("expected the modified module to be loaded again")
+	}
+	second.release(ctx)
+
+	for range 2 {
+		if err := first.release(ctx); err != nil {
+			t.Fatalf("failed to release module: %v", err)
+		}
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("expected the released entries to be dropped, got %d entries", len(cache.entries))
+	}
+
+	// Plugins sharing the cache get instances of their own, and the entry
+	// is closed with the last of them.
+	cfg := Config{Path: path}
+	cfg.Default()
+	var plugins []*WasmPlugin
+	for range 2 {
+		plugin, err := NewWasmPlugin(ctx, &cfg, nil, WithCompiledModuleCache(cache))
+		if err != nil {
+			t.Fatalf("failed to create plugin: %v", err)
+		}
+		plugins = append(plugins, plugin)
+	}
+	if plugins[0].Capabilities() != 0 || plugins[0].Module == plugins[1].Module {
+		t.Error("expected plugins of the modified module with distinct instances")
+	}
+	for i, plugin := range plugins {
+		if len(cache.entries) != 1 {
+			t.Errorf("expected the entry to be kept while plugins use it, got %d entries", len(cache.entries))
+		}
+		if err := plugin.Shutdown(ctx); err != nil {
+			t.Fatalf("failed to shutdown plugin %d: %v", i, err)
+		}
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("expected the entry to be closed with the last plugin, got %d entries", len(cache.entries))
+	}
+}
+
+func TestCompiledModuleCacheFailedPlugin(t *testing.T) {
+	cache := NewCompiledModuleCache()
+	cfg := Config{Path: wasmtest.NewGuest(int32(telemetryTypeTraces)).Write(t)}
+	cfg.Default()
+
+	if _, err := NewWasmPlugin(t.Context(), &cfg, []string{"missing"}, WithCompiledModuleCache(cache)); err == nil {
+		t.Fatal("expected an error for a missing required function")
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("expected the entry of the failed plugin to be released, got %d entries", len(cache.entries))
+	}
+}
+
+// cacheDirFiles returns the modification times of the files under dir, by
+// path.
+func cacheDirFiles(t *testing.T, dir string) map[string]time.Time {
+	t.Helper()
+	files := make(map[string]time.Time)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[path] = info.ModTime()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to list cache dir: %v", err)
+	}
+	return files
+}
+
+func TestCompiledCacheDir(t *testing.T) {
+	cfg := Config{
+		Path:             wasmtest.NewGuest(int32(telemetryTypeTraces)).Write(t),
+		RuntimeConfig:    RuntimeConfig{Mode: RuntimeModeCompiled},
+		CompiledCacheDir: filepath.Join(t.TempDir(), "compiled"),
+	}
+	cfg.Default()
+
+	// Each start gets a fresh module cache, like a restarted collector.
+	start := func() {
+		t.Helper()
+		plugin, err := NewWasmPlugin(t.Context(), &cfg, nil, WithCompiledModuleCache(NewCompiledModuleCache()))
+		if err != nil {
+			t.Fatalf("failed to create plugin: %v", err)
+		}
+		if err := plugin.Shutdown(t.Context()); err != nil {
+			t.Fatalf("failed to shutdown plugin: %v", err)
+		}
+	}
+
+	start()
+	populated := cacheDirFiles(t, cfg.CompiledCacheDir)
+	if len(populated) == 0 {
+		t.Fatal("expected the compiled module to be written to the cache dir")
+	}
+
+	start()
+	reused := cacheDirFiles(t, cfg.CompiledCacheDir)
+	if len(reused) != len(populated) {
+		t.Fatalf("expected the compiled module to be reused, got %d files instead of %d", len(reused), len(populated))
+	}
+	for path, modTime := range populated {
+		if !reused[path].Equal(modTime) {
+			t.Errorf("expected %s to be reused, not written again", path)
+		}
+	}
+}
+
+func BenchmarkNewWasmPlugin(b *testing.B) {
+	cfg := Config{Path: wasmtest.NewGuest(int32(telemetryTypeTraces)).Write(b)}
+	cfg.Default()
+
+	for name, cache := range map[string]func() *CompiledModuleCache{
+		"shared": func() *CompiledModuleCache { return defaultCompiledModuleCache },
+		"fresh":  NewCompiledModuleCache,
+	} {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				plugin, err := NewWasmPlugin(b.Context(), &cfg, nil, WithCompiledModuleCache(cache()))
+				if err != nil {
+					b.Fatalf("failed to create plugin: %v", err)
+				}
+				plugin.Shutdown(b.Context())
+			}
+		})
+	}
+}
+
+func TestGzippedModule(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("processTraces", 0))
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(mod.Bytes())
+	w.Close()
+	path := filepath.Join(t.TempDir(), "nop.wasm.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+
+	// The digest is the one of the decompressed module.
+	cfg := Config{Path: path, ExpectedDigest: moduleDigest(mod.Bytes())}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"})
+	if err != nil {
+		t.Fatalf("failed to load gzipped module: %v", err)
+	}
+	t.Cleanup(func() { plugin.Shutdown(context.Background()) })
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{}); err != nil {
+		t.Errorf("failed to call gzipped module: %v", err)
+	}
+
+	// A truncated gzip stream isn't a module.
+	if err := os.WriteFile(path, buf.Bytes()[:buf.Len()/2], 0o600); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+	if _, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"}); !errors.Is(err, ErrInvalidModule) {
+		t.Errorf("expected ErrInvalidModule, got %v", err)
+	}
+}
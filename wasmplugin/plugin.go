@@ -4,10 +4,16 @@ package wasmplugin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"maps"
+	"math"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/stealthrocket/wasi-go"
 	wasigo "github.com/stealthrocket/wasi-go/imports"
@@ -15,66 +21,188 @@ import (
 	"github.com/stealthrocket/wazergo"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/sys"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 const (
 	// guestExportMemory is the name of the memory export in the guest module
 	guestExportMemory = "memory"
 
+	// wasmPageSize is the size of a WebAssembly memory page
+	wasmPageSize = 65536
+
 	// otelWasm is the name of the host module
 	otelWasm = "opentelemetry.io/wasm"
 
 	// Host function exports
-	currentTraces         = "currentTraces"
-	currentMetrics        = "currentMetrics"
-	currentLogs           = "currentLogs"
-	setResultTraces       = "setResultTraces"
-	setResultMetrics      = "setResultMetrics"
-	setResultLogs         = "setResultLogs"
-	getPluginConfig       = "getPluginConfig"
-	setResultStatusReason = "setResultStatusReason"
-	getShutdownRequested  = "getShutdownRequested"
+	currentTraces          = "currentTraces"
+	currentMetrics         = "currentMetrics"
+	currentLogs            = "currentLogs"
+	setResultTraces        = "setResultTraces"
+	setResultMetrics       = "setResultMetrics"
+	setResultLogs          = "setResultLogs"
+	currentProfiles        = "currentProfiles"
+	setResultProfiles      = "setResultProfiles"
+	setResultUnchanged     = "setResultUnchanged"
+	getPluginConfig        = "getPluginConfig"
+	getPluginConfigVersion = "getPluginConfigVersion"
+	getPluginConfigStatus  = "getPluginConfigStatus"
+	setResultStatusReason  = "setResultStatusReason"
+	getShutdownRequested   = "getShutdownRequested"
+	getDeadlineUnixNano    = "getDeadlineUnixNano"
+	getBagValue            = "getBagValue"
+	setBagValue            = "setBagValue"
+	getEnv                 = "getEnv"
+	hostNow                = "hostNow"
+	currentTracesChunk     = "currentTracesChunk"
+	getExtensions          = "getExtensions"
+	authenticate           = "authenticate"
+	logMessage             = "logMessage"
+	getLogLevel            = "getLogLevel"
+	recordMetric           = "recordMetric"
+	getTraceParent         = "getTraceParent"
+	getConfigFile          = "getConfigFile"
+	getComponentInfo       = "getComponentInfo"
+	appendResultTraces     = "appendResultTraces"
+	emitTraces             = "emitTraces"
+	kvGet                  = "kvGet"
+	kvSet                  = "kvSet"
+	randUint64             = "randUint64"
+	hashSHA256             = "hashSHA256"
+	hashFNV64              = "hashFNV64"
+	httpDo                 = "httpDo"
+	httpResponseChunk      = "httpResponseChunk"
+	hostReadFile           = "hostReadFile"
+	hostStatFile           = "hostStatFile"
+
+	// envNotFound is returned by getEnv if the variable isn't exposed
+	envNotFound = math.MaxUint32
 
 	// Guest function
 	getSupportedTelemetry = "getSupportedTelemetry"
 
+	// Optional guest function returning non-zero if the guest is safe for
+	// concurrent calls
+	concurrentSafe = "otelwasm_concurrent_safe"
+
+	// Optional guest function returning the capabilities of the guest
+	getCapabilities = "getCapabilities"
+
+	// Optional guest function setting the guest up once its component
+	// starts
+	guestStart = "start"
+
+	// Optional guest function flushing the data buffered by the guest
+	guestShutdown = "shutdown"
+
+	// Optional guest function reading the plugin config again once it's
+	// updated
+	guestReconfigure = "reconfigure"
+
+	// Optional guest function returning the version of the ABI the guest was
+	// built against, see ABIVersion
+	abiVersion = "otelwasm_abi_version"
+
 	// WASI extension name
 	wasmEdgeV2Extension = "wasmedgev2"
+
+	// Slow guest call span event
+	slowCallEvent              = "wasm.slow_call"
+	slowCallFunctionAttribute  = "wasm.function"
+	slowCallDurationAttribute  = "wasm.call.duration"
+	slowCallThresholdAttribute = "wasm.call.threshold"
 )
 
 var builtInGuestFunctions = []string{
 	getSupportedTelemetry,
 }
 
+// optionalGuestFunctions are built-in guest functions the guest may not
+// export.
+var optionalGuestFunctions = []string{
+	concurrentSafe,
+	getCapabilities,
+	guestStart,
+	guestShutdown,
+	guestReconfigure,
+}
+
+// isBuiltInGuestFunction reports whether functionName is a built-in guest
+// function, called by the host itself rather than to process telemetry.
+func isBuiltInGuestFunction(functionName string) bool {
+	return slices.Contains(builtInGuestFunctions, functionName) || slices.Contains(optionalGuestFunctions, functionName)
+}
+
 type telemetryType uint32
 
 const (
 	telemetryTypeMetrics telemetryType = 1 << iota
 	telemetryTypeLogs
 	telemetryTypeTraces
+	telemetryTypeProfiles
 )
 
 // StatusCode represents the result status code from WASM function calls
 type StatusCode uint32
 
+// The status codes returned by the guest functions, mirrored by the
+// api.StatusCode of the guest SDK. Hosts predating a code handle it as
+// StatusCodeError.
+const (
+	StatusCodeOK StatusCode = iota
+	StatusCodeError
+	// StatusCodeInvalidArgument means the guest rejected its input.
+	StatusCodeInvalidArgument
+	// StatusCodePermanent means the call failed and retrying it with the
+	// same input would fail again, e.g. the destination of an exporter
+	// refused the data.
+	StatusCodePermanent
+	// StatusCodeRetryable means the call failed but may succeed if retried,
+	// e.g. the destination of an exporter was unreachable.
+	StatusCodeRetryable
+	// StatusCodeThrottled means the call was refused to slow the caller
+	// down, e.g. the destination of an exporter is rate limited, and may
+	// succeed if retried later.
+	StatusCodeThrottled
+)
+
 // String returns the string representation of the status code
 func (s StatusCode) String() string {
 	switch s {
-	case 0:
+	case StatusCodeOK:
 		return "OK"
-	case 1:
+	case StatusCodeError:
 		return "ERROR"
-	case 2:
+	case StatusCodeInvalidArgument:
 		return "INVALID_ARGUMENT"
+	case StatusCodePermanent:
+		return "PERMANENT"
+	case StatusCodeRetryable:
+		return "RETRYABLE"
+	case StatusCodeThrottled:
+		return "THROTTLED"
 	default:
 		return fmt.Sprintf("UNKNOWN(%d)", s)
 	}
 }
 
 // WasmPlugin represents a WebAssembly plugin for OpenTelemetry components
+//
+// A plugin runs a single module instance, shared by all the goroutines
+// calling ProcessFunctionCall, e.g. the consumers of a pipeline. The guest
+// memory isn't safe for concurrent calls, so they are serialized, unless the
+// guest declares it is safe for them through otelwasm_concurrent_safe, see
+// ConcurrentSafe. Each call passes its own Stack, so the calls don't share
+// telemetry. Pipelines needing parallel guest calls run several components
+// instead, each with a plugin of its own.
 type WasmPlugin struct {
 	// Runtime is the WebAssembly runtime
 	Runtime wazero.Runtime
@@ -85,9 +213,14 @@ type WasmPlugin struct {
 	// Module is the instantiated WASM module
 	Module api.Module
 
-	// PluginConfigJSON is the JSON representation of the plugin config
+	// PluginConfigJSON is the JSON representation of the plugin config.
+	// Use CurrentPluginConfigJSON to read it if the config may be updated
+	// concurrently by UpdatePluginConfig.
 	PluginConfigJSON []byte
 
+	// pluginConfigVersion is incremented each time the config is updated.
+	pluginConfigVersion uint32
+
 	// Exported functions from the WASM module
 	ExportedFunctions map[string]api.Function
 
@@ -96,6 +229,84 @@ type WasmPlugin struct {
 	// This is a workaround to avoid panic when calling wasi functions with different context than the one used to instantiate the host module.
 	// TODO: Remove this if possible after replacing WASI implementation with our own.
 	wasiP1HostModule *wasi_snapshot_preview1.Module
+
+	// configMu guards PluginConfigJSON and pluginConfigVersion against
+	// concurrent updates.
+	configMu sync.RWMutex
+
+	// runtimeMode is the mode of the runtime the guest was instantiated in.
+	runtimeMode RuntimeMode
+
+	// module is the cache entry of the compiled guest, released once by
+	// Shutdown.
+	module        *cachedModule
+	releaseModule sync.Once
+
+	// memory is the memory of the guest, accounted in the global memory
+	// limit.
+	memory *accountedMemory
+
+	// slowCallThreshold is the duration past which guest calls are reported
+	// as slow. Zero disables the report.
+	slowCallThreshold time.Duration
+
+	// executionTimeout is the maximum duration of a guest call. Zero disables
+	// the timeout.
+	executionTimeout time.Duration
+
+	// quota enforces the resource quota of the guest. Nil if disabled.
+	quota *quota
+
+	// faults fails guest calls for testing. Nil if disabled.
+	faults *faultInjector
+
+	// guestPanic records the panics the guest writes to its output. Nil if
+	// the output isn't logged.
+	guestPanic *guestPanic
+
+	// telemetry records the plugin metrics.
+	telemetry *telemetry
+
+	// errorLog logs the guest failures. Nil if the plugin has no logger.
+	errorLog *errorLogger
+
+	// tracer starts a span around each guest call. Nil if disabled.
+	tracer trace.Tracer
+
+	// callLog records each guest call to a file. Nil if disabled.
+	callLog        *callLog
+	releaseCallLog sync.Once
+
+	// guestMetrics forwards the metrics recorded by the guest. Nil if
+	// disabled.
+	guestMetrics *guestMetricsForwarder
+
+	// concurrentSafe is set if the guest declared it is safe for concurrent
+	// calls. Calls are serialized by callMu otherwise.
+	concurrentSafe bool
+	callMu         sync.Mutex
+
+	// capabilities are the capabilities declared by the guest.
+	capabilities Capabilities
+
+	// compressLogs is set if the logs are passed compressed to the guest.
+	compressLogs bool
+
+	// tracesJSON is set if the traces are passed in JSON to the guest.
+	tracesJSON bool
+
+	// ready is set once the guest is instantiated and its declarations are
+	// read, and cleared once the guest is closed.
+	ready atomic.Bool
+
+	// guestStarted and guestShutDown are set once the guest is started and
+	// shut down, so each hook is called at most once, and only guests
+	// started are shut down.
+	guestStarted  atomic.Bool
+	guestShutDown atomic.Bool
+
+	// info describes the guest to the introspection of the loaded plugins.
+	info PluginInfo
 }
 
 // stackKey is the key used to store the stack in the context
@@ -103,12 +314,18 @@ type stackKey struct{}
 
 // Stack holds the data being passed between the host and the guest
 type Stack struct {
-	CurrentTraces     ptrace.Traces
-	CurrentMetrics    pmetric.Metrics
-	CurrentLogs       plog.Logs
+	CurrentTraces  ptrace.Traces
+	CurrentMetrics pmetric.Metrics
+	CurrentLogs    plog.Logs
+	// CurrentProfiles are the profiles passed to the guest with
+	// currentProfiles.
+	CurrentProfiles pprofile.Profiles
+	// ResultTraces, ResultMetrics, ResultLogs and ResultProfiles are the
+	// results set by the guest, the zero values if it set none.
 	ResultTraces      ptrace.Traces
 	ResultMetrics     pmetric.Metrics
 	ResultLogs        plog.Logs
+	ResultProfiles    pprofile.Profiles
 	StatusReason      string
 	RequestedShutdown atomic.Bool
 
@@ -116,8 +333,128 @@ type Stack struct {
 	OnResultLogsChange    func(plog.Logs)
 	OnResultTracesChange  func(ptrace.Traces)
 
-	// PluginConfigJSON is the plugin config in JSON representation passed to the guest
+	// ResultUnchanged is set if the guest reported with setResultUnchanged
+	// that it left the telemetry of the call as is, so the processor passes
+	// its own copy on rather than a result the guest serialized. The result
+	// set by the guest, if any, is ignored.
+	ResultUnchanged bool
+
+	// ResultTracesBatches are the batches the guest appended with
+	// appendResultTraces, each passed downstream on its own. ResultTraces
+	// is ignored if any.
+	ResultTracesBatches []ptrace.Traces
+
+	// EmitTraces passes the traces the guest emitted with emitTraces to the
+	// next consumer immediately, so a long-running receiver can produce any
+	// number of batches, unlike the single ResultTraces slot. The guest is
+	// told if it fails. Emitting traces fails the call if nil.
+	EmitTraces func(ptrace.Traces) error
+
+	// PluginConfigJSON is the plugin config in JSON representation passed to
+	// the guest. It is set to the current config of the plugin when the call
+	// starts if nil.
 	PluginConfigJSON []byte
+
+	// PluginConfigVersion is the version of PluginConfigJSON the guest reads
+	// with getPluginConfigVersion, so it knows when to decode the config
+	// again.
+	PluginConfigVersion uint32
+
+	// Bag is the bag shared with the other guests handling the same batch.
+	// Bag host functions are no-ops if nil.
+	Bag *Bag
+
+	// guestMetrics are the metrics recorded by the guest during the call,
+	// forwarded once the call returns.
+	guestMetrics pmetric.Metrics
+
+	// currentTracesProto caches currentTracesProtoOf serialized, so the
+	// traces are marshaled once however many times the guest reads them,
+	// e.g. to retry with a buffer large enough. The cache is dropped once
+	// CurrentTraces is replaced, and at the start of every call.
+	currentTracesProto   []byte
+	currentTracesProtoOf ptrace.Traces
+
+	// currentLogsProto caches currentLogsProtoOf serialized, and compressed
+	// if compressLogs is set, like currentTracesProto.
+	currentLogsProto   []byte
+	currentLogsProtoOf plog.Logs
+
+	// tracesJSON is set if the traces are passed in JSON to the guest, see
+	// Config.TracesEncoding, which writes its traces in JSON too.
+	// currentTracesProto holds the JSON then.
+	tracesJSON bool
+
+	// compressLogs is set if the logs are passed compressed between the host
+	// and the guest, see Config.LogsCompression.
+	compressLogs bool
+
+	// inputSize and outputSize are the sizes of the serialized telemetry
+	// read and written by the guest, in bytes.
+	inputSize  uint32
+	outputSize uint32
+
+	// deadline is the deadline of the call, the earliest of the deadline of
+	// its context and the execution timeout, zero if none. The guest reads
+	// it with getDeadlineUnixNano.
+	deadline time.Time
+
+	// httpResponse is the response of the last httpDo of the call, in
+	// JSON, read by the guest with httpResponseChunk.
+	httpResponse []byte
+
+	// Extensions are the collector extensions available to the guest. The
+	// guest sees no extension if nil.
+	Extensions Extensions
+
+	// Clock is the clock read by the guest through hostNow. SystemClock is
+	// used if nil.
+	Clock Clock
+
+	// HostError is the first error raised by a host function called by the
+	// guest, e.g. because the guest passed a buffer outside its memory. Host
+	// functions can't return errors to the guest, so the error is recorded
+	// here and returned by ProcessFunctionCall once the guest returns.
+	HostError error
+}
+
+// recordHostError records the error raised by the given host function,
+// unless an error was already recorded.
+func (s *Stack) recordHostError(function string, err error) {
+	if s.HostError == nil {
+		s.HostError = fmt.Errorf("%s: %w", function, err)
+	}
+}
+
+// marshalTraces serializes the traces read by the guest. It is a variable so
+// tests can count the marshals.
+var marshalTraces = (&ptrace.ProtoMarshaler{}).MarshalTraces
+
+// marshalProfiles serializes the profiles read by the guest. It is a variable
+// so tests can fail the marshal.
+var marshalProfiles = (&pprofile.ProtoMarshaler{}).MarshalProfiles
+
+// currentTracesBytes returns CurrentTraces serialized.
+func (s *Stack) currentTracesBytes() ([]byte, error) {
+	if s.currentTracesProto == nil || s.currentTracesProtoOf != s.CurrentTraces {
+		marshal := marshalTraces
+		if s.tracesJSON {
+			marshal = marshalTracesJSON
+		}
+		b, err := marshal(s.CurrentTraces)
+		if err != nil {
+			return nil, err
+		}
+		s.currentTracesProto, s.currentTracesProtoOf = b, s.CurrentTraces
+	}
+	return s.currentTracesProto, nil
+}
+
+// resetCaches drops the values cached by a previous call.
+func (s *Stack) resetCaches() {
+	s.currentTracesProto, s.currentTracesProtoOf = nil, ptrace.Traces{}
+	s.currentLogsProto, s.currentLogsProtoOf = nil, plog.Logs{}
+	s.httpResponse = nil
 }
 
 // paramsFromContext retrieves the Stack from the context
@@ -126,32 +463,216 @@ func paramsFromContext(ctx context.Context) *Stack {
 }
 
 // NewWasmPlugin creates a new WasmPlugin instance
-func NewWasmPlugin(ctx context.Context, cfg *Config, requiredFunctions []string) (*WasmPlugin, error) {
+func NewWasmPlugin(ctx context.Context, cfg *Config, requiredFunctions []string, opts ...Option) (_ *WasmPlugin, err error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 
-	f, err := os.Open(cfg.Path)
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	telemetry, err := newTelemetry(o.meterProvider)
+	if err != nil {
+		return nil, fmt.Errorf("wasm: error creating telemetry: %w", err)
+	}
+
+	moduleCache := o.moduleCache
+	if moduleCache == nil {
+		moduleCache = defaultCompiledModuleCache
+	}
+
+	if o.memoryAccountant == nil {
+		o.memoryAccountant = defaultMemoryAccountant
+	}
+	if cfg.GlobalMemoryLimitPages > 0 {
+		o.memoryAccountant.lowerLimit(cfg.GlobalMemoryLimitPages)
+	}
+
+	state, err := newKVStore(cfg.State)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
 
-	bytes, err := io.ReadAll(f)
+	// The runtime modes are tried in order until one instantiates the guest.
+	modes := cfg.RuntimeConfig.modes()
+	var inst *instance
+	var errs []error
+	for _, mode := range modes {
+		if inst, err = instantiate(ctx, cfg, mode, moduleCache, state, &o); err == nil {
+			break
+		}
+		if len(modes) == 1 {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s runtime: %w", mode, err))
+	}
+	if inst == nil {
+		return nil, fmt.Errorf("wasm: no runtime instantiated the guest: %w", errors.Join(errs...))
+	}
+	defer func() {
+		if err != nil {
+			inst.runtime.Close(ctx)
+			inst.module.release(ctx)
+		}
+	}()
+	if len(errs) > 0 && o.logger != nil {
+		o.logger.Warn("Guest instantiated by a fallback runtime",
+			zap.String("mode", string(inst.mode)), zap.Error(errors.Join(errs...)))
+	}
+	mod := inst.mod
+
+	// Check if all required functions are exported
+	exportedFunctions := make(map[string]api.Function)
+	for _, funcName := range requiredFunctions {
+		fn := exportedFunction(mod, funcName, cfg.ABI.FunctionPrefixes)
+		if fn == nil {
+			return nil, notExportedError(mod, funcName)
+		}
+		exportedFunctions[funcName] = fn
+	}
+
+	// Check if all built-in guest functions are exported
+	for _, funcName := range builtInGuestFunctions {
+		fn := exportedFunction(mod, funcName, cfg.ABI.FunctionPrefixes)
+		if fn == nil {
+			return nil, notExportedError(mod, funcName)
+		}
+		exportedFunctions[funcName] = fn
+	}
+
+	for _, funcName := range optionalGuestFunctions {
+		if fn := exportedFunction(mod, funcName, cfg.ABI.FunctionPrefixes); fn != nil {
+			exportedFunctions[funcName] = fn
+		}
+	}
+
+	// Convert the plugin config to JSON representation
+	pluginConfigJSON, err := json.Marshal(cfg.PluginConfig)
 	if err != nil {
+		return nil, fmt.Errorf("wasm: error marshalling plugin config: %w", err)
+	}
+
+	plugin := &WasmPlugin{
+		Runtime:           inst.runtime,
+		Sys:               inst.sys,
+		Module:            mod,
+		PluginConfigJSON:  pluginConfigJSON,
+		ExportedFunctions: exportedFunctions,
+		wasiP1HostModule:  inst.wasiP1HostModule,
+		guestPanic:        inst.lastPanic,
+		runtimeMode:       inst.mode,
+		module:            inst.module,
+		memory:            inst.memory,
+		slowCallThreshold: cfg.SlowCallThreshold,
+		executionTimeout:  cfg.ExecutionTimeout,
+		quota:             newQuota(cfg.Quota),
+		faults:            newFaultInjector(cfg.TestingFaultInjection),
+		telemetry:         telemetry,
+		errorLog:          newErrorLogger(cfg.ErrorLog, o.logger),
+		guestMetrics:      newGuestMetricsForwarder(&o),
+	}
+	if cfg.TraceGuestCalls {
+		plugin.tracer = newTracer(o.tracerProvider)
+	}
+	if plugin.callLog, err = openCallLog(cfg.CallLog, o.logger); err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			plugin.callLog.release()
+		}
+	}()
+	if cfg.EnvPassthrough && o.logger != nil {
+		o.logger.Warn("env_passthrough is deprecated and exposes the whole host environment to the guest; list the variables the guest reads in env_allowlist instead")
+	}
+	if plugin.faults != nil && o.logger != nil {
+		o.logger.Warn("Fault injection is enabled, guest calls will fail on purpose; never enable it in production")
+	}
 
-	runtime, guest, err := prepareRuntime(ctx, bytes, cfg.RuntimeConfig)
+	if plugin.concurrentSafe, err = plugin.isConcurrentSafe(ctx); err != nil {
+		return nil, err
+	}
+	if plugin.capabilities, err = plugin.readCapabilities(ctx); err != nil {
+		return nil, err
+	}
+	plugin.compressLogs = cfg.LogsCompression == LogsCompressionGzip && plugin.capabilities&CapabilityCompressedLogs != 0
+	if plugin.tracesJSON, err = cfg.TracesEncoding.tracesJSON(plugin.capabilities); err != nil {
+		return nil, err
+	}
+	telemetryTypes, err := plugin.supportedTelemetryTypes(ctx)
 	if err != nil {
 		return nil, err
 	}
+	plugin.info = PluginInfo{
+		Path:        cfg.Path,
+		Digest:      inst.digest,
+		Signals:     telemetryTypes.signals(),
+		ABIVersion:  inst.abiVersion,
+		RuntimeMode: inst.mode,
+	}
+	plugin.ready.Store(true)
+	loadedPlugins.add(plugin)
+
+	return plugin, nil
+}
+
+// instance is a guest instantiated in a runtime of its own.
+type instance struct {
+	mode             RuntimeMode
+	digest           string
+	abiVersion       uint32
+	module           *cachedModule
+	memory           *accountedMemory
+	runtime          wazero.Runtime
+	sys              wasi.System
+	wasiP1HostModule *wasi_snapshot_preview1.Module
+	mod              api.Module
+	lastPanic        *guestPanic
+}
+
+// instantiate instantiates the guest of cfg in a runtime of the given mode.
+// The runtime is closed if the guest fails to instantiate.
+func instantiate(ctx context.Context, cfg *Config, mode RuntimeMode, moduleCache *CompiledModuleCache, state *kvStore, o *options) (_ *instance, err error) {
+	bytes, module, err := moduleCache.load(cfg.Path, mode, cfg.CompiledCacheDir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			module.release(ctx)
+		}
+	}()
+
+	if cfg.ExpectedDigest != "" {
+		if err := verifyDigest(bytes, cfg.ExpectedDigest); err != nil {
+			return nil, fmt.Errorf("wasm: error verifying module %s: %w", cfg.Path, err)
+		}
+	}
+
+	runtime, guest, err := prepareRuntime(ctx, bytes, mode, module.compilation, cfg.ExecutionTimeout > 0 || o.interruptibleCalls)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			runtime.Close(ctx)
+		}
+	}()
+
+	env, environ := guestEnv(cfg)
 
 	// Instantiate WASI module (wasi_snapshot_preview1 and wasmedge socket extension)
-	var sys wasi.System
-	ctx, sys, err = wasigo.NewBuilder().
+	var wasiSys wasi.System
+	wasiBuilder := wasigo.NewBuilder().
 		WithSocketsExtension(wasmEdgeV2Extension, guest).
-		WithEnv(os.Environ()...).Instantiate(ctx, runtime)
+		WithEnv(environ...)
+	var lastPanic *guestPanic
+	if !cfg.StdioPassthrough && o.logger != nil {
+		lastPanic = &guestPanic{}
+		wasiBuilder = wasiBuilder.WithWrappers(logGuestOutput(o.logger, lastPanic))
+	}
+	ctx, wasiSys, err = wasiBuilder.Instantiate(ctx, runtime)
 	if err != nil {
 		return nil, fmt.Errorf("wasm: error instantiating wasi module: %w", err)
 	}
@@ -163,70 +684,135 @@ func NewWasmPlugin(ctx context.Context, cfg *Config, requiredFunctions []string)
 		return nil, fmt.Errorf("wasm: error retrieving wasi host module instance")
 	}
 
-	if _, err := instantiateHostModule(ctx, runtime); err != nil {
+	configFiles, err := readConfigFiles(cfg.PluginConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := instantiateHostModule(ctx, runtime, env, configFiles, state, cfg, o, newHostCallTracer(cfg.TraceHostCalls, o.logger))
+	if err != nil {
 		return nil, fmt.Errorf("wasm: error instantiating host module: %w", err)
 	}
+	if err := checkHostImports(guest, host); err != nil {
+		return nil, err
+	}
 
 	config := wazero.NewModuleConfig().
 		WithStartFunctions("_initialize"). // reactor module
 		WithStdout(os.Stdout).
 		WithStderr(os.Stderr)
 
-	mod, err := runtime.InstantiateModule(ctx, guest, config)
+	memory, err := o.memoryAccountant.newMemory(guest.ExportedMemories()[guestExportMemory].Min(), cfg.MaxMemoryPages)
 	if err != nil {
-		return nil, fmt.Errorf("wasm: error instantiating guest: %w", err)
+		return nil, err
 	}
-
-	// Check if all required functions are exported
-	exportedFunctions := make(map[string]api.Function)
-	for _, funcName := range requiredFunctions {
-		fn := mod.ExportedFunction(funcName)
-		if fn == nil {
-			return nil, fmt.Errorf("wasm: %s is not exported: %w", funcName, ErrRequiredFunctionNotExported)
+	defer func() {
+		if err != nil {
+			memory.Free()
 		}
-		exportedFunctions[funcName] = fn
-	}
+	}()
 
-	// Check if all built-in guest functions are exported
-	for _, funcName := range builtInGuestFunctions {
-		fn := mod.ExportedFunction(funcName)
-		if fn == nil {
-			return nil, fmt.Errorf("wasm: %s is not exported: %w", funcName, ErrRequiredFunctionNotExported)
-		}
-		exportedFunctions[funcName] = fn
+	mod, err := runtime.InstantiateModule(experimental.WithMemoryAllocator(ctx, memory), guest, config)
+	if err != nil {
+		return nil, fmt.Errorf("wasm: error instantiating guest: %w", err)
+	}
+	version, err := checkABIVersion(ctx, mod)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert the plugin config to JSON representation
-	pluginConfigJSON, err := json.Marshal(cfg.PluginConfig)
+	return &instance{
+		mode:             mode,
+		digest:           moduleDigest(bytes),
+		abiVersion:       version,
+		module:           module,
+		memory:           memory,
+		runtime:          runtime,
+		sys:              wasiSys,
+		wasiP1HostModule: wasiP1HostModule,
+		mod:              mod,
+		lastPanic:        lastPanic,
+	}, nil
+}
+
+// isConcurrentSafe reports whether the guest declared it is safe for
+// concurrent calls.
+func (p *WasmPlugin) isConcurrentSafe(ctx context.Context) (bool, error) {
+	if _, ok := p.ExportedFunctions[concurrentSafe]; !ok {
+		return false, nil
+	}
+	res, err := p.ProcessFunctionCall(ctx, concurrentSafe, &Stack{})
 	if err != nil {
-		return nil, fmt.Errorf("wasm: error marshalling plugin config: %w", err)
+		return false, fmt.Errorf("wasm: failed to get concurrency safety: %w", err)
+	}
+	if len(res) != 1 {
+		return false, fmt.Errorf("wasm: %s must return whether the guest is concurrent safe: %w", concurrentSafe, ErrInvalidModule)
 	}
+	return res[0] != 0, nil
+}
 
-	plugin := &WasmPlugin{
-		Runtime:           runtime,
-		Sys:               sys,
-		Module:            mod,
-		PluginConfigJSON:  pluginConfigJSON,
-		ExportedFunctions: exportedFunctions,
-		wasiP1HostModule:  wasiP1HostModule,
+// RuntimeMode returns the mode of the runtime the guest was instantiated in,
+// which is a fallback mode if the configured one failed.
+func (p *WasmPlugin) RuntimeMode() RuntimeMode {
+	return p.runtimeMode
+}
+
+// ConcurrentSafe reports whether the guest is called concurrently. Calls to
+// guests that don't declare they are safe for it are serialized.
+func (p *WasmPlugin) ConcurrentSafe() bool {
+	return p.concurrentSafe
+}
+
+// exportedFunction resolves the guest export of the given ABI function name.
+// Each prefix is probed in order and the first match wins. Functions are
+// stored under their unprefixed name so callers don't need to know which ABI
+// generation the guest was built against.
+func exportedFunction(mod api.Module, name string, prefixes []string) api.Function {
+	if len(prefixes) == 0 {
+		return mod.ExportedFunction(name)
 	}
+	for _, prefix := range prefixes {
+		if fn := mod.ExportedFunction(prefix + name); fn != nil {
+			return fn
+		}
+	}
+	return nil
+}
 
-	return plugin, nil
+// ExportedFunctionNames returns the names of all the functions exported by
+// the guest, sorted, e.g. for tooling verifying guests without knowing the
+// names in advance. The names are as exported, with their ABI prefix if any.
+func (p *WasmPlugin) ExportedFunctionNames() []string {
+	return exportedFunctionNames(p.Module)
 }
 
-// prepareRuntime initializes a new WebAssembly runtime
-func prepareRuntime(ctx context.Context, guestBin []byte, rc RuntimeConfig) (runtime wazero.Runtime, guest wazero.CompiledModule, err error) {
+func exportedFunctionNames(mod api.Module) []string {
+	return slices.Sorted(maps.Keys(mod.ExportedFunctionDefinitions()))
+}
+
+// notExportedError returns the error of the function name missing from the
+// exports of mod, listing the functions it exports instead.
+func notExportedError(mod api.Module, name string) error {
+	return fmt.Errorf("wasm: %s is not exported, the guest exports %s: %w",
+		name, strings.Join(exportedFunctionNames(mod), ", "), ErrRequiredFunctionNotExported)
+}
+
+// runtimeConfigs are the constructors of the wazero configuration of each
+// runtime mode.
+var runtimeConfigs = map[RuntimeMode]func() wazero.RuntimeConfig{
 	// TODO: Switch to compiler backend after fixing the memory allocator issue in wazero
-	var wrc wazero.RuntimeConfig
-	switch rc.Mode {
-	case RuntimeModeInterpreter:
-		wrc = wazero.NewRuntimeConfigInterpreter()
-	case RuntimeModeCompiled:
-		// TODO: Add validation of supported platforms and architectures
-		wrc = wazero.NewRuntimeConfigCompiler()
-	default:
-		return nil, nil, fmt.Errorf("wasm: invalid runtime mode: %s", rc.Mode)
+	RuntimeModeInterpreter: wazero.NewRuntimeConfigInterpreter,
+	// TODO: Add validation of supported platforms and architectures
+	RuntimeModeCompiled: wazero.NewRuntimeConfigCompiler,
+}
+
+// prepareRuntime initializes a new WebAssembly runtime
+func prepareRuntime(ctx context.Context, guestBin []byte, mode RuntimeMode, cache wazero.CompilationCache, closeOnContextDone bool) (runtime wazero.Runtime, guest wazero.CompiledModule, err error) {
+	newRuntimeConfig, ok := runtimeConfigs[mode]
+	if !ok {
+		return nil, nil, fmt.Errorf("wasm: invalid runtime mode: %s", mode)
 	}
+	wrc := newRuntimeConfig().WithCompilationCache(cache).WithCloseOnContextDone(closeOnContextDone)
 	runtime = wazero.NewRuntimeWithConfig(ctx, wrc)
 
 	guest, err = compileGuest(ctx, runtime, guestBin)
@@ -240,12 +826,12 @@ func prepareRuntime(ctx context.Context, guestBin []byte, rc RuntimeConfig) (run
 // compileGuest compiles the guest module
 func compileGuest(ctx context.Context, runtime wazero.Runtime, guestBin []byte) (guest wazero.CompiledModule, err error) {
 	if guest, err = runtime.CompileModule(ctx, guestBin); err != nil {
-		err = fmt.Errorf("wasm: error compiling guest: %w", err)
+		err = fmt.Errorf("wasm: error compiling guest: %w: %w", ErrInvalidModule, err)
 	} else if _, ok := guest.ExportedMemories()[guestExportMemory]; !ok {
 		// This section checks if the guest exports memory section.
 		// As of WebAssembly Core Specification 2.0, there can be at most one memory.
 		// https://webassembly.github.io/spec/core/syntax/modules.html#memories
-		err = fmt.Errorf("wasm: guest doesn't export memory[%s]", guestExportMemory)
+		err = fmt.Errorf("wasm: guest doesn't export memory[%s]: %w", guestExportMemory, ErrInvalidModule)
 	}
 	return
 }
@@ -257,6 +843,31 @@ func createContextWithStack(ctx context.Context, stack *Stack) context.Context {
 
 // ProcessFunctionCall executes a WASM function and handles stack management
 func (p *WasmPlugin) ProcessFunctionCall(ctx context.Context, functionName string, stack *Stack) ([]uint64, error) {
+	if (p.tracer == nil && p.callLog == nil) || isBuiltInGuestFunction(functionName) {
+		return p.processFunctionCall(ctx, functionName, stack)
+	}
+	call := func(ctx context.Context) ([]uint64, error) {
+		return p.processFunctionCall(ctx, functionName, stack)
+	}
+	if p.callLog != nil {
+		logged := call
+		call = func(ctx context.Context) ([]uint64, error) {
+			return p.callLog.logCall(ctx, functionName, stack, logged)
+		}
+	}
+	if p.tracer == nil {
+		return call(ctx)
+	}
+	return p.traceCall(ctx, functionName, stack, call)
+}
+
+func (p *WasmPlugin) processFunctionCall(ctx context.Context, functionName string, stack *Stack) ([]uint64, error) {
+	stack.resetCaches()
+	stack.compressLogs = p.compressLogs
+	stack.tracesJSON = p.tracesJSON
+	if stack.PluginConfigJSON == nil {
+		stack.PluginConfigJSON, stack.PluginConfigVersion = p.currentPluginConfig()
+	}
 	ctx = createContextWithStack(ctx, stack)
 	// Set the WASI host module instance in the context
 	ctx = withModuleInstance(ctx, p.wasiP1HostModule)
@@ -266,7 +877,119 @@ func (p *WasmPlugin) ProcessFunctionCall(ctx context.Context, functionName strin
 		return nil, fmt.Errorf("wasm: function not found: %s", functionName)
 	}
 
-	return fn.Call(ctx)
+	if p.concurrentSafe {
+		// api.Function isn't goroutine safe, so each call gets a function
+		// instance of its own.
+		fn = p.Module.ExportedFunction(fn.Definition().ExportNames()[0])
+	} else {
+		p.callMu.Lock()
+		defer p.callMu.Unlock()
+	}
+
+	// Built-in functions are probed by the host itself, so they aren't
+	// accounted in the guest quota, nor failed by the fault injection.
+	q, faults := p.quota, p.faults
+	if isBuiltInGuestFunction(functionName) {
+		q, faults = nil, nil
+	}
+	if err := q.admit(); err != nil {
+		return nil, p.guestError(ctx, &GuestError{Function: functionName, Reason: ErrorReasonQuota, Err: err})
+	}
+	if err := faults.inject(); err != nil {
+		return nil, p.guestError(ctx, &GuestError{Function: functionName, Reason: ErrorReasonInjected, Err: err})
+	}
+
+	callCtx := ctx
+	if p.executionTimeout > 0 {
+		// The runtime closes the guest once the context of the call is done,
+		// so the call is only bounded by the timeout, not by the cancellation
+		// of ctx.
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), p.executionTimeout)
+		defer cancel()
+	}
+	stack.deadline = callDeadline(ctx, callCtx)
+
+	// A panic written by a previous call doesn't explain this one.
+	p.guestPanic.take()
+	start := time.Now()
+	res, err := fn.Call(callCtx)
+	elapsed := time.Since(start)
+	p.reportSlowCall(ctx, functionName, elapsed)
+	q.record(p.memoryPages(), elapsed)
+	// The metrics recorded before a failure are forwarded too.
+	p.guestMetrics.forward(ctx, stack)
+	if err != nil {
+		if limitErr := p.memory.takeRefused(); limitErr != nil {
+			// The guest likely failed for the lack of memory.
+			err = fmt.Errorf("%w: %w", limitErr, err)
+		}
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		// The guest can't serve calls anymore.
+		p.ready.Store(false)
+		if exitErr.ExitCode() == sys.ExitCodeDeadlineExceeded {
+			return nil, p.guestError(ctx, &GuestError{Function: functionName, Reason: ErrorReasonTimeout, Err: ErrExecutionTimeout})
+		}
+		return nil, p.guestError(ctx, &GuestError{Function: functionName, Reason: ErrorReasonExit, ExitCode: exitErr.ExitCode(), Panic: p.guestPanic.take(), Err: err})
+	}
+	if err != nil {
+		return nil, p.guestError(ctx, &GuestError{Function: functionName, Reason: ErrorReasonTrap, Panic: p.guestPanic.take(), StackTrace: wasmStackTrace(err), Err: err})
+	}
+	if stack.HostError != nil {
+		return nil, p.guestError(ctx, &GuestError{Function: functionName, Reason: ErrorReasonHostCall, Err: stack.HostError})
+	}
+	return res, nil
+}
+
+// CheckStatus returns a *GuestError if the status code returned by a call to
+// the given guest function isn't OK. res and stack are the result and the
+// stack of the call.
+func (p *WasmPlugin) CheckStatus(ctx context.Context, functionName string, res []uint64, stack *Stack) error {
+	status := StatusCode(res[0])
+	if status == StatusCodeOK {
+		return nil
+	}
+	reason := ErrorReasonStatus
+	if status == StatusCodeInvalidArgument {
+		reason = ErrorReasonInvalidArgument
+	}
+	return p.guestError(ctx, &GuestError{
+		Function:     functionName,
+		Reason:       reason,
+		Status:       status,
+		StatusReason: stack.StatusReason,
+	})
+}
+
+// guestError records err in the plugin metrics, logs it and returns it.
+func (p *WasmPlugin) guestError(ctx context.Context, err *GuestError) error {
+	p.telemetry.recordGuestError(ctx, err)
+	p.errorLog.log(err)
+	return err
+}
+
+// memoryPages returns the current number of pages of the guest memory.
+func (p *WasmPlugin) memoryPages() uint32 {
+	mem := p.Module.Memory()
+	if mem == nil {
+		return 0
+	}
+	return mem.Size() / wasmPageSize
+}
+
+// reportSlowCall adds an event to the span of ctx if the call of the given
+// function took longer than the slow call threshold.
+func (p *WasmPlugin) reportSlowCall(ctx context.Context, functionName string, elapsed time.Duration) {
+	if p.slowCallThreshold == 0 || elapsed <= p.slowCallThreshold {
+		return
+	}
+	trace.SpanFromContext(ctx).AddEvent(slowCallEvent, trace.WithAttributes(
+		attribute.String(slowCallFunctionAttribute, functionName),
+		attribute.Float64(slowCallDurationAttribute, elapsed.Seconds()),
+		attribute.Float64(slowCallThresholdAttribute, p.slowCallThreshold.Seconds()),
+	))
 }
 
 func (p *WasmPlugin) supportedTelemetryTypes(ctx context.Context) (telemetryType, error) {
@@ -308,40 +1031,256 @@ func (p *WasmPlugin) IsTracesSupported(ctx context.Context) (bool, error) {
 	return telemetryTypes&telemetryTypeTraces != 0, nil
 }
 
+// IsProfilesSupported reports whether the guest supports the profiles
+// signal, which guests built with older SDKs never do.
+func (p *WasmPlugin) IsProfilesSupported(ctx context.Context) (bool, error) {
+	telemetryTypes, err := p.supportedTelemetryTypes(ctx)
+	if err != nil {
+		return false, err
+	}
+	return telemetryTypes&telemetryTypeProfiles != 0, nil
+}
+
+// UpdateConfig replaces the plugin config passed to the guest without
+// recompiling or reinstantiating the module. Calls started after UpdateConfig
+// returns see the new config the next time the guest reads it, e.g. via
+// imports.GetConfig. Calls already in flight keep the config they started with.
+//
+// Guests caching their config, e.g. the components wrapped by factoryconnector,
+// export a reconfigure function, called once the config is replaced so they
+// read it again. An error returned by it leaves the new config in place.
+//
+// The collector doesn't update running components: it rebuilds them, and
+// their plugins, when its configuration is reloaded. UpdateConfig is for
+// hosts managing the lifetime of the plugin themselves.
+func (p *WasmPlugin) UpdateConfig(ctx context.Context, newConfig PluginConfig) error {
+	pluginConfigJSON, err := json.Marshal(newConfig)
+	if err != nil {
+		return fmt.Errorf("wasm: error marshalling plugin config: %w", err)
+	}
+	return p.UpdatePluginConfig(ctx, pluginConfigJSON)
+}
+
+// UpdatePluginConfig is UpdateConfig for a config already in its JSON
+// representation. It also increments the version of the config the guest
+// reads with getPluginConfigVersion, so guests not exporting reconfigure
+// can notice the update on their next call and decode the config again.
+//
+// The config and its version are swapped together, and each call takes a
+// snapshot of both when it starts: a call in flight keeps reading the config
+// it started with, and the update is seen from the next call on.
+func (p *WasmPlugin) UpdatePluginConfig(ctx context.Context, pluginConfigJSON []byte) error {
+	if !json.Valid(pluginConfigJSON) {
+		return errors.New("wasm: plugin config is not valid JSON")
+	}
+
+	p.configMu.Lock()
+	p.PluginConfigJSON = pluginConfigJSON
+	p.pluginConfigVersion++
+	version := p.pluginConfigVersion
+	p.configMu.Unlock()
+
+	if _, ok := p.ExportedFunctions[guestReconfigure]; !ok {
+		return nil
+	}
+	stack := &Stack{PluginConfigJSON: pluginConfigJSON, PluginConfigVersion: version}
+	res, err := p.ProcessFunctionCall(ctx, guestReconfigure, stack)
+	if err != nil {
+		return err
+	}
+	return p.CheckStatus(ctx, guestReconfigure, res, stack)
+}
+
+// CurrentPluginConfigJSON returns the JSON representation of the current
+// plugin config. It is safe to call concurrently with UpdatePluginConfig.
+func (p *WasmPlugin) CurrentPluginConfigJSON() []byte {
+	config, _ := p.currentPluginConfig()
+	return config
+}
+
+func (p *WasmPlugin) currentPluginConfig() ([]byte, uint32) {
+	p.configMu.RLock()
+	defer p.configMu.RUnlock()
+	return p.PluginConfigJSON, p.pluginConfigVersion
+}
+
+// StartGuest calls the start function of the guest, if it exports one, so the
+// guest can set up what it needs for the component's lifetime, e.g. a
+// connection pool. It is called by the component's Start, and calls the
+// guest at most once.
+func (p *WasmPlugin) StartGuest(ctx context.Context) error {
+	if !p.guestStarted.CompareAndSwap(false, true) {
+		return nil
+	}
+	if _, ok := p.ExportedFunctions[guestStart]; !ok {
+		return nil
+	}
+	stack := &Stack{}
+	res, err := p.ProcessFunctionCall(ctx, guestStart, stack)
+	if err != nil {
+		return err
+	}
+	return p.CheckStatus(ctx, guestStart, res, stack)
+}
+
+// ShutdownGuest calls the shutdown function of the guest, if it exports one,
+// so the guest can flush the data it buffers before the runtime is closed.
+// The guest is told the shutdown is requested through getShutdownRequested.
+// It calls the guest at most once, and only if it was started by StartGuest,
+// e.g. not if the component failed before starting.
+func (p *WasmPlugin) ShutdownGuest(ctx context.Context) error {
+	if !p.guestStarted.Load() {
+		return nil
+	}
+	if _, ok := p.ExportedFunctions[guestShutdown]; !ok || !p.guestShutDown.CompareAndSwap(false, true) {
+		return nil
+	}
+	stack := &Stack{}
+	stack.RequestedShutdown.Store(true)
+	res, err := p.ProcessFunctionCall(ctx, guestShutdown, stack)
+	if err != nil {
+		return err
+	}
+	return p.CheckStatus(ctx, guestShutdown, res, stack)
+}
+
+// WithInterruptibleCalls makes the guest calls abort once their context is
+// done or the plugin is shut down, at the cost of slower guest execution. It
+// is meant for long-running calls, such as receivers, which must not outlive
+// the plugin.
+func WithInterruptibleCalls() Option {
+	return func(o *options) {
+		o.interruptibleCalls = true
+	}
+}
+
+// Ready reports whether the guest is instantiated, configured and able to
+// serve calls. It turns false once the guest is closed, by Shutdown or
+// because it exited or timed out.
+func (p *WasmPlugin) Ready() bool {
+	return p.ready.Load()
+}
+
 // Shutdown closes the WASM runtime and system
 func (p *WasmPlugin) Shutdown(ctx context.Context) error {
+	p.ready.Store(false)
+	loadedPlugins.remove(p)
 	if err := p.Sys.Close(ctx); err != nil {
 		return fmt.Errorf("wasm: error closing system: %w", err)
 	}
 	if err := p.Runtime.Close(ctx); err != nil {
 		return fmt.Errorf("wasm: error closing runtime: %w", err)
 	}
+	var err error
+	p.releaseModule.Do(func() {
+		if p.module != nil {
+			err = p.module.release(ctx)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("wasm: error closing compiled module: %w", err)
+	}
+	p.releaseCallLog.Do(func() {
+		err = p.callLog.release()
+	})
+	if err != nil {
+		return fmt.Errorf("wasm: error closing call log: %w", err)
+	}
 	return nil
 }
 
 // Host function implementations
+
+// currentTracesFn writes the serialized current traces if they fit within
+// bufLimit, and returns their size either way, so the guest can grow its
+// buffer and call again. The retry reuses the traces marshaled by the first
+// call.
 func currentTracesFn(ctx context.Context, mod api.Module, stack []uint64) {
 	buf := uint32(stack[0])
 	bufLimit := uint32(stack[1])
 
-	traces := paramsFromContext(ctx).CurrentTraces
-	stack[0] = uint64(marshalTraceIfUnderLimit(mod.Memory(), traces, buf, bufLimit))
+	params := paramsFromContext(ctx)
+	tracesBytes, err := params.currentTracesBytes()
+	if err != nil {
+		params.recordHostError(currentTraces, err)
+		stack[0] = 0
+		return
+	}
+	params.inputSize = uint32(len(tracesBytes))
+	if params.inputSize > bufLimit {
+		stack[0] = uint64(params.inputSize)
+		return
+	}
+	stack[0] = uint64(writeBytesIfUnderLimit(mod.Memory(), tracesBytes, buf, bufLimit))
+}
+
+// currentTracesChunkFn writes the frame of the serialized current traces
+// starting at offset, up to bufLimit bytes, and returns the size of the whole
+// payload. Guests read payloads larger than their buffer frame by frame,
+// while the traces are marshaled once.
+func currentTracesChunkFn(ctx context.Context, mod api.Module, stack []uint64) {
+	offset := uint32(stack[0])
+	buf := uint32(stack[1])
+	bufLimit := uint32(stack[2])
+
+	params := paramsFromContext(ctx)
+	tracesBytes, err := params.currentTracesBytes()
+	if err != nil {
+		params.recordHostError(currentTracesChunk, err)
+		stack[0] = 0
+		return
+	}
+
+	if offset < uint32(len(tracesBytes)) {
+		chunk := tracesBytes[offset:]
+		if uint32(len(chunk)) > bufLimit {
+			chunk = chunk[:bufLimit]
+		}
+		if !mod.Memory().Write(buf, chunk) {
+			params.recordHostError(currentTracesChunk, errOutOfMemory)
+		}
+	}
+	params.inputSize = uint32(len(tracesBytes))
+	stack[0] = uint64(len(tracesBytes))
 }
 
 func currentMetricsFn(ctx context.Context, mod api.Module, stack []uint64) {
 	buf := uint32(stack[0])
 	bufLimit := uint32(stack[1])
 
-	metrics := paramsFromContext(ctx).CurrentMetrics
-	stack[0] = uint64(marshalMetricsIfUnderLimit(mod.Memory(), metrics, buf, bufLimit))
+	params := paramsFromContext(ctx)
+	params.inputSize = marshalMetricsIfUnderLimit(mod.Memory(), params.CurrentMetrics, buf, bufLimit)
+	stack[0] = uint64(params.inputSize)
 }
 
 func currentLogsFn(ctx context.Context, mod api.Module, stack []uint64) {
 	buf := uint32(stack[0])
 	bufLimit := uint32(stack[1])
 
-	logs := paramsFromContext(ctx).CurrentLogs
-	stack[0] = uint64(marshalLogsIfUnderLimit(mod.Memory(), logs, buf, bufLimit))
+	params := paramsFromContext(ctx)
+	logsBytes, err := params.currentLogsBytes()
+	if err != nil {
+		params.recordHostError(currentLogs, err)
+		stack[0] = 0
+		return
+	}
+	params.inputSize = writeBytesIfUnderLimit(mod.Memory(), logsBytes, buf, bufLimit)
+	stack[0] = uint64(params.inputSize)
+}
+
+func currentProfilesFn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf := uint32(stack[0])
+	bufLimit := uint32(stack[1])
+
+	params := paramsFromContext(ctx)
+	profilesBytes, err := marshalProfiles(params.CurrentProfiles)
+	if err != nil {
+		params.recordHostError(currentProfiles, err)
+		stack[0] = 0
+		return
+	}
+	params.inputSize = writeBytesIfUnderLimit(mod.Memory(), profilesBytes, buf, bufLimit)
+	stack[0] = uint64(params.inputSize)
 }
 
 func getPluginConfigFn(ctx context.Context, mod api.Module, stack []uint64) {
@@ -352,6 +1291,24 @@ func getPluginConfigFn(ctx context.Context, mod api.Module, stack []uint64) {
 	stack[0] = uint64(writeBytesIfUnderLimit(mod.Memory(), pluginConfig, buf, bufLimit))
 }
 
+// getPluginConfigStatusFn is getPluginConfigFn writing the hostStatus of
+// the read at status_ptr, so the guest tells a buffer too small from a
+// failure.
+func getPluginConfigStatusFn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf := uint32(stack[0])
+	bufLimit := uint32(stack[1])
+	statusPtr := uint32(stack[2])
+
+	pluginConfig := paramsFromContext(ctx).PluginConfigJSON
+	status := writeBytesWithStatus(mod.Memory(), pluginConfig, buf, bufLimit)
+	writeHostStatus(ctx, mod, getPluginConfigStatus, statusPtr, status)
+	stack[0] = uint64(len(pluginConfig))
+}
+
+func getPluginConfigVersionFn(ctx context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(paramsFromContext(ctx).PluginConfigVersion)
+}
+
 func getShutdownRequestedFn(ctx context.Context, mod api.Module, stack []uint64) {
 	// Read the shutdown requested flag from the stack
 	shutdownRequested := paramsFromContext(ctx).RequestedShutdown.Load()
@@ -372,24 +1329,86 @@ func setResultTracesFn(ctx context.Context, mod api.Module, stack []uint64) {
 	// Read the serialized traces from WASM memory
 	tracesBytes, ok := mod.Memory().Read(buf, size)
 	if !ok {
-		panic("out of memory reading result traces") // Bug: caller passed a length outside memory
+		paramsFromContext(ctx).recordHostError(setResultTraces, errOutOfMemory)
+		return
 	}
 
 	// Unmarshal the traces
-	unmarshaler := ptrace.ProtoUnmarshaler{}
-	traces, err := unmarshaler.UnmarshalTraces(tracesBytes)
+	traces, err := unmarshalTraces(tracesBytes, paramsFromContext(ctx).tracesJSON)
 	if err != nil {
-		panic(err) // Bug: in unmarshaller
+		paramsFromContext(ctx).recordHostError(setResultTraces, err)
+		return
 	}
 
 	// Store the result traces in context
 	paramsFromContext(ctx).ResultTraces = traces
+	paramsFromContext(ctx).outputSize = size
 	onResultTracesChange := paramsFromContext(ctx).OnResultTracesChange
 	if onResultTracesChange != nil {
 		onResultTracesChange(traces)
 	}
 }
 
+// setResultUnchangedFn reports the telemetry of the call unchanged, see
+// Stack.ResultUnchanged.
+func setResultUnchangedFn(ctx context.Context, _ api.Module, _ []uint64) {
+	paramsFromContext(ctx).ResultUnchanged = true
+}
+
+// appendResultTracesFn appends a batch to the result traces batches, see
+// Stack.ResultTracesBatches.
+func appendResultTracesFn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf := uint32(stack[0])
+	size := uint32(stack[1])
+
+	params := paramsFromContext(ctx)
+	tracesBytes, ok := mod.Memory().Read(buf, size)
+	if !ok {
+		params.recordHostError(appendResultTraces, errOutOfMemory)
+		return
+	}
+
+	traces, err := unmarshalTraces(tracesBytes, params.tracesJSON)
+	if err != nil {
+		params.recordHostError(appendResultTraces, err)
+		return
+	}
+
+	params.ResultTracesBatches = append(params.ResultTracesBatches, traces)
+	params.outputSize += size
+}
+
+// emitTracesFn passes a batch to Stack.EmitTraces, and returns 0 if it
+// succeeds or 1 otherwise.
+func emitTracesFn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf := uint32(stack[0])
+	size := uint32(stack[1])
+	stack[0] = 1
+
+	params := paramsFromContext(ctx)
+	if params.EmitTraces == nil {
+		params.recordHostError(emitTraces, errEmitUnsupported)
+		return
+	}
+	tracesBytes, ok := mod.Memory().Read(buf, size)
+	if !ok {
+		params.recordHostError(emitTraces, errOutOfMemory)
+		return
+	}
+
+	traces, err := unmarshalTraces(tracesBytes, params.tracesJSON)
+	if err != nil {
+		params.recordHostError(emitTraces, err)
+		return
+	}
+
+	params.outputSize += size
+	if err := params.EmitTraces(traces); err != nil {
+		return
+	}
+	stack[0] = 0
+}
+
 func setResultMetricsFn(ctx context.Context, mod api.Module, stack []uint64) {
 	// Read buffer pointer and size from the stack
 	buf := uint32(stack[0])
@@ -398,18 +1417,21 @@ func setResultMetricsFn(ctx context.Context, mod api.Module, stack []uint64) {
 	// Read the serialized metrics from WASM memory
 	metricsBytes, ok := mod.Memory().Read(buf, size)
 	if !ok {
-		panic("out of memory reading result metrics") // Bug: caller passed a length outside memory
+		paramsFromContext(ctx).recordHostError(setResultMetrics, errOutOfMemory)
+		return
 	}
 
 	// Unmarshal the metrics
 	unmarshaler := pmetric.ProtoUnmarshaler{}
 	metrics, err := unmarshaler.UnmarshalMetrics(metricsBytes)
 	if err != nil {
-		panic(err) // Bug: in unmarshaller
+		paramsFromContext(ctx).recordHostError(setResultMetrics, err)
+		return
 	}
 
 	// Store the result metrics in context
 	paramsFromContext(ctx).ResultMetrics = metrics
+	paramsFromContext(ctx).outputSize = size
 	onResultMetricsChange := paramsFromContext(ctx).OnResultMetricsChange
 	if onResultMetricsChange != nil {
 		onResultMetricsChange(metrics)
@@ -424,24 +1446,54 @@ func setResultLogsFn(ctx context.Context, mod api.Module, stack []uint64) {
 	// Read the serialized logs from WASM memory
 	logsBytes, ok := mod.Memory().Read(buf, size)
 	if !ok {
-		panic("out of memory reading result logs") // Bug: caller passed a length outside memory
+		paramsFromContext(ctx).recordHostError(setResultLogs, errOutOfMemory)
+		return
 	}
 
-	// Unmarshal the logs
+	// Unmarshal the logs, compressed if the guest was passed compressed logs
+	params := paramsFromContext(ctx)
+	if params.compressLogs {
+		var err error
+		if logsBytes, err = decompressLogs(logsBytes); err != nil {
+			params.recordHostError(setResultLogs, err)
+			return
+		}
+	}
 	unmarshaler := plog.ProtoUnmarshaler{}
 	logs, err := unmarshaler.UnmarshalLogs(logsBytes)
 	if err != nil {
-		panic(err) // Bug: in unmarshaller
+		paramsFromContext(ctx).recordHostError(setResultLogs, err)
+		return
 	}
 
 	// Store the result logs in context
 	paramsFromContext(ctx).ResultLogs = logs
+	paramsFromContext(ctx).outputSize = size
 	onResultLogsChange := paramsFromContext(ctx).OnResultLogsChange
 	if onResultLogsChange != nil {
 		onResultLogsChange(logs)
 	}
 }
 
+func setResultProfilesFn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf := uint32(stack[0])
+	size := uint32(stack[1])
+
+	params := paramsFromContext(ctx)
+	profilesBytes, ok := mod.Memory().Read(buf, size)
+	if !ok {
+		params.recordHostError(setResultProfiles, errOutOfMemory)
+		return
+	}
+	profiles, err := (&pprofile.ProtoUnmarshaler{}).UnmarshalProfiles(profilesBytes)
+	if err != nil {
+		params.recordHostError(setResultProfiles, err)
+		return
+	}
+	params.ResultProfiles = profiles
+	params.outputSize = size
+}
+
 func setResultStatusReasonFn(ctx context.Context, mod api.Module, stack []uint64) {
 	// Read buffer pointer and size from the stack
 	buf := uint32(stack[0])
@@ -450,44 +1502,221 @@ func setResultStatusReasonFn(ctx context.Context, mod api.Module, stack []uint64
 	// Read the status reason string from WASM memory
 	reasonBytes, ok := mod.Memory().Read(buf, size)
 	if !ok {
-		panic("out of memory reading status reason") // Bug: caller passed a length outside memory
+		paramsFromContext(ctx).recordHostError(setResultStatusReason, errOutOfMemory)
+		return
 	}
 
 	// Store the status reason in context
 	paramsFromContext(ctx).StatusReason = string(reasonBytes)
 }
 
+// getBagValueFn writes the value of a key of the bag of the call if it fits
+// within bufLimit, and returns the number of bytes written, zero if it
+// doesn't fit or the call has no bag.
+func getBagValueFn(ctx context.Context, mod api.Module, stack []uint64) {
+	key := uint32(stack[0])
+	keyLen := uint32(stack[1])
+	buf := uint32(stack[2])
+	bufLimit := uint32(stack[3])
+
+	bag := paramsFromContext(ctx).Bag
+	if bag == nil {
+		stack[0] = 0
+		return
+	}
+
+	keyBytes, ok := mod.Memory().Read(key, keyLen)
+	if !ok {
+		paramsFromContext(ctx).recordHostError(getBagValue, errOutOfMemory)
+		stack[0] = 0
+		return
+	}
+
+	value, _ := bag.Get(string(keyBytes))
+	stack[0] = uint64(writeBytesIfUnderLimit(mod.Memory(), []byte(value), buf, bufLimit))
+}
+
+func setBagValueFn(ctx context.Context, mod api.Module, stack []uint64) {
+	key := uint32(stack[0])
+	keyLen := uint32(stack[1])
+	value := uint32(stack[2])
+	valueLen := uint32(stack[3])
+
+	bag := paramsFromContext(ctx).Bag
+	if bag == nil {
+		return
+	}
+
+	keyBytes, ok := mod.Memory().Read(key, keyLen)
+	if !ok {
+		paramsFromContext(ctx).recordHostError(setBagValue, errOutOfMemory)
+		return
+	}
+	valueBytes, ok := mod.Memory().Read(value, valueLen)
+	if !ok {
+		paramsFromContext(ctx).recordHostError(setBagValue, errOutOfMemory)
+		return
+	}
+
+	bag.Set(string(keyBytes), string(valueBytes))
+}
+
+// guestEnv returns the variables of the host environment exposed to the
+// guest of cfg, by name and as "name=value" pairs for WASI.
+func guestEnv(cfg *Config) (map[string]string, []string) {
+	if cfg.EnvPassthrough {
+		environ := os.Environ()
+		env := make(map[string]string, len(environ))
+		for _, kv := range environ {
+			if name, value, ok := strings.Cut(kv, "="); ok {
+				env[name] = value
+			}
+		}
+		return env, environ
+	}
+
+	env := allowedEnv(cfg.EnvAllowlist)
+	environ := make([]string, 0, len(env))
+	for _, name := range cfg.EnvAllowlist {
+		if value, ok := env[name]; ok {
+			environ = append(environ, name+"="+value)
+		}
+	}
+	return env, environ
+}
+
+// allowedEnv returns the variables of the host environment in allowlist.
+func allowedEnv(allowlist []string) map[string]string {
+	env := make(map[string]string, len(allowlist))
+	for _, name := range allowlist {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// newGetEnvFn returns the getEnv host function exposing env.
+func newGetEnvFn(env map[string]string) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		name := uint32(stack[0])
+		nameLen := uint32(stack[1])
+		buf := uint32(stack[2])
+		bufLimit := uint32(stack[3])
+
+		nameBytes, ok := mod.Memory().Read(name, nameLen)
+		if !ok {
+			paramsFromContext(ctx).recordHostError(getEnv, errOutOfMemory)
+			stack[0] = envNotFound
+			return
+		}
+
+		value, ok := env[string(nameBytes)]
+		if !ok {
+			stack[0] = envNotFound
+			return
+		}
+
+		// The length is returned even if the value doesn't fit, so the guest
+		// can retry with a large enough buffer.
+		if uint32(len(value)) <= bufLimit && !mod.Memory().WriteString(buf, value) {
+			paramsFromContext(ctx).recordHostError(getEnv, errOutOfMemory)
+			stack[0] = 0
+			return
+		}
+		stack[0] = uint64(len(value))
+	}
+}
+
+// callDeadline returns the earliest deadline of ctx, the context of the call,
+// and callCtx, the context bound by the execution timeout, which doesn't
+// inherit the deadline of ctx. It returns the zero time if neither has one.
+func callDeadline(ctx, callCtx context.Context) time.Time {
+	deadline, ok := ctx.Deadline()
+	if timeout, bounded := callCtx.Deadline(); bounded && (!ok || timeout.Before(deadline)) {
+		return timeout
+	}
+	return deadline
+}
+
+// getDeadlineUnixNanoFn returns the deadline of the call in nanoseconds since
+// the Unix epoch, or 0 if the call has no deadline.
+func getDeadlineUnixNanoFn(ctx context.Context, mod api.Module, stack []uint64) {
+	deadline := paramsFromContext(ctx).deadline
+	if deadline.IsZero() {
+		stack[0] = 0
+		return
+	}
+	stack[0] = uint64(deadline.UnixNano())
+}
+
+func hostNowFn(ctx context.Context, mod api.Module, stack []uint64) {
+	buf := uint32(stack[0])
+
+	params := paramsFromContext(ctx)
+	clock := params.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	wall, monotonic := clock()
+
+	if !mod.Memory().WriteUint64Le(buf, uint64(wall)) || !mod.Memory().WriteUint64Le(buf+8, uint64(monotonic)) {
+		params.recordHostError(hostNow, errOutOfMemory)
+	}
+}
+
 // instantiateHostModule creates and instantiates the host module with exported functions
-func instantiateHostModule(ctx context.Context, runtime wazero.Runtime) (api.Module, error) {
-	return runtime.NewHostModuleBuilder(otelWasm).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(currentTracesFn), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
-		WithParameterNames("buf", "buf_limit").Export(currentTraces).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(currentMetricsFn), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
-		WithParameterNames("buf", "buf_limit").Export(currentMetrics).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(currentLogsFn), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
-		WithParameterNames("buf", "buf_limit").Export(currentLogs).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(setResultTracesFn), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{}).
-		WithParameterNames("buf", "buf_len").Export(setResultTraces).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(setResultMetricsFn), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{}).
-		WithParameterNames("buf", "buf_len").Export(setResultMetrics).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(setResultLogsFn), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{}).
-		WithParameterNames("buf", "buf_len").Export(setResultLogs).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(getPluginConfigFn), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
-		WithParameterNames("buf", "buf_limit").Export(getPluginConfig).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(setResultStatusReasonFn), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{}).
-		WithParameterNames("buf", "buf_len").Export(setResultStatusReason).
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(getShutdownRequestedFn), []api.ValueType{}, []api.ValueType{api.ValueTypeI32}).
-		Export(getShutdownRequested).
-		Instantiate(ctx)
+func instantiateHostModule(ctx context.Context, runtime wazero.Runtime, env map[string]string, configFiles map[string][]byte, state *kvStore, cfg *Config, o *options, tracer *hostCallTracer) (api.Module, error) {
+	builder := runtime.NewHostModuleBuilder(otelWasm)
+	export := func(name string, fn api.GoModuleFunc, params, results []api.ValueType, paramNames ...string) {
+		builder.NewFunctionBuilder().
+			WithGoModuleFunction(tracer.wrap(name, fn, len(params), len(results)), params, results).
+			WithParameterNames(paramNames...).Export(name)
+	}
+
+	i32 := api.ValueTypeI32
+	export(currentTraces, currentTracesFn, []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_limit")
+	export(currentMetrics, currentMetricsFn, []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_limit")
+	export(currentLogs, currentLogsFn, []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_limit")
+	export(setResultTraces, setResultTracesFn, []api.ValueType{i32, i32}, nil, "buf", "buf_len")
+	export(appendResultTraces, appendResultTracesFn, []api.ValueType{i32, i32}, nil, "buf", "buf_len")
+	export(emitTraces, emitTracesFn, []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_len")
+	export(setResultMetrics, setResultMetricsFn, []api.ValueType{i32, i32}, nil, "buf", "buf_len")
+	export(setResultLogs, setResultLogsFn, []api.ValueType{i32, i32}, nil, "buf", "buf_len")
+	export(currentProfiles, currentProfilesFn, []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_limit")
+	export(setResultProfiles, setResultProfilesFn, []api.ValueType{i32, i32}, nil, "buf", "buf_len")
+	export(setResultUnchanged, setResultUnchangedFn, nil, nil)
+	export(getPluginConfig, getPluginConfigFn, []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_limit")
+	export(getPluginConfigVersion, getPluginConfigVersionFn, nil, []api.ValueType{i32})
+	export(getPluginConfigStatus, getPluginConfigStatusFn, []api.ValueType{i32, i32, i32}, []api.ValueType{i32}, "buf", "buf_limit", "status_ptr")
+	export(setResultStatusReason, setResultStatusReasonFn, []api.ValueType{i32, i32}, nil, "buf", "buf_len")
+	export(getShutdownRequested, getShutdownRequestedFn, nil, []api.ValueType{i32})
+	export(getDeadlineUnixNano, getDeadlineUnixNanoFn, nil, []api.ValueType{api.ValueTypeI64})
+	export(getBagValue, getBagValueFn, []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}, "key", "key_len", "buf", "buf_limit")
+	export(setBagValue, setBagValueFn, []api.ValueType{i32, i32, i32, i32}, nil, "key", "key_len", "value", "value_len")
+	export(getEnv, newGetEnvFn(env), []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}, "name", "name_len", "buf", "buf_limit")
+	export(kvGet, newKVGetFn(state), []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}, "key", "key_len", "buf", "buf_limit")
+	export(kvSet, newKVSetFn(state, o.logger), []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}, "key", "key_len", "value", "value_len")
+	export(getConfigFile, newGetConfigFileFn(configFiles), []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}, "name", "name_len", "buf", "buf_limit")
+	export(getComponentInfo, newGetComponentInfoFn(o.component), []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_limit")
+	export(hostNow, hostNowFn, []api.ValueType{i32}, nil, "buf")
+	export(randUint64, newRandUint64Fn(cfg.RandSeed), nil, []api.ValueType{api.ValueTypeI64})
+	export(hashSHA256, newHashSHA256Fn(cfg.Hash), []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}, "buf", "buf_len", "salted", "digest")
+	export(hashFNV64, hashFNV64Fn, []api.ValueType{i32, i32}, []api.ValueType{api.ValueTypeI64}, "buf", "buf_len")
+	export(httpDo, newHTTPDoFn(newHTTPClient(cfg.HTTP)), []api.ValueType{i32, i32}, []api.ValueType{i32}, "req", "req_len")
+	files := newFileReader(cfg.Files)
+	export(hostReadFile, newHostReadFileFn(files), []api.ValueType{i32, i32, api.ValueTypeI64, i32, i32, i32}, []api.ValueType{i32}, "path", "path_len", "offset", "buf", "buf_limit", "status_ptr")
+	export(hostStatFile, newHostStatFileFn(files), []api.ValueType{i32, i32, i32, i32}, nil, "path", "path_len", "stat_ptr", "status_ptr")
+	export(httpResponseChunk, httpResponseChunkFn, []api.ValueType{i32, i32, i32}, []api.ValueType{i32}, "offset", "buf", "buf_limit")
+	export(currentTracesChunk, currentTracesChunkFn, []api.ValueType{i32, i32, i32}, []api.ValueType{i32}, "offset", "buf", "buf_limit")
+	export(getExtensions, getExtensionsFn, []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_limit")
+	export(authenticate, authenticateFn, []api.ValueType{i32, i32, i32, i32, i32, i32}, []api.ValueType{i32}, "id", "id_len", "headers", "headers_len", "buf", "buf_limit")
+	export(logMessage, newLogMessageFn(o.logger), []api.ValueType{i32, i32, i32, i32, i32}, nil, "level", "msg", "msg_len", "fields", "fields_len")
+	export(getLogLevel, newGetLogLevelFn(o.logger), nil, []api.ValueType{i32})
+	export(getTraceParent, getTraceParentFn, []api.ValueType{i32, i32}, []api.ValueType{i32}, "buf", "buf_limit")
+	export(recordMetric, newRecordMetricFn(newGuestMetrics(o.meterProvider), o.guestMetricsConsumer != nil), []api.ValueType{i32, i32, i32, api.ValueTypeI64, i32, i32}, nil, "name", "name_len", "kind", "value", "attrs", "attrs_len")
+
+	return builder.Instantiate(ctx)
 }
 
 // moduleInstanceFor returns the module instance from the context that contains the internal
@@ -0,0 +1,1050 @@
+package wasmplugin
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// returnsI32 returns an exported function returning the given value.
+func returnsI32(export string, v int32) wasmtest.Function {
+	return wasmtest.Function{
+		Export:  export,
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body:    wasmtest.I32Const(v),
+	}
+}
+
+func newTestPlugin(t testing.TB, mod *wasmtest.Module, cfg Config, requiredFunctions ...string) *WasmPlugin {
+	t.Helper()
+	cfg.Path = mod.Write(t)
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, requiredFunctions)
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := plugin.Shutdown(t.Context()); err != nil {
+			t.Errorf("failed to shutdown plugin: %v", err)
+		}
+	})
+	return plugin
+}
+
+func TestRuntimeFallbacks(t *testing.T) {
+	// The compiled runtime is limited to a single memory page, so it fails to
+	// compile the guest declaring two.
+	newCompiler := runtimeConfigs[RuntimeModeCompiled]
+	runtimeConfigs[RuntimeModeCompiled] = func() wazero.RuntimeConfig {
+		return newCompiler().WithMemoryLimitPages(1)
+	}
+	t.Cleanup(func() { runtimeConfigs[RuntimeModeCompiled] = newCompiler })
+
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces))
+	mod.MemoryPages = 2
+	path := mod.Write(t)
+
+	tests := []struct {
+		name      string
+		fallbacks []RuntimeMode
+		wantMode  RuntimeMode
+	}{
+		{name: "without fallback"},
+		{name: "failing fallback", fallbacks: []RuntimeMode{RuntimeModeCompiled}},
+		{name: "working fallback", fallbacks: []RuntimeMode{RuntimeModeInterpreter}, wantMode: RuntimeModeInterpreter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Path: path, RuntimeConfig: RuntimeConfig{Mode: RuntimeModeCompiled, Fallbacks: tt.fallbacks}}
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, nil, WithCompiledModuleCache(NewCompiledModuleCache()))
+			if tt.wantMode == "" {
+				if err == nil {
+					plugin.Shutdown(t.Context())
+					t.Fatal("expected the guest to fail to instantiate")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			defer plugin.Shutdown(t.Context())
+			if plugin.RuntimeMode() != tt.wantMode {
+				t.Errorf("expected the %s runtime, got %s", tt.wantMode, plugin.RuntimeMode())
+			}
+		})
+	}
+}
+
+func TestFunctionPrefixes(t *testing.T) {
+	// Both ABI generations are exported, and each returns a distinct value so
+	// the test can tell which one was resolved.
+	bothGenerations := wasmtest.NewGuest(int32(telemetryTypeTraces),
+		returnsI32("processTraces", 1),
+		returnsI32("v2_processTraces", 2),
+	)
+	legacyOnly := wasmtest.NewGuest(int32(telemetryTypeTraces),
+		returnsI32("processTraces", 1),
+	)
+	prefixedOnly := &wasmtest.Module{Functions: []wasmtest.Function{
+		returnsI32("v2_getSupportedTelemetry", int32(telemetryTypeTraces)),
+		returnsI32("v2_processTraces", 2),
+	}}
+
+	tests := []struct {
+		name     string
+		module   *wasmtest.Module
+		prefixes []string
+		want     uint64
+	}{
+		{
+			name:   "unprefixed by default",
+			module: bothGenerations,
+			want:   1,
+		},
+		{
+			name:     "prefixed generation preferred",
+			module:   bothGenerations,
+			prefixes: []string{"v2_", ""},
+			want:     2,
+		},
+		{
+			name:     "unprefixed generation preferred",
+			module:   bothGenerations,
+			prefixes: []string{"", "v2_"},
+			want:     1,
+		},
+		{
+			name:     "falls back to legacy name",
+			module:   legacyOnly,
+			prefixes: []string{"v2_", ""},
+			want:     1,
+		},
+		{
+			name:     "prefixed only",
+			module:   prefixedOnly,
+			prefixes: []string{"v2_"},
+			want:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{ABI: ABIConfig{FunctionPrefixes: tt.prefixes}}
+			plugin := newTestPlugin(t, tt.module, cfg, "processTraces")
+
+			res, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+			if err != nil {
+				t.Fatalf("failed to call processTraces: %v", err)
+			}
+			if res[0] != tt.want {
+				t.Errorf("processTraces returned %d, want %d", res[0], tt.want)
+			}
+
+			supported, err := plugin.IsTracesSupported(t.Context())
+			if err != nil {
+				t.Fatalf("failed to get supported telemetry: %v", err)
+			}
+			if !supported {
+				t.Error("expected traces to be supported")
+			}
+		})
+	}
+}
+
+func TestFunctionPrefixesNotExported(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("processTraces", 1))
+	cfg := Config{
+		Path: mod.Write(t),
+		ABI:  ABIConfig{FunctionPrefixes: []string{"v2_"}},
+	}
+	cfg.Default()
+
+	_, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"})
+	if !errors.Is(err, ErrRequiredFunctionNotExported) {
+		t.Errorf("expected ErrRequiredFunctionNotExported, got %v", err)
+	}
+	// The error lists the unprefixed export, hinting at the prefix.
+	if err != nil && !strings.Contains(err.Error(), "the guest exports getSupportedTelemetry, processTraces") {
+		t.Errorf("expected the error to list the exports of the guest, got %v", err)
+	}
+}
+
+func TestExportedFunctionNames(t *testing.T) {
+	mod := &wasmtest.Module{Functions: []wasmtest.Function{
+		returnsI32("v2_processTraces", 0),
+		returnsI32("v2_getSupportedTelemetry", int32(telemetryTypeTraces)),
+		returnsI32("processLogs", 0),
+	}}
+	plugin := newTestPlugin(t, mod, Config{ABI: ABIConfig{FunctionPrefixes: []string{"v2_", ""}}}, "processTraces")
+
+	want := []string{"processLogs", "v2_getSupportedTelemetry", "v2_processTraces"}
+	if got := plugin.ExportedFunctionNames(); !slices.Equal(got, want) {
+		t.Errorf("expected exports %v, got %v", want, got)
+	}
+}
+
+func TestFunctionPrefixesOptionalFunctions(t *testing.T) {
+	mod := &wasmtest.Module{Functions: []wasmtest.Function{
+		returnsI32("v2_getSupportedTelemetry", int32(telemetryTypeTraces)),
+		returnsI32("v2_processTraces", 0),
+		returnsI32("v2_otelwasm_concurrent_safe", 1),
+	}}
+	plugin := newTestPlugin(t, mod, Config{ABI: ABIConfig{FunctionPrefixes: []string{"v2_"}}}, "processTraces")
+
+	if _, ok := plugin.ExportedFunctions[concurrentSafe]; !ok {
+		t.Fatalf("expected %s to be resolved under the prefix", concurrentSafe)
+	}
+	if !plugin.ConcurrentSafe() {
+		t.Error("expected the prefixed declaration to make the guest concurrent safe")
+	}
+}
+
+// busyLoop returns an exported function spinning for the given number of
+// iterations before returning.
+func busyLoop(export string, iterations int32) wasmtest.Function {
+	return wasmtest.Function{
+		Export:  export,
+		Results: []api.ValueType{api.ValueTypeI32},
+		Locals:  []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(iterations), wasmtest.LocalSet(0),
+			wasmtest.Loop(),
+			wasmtest.LocalGet(0), wasmtest.I32Const(1), wasmtest.I32Sub, wasmtest.LocalSet(0),
+			wasmtest.LocalGet(0), wasmtest.BrIf(0),
+			wasmtest.End,
+			wasmtest.I32Const(0),
+		),
+	}
+}
+
+func TestSlowCallEvent(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), busyLoop("processTraces", 1_000_000))
+
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		wantEvent bool
+	}{
+		{name: "disabled", threshold: 0},
+		{name: "under threshold", threshold: time.Hour},
+		{name: "past threshold", threshold: time.Nanosecond, wantEvent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, mod, Config{SlowCallThreshold: tt.threshold}, "processTraces")
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			ctx, span := tp.Tracer("test").Start(t.Context(), "process")
+			if _, err := plugin.ProcessFunctionCall(ctx, "processTraces", &Stack{}); err != nil {
+				t.Fatalf("failed to call processTraces: %v", err)
+			}
+			span.End()
+
+			events := recorder.Ended()[0].Events()
+			if !tt.wantEvent {
+				if len(events) != 0 {
+					t.Errorf("expected no event, got %v", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Name != slowCallEvent {
+				t.Fatalf("expected a single %s event, got %v", slowCallEvent, events)
+			}
+			attrs := attribute.NewSet(events[0].Attributes...)
+			if v, _ := attrs.Value(slowCallFunctionAttribute); v.AsString() != "processTraces" {
+				t.Errorf("expected function processTraces, got %q", v.AsString())
+			}
+			if v, _ := attrs.Value(slowCallDurationAttribute); v.AsFloat64() <= tt.threshold.Seconds() {
+				t.Errorf("expected duration past %v, got %vs", tt.threshold, v.AsFloat64())
+			}
+		})
+	}
+}
+
+func TestExecutionTimeout(t *testing.T) {
+	spin := wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.Loop(), wasmtest.Br(0), wasmtest.End,
+			wasmtest.I32Const(0),
+		),
+	}
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), spin, returnsI32("processLogs", 0))
+
+	for _, mode := range []RuntimeMode{RuntimeModeInterpreter, RuntimeModeCompiled} {
+		t.Run(string(mode), func(t *testing.T) {
+			cfg := Config{RuntimeConfig: RuntimeConfig{Mode: mode}, ExecutionTimeout: 100 * time.Millisecond}
+			plugin := newTestPlugin(t, mod, cfg, "processTraces", "processLogs")
+
+			// Calls returning in time don't leave the timeout goroutines
+			// behind.
+			goroutines := runtime.NumGoroutine()
+			for range 10 {
+				if _, err := plugin.ProcessFunctionCall(t.Context(), "processLogs", &Stack{}); err != nil {
+					t.Fatalf("failed to call processLogs: %v", err)
+				}
+			}
+			for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines; {
+				if time.Now().After(deadline) {
+					t.Fatalf("expected %d goroutines, got %d", goroutines, runtime.NumGoroutine())
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+			var guestErr *GuestError
+			if !errors.As(err, &guestErr) || guestErr.Reason != ErrorReasonTimeout || !errors.Is(err, ErrExecutionTimeout) {
+				t.Fatalf("expected a %s guest error, got %v", ErrorReasonTimeout, err)
+			}
+			if want := "wasm: processTraces: guest exceeded execution timeout"; err.Error() != want {
+				t.Errorf("expected error %q, got %q", want, err.Error())
+			}
+
+			if _, err := plugin.ProcessFunctionCall(t.Context(), "processLogs", &Stack{}); err == nil {
+				t.Error("expected the guest to be closed after the timeout")
+			}
+		})
+	}
+}
+
+func TestGuestExitCode(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import("wasi_snapshot_preview1", "proc_exit", []api.ValueType{api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(3), mod.Call("proc_exit"),
+			wasmtest.I32Const(0),
+		),
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+
+	_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+	var guestErr *GuestError
+	if !errors.As(err, &guestErr) || guestErr.Reason != ErrorReasonExit {
+		t.Fatalf("expected a %s guest error, got %v", ErrorReasonExit, err)
+	}
+	if guestErr.ExitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", guestErr.ExitCode)
+	}
+	if want := "wasm: processTraces: guest exited with code 3"; err.Error() != want {
+		t.Errorf("expected error %q, got %q", want, err.Error())
+	}
+}
+
+func TestGuestTrapStackTrace(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces))
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body:    wasmtest.Unreachable,
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+
+	_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+	var guestErr *GuestError
+	if !errors.As(err, &guestErr) || guestErr.Reason != ErrorReasonTrap {
+		t.Fatalf("expected a %s guest error, got %v", ErrorReasonTrap, err)
+	}
+	if !strings.Contains(err.Error(), "unreachable") || !strings.Contains(err.Error(), "wasm stack trace") {
+		t.Errorf("expected the error to contain the trap and its stack trace, got %q", err.Error())
+	}
+	// The test guest has no name section, so the frame is only named by its
+	// index and signature.
+	if want := []string{".$1() i32"}; !slices.Equal(guestErr.StackTrace, want) {
+		t.Errorf("expected the stack trace %q, got %q", want, guestErr.StackTrace)
+	}
+}
+
+func TestReady(t *testing.T) {
+	if (&WasmPlugin{}).Ready() {
+		t.Fatal("expected a plugin not instantiated to be not ready")
+	}
+
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import("wasi_snapshot_preview1", "proc_exit", []api.ValueType{api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(3), mod.Call("proc_exit"),
+			wasmtest.I32Const(0),
+		),
+	})
+
+	t.Run("exit", func(t *testing.T) {
+		plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+		if !plugin.Ready() {
+			t.Fatal("expected the plugin to be ready once created")
+		}
+		plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+		if plugin.Ready() {
+			t.Error("expected the plugin to be not ready once the guest exited")
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+		if !plugin.Ready() {
+			t.Fatal("expected the plugin to be ready once created")
+		}
+		if err := plugin.Shutdown(t.Context()); err != nil {
+			t.Fatalf("failed to shut down: %v", err)
+		}
+		if plugin.Ready() {
+			t.Error("expected the plugin to be not ready once shut down")
+		}
+	})
+}
+
+func TestUpdateConfig(t *testing.T) {
+	newConfig := PluginConfig{"key": "updated"}
+	newConfigJSON := `{"key":"updated"}`
+
+	// reconfigureGuest returns a guest whose reconfigure function fails
+	// unless it reads the new config.
+	reconfigureGuest := func() *wasmtest.Module {
+		mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+			Import(wasmtest.HostModule, getPluginConfig, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32})
+		mod.Functions = append(mod.Functions, wasmtest.Function{
+			Export:  guestReconfigure,
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(64), mod.Call(getPluginConfig),
+				wasmtest.I32Const(int32(len(newConfigJSON))), wasmtest.I32Ne,
+			),
+		})
+		return mod
+	}
+
+	tests := []struct {
+		name    string
+		mod     *wasmtest.Module
+		wantErr bool
+	}{
+		{name: "not exported", mod: wasmtest.NewGuest(int32(telemetryTypeTraces))},
+		{name: "reconfigured", mod: reconfigureGuest()},
+		{name: "reconfigure failed", mod: wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32(guestReconfigure, 1)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, tt.mod, Config{})
+			module := plugin.Module
+
+			err := plugin.UpdateConfig(t.Context(), newConfig)
+			var guestErr *GuestError
+			if tt.wantErr != (errors.As(err, &guestErr) && guestErr.Function == guestReconfigure) {
+				t.Errorf("expected a reconfigure error %v, got %v", tt.wantErr, err)
+			}
+			if got := string(plugin.CurrentPluginConfigJSON()); got != newConfigJSON {
+				t.Errorf("expected the config %s, got %s", newConfigJSON, got)
+			}
+			if plugin.Module != module {
+				t.Error("expected the guest module to be kept across config updates")
+			}
+		})
+	}
+}
+
+func TestUpdatePluginConfig(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, getPluginConfigVersion, nil, []api.ValueType{api.ValueTypeI32})
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "version",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body:    wasmtest.Instructions(mod.Call(getPluginConfigVersion)),
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "version")
+
+	version := func(stack *Stack) uint64 {
+		t.Helper()
+		res, err := plugin.ProcessFunctionCall(t.Context(), "version", stack)
+		if err != nil {
+			t.Fatalf("failed to call the guest: %v", err)
+		}
+		return res[0]
+	}
+
+	if got := version(&Stack{}); got != 0 {
+		t.Errorf("expected the version 0 before any update, got %d", got)
+	}
+	// A call started before the updates keeps its snapshot.
+	inFlight := &Stack{}
+	version(inFlight)
+	for _, config := range []string{`{"key":"first"}`, `{"key":"second"}`} {
+		if err := plugin.UpdatePluginConfig(t.Context(), []byte(config)); err != nil {
+			t.Fatalf("failed to update the config: %v", err)
+		}
+	}
+	if got := version(&Stack{}); got != 2 {
+		t.Errorf("expected the version 2 after two updates, got %d", got)
+	}
+	if got := version(inFlight); got != 0 || string(inFlight.PluginConfigJSON) != "null" {
+		t.Errorf("expected the in-flight call to keep the version 0, got %d with %s", got, inFlight.PluginConfigJSON)
+	}
+
+	if err := plugin.UpdatePluginConfig(t.Context(), []byte(`{"key":`)); err == nil {
+		t.Error("expected an error for an invalid config")
+	}
+	if got := version(&Stack{}); got != 2 {
+		t.Errorf("expected the version to be kept on an invalid config, got %d", got)
+	}
+}
+
+func TestGuestLifecycle(t *testing.T) {
+	// start and shutdown count their calls at offsets 0 and 4, and shutdown
+	// stores getShutdownRequested at offset 8.
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, getShutdownRequested, nil, []api.ValueType{api.ValueTypeI32})
+	increment := func(offset uint32) []byte {
+		return wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(0), wasmtest.I32Load(offset),
+			wasmtest.I32Const(1), wasmtest.I32Add, wasmtest.I32Store(offset),
+		)
+	}
+	mod.Functions = append(mod.Functions,
+		wasmtest.Function{
+			Export:  guestStart,
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    wasmtest.Instructions(increment(0), wasmtest.I32Const(0)),
+		},
+		wasmtest.Function{
+			Export:  guestShutdown,
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body: wasmtest.Instructions(
+				increment(4),
+				wasmtest.I32Const(0), mod.Call(getShutdownRequested), wasmtest.I32Store(8),
+				wasmtest.I32Const(0),
+			),
+		},
+	)
+	plugin := newTestPlugin(t, mod, Config{})
+
+	// The guest is only shut down once started.
+	if err := plugin.ShutdownGuest(t.Context()); err != nil {
+		t.Fatalf("failed to skip shutting the guest down: %v", err)
+	}
+	if calls, _ := plugin.Module.Memory().ReadUint32Le(4); calls != 0 {
+		t.Fatalf("expected the guest not started not to be shut down, got %d calls", calls)
+	}
+
+	for range 2 {
+		if err := plugin.StartGuest(t.Context()); err != nil {
+			t.Fatalf("failed to start the guest: %v", err)
+		}
+	}
+	for range 2 {
+		if err := plugin.ShutdownGuest(t.Context()); err != nil {
+			t.Fatalf("failed to shut the guest down: %v", err)
+		}
+	}
+
+	memory := plugin.Module.Memory()
+	for _, hook := range []struct {
+		name   string
+		offset uint32
+	}{{guestStart, 0}, {guestShutdown, 4}} {
+		if calls, _ := memory.ReadUint32Le(hook.offset); calls != 1 {
+			t.Errorf("expected %s to be called once, got %d calls", hook.name, calls)
+		}
+	}
+	if requested, _ := memory.ReadUint32Le(8); requested != 1 {
+		t.Error("expected the shutdown to be requested during the guest shutdown")
+	}
+}
+
+// slowEchoGuest returns a guest whose processTraces sets the current traces
+// as result, reading the clock in between, so the stack can hold the call
+// while the traces sit in the guest memory.
+func slowEchoGuest() *wasmtest.Module {
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, currentTraces, []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, hostNow, []api.ValueType{i32}, nil).
+		Import(wasmtest.HostModule, setResultTraces, []api.ValueType{i32, i32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(0), wasmtest.I32Const(1<<15), mod.Call(currentTraces),
+			wasmtest.I32Const(1<<15), mod.Call(hostNow),
+			mod.Call(setResultTraces),
+			wasmtest.I32Const(0),
+		),
+	})
+	return mod
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	// The traces of a call sit in the guest memory while the guest reads the
+	// clock, so calls overlapping in the guest would mix up their results.
+	// Run with -race.
+	plugin := newTestPlugin(t, slowEchoGuest(), Config{}, "processTraces")
+	slowClock := func() (wall, monotonic int64) {
+		time.Sleep(time.Millisecond)
+		return SystemClock()
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				name := fmt.Sprintf("span-%d-%d", i, j)
+				traces := ptrace.NewTraces()
+				traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName(name)
+				stack := &Stack{CurrentTraces: traces, Clock: slowClock}
+				if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+					t.Errorf("failed to call processTraces: %v", err)
+					return
+				}
+				if got := stack.ResultTraces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name(); got != name {
+					t.Errorf("expected the result of the call, %s, got %s", name, got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConcurrentSafe(t *testing.T) {
+	tests := []struct {
+		name           string
+		declaration    []wasmtest.Function
+		wantConcurrent bool
+	}{
+		{name: "undeclared"},
+		{name: "declared unsafe", declaration: []wasmtest.Function{returnsI32("otelwasm_concurrent_safe", 0)}},
+		{name: "declared safe", declaration: []wasmtest.Function{returnsI32("otelwasm_concurrent_safe", 1)}, wantConcurrent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// processTraces sets empty result traces, which calls back the
+			// host so the test can block the guest call.
+			mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+				Import(wasmtest.HostModule, setResultTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+			mod.Functions = append(mod.Functions, wasmtest.Function{
+				Export:  "processTraces",
+				Results: []api.ValueType{api.ValueTypeI32},
+				Body: wasmtest.Instructions(
+					wasmtest.I32Const(0), wasmtest.I32Const(0), mod.Call(setResultTraces),
+					wasmtest.I32Const(0),
+				),
+			})
+			mod.Functions = append(mod.Functions, tt.declaration...)
+			plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+
+			if plugin.ConcurrentSafe() != tt.wantConcurrent {
+				t.Errorf("expected ConcurrentSafe() = %v", tt.wantConcurrent)
+			}
+
+			entered := make(chan struct{}, 2)
+			release := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					stack := &Stack{OnResultTracesChange: func(ptrace.Traces) {
+						entered <- struct{}{}
+						<-release
+					}}
+					if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+						t.Errorf("failed to call processTraces: %v", err)
+					}
+				}()
+			}
+
+			<-entered
+			concurrent := false
+			select {
+			case <-entered:
+				concurrent = true
+			case <-time.After(100 * time.Millisecond):
+			}
+			close(release)
+			wg.Wait()
+
+			if concurrent != tt.wantConcurrent {
+				t.Errorf("expected concurrent calls %v, got %v", tt.wantConcurrent, concurrent)
+			}
+		})
+	}
+}
+
+func TestConcurrentSafeWithoutResult(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), wasmtest.Function{Export: "otelwasm_concurrent_safe", Body: wasmtest.Nop})
+	cfg := Config{Path: mod.Write(t)}
+	cfg.Default()
+	plugin, err := NewWasmPlugin(t.Context(), &cfg, nil)
+	if err == nil {
+		plugin.Shutdown(t.Context())
+	}
+	if !errors.Is(err, ErrInvalidModule) {
+		t.Errorf("expected ErrInvalidModule, got %v", err)
+	}
+}
+
+func TestHostErrorReturned(t *testing.T) {
+	tests := []struct {
+		name string
+		// buf and size are passed to setResultTraces.
+		buf, size int32
+	}{
+		{name: "buffer out of memory", buf: 0, size: 1 << 20},
+		// The data segment below isn't valid protobuf.
+		{name: "invalid payload", buf: 0, size: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+				Import(wasmtest.HostModule, setResultTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+			mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte{0xff, 0xff}}}
+			mod.Functions = append(mod.Functions, wasmtest.Function{
+				Export:  "processTraces",
+				Results: []api.ValueType{api.ValueTypeI32},
+				Body: wasmtest.Instructions(
+					wasmtest.I32Const(tt.buf), wasmtest.I32Const(tt.size), mod.Call(setResultTraces),
+					wasmtest.I32Const(0),
+				),
+			})
+			plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+
+			stack := &Stack{}
+			_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack)
+			var guestErr *GuestError
+			if !errors.As(err, &guestErr) || guestErr.Reason != ErrorReasonHostCall {
+				t.Fatalf("expected a %s guest error, got %v", ErrorReasonHostCall, err)
+			}
+			if stack.HostError == nil {
+				t.Error("expected the host error to be recorded in the stack")
+			}
+			if stack.ResultTraces != (ptrace.Traces{}) {
+				t.Error("expected no result traces")
+			}
+		})
+	}
+}
+
+func TestGetEnv(t *testing.T) {
+	t.Setenv("OTELWASM_TEST_ALLOWED", "allowed value")
+	t.Setenv("OTELWASM_TEST_HIDDEN", "hidden value")
+
+	// processTraces looks up the variable named in the data segment, echoes
+	// its value as the status reason and returns the result of getEnv.
+	const bufOffset = 1024
+	newGuest := func(variable string, bufOffset int32) *wasmtest.Module {
+		mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+			Import(wasmtest.HostModule, getEnv, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
+			Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+		mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte(variable)}}
+		mod.Functions = append(mod.Functions, wasmtest.Function{
+			Export:  "processTraces",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Locals:  []api.ValueType{api.ValueTypeI32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(int32(len(variable))),
+				wasmtest.I32Const(bufOffset), wasmtest.I32Const(1024), mod.Call(getEnv),
+				wasmtest.LocalSet(0),
+				wasmtest.LocalGet(0), wasmtest.I32Const(-1), wasmtest.I32Ne, wasmtest.If(),
+				wasmtest.I32Const(bufOffset), wasmtest.LocalGet(0), mod.Call(setResultStatusReason),
+				wasmtest.End,
+				wasmtest.LocalGet(0),
+			),
+		})
+		return mod
+	}
+
+	tests := []struct {
+		variable   string
+		wantStatus uint32
+		wantValue  string
+	}{
+		{variable: "OTELWASM_TEST_ALLOWED", wantStatus: uint32(len("allowed value")), wantValue: "allowed value"},
+		{variable: "OTELWASM_TEST_HIDDEN", wantStatus: envNotFound},
+		{variable: "OTELWASM_TEST_UNSET", wantStatus: envNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.variable, func(t *testing.T) {
+			cfg := Config{EnvAllowlist: []string{"OTELWASM_TEST_ALLOWED", "OTELWASM_TEST_UNSET"}}
+			plugin := newTestPlugin(t, newGuest(tt.variable, bufOffset), cfg, "processTraces")
+
+			stack := &Stack{}
+			res, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack)
+			if err != nil {
+				t.Fatalf("failed to call processTraces: %v", err)
+			}
+			if got := uint32(res[0]); got != tt.wantStatus {
+				t.Errorf("expected getEnv to return %d, got %d", tt.wantStatus, got)
+			}
+			if stack.StatusReason != tt.wantValue {
+				t.Errorf("expected value %q, got %q", tt.wantValue, stack.StatusReason)
+			}
+		})
+	}
+
+	t.Run("passthrough", func(t *testing.T) {
+		cfg := Config{EnvAllowlist: []string{"OTELWASM_TEST_ALLOWED"}, EnvPassthrough: true}
+		plugin := newTestPlugin(t, newGuest("OTELWASM_TEST_HIDDEN", bufOffset), cfg, "processTraces")
+
+		stack := &Stack{}
+		if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+			t.Fatalf("failed to call processTraces: %v", err)
+		}
+		if stack.StatusReason != "hidden value" {
+			t.Errorf("expected the variable outside the allowlist to be passed through, got %q", stack.StatusReason)
+		}
+	})
+
+	t.Run("buffer out of memory", func(t *testing.T) {
+		cfg := Config{EnvAllowlist: []string{"OTELWASM_TEST_ALLOWED"}}
+		plugin := newTestPlugin(t, newGuest("OTELWASM_TEST_ALLOWED", 1<<30), cfg, "processTraces")
+
+		_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+		if !errors.Is(err, errOutOfMemory) {
+			t.Errorf("expected errOutOfMemory, got %v", err)
+		}
+	})
+}
+
+func TestCurrentTracesChunk(t *testing.T) {
+	// processTraces reads the current traces in 7 byte frames, then sets them
+	// as the result traces.
+	const (
+		bufOffset = 1024
+		frameSize = 7
+	)
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, currentTracesChunk, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
+		Import(wasmtest.HostModule, setResultTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		// Local 0 is the offset of the next frame, local 1 the payload size.
+		Locals: []api.ValueType{api.ValueTypeI32, api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.Loop(),
+			wasmtest.LocalGet(0),
+			wasmtest.LocalGet(0), wasmtest.I32Const(bufOffset), wasmtest.I32Add,
+			wasmtest.I32Const(frameSize), mod.Call(currentTracesChunk), wasmtest.LocalSet(1),
+			wasmtest.LocalGet(0), wasmtest.I32Const(frameSize), wasmtest.I32Add, wasmtest.LocalSet(0),
+			wasmtest.LocalGet(0), wasmtest.LocalGet(1), wasmtest.I32LtU, wasmtest.BrIf(0),
+			wasmtest.End,
+			wasmtest.I32Const(bufOffset), wasmtest.LocalGet(1), mod.Call(setResultTraces),
+			wasmtest.I32Const(0),
+		),
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+
+	traces := ptrace.NewTraces()
+	spans := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for _, name := range []string{"first", "second", "third"} {
+		spans.AppendEmpty().SetName(name)
+	}
+	stack := &Stack{CurrentTraces: traces}
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+		t.Fatalf("failed to call processTraces: %v", err)
+	}
+
+	if len(stack.currentTracesProto) <= frameSize {
+		t.Fatalf("expected a payload spanning several frames, got %d bytes", len(stack.currentTracesProto))
+	}
+	got := stack.ResultTraces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	if got.Len() != 3 || got.At(2).Name() != "third" {
+		t.Errorf("expected the reassembled traces to match, got %d spans", got.Len())
+	}
+}
+
+// newCurrentTracesRetryPlugin returns a plugin whose processTraces reads the
+// current traces with an empty buffer first, then with a buffer of the size
+// returned by the host, and sets them as the result traces.
+func newCurrentTracesRetryPlugin(tb testing.TB) *WasmPlugin {
+	const bufOffset = 1024
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, currentTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
+		Import(wasmtest.HostModule, setResultTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Locals:  []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(bufOffset), wasmtest.I32Const(0), mod.Call(currentTraces), wasmtest.LocalSet(0),
+			wasmtest.I32Const(bufOffset), wasmtest.LocalGet(0), mod.Call(currentTraces), wasmtest.LocalSet(0),
+			wasmtest.I32Const(bufOffset), wasmtest.LocalGet(0), mod.Call(setResultTraces),
+			wasmtest.I32Const(0),
+		),
+	})
+	return newTestPlugin(tb, mod, Config{}, "processTraces")
+}
+
+// countTracesMarshals counts the traces marshaled by the host until the end
+// of the test.
+func countTracesMarshals(tb testing.TB) *int {
+	var n int
+	marshal := marshalTraces
+	marshalTraces = func(td ptrace.Traces) ([]byte, error) {
+		n++
+		return marshal(td)
+	}
+	tb.Cleanup(func() { marshalTraces = marshal })
+	return &n
+}
+
+func TestCurrentTracesRetry(t *testing.T) {
+	plugin := newCurrentTracesRetryPlugin(t)
+	marshals := countTracesMarshals(t)
+
+	stack := &Stack{}
+	for _, name := range []string{"first", "second"} {
+		traces := ptrace.NewTraces()
+		traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName(name)
+		stack.CurrentTraces = traces
+		if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+			t.Fatalf("failed to call processTraces: %v", err)
+		}
+
+		// The stack is reused, so a stale cache would return the traces of
+		// the previous call.
+		if got := stack.ResultTraces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name(); got != name {
+			t.Errorf("expected the result span %q, got %q", name, got)
+		}
+	}
+	if *marshals != 2 {
+		t.Errorf("expected a marshal per call, got %d marshals for 2 calls", *marshals)
+	}
+}
+
+func TestCurrentTracesMarshalError(t *testing.T) {
+	plugin := newCurrentTracesRetryPlugin(t)
+	errMarshal := errors.New("marshal failed")
+	marshal := marshalTraces
+	marshalTraces = func(ptrace.Traces) ([]byte, error) { return nil, errMarshal }
+	t.Cleanup(func() { marshalTraces = marshal })
+
+	_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{CurrentTraces: ptrace.NewTraces()})
+	if !errors.Is(err, errMarshal) {
+		t.Errorf("expected the marshal error as host error, got %v", err)
+	}
+}
+
+func TestCurrentProfilesMarshalError(t *testing.T) {
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeProfiles)).
+		Import(wasmtest.HostModule, currentProfiles, []api.ValueType{i32, i32}, []api.ValueType{i32})
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processProfiles",
+		Results: []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(1<<16), mod.Call(currentProfiles), wasmtest.Drop,
+			wasmtest.I32Const(0),
+		),
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "processProfiles")
+	errMarshal := errors.New("marshal failed")
+	marshal := marshalProfiles
+	marshalProfiles = func(pprofile.Profiles) ([]byte, error) { return nil, errMarshal }
+	t.Cleanup(func() { marshalProfiles = marshal })
+
+	_, err := plugin.ProcessFunctionCall(t.Context(), "processProfiles", &Stack{CurrentProfiles: pprofile.NewProfiles()})
+	if !errors.Is(err, errMarshal) {
+		t.Errorf("expected the marshal error as host error, got %v", err)
+	}
+}
+
+func BenchmarkCurrentTracesRetry(b *testing.B) {
+	plugin := newCurrentTracesRetryPlugin(b)
+	marshals := countTracesMarshals(b)
+
+	traces := ptrace.NewTraces()
+	spans := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for range 100 {
+		spans.AppendEmpty().SetName("span")
+	}
+	stack := &Stack{CurrentTraces: traces}
+	for b.Loop() {
+		if _, err := plugin.ProcessFunctionCall(b.Context(), "processTraces", stack); err != nil {
+			b.Fatalf("failed to call processTraces: %v", err)
+		}
+	}
+	b.ReportMetric(float64(*marshals)/float64(b.N), "marshals/op")
+}
+
+func TestAppendResultTraces(t *testing.T) {
+	// processTraces appends the current traces twice to the result batches.
+	const bufOffset = 1024
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, currentTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
+		Import(wasmtest.HostModule, appendResultTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Locals:  []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(bufOffset), wasmtest.I32Const(60000), mod.Call(currentTraces), wasmtest.LocalSet(0),
+			wasmtest.I32Const(bufOffset), wasmtest.LocalGet(0), mod.Call(appendResultTraces),
+			wasmtest.I32Const(bufOffset), wasmtest.LocalGet(0), mod.Call(appendResultTraces),
+			wasmtest.I32Const(0),
+		),
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+	stack := &Stack{CurrentTraces: traces}
+	if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+		t.Fatalf("failed to call processTraces: %v", err)
+	}
+
+	if len(stack.ResultTracesBatches) != 2 {
+		t.Fatalf("expected 2 result batches, got %d", len(stack.ResultTracesBatches))
+	}
+	for i, batch := range stack.ResultTracesBatches {
+		if batch.SpanCount() != 1 {
+			t.Errorf("expected batch %d to hold the span, got %d spans", i, batch.SpanCount())
+		}
+	}
+}
+
+func TestEmitTracesUnsupported(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, emitTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32})
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(0), mod.Call(emitTraces),
+		),
+	})
+	plugin := newTestPlugin(t, mod, Config{}, "processTraces")
+
+	// Processors don't set Stack.EmitTraces.
+	_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+	if !errors.Is(err, errEmitUnsupported) {
+		t.Errorf("expected %v, got %v", errEmitUnsupported, err)
+	}
+}
@@ -0,0 +1,135 @@
+package wasmplugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QuotaAction is what happens to a guest exceeding its quota.
+type QuotaAction string
+
+const (
+	// QuotaActionThrottle rejects the calls to the guest until the end of the
+	// current quota window.
+	QuotaActionThrottle QuotaAction = "throttle"
+
+	// QuotaActionDisable rejects all the subsequent calls to the guest.
+	QuotaActionDisable QuotaAction = "disable"
+)
+
+// QuotaConfig is the configuration of the resources a guest may consume per
+// window. Quotas are disabled unless at least one limit is set.
+type QuotaConfig struct {
+	// Window is the duration over which the consumption is accounted.
+	// The default is one minute.
+	Window time.Duration `mapstructure:"window,omitempty"`
+
+	// MaxCalls is the maximum number of guest function calls per window.
+	MaxCalls int64 `mapstructure:"max_calls,omitempty"`
+
+	// MaxMemoryPageSeconds is the maximum guest memory usage per window,
+	// measured as the number of 64KiB pages of the guest memory multiplied by
+	// the duration of the calls in seconds.
+	MaxMemoryPageSeconds float64 `mapstructure:"max_memory_page_seconds,omitempty"`
+
+	// Action is applied when the guest exceeds its quota.
+	// The default is "throttle".
+	Action QuotaAction `mapstructure:"action,omitempty"`
+}
+
+func (cfg *QuotaConfig) Validate() error {
+	if cfg.Window < 0 {
+		return fmt.Errorf("invalid quota window: %s", cfg.Window)
+	}
+	if cfg.MaxCalls < 0 || cfg.MaxMemoryPageSeconds < 0 {
+		return fmt.Errorf("quota limits must not be negative")
+	}
+	if cfg.Action != "" && cfg.Action != QuotaActionThrottle && cfg.Action != QuotaActionDisable {
+		return fmt.Errorf("invalid quota action: %s", cfg.Action)
+	}
+	return nil
+}
+
+// Default sets the default values for the quota configuration
+// if they are not set.
+func (cfg *QuotaConfig) Default() {
+	if cfg.Window == 0 {
+		cfg.Window = DefaultQuotaConfig.Window
+	}
+	if cfg.Action == "" {
+		cfg.Action = DefaultQuotaConfig.Action
+	}
+}
+
+// DefaultQuotaConfig is the default configuration for the guest quotas.
+var DefaultQuotaConfig = QuotaConfig{
+	Window: time.Minute,
+	Action: QuotaActionThrottle,
+}
+
+// quota accounts the resources consumed by a guest and enforces its limits.
+type quota struct {
+	cfg QuotaConfig
+	now func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	calls       int64
+	pageSeconds float64
+	disabled    bool
+}
+
+// newQuota returns the quota of the given configuration, or nil if it
+// doesn't set any limit.
+func newQuota(cfg QuotaConfig) *quota {
+	if cfg.MaxCalls == 0 && cfg.MaxMemoryPageSeconds == 0 {
+		return nil
+	}
+	cfg.Default()
+	return &quota{cfg: cfg, now: time.Now, windowStart: time.Now()}
+}
+
+// admit returns an error if the guest must not be called. Otherwise the call
+// is accounted right away, so concurrent calls can't exceed MaxCalls.
+func (q *quota) admit() error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.disabled {
+		return ErrGuestDisabled
+	}
+	if now := q.now(); now.Sub(q.windowStart) >= q.cfg.Window {
+		q.windowStart = now
+		q.calls = 0
+		q.pageSeconds = 0
+	}
+	if !q.exceeded() {
+		q.calls++
+		return nil
+	}
+	if q.cfg.Action == QuotaActionDisable {
+		q.disabled = true
+		return ErrGuestDisabled
+	}
+	return ErrQuotaExceeded
+}
+
+func (q *quota) exceeded() bool {
+	return (q.cfg.MaxCalls > 0 && q.calls >= q.cfg.MaxCalls) ||
+		(q.cfg.MaxMemoryPageSeconds > 0 && q.pageSeconds >= q.cfg.MaxMemoryPageSeconds)
+}
+
+// record accounts the memory of an admitted call that lasted elapsed with
+// the given number of memory pages.
+func (q *quota) record(pages uint32, elapsed time.Duration) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pageSeconds += float64(pages) * elapsed.Seconds()
+}
@@ -0,0 +1,153 @@
+package wasmplugin
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestQuotaConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  QuotaConfig
+		wantErr bool
+	}{
+		{name: "disabled", config: QuotaConfig{}},
+		{name: "valid", config: QuotaConfig{Window: time.Second, MaxCalls: 10, Action: QuotaActionDisable}},
+		{name: "negative window", config: QuotaConfig{Window: -time.Second}, wantErr: true},
+		{name: "negative limit", config: QuotaConfig{MaxMemoryPageSeconds: -1}, wantErr: true},
+		{name: "invalid action", config: QuotaConfig{Action: "invalid"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("QuotaConfig.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuota(t *testing.T) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("processTraces", 0))
+
+	tests := []struct {
+		name string
+		cfg  QuotaConfig
+		// wantErrs are the errors expected from each call in the first window.
+		wantErrs []error
+		// wantAfterWindow is the error expected once the window elapsed.
+		wantAfterWindow error
+	}{
+		{
+			name:            "calls throttled",
+			cfg:             QuotaConfig{MaxCalls: 2},
+			wantErrs:        []error{nil, nil, ErrQuotaExceeded, ErrQuotaExceeded},
+			wantAfterWindow: nil,
+		},
+		{
+			name:            "calls disabled",
+			cfg:             QuotaConfig{MaxCalls: 2, Action: QuotaActionDisable},
+			wantErrs:        []error{nil, nil, ErrGuestDisabled, ErrGuestDisabled},
+			wantAfterWindow: ErrGuestDisabled,
+		},
+		{
+			// Any call consumes more than this, so only the first one is
+			// admitted.
+			name:            "memory throttled",
+			cfg:             QuotaConfig{MaxMemoryPageSeconds: 1e-15},
+			wantErrs:        []error{nil, ErrQuotaExceeded},
+			wantAfterWindow: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, mod, Config{Quota: tt.cfg}, "processTraces")
+			now := time.Now()
+			plugin.quota.now = func() time.Time { return now }
+
+			call := func() error {
+				_, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", &Stack{})
+				return err
+			}
+			for i, want := range tt.wantErrs {
+				if err := call(); !errors.Is(err, want) {
+					t.Errorf("call %d: expected %v, got %v", i, want, err)
+				}
+			}
+
+			// Built-in functions aren't accounted, so they keep working.
+			if _, err := plugin.IsTracesSupported(t.Context()); err != nil {
+				t.Errorf("failed to get supported telemetry: %v", err)
+			}
+
+			now = now.Add(DefaultQuotaConfig.Window)
+			if err := call(); !errors.Is(err, tt.wantAfterWindow) {
+				t.Errorf("after window: expected %v, got %v", tt.wantAfterWindow, err)
+			}
+		})
+	}
+}
+
+func TestQuotaConcurrentCalls(t *testing.T) {
+	// processTraces sets empty result traces, which calls back the host so
+	// the admitted calls are held in the guest until all calls were made.
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("otelwasm_concurrent_safe", 1)).
+		Import(wasmtest.HostModule, setResultTraces, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "processTraces",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(0), mod.Call(setResultTraces),
+			wasmtest.I32Const(0),
+		),
+	})
+	plugin := newTestPlugin(t, mod, Config{Quota: QuotaConfig{MaxCalls: 3}}, "processTraces")
+
+	const calls = 10
+	// done receives whether each call was admitted, once it is held in the
+	// guest or rejected.
+	done := make(chan bool, calls)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stack := &Stack{OnResultTracesChange: func(ptrace.Traces) {
+				done <- true
+				<-release
+			}}
+			if _, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack); err != nil {
+				done <- false
+			}
+		}()
+	}
+
+	admitted := 0
+	for range calls {
+		if <-done {
+			admitted++
+		}
+	}
+	close(release)
+	wg.Wait()
+	if admitted != 3 {
+		t.Errorf("expected 3 calls admitted, got %d", admitted)
+	}
+}
+
+func TestNewQuotaDefaults(t *testing.T) {
+	// The component factories don't default the quota configuration.
+	q := newQuota(QuotaConfig{MaxCalls: 1})
+	if q.cfg.Window != DefaultQuotaConfig.Window || q.cfg.Action != DefaultQuotaConfig.Action {
+		t.Errorf("expected the default window and action, got %+v", q.cfg)
+	}
+}
@@ -0,0 +1,35 @@
+package wasmplugin
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// newRandUint64Fn returns the randUint64 host function, returning random
+// numbers from a cryptographically secure source, or from a pseudo-random
+// generator seeded with seed if it isn't zero. The seeded generator is
+// created with the guest, so each instance of a guest draws the same
+// sequence.
+func newRandUint64Fn(seed uint64) api.GoModuleFunc {
+	if seed == 0 {
+		return func(ctx context.Context, mod api.Module, stack []uint64) {
+			var b [8]byte
+			cryptorand.Read(b[:])
+			stack[0] = binary.LittleEndian.Uint64(b[:])
+		}
+	}
+
+	var mu sync.Mutex
+	generator := rand.New(rand.NewPCG(seed, seed))
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		// Guests safe for concurrent calls draw concurrently.
+		mu.Lock()
+		defer mu.Unlock()
+		stack[0] = generator.Uint64()
+	}
+}
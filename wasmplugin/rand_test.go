@@ -0,0 +1,51 @@
+package wasmplugin
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// draw returns numbers drawn with randUint64 by a new guest seeded with seed.
+func draw(t *testing.T, seed uint64, n int) []uint64 {
+	t.Helper()
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces)).
+		Import(wasmtest.HostModule, randUint64, nil, []api.ValueType{api.ValueTypeI64})
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "rand",
+		Results: []api.ValueType{api.ValueTypeI64},
+		Body:    mod.Call(randUint64),
+	})
+	plugin := newTestPlugin(t, mod, Config{RandSeed: seed}, "rand")
+
+	numbers := make([]uint64, n)
+	for i := range numbers {
+		res, err := plugin.ProcessFunctionCall(t.Context(), "rand", &Stack{})
+		if err != nil {
+			t.Fatalf("failed to call the guest: %v", err)
+		}
+		numbers[i] = res[0]
+	}
+	return numbers
+}
+
+func TestRandUint64Seeded(t *testing.T) {
+	first := draw(t, 42, 8)
+	if second := draw(t, 42, 8); !slices.Equal(first, second) {
+		t.Errorf("expected guests of the same seed to draw the same numbers, got %v and %v", first, second)
+	}
+	if other := draw(t, 43, 8); slices.Equal(first, other) {
+		t.Errorf("expected guests of different seeds to draw different numbers, got %v", first)
+	}
+	if len(slices.Compact(slices.Clone(first))) == 1 {
+		t.Errorf("expected distinct numbers, got %v", first)
+	}
+}
+
+func TestRandUint64Unseeded(t *testing.T) {
+	if first, second := draw(t, 0, 8), draw(t, 0, 8); slices.Equal(first, second) {
+		t.Errorf("expected unseeded guests to draw different numbers, got %v twice", first)
+	}
+}
@@ -0,0 +1,36 @@
+package wasmplugin
+
+import "sync"
+
+// stackPool holds the stacks released by the components, so the hot paths
+// don't allocate a stack per call.
+var stackPool = sync.Pool{
+	New: func() any { return new(Stack) },
+}
+
+// AcquireStack returns an empty stack from the pool. The stack must be
+// passed to a single call at a time, and released with ReleaseStack once
+// the call returned and its results were read. It must not be used after
+// being released.
+//
+// Long-lived stacks, such as the one of a receiver, are allocated rather
+// than pooled.
+func AcquireStack() *Stack {
+	return stackPool.Get().(*Stack)
+}
+
+// ReleaseStack resets s and returns it to the pool.
+func ReleaseStack(s *Stack) {
+	s.Reset()
+	stackPool.Put(s)
+}
+
+// Reset clears the stack, including its callbacks and RequestedShutdown, so
+// nothing of a call leaks into the next one. The capacity of
+// ResultTracesBatches is kept.
+func (s *Stack) Reset() {
+	batches := s.ResultTracesBatches
+	clear(batches)
+	*s = Stack{}
+	s.ResultTracesBatches = batches[:0]
+}
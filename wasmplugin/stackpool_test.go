@@ -0,0 +1,87 @@
+package wasmplugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestStackReset(t *testing.T) {
+	s := &Stack{
+		CurrentTraces:         ptrace.NewTraces(),
+		ResultTraces:          ptrace.NewTraces(),
+		StatusReason:          "failed",
+		OnResultTracesChange:  func(ptrace.Traces) {},
+		OnResultMetricsChange: func(pmetric.Metrics) {},
+		EmitTraces:            func(ptrace.Traces) error { return nil },
+		ResultTracesBatches:   []ptrace.Traces{ptrace.NewTraces(), ptrace.NewTraces()},
+		PluginConfigJSON:      []byte("{}"),
+		Bag:                   &Bag{},
+		HostError:             errors.New("host error"),
+	}
+	s.RequestedShutdown.Store(true)
+	s.inputSize, s.outputSize = 1, 2
+	batches := s.ResultTracesBatches
+
+	s.Reset()
+	if s.RequestedShutdown.Load() {
+		t.Error("expected RequestedShutdown to be reset")
+	}
+	if s.CurrentTraces != (ptrace.Traces{}) || s.ResultTraces != (ptrace.Traces{}) || s.StatusReason != "" ||
+		s.OnResultTracesChange != nil || s.OnResultMetricsChange != nil || s.EmitTraces != nil ||
+		s.PluginConfigJSON != nil || s.Bag != nil || s.HostError != nil || s.inputSize != 0 || s.outputSize != 0 {
+		t.Errorf("expected an empty stack, got %+v", s)
+	}
+	if len(s.ResultTracesBatches) != 0 || cap(s.ResultTracesBatches) != 2 {
+		t.Errorf("expected the batches to be emptied, keeping their capacity, got %d of %d", len(s.ResultTracesBatches), cap(s.ResultTracesBatches))
+	}
+	// The released batches aren't retained.
+	if batches[0] != (ptrace.Traces{}) {
+		t.Error("expected the batches to be cleared")
+	}
+}
+
+func TestAcquireStack(t *testing.T) {
+	s := AcquireStack()
+	s.StatusReason = "failed"
+	s.RequestedShutdown.Store(true)
+	ReleaseStack(s)
+
+	for range 10 {
+		s := AcquireStack()
+		if s.StatusReason != "" || s.RequestedShutdown.Load() {
+			t.Fatalf("expected an empty stack, got %+v", s)
+		}
+		defer ReleaseStack(s)
+	}
+}
+
+func BenchmarkStack(b *testing.B) {
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces), returnsI32("processTraces", 0))
+	plugin := newTestPlugin(b, mod, Config{}, "processTraces")
+	traces := ptrace.NewTraces()
+
+	b.Run("allocated", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			stack := &Stack{CurrentTraces: traces}
+			if _, err := plugin.ProcessFunctionCall(b.Context(), "processTraces", stack); err != nil {
+				b.Fatalf("failed to call processTraces: %v", err)
+			}
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			stack := AcquireStack()
+			stack.CurrentTraces = traces
+			if _, err := plugin.ProcessFunctionCall(b.Context(), "processTraces", stack); err != nil {
+				b.Fatalf("failed to call processTraces: %v", err)
+			}
+			ReleaseStack(stack)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package wasmplugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+)
+
+// StateConfig is the configuration of the key-value store the guest keeps its
+// state in across calls, through the kvGet and kvSet host functions.
+type StateConfig struct {
+	// Path is the file the store is persisted to, so the state survives
+	// restarts, e.g. the cursor of a receiver. The store is kept in memory
+	// only if empty.
+	Path string `mapstructure:"path,omitempty"`
+
+	// MaxEntries is the maximum number of keys of the store.
+	// The default is 1024.
+	MaxEntries int `mapstructure:"max_entries,omitempty"`
+
+	// MaxValueSize is the maximum size of a value, in bytes.
+	// The default is 64KiB.
+	MaxValueSize int `mapstructure:"max_value_size,omitempty"`
+}
+
+func (cfg *StateConfig) Validate() error {
+	if cfg.MaxEntries < 0 || cfg.MaxValueSize < 0 {
+		return fmt.Errorf("state limits must not be negative")
+	}
+	return nil
+}
+
+// Default sets the default values for the state configuration
+// if they are not set.
+func (cfg *StateConfig) Default() {
+	if cfg.MaxEntries == 0 {
+		cfg.MaxEntries = DefaultStateConfig.MaxEntries
+	}
+	if cfg.MaxValueSize == 0 {
+		cfg.MaxValueSize = DefaultStateConfig.MaxValueSize
+	}
+}
+
+// DefaultStateConfig is the default configuration for the guest state.
+var DefaultStateConfig = StateConfig{
+	MaxEntries:   1024,
+	MaxValueSize: 64 << 10,
+}
+
+// Status codes returned by kvSet.
+const (
+	kvSetOK uint32 = iota
+	// kvSetLimitExceeded means the value is too large, or the store is
+	// full.
+	kvSetLimitExceeded
+	// kvSetPersistFailed means the store couldn't be written to its file.
+	// The value is set in memory regardless.
+	kvSetPersistFailed
+)
+
+// kvNotFound is returned by kvGet if the key isn't set.
+const kvNotFound = math.MaxUint32
+
+var errStateLimitExceeded = errors.New("state limit exceeded")
+
+// kvStore is the key-value store of the guest state. It is shared by the
+// concurrent calls of the guest: each operation is atomic, and the last write
+// of a key wins.
+type kvStore struct {
+	cfg StateConfig
+
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+// newKVStore returns the store of cfg, loading its file if it exists.
+func newKVStore(cfg StateConfig) (*kvStore, error) {
+	cfg.Default()
+	s := &kvStore{cfg: cfg, entries: make(map[string][]byte)}
+	if cfg.Path == "" {
+		return s, nil
+	}
+	b, err := os.ReadFile(cfg.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("wasm: error reading state: %w", err)
+	}
+	if err := json.Unmarshal(b, &s.entries); err != nil {
+		return nil, fmt.Errorf("wasm: error decoding state %s: %w", cfg.Path, err)
+	}
+	return s, nil
+}
+
+// get returns the value of key.
+func (s *kvStore) get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.entries[key]
+	return value, ok
+}
+
+// set sets the value of key, or deletes key if value is empty, and persists
+// the store.
+func (s *kvStore) set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(value) == 0 {
+		if _, ok := s.entries[key]; !ok {
+			return nil
+		}
+		delete(s.entries, key)
+		return s.persist()
+	}
+	if len(value) > s.cfg.MaxValueSize {
+		return fmt.Errorf("%w: value of %d bytes", errStateLimitExceeded, len(value))
+	}
+	if _, ok := s.entries[key]; !ok && len(s.entries) >= s.cfg.MaxEntries {
+		return fmt.Errorf("%w: %d entries", errStateLimitExceeded, len(s.entries))
+	}
+	// The guest memory backing value is reused, so the value is copied.
+	s.entries[key] = append([]byte(nil), value...)
+	return s.persist()
+}
+
+// persist writes the store to its file, replacing it atomically so a crash
+// doesn't leave a truncated state. Only called with mu held.
+func (s *kvStore) persist() error {
+	if s.cfg.Path == "" {
+		return nil
+	}
+	b, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.cfg.Path), filepath.Base(s.cfg.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.cfg.Path)
+}
+
+// newKVGetFn returns the kvGet host function reading store.
+func newKVGetFn(store *kvStore) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		key := uint32(stack[0])
+		keyLen := uint32(stack[1])
+		buf := uint32(stack[2])
+		bufLimit := uint32(stack[3])
+
+		keyBytes, ok := mod.Memory().Read(key, keyLen)
+		if !ok {
+			paramsFromContext(ctx).recordHostError(kvGet, errOutOfMemory)
+			stack[0] = kvNotFound
+			return
+		}
+
+		value, ok := store.get(string(keyBytes))
+		if !ok {
+			stack[0] = kvNotFound
+			return
+		}
+		// The length is returned even if the value doesn't fit, so the guest
+		// can retry with a large enough buffer.
+		if uint32(len(value)) <= bufLimit {
+			mod.Memory().Write(buf, value)
+		}
+		stack[0] = uint64(len(value))
+	}
+}
+
+// newKVSetFn returns the kvSet host function writing store.
+func newKVSetFn(store *kvStore, logger *zap.Logger) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		key := uint32(stack[0])
+		keyLen := uint32(stack[1])
+		value := uint32(stack[2])
+		valueLen := uint32(stack[3])
+
+		keyBytes, ok := mod.Memory().Read(key, keyLen)
+		if !ok {
+			paramsFromContext(ctx).recordHostError(kvSet, errOutOfMemory)
+			stack[0] = uint64(kvSetLimitExceeded)
+			return
+		}
+		valueBytes, ok := mod.Memory().Read(value, valueLen)
+		if !ok {
+			paramsFromContext(ctx).recordHostError(kvSet, errOutOfMemory)
+			stack[0] = uint64(kvSetLimitExceeded)
+			return
+		}
+
+		err := store.set(string(keyBytes), valueBytes)
+		switch {
+		case err == nil:
+			stack[0] = uint64(kvSetOK)
+		case errors.Is(err, errStateLimitExceeded):
+			stack[0] = uint64(kvSetLimitExceeded)
+		default:
+			if logger != nil {
+				logger.Warn("Failed to persist guest state", zap.Error(err))
+			}
+			stack[0] = uint64(kvSetPersistFailed)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package wasmplugin
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// stateGuest returns a guest whose processTraces sets "cursor" to "obj-42"
+// and returns the status of kvSet, and whose processLogs echoes the value of
+// "cursor" as the status reason and returns the result of kvGet.
+func stateGuest() *wasmtest.Module {
+	const (
+		key       = "cursor"
+		value     = "obj-42"
+		bufOffset = 1024
+	)
+	i32 := api.ValueTypeI32
+	mod := wasmtest.NewGuest(int32(telemetryTypeTraces|telemetryTypeLogs)).
+		Import(wasmtest.HostModule, kvGet, []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, kvSet, []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, setResultStatusReason, []api.ValueType{i32, i32}, nil)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte(key)}, {Offset: 16, Bytes: []byte(value)}}
+	mod.Functions = append(mod.Functions,
+		wasmtest.Function{
+			Export:  "processTraces",
+			Results: []api.ValueType{i32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(int32(len(key))),
+				wasmtest.I32Const(16), wasmtest.I32Const(int32(len(value))),
+				mod.Call(kvSet),
+			),
+		},
+		wasmtest.Function{
+			Export:  "processLogs",
+			Results: []api.ValueType{i32},
+			Locals:  []api.ValueType{i32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(int32(len(key))),
+				wasmtest.I32Const(bufOffset), wasmtest.I32Const(1024), mod.Call(kvGet),
+				wasmtest.LocalSet(0),
+				wasmtest.LocalGet(0), wasmtest.I32Const(-1), wasmtest.I32Ne, wasmtest.If(),
+				wasmtest.I32Const(bufOffset), wasmtest.LocalGet(0), mod.Call(setResultStatusReason),
+				wasmtest.End,
+				wasmtest.LocalGet(0),
+			),
+		},
+	)
+	return mod
+}
+
+// call calls the given function of plugin, and returns its result and status
+// reason.
+func call(t *testing.T, plugin *WasmPlugin, function string) (uint32, string) {
+	t.Helper()
+	stack := &Stack{}
+	res, err := plugin.ProcessFunctionCall(t.Context(), function, stack)
+	if err != nil {
+		t.Fatalf("failed to call %s: %v", function, err)
+	}
+	return uint32(res[0]), stack.StatusReason
+}
+
+func TestStateAcrossCalls(t *testing.T) {
+	plugin := newTestPlugin(t, stateGuest(), Config{}, "processTraces", "processLogs")
+
+	if res, _ := call(t, plugin, "processLogs"); res != kvNotFound {
+		t.Fatalf("expected the key to be unset, got %d", res)
+	}
+	if res, _ := call(t, plugin, "processTraces"); res != kvSetOK {
+		t.Fatalf("expected kvSet to succeed, got %d", res)
+	}
+	if _, value := call(t, plugin, "processLogs"); value != "obj-42" {
+		t.Errorf("expected the value set by the previous call, got %q", value)
+	}
+}
+
+func TestStatePersistedAcrossRestarts(t *testing.T) {
+	mod := stateGuest()
+	cfg := Config{State: StateConfig{Path: filepath.Join(t.TempDir(), "state.json")}}
+
+	first := newTestPlugin(t, mod, cfg, "processTraces", "processLogs")
+	if res, _ := call(t, first, "processTraces"); res != kvSetOK {
+		t.Fatalf("expected kvSet to succeed, got %d", res)
+	}
+	if err := first.Shutdown(t.Context()); err != nil {
+		t.Fatalf("failed to shutdown plugin: %v", err)
+	}
+
+	second := newTestPlugin(t, mod, cfg, "processTraces", "processLogs")
+	if _, value := call(t, second, "processLogs"); value != "obj-42" {
+		t.Errorf("expected the value persisted by the previous plugin, got %q", value)
+	}
+}
+
+func TestStateValueSizeLimit(t *testing.T) {
+	cfg := Config{State: StateConfig{MaxValueSize: 3}}
+	plugin := newTestPlugin(t, stateGuest(), cfg, "processTraces", "processLogs")
+
+	if res, _ := call(t, plugin, "processTraces"); res != kvSetLimitExceeded {
+		t.Fatalf("expected kvSet to exceed the limit, got %d", res)
+	}
+	if res, _ := call(t, plugin, "processLogs"); res != kvNotFound {
+		t.Errorf("expected the key to be unset, got %d", res)
+	}
+}
+
+func TestKVStoreEntriesLimit(t *testing.T) {
+	store, err := newKVStore(StateConfig{MaxEntries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.set("first", []byte("1")); err != nil {
+		t.Fatalf("failed to set the first key: %v", err)
+	}
+	if err := store.set("second", []byte("2")); !errors.Is(err, errStateLimitExceeded) {
+		t.Fatalf("expected the store to be full, got %v", err)
+	}
+	// Overwriting a key doesn't add an entry.
+	if err := store.set("first", []byte("one")); err != nil {
+		t.Fatalf("failed to overwrite the first key: %v", err)
+	}
+	// An empty value deletes the key, making room for another.
+	if err := store.set("first", nil); err != nil {
+		t.Fatalf("failed to delete the first key: %v", err)
+	}
+	if _, ok := store.get("first"); ok {
+		t.Error("expected the first key to be deleted")
+	}
+	if err := store.set("second", []byte("2")); err != nil {
+		t.Errorf("failed to set the second key: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package wasmplugin
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+const (
+	// scopeName is the instrumentation scope of the plugin metrics
+	scopeName = "github.com/otelwasm/otelwasm/wasmplugin"
+
+	// guestErrorsMetric counts the failed guest calls. It is exposed as
+	// otelwasm_guest_errors_total by the Prometheus exporter.
+	guestErrorsMetric = "otelwasm_guest_errors"
+
+	// reasonAttribute is the attribute holding the ErrorReason of a failure
+	reasonAttribute = "reason"
+)
+
+// Option configures a WasmPlugin.
+type Option func(*options)
+
+type options struct {
+	meterProvider  metric.MeterProvider
+	tracerProvider trace.TracerProvider
+	logger         *zap.Logger
+	moduleCache    *CompiledModuleCache
+
+	memoryAccountant *MemoryAccountant
+
+	guestMetricsConsumer GuestMetricsConsumer
+
+	component componentInfo
+
+	interruptibleCalls bool
+}
+
+// WithMeterProvider sets the meter provider the plugin metrics are recorded
+// with. Metrics are discarded by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = mp
+	}
+}
+
+// WithTracerProvider sets the tracer provider the guest call spans are
+// recorded with, see Config.TraceGuestCalls. Spans are discarded by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithLogger sets the logger the plugin logs with. Nothing is logged by
+// default.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// telemetry holds the instruments of the plugin metrics.
+type telemetry struct {
+	guestErrors metric.Int64Counter
+}
+
+func newTelemetry(mp metric.MeterProvider) (*telemetry, error) {
+	if mp == nil {
+		mp = noop.NewMeterProvider()
+	}
+	meter := mp.Meter(scopeName)
+
+	guestErrors, err := meter.Int64Counter(guestErrorsMetric,
+		metric.WithDescription("Number of failed guest function calls, by reason."),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &telemetry{guestErrors: guestErrors}, nil
+}
+
+// newTracer returns the tracer of the guest call spans.
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = tracenoop.NewTracerProvider()
+	}
+	return tp.Tracer(scopeName)
+}
+
+// recordGuestError counts err in the guest errors metric.
+func (t *telemetry) recordGuestError(ctx context.Context, err *GuestError) {
+	t.guestErrors.Add(ctx, 1, metric.WithAttributes(attribute.String(reasonAttribute, string(err.Reason))))
+}
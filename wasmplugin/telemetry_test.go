@@ -0,0 +1,99 @@
+package wasmplugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// guestErrors returns the guest errors counter values by reason.
+func guestErrors(t *testing.T, reader sdkmetric.Reader) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	counts := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != guestErrorsMetric {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				reason, _ := dp.Attributes.Value(attribute.Key(reasonAttribute))
+				counts[reason.AsString()] = dp.Value
+			}
+		}
+	}
+	return counts
+}
+
+func TestGuestErrorsMetric(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  []byte
+		quota QuotaConfig
+		// calls is the number of calls made, only the last one failing.
+		calls      int
+		wantReason ErrorReason
+	}{
+		{name: "status", body: wasmtest.I32Const(1), calls: 1, wantReason: ErrorReasonStatus},
+		{name: "invalid argument", body: wasmtest.I32Const(2), calls: 1, wantReason: ErrorReasonInvalidArgument},
+		{name: "trap", body: wasmtest.Unreachable, calls: 1, wantReason: ErrorReasonTrap},
+		{name: "quota", body: wasmtest.I32Const(0), quota: QuotaConfig{MaxCalls: 1}, calls: 2, wantReason: ErrorReasonQuota},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := wasmtest.NewGuest(int32(telemetryTypeTraces), wasmtest.Function{
+				Export:  "processTraces",
+				Results: []api.ValueType{api.ValueTypeI32},
+				Body:    tt.body,
+			})
+			cfg := Config{Path: mod.Write(t), Quota: tt.quota}
+			cfg.Default()
+			reader := sdkmetric.NewManualReader()
+			plugin, err := NewWasmPlugin(t.Context(), &cfg, []string{"processTraces"},
+				WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			t.Cleanup(func() { _ = plugin.Shutdown(t.Context()) })
+
+			if got := guestErrors(t, reader); len(got) != 0 {
+				t.Fatalf("expected no guest errors, got %v", got)
+			}
+
+			for i := 0; i < tt.calls; i++ {
+				stack := &Stack{}
+				res, err := plugin.ProcessFunctionCall(t.Context(), "processTraces", stack)
+				if err == nil {
+					err = plugin.CheckStatus(t.Context(), "processTraces", res, stack)
+				}
+				if i < tt.calls-1 {
+					if err != nil {
+						t.Fatalf("call %d: unexpected error: %v", i, err)
+					}
+					continue
+				}
+				var guestErr *GuestError
+				if !errors.As(err, &guestErr) {
+					t.Fatalf("expected a GuestError, got %v", err)
+				}
+				if guestErr.Reason != tt.wantReason {
+					t.Errorf("expected reason %s, got %s", tt.wantReason, guestErr.Reason)
+				}
+			}
+
+			want := map[string]int64{string(tt.wantReason): 1}
+			if got := guestErrors(t, reader); len(got) != 1 || got[string(tt.wantReason)] != 1 {
+				t.Errorf("expected guest errors %v, got %v", want, got)
+			}
+		})
+	}
+}
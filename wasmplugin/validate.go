@@ -0,0 +1,161 @@
+package wasmplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ComponentKind is the kind of collector component running a guest.
+type ComponentKind string
+
+const (
+	ComponentKindReceiver  ComponentKind = "receiver"
+	ComponentKindProcessor ComponentKind = "processor"
+	ComponentKindExporter  ComponentKind = "exporter"
+	ComponentKindConnector ComponentKind = "connector"
+)
+
+// componentFunctions are the guest functions each kind of component calls,
+// per signal the guest supports.
+var componentFunctions = map[ComponentKind]map[telemetryType]string{
+	ComponentKindReceiver: {
+		telemetryTypeTraces:  "startTracesReceiver",
+		telemetryTypeMetrics: "startMetricsReceiver",
+		telemetryTypeLogs:    "startLogsReceiver",
+	},
+	ComponentKindProcessor: {
+		telemetryTypeTraces:   "processTraces",
+		telemetryTypeMetrics:  "processMetrics",
+		telemetryTypeLogs:     "processLogs",
+		telemetryTypeProfiles: "processProfiles",
+	},
+	ComponentKindExporter: {
+		telemetryTypeTraces:  "pushTraces",
+		telemetryTypeMetrics: "pushMetrics",
+		telemetryTypeLogs:    "pushLogs",
+	},
+	ComponentKindConnector: {
+		telemetryTypeTraces: "connectTracesToMetrics",
+	},
+}
+
+// ValidationReason is the category of a ValidationError.
+type ValidationReason string
+
+const (
+	// ValidationReasonInvalidModule means the module failed to compile.
+	ValidationReasonInvalidModule ValidationReason = "invalid_module"
+
+	// ValidationReasonABIMismatch means the guest was built against an ABI
+	// version the host doesn't support, or imports host functions it doesn't
+	// know.
+	ValidationReasonABIMismatch ValidationReason = "abi_mismatch"
+
+	// ValidationReasonMissingExport means the guest doesn't export a
+	// function the host or the component calls, see
+	// ValidationError.Missing.
+	ValidationReasonMissingExport ValidationReason = "missing_export"
+
+	// ValidationReasonInstantiation means the guest failed to load or
+	// initialize otherwise, e.g. its file can't be read or its initialization
+	// trapped.
+	ValidationReasonInstantiation ValidationReason = "instantiation"
+)
+
+// ValidationError is returned by ValidateModule for a module the component
+// can't run.
+type ValidationError struct {
+	// Reason is the category of the failure.
+	Reason ValidationReason
+
+	// Missing are the guest functions not exported. Only set if Reason is
+	// ValidationReasonMissingExport.
+	Missing []string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateModule checks that the guest of cfg can run in a component of the
+// given kind, without creating the component: the guest is compiled and
+// instantiated, its ABI version checked, and the functions the component
+// calls for the signals declared by getSupportedTelemetry looked up. The
+// guest is shut down before returning.
+//
+// A *ValidationError is returned if the module is invalid, any other error
+// if cfg is.
+func ValidateModule(ctx context.Context, cfg *Config, kind ComponentKind) error {
+	functions, ok := componentFunctions[kind]
+	if !ok {
+		return fmt.Errorf("wasm: unknown component kind %q", kind)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	plugin, err := NewWasmPlugin(ctx, cfg, nil)
+	if err != nil {
+		return newValidationError(err)
+	}
+	defer plugin.Shutdown(ctx)
+
+	telemetryTypes, err := plugin.supportedTelemetryTypes(ctx)
+	if err != nil {
+		return &ValidationError{Reason: ValidationReasonInstantiation, Err: err}
+	}
+
+	var supported bool
+	var missing []string
+	for _, t := range []telemetryType{telemetryTypeTraces, telemetryTypeMetrics, telemetryTypeLogs, telemetryTypeProfiles} {
+		name, ok := functions[t]
+		if !ok || telemetryTypes&t == 0 {
+			continue
+		}
+		supported = true
+		if exportedFunction(plugin.Module, name, cfg.ABI.FunctionPrefixes) == nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return &ValidationError{
+			Reason:  ValidationReasonMissingExport,
+			Missing: missing,
+			Err: fmt.Errorf("wasm: %s not exported, the guest exports %s: %w",
+				strings.Join(missing, ", "), strings.Join(plugin.ExportedFunctionNames(), ", "), ErrRequiredFunctionNotExported),
+		}
+	}
+	if !supported {
+		return &ValidationError{
+			Reason: ValidationReasonMissingExport,
+			Err:    fmt.Errorf("wasm: guest supports no signal a %s handles: %w", kind, ErrRequiredFunctionNotExported),
+		}
+	}
+	return nil
+}
+
+// newValidationError categorizes an error creating the plugin.
+func newValidationError(err error) *ValidationError {
+	switch {
+	case errors.Is(err, ErrInvalidModule):
+		return &ValidationError{Reason: ValidationReasonInvalidModule, Err: err}
+	case errors.Is(err, ErrABIVersionUnsupported):
+		return &ValidationError{Reason: ValidationReasonABIMismatch, Err: err}
+	case errors.Is(err, ErrRequiredFunctionNotExported):
+		// Only the built-in guest functions are required to create the
+		// plugin.
+		return &ValidationError{Reason: ValidationReasonMissingExport, Missing: slices.Clone(builtInGuestFunctions), Err: err}
+	default:
+		return &ValidationError{Reason: ValidationReasonInstantiation, Err: err}
+	}
+}
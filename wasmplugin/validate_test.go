@@ -0,0 +1,121 @@
+package wasmplugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+)
+
+func TestValidateModule(t *testing.T) {
+	traces, logs, profiles := int32(telemetryTypeTraces), int32(telemetryTypeLogs), int32(telemetryTypeProfiles)
+
+	invalid := filepath.Join(t.TempDir(), "invalid.wasm")
+	if err := os.WriteFile(invalid, []byte("not wasm"), 0o600); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		mod         *wasmtest.Module
+		kind        ComponentKind
+		wantReason  ValidationReason
+		wantMissing []string
+	}{
+		{
+			name: "valid processor",
+			mod:  wasmtest.NewGuest(traces|logs, returnsI32("processTraces", 0), returnsI32("processLogs", 0)),
+			kind: ComponentKindProcessor,
+		},
+		{
+			name: "valid profiles processor",
+			mod:  wasmtest.NewGuest(profiles, returnsI32("processProfiles", 0)),
+			kind: ComponentKindProcessor,
+		},
+		{
+			name: "valid connector",
+			mod:  wasmtest.NewGuest(traces, returnsI32("connectTracesToMetrics", 0)),
+			kind: ComponentKindConnector,
+		},
+		{
+			name:        "missing export of a supported signal",
+			mod:         wasmtest.NewGuest(traces|logs, returnsI32("processTraces", 0)),
+			kind:        ComponentKindProcessor,
+			wantReason:  ValidationReasonMissingExport,
+			wantMissing: []string{"processLogs"},
+		},
+		{
+			name:        "missing export of profiles",
+			mod:         wasmtest.NewGuest(traces|profiles, returnsI32("processTraces", 0)),
+			kind:        ComponentKindProcessor,
+			wantReason:  ValidationReasonMissingExport,
+			wantMissing: []string{"processProfiles"},
+		},
+		{
+			name:        "exports of another component",
+			mod:         wasmtest.NewGuest(traces, returnsI32("processTraces", 0)),
+			kind:        ComponentKindExporter,
+			wantReason:  ValidationReasonMissingExport,
+			wantMissing: []string{"pushTraces"},
+		},
+		{
+			name:       "no signal of the component",
+			mod:        wasmtest.NewGuest(logs, returnsI32("connectTracesToMetrics", 0)),
+			kind:       ComponentKindConnector,
+			wantReason: ValidationReasonMissingExport,
+		},
+		{
+			name:       "abi mismatch",
+			mod:        wasmtest.NewGuest(traces, returnsI32("pushTraces", 0), returnsI32(abiVersion, ABIVersion+1)),
+			kind:       ComponentKindExporter,
+			wantReason: ValidationReasonABIMismatch,
+		},
+		{
+			name:       "invalid module",
+			path:       invalid,
+			kind:       ComponentKindExporter,
+			wantReason: ValidationReasonInvalidModule,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Path: tt.path}
+			if tt.mod != nil {
+				cfg.Path = tt.mod.Write(t)
+			}
+			cfg.Default()
+
+			err := ValidateModule(t.Context(), &cfg, tt.kind)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("expected the module to be valid, got %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if validationErr.Reason != tt.wantReason || !slices.Equal(validationErr.Missing, tt.wantMissing) {
+				t.Errorf("expected the reason %s missing %v, got %s missing %v",
+					tt.wantReason, tt.wantMissing, validationErr.Reason, validationErr.Missing)
+			}
+		})
+	}
+}
+
+func TestValidateModuleUnknownKind(t *testing.T) {
+	cfg := Config{Path: wasmtest.NewGuest(int32(telemetryTypeTraces)).Write(t)}
+	cfg.Default()
+
+	err := ValidateModule(t.Context(), &cfg, "extension")
+	var validationErr *ValidationError
+	if err == nil || errors.As(err, &validationErr) {
+		t.Errorf("expected a non-validation error for an unknown kind, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package wasmtest
+
+// Instructions returns the concatenation of the given instructions.
+func Instructions(instrs ...[]byte) []byte {
+	var out []byte
+	for _, instr := range instrs {
+		out = append(out, instr...)
+	}
+	return out
+}
+
+// Single byte instructions.
+var (
+	Unreachable = []byte{0x00}
+	Nop         = []byte{0x01}
+	Else        = []byte{0x05}
+	End         = []byte{0x0b}
+	Return      = []byte{0x0f}
+	Drop        = []byte{0x1a}
+	I32Eqz      = []byte{0x45}
+	I32Eq       = []byte{0x46}
+	I32Ne       = []byte{0x47}
+	I32Add      = []byte{0x6a}
+	I32Sub      = []byte{0x6b}
+	I32LtU      = []byte{0x49}
+	MemorySize  = []byte{0x3f, 0x00}
+	MemoryGrow  = []byte{0x40, 0x00}
+)
+
+// Block starts a block without results.
+func Block() []byte { return []byte{0x02, 0x40} }
+
+// Loop starts a loop without results.
+func Loop() []byte { return []byte{0x03, 0x40} }
+
+// If starts an if block without results.
+func If() []byte { return []byte{0x04, 0x40} }
+
+// Br branches to the given label depth.
+func Br(depth uint32) []byte { return append([]byte{0x0c}, uleb(depth)...) }
+
+// BrIf conditionally branches to the given label depth.
+func BrIf(depth uint32) []byte { return append([]byte{0x0d}, uleb(depth)...) }
+
+// Call calls the function at the given index.
+func Call(index uint32) []byte { return append([]byte{0x10}, uleb(index)...) }
+
+// LocalGet pushes the given local.
+func LocalGet(index uint32) []byte { return append([]byte{0x20}, uleb(index)...) }
+
+// LocalSet pops into the given local.
+func LocalSet(index uint32) []byte { return append([]byte{0x21}, uleb(index)...) }
+
+// I32Const pushes an i32 constant.
+func I32Const(v int32) []byte { return append([]byte{0x41}, sleb(int64(v))...) }
+
+// I64Const pushes an i64 constant.
+func I64Const(v int64) []byte { return append([]byte{0x42}, sleb(v)...) }
+
+// I32Load loads an i32 from the address on the stack plus offset.
+func I32Load(offset uint32) []byte { return append([]byte{0x28, 0x02}, uleb(offset)...) }
+
+// I32Store stores an i32 at the address on the stack plus offset.
+func I32Store(offset uint32) []byte { return append([]byte{0x36, 0x02}, uleb(offset)...) }
+
+// I64Store stores an i64 at the address on the stack plus offset.
+func I64Store(offset uint32) []byte { return append([]byte{0x37, 0x03}, uleb(offset)...) }
@@ -0,0 +1,232 @@
+// Package wasmtest provides a tiny WebAssembly binary encoder for building
+// guest modules in host-side tests without a guest toolchain.
+package wasmtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+const (
+	// HostModule is the name of the otelwasm host module guests import from.
+	HostModule = "opentelemetry.io/wasm"
+
+	// WASIModule is the name of the WASI preview1 host module.
+	WASIModule = "wasi_snapshot_preview1"
+)
+
+// Import is a function imported by the guest.
+type Import struct {
+	Module  string
+	Name    string
+	Params  []api.ValueType
+	Results []api.ValueType
+}
+
+// Function is a function defined by the guest.
+type Function struct {
+	// Export is the export name of the function. The function isn't
+	// exported if empty.
+	Export  string
+	Params  []api.ValueType
+	Results []api.ValueType
+	Locals  []api.ValueType
+
+	// Body is the instruction sequence without the trailing end opcode.
+	Body []byte
+}
+
+// Data is an active data segment written to memory at instantiation.
+type Data struct {
+	Offset uint32
+	Bytes  []byte
+}
+
+// Module describes a guest module. The memory is always exported as
+// "memory", which is what the host requires.
+type Module struct {
+	Imports   []Import
+	Functions []Function
+	Data      []Data
+
+	// MemoryPages is the initial number of memory pages. Defaults to 1.
+	MemoryPages uint32
+	// MaxMemoryPages is the maximum number of memory pages. No maximum is
+	// declared if zero.
+	MaxMemoryPages uint32
+}
+
+// NewGuest returns a module exporting getSupportedTelemetry, which returns
+// the given telemetry type flags, along with the given functions.
+func NewGuest(supportedTelemetry int32, functions ...Function) *Module {
+	return &Module{
+		Functions: append([]Function{{
+			Export:  "getSupportedTelemetry",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    I32Const(supportedTelemetry),
+		}}, functions...),
+	}
+}
+
+// Import adds a function import and returns the module for chaining.
+func (m *Module) Import(module, name string, params, results []api.ValueType) *Module {
+	m.Imports = append(m.Imports, Import{Module: module, Name: name, Params: params, Results: results})
+	return m
+}
+
+// Call returns a call instruction to the named import or exported function.
+// It panics if the name is unknown.
+func (m *Module) Call(name string) []byte {
+	for i, imp := range m.Imports {
+		if imp.Name == name {
+			return Call(uint32(i))
+		}
+	}
+	for i, fn := range m.Functions {
+		if fn.Export == name {
+			return Call(uint32(len(m.Imports) + i))
+		}
+	}
+	panic("wasmtest: unknown function " + name)
+}
+
+// Write encodes the module into a temporary file and returns its path.
+func (m *Module) Write(tb testing.TB) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "guest.wasm")
+	if err := os.WriteFile(path, m.Bytes(), 0o600); err != nil {
+		tb.Fatalf("failed to write test module: %v", err)
+	}
+	return path
+}
+
+// Bytes encodes the module in the WebAssembly binary format.
+func (m *Module) Bytes() []byte {
+	var types [][]byte
+	typeIndex := func(params, results []api.ValueType) uint32 {
+		t := []byte{0x60}
+		t = append(t, vec(len(params), valueTypes(params))...)
+		t = append(t, vec(len(results), valueTypes(results))...)
+		for i, existing := range types {
+			if string(existing) == string(t) {
+				return uint32(i)
+			}
+		}
+		types = append(types, t)
+		return uint32(len(types) - 1)
+	}
+
+	var imports []byte
+	for _, imp := range m.Imports {
+		imports = append(imports, name(imp.Module)...)
+		imports = append(imports, name(imp.Name)...)
+		imports = append(imports, 0x00)
+		imports = append(imports, uleb(typeIndex(imp.Params, imp.Results))...)
+	}
+
+	var funcs, exports, code []byte
+	exportCount := 1
+	exports = append(exports, name("memory")...)
+	exports = append(exports, 0x02, 0x00)
+	for i, fn := range m.Functions {
+		funcs = append(funcs, uleb(typeIndex(fn.Params, fn.Results))...)
+		if fn.Export != "" {
+			exportCount++
+			exports = append(exports, name(fn.Export)...)
+			exports = append(exports, 0x00)
+			exports = append(exports, uleb(uint32(len(m.Imports)+i))...)
+		}
+		var body []byte
+		body = append(body, uleb(uint32(len(fn.Locals)))...)
+		for _, local := range fn.Locals {
+			body = append(body, 0x01, local)
+		}
+		body = append(body, fn.Body...)
+		body = append(body, 0x0b)
+		code = append(code, uleb(uint32(len(body)))...)
+		code = append(code, body...)
+	}
+
+	pages := m.MemoryPages
+	if pages == 0 {
+		pages = 1
+	}
+	memory := []byte{0x00}
+	if m.MaxMemoryPages > 0 {
+		memory = []byte{0x01}
+	}
+	memory = append(memory, uleb(pages)...)
+	if m.MaxMemoryPages > 0 {
+		memory = append(memory, uleb(m.MaxMemoryPages)...)
+	}
+
+	var data []byte
+	for _, d := range m.Data {
+		data = append(data, 0x00)
+		data = append(data, I32Const(int32(d.Offset))...)
+		data = append(data, 0x0b)
+		data = append(data, vec(len(d.Bytes), d.Bytes)...)
+	}
+
+	out := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	var typeSection []byte
+	for _, t := range types {
+		typeSection = append(typeSection, t...)
+	}
+	out = append(out, section(1, vec(len(types), typeSection))...)
+	if len(m.Imports) > 0 {
+		out = append(out, section(2, vec(len(m.Imports), imports))...)
+	}
+	out = append(out, section(3, vec(len(m.Functions), funcs))...)
+	out = append(out, section(5, vec(1, memory))...)
+	out = append(out, section(7, vec(exportCount, exports))...)
+	out = append(out, section(10, vec(len(m.Functions), code))...)
+	if len(m.Data) > 0 {
+		out = append(out, section(11, vec(len(m.Data), data))...)
+	}
+	return out
+}
+
+func section(id byte, content []byte) []byte {
+	return append(append([]byte{id}, uleb(uint32(len(content)))...), content...)
+}
+
+func vec(n int, content []byte) []byte {
+	return append(uleb(uint32(n)), content...)
+}
+
+func name(s string) []byte {
+	return vec(len(s), []byte(s))
+}
+
+func valueTypes(types []api.ValueType) []byte {
+	return append([]byte(nil), types...)
+}
+
+func uleb(v uint32) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			out = append(out, b|0x80)
+			continue
+		}
+		return append(out, b)
+	}
+}
+
+func sleb(v int64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
@@ -0,0 +1,39 @@
+package wasmprocessor
+
+import (
+	"context"
+
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor"
+)
+
+// The processors below attach a bag to the context of the batches entering
+// them unless an upstream stage already did, so that the bag is passed along
+// to the downstream stages of the pipeline.
+
+type bagTracesProcessor struct {
+	processor.Traces
+}
+
+func (p bagTracesProcessor) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	return p.Traces.ConsumeTraces(wasmplugin.ContextWithBag(ctx), td)
+}
+
+type bagMetricsProcessor struct {
+	processor.Metrics
+}
+
+func (p bagMetricsProcessor) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	return p.Metrics.ConsumeMetrics(wasmplugin.ContextWithBag(ctx), md)
+}
+
+type bagLogsProcessor struct {
+	processor.Logs
+}
+
+func (p bagLogsProcessor) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	return p.Logs.ConsumeLogs(wasmplugin.ContextWithBag(ctx), ld)
+}
@@ -0,0 +1,95 @@
+package wasmprocessor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+var i32 = api.ValueTypeI32
+
+// bagWriterGuest stores "hint=slow-path" in the bag and forwards the traces
+// untouched.
+func bagWriterGuest() *wasmtest.Module {
+	mod := wasmtest.NewGuest(4).
+		Import(wasmtest.HostModule, "setBagValue", []api.ValueType{i32, i32, i32, i32}, nil).
+		Import(wasmtest.HostModule, "currentTraces", []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, "setResultTraces", []api.ValueType{i32, i32}, nil)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte("hint")}, {Offset: 16, Bytes: []byte("slow-path")}}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  processTracesFunctionName,
+		Results: []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(4), wasmtest.I32Const(16), wasmtest.I32Const(9),
+			mod.Call("setBagValue"),
+			wasmtest.I32Const(1024),
+			wasmtest.I32Const(1024), wasmtest.I32Const(60000), mod.Call("currentTraces"),
+			mod.Call("setResultTraces"),
+			wasmtest.I32Const(0),
+		),
+	})
+	return mod
+}
+
+// bagReaderGuest fails with the value stored under "hint" in the bag as the
+// status reason, so tests can observe it.
+func bagReaderGuest() *wasmtest.Module {
+	mod := wasmtest.NewGuest(4).
+		Import(wasmtest.HostModule, "getBagValue", []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, "setResultStatusReason", []api.ValueType{i32, i32}, nil)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte("hint")}}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  processTracesFunctionName,
+		Results: []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(1024),
+			wasmtest.I32Const(0), wasmtest.I32Const(4), wasmtest.I32Const(1024), wasmtest.I32Const(1024),
+			mod.Call("getBagValue"),
+			mod.Call("setResultStatusReason"),
+			wasmtest.I32Const(1),
+		),
+	})
+	return mod
+}
+
+func createTestTracesProcessor(t *testing.T, mod *wasmtest.Module, next consumer.Traces) processor.Traces {
+	t.Helper()
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	p, err := NewFactory().CreateTraces(t.Context(), processortest.NewNopSettings(typeStr), cfg, next)
+	if err != nil {
+		t.Fatalf("failed to create traces processor: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := p.Shutdown(t.Context()); err != nil {
+			t.Errorf("failed to shutdown processor: %v", err)
+		}
+	})
+	return p
+}
+
+func TestBagPassedDownstream(t *testing.T) {
+	reader := createTestTracesProcessor(t, bagReaderGuest(), consumertest.NewNop())
+	writer := createTestTracesProcessor(t, bagWriterGuest(), reader)
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+
+	err := writer.ConsumeTraces(t.Context(), traces)
+	if err == nil || !strings.HasSuffix(err.Error(), ": slow-path") {
+		t.Errorf("expected the reader to see the value set by the writer, got %v", err)
+	}
+
+	// A batch not going through the writer gets a bag of its own.
+	err = reader.ConsumeTraces(t.Context(), traces)
+	if err == nil || !strings.HasSuffix(err.Error(), ": ") {
+		t.Errorf("expected the reader to see an empty bag, got %v", err)
+	}
+}
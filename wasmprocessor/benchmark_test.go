@@ -1,6 +1,7 @@
 package wasmprocessor
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/consumer/consumertest"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/processor/processortest"
 )
@@ -273,3 +275,136 @@ func BenchmarkAttributesProcessorGo(b *testing.B) {
 		b.Errorf("failed to shutdown processor: %v", err)
 	}
 }
+
+// generateBatchTraces returns traces of n spans, as a batch processor
+// upstream would pass.
+func generateBatchTraces(n int) ptrace.Traces {
+	td := ptrace.NewTraces()
+	resourceSpans := td.ResourceSpans().AppendEmpty()
+	resourceSpans.Resource().Attributes().PutStr("example_key", "example_value")
+	spans := resourceSpans.ScopeSpans().AppendEmpty().Spans()
+	for i := range n {
+		span := spans.AppendEmpty()
+		span.SetName(fmt.Sprintf("example_span_%d", i))
+		span.SetKind(ptrace.SpanKindServer)
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Now().Add(1 * time.Second)))
+		span.Attributes().PutStr("example_span_key", "example_span_value")
+	}
+	return td
+}
+
+// BenchmarkResultUnchangedWasmInterpreter compares the attributes processor,
+// which serializes its result back to the host, with the nop processor,
+// which reports the traces unchanged so the host passes its own copy on.
+func BenchmarkResultUnchangedWasmInterpreter(b *testing.B) {
+	for _, bc := range []struct {
+		name         string
+		path         string
+		pluginConfig wasmplugin.PluginConfig
+	}{
+		{
+			name: "result",
+			path: "testdata/attributesprocessor/main.wasm",
+			pluginConfig: wasmplugin.PluginConfig{
+				"actions": []map[string]string{
+					{
+						"key":    "key",
+						"value":  "value",
+						"action": "insert",
+					},
+				},
+			},
+		},
+		{
+			name: "unchanged",
+			path: "testdata/nop/main.wasm",
+		},
+	} {
+		for _, spans := range []int{1, 1000} {
+			b.Run(fmt.Sprintf("%s/spans=%d", bc.name, spans), func(b *testing.B) {
+				factory := NewFactory()
+				cfg := factory.CreateDefaultConfig().(*Config)
+				cfg.Path = bc.path
+				cfg.PluginConfig = bc.pluginConfig
+				ctx := b.Context()
+
+				tp, err := factory.CreateTraces(ctx, processortest.NewNopSettings(typeStr), cfg, consumertest.NewNop())
+				if err != nil {
+					b.Fatalf("failed to create traces processor: %v", err)
+				}
+				if err := tp.Start(ctx, componenttest.NewNopHost()); err != nil {
+					b.Fatalf("failed to start processor: %v", err)
+				}
+				defer tp.Shutdown(ctx)
+
+				td := generateBatchTraces(spans)
+				b.ReportAllocs()
+				for b.Loop() {
+					if err := tp.ConsumeTraces(ctx, td); err != nil {
+						b.Fatalf("failed to consume traces: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// generateBatchLogs returns logs of n records, as a batch processor upstream
+// would pass.
+func generateBatchLogs(n int) plog.Logs {
+	ld := plog.NewLogs()
+	resourceLogs := ld.ResourceLogs().AppendEmpty()
+	resourceLogs.Resource().Attributes().PutStr("example_key", "example_value")
+	records := resourceLogs.ScopeLogs().AppendEmpty().LogRecords()
+	for i := range n {
+		record := records.AppendEmpty()
+		record.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		record.SetSeverityNumber(plog.SeverityNumberInfo)
+		record.Body().SetStr(fmt.Sprintf("GET /api/v1/items/%d 200 OK in 12ms", i))
+		record.Attributes().PutStr("example_record_key", "example_record_value")
+	}
+	return ld
+}
+
+// BenchmarkLogsCompressionWasmInterpreter compares passing the logs to the
+// attributes processor uncompressed and compressed with gzip.
+func BenchmarkLogsCompressionWasmInterpreter(b *testing.B) {
+	for _, compression := range []wasmplugin.LogsCompression{wasmplugin.LogsCompressionNone, wasmplugin.LogsCompressionGzip} {
+		for _, records := range []int{1, 100, 1000, 10000} {
+			b.Run(fmt.Sprintf("%s/records=%d", compression, records), func(b *testing.B) {
+				factory := NewFactory()
+				cfg := factory.CreateDefaultConfig().(*Config)
+				cfg.Path = "testdata/attributesprocessor/main.wasm"
+				cfg.LogsCompression = compression
+				cfg.PluginConfig = wasmplugin.PluginConfig{
+					"actions": []map[string]string{
+						{
+							"key":    "key",
+							"value":  "value",
+							"action": "insert",
+						},
+					},
+				}
+				ctx := b.Context()
+
+				lp, err := factory.CreateLogs(ctx, processortest.NewNopSettings(typeStr), cfg, consumertest.NewNop())
+				if err != nil {
+					b.Fatalf("failed to create logs processor: %v", err)
+				}
+				if err := lp.Start(ctx, componenttest.NewNopHost()); err != nil {
+					b.Fatalf("failed to start processor: %v", err)
+				}
+				defer lp.Shutdown(ctx)
+
+				ld := generateBatchLogs(records)
+				b.ReportAllocs()
+				for b.Loop() {
+					if err := lp.ConsumeLogs(ctx, ld); err != nil {
+						b.Fatalf("failed to consume logs: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package wasmprocessor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+// statusGuest returns a guest supporting the given telemetry types, whose
+// process function returns the given body.
+func statusGuest(telemetryTypes int32, functionName string, body []byte) *wasmtest.Module {
+	return wasmtest.NewGuest(telemetryTypes, wasmtest.Function{
+		Export:  functionName,
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body:    body,
+	})
+}
+
+// addAttributeConfig returns the config of the add_new_attribute guest
+// setting name to value.
+func addAttributeConfig(name, value string) wasmplugin.Config {
+	return wasmplugin.Config{
+		Path: "testdata/add_new_attribute/main.wasm",
+		PluginConfig: wasmplugin.PluginConfig{
+			"attribute_name":  name,
+			"attribute_value": value,
+		},
+	}
+}
+
+func TestChain(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/add_new_attribute/main.wasm"
+	cfg.PluginConfig = addAttributeConfig("first", "1").PluginConfig
+	// The logs only guest is skipped by the traces processor.
+	logsOnly := statusGuest(2, processLogsFunctionName, wasmtest.I32Const(0))
+	cfg.Chain = []wasmplugin.Config{
+		addAttributeConfig("second", "2"),
+		{Path: logsOnly.Write(t)},
+	}
+	ctx := t.Context()
+	wp, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+	if wp.next == nil || wp.next.next != nil {
+		t.Fatal("expected a chain of 2 modules")
+	}
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+	processed, err := wp.processTraces(ctx, traces)
+	if err != nil {
+		t.Fatalf("failed to process traces: %v", err)
+	}
+	attrs := processed.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes()
+	for name, want := range map[string]string{"first": "1", "second": "2"} {
+		if v, _ := attrs.Get(name); v.Str() != want {
+			t.Errorf("expected %s=%s, got %q", name, want, v.Str())
+		}
+	}
+}
+
+func TestChainStopsAtFirstError(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = statusGuest(4, processTracesFunctionName, wasmtest.I32Const(1)).Write(t)
+	// The trapping guest fails with another error if it is called.
+	cfg.Chain = []wasmplugin.Config{{Path: statusGuest(4, processTracesFunctionName, wasmtest.Unreachable).Write(t)}}
+	ctx := t.Context()
+	wp, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+
+	_, err = wp.processTraces(ctx, ptrace.NewTraces())
+	var guestErr *wasmplugin.GuestError
+	if !errors.As(err, &guestErr) || guestErr.Reason != wasmplugin.ErrorReasonStatus {
+		t.Errorf("expected the error status of the first module, got %v", err)
+	}
+}
+
+func TestChainWithoutSupportedModule(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = statusGuest(2, processLogsFunctionName, wasmtest.I32Const(0)).Write(t)
+	cfg.Chain = []wasmplugin.Config{{Path: statusGuest(2, processTracesFunctionName, wasmtest.I32Const(0)).Write(t)}}
+
+	_, err := newWasmTracesProcessor(t.Context(), cfg, processortest.NewNopSettings(typeStr))
+	if !errors.Is(err, pipeline.ErrSignalNotSupported) {
+		t.Errorf("expected ErrSignalNotSupported, got %v", err)
+	}
+}
+
+func TestChainValidate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/nop/main.wasm"
+	cfg.Chain = []wasmplugin.Config{{Path: "testdata/nop/main.wasm"}, {}}
+
+	err := cfg.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), "chain[1]:") {
+		t.Errorf("expected the second chained module to be invalid, got %v", err)
+	}
+}
+
+// capabilitiesGuest returns a guest of every signal declaring the given
+// capabilities, or none if declare is false.
+func capabilitiesGuest(declare bool, capabilities int32) *wasmtest.Module {
+	mod := wasmtest.NewGuest(1 | 2 | 4 | 8)
+	if declare {
+		mod.Functions = append(mod.Functions, wasmtest.Function{
+			Export:  "getCapabilities",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    wasmtest.I32Const(capabilities),
+		})
+	}
+	for _, name := range []string{processTracesFunctionName, processMetricsFunctionName, processLogsFunctionName, processProfilesFunctionName} {
+		mod.Functions = append(mod.Functions, wasmtest.Function{
+			Export:  name,
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    wasmtest.I32Const(0),
+		})
+	}
+	return mod
+}
+
+func TestChainCapabilities(t *testing.T) {
+	readOnly := capabilitiesGuest(true, 0)
+	mutating := capabilitiesGuest(true, int32(wasmplugin.CapabilityMutatesData))
+	undeclared := capabilitiesGuest(false, 0)
+
+	tests := []struct {
+		name            string
+		modules         []*wasmtest.Module
+		wantMutatesData bool
+	}{
+		{name: "read only", modules: []*wasmtest.Module{readOnly}},
+		{name: "mutating", modules: []*wasmtest.Module{mutating}, wantMutatesData: true},
+		{name: "read only chain", modules: []*wasmtest.Module{readOnly, readOnly}},
+		{name: "mutating link", modules: []*wasmtest.Module{readOnly, mutating}, wantMutatesData: true},
+		{name: "undeclared link", modules: []*wasmtest.Module{readOnly, undeclared}, wantMutatesData: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Path = tt.modules[0].Write(t)
+			for _, mod := range tt.modules[1:] {
+				cfg.Chain = append(cfg.Chain, wasmplugin.Config{Path: mod.Write(t)})
+			}
+			factory, set := NewFactory(), processortest.NewNopSettings(typeStr)
+			ctx := t.Context()
+
+			tp, err := factory.CreateTraces(ctx, set, cfg, consumertest.NewNop())
+			if err != nil {
+				t.Fatalf("failed to create traces processor: %v", err)
+			}
+			t.Cleanup(func() { tp.Shutdown(ctx) })
+			mp, err := factory.CreateMetrics(ctx, set, cfg, consumertest.NewNop())
+			if err != nil {
+				t.Fatalf("failed to create metrics processor: %v", err)
+			}
+			t.Cleanup(func() { mp.Shutdown(ctx) })
+			lp, err := factory.CreateLogs(ctx, set, cfg, consumertest.NewNop())
+			if err != nil {
+				t.Fatalf("failed to create logs processor: %v", err)
+			}
+			t.Cleanup(func() { lp.Shutdown(ctx) })
+
+			for signal, got := range map[string]bool{
+				"traces":  tp.Capabilities().MutatesData,
+				"metrics": mp.Capabilities().MutatesData,
+				"logs":    lp.Capabilities().MutatesData,
+			} {
+				if got != tt.wantMutatesData {
+					t.Errorf("expected %s MutatesData %v, got %v", signal, tt.wantMutatesData, got)
+				}
+			}
+		})
+	}
+}
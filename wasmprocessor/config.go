@@ -1,11 +1,114 @@
 package wasmprocessor
 
-import "github.com/otelwasm/otelwasm/wasmplugin"
+import (
+	"fmt"
+
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/pipeline"
+)
 
 type Config struct {
 	wasmplugin.Config `mapstructure:",squash"`
+
+	// TracesPath, MetricsPath and LogsPath are the paths of the modules
+	// processing each signal, overriding Path, so a single processor runs a
+	// module per signal. The other options of the module, e.g. its plugin
+	// config, are shared. Path may be left empty if the processor only runs
+	// for signals with a path of their own. A module set for a signal must
+	// support it, while the module of Path is skipped for the signals it
+	// doesn't support.
+	TracesPath  string `mapstructure:"traces_path,omitempty"`
+	MetricsPath string `mapstructure:"metrics_path,omitempty"`
+	LogsPath    string `mapstructure:"logs_path,omitempty"`
+
+	// WarmUpPath is the path of a representative batch, in OTLP JSON, of the
+	// signal of the processor. The batch is read when the processor is
+	// created, and processed by the guests once they are started, the result
+	// discarded, so the first real batch doesn't pay for running the code
+	// paths of the guest for the first time. The batches the guests append
+	// to the result are discarded too. Guests keeping state across batches
+	// keep that of the batch as well. No batch is processed if empty.
+	WarmUpPath string `mapstructure:"warm_up_path,omitempty"`
+
+	// Chain is the list of the modules run after the module of Path, each
+	// with its own configuration. The telemetry returned by a module is the
+	// input of the next one, and the first module returning an error status
+	// fails the processing without calling the next ones.
+	//
+	// Every module is called with a Stack of its own, holding its own plugin
+	// config, while the bag of the batch is shared by the whole chain.
+	// Modules not supporting the signal of the processor, either declared by
+	// getSupportedTelemetry or by not exporting its process function, are
+	// skipped, so a chain may be used in pipelines of different signals.
+	// The processor fails to be created if no module supports its signal.
+	// The chain follows the module of the signal path, if set.
+	Chain []wasmplugin.Config `mapstructure:"chain,omitempty"`
+
+	// InjectResourceAttributes are the attributes set by the host on the
+	// resource of all the telemetry returned by the chain, once the last
+	// module returns, e.g. to stamp an instance id without running a guest
+	// for it. They override the attributes of the same name, whether set by
+	// the guests or present on the incoming telemetry.
+	InjectResourceAttributes map[string]string `mapstructure:"inject_resource_attributes,omitempty"`
 }
 
 func (cfg *Config) Validate() error {
-	return cfg.Config.Validate()
+	// The module options are validated once, with any of the paths, which
+	// are all checked when the modules are loaded.
+	moduleCfg := cfg.Config
+	for _, path := range []string{cfg.TracesPath, cfg.MetricsPath, cfg.LogsPath} {
+		if moduleCfg.Path == "" {
+			moduleCfg.Path = path
+		}
+	}
+	if err := moduleCfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.ExpectedDigest != "" && cfg.TracesPath+cfg.MetricsPath+cfg.LogsPath != "" {
+		return fmt.Errorf("expected_digest can't be set with traces_path, metrics_path or logs_path, as the modules differ")
+	}
+	for name := range cfg.InjectResourceAttributes {
+		if name == "" {
+			return fmt.Errorf("inject_resource_attributes: attribute name can't be empty")
+		}
+	}
+	for i, moduleCfg := range cfg.Chain {
+		moduleCfg.Default()
+		if err := moduleCfg.Validate(); err != nil {
+			return fmt.Errorf("chain[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// signalPath returns the path of the module processing signal, and whether
+// it was set for the signal.
+func (cfg *Config) signalPath(signal pipeline.Signal) (string, bool) {
+	var path string
+	switch signal {
+	case pipeline.SignalTraces:
+		path = cfg.TracesPath
+	case pipeline.SignalMetrics:
+		path = cfg.MetricsPath
+	case pipeline.SignalLogs:
+		path = cfg.LogsPath
+	}
+	if path != "" {
+		return path, true
+	}
+	return cfg.Path, false
+}
+
+// modules returns the configurations of the modules processing signal, in
+// the order they process the telemetry. The defaults are set on the chained
+// modules.
+func (cfg *Config) modules(signal pipeline.Signal) []wasmplugin.Config {
+	head := cfg.Config
+	head.Path, _ = cfg.signalPath(signal)
+	modules := []wasmplugin.Config{head}
+	for _, moduleCfg := range cfg.Chain {
+		moduleCfg.Default()
+		modules = append(modules, moduleCfg)
+	}
+	return modules
 }
@@ -0,0 +1,116 @@
+package wasmprocessor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+func TestSignalPaths(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.TracesPath = "testdata/add_new_attribute/main.wasm"
+	cfg.PluginConfig = addAttributeConfig("processed_by", "traces_module").PluginConfig
+	// The logs module drops every batch, so the logs aren't processed by the
+	// traces module.
+	cfg.LogsPath = statusGuest(2, processLogsFunctionName, wasmtest.I32Const(0)).Write(t)
+	ctx := t.Context()
+	settings := processortest.NewNopSettings(typeStr)
+
+	tracesSink := new(consumertest.TracesSink)
+	tp, err := factory.CreateTraces(ctx, settings, cfg, tracesSink)
+	if err != nil {
+		t.Fatalf("failed to create traces processor: %v", err)
+	}
+	logsSink := new(consumertest.LogsSink)
+	lp, err := factory.CreateLogs(ctx, settings, cfg, logsSink)
+	if err != nil {
+		t.Fatalf("failed to create logs processor: %v", err)
+	}
+	for _, p := range []interface {
+		Start(ctx context.Context, host component.Host) error
+		Shutdown(ctx context.Context) error
+	}{tp, lp} {
+		if err := p.Start(ctx, componenttest.NewNopHost()); err != nil {
+			t.Fatalf("failed to start processor: %v", err)
+		}
+		defer p.Shutdown(ctx)
+	}
+	if _, err := factory.CreateMetrics(ctx, settings, cfg, consumertest.NewNop()); !errors.Is(err, pipeline.ErrSignalNotSupported) {
+		t.Errorf("expected the metrics without a module to be unsupported, got %v", err)
+	}
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+	if err := tp.ConsumeTraces(ctx, traces); err != nil {
+		t.Fatalf("failed to consume traces: %v", err)
+	}
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("log")
+	if err := lp.ConsumeLogs(ctx, logs); err != nil {
+		t.Fatalf("failed to consume logs: %v", err)
+	}
+
+	if got := tracesSink.AllTraces(); len(got) != 1 {
+		t.Fatalf("expected the traces to be passed on, got %d batches", len(got))
+	}
+	attrs := tracesSink.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes()
+	if v, _ := attrs.Get("processed_by"); v.Str() != "traces_module" {
+		t.Errorf("expected the traces to be processed by the traces module, got %v", attrs.AsRaw())
+	}
+	if got := logsSink.LogRecordCount(); got != 1 {
+		t.Errorf("expected the logs to be passed on by the logs module, got %d records", got)
+	}
+}
+
+func TestSignalPathUnsupported(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	// The module of Path is skipped for traces, unlike the one of
+	// traces_path.
+	cfg.Path = "testdata/add_new_attribute/main.wasm"
+	cfg.TracesPath = statusGuest(2, processLogsFunctionName, wasmtest.I32Const(0)).Write(t)
+
+	_, err := newWasmTracesProcessor(t.Context(), cfg, processortest.NewNopSettings(typeStr))
+	if err == nil || !strings.HasPrefix(err.Error(), "traces_path:") {
+		t.Errorf("expected the module of traces_path to be refused, got %v", err)
+	}
+}
+
+func TestSignalPathsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{name: "signal path only", modify: func(cfg *Config) { cfg.LogsPath = "logs.wasm" }},
+		{name: "no path", modify: func(cfg *Config) {}, wantErr: true},
+		{
+			name: "digest with signal paths",
+			modify: func(cfg *Config) {
+				cfg.Path = "all.wasm"
+				cfg.TracesPath = "traces.wasm"
+				cfg.ExpectedDigest = "sha256:" + strings.Repeat("0", 64)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			tt.modify(cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -5,14 +5,15 @@ import (
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/xconsumer"
 	"go.opentelemetry.io/collector/processor"
 	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/collector/processor/xprocessor"
 )
 
 var (
-	typeStr                                = component.MustNewType("wasm")
-	processorCapabilities                  = consumer.Capabilities{MutatesData: true}
-	_                     component.Config = (*Config)(nil)
+	typeStr                  = component.MustNewType("wasm")
+	_       component.Config = (*Config)(nil)
 )
 
 func createDefaultConfig() component.Config {
@@ -21,14 +22,17 @@ func createDefaultConfig() component.Config {
 	return cfg
 }
 
-// NewFactory creates a factory for wasmprocessor.
+// NewFactory creates a factory for wasmprocessor. The factory is an
+// xprocessor.Factory, also creating processors for the profiles signal,
+// which is still in development in the collector.
 func NewFactory() processor.Factory {
-	return processor.NewFactory(
+	return xprocessor.NewFactory(
 		typeStr,
 		createDefaultConfig,
-		processor.WithTraces(createTraces, component.StabilityLevelAlpha),
-		processor.WithMetrics(createMetrics, component.StabilityLevelAlpha),
-		processor.WithLogs(createLogs, component.StabilityLevelAlpha),
+		xprocessor.WithTraces(createTraces, component.StabilityLevelAlpha),
+		xprocessor.WithMetrics(createMetrics, component.StabilityLevelAlpha),
+		xprocessor.WithLogs(createLogs, component.StabilityLevelAlpha),
+		xprocessor.WithProfiles(createProfiles, component.StabilityLevelDevelopment),
 	)
 }
 
@@ -38,15 +42,23 @@ func createTraces(
 	cfg component.Config,
 	nextConsumer consumer.Traces,
 ) (processor.Traces, error) {
-	wasmProcessor, err := newWasmTracesProcessor(ctx, cfg.(*Config))
+	wasmProcessor, err := newWasmTracesProcessor(ctx, cfg.(*Config), set)
 	if err != nil {
 		return nil, err
 	}
-	return processorhelper.NewTraces(ctx, set, cfg, nextConsumer,
+	for link := wasmProcessor; link != nil; link = link.next {
+		link.nextTraces = nextConsumer
+	}
+	p, err := processorhelper.NewTraces(ctx, set, cfg, nextConsumer,
 		wasmProcessor.processTraces,
-		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithCapabilities(wasmProcessor.capabilities()),
+		processorhelper.WithStart(wasmProcessor.start),
 		processorhelper.WithShutdown(wasmProcessor.shutdown),
 	)
+	if err != nil {
+		return nil, err
+	}
+	return bagTracesProcessor{p}, nil
 }
 
 func createMetrics(
@@ -55,15 +67,20 @@ func createMetrics(
 	cfg component.Config,
 	nextConsumer consumer.Metrics,
 ) (processor.Metrics, error) {
-	wasmProcessor, err := newWasmMetricsProcessor(ctx, cfg.(*Config))
+	wasmProcessor, err := newWasmMetricsProcessor(ctx, cfg.(*Config), set)
 	if err != nil {
 		return nil, err
 	}
-	return processorhelper.NewMetrics(ctx, set, cfg, nextConsumer,
+	p, err := processorhelper.NewMetrics(ctx, set, cfg, nextConsumer,
 		wasmProcessor.processMetrics,
-		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithCapabilities(wasmProcessor.capabilities()),
+		processorhelper.WithStart(wasmProcessor.start),
 		processorhelper.WithShutdown(wasmProcessor.shutdown),
 	)
+	if err != nil {
+		return nil, err
+	}
+	return bagMetricsProcessor{p}, nil
 }
 
 func createLogs(
@@ -72,13 +89,31 @@ func createLogs(
 	cfg component.Config,
 	nextConsumer consumer.Logs,
 ) (processor.Logs, error) {
-	wasmProcessor, err := newWasmLogsProcessor(ctx, cfg.(*Config))
+	wasmProcessor, err := newWasmLogsProcessor(ctx, cfg.(*Config), set)
 	if err != nil {
 		return nil, err
 	}
-	return processorhelper.NewLogs(ctx, set, cfg, nextConsumer,
+	p, err := processorhelper.NewLogs(ctx, set, cfg, nextConsumer,
 		wasmProcessor.processLogs,
-		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithCapabilities(wasmProcessor.capabilities()),
+		processorhelper.WithStart(wasmProcessor.start),
 		processorhelper.WithShutdown(wasmProcessor.shutdown),
 	)
+	if err != nil {
+		return nil, err
+	}
+	return bagLogsProcessor{p}, nil
+}
+
+func createProfiles(
+	ctx context.Context,
+	set processor.Settings,
+	cfg component.Config,
+	nextConsumer xconsumer.Profiles,
+) (xprocessor.Profiles, error) {
+	wasmProcessor, err := newWasmProfilesProcessor(ctx, cfg.(*Config), set)
+	if err != nil {
+		return nil, err
+	}
+	return &profilesProcessor{wasmProcessor: wasmProcessor, next: nextConsumer}, nil
 }
@@ -2,166 +2,389 @@ package wasmprocessor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/pipeline/xpipeline"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
 )
 
 const (
 	processTracesFunctionName  = "processTraces"
 	processMetricsFunctionName = "processMetrics"
 	processLogsFunctionName    = "processLogs"
+
+	processProfilesFunctionName = "processProfiles"
 )
 
+// wasmProcessor processes telemetry with a guest module, then passes the
+// result to the processor of the next module of the chain, if any.
+//
+// The telemetry passed to the guest is passed on as is if the guest reports
+// it unchanged, see wasmplugin.Stack.ResultUnchanged, or sets no result, in
+// which case the result on the stack is the zero value, e.g.
+// ptrace.Traces{}. Guests drop all the telemetry by setting an empty result.
 type wasmProcessor struct {
 	plugin *wasmplugin.WasmPlugin
+
+	// next is the processor of the next module of the chain. Nil for the
+	// last module.
+	next *wasmProcessor
+
+	// nextTraces consumes the result batches appended by the guests, see
+	// wasmplugin.Stack.ResultTracesBatches. The batches are discarded if nil,
+	// and while warming up.
+	nextTraces consumer.Traces
+
+	// resourceAttributes are injected into the telemetry returned by the
+	// last module of the chain. Only set on the last one.
+	resourceAttributes resourceAttributes
+
+	// warmUp processes the warm up batch through the chain once it is
+	// started, see Config.WarmUpPath. Only set on the first module, nil if
+	// there is no batch.
+	warmUp func(context.Context) error
 }
 
-func newWasmMetricsProcessor(ctx context.Context, cfg *Config) (*wasmProcessor, error) {
+// newWasmProcessor instantiates the modules of cfg supporting signal, and
+// chains them in order. Modules not supporting signal, or not exporting
+// functionName, are skipped.
+func newWasmProcessor(
+	ctx context.Context,
+	cfg *Config,
+	set processor.Settings,
+	signal pipeline.Signal,
+	functionName string,
+	isSupported func(*wasmplugin.WasmPlugin, context.Context) (bool, error),
+) (head *wasmProcessor, err error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 
-	// Specify required functions for the processor
-	requiredFunctions := []string{processMetricsFunctionName}
-
-	// Initialize the WASM plugin
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
-	if err != nil {
-		return nil, err
+	defer func() {
+		if err != nil && head != nil {
+			err = errors.Join(err, head.shutdown(ctx))
+		}
+	}()
+	var tail *wasmProcessor
+	var notExported []error
+	_, assigned := cfg.signalPath(signal)
+	for i, moduleCfg := range cfg.modules(signal) {
+		// The module set for the signal must support it, unlike the module
+		// of Path and the chained ones.
+		assignedModule := i == 0 && assigned
+		if moduleCfg.Path == "" {
+			// Path may be empty if the paths are set for the other signals.
+			continue
+		}
+
+		// Initialize the WASM plugin
+		plugin, err := wasmplugin.NewWasmPlugin(ctx, &moduleCfg, []string{functionName},
+			wasmplugin.WithMeterProvider(set.MeterProvider),
+			wasmplugin.WithTracerProvider(set.TracerProvider),
+			wasmplugin.WithLogger(set.Logger),
+			wasmplugin.WithComponent(set.ID.String(), set.Resource))
+		if errors.Is(err, wasmplugin.ErrRequiredFunctionNotExported) && !assignedModule {
+			// Guests only export the functions of the signals they support.
+			notExported = append(notExported, err)
+			continue
+		}
+		if err != nil && assignedModule {
+			return head, fmt.Errorf("%s_path: %w", signal, err)
+		}
+		if err != nil {
+			return head, err
+		}
+
+		supported, err := isSupported(plugin, ctx)
+		if err != nil {
+			return head, errors.Join(fmt.Errorf("failed to check %s support status: %w", signal, err), plugin.Shutdown(ctx))
+		}
+		if !supported && assignedModule {
+			return head, errors.Join(fmt.Errorf("%s_path: module %s doesn't support %s: %w",
+				signal, moduleCfg.Path, signal, pipeline.ErrSignalNotSupported), plugin.Shutdown(ctx))
+		}
+		if !supported {
+			if err := plugin.Shutdown(ctx); err != nil {
+				return head, err
+			}
+			continue
+		}
+
+		link := &wasmProcessor{plugin: plugin}
+		if head == nil {
+			head = link
+		} else {
+			tail.next = link
+		}
+		tail = link
 	}
-
-	if supported, err := plugin.IsMetricsSupported(ctx); err != nil {
-		return nil, fmt.Errorf("failed to check metrics support status: %w", err)
-	} else if !supported {
+	if tail != nil {
+		tail.resourceAttributes = cfg.InjectResourceAttributes
+	}
+	if head == nil {
+		if len(notExported) > 0 {
+			return nil, fmt.Errorf("%w: %w", pipeline.ErrSignalNotSupported, errors.Join(notExported...))
+		}
 		return nil, pipeline.ErrSignalNotSupported
 	}
-
-	return &wasmProcessor{
-		plugin: plugin,
-	}, nil
+	return head, nil
 }
 
-func newWasmLogsProcessor(ctx context.Context, cfg *Config) (*wasmProcessor, error) {
-	if err := cfg.Validate(); err != nil {
+func newWasmMetricsProcessor(ctx context.Context, cfg *Config, set processor.Settings) (*wasmProcessor, error) {
+	wp, err := newWasmProcessor(ctx, cfg, set, pipeline.SignalMetrics, processMetricsFunctionName,
+		(*wasmplugin.WasmPlugin).IsMetricsSupported)
+	if err != nil {
 		return nil, err
 	}
+	if err := wp.loadWarmUpMetrics(cfg.WarmUpPath); err != nil {
+		return nil, errors.Join(err, wp.shutdown(ctx))
+	}
+	return wp, nil
+}
 
-	// Specify required functions for the processor
-	requiredFunctions := []string{processLogsFunctionName}
-
-	// Initialize the WASM plugin
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
+func newWasmLogsProcessor(ctx context.Context, cfg *Config, set processor.Settings) (*wasmProcessor, error) {
+	wp, err := newWasmProcessor(ctx, cfg, set, pipeline.SignalLogs, processLogsFunctionName,
+		(*wasmplugin.WasmPlugin).IsLogsSupported)
 	if err != nil {
 		return nil, err
 	}
-
-	if supported, err := plugin.IsLogsSupported(ctx); err != nil {
-		return nil, fmt.Errorf("failed to check logs support status: %w", err)
-	} else if !supported {
-		return nil, pipeline.ErrSignalNotSupported
+	if err := wp.loadWarmUpLogs(cfg.WarmUpPath); err != nil {
+		return nil, errors.Join(err, wp.shutdown(ctx))
 	}
-
-	return &wasmProcessor{
-		plugin: plugin,
-	}, nil
+	return wp, nil
 }
 
-func newWasmTracesProcessor(ctx context.Context, cfg *Config) (*wasmProcessor, error) {
-	if err := cfg.Validate(); err != nil {
+func newWasmTracesProcessor(ctx context.Context, cfg *Config, set processor.Settings) (*wasmProcessor, error) {
+	wp, err := newWasmProcessor(ctx, cfg, set, pipeline.SignalTraces, processTracesFunctionName,
+		(*wasmplugin.WasmPlugin).IsTracesSupported)
+	if err != nil {
 		return nil, err
 	}
+	if err := wp.loadWarmUpTraces(cfg.WarmUpPath); err != nil {
+		return nil, errors.Join(err, wp.shutdown(ctx))
+	}
+	return wp, nil
+}
 
-	// Specify required functions for the processor
-	requiredFunctions := []string{processTracesFunctionName}
-
-	// Initialize the WASM plugin
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
+func newWasmProfilesProcessor(ctx context.Context, cfg *Config, set processor.Settings) (*wasmProcessor, error) {
+	wp, err := newWasmProcessor(ctx, cfg, set, xpipeline.SignalProfiles, processProfilesFunctionName,
+		(*wasmplugin.WasmPlugin).IsProfilesSupported)
 	if err != nil {
 		return nil, err
 	}
-
-	if supported, err := plugin.IsTracesSupported(ctx); err != nil {
-		return nil, fmt.Errorf("failed to check traces support status: %w", err)
-	} else if !supported {
-		return nil, pipeline.ErrSignalNotSupported
+	if err := wp.loadWarmUpProfiles(cfg.WarmUpPath); err != nil {
+		return nil, errors.Join(err, wp.shutdown(ctx))
 	}
-
-	return &wasmProcessor{
-		plugin: plugin,
-	}, nil
+	return wp, nil
 }
 
 func (wp *wasmProcessor) processTraces(
 	ctx context.Context,
 	td ptrace.Traces,
 ) (ptrace.Traces, error) {
-	stack := &wasmplugin.Stack{
-		CurrentTraces:    td,
-		PluginConfigJSON: wp.plugin.PluginConfigJSON,
-	}
+	stack := wasmplugin.AcquireStack()
+	defer wasmplugin.ReleaseStack(stack)
+	stack.CurrentTraces = td
+	stack.Bag = wasmplugin.BagFromContext(ctx)
 
 	res, err := wp.plugin.ProcessFunctionCall(ctx, processTracesFunctionName, stack)
 	if err != nil {
 		return td, err
 	}
 
-	statusCode := wasmplugin.StatusCode(res[0])
-	if statusCode != 0 {
-		return td, fmt.Errorf("wasm: error processing traces: %s: %s", statusCode.String(), stack.StatusReason)
+	if err := wp.plugin.CheckStatus(ctx, processTracesFunctionName, res, stack); err != nil {
+		return td, fmt.Errorf("wasm: error processing traces: %w", err)
+	}
+
+	if len(stack.ResultTracesBatches) > 0 {
+		return td, wp.consumeTracesBatches(ctx, stack.ResultTracesBatches)
 	}
 
-	return stack.ResultTraces, nil
+	result := stack.ResultTraces
+	if stack.ResultUnchanged || result == (ptrace.Traces{}) {
+		result = td
+	}
+	if wp.next != nil {
+		return wp.next.processTraces(ctx, result)
+	}
+	wp.resourceAttributes.injectTraces(result)
+	return result, nil
+}
+
+// consumeTracesBatches passes each batch through the rest of the chain, then
+// to the next consumer, on its own. processorhelper.ErrSkipProcessingData is
+// returned on success, as the batches were consumed already.
+func (wp *wasmProcessor) consumeTracesBatches(ctx context.Context, batches []ptrace.Traces) error {
+	var errs []error
+	for _, batch := range batches {
+		if wp.next != nil {
+			var err error
+			if batch, err = wp.next.processTraces(ctx, batch); errors.Is(err, processorhelper.ErrSkipProcessingData) {
+				continue
+			} else if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		} else {
+			wp.resourceAttributes.injectTraces(batch)
+		}
+		if wp.nextTraces == nil || isWarmUp(ctx) {
+			continue
+		}
+		if err := wp.nextTraces.ConsumeTraces(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return processorhelper.ErrSkipProcessingData
 }
 
 func (wp *wasmProcessor) processMetrics(
 	ctx context.Context,
 	md pmetric.Metrics,
 ) (pmetric.Metrics, error) {
-	stack := &wasmplugin.Stack{
-		CurrentMetrics:   md,
-		PluginConfigJSON: wp.plugin.PluginConfigJSON,
-	}
+	stack := wasmplugin.AcquireStack()
+	defer wasmplugin.ReleaseStack(stack)
+	stack.CurrentMetrics = md
+	stack.Bag = wasmplugin.BagFromContext(ctx)
 
 	res, err := wp.plugin.ProcessFunctionCall(ctx, processMetricsFunctionName, stack)
 	if err != nil {
 		return md, err
 	}
 
-	statusCode := wasmplugin.StatusCode(res[0])
-	if statusCode != 0 {
-		return md, fmt.Errorf("wasm: error processing metrics: %s: %s", statusCode.String(), stack.StatusReason)
+	if err := wp.plugin.CheckStatus(ctx, processMetricsFunctionName, res, stack); err != nil {
+		return md, fmt.Errorf("wasm: error processing metrics: %w", err)
 	}
 
-	return stack.ResultMetrics, nil
+	result := stack.ResultMetrics
+	if stack.ResultUnchanged || result == (pmetric.Metrics{}) {
+		result = md
+	}
+	if wp.next != nil {
+		return wp.next.processMetrics(ctx, result)
+	}
+	wp.resourceAttributes.injectMetrics(result)
+	return result, nil
 }
 
 func (wp *wasmProcessor) processLogs(
 	ctx context.Context,
 	ld plog.Logs,
 ) (plog.Logs, error) {
-	stack := &wasmplugin.Stack{
-		CurrentLogs:      ld,
-		PluginConfigJSON: wp.plugin.PluginConfigJSON,
-	}
+	stack := wasmplugin.AcquireStack()
+	defer wasmplugin.ReleaseStack(stack)
+	stack.CurrentLogs = ld
+	stack.Bag = wasmplugin.BagFromContext(ctx)
 
 	res, err := wp.plugin.ProcessFunctionCall(ctx, processLogsFunctionName, stack)
 	if err != nil {
 		return ld, err
 	}
 
-	statusCode := wasmplugin.StatusCode(res[0])
-	if statusCode != 0 {
-		return ld, fmt.Errorf("wasm: error processing logs: %s: %s", statusCode.String(), stack.StatusReason)
+	if err := wp.plugin.CheckStatus(ctx, processLogsFunctionName, res, stack); err != nil {
+		return ld, fmt.Errorf("wasm: error processing logs: %w", err)
 	}
 
-	return stack.ResultLogs, nil
+	result := stack.ResultLogs
+	if stack.ResultUnchanged || result == (plog.Logs{}) {
+		result = ld
+	}
+	if wp.next != nil {
+		return wp.next.processLogs(ctx, result)
+	}
+	wp.resourceAttributes.injectLogs(result)
+	return result, nil
 }
 
+func (wp *wasmProcessor) processProfiles(
+	ctx context.Context,
+	pd pprofile.Profiles,
+) (pprofile.Profiles, error) {
+	stack := wasmplugin.AcquireStack()
+	defer wasmplugin.ReleaseStack(stack)
+	stack.CurrentProfiles = pd
+	stack.Bag = wasmplugin.BagFromContext(ctx)
+
+	res, err := wp.plugin.ProcessFunctionCall(ctx, processProfilesFunctionName, stack)
+	if err != nil {
+		return pd, err
+	}
+
+	if err := wp.plugin.CheckStatus(ctx, processProfilesFunctionName, res, stack); err != nil {
+		return pd, fmt.Errorf("wasm: error processing profiles: %w", err)
+	}
+
+	result := stack.ResultProfiles
+	if stack.ResultUnchanged || result == (pprofile.Profiles{}) {
+		result = pd
+	}
+	if wp.next != nil {
+		return wp.next.processProfiles(ctx, result)
+	}
+	wp.resourceAttributes.injectProfiles(result)
+	return result, nil
+}
+
+// capabilities returns the consumer capabilities declared by the guests of
+// the chain. The data is mutated if any guest mutates it, or resource
+// attributes are injected into it.
+func (wp *wasmProcessor) capabilities() consumer.Capabilities {
+	mutatesData := wp.plugin.Capabilities().MutatesData() || len(wp.resourceAttributes) > 0
+	if wp.next != nil {
+		mutatesData = mutatesData || wp.next.capabilities().MutatesData
+	}
+	return consumer.Capabilities{MutatesData: mutatesData}
+}
+
+// start reports the status of the processor, for the health check extension:
+// starting, then OK if the guests of the chain are ready. They were
+// instantiated at creation, and are started in chain order, then warmed up.
+func (wp *wasmProcessor) start(ctx context.Context, host component.Host) error {
+	componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusStarting))
+	for link := wp; link != nil; link = link.next {
+		if !link.plugin.Ready() {
+			componentstatus.ReportStatus(host, componentstatus.NewPermanentErrorEvent(wasmplugin.ErrGuestNotReady))
+			return nil
+		}
+	}
+	for link := wp; link != nil; link = link.next {
+		if err := link.plugin.StartGuest(ctx); err != nil {
+			return fmt.Errorf("wasm: error starting guest: %w", err)
+		}
+	}
+	if wp.warmUp != nil {
+		if err := wp.warmUp(ctx); err != nil {
+			return err
+		}
+	}
+	componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusOK))
+	return nil
+}
+
+// shutdown shuts the guests of the chain down. Each guest is shut down
+// before its runtime is closed, which is closed regardless.
 func (wp *wasmProcessor) shutdown(ctx context.Context) error {
-	return wp.plugin.Shutdown(ctx)
+	var err error
+	if guestErr := wp.plugin.ShutdownGuest(ctx); guestErr != nil {
+		err = fmt.Errorf("wasm: error shutting down guest: %w", guestErr)
+	}
+	err = errors.Join(err, wp.plugin.Shutdown(ctx))
+	if wp.next != nil {
+		err = errors.Join(err, wp.next.shutdown(ctx))
+	}
+	return err
 }
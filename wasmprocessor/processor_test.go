@@ -1,11 +1,25 @@
 package wasmprocessor
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	goruntime "runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/otelwasm/otelwasm/wasmplugin"
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
@@ -56,6 +70,34 @@ func TestCreateTracesProcessor(t *testing.T) {
 	}
 }
 
+func TestQuotaFromFactoryConfig(t *testing.T) {
+	// The quota is left to its defaults by the factory, which must still
+	// enforce the limit.
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Path = "testdata/nop/main.wasm"
+	cfg.Quota.MaxCalls = 2
+	ctx := t.Context()
+
+	tp, err := factory.CreateTraces(ctx, processortest.NewNopSettings(typeStr), cfg, consumertest.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create traces processor: %v", err)
+	}
+	if err := tp.Start(ctx, componenttest.NewNopHost()); err != nil {
+		t.Fatalf("failed to start processor: %v", err)
+	}
+	defer tp.Shutdown(ctx)
+
+	for i := 0; i < 2; i++ {
+		if err := tp.ConsumeTraces(ctx, generateExampleTraces()); err != nil {
+			t.Fatalf("call %d: failed to process traces: %v", i, err)
+		}
+	}
+	if err := tp.ConsumeTraces(ctx, generateExampleTraces()); !errors.Is(err, wasmplugin.ErrQuotaExceeded) {
+		t.Errorf("expected the quota to be exceeded, got %v", err)
+	}
+}
+
 func TestCreateMetricsProcessor(t *testing.T) {
 	// Test that the processor can be created with the default config
 	factory := NewFactory()
@@ -112,10 +154,11 @@ func TestProcessTracesWithNopProcessor(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/nop/main.wasm"
 	ctx := t.Context()
-	wasmProc, err := newWasmTracesProcessor(ctx, cfg)
+	wasmProc, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm processor: %v", err)
 	}
+	t.Cleanup(func() { wasmProc.shutdown(ctx) })
 
 	// Create test traces with 1 resource, 1 scope, and 1 span
 	traces := ptrace.NewTraces()
@@ -159,11 +202,13 @@ func TestProcessTracesWithNopProcessor(t *testing.T) {
 func TestProcessTracesWithCurlProcessor(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/curl/main.wasm"
+	cfg.HTTP.AllowedHosts = []string{"example.com"}
 	ctx := t.Context()
-	wasmProc, err := newWasmTracesProcessor(ctx, cfg)
+	wasmProc, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm processor: %v", err)
 	}
+	t.Cleanup(func() { wasmProc.shutdown(ctx) })
 
 	// Create test traces with 1 resource, 1 scope, and 1 span
 	traces := ptrace.NewTraces()
@@ -187,10 +232,11 @@ func TestProcessMetricsWithNopProcessor(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/nop/main.wasm"
 	ctx := t.Context()
-	wasmProc, err := newWasmMetricsProcessor(ctx, cfg)
+	wasmProc, err := newWasmMetricsProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm processor: %v", err)
 	}
+	t.Cleanup(func() { wasmProc.shutdown(ctx) })
 
 	// Create test metrics with 1 resource, 1 scope, and 1 metric
 	metrics := pmetric.NewMetrics()
@@ -237,10 +283,11 @@ func TestProcessLogsWithNopProcessor(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/nop/main.wasm"
 	ctx := t.Context()
-	wasmProc, err := newWasmLogsProcessor(ctx, cfg)
+	wasmProc, err := newWasmLogsProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm processor: %v", err)
 	}
+	t.Cleanup(func() { wasmProc.shutdown(ctx) })
 
 	// Create test logs with 1 resource, 1 scope, and 1 log record
 	logs := plog.NewLogs()
@@ -291,10 +338,11 @@ func TestProcessTracesWithAddNewAttributeProcessor(t *testing.T) {
 		"attribute_value": "new-value",
 	}
 	ctx := t.Context()
-	wasmProc, err := newWasmTracesProcessor(ctx, cfg)
+	wasmProc, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
 	if err != nil {
 		t.Fatalf("failed to create wasm processor: %v", err)
 	}
+	t.Cleanup(func() { wasmProc.shutdown(ctx) })
 
 	// Create test traces with 1 resource, 1 scope, and 1 span
 	traces := ptrace.NewTraces()
@@ -343,6 +391,89 @@ func TestProcessTracesWithAddNewAttributeProcessor(t *testing.T) {
 	}
 }
 
+func TestProcessTracesWithProbabilisticSampler(t *testing.T) {
+	newProcessor := func(t *testing.T, percentage any) *wasmProcessor {
+		t.Helper()
+		cfg := createDefaultConfig().(*Config)
+		cfg.Path = "testdata/probabilistic_sampler/main.wasm"
+		cfg.PluginConfig = wasmplugin.PluginConfig{"sampling_percentage": percentage}
+		wasmProc, err := newWasmTracesProcessor(t.Context(), cfg, processortest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wasmProc.shutdown(t.Context()) })
+		return wasmProc
+	}
+
+	t.Run("keep ratio", func(t *testing.T) {
+		wasmProc := newProcessor(t, 25)
+
+		// Each trace has 2 spans, in scopes of their own, so whole traces
+		// and empty scopes can be told apart.
+		const traceCount = 4000
+		random := rand.New(rand.NewPCG(1, 2))
+		traces := ptrace.NewTraces()
+		rs := traces.ResourceSpans().AppendEmpty()
+		for range traceCount {
+			var traceID pcommon.TraceID
+			binary.LittleEndian.PutUint64(traceID[:8], random.Uint64())
+			binary.LittleEndian.PutUint64(traceID[8:], random.Uint64())
+			for range 2 {
+				rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetTraceID(traceID)
+			}
+		}
+
+		processed, err := wasmProc.processTraces(t.Context(), traces)
+		if err != nil {
+			t.Fatalf("failed to process traces: %v", err)
+		}
+
+		spansByTrace := map[pcommon.TraceID]int{}
+		sss := processed.ResourceSpans().At(0).ScopeSpans()
+		for i := 0; i < sss.Len(); i++ {
+			spans := sss.At(i).Spans()
+			if spans.Len() != 1 {
+				t.Fatalf("expected the scopes to keep their span, got %d spans", spans.Len())
+			}
+			spansByTrace[spans.At(0).TraceID()]++
+		}
+		for traceID, count := range spansByTrace {
+			if count != 2 {
+				t.Fatalf("expected the trace %v to be kept whole, got %d spans", traceID, count)
+			}
+		}
+		if ratio := float64(len(spansByTrace)) / traceCount; ratio < 0.22 || ratio > 0.28 {
+			t.Errorf("expected about 25%% of the traces to be kept, got %.1f%%", ratio*100)
+		}
+	})
+
+	t.Run("drop all", func(t *testing.T) {
+		wasmProc := newProcessor(t, 0)
+		traces := ptrace.NewTraces()
+		traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetTraceID([16]byte{1})
+
+		processed, err := wasmProc.processTraces(t.Context(), traces)
+		if err != nil {
+			t.Fatalf("failed to process traces: %v", err)
+		}
+		if processed.ResourceSpans().Len() != 0 {
+			t.Errorf("expected the empty resources to be removed, got %d", processed.ResourceSpans().Len())
+		}
+	})
+
+	t.Run("invalid percentage", func(t *testing.T) {
+		wasmProc := newProcessor(t, 150)
+		traces := ptrace.NewTraces()
+		traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+
+		_, err := wasmProc.processTraces(t.Context(), traces)
+		var guestErr *wasmplugin.GuestError
+		if !errors.As(err, &guestErr) || !strings.Contains(guestErr.StatusReason, "sampling_percentage") {
+			t.Errorf("expected the config to be refused, got %v", err)
+		}
+	})
+}
+
 func TestConfigValidate(t *testing.T) {
 	// Test that the config validation works as expected
 	cfg := createDefaultConfig().(*Config)
@@ -351,3 +482,690 @@ func TestConfigValidate(t *testing.T) {
 		t.Errorf("config validation failed: %v", err)
 	}
 }
+
+func TestUpdateConfigWithAddNewAttributeProcessor(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/add_new_attribute/main.wasm"
+	cfg.PluginConfig = wasmplugin.PluginConfig{
+		"attribute_name":  "new-attribute",
+		"attribute_value": "new-value",
+	}
+	ctx := t.Context()
+	wasmProc, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wasmProc.shutdown(ctx) })
+	module := wasmProc.plugin.Module
+
+	attributeValue := func() string {
+		t.Helper()
+		traces := ptrace.NewTraces()
+		traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+
+		processedTraces, err := wasmProc.processTraces(ctx, traces)
+		if err != nil {
+			t.Fatalf("failed to process traces: %v", err)
+		}
+		val, _ := processedTraces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get("new-attribute")
+		return val.Str()
+	}
+
+	if got := attributeValue(); got != "new-value" {
+		t.Errorf("expected new-attribute to be 'new-value', got %q", got)
+	}
+
+	err = wasmProc.plugin.UpdateConfig(ctx, wasmplugin.PluginConfig{
+		"attribute_name":  "new-attribute",
+		"attribute_value": "updated-value",
+	})
+	if err != nil {
+		t.Fatalf("failed to update config: %v", err)
+	}
+
+	if got := attributeValue(); got != "updated-value" {
+		t.Errorf("expected new-attribute to be 'updated-value', got %q", got)
+	}
+	if wasmProc.plugin.Module != module {
+		t.Error("expected the guest module to be kept across config updates")
+	}
+}
+
+func TestRedactEmail(t *testing.T) {
+	newTraces := func() ptrace.Traces {
+		traces := ptrace.NewTraces()
+		span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		span.Attributes().PutStr("user.email", "alice@example.com")
+		span.Attributes().PutStr("http.method", "GET")
+		return traces
+	}
+
+	t.Run("salted", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		cfg.Path = "testdata/redact_email/main.wasm"
+		cfg.Hash.Salt = "pepper"
+		ctx := t.Context()
+		wp, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+
+		processed, err := wp.processTraces(ctx, newTraces())
+		if err != nil {
+			t.Fatalf("failed to process traces: %v", err)
+		}
+		mac := hmac.New(sha256.New, []byte("pepper"))
+		mac.Write([]byte("alice@example.com"))
+		attrs := processed.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes()
+		if v, _ := attrs.Get("user.email"); v.Str() != hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("expected the email to be replaced by its salted digest, got %q", v.Str())
+		}
+		if v, _ := attrs.Get("http.method"); v.Str() != "GET" {
+			t.Errorf("expected the other attributes to be kept, got %q", v.Str())
+		}
+	})
+
+	t.Run("no salt", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		cfg.Path = "testdata/redact_email/main.wasm"
+		ctx := t.Context()
+		wp, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+
+		// The guest refuses to pass the addresses on unhashed.
+		if _, err := wp.processTraces(ctx, newTraces()); err == nil {
+			t.Error("expected the processing to fail without a salt")
+		}
+	})
+}
+
+// redactConfig returns the config of the redact example processor.
+func TestSlowSpanEvent(t *testing.T) {
+	ctx := t.Context()
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/slow_span_event/main.wasm"
+	cfg.PluginConfig = wasmplugin.PluginConfig{"threshold": "500ms"}
+	wp, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+
+	start := time.Unix(1700000000, 0)
+	traces := ptrace.NewTraces()
+	spans := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for _, d := range []time.Duration{100 * time.Millisecond, 2 * time.Second} {
+		span := spans.AppendEmpty()
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(d)))
+	}
+	processed, err := wp.processTraces(ctx, traces)
+	if err != nil {
+		t.Fatalf("failed to process traces: %v", err)
+	}
+
+	spans = processed.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	if n := spans.At(0).Events().Len(); n != 0 {
+		t.Errorf("expected no event on the fast span, got %d", n)
+	}
+	events := spans.At(1).Events()
+	if events.Len() != 1 {
+		t.Fatalf("expected an event on the slow span, got %d", events.Len())
+	}
+	e := events.At(0)
+	if e.Name() != "otelwasm.slow_span" || !e.Timestamp().AsTime().Equal(start.Add(2*time.Second)) {
+		t.Errorf("expected the slow span event at the end of the span, got %s at %v", e.Name(), e.Timestamp().AsTime())
+	}
+	if d, ok := e.Attributes().Get("duration_ms"); !ok || d.Int() != 2000 {
+		t.Errorf("expected a duration of 2000ms, got %v", e.Attributes().AsRaw())
+	}
+}
+
+func redactConfig(pluginConfig wasmplugin.PluginConfig) *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/redact/main.wasm"
+	cfg.PluginConfig = pluginConfig
+	return cfg
+}
+
+// putSensitive sets sensitive attributes, of every type and nested, on attrs
+// along with a harmless one.
+func putSensitive(attrs pcommon.Map) {
+	attrs.PutStr("user.email", "alice@example.com")
+	attrs.PutInt("user.ssn", 123456789)
+	nested := attrs.PutEmptyMap("http.request")
+	nested.PutStr("password", "hunter2")
+	nested.PutStr("method", "GET")
+	attrs.PutEmptySlice("credentials").AppendEmpty().SetEmptyMap().PutStr("password", "swordfish")
+	attrs.PutStr("service.version", "1.0")
+}
+
+// checkRedacted checks that the sensitive attributes of putSensitive are
+// replaced by want, and the others passed through.
+func checkRedacted(t *testing.T, attrs pcommon.Map, want func(original string) string) {
+	t.Helper()
+	nested, _ := attrs.Get("http.request")
+	credentials, _ := attrs.Get("credentials")
+	for name, tc := range map[string]struct {
+		attrs    pcommon.Map
+		key      string
+		original string
+	}{
+		"string":          {attrs, "user.email", "alice@example.com"},
+		"int":             {attrs, "user.ssn", "123456789"},
+		"nested map":      {nested.Map(), "password", "hunter2"},
+		"map in a slice":  {credentials.Slice().At(0).Map(), "password", "swordfish"},
+		"kept":            {attrs, "service.version", ""},
+		"kept nested map": {nested.Map(), "method", ""},
+	} {
+		v, ok := tc.attrs.Get(tc.key)
+		if !ok {
+			t.Errorf("%s: expected %s to be kept", name, tc.key)
+			continue
+		}
+		if tc.original == "" {
+			if v.Str() == "" || v.Str() == "****" {
+				t.Errorf("%s: expected %s to pass through, got %q", name, tc.key, v.AsString())
+			}
+			continue
+		}
+		if got := v.AsString(); got != want(tc.original) {
+			t.Errorf("%s: expected %s to be redacted to %q, got %q", name, tc.key, want(tc.original), got)
+		}
+	}
+}
+
+func TestRedactProcessor(t *testing.T) {
+	patterns := []any{`^user\.`, `(?i)password`}
+	mask := func(string) string { return "[redacted]" }
+	ctx := t.Context()
+	set := processortest.NewNopSettings(typeStr)
+	cfg := redactConfig(wasmplugin.PluginConfig{"patterns": patterns, "mask": "[redacted]"})
+
+	t.Run("traces", func(t *testing.T) {
+		wp, err := newWasmTracesProcessor(ctx, cfg, set)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+
+		traces := ptrace.NewTraces()
+		rs := traces.ResourceSpans().AppendEmpty()
+		putSensitive(rs.Resource().Attributes())
+		span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		putSensitive(span.Attributes())
+		putSensitive(span.Events().AppendEmpty().Attributes())
+		processed, err := wp.processTraces(ctx, traces)
+		if err != nil {
+			t.Fatalf("failed to process traces: %v", err)
+		}
+		rs = processed.ResourceSpans().At(0)
+		span = rs.ScopeSpans().At(0).Spans().At(0)
+		checkRedacted(t, rs.Resource().Attributes(), mask)
+		checkRedacted(t, span.Attributes(), mask)
+		checkRedacted(t, span.Events().At(0).Attributes(), mask)
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		wp, err := newWasmMetricsProcessor(ctx, cfg, set)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+
+		metrics := pmetric.NewMetrics()
+		ms := metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+		putSensitive(ms.AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty().Attributes())
+		putSensitive(ms.AppendEmpty().SetEmptyHistogram().DataPoints().AppendEmpty().Attributes())
+		processed, err := wp.processMetrics(ctx, metrics)
+		if err != nil {
+			t.Fatalf("failed to process metrics: %v", err)
+		}
+		ms = processed.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		checkRedacted(t, ms.At(0).Gauge().DataPoints().At(0).Attributes(), mask)
+		checkRedacted(t, ms.At(1).Histogram().DataPoints().At(0).Attributes(), mask)
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		wp, err := newWasmLogsProcessor(ctx, cfg, set)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+
+		logs := plog.NewLogs()
+		putSensitive(logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Attributes())
+		processed, err := wp.processLogs(ctx, logs)
+		if err != nil {
+			t.Fatalf("failed to process logs: %v", err)
+		}
+		checkRedacted(t, processed.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes(), mask)
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		cfg := redactConfig(wasmplugin.PluginConfig{"patterns": patterns, "action": "hash"})
+		cfg.Hash.Salt = "pepper"
+		wp, err := newWasmLogsProcessor(ctx, cfg, set)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+
+		logs := plog.NewLogs()
+		putSensitive(logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Attributes())
+		processed, err := wp.processLogs(ctx, logs)
+		if err != nil {
+			t.Fatalf("failed to process logs: %v", err)
+		}
+		digest := func(original string) string {
+			mac := hmac.New(sha256.New, []byte("pepper"))
+			mac.Write([]byte(original))
+			return hex.EncodeToString(mac.Sum(nil))
+		}
+		checkRedacted(t, processed.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes(), digest)
+	})
+
+	for name, pluginConfig := range map[string]wasmplugin.PluginConfig{
+		"no pattern":      {},
+		"empty pattern":   {"patterns": []any{""}},
+		"invalid pattern": {"patterns": []any{"("}},
+		"invalid action":  {"patterns": patterns, "action": "drop"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			wp, err := newWasmLogsProcessor(ctx, redactConfig(pluginConfig), set)
+			if err != nil {
+				t.Fatalf("failed to create wasm processor: %v", err)
+			}
+			t.Cleanup(func() { wp.shutdown(ctx) })
+			if _, err := wp.processLogs(ctx, plog.NewLogs()); err == nil {
+				t.Error("expected the invalid config to fail the processing")
+			}
+		})
+	}
+}
+
+func TestProcessorCapabilities(t *testing.T) {
+	tests := []struct {
+		name            string
+		capabilities    []wasmtest.Function
+		wantMutatesData bool
+	}{
+		{name: "undeclared", wantMutatesData: true},
+		{
+			name: "declared read only",
+			capabilities: []wasmtest.Function{{
+				Export:  "getCapabilities",
+				Results: []api.ValueType{api.ValueTypeI32},
+				Body:    wasmtest.I32Const(0),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := wasmtest.NewGuest(4, tt.capabilities...)
+			mod.Functions = append(mod.Functions, wasmtest.Function{
+				Export:  processTracesFunctionName,
+				Results: []api.ValueType{api.ValueTypeI32},
+				Body:    wasmtest.I32Const(0),
+			})
+			p := createTestTracesProcessor(t, mod, consumertest.NewNop())
+			if got := p.Capabilities().MutatesData; got != tt.wantMutatesData {
+				t.Errorf("expected MutatesData %v, got %v", tt.wantMutatesData, got)
+			}
+		})
+	}
+}
+
+// statusHost is a host recording the status reported by the components.
+type statusHost struct {
+	component.Host
+	events []*componentstatus.Event
+}
+
+func (h *statusHost) Report(event *componentstatus.Event) {
+	h.events = append(h.events, event)
+}
+
+func TestProcessorStartStatus(t *testing.T) {
+	t.Run("ready", func(t *testing.T) {
+		p := createTestTracesProcessor(t, bagWriterGuest(), consumertest.NewNop())
+		host := &statusHost{Host: componenttest.NewNopHost()}
+		if err := p.Start(t.Context(), host); err != nil {
+			t.Fatalf("failed to start processor: %v", err)
+		}
+		if len(host.events) != 2 || host.events[0].Status() != componentstatus.StatusStarting || host.events[1].Status() != componentstatus.StatusOK {
+			t.Errorf("expected the starting then OK status, got %v", host.events)
+		}
+	})
+
+	t.Run("guest closed", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		cfg.Path = bagWriterGuest().Write(t)
+		wp, err := newWasmTracesProcessor(t.Context(), cfg, processortest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		if err := wp.shutdown(t.Context()); err != nil {
+			t.Fatalf("failed to shut the guest down: %v", err)
+		}
+		host := &statusHost{Host: componenttest.NewNopHost()}
+		if err := wp.start(t.Context(), host); err != nil {
+			t.Fatalf("failed to start processor: %v", err)
+		}
+		if len(host.events) != 2 || host.events[1].Status() != componentstatus.StatusPermanentError ||
+			!errors.Is(host.events[1].Err(), wasmplugin.ErrGuestNotReady) {
+			t.Errorf("expected a permanent error status, got %v", host.events)
+		}
+	})
+}
+
+func TestStartAndShutdownCallGuestHooks(t *testing.T) {
+	// The guest start and shutdown fail with "started" and "flushed" as the
+	// status reason, so the test can tell they were called.
+	mod := wasmtest.NewGuest(4).
+		Import(wasmtest.HostModule, "setResultStatusReason", []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: []byte("startedflushed")}}
+	mod.Functions = append(mod.Functions,
+		wasmtest.Function{
+			Export:  processTracesFunctionName,
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    wasmtest.I32Const(0),
+		},
+		wasmtest.Function{
+			Export:  "start",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(7), mod.Call("setResultStatusReason"),
+				wasmtest.I32Const(1),
+			),
+		},
+		wasmtest.Function{
+			Export:  "shutdown",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(7), wasmtest.I32Const(7), mod.Call("setResultStatusReason"),
+				wasmtest.I32Const(1),
+			),
+		},
+	)
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	wp, err := newWasmTracesProcessor(t.Context(), cfg, processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+
+	host := componenttest.NewNopHost()
+	var guestErr *wasmplugin.GuestError
+	if err := wp.start(t.Context(), host); !errors.As(err, &guestErr) || guestErr.StatusReason != "started" {
+		t.Fatalf("expected the guest start error, got %v", err)
+	}
+	if err := wp.start(t.Context(), host); err != nil {
+		t.Errorf("expected the guest to be started once, got %v", err)
+	}
+	if err := wp.shutdown(t.Context()); !errors.As(err, &guestErr) || guestErr.StatusReason != "flushed" {
+		t.Fatalf("expected the guest shutdown error, got %v", err)
+	}
+}
+
+func TestProcessTracesResultBatches(t *testing.T) {
+	// processTraces appends the current traces twice to the result batches.
+	mod := wasmtest.NewGuest(4).
+		Import(wasmtest.HostModule, "currentTraces", []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, "appendResultTraces", []api.ValueType{i32, i32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  processTracesFunctionName,
+		Results: []api.ValueType{i32},
+		Locals:  []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(1024), wasmtest.I32Const(60000), mod.Call("currentTraces"), wasmtest.LocalSet(0),
+			wasmtest.I32Const(1024), wasmtest.LocalGet(0), mod.Call("appendResultTraces"),
+			wasmtest.I32Const(1024), wasmtest.LocalGet(0), mod.Call("appendResultTraces"),
+			wasmtest.I32Const(0),
+		),
+	})
+	sink := new(consumertest.TracesSink)
+	p := createTestTracesProcessor(t, mod, sink)
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+	if err := p.ConsumeTraces(t.Context(), traces); err != nil {
+		t.Fatalf("failed to process traces: %v", err)
+	}
+
+	batches := sink.AllTraces()
+	if len(batches) != 2 {
+		t.Fatalf("expected each result batch to be consumed on its own, got %d batches", len(batches))
+	}
+	for i, batch := range batches {
+		if batch.SpanCount() != 1 {
+			t.Errorf("expected batch %d to hold the span, got %d spans", i, batch.SpanCount())
+		}
+	}
+}
+
+func TestProcessResultUnchanged(t *testing.T) {
+	// Each process function sets the current telemetry as its result, then
+	// reports it unchanged.
+	mod := wasmtest.NewGuest(7).
+		Import(wasmtest.HostModule, "setResultUnchanged", nil, nil)
+	for _, signal := range []string{"Traces", "Metrics", "Logs"} {
+		mod.Import(wasmtest.HostModule, "current"+signal, []api.ValueType{i32, i32}, []api.ValueType{i32}).
+			Import(wasmtest.HostModule, "setResult"+signal, []api.ValueType{i32, i32}, nil)
+		mod.Functions = append(mod.Functions, wasmtest.Function{
+			Export:  "process" + signal,
+			Results: []api.ValueType{i32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(1024),
+				wasmtest.I32Const(1024), wasmtest.I32Const(60000), mod.Call("current"+signal),
+				mod.Call("setResult"+signal),
+				mod.Call("setResultUnchanged"),
+				wasmtest.I32Const(0),
+			),
+		})
+	}
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	ctx := t.Context()
+	settings := processortest.NewNopSettings(typeStr)
+
+	t.Run("traces", func(t *testing.T) {
+		wp, err := newWasmTracesProcessor(ctx, cfg, settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		td := ptrace.NewTraces()
+		td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+		result, err := wp.processTraces(ctx, td)
+		if err != nil {
+			t.Fatalf("failed to process traces: %v", err)
+		}
+		if result != td {
+			t.Error("expected the traces passed to the guest to be passed on")
+		}
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		wp, err := newWasmMetricsProcessor(ctx, cfg, settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		md := pmetric.NewMetrics()
+		md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetName("test-metric")
+		result, err := wp.processMetrics(ctx, md)
+		if err != nil {
+			t.Fatalf("failed to process metrics: %v", err)
+		}
+		if result != md {
+			t.Error("expected the metrics passed to the guest to be passed on")
+		}
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		wp, err := newWasmLogsProcessor(ctx, cfg, settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		ld := plog.NewLogs()
+		ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("test-log")
+		result, err := wp.processLogs(ctx, ld)
+		if err != nil {
+			t.Fatalf("failed to process logs: %v", err)
+		}
+		if result != ld {
+			t.Error("expected the logs passed to the guest to be passed on")
+		}
+	})
+}
+
+func TestProcessWithoutResult(t *testing.T) {
+	// processTraces, processMetrics and processLogs set no result.
+	noResult := wasmtest.NewGuest(7)
+	// They set an empty result.
+	emptyResult := wasmtest.NewGuest(7)
+	for _, signal := range []string{"Traces", "Metrics", "Logs"} {
+		noResult.Functions = append(noResult.Functions, wasmtest.Function{
+			Export:  "process" + signal,
+			Results: []api.ValueType{i32},
+			Body:    wasmtest.I32Const(0),
+		})
+		emptyResult.Import(wasmtest.HostModule, "setResult"+signal, []api.ValueType{i32, i32}, nil)
+		emptyResult.Functions = append(emptyResult.Functions, wasmtest.Function{
+			Export:  "process" + signal,
+			Results: []api.ValueType{i32},
+			Body: wasmtest.Instructions(
+				wasmtest.I32Const(0), wasmtest.I32Const(0), emptyResult.Call("setResult"+signal),
+				wasmtest.I32Const(0),
+			),
+		})
+	}
+	ctx := t.Context()
+	settings := processortest.NewNopSettings(typeStr)
+	newConfig := func(t *testing.T, mod *wasmtest.Module) *Config {
+		cfg := createDefaultConfig().(*Config)
+		cfg.Path = mod.Write(t)
+		return cfg
+	}
+
+	t.Run("traces", func(t *testing.T) {
+		td := ptrace.NewTraces()
+		td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+
+		wp, err := newWasmTracesProcessor(ctx, newConfig(t, noResult), settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		if result, err := wp.processTraces(ctx, td); err != nil || result != td {
+			t.Errorf("expected the traces to be passed on without a result, got %v", err)
+		}
+
+		wp, err = newWasmTracesProcessor(ctx, newConfig(t, emptyResult), settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		if result, err := wp.processTraces(ctx, td); err != nil || result.ResourceSpans().Len() != 0 {
+			t.Errorf("expected the traces to be dropped by an empty result, got %v", err)
+		}
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		md := pmetric.NewMetrics()
+		md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetName("test-metric")
+
+		wp, err := newWasmMetricsProcessor(ctx, newConfig(t, noResult), settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		if result, err := wp.processMetrics(ctx, md); err != nil || result != md {
+			t.Errorf("expected the metrics to be passed on without a result, got %v", err)
+		}
+
+		wp, err = newWasmMetricsProcessor(ctx, newConfig(t, emptyResult), settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		if result, err := wp.processMetrics(ctx, md); err != nil || result.ResourceMetrics().Len() != 0 {
+			t.Errorf("expected the metrics to be dropped by an empty result, got %v", err)
+		}
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		ld := plog.NewLogs()
+		ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("test-log")
+
+		wp, err := newWasmLogsProcessor(ctx, newConfig(t, noResult), settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		if result, err := wp.processLogs(ctx, ld); err != nil || result != ld {
+			t.Errorf("expected the logs to be passed on without a result, got %v", err)
+		}
+
+		wp, err = newWasmLogsProcessor(ctx, newConfig(t, emptyResult), settings)
+		if err != nil {
+			t.Fatalf("failed to create wasm processor: %v", err)
+		}
+		t.Cleanup(func() { wp.shutdown(ctx) })
+		if result, err := wp.processLogs(ctx, ld); err != nil || result.ResourceLogs().Len() != 0 {
+			t.Errorf("expected the logs to be dropped by an empty result, got %v", err)
+		}
+	})
+}
+
+func TestProcessTracesWithAttributesProcessorCompiled(t *testing.T) {
+	// The compiler of wazero only targets these architectures.
+	if goruntime.GOARCH != "amd64" && goruntime.GOARCH != "arm64" {
+		t.Skipf("compiled mode isn't supported on %s", goruntime.GOARCH)
+	}
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/attributesprocessor/main.wasm"
+	cfg.RuntimeConfig.Mode = wasmplugin.RuntimeModeCompiled
+	cfg.PluginConfig = wasmplugin.PluginConfig{
+		"actions": []map[string]string{
+			{"key": "env", "value": "prod", "action": "insert"},
+			{"key": "http.url", "action": "delete"},
+		},
+	}
+	ctx := t.Context()
+	wasmProc, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wasmProc.shutdown(ctx) })
+	if mode := wasmProc.plugin.RuntimeMode(); mode != wasmplugin.RuntimeModeCompiled {
+		t.Fatalf("expected the compiled runtime mode, got %s", mode)
+	}
+
+	// Process enough batches for the guest to grow its heap and collect
+	// garbage under the compiler, not only the first call.
+	for i := 0; i < 200; i++ {
+		td := generateExampleTraces()
+		td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().PutStr("http.url", "http://example.com")
+		processed, err := wasmProc.processTraces(ctx, td)
+		if err != nil {
+			t.Fatalf("failed to process traces of iteration %d: %v", i, err)
+		}
+		attrs := processed.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes()
+		if val, ok := attrs.Get("env"); !ok || val.Str() != "prod" {
+			t.Fatalf("expected env to be inserted in iteration %d, got %v", i, attrs.AsRaw())
+		}
+		if _, ok := attrs.Get("http.url"); ok {
+			t.Fatalf("expected http.url to be deleted in iteration %d, got %v", i, attrs.AsRaw())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package wasmprocessor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/xconsumer"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+// profilesProcessor runs the chain on the profiles and passes the result to
+// the next consumer, as the processors created with processorhelper do for
+// the other signals, which it has no counterpart of for profiles yet. It
+// attaches a bag to the context of the batches like bagTracesProcessor.
+type profilesProcessor struct {
+	*wasmProcessor
+	next xconsumer.Profiles
+}
+
+func (p *profilesProcessor) ConsumeProfiles(ctx context.Context, pd pprofile.Profiles) error {
+	ctx = wasmplugin.ContextWithBag(ctx)
+	pd, err := p.processProfiles(ctx, pd)
+	if errors.Is(err, processorhelper.ErrSkipProcessingData) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return p.next.ConsumeProfiles(ctx, pd)
+}
+
+func (p *profilesProcessor) Capabilities() consumer.Capabilities {
+	return p.capabilities()
+}
+
+func (p *profilesProcessor) Start(ctx context.Context, host component.Host) error {
+	return p.start(ctx, host)
+}
+
+func (p *profilesProcessor) Shutdown(ctx context.Context) error {
+	return p.shutdown(ctx)
+}
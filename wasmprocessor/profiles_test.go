@@ -0,0 +1,110 @@
+package wasmprocessor
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/collector/processor/xprocessor"
+)
+
+func generateExampleProfiles() pprofile.Profiles {
+	pd := pprofile.NewProfiles()
+	rp := pd.ResourceProfiles().AppendEmpty()
+	rp.Resource().Attributes().PutStr("service.name", "test-service")
+	sp := rp.ScopeProfiles().AppendEmpty()
+	sp.Scope().SetName("test-scope")
+	profile := sp.Profiles().AppendEmpty()
+	profile.SetProfileID(pprofile.ProfileID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	profile.Sample().AppendEmpty().Value().Append(42)
+	return pd
+}
+
+func TestCreateProfilesProcessor(t *testing.T) {
+	factory, ok := NewFactory().(xprocessor.Factory)
+	if !ok {
+		t.Fatal("factory is not an xprocessor.Factory")
+	}
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Path = "testdata/nop/main.wasm"
+	ctx := t.Context()
+
+	sink := new(consumertest.ProfilesSink)
+	pp, err := factory.CreateProfiles(ctx, processortest.NewNopSettings(typeStr), cfg, sink)
+	if err != nil {
+		t.Fatalf("failed to create profiles processor: %v", err)
+	}
+	if err := pp.Start(ctx, componenttest.NewNopHost()); err != nil {
+		t.Fatalf("failed to start processor: %v", err)
+	}
+	defer pp.Shutdown(ctx)
+
+	if err := pp.ConsumeProfiles(ctx, generateExampleProfiles()); err != nil {
+		t.Fatalf("failed to process profiles: %v", err)
+	}
+
+	got := sink.AllProfiles()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 batch of profiles, got %d", len(got))
+	}
+	rps := got[0].ResourceProfiles()
+	if rps.Len() != 1 {
+		t.Fatalf("expected 1 resource profile, got %d", rps.Len())
+	}
+	if v, ok := rps.At(0).Resource().Attributes().Get("service.name"); !ok || v.Str() != "test-service" {
+		t.Errorf("expected service.name to be 'test-service', got %v", v)
+	}
+	profiles := rps.At(0).ScopeProfiles().At(0).Profiles()
+	if profiles.Len() != 1 || profiles.At(0).Sample().At(0).Value().At(0) != 42 {
+		t.Errorf("expected the profile to be passed on as is, got %d profiles", profiles.Len())
+	}
+}
+
+func TestCreateProfilesProcessorUnsupported(t *testing.T) {
+	factory := NewFactory().(xprocessor.Factory)
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Path = "testdata/add_new_attribute/main.wasm"
+
+	_, err := factory.CreateProfiles(t.Context(), processortest.NewNopSettings(typeStr), cfg, consumertest.NewNop())
+	if !errors.Is(err, pipeline.ErrSignalNotSupported) {
+		t.Errorf("expected ErrSignalNotSupported, got %v", err)
+	}
+}
+
+// TestProfilesHostFunctionsOptIn checks only the guests registering a
+// profiles processor import the profiles host functions, so the others run
+// on the hosts predating them.
+func TestProfilesHostFunctionsOptIn(t *testing.T) {
+	for path, want := range map[string]bool{
+		"testdata/nop/main.wasm":               true,
+		"testdata/add_new_attribute/main.wasm": false,
+	} {
+		module, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runtime := wazero.NewRuntimeWithConfig(t.Context(), wazero.NewRuntimeConfigInterpreter())
+		compiled, err := runtime.CompileModule(t.Context(), module)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", path, err)
+		}
+		imported := map[string]bool{}
+		for _, fn := range compiled.ImportedFunctions() {
+			_, name, _ := fn.Import()
+			imported[name] = true
+		}
+		runtime.Close(t.Context())
+		for _, name := range []string{"currentProfiles", "setResultProfiles"} {
+			if imported[name] != want {
+				t.Errorf("%s: expected importing %s %v, got %v", path, name, want, imported[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package wasmprocessor
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// resourceAttributes are the attributes set by the host on the resource of
+// all the telemetry returned by the chain, see
+// Config.InjectResourceAttributes.
+type resourceAttributes map[string]string
+
+func (ra resourceAttributes) inject(attrs pcommon.Map) {
+	for name, value := range ra {
+		attrs.PutStr(name, value)
+	}
+}
+
+func (ra resourceAttributes) injectTraces(td ptrace.Traces) {
+	if len(ra) == 0 {
+		return
+	}
+	rss := td.ResourceSpans()
+	for i := range rss.Len() {
+		ra.inject(rss.At(i).Resource().Attributes())
+	}
+}
+
+func (ra resourceAttributes) injectMetrics(md pmetric.Metrics) {
+	if len(ra) == 0 {
+		return
+	}
+	rms := md.ResourceMetrics()
+	for i := range rms.Len() {
+		ra.inject(rms.At(i).Resource().Attributes())
+	}
+}
+
+func (ra resourceAttributes) injectLogs(ld plog.Logs) {
+	if len(ra) == 0 {
+		return
+	}
+	rls := ld.ResourceLogs()
+	for i := range rls.Len() {
+		ra.inject(rls.At(i).Resource().Attributes())
+	}
+}
+
+func (ra resourceAttributes) injectProfiles(pd pprofile.Profiles) {
+	if len(ra) == 0 {
+		return
+	}
+	rps := pd.ResourceProfiles()
+	for i := range rps.Len() {
+		ra.inject(rps.At(i).Resource().Attributes())
+	}
+}
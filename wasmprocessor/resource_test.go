@@ -0,0 +1,183 @@
+package wasmprocessor
+
+import (
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+// injectConfig returns the config of a read only guest passing the telemetry
+// on, with resource attributes injected.
+func injectConfig(t *testing.T) *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = capabilitiesGuest(true, 0).Write(t)
+	cfg.InjectResourceAttributes = map[string]string{
+		"collector.instance.id": "instance-1",
+		"deployment.env":        "test",
+	}
+	return cfg
+}
+
+// checkResource checks that attrs hold the injected attributes, the
+// injected value overriding the incoming one, and kept the others.
+func checkResource(t *testing.T, attrs pcommon.Map) {
+	t.Helper()
+	want := map[string]string{
+		"collector.instance.id": "instance-1",
+		"deployment.env":        "test",
+		"service.name":          "svc",
+	}
+	for name, value := range want {
+		if v, ok := attrs.Get(name); !ok || v.Str() != value {
+			t.Errorf("expected %s=%s, got %q", name, value, v.Str())
+		}
+	}
+	if attrs.Len() != len(want) {
+		t.Errorf("expected %d resource attributes, got %v", len(want), attrs.AsRaw())
+	}
+}
+
+// incomingResource sets the resource attributes of the incoming telemetry.
+func incomingResource(attrs pcommon.Map) {
+	attrs.PutStr("service.name", "svc")
+	attrs.PutStr("collector.instance.id", "incoming")
+}
+
+func TestInjectResourceAttributesTraces(t *testing.T) {
+	ctx := t.Context()
+	wp, err := newWasmTracesProcessor(ctx, injectConfig(t), processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+
+	td := ptrace.NewTraces()
+	for range 2 {
+		incomingResource(td.ResourceSpans().AppendEmpty().Resource().Attributes())
+	}
+	processed, err := wp.processTraces(ctx, td)
+	if err != nil {
+		t.Fatalf("failed to process traces: %v", err)
+	}
+	for i := range processed.ResourceSpans().Len() {
+		checkResource(t, processed.ResourceSpans().At(i).Resource().Attributes())
+	}
+}
+
+func TestInjectResourceAttributesMetrics(t *testing.T) {
+	ctx := t.Context()
+	wp, err := newWasmMetricsProcessor(ctx, injectConfig(t), processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+
+	md := pmetric.NewMetrics()
+	for range 2 {
+		incomingResource(md.ResourceMetrics().AppendEmpty().Resource().Attributes())
+	}
+	processed, err := wp.processMetrics(ctx, md)
+	if err != nil {
+		t.Fatalf("failed to process metrics: %v", err)
+	}
+	for i := range processed.ResourceMetrics().Len() {
+		checkResource(t, processed.ResourceMetrics().At(i).Resource().Attributes())
+	}
+}
+
+func TestInjectResourceAttributesLogs(t *testing.T) {
+	ctx := t.Context()
+	wp, err := newWasmLogsProcessor(ctx, injectConfig(t), processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+
+	ld := plog.NewLogs()
+	for range 2 {
+		incomingResource(ld.ResourceLogs().AppendEmpty().Resource().Attributes())
+	}
+	processed, err := wp.processLogs(ctx, ld)
+	if err != nil {
+		t.Fatalf("failed to process logs: %v", err)
+	}
+	for i := range processed.ResourceLogs().Len() {
+		checkResource(t, processed.ResourceLogs().At(i).Resource().Attributes())
+	}
+}
+
+func TestInjectResourceAttributesProfiles(t *testing.T) {
+	ctx := t.Context()
+	wp, err := newWasmProfilesProcessor(ctx, injectConfig(t), processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+
+	pd := pprofile.NewProfiles()
+	for range 2 {
+		incomingResource(pd.ResourceProfiles().AppendEmpty().Resource().Attributes())
+	}
+	processed, err := wp.processProfiles(ctx, pd)
+	if err != nil {
+		t.Fatalf("failed to process profiles: %v", err)
+	}
+	for i := range processed.ResourceProfiles().Len() {
+		checkResource(t, processed.ResourceProfiles().At(i).Resource().Attributes())
+	}
+}
+
+func TestInjectResourceAttributesChain(t *testing.T) {
+	// The attributes are injected once, by the last module of the chain.
+	cfg := injectConfig(t)
+	cfg.Path = "testdata/add_new_attribute/main.wasm"
+	cfg.PluginConfig = addAttributeConfig("first", "1").PluginConfig
+	cfg.Chain = append(cfg.Chain, addAttributeConfig("second", "2"))
+	ctx := t.Context()
+	wp, err := newWasmTracesProcessor(ctx, cfg, processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+	if wp.resourceAttributes != nil || wp.next.resourceAttributes == nil {
+		t.Error("expected the attributes to be injected by the last module only")
+	}
+
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	incomingResource(rs.Resource().Attributes())
+	rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+	processed, err := wp.processTraces(ctx, td)
+	if err != nil {
+		t.Fatalf("failed to process traces: %v", err)
+	}
+	checkResource(t, processed.ResourceSpans().At(0).Resource().Attributes())
+}
+
+func TestInjectResourceAttributesCapabilities(t *testing.T) {
+	ctx := t.Context()
+	wp, err := newWasmTracesProcessor(ctx, injectConfig(t), processortest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm processor: %v", err)
+	}
+	t.Cleanup(func() { wp.shutdown(ctx) })
+	// The guest is read only, but the host mutates the data.
+	if !wp.capabilities().MutatesData {
+		t.Error("expected the processor injecting attributes to mutate the data")
+	}
+}
+
+func TestInjectResourceAttributesValidate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = wasmtest.NewGuest(4).Write(t)
+	cfg.InjectResourceAttributes = map[string]string{"": "value"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an empty attribute name to be refused")
+	}
+}
@@ -0,0 +1,70 @@
+package wasmprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+// warmUpKey marks the context of the warm up call, whose result batches are
+// discarded rather than passed to the next consumer.
+type warmUpKey struct{}
+
+// isWarmUp reports whether ctx is that of the warm up call.
+func isWarmUp(ctx context.Context) bool {
+	warmUp, _ := ctx.Value(warmUpKey{}).(bool)
+	return warmUp
+}
+
+// loadWarmUp reads the OTLP JSON batch of the file at path, and returns the
+// function processing it through the guest and discarding the result, so the
+// first real batch doesn't pay for the code paths run for the first time. The
+// batch is read at creation, so a bad one fails it, but processed once the
+// guests are started. It returns nil if path is empty.
+func loadWarmUp[T any](path string, unmarshal func([]byte) (T, error), process func(context.Context, T) (T, error)) (func(context.Context) error, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("wasm: error reading warm up batch: %w", err)
+	}
+	batch, err := unmarshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("wasm: error decoding warm up batch: %w", err)
+	}
+	return func(ctx context.Context) error {
+		ctx = context.WithValue(ctx, warmUpKey{}, true)
+		if _, err := process(ctx, batch); err != nil && !errors.Is(err, processorhelper.ErrSkipProcessingData) {
+			return fmt.Errorf("wasm: error warming up guest: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+func (wp *wasmProcessor) loadWarmUpTraces(path string) (err error) {
+	wp.warmUp, err = loadWarmUp(path, (&ptrace.JSONUnmarshaler{}).UnmarshalTraces, wp.processTraces)
+	return err
+}
+
+func (wp *wasmProcessor) loadWarmUpMetrics(path string) (err error) {
+	wp.warmUp, err = loadWarmUp(path, (&pmetric.JSONUnmarshaler{}).UnmarshalMetrics, wp.processMetrics)
+	return err
+}
+
+func (wp *wasmProcessor) loadWarmUpLogs(path string) (err error) {
+	wp.warmUp, err = loadWarmUp(path, (&plog.JSONUnmarshaler{}).UnmarshalLogs, wp.processLogs)
+	return err
+}
+
+func (wp *wasmProcessor) loadWarmUpProfiles(path string) (err error) {
+	wp.warmUp, err = loadWarmUp(path, (&pprofile.JSONUnmarshaler{}).UnmarshalProfiles, wp.processProfiles)
+	return err
+}
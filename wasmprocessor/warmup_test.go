@@ -0,0 +1,160 @@
+package wasmprocessor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWarmUp(t *testing.T) {
+	// The guest calls are traced to observe the warm up call.
+	exporter := tracetest.NewInMemoryExporter()
+	settings := processortest.NewNopSettings(typeStr)
+	settings.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/nop/main.wasm"
+	cfg.WarmUpPath = "testdata/warmup/traces.json"
+	cfg.TraceGuestCalls = true
+	sink := new(consumertest.TracesSink)
+	p, err := NewFactory().CreateTraces(t.Context(), settings, cfg, sink)
+	if err != nil {
+		t.Fatalf("failed to create traces processor: %v", err)
+	}
+	t.Cleanup(func() { p.Shutdown(t.Context()) })
+
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("expected the warm up batch not to be processed before start, got %d spans", len(spans))
+	}
+	if err := p.Start(t.Context(), componenttest.NewNopHost()); err != nil {
+		t.Fatalf("failed to start traces processor: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != processTracesFunctionName {
+		t.Fatalf("expected the warm up batch to be processed, got %d spans", len(spans))
+	}
+	if len(sink.AllTraces()) != 0 {
+		t.Errorf("expected the warm up batch not to reach the next consumer, got %d batches", len(sink.AllTraces()))
+	}
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+	if err := p.ConsumeTraces(t.Context(), traces); err != nil {
+		t.Fatalf("failed to consume traces: %v", err)
+	}
+	if len(sink.AllTraces()) != 1 {
+		t.Errorf("expected 1 batch, got %d", len(sink.AllTraces()))
+	}
+}
+
+func TestWarmUpAfterStart(t *testing.T) {
+	// start sets the flag at offset 0, which processTraces fails without.
+	guest := wasmtest.NewGuest(4,
+		wasmtest.Function{
+			Export:  "start",
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    wasmtest.Instructions(wasmtest.I32Const(0), wasmtest.I32Const(1), wasmtest.I32Store(0), wasmtest.I32Const(0)),
+		},
+		wasmtest.Function{
+			Export:  processTracesFunctionName,
+			Results: []api.ValueType{api.ValueTypeI32},
+			Body:    wasmtest.Instructions(wasmtest.I32Const(0), wasmtest.I32Load(0), wasmtest.I32Eqz),
+		},
+	)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = guest.Write(t)
+	cfg.WarmUpPath = "testdata/warmup/traces.json"
+	p, err := NewFactory().CreateTraces(t.Context(), processortest.NewNopSettings(typeStr), cfg, consumertest.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create traces processor: %v", err)
+	}
+	t.Cleanup(func() { p.Shutdown(t.Context()) })
+	if err := p.Start(t.Context(), componenttest.NewNopHost()); err != nil {
+		t.Errorf("expected the guest to be warmed up once started, got %v", err)
+	}
+}
+
+func TestWarmUpDiscardsResultBatches(t *testing.T) {
+	i32 := api.ValueTypeI32
+	// processTraces appends the current traces to the result batches.
+	mod := wasmtest.NewGuest(4).
+		Import(wasmtest.HostModule, "currentTraces", []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Import(wasmtest.HostModule, "appendResultTraces", []api.ValueType{i32, i32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  processTracesFunctionName,
+		Results: []api.ValueType{i32},
+		Locals:  []api.ValueType{i32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(1024), wasmtest.I32Const(60000), mod.Call("currentTraces"), wasmtest.LocalSet(0),
+			wasmtest.I32Const(1024), wasmtest.LocalGet(0), mod.Call("appendResultTraces"),
+			wasmtest.I32Const(0),
+		),
+	})
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	cfg.WarmUpPath = "testdata/warmup/traces.json"
+	sink := new(consumertest.TracesSink)
+	p, err := NewFactory().CreateTraces(t.Context(), processortest.NewNopSettings(typeStr), cfg, sink)
+	if err != nil {
+		t.Fatalf("failed to create traces processor: %v", err)
+	}
+	t.Cleanup(func() { p.Shutdown(t.Context()) })
+	if err := p.Start(t.Context(), componenttest.NewNopHost()); err != nil {
+		t.Fatalf("failed to start traces processor: %v", err)
+	}
+	if len(sink.AllTraces()) != 0 {
+		t.Fatalf("expected the warm up batches not to reach the next consumer, got %d batches", len(sink.AllTraces()))
+	}
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+	if err := p.ConsumeTraces(t.Context(), traces); err != nil {
+		t.Fatalf("failed to consume traces: %v", err)
+	}
+	if len(sink.AllTraces()) != 1 {
+		t.Errorf("expected the result batch to reach the next consumer, got %d batches", len(sink.AllTraces()))
+	}
+}
+
+func TestWarmUpErrors(t *testing.T) {
+	failing := wasmtest.NewGuest(4, wasmtest.Function{
+		Export:  processTracesFunctionName,
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body:    wasmtest.I32Const(1),
+	})
+	tests := []struct {
+		name       string
+		path       string
+		warmUpPath string
+		wantErr    string
+	}{
+		{name: "missing batch", path: "testdata/nop/main.wasm", warmUpPath: "testdata/warmup/missing.json", wantErr: "error reading warm up batch"},
+		{name: "invalid batch", path: "testdata/nop/main.wasm", warmUpPath: "testdata/nop/main.wasm", wantErr: "error decoding warm up batch"},
+		{name: "failing guest", path: failing.Write(t), warmUpPath: "testdata/warmup/traces.json", wantErr: "error warming up guest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Path = tt.path
+			cfg.WarmUpPath = tt.warmUpPath
+			p, err := NewFactory().CreateTraces(t.Context(), processortest.NewNopSettings(typeStr), cfg, consumertest.NewNop())
+			if err == nil {
+				// The guest is warmed up once started.
+				err = p.Start(t.Context(), componenttest.NewNopHost())
+				p.Shutdown(t.Context())
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
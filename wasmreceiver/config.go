@@ -1,11 +1,57 @@
 package wasmreceiver
 
-import "github.com/otelwasm/otelwasm/wasmplugin"
+import (
+	"fmt"
+
+	"github.com/otelwasm/otelwasm/wasmplugin"
+)
+
+// ErrorPolicy is what the receiver does when the next consumer of a signal
+// fails to consume the telemetry produced by the guest.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyDrop logs the error and drops the telemetry. This is the
+	// default.
+	ErrorPolicyDrop ErrorPolicy = "drop"
+
+	// ErrorPolicyPropagate passes the error back to the guest for the
+	// telemetry it emits, e.g. so a guest receiver tells its clients to
+	// retry, and reports a recoverable error status for the telemetry it
+	// sets as result, which the guest can't be told about.
+	ErrorPolicyPropagate ErrorPolicy = "propagate"
+)
+
+// ConsumerErrorsConfig is the error policy of each signal.
+type ConsumerErrorsConfig struct {
+	Traces  ErrorPolicy `mapstructure:"traces,omitempty"`
+	Metrics ErrorPolicy `mapstructure:"metrics,omitempty"`
+	Logs    ErrorPolicy `mapstructure:"logs,omitempty"`
+}
+
+func (cfg *ConsumerErrorsConfig) Validate() error {
+	for signal, policy := range map[string]ErrorPolicy{"traces": cfg.Traces, "metrics": cfg.Metrics, "logs": cfg.Logs} {
+		switch policy {
+		case "", ErrorPolicyDrop, ErrorPolicyPropagate:
+		default:
+			return fmt.Errorf("consumer_errors::%s: invalid error policy: %s", signal, policy)
+		}
+	}
+	return nil
+}
 
 type Config struct {
 	wasmplugin.Config `mapstructure:",squash"`
+
+	// ConsumerErrors is the policy applied to the errors of the next
+	// consumer, per signal, e.g. to drop logs while the sources of traces
+	// retry.
+	ConsumerErrors ConsumerErrorsConfig `mapstructure:"consumer_errors"`
 }
 
 func (cfg *Config) Validate() error {
-	return cfg.Config.Validate()
+	if err := cfg.Config.Validate(); err != nil {
+		return err
+	}
+	return cfg.ConsumerErrors.Validate()
 }
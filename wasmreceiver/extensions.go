@@ -0,0 +1,71 @@
+package wasmreceiver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/extensionauth"
+)
+
+// hostExtensions exposes the extensions of the collector host to the guest.
+type hostExtensions struct {
+	host component.Host
+}
+
+var _ wasmplugin.Extensions = hostExtensions{}
+
+func (e hostExtensions) IDs() []string {
+	ids := []string{}
+	if e.host == nil {
+		return ids
+	}
+	for id := range e.host.GetExtensions() {
+		ids = append(ids, id.String())
+	}
+	return ids
+}
+
+func (e hostExtensions) Authenticate(ctx context.Context, id string, headers map[string][]string) (wasmplugin.ClientInfo, error) {
+	var extID component.ID
+	if err := extID.UnmarshalText([]byte(id)); err != nil {
+		return wasmplugin.ClientInfo{}, err
+	}
+	var ext component.Component
+	if e.host != nil {
+		ext = e.host.GetExtensions()[extID]
+	}
+	if ext == nil {
+		return wasmplugin.ClientInfo{}, fmt.Errorf("extension %s not found", id)
+	}
+	server, ok := ext.(extensionauth.Server)
+	if !ok {
+		return wasmplugin.ClientInfo{}, fmt.Errorf("extension %s is not a server authenticator", id)
+	}
+
+	ctx, err := server.Authenticate(ctx, headers)
+	if err != nil {
+		return wasmplugin.ClientInfo{}, err
+	}
+	return clientInfo(client.FromContext(ctx)), nil
+}
+
+// clientInfo returns the part of info passed to the guest.
+func clientInfo(info client.Info) wasmplugin.ClientInfo {
+	var res wasmplugin.ClientInfo
+	if info.Auth != nil {
+		res.Auth = make(map[string]any)
+		for _, name := range info.Auth.GetAttributeNames() {
+			res.Auth[name] = info.Auth.GetAttribute(name)
+		}
+	}
+	for key := range info.Metadata.Keys() {
+		if res.Metadata == nil {
+			res.Metadata = make(map[string][]string)
+		}
+		res.Metadata[key] = info.Metadata.Get(key)
+	}
+	return res
+}
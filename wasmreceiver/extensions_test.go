@@ -0,0 +1,91 @@
+package wasmreceiver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/extensionauth"
+)
+
+// extensionsHost is a host with the given extensions.
+type extensionsHost map[component.ID]component.Component
+
+func (h extensionsHost) GetExtensions() map[component.ID]component.Component {
+	return h
+}
+
+// authData is the auth data of the clients authenticated by testAuth.
+type authData map[string]any
+
+func (d authData) GetAttribute(name string) any { return d[name] }
+
+func (d authData) GetAttributeNames() []string {
+	var names []string
+	for name := range d {
+		names = append(names, name)
+	}
+	return names
+}
+
+// testAuth authenticates requests carrying the "Bearer secret" authorization
+// header.
+type testAuth struct {
+	component.StartFunc
+	component.ShutdownFunc
+}
+
+func (testAuth) Authenticate(ctx context.Context, headers map[string][]string) (context.Context, error) {
+	if v := headers["authorization"]; len(v) != 1 || v[0] != "Bearer secret" {
+		return ctx, errors.New("unauthenticated")
+	}
+	return client.NewContext(ctx, client.Info{
+		Auth:     authData{"subject": "alice"},
+		Metadata: client.NewMetadata(map[string][]string{"tenant": {"acme"}}),
+	}), nil
+}
+
+var _ extensionauth.Server = testAuth{}
+
+type nopExtension struct {
+	component.StartFunc
+	component.ShutdownFunc
+}
+
+func TestHostExtensions(t *testing.T) {
+	extensions := hostExtensions{host: extensionsHost{
+		component.MustNewIDWithName("basicauth", "server"): testAuth{},
+		component.MustNewID("file_storage"):                nopExtension{},
+	}}
+
+	ids := extensions.IDs()
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"basicauth/server", "file_storage"}) {
+		t.Errorf("unexpected extension IDs %v", ids)
+	}
+
+	info, err := extensions.Authenticate(t.Context(), "basicauth/server", map[string][]string{"authorization": {"Bearer secret"}})
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if info.Auth["subject"] != "alice" || !slices.Equal(info.Metadata["tenant"], []string{"acme"}) {
+		t.Errorf("unexpected client info %+v", info)
+	}
+
+	for id, headers := range map[string]map[string][]string{
+		"basicauth/server": {"authorization": {"Bearer guess"}},
+		"file_storage":     {},
+		"oidc":             {},
+	} {
+		if _, err := extensions.Authenticate(t.Context(), id, headers); err == nil {
+			t.Errorf("%s: expected the authentication to fail", id)
+		}
+	}
+
+	if ids := (hostExtensions{}).IDs(); len(ids) != 0 {
+		t.Errorf("expected no extension without host, got %v", ids)
+	}
+}
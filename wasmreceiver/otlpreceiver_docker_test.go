@@ -0,0 +1,131 @@
+//go:build docker
+// +build docker
+
+package wasmreceiver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestOTLPReceiver(t *testing.T) {
+	t.Run("http", func(t *testing.T) {
+		endpoint := freeEndpoint(t)
+		cfg := createDefaultConfig().(*Config)
+		cfg.Path = "testdata/otlpreceiver/main.wasm"
+		cfg.PluginConfig = map[string]any{
+			"protocols": map[string]any{
+				"http": map[string]any{"endpoint": endpoint},
+			},
+		}
+		sink := new(consumertest.TracesSink)
+		ctx, wasmRecv, err := newTracesWasmReceiver(t.Context(), cfg, sink, receivertest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm receiver: %v", err)
+		}
+		host := &statusHost{Host: componenttest.NewNopHost()}
+		if err := wasmRecv.Start(ctx, host); err != nil {
+			t.Fatalf("failed to start wasm receiver: %v", err)
+		}
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := wasmRecv.Shutdown(ctx); err != nil {
+				t.Errorf("failed to stop wasm receiver: %v", err)
+			}
+		})
+
+		traces := ptrace.NewTraces()
+		traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+		body, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+		if err != nil {
+			t.Fatalf("failed to marshal traces: %v", err)
+		}
+		// The guest listens once it started, so the request is retried until
+		// it does.
+		var resp *http.Response
+		for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(100 * time.Millisecond) {
+			resp, err = http.Post(fmt.Sprintf("http://%s/v1/traces", endpoint), "application/x-protobuf", bytes.NewReader(body))
+			if err == nil || time.Now().After(deadline) {
+				break
+			}
+		}
+		if err != nil {
+			t.Fatalf("failed to post traces: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected the traces to be accepted, got status %d", resp.StatusCode)
+		}
+		if sink.SpanCount() != 1 {
+			t.Errorf("expected the span to be consumed, got %d spans", sink.SpanCount())
+		}
+	})
+
+	// gRPC isn't supported by the guest yet, see
+	// https://github.com/otelwasm/otelwasm/issues/59, so the receiver must fail
+	// to start instead of panicking on the first request.
+	t.Run("grpc rejected", func(t *testing.T) {
+		cfg := createDefaultConfig().(*Config)
+		cfg.Path = "testdata/otlpreceiver/main.wasm"
+		cfg.PluginConfig = map[string]any{
+			"protocols": map[string]any{
+				"grpc": map[string]any{"endpoint": freeEndpoint(t)},
+				"http": map[string]any{"endpoint": freeEndpoint(t)},
+			},
+		}
+		ctx, wasmRecv, err := newTracesWasmReceiver(t.Context(), cfg, consumertest.NewNop(), receivertest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm receiver: %v", err)
+		}
+		host := &statusHost{Host: componenttest.NewNopHost()}
+		if err := wasmRecv.Start(ctx, host); err != nil {
+			t.Fatalf("failed to start wasm receiver: %v", err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wasmRecv.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			wasmRecv.Shutdown(ctx)
+			t.Fatal("expected the guest to exit")
+		}
+
+		errs := host.errors()
+		if len(errs) != 1 || errs[0].Status() != componentstatus.StatusFatalError {
+			t.Fatalf("expected a fatal error status, got %v", errs)
+		}
+		var guestErr *wasmplugin.GuestError
+		if !errors.As(errs[0].Err(), &guestErr) || guestErr.Reason != wasmplugin.ErrorReasonExit {
+			t.Errorf("expected the guest to exit, got %v", errs[0].Err())
+		}
+	})
+}
+
+// freeEndpoint returns a local endpoint nothing listens on.
+func freeEndpoint(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
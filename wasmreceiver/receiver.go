@@ -2,11 +2,14 @@ package wasmreceiver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/otelwasm/otelwasm/wasmplugin"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
@@ -24,8 +27,13 @@ type Receiver struct {
 	nextConsumerL consumer.Logs
 	nextConsumerT consumer.Traces
 
+	host  component.Host
 	stack *wasmplugin.Stack
 	wg    sync.WaitGroup
+
+	// closed is set once Shutdown closed the runtime under the guest, which
+	// fails the running guest calls.
+	closed atomic.Bool
 }
 
 func newMetricsWasmReceiver(ctx context.Context, cfg *Config, nextConsumerM consumer.Metrics, set receiver.Settings) (context.Context, *Receiver, error) {
@@ -35,7 +43,12 @@ func newMetricsWasmReceiver(ctx context.Context, cfg *Config, nextConsumerM cons
 
 	requiredFunctions := []string{"startMetricsReceiver"}
 
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
+	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions,
+		wasmplugin.WithMeterProvider(set.MeterProvider),
+		wasmplugin.WithTracerProvider(set.TracerProvider),
+		wasmplugin.WithLogger(set.Logger),
+		wasmplugin.WithComponent(set.ID.String(), set.Resource),
+		wasmplugin.WithInterruptibleCalls())
 	if err != nil {
 		return ctx, nil, err
 	}
@@ -61,7 +74,12 @@ func newLogsWasmReceiver(ctx context.Context, cfg *Config, nextConsumerL consume
 
 	requiredFunctions := []string{"startLogsReceiver"}
 
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
+	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions,
+		wasmplugin.WithMeterProvider(set.MeterProvider),
+		wasmplugin.WithTracerProvider(set.TracerProvider),
+		wasmplugin.WithLogger(set.Logger),
+		wasmplugin.WithComponent(set.ID.String(), set.Resource),
+		wasmplugin.WithInterruptibleCalls())
 	if err != nil {
 		return ctx, nil, err
 	}
@@ -87,7 +105,12 @@ func newTracesWasmReceiver(ctx context.Context, cfg *Config, nextConsumerT consu
 
 	requiredFunctions := []string{"startTracesReceiver"}
 
-	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions)
+	plugin, err := wasmplugin.NewWasmPlugin(ctx, &cfg.Config, requiredFunctions,
+		wasmplugin.WithMeterProvider(set.MeterProvider),
+		wasmplugin.WithTracerProvider(set.TracerProvider),
+		wasmplugin.WithLogger(set.Logger),
+		wasmplugin.WithComponent(set.ID.String(), set.Resource),
+		wasmplugin.WithInterruptibleCalls())
 	if err != nil {
 		return ctx, nil, err
 	}
@@ -124,31 +147,58 @@ var (
 // to Start() function since that context will be cancelled soon and can abort the long-running
 // operation. Create a new context from the context.Background() for long-running operations.
 func (r *Receiver) Start(ctx context.Context, host component.Host) error {
+	// The status is reported for the health check extension: starting, then
+	// OK if the guest is ready, before the guest receivers run and may fail.
+	componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusStarting))
+	if !r.plugin.Ready() {
+		componentstatus.ReportStatus(host, componentstatus.NewPermanentErrorEvent(wasmplugin.ErrGuestNotReady))
+		return nil
+	}
+
+	// The guest calls run until Shutdown, and abort once their context is
+	// done, so they must not be bound to the start context.
+	ctx = context.WithoutCancel(ctx)
+
+	policies := r.cfg.ConsumerErrors
+
 	onResultMetricsChange := func(resultMetrics pmetric.Metrics) {
 		if r.nextConsumerM != nil {
-			r.nextConsumerM.ConsumeMetrics(ctx, resultMetrics)
+			r.consumerError("metrics", policies.Metrics, r.nextConsumerM.ConsumeMetrics(ctx, resultMetrics), false)
 		}
 	}
 
 	onResultLogsChange := func(resultLogs plog.Logs) {
 		if r.nextConsumerL != nil {
-			r.nextConsumerL.ConsumeLogs(ctx, resultLogs)
+			r.consumerError("logs", policies.Logs, r.nextConsumerL.ConsumeLogs(ctx, resultLogs), false)
 		}
 	}
 
 	onResultTracesChange := func(resultTraces ptrace.Traces) {
 		if r.nextConsumerT != nil {
-			r.nextConsumerT.ConsumeTraces(ctx, resultTraces)
+			r.consumerError("traces", policies.Traces, r.nextConsumerT.ConsumeTraces(ctx, resultTraces), false)
+		}
+	}
+
+	// Emitted traces are batches of their own, passed on as the guest
+	// produces them, and the guest is told if the next consumer refuses them.
+	emitTraces := func(traces ptrace.Traces) error {
+		if r.nextConsumerT == nil {
+			return pipeline.ErrSignalNotSupported
 		}
+		return r.consumerError("traces", policies.Traces, r.nextConsumerT.ConsumeTraces(ctx, traces), true)
 	}
 
+	r.host = host
 	r.stack = &wasmplugin.Stack{
 		OnResultMetricsChange: onResultMetricsChange,
 		OnResultLogsChange:    onResultLogsChange,
 		OnResultTracesChange:  onResultTracesChange,
-		PluginConfigJSON:      r.plugin.PluginConfigJSON,
+		EmitTraces:            emitTraces,
+		Extensions:            hostExtensions{host: host},
 	}
 
+	componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusOK))
+
 	if r.nextConsumerM != nil {
 		r.wg.Add(1)
 		go r.runMetrics(ctx)
@@ -170,28 +220,62 @@ func (r *Receiver) Start(ctx context.Context, host component.Host) error {
 func (r *Receiver) runMetrics(ctx context.Context) {
 	defer r.wg.Done()
 
-	_, err := r.plugin.ProcessFunctionCall(ctx, "startMetricsReceiver", r.stack)
-	if err != nil {
-		r.set.Logger.Fatal("metrics receiver failed", zap.Error(err))
+	if _, err := r.plugin.ProcessFunctionCall(ctx, "startMetricsReceiver", r.stack); err != nil {
+		r.fail("metrics", err)
 	}
 }
 
 func (r *Receiver) runLogs(ctx context.Context) {
 	defer r.wg.Done()
 
-	_, err := r.plugin.ProcessFunctionCall(ctx, "startLogsReceiver", r.stack)
-	if err != nil {
-		r.set.Logger.Fatal("metrics receiver failed", zap.Error(err))
+	if _, err := r.plugin.ProcessFunctionCall(ctx, "startLogsReceiver", r.stack); err != nil {
+		r.fail("logs", err)
 	}
 }
 
 func (r *Receiver) runTraces(ctx context.Context) {
 	defer r.wg.Done()
 
-	_, err := r.plugin.ProcessFunctionCall(ctx, "startTracesReceiver", r.stack)
-	if err != nil {
-		r.set.Logger.Fatal("metrics receiver failed", zap.Error(err))
+	if _, err := r.plugin.ProcessFunctionCall(ctx, "startTracesReceiver", r.stack); err != nil {
+		r.fail("traces", err)
+	}
+}
+
+// consumerError applies the error policy of signal to err, the error of its
+// next consumer, and returns the error to pass back to the guest, if any.
+// emitted is set for the telemetry emitted by the guest, as opposed to the
+// telemetry set as result, which has no way back to the guest.
+func (r *Receiver) consumerError(signal string, policy ErrorPolicy, err error, emitted bool) error {
+	if err == nil {
+		return nil
+	}
+	if policy != ErrorPolicyPropagate {
+		r.set.Logger.Debug("Dropped "+signal+" refused by the next consumer", zap.Error(err))
+		return nil
+	}
+	if emitted {
+		return err
+	}
+	componentstatus.ReportStatus(r.host, componentstatus.NewRecoverableErrorEvent(
+		fmt.Errorf("wasm: next consumer refused %s: %w", signal, err)))
+	return nil
+}
+
+// fail reports the failure of the guest receiver of the given signal. The
+// guest can't be restarted, so a fatal error status is reported, shutting the
+// collector down.
+func (r *Receiver) fail(signal string, err error) {
+	if r.closed.Load() {
+		r.set.Logger.Debug(signal+" receiver stopped by closing the runtime", zap.Error(err))
+		return
 	}
+	fields := []zap.Field{zap.Error(err)}
+	var guestErr *wasmplugin.GuestError
+	if errors.As(err, &guestErr) && guestErr.Reason == wasmplugin.ErrorReasonExit {
+		fields = append(fields, zap.Uint32("exit_code", guestErr.ExitCode))
+	}
+	r.set.Logger.Error(signal+" receiver failed", fields...)
+	componentstatus.ReportStatus(r.host, componentstatus.NewFatalErrorEvent(err))
 }
 
 // Shutdown is invoked during service shutdown. After Shutdown() is called, if the component
@@ -210,11 +294,32 @@ func (r *Receiver) runTraces(ctx context.Context) {
 // methods of the component are called after that. If necessary a new component with
 // the same or different configuration may be created and started (this may happen
 // for example if we want to restart the component).
+//
+// The guest is requested to stop through getShutdownRequested. If it doesn't
+// stop before ctx is done, the runtime is closed, aborting the guest, and an
+// error is returned.
 func (r *Receiver) Shutdown(ctx context.Context) error {
+	if r.stack == nil {
+		return nil
+	}
 	r.stack.RequestedShutdown.Store(true)
-	// TODO: Set timeout for shutdown
 
-	r.wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
 
-	return nil
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	r.closed.Store(true)
+	// ctx is done, so the runtime is closed with a context of its own.
+	if err := r.plugin.Shutdown(context.WithoutCancel(ctx)); err != nil {
+		return fmt.Errorf("wasm: guest receiver didn't stop in time: %w", errors.Join(ctx.Err(), err))
+	}
+	return fmt.Errorf("wasm: guest receiver didn't stop in time, closed the runtime: %w", ctx.Err())
 }
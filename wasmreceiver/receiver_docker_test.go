@@ -132,6 +132,7 @@ func TestS3Receiver(t *testing.T) {
 				}
 
 				cfg.Path = "testdata/awss3receiver/main.wasm"
+				cfg.EnvAllowlist = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
 				ctx := t.Context()
 				settings := receivertest.NewNopSettings(typeStr)
 
@@ -163,6 +164,7 @@ func TestS3Receiver(t *testing.T) {
 				}
 
 				cfg.Path = "testdata/awss3receiver/main.wasm"
+				cfg.EnvAllowlist = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
 				ctx := t.Context()
 				settings := receivertest.NewNopSettings(typeStr)
 
@@ -194,6 +196,7 @@ func TestS3Receiver(t *testing.T) {
 				}
 
 				cfg.Path = "testdata/awss3receiver/main.wasm"
+				cfg.EnvAllowlist = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
 				ctx := t.Context()
 				settings := receivertest.NewNopSettings(typeStr)
 
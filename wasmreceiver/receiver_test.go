@@ -1,9 +1,23 @@
 package wasmreceiver
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/otelwasm/otelwasm/wasmplugin"
+	"github.com/otelwasm/otelwasm/wasmplugin/wasmtest"
+	"github.com/tetratelabs/wazero/api"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/receiver/receivertest"
 )
 
@@ -30,6 +44,45 @@ func TestProcessMetricsWithNopReceiver(t *testing.T) {
 	}
 }
 
+func TestReceiverStartStatus(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/nop/main.wasm"
+
+	t.Run("ready", func(t *testing.T) {
+		ctx, wasmRecv, err := newMetricsWasmReceiver(t.Context(), cfg, consumertest.NewNop(), receivertest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm receiver: %v", err)
+		}
+		host := &statusHost{Host: componenttest.NewNopHost()}
+		if err := wasmRecv.Start(ctx, host); err != nil {
+			t.Fatalf("failed to start wasm receiver: %v", err)
+		}
+		if err := wasmRecv.Shutdown(ctx); err != nil {
+			t.Fatalf("failed to stop wasm receiver: %v", err)
+		}
+		if len(host.events) != 2 || host.events[0].Status() != componentstatus.StatusStarting || host.events[1].Status() != componentstatus.StatusOK {
+			t.Errorf("expected the starting then OK status, got %v", host.events)
+		}
+	})
+
+	t.Run("guest closed", func(t *testing.T) {
+		ctx, wasmRecv, err := newMetricsWasmReceiver(t.Context(), cfg, consumertest.NewNop(), receivertest.NewNopSettings(typeStr))
+		if err != nil {
+			t.Fatalf("failed to create wasm receiver: %v", err)
+		}
+		if err := wasmRecv.plugin.Shutdown(ctx); err != nil {
+			t.Fatalf("failed to shut the guest down: %v", err)
+		}
+		host := &statusHost{Host: componenttest.NewNopHost()}
+		if err := wasmRecv.Start(ctx, host); err != nil {
+			t.Fatalf("failed to start wasm receiver: %v", err)
+		}
+		if len(host.events) != 2 || !errors.Is(host.events[1].Err(), wasmplugin.ErrGuestNotReady) {
+			t.Errorf("expected a permanent error status, got %v", host.events)
+		}
+	})
+}
+
 func TestProcessLogsWithNopReceiver(t *testing.T) {
 	cfg := createDefaultConfig().(*Config)
 	cfg.Path = "testdata/nop/main.wasm"
@@ -75,3 +128,349 @@ func TestProcessTracesWithNopReceiver(t *testing.T) {
 		t.Fatalf("failed to stop wasm receiver: %v", err)
 	}
 }
+
+func TestEmitTraces(t *testing.T) {
+	// startTracesReceiver emits the traces at offset 0 twice, and fails with
+	// a non-zero status unless both batches were consumed.
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+	tracesBytes, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	if err != nil {
+		t.Fatalf("failed to marshal traces: %v", err)
+	}
+	mod := wasmtest.NewGuest(4).
+		Import(wasmtest.HostModule, "emitTraces", []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32})
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: tracesBytes}}
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "startTracesReceiver",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(0), wasmtest.I32Const(int32(len(tracesBytes))), mod.Call("emitTraces"),
+			wasmtest.I32Const(0), wasmtest.I32Const(int32(len(tracesBytes))), mod.Call("emitTraces"),
+			wasmtest.I32Add,
+		),
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	sink := new(consumertest.TracesSink)
+	ctx, wasmRecv, err := newTracesWasmReceiver(t.Context(), cfg, sink, receivertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm receiver: %v", err)
+	}
+
+	host := &statusHost{Host: componenttest.NewNopHost()}
+	if err := wasmRecv.Start(ctx, host); err != nil {
+		t.Fatalf("failed to start wasm receiver: %v", err)
+	}
+	if err := wasmRecv.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to stop wasm receiver: %v", err)
+	}
+
+	if errs := host.errors(); len(errs) != 0 {
+		t.Fatalf("expected no error status reported, got %v", errs)
+	}
+	batches := sink.AllTraces()
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(batches))
+	}
+	for i, batch := range batches {
+		if batch.SpanCount() != 1 {
+			t.Errorf("expected batch %d to hold the span, got %d spans", i, batch.SpanCount())
+		}
+	}
+}
+
+// statusHost is a host recording the status reported by the components.
+type statusHost struct {
+	component.Host
+	events []*componentstatus.Event
+}
+
+func (h *statusHost) Report(event *componentstatus.Event) {
+	h.events = append(h.events, event)
+}
+
+// errors returns the reported events carrying an error, leaving out the
+// starting and OK events of Start.
+func (h *statusHost) errors() []*componentstatus.Event {
+	var events []*componentstatus.Event
+	for _, event := range h.events {
+		if event.Err() != nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestGuestExitReportsStatus(t *testing.T) {
+	mod := wasmtest.NewGuest(4).
+		Import("wasi_snapshot_preview1", "proc_exit", []api.ValueType{api.ValueTypeI32}, nil)
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  "startTracesReceiver",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.I32Const(3), mod.Call("proc_exit"),
+			wasmtest.I32Const(0),
+		),
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	ctx, wasmRecv, err := newTracesWasmReceiver(t.Context(), cfg, consumertest.NewNop(), receivertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm receiver: %v", err)
+	}
+
+	host := &statusHost{Host: componenttest.NewNopHost()}
+	if err := wasmRecv.Start(ctx, host); err != nil {
+		t.Fatalf("failed to start wasm receiver: %v", err)
+	}
+	if err := wasmRecv.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to stop wasm receiver: %v", err)
+	}
+
+	errs := host.errors()
+	if len(errs) != 1 || errs[0].Status() != componentstatus.StatusFatalError {
+		t.Fatalf("expected a fatal error status, got %v", errs)
+	}
+	var guestErr *wasmplugin.GuestError
+	if !errors.As(errs[0].Err(), &guestErr) || guestErr.ExitCode != 3 {
+		t.Errorf("expected the status to carry exit code 3, got %v", errs[0].Err())
+	}
+}
+
+func TestShutdownClosesStuckGuest(t *testing.T) {
+	// The guest spins forever, without checking getShutdownRequested.
+	mod := wasmtest.NewGuest(4, wasmtest.Function{
+		Export:  "startTracesReceiver",
+		Results: []api.ValueType{api.ValueTypeI32},
+		Body: wasmtest.Instructions(
+			wasmtest.Loop(), wasmtest.Br(0), wasmtest.End,
+			wasmtest.I32Const(0),
+		),
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = mod.Write(t)
+	ctx, wasmRecv, err := newTracesWasmReceiver(t.Context(), cfg, consumertest.NewNop(), receivertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm receiver: %v", err)
+	}
+
+	host := &statusHost{Host: componenttest.NewNopHost()}
+	if err := wasmRecv.Start(ctx, host); err != nil {
+		t.Fatalf("failed to start wasm receiver: %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := wasmRecv.Shutdown(shutdownCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the shutdown to time out, got %v", err)
+	}
+
+	// Closing the runtime aborts the guest.
+	done := make(chan struct{})
+	go func() {
+		wasmRecv.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("guest receiver still running after shutdown")
+	}
+	if errs := host.errors(); len(errs) != 0 {
+		t.Errorf("expected no error status reported on shutdown, got %v", errs)
+	}
+}
+
+func TestShutdownWithoutStart(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/nop/main.wasm"
+	_, wasmRecv, err := newTracesWasmReceiver(t.Context(), cfg, consumertest.NewNop(), receivertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm receiver: %v", err)
+	}
+	if err := wasmRecv.Shutdown(t.Context()); err != nil {
+		t.Fatalf("failed to stop wasm receiver: %v", err)
+	}
+}
+
+// resultGuest returns a guest whose receiver function passes payload to the
+// host function fn once. If fn returns a status, the guest traps unless it
+// is 0.
+func resultGuest(t *testing.T, telemetry int32, export, fn string, payload []byte, status bool) string {
+	t.Helper()
+	i32 := api.ValueTypeI32
+	var results []api.ValueType
+	if status {
+		results = []api.ValueType{i32}
+	}
+	mod := wasmtest.NewGuest(telemetry).Import(wasmtest.HostModule, fn, []api.ValueType{i32, i32}, results)
+	mod.Data = []wasmtest.Data{{Offset: 0, Bytes: payload}}
+	body := [][]byte{wasmtest.I32Const(0), wasmtest.I32Const(int32(len(payload))), mod.Call(fn)}
+	if status {
+		body = append(body, wasmtest.If(), wasmtest.Unreachable, wasmtest.End)
+	}
+	body = append(body, wasmtest.I32Const(0))
+	mod.Functions = append(mod.Functions, wasmtest.Function{
+		Export:  export,
+		Results: []api.ValueType{i32},
+		Body:    wasmtest.Instructions(body...),
+	})
+	return mod.Write(t)
+}
+
+func TestConsumerErrorPolicies(t *testing.T) {
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("log")
+	logsBytes, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	if err != nil {
+		t.Fatalf("failed to marshal logs: %v", err)
+	}
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+	tracesBytes, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	if err != nil {
+		t.Fatalf("failed to marshal traces: %v", err)
+	}
+
+	// Logs refused by the next consumer are dropped, while traces errors
+	// propagate.
+	policies := ConsumerErrorsConfig{Logs: ErrorPolicyDrop, Traces: ErrorPolicyPropagate}
+	refused := errors.New("refused")
+
+	tests := []struct {
+		name string
+		path string
+		// logs is set for a logs receiver, traces otherwise.
+		logs bool
+		// fail is set for a failing next consumer.
+		fail bool
+		want componentstatus.Status
+	}{
+		{
+			name: "dropped logs",
+			path: resultGuest(t, 2, "startLogsReceiver", "setResultLogs", logsBytes, false),
+			logs: true,
+			fail: true,
+		},
+		{
+			name: "propagated traces result",
+			path: resultGuest(t, 4, "startTracesReceiver", "setResultTraces", tracesBytes, false),
+			fail: true,
+			want: componentstatus.StatusRecoverableError,
+		},
+		{
+			name: "propagated emitted traces",
+			path: resultGuest(t, 4, "startTracesReceiver", "emitTraces", tracesBytes, true),
+			fail: true,
+			// The guest traps once told about the error.
+			want: componentstatus.StatusFatalError,
+		},
+		{
+			name: "consumed traces",
+			path: resultGuest(t, 4, "startTracesReceiver", "emitTraces", tracesBytes, true),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Path = tt.path
+			cfg.ConsumerErrors = policies
+			var r *Receiver
+			var err error
+			switch {
+			case tt.logs && tt.fail:
+				_, r, err = newLogsWasmReceiver(t.Context(), cfg, consumertest.NewErr(refused), receivertest.NewNopSettings(typeStr))
+			case tt.logs:
+				_, r, err = newLogsWasmReceiver(t.Context(), cfg, new(consumertest.LogsSink), receivertest.NewNopSettings(typeStr))
+			case tt.fail:
+				_, r, err = newTracesWasmReceiver(t.Context(), cfg, consumertest.NewErr(refused), receivertest.NewNopSettings(typeStr))
+			default:
+				_, r, err = newTracesWasmReceiver(t.Context(), cfg, new(consumertest.TracesSink), receivertest.NewNopSettings(typeStr))
+			}
+			if err != nil {
+				t.Fatalf("failed to create wasm receiver: %v", err)
+			}
+
+			host := &statusHost{Host: componenttest.NewNopHost()}
+			if err := r.Start(t.Context(), host); err != nil {
+				t.Fatalf("failed to start wasm receiver: %v", err)
+			}
+			if err := r.Shutdown(t.Context()); err != nil {
+				t.Fatalf("failed to stop wasm receiver: %v", err)
+			}
+
+			errs := host.errors()
+			if tt.want == componentstatus.StatusNone {
+				if len(errs) != 0 {
+					t.Errorf("expected no error status reported, got %v", errs[0].Err())
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Status() != tt.want {
+				t.Fatalf("expected a %v status, got %v", tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestFileTailReceiver(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("before start\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Path = "testdata/filetail/main.wasm"
+	cfg.Files.AllowedPaths = []string{dir}
+	cfg.PluginConfig = wasmplugin.PluginConfig{"path": path, "poll_interval": "10ms"}
+	sink := new(consumertest.LogsSink)
+	ctx, wasmRecv, err := newLogsWasmReceiver(t.Context(), cfg, sink, receivertest.NewNopSettings(typeStr))
+	if err != nil {
+		t.Fatalf("failed to create wasm receiver: %v", err)
+	}
+	if err := wasmRecv.Start(ctx, componenttest.NewNopHost()); err != nil {
+		t.Fatalf("failed to start wasm receiver: %v", err)
+	}
+	defer wasmRecv.Shutdown(ctx)
+
+	// The lines written before start are skipped, and a line is emitted
+	// once complete.
+	time.Sleep(100 * time.Millisecond)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("first line\nsecond"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := f.WriteString(" line\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	var bodies []string
+	deadline := time.Now().Add(5 * time.Second)
+	for len(bodies) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		bodies = bodies[:0]
+		for _, logs := range sink.AllLogs() {
+			records := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+			for i := range records.Len() {
+				bodies = append(bodies, records.At(i).Body().Str())
+				if got := records.At(i).Attributes().AsRaw()["log.file.path"]; got != path {
+					t.Errorf("expected the log.file.path attribute %s, got %v", path, got)
+				}
+			}
+		}
+	}
+	if !slices.Equal(bodies, []string{"first line", "second line"}) {
+		t.Errorf("expected the appended lines to be emitted, got %q", bodies)
+	}
+}